@@ -0,0 +1,114 @@
+//go:build js && wasm
+
+// Package main implements gones in the browser: the Ebitengine frontend
+// built for WebAssembly, run by the page in web/. The page gives it ROMs
+// through the gones.loadROM function, and keeps the files it writes (battery
+// saves, save states, settings) in IndexedDB.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall/js"
+
+	"gones/internal/app"
+	"gones/internal/remote"
+)
+
+func main() {
+	application, err := app.NewApplicationWithMode(app.GetDefaultConfigPath(), false)
+	if err != nil {
+		fmt.Printf("Failed to create application: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Closing the tab does not exit, so battery RAM is written out soon
+	// after every change; the page persists the files in the background
+	config := application.GetConfig()
+	config.Emulation.SRAMFlushInterval = 1
+
+	// Page events run on their own goroutines, so ROMs are loaded through
+	// the method server, which runs calls between frames
+	server := application.MethodServer()
+	server.Handle("loadPageROM", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Name string `json:"name"`
+			Data []byte `json:"data"`
+		}
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, remote.InvalidParams("invalid params: %v", err)
+		}
+		return nil, loadROM(application, args.Name, args.Data)
+	})
+
+	js.Global().Set("gones", js.ValueOf(map[string]any{
+		"loadROM": js.FuncOf(func(this js.Value, args []js.Value) any {
+			if len(args) != 2 {
+				return rejected("loadROM takes a file name and its bytes")
+			}
+			name := args[0].String()
+			data := make([]byte, args[1].Get("length").Int())
+			js.CopyBytesToGo(data, args[1])
+			return promise(func() error {
+				_, err := server.Call(context.Background(), "loadPageROM", map[string]any{"name": name, "data": data})
+				return err
+			})
+		}),
+	}))
+	js.Global().Call("dispatchEvent", js.Global().Get("Event").New("gonesready"))
+
+	fmt.Println("🌐 gones is running in the browser")
+	if err := application.Run(context.Background()); err != nil {
+		fmt.Printf("Application run failed: %v\n", err)
+	}
+	if err := application.Cleanup(); err != nil {
+		fmt.Printf("Application cleanup error: %v\n", err)
+	}
+}
+
+// loadROM keeps a ROM from the page in the ROMs directory, where the ROM
+// browser lists it next time, and plays it
+func loadROM(application *app.Application, name string, data []byte) error {
+	name = filepath.Base(name)
+	if name == "." || name == "/" {
+		return remote.InvalidParams("the ROM needs a file name")
+	}
+	dir := application.GetConfig().Paths.ROMs
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create the ROM directory: %v", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save the ROM: %v", err)
+	}
+	if err := application.LoadROM(path); err != nil {
+		return err
+	}
+	application.HideMenu()
+	fmt.Printf("📁 ROM loaded from the page: %s\n", name)
+	return nil
+}
+
+// promise returns a JavaScript promise of run, which runs on its own
+// goroutine since it may block
+func promise(run func() error) js.Value {
+	return js.Global().Get("Promise").New(js.FuncOf(func(this js.Value, args []js.Value) any {
+		resolve, reject := args[0], args[1]
+		go func() {
+			if err := run(); err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			resolve.Invoke()
+		}()
+		return nil
+	}))
+}
+
+// rejected returns a promise that failed with message
+func rejected(message string) js.Value {
+	return js.Global().Get("Promise").Call("reject", js.Global().Get("Error").New(message))
+}
@@ -0,0 +1,76 @@
+// Package main implements the batch subcommand, which boots every ROM in a
+// directory and reports the ones that do not run.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"gones/internal/batch"
+)
+
+// runBatch runs `gones batch` and returns the exit status: 0 when every ROM
+// booted fine, 1 when any did not and 2 for usage errors
+func runBatch(args []string) int {
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	frames := flags.Int("frames", batch.DefaultFrames, "Frames to run each ROM for")
+	quiet := flags.Bool("quiet", false, "Print only the report, not each ROM as it finishes")
+	jsonOut := flags.Bool("json", false, "Write the report to stdout as JSON instead of a table; progress goes to stderr")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gones batch [options] DIR")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Boots every .nes and .zip file under DIR headlessly and reports the ROMs that")
+		fmt.Fprintln(flags.Output(), "crash (emulator panics or CPU jams), use an unsupported mapper, fail to load,")
+		fmt.Fprintln(flags.Output(), "or show a blank single-color screen at the end of the run.")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 1 || *frames < 1 {
+		flags.Usage()
+		return 2
+	}
+
+	dir := flags.Arg(0)
+	progress := os.Stdout
+	if *jsonOut {
+		progress = os.Stderr
+	}
+	fmt.Fprintf(progress, "📚 Booting the ROMs in %s for %d frames each\n", dir, *frames)
+	results, err := batch.RunDir(dir, *frames, func(r batch.Result) {
+		if !*quiet {
+			fmt.Fprintf(progress, "  %-11s %s\n", r.Status, r.ROM)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "batch: %v\n", err)
+		return 2
+	}
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "batch: no .nes or .zip files in %s\n", dir)
+		return 2
+	}
+	if *jsonOut {
+		ok, err := batch.WriteJSON(os.Stdout, results)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "batch: %v\n", err)
+			return 1
+		}
+		if !ok {
+			return 1
+		}
+		return 0
+	}
+	fmt.Println()
+	if !batch.WriteReport(os.Stdout, results) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,111 @@
+// Package main implements the bench subcommand, which measures how fast the
+// emulator core runs a ROM.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"gones/internal/bench"
+)
+
+// runBench runs `gones bench` and returns the exit status
+func runBench(args []string) int {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	frames := flags.Int("frames", bench.DefaultFrames, "Frames to measure")
+	warmup := flags.Int("warmup", bench.DefaultWarmup, "Frames to run before measuring")
+	cpuProfile := flags.String("cpuprofile", "", "Write a CPU profile of the measured frames to a file (go tool pprof)")
+	memProfile := flags.String("memprofile", "", "Write a heap allocation profile to a file after the run (go tool pprof)")
+	jsonOut := flags.Bool("json", false, "Write the result to stdout as JSON instead of a report; other messages go to stderr")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gones bench [options] ROM")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Runs a ROM unthrottled without video or audio and reports emulated frames per")
+		fmt.Fprintln(flags.Output(), "second, the time spent in the CPU, PPU and APU, and allocations per frame.")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	rom := flags.Arg(0)
+	if *warmup == 0 {
+		*warmup = -1 // bench.Options takes 0 as the default
+	}
+
+	if *cpuProfile != "" {
+		file, err := os.Create(*cpuProfile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return 2
+		}
+		defer file.Close()
+		if err := pprof.StartCPUProfile(file); err != nil {
+			fmt.Fprintf(os.Stderr, "bench: failed to start CPU profile: %v\n", err)
+			return 2
+		}
+	}
+	if *memProfile != "" {
+		runtime.MemProfileRate = 4096 // Finer than the default to catch per-frame allocations
+	}
+
+	messages := os.Stdout
+	if *jsonOut {
+		messages = os.Stderr
+	}
+	fmt.Fprintf(messages, "⏱️  Benchmarking %s: %d frames after %d warm-up\n", rom, *frames, max(*warmup, 0))
+	result, err := bench.Run(rom, bench.Options{Frames: *frames, Warmup: *warmup})
+	if *cpuProfile != "" {
+		pprof.StopCPUProfile()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+		return 1
+	}
+	if *jsonOut {
+		if err := result.WriteJSON(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return 1
+		}
+	} else {
+		fmt.Println()
+		result.Write(os.Stdout)
+	}
+
+	if *cpuProfile != "" {
+		fmt.Fprintf(messages, "\n📊 CPU profile: %s\n", *cpuProfile)
+	}
+	if *memProfile != "" {
+		if err := writeHeapProfile(*memProfile); err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(messages, "📊 Heap profile: %s\n", *memProfile)
+	}
+	return 0
+}
+
+// writeHeapProfile writes the allocations made so far to path
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	runtime.GC() // Bring the profile up to date
+	if err := pprof.Lookup("allocs").WriteTo(file, 0); err != nil {
+		return fmt.Errorf("failed to write heap profile: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Package main implements the gones subcommands and the dispatch between them.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// command is a gones subcommand
+type command struct {
+	name    string
+	usage   string // Arguments, shown after the name
+	summary string
+	run     func(args []string) int // Returns the exit status
+}
+
+// commands lists the subcommands in the order help shows them. It is filled
+// in by init, as help refers to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "[options] [ROM]", "Play a ROM, or run it headless with -nogui (the default command)", runRun},
+		{"info", "ROM...", "Show a ROM's header: mapper, PRG/CHR sizes, mirroring, battery and SHA-256", runInfo},
+		{"rom", "split|convert ...", "Split a ROM into PRG/CHR files, or convert its header to NES 2.0", runROM},
+		{"library", "[options] [DIR...]", "Scan the ROM directories and name the ROMs from a No-Intro style database", runLibrary},
+		{"verify", "[options] ROM|DIR...", "Run accuracy test ROMs and report which pass", runVerify},
+		{"bench", "[options] ROM", "Measure emulation speed", runBench},
+		{"batch", "[options] DIR", "Boot every ROM in a directory and report crashes, unsupported mappers and blank screens", runBatch},
+		{"record", "[options] ROM OUTPUT", "Record a headless run to video or audio", runRecord},
+		{"play-movie", "[options] ROM MOVIE", "Play back an input script, then hand over the controls", runPlayMovie},
+		{"netplay", "host|join ...", "Play a ROM with another player over the network", runNetplay},
+		{"nsf", "[options] FILE", "Show an NSF music file's songs, or render one to WAV", runNSF},
+		{"help", "[command]", "Show help for gones or a command", runHelp},
+		{"version", "", "Show version information", runVersion},
+	}
+}
+
+// runCommand runs the subcommand named by the first argument, or run for
+// anything else, and returns the exit status
+func runCommand(args []string) int {
+	if len(args) > 0 {
+		if cmd := findCommand(args[0]); cmd != nil {
+			return cmd.run(args[1:])
+		}
+	}
+	return runRun(args)
+}
+
+// findCommand returns the subcommand called name, or nil
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// printCommands lists the subcommands for the usage message
+func printCommands() {
+	for _, cmd := range commands {
+		fmt.Printf("  %-34s # %s\n", "gones "+cmd.name+" "+cmd.usage, cmd.summary)
+	}
+}
+
+// runHelp runs `gones help [command]`
+func runHelp(args []string) int {
+	switch len(args) {
+	case 0:
+		return runRun([]string{"-help"})
+	case 1:
+		cmd := findCommand(args[0])
+		if cmd == nil {
+			fmt.Fprintf(os.Stderr, "help: unknown command %q\n", args[0])
+			return 2
+		}
+		if cmd.name == "help" || cmd.name == "version" {
+			fmt.Printf("Usage: gones %s %s\n\n%s\n", cmd.name, cmd.usage, cmd.summary)
+			return 0
+		}
+		return cmd.run([]string{"-help"})
+	default:
+		fmt.Fprintln(os.Stderr, "Usage: gones help [command]")
+		return 2
+	}
+}
+
+// runVersion runs `gones version`
+func runVersion(args []string) int {
+	printVersion()
+	return 0
+}
+
+// runRecord runs `gones record`, a headless run recorded to a file
+func runRecord(args []string) int {
+	flags := flag.NewFlagSet("record", flag.ContinueOnError)
+	frames := flags.Int("frames", 600, "Frames to record")
+	script := flags.String("input-script", "", "JSON/CSV script of per-frame controller states")
+	fourScore := flags.Bool("fourscore", false, "Connect a Four Score adapter for 3-4 players")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gones record [options] ROM OUTPUT")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Runs a ROM headless and records it. The format follows OUTPUT's extension:")
+		fmt.Fprintln(flags.Output(), ".gif, .png (APNG), .mp4/.mkv/.webm (needs ffmpeg), or .wav for the audio only.")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	runArgs := []string{"-nogui", "-rom", flags.Arg(0), "-record", flags.Arg(1),
+		"-frames", strconv.Itoa(*frames), "-dump-frames="}
+	if *script != "" {
+		runArgs = append(runArgs, "-input-script", *script)
+	}
+	if *fourScore {
+		runArgs = append(runArgs, "-fourscore")
+	}
+	return runRun(runArgs)
+}
+
+// runPlayMovie runs `gones play-movie`, playing back an input script in the
+// GUI (or headless) from power on
+func runPlayMovie(args []string) int {
+	flags := flag.NewFlagSet("play-movie", flag.ContinueOnError)
+	nogui := flags.Bool("nogui", false, "Play the movie headless, for checking it or recording it")
+	recordFile := flags.String("record", "", "Record the playback to a file (see gones record)")
+	fourScore := flags.Bool("fourscore", false, "Connect a Four Score adapter for movies with 3-4 players")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gones play-movie [options] ROM MOVIE")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Plays back a JSON/CSV input script (see INPUT SCRIPTS in gones -help) from power")
+		fmt.Fprintln(flags.Output(), "on, or from the savestate it holds. In the GUI the controls return to the player")
+		fmt.Fprintln(flags.Output(), "when the movie ends, and Alt+F12 branches a new movie off it.")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	runArgs := []string{"-rom", flags.Arg(0), "-input-script", flags.Arg(1)}
+	if *nogui {
+		runArgs = append(runArgs, "-nogui", "-dump-frames=")
+	}
+	if *recordFile != "" {
+		runArgs = append(runArgs, "-record", *recordFile)
+	}
+	if *fourScore {
+		runArgs = append(runArgs, "-fourscore")
+	}
+	return runRun(runArgs)
+}
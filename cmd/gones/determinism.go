@@ -0,0 +1,55 @@
+// Package main implements the determinism self-check (-determinism), which
+// runs a ROM and input script twice and reports the first frame they differ.
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gones/internal/input"
+	"gones/internal/verify"
+)
+
+// runDeterminismCheck runs -determinism and returns the exit status: 0 when
+// the runs match, 1 when they diverge and 2 when the check cannot run. The
+// run is as long as a headless run: 120 frames, at least the script's
+// length, or frames if positive.
+func runDeterminismCheck(rom, scriptFile string, frames int, mode string) int {
+	if rom == "" {
+		fmt.Fprintln(os.Stderr, "-determinism needs a ROM (-rom)")
+		return 2
+	}
+	if mode != verify.DeterminismSequential && mode != verify.DeterminismParallel {
+		fmt.Fprintf(os.Stderr, "-determinism is %s or %s, not %q\n", verify.DeterminismSequential, verify.DeterminismParallel, mode)
+		return 2
+	}
+	var script *input.Script
+	targetFrames := 120
+	if scriptFile != "" {
+		var err error
+		script, err = input.LoadScript(scriptFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load input script: %v\n", err)
+			return 2
+		}
+		targetFrames = max(targetFrames, script.Length())
+	}
+	if frames > 0 {
+		targetFrames = frames
+	}
+
+	fmt.Printf("🔁 Checking determinism: %s, %d frames, %s runs\n", rom, targetFrames, mode)
+	start := time.Now()
+	divergence, err := verify.CheckDeterminism(rom, script, targetFrames, mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Determinism check failed: %v\n", err)
+		return 2
+	}
+	if divergence != nil {
+		fmt.Printf("❌ Not deterministic: %s\n", divergence)
+		return 1
+	}
+	fmt.Printf("✅ Deterministic: both runs match for %d frames (%.1fs)\n", targetFrames, time.Since(start).Seconds())
+	return 0
+}
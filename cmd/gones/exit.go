@@ -0,0 +1,145 @@
+// Package main implements the exit conditions of headless runs (-exit-when
+// and -exit-at-addr): memory comparisons and text in the nametables that end
+// the run with an exit status.
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// exitOperators are the memory comparisons, two character ones first so "<="
+// is not read as "<". "=" is short for "==".
+var exitOperators = []string{"==", "!=", "<=", ">=", "<", ">", "="}
+
+// exitCondition ends a headless run with an exit status when memory at an
+// address compares with a value, or when text appears in the nametables
+type exitCondition struct {
+	address uint16
+	op      string // Memory comparison; empty for text conditions
+	value   uint8
+	text    string // Text in the nametables
+	code    int    // Exit status when met
+}
+
+// parseExitCondition parses "CONDITION[=>CODE]", where CONDITION is a hex
+// memory comparison such as "$6000==00" or "10>=3" (operators ==, !=, <, <=,
+// >, >= and = for ==) or "text:Passed" for text in the nametables. The exit
+// status defaults to 0.
+func parseExitCondition(s string) (exitCondition, error) {
+	var condition exitCondition
+	expression := s
+	if i := strings.LastIndex(s, "=>"); i >= 0 {
+		code, err := strconv.Atoi(strings.TrimSpace(s[i+2:]))
+		if err != nil || code < 0 || code > 125 {
+			return exitCondition{}, fmt.Errorf("invalid exit status in %q (0-125)", s)
+		}
+		condition.code = code
+		expression = s[:i]
+	}
+	expression = strings.TrimSpace(expression)
+
+	if text, found := strings.CutPrefix(expression, "text:"); found {
+		if text == "" {
+			return exitCondition{}, fmt.Errorf("empty text in exit condition %q", s)
+		}
+		condition.text = text
+		return condition, nil
+	}
+
+	for _, op := range exitOperators {
+		address, value, found := strings.Cut(expression, op)
+		if !found {
+			continue
+		}
+		a, err := strconv.ParseUint(hexDigits(address), 16, 16)
+		if err != nil {
+			return exitCondition{}, fmt.Errorf("invalid address in exit condition %q", s)
+		}
+		v, err := strconv.ParseUint(hexDigits(value), 16, 8)
+		if err != nil {
+			return exitCondition{}, fmt.Errorf("invalid value in exit condition %q", s)
+		}
+		if op == "=" {
+			op = "=="
+		}
+		condition.address, condition.op, condition.value = uint16(a), op, uint8(v)
+		return condition, nil
+	}
+	return exitCondition{}, fmt.Errorf("exit condition must be ADDR==VALUE (or !=, <, <=, >, >=) or text:TEXT: %q", s)
+}
+
+// hexDigits strips the $ or 0x prefix of a hex number
+func hexDigits(s string) string {
+	s = strings.TrimSpace(s)
+	return strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(s, "$"), "0x"), "0X")
+}
+
+func (c exitCondition) String() string {
+	if c.op == "" {
+		return fmt.Sprintf("text %q (exit %d)", c.text, c.code)
+	}
+	return fmt.Sprintf("$%04X%s$%02X (exit %d)", c.address, c.op, c.value, c.code)
+}
+
+// holds reports whether a memory condition holds for a value
+func (c exitCondition) holds(value uint8) bool {
+	switch c.op {
+	case "==":
+		return value == c.value
+	case "!=":
+		return value != c.value
+	case "<":
+		return value < c.value
+	case "<=":
+		return value <= c.value
+	case ">":
+		return value > c.value
+	case ">=":
+		return value >= c.value
+	}
+	return false
+}
+
+// exitConditions is the repeatable -exit-when and -exit-at-addr flag
+type exitConditions []exitCondition
+
+func (c exitConditions) String() string {
+	names := make([]string, len(c))
+	for i, condition := range c {
+		names[i] = condition.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func (c *exitConditions) Set(s string) error {
+	condition, err := parseExitCondition(s)
+	if err != nil {
+		return err
+	}
+	*c = append(*c, condition)
+	return nil
+}
+
+// met returns the first condition met, reading memory with peek and the
+// nametable text with text, which is only called when a text condition needs
+// it
+func (c exitConditions) met(peek func(address uint16) (uint8, bool), text func() string) (exitCondition, bool) {
+	screen, read := "", false
+	for _, condition := range c {
+		if condition.op == "" {
+			if !read {
+				screen, read = text(), true
+			}
+			if strings.Contains(screen, condition.text) {
+				return condition, true
+			}
+			continue
+		}
+		if value, ok := peek(condition.address); ok && condition.holds(value) {
+			return condition, true
+		}
+	}
+	return exitCondition{}, false
+}
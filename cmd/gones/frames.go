@@ -0,0 +1,188 @@
+// Package main implements the frame dumps of headless runs: single frames or
+// image sequences as PPM, PNG, BMP or raw RGBA files, and raw frames streamed
+// to stdout.
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Frame dump formats (-frame-format)
+const (
+	frameFormatPPM  = "ppm"
+	frameFormatPNG  = "png"
+	frameFormatBMP  = "bmp"
+	frameFormatRGBA = "rgba"
+)
+
+// frameFormats lists the frame dump formats
+var frameFormats = []string{frameFormatPPM, frameFormatPNG, frameFormatBMP, frameFormatRGBA}
+
+// Frame size
+const (
+	frameWidth  = 256
+	frameHeight = 240
+)
+
+// frameDumper writes frames to numbered files in a directory
+type frameDumper struct {
+	format string
+	dir    string
+	saved  []string // Files written, in order
+}
+
+// newFrameDumper checks the format and creates the directory
+func newFrameDumper(format, dir string) (*frameDumper, error) {
+	format = strings.ToLower(format)
+	valid := false
+	for _, f := range frameFormats {
+		valid = valid || f == format
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown frame format %q (use %s)", format, strings.Join(frameFormats, ", "))
+	}
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create frame directory: %v", err)
+	}
+	return &frameDumper{format: format, dir: dir}, nil
+}
+
+// path returns the file a frame is written to, such as frame_031.png
+func (d *frameDumper) path(frame int) string {
+	return filepath.Join(d.dir, fmt.Sprintf("frame_%03d.%s", frame, d.format))
+}
+
+// dump writes a frame of 0xRRGGBB pixels and returns its file
+func (d *frameDumper) dump(pixels []uint32, frame int) (string, error) {
+	path := d.path(frame)
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	w := bufio.NewWriter(file)
+	err = encodeFrame(w, d.format, pixels)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	d.saved = append(d.saved, path)
+	return path, nil
+}
+
+// encodeFrame writes a frame of 0xRRGGBB pixels in a frame format
+func encodeFrame(w io.Writer, format string, pixels []uint32) error {
+	if len(pixels) < frameWidth*frameHeight {
+		return fmt.Errorf("frame too small: %d pixels", len(pixels))
+	}
+	pixels = pixels[:frameWidth*frameHeight]
+	switch format {
+	case frameFormatPPM:
+		return writePPM(w, pixels)
+	case frameFormatPNG:
+		img := image.NewRGBA(image.Rect(0, 0, frameWidth, frameHeight))
+		img.Pix = appendRGBA(img.Pix[:0], pixels)
+		return png.Encode(w, img)
+	case frameFormatBMP:
+		return writeBMP(w, pixels)
+	case frameFormatRGBA:
+		_, err := w.Write(appendRGBA(nil, pixels))
+		return err
+	default:
+		return fmt.Errorf("unknown frame format %q", format)
+	}
+}
+
+// appendRGBA appends pixels as R, G, B, A bytes, the layout of raw RGBA
+// frames (ffmpeg -f rawvideo -pix_fmt rgba)
+func appendRGBA(buf []byte, pixels []uint32) []byte {
+	for _, pixel := range pixels {
+		buf = append(buf, uint8(pixel>>16), uint8(pixel>>8), uint8(pixel), 0xFF)
+	}
+	return buf
+}
+
+// writePPM writes a plain text (P3) PPM image
+func writePPM(w io.Writer, pixels []uint32) error {
+	fmt.Fprintf(w, "P3\n%d %d\n255\n", frameWidth, frameHeight)
+	for y := 0; y < frameHeight; y++ {
+		for _, pixel := range pixels[y*frameWidth : (y+1)*frameWidth] {
+			fmt.Fprintf(w, "%d %d %d ", (pixel>>16)&0xFF, (pixel>>8)&0xFF, pixel&0xFF)
+		}
+		if _, err := fmt.Fprintf(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBMP writes a 24-bit uncompressed BMP image. Rows are stored bottom up
+// in BGR order; 256 pixels of 3 bytes need no row padding.
+func writeBMP(w io.Writer, pixels []uint32) error {
+	const headerSize = 14 + 40
+	imageSize := frameWidth * frameHeight * 3
+
+	buf := make([]byte, 0, headerSize+imageSize)
+	buf = append(buf, 'B', 'M')
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(headerSize+imageSize))
+	buf = binary.LittleEndian.AppendUint32(buf, 0) // Reserved
+	buf = binary.LittleEndian.AppendUint32(buf, headerSize)
+	buf = binary.LittleEndian.AppendUint32(buf, 40) // BITMAPINFOHEADER
+	buf = binary.LittleEndian.AppendUint32(buf, frameWidth)
+	buf = binary.LittleEndian.AppendUint32(buf, frameHeight)
+	buf = binary.LittleEndian.AppendUint16(buf, 1)  // Planes
+	buf = binary.LittleEndian.AppendUint16(buf, 24) // Bits per pixel
+	buf = binary.LittleEndian.AppendUint32(buf, 0)  // No compression
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(imageSize))
+	buf = binary.LittleEndian.AppendUint32(buf, 2835) // 72 DPI
+	buf = binary.LittleEndian.AppendUint32(buf, 2835)
+	buf = binary.LittleEndian.AppendUint32(buf, 0) // Colors used
+	buf = binary.LittleEndian.AppendUint32(buf, 0) // Important colors
+	for y := frameHeight - 1; y >= 0; y-- {
+		for _, pixel := range pixels[y*frameWidth : (y+1)*frameWidth] {
+			buf = append(buf, uint8(pixel), uint8(pixel>>8), uint8(pixel>>16))
+		}
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// frameStream writes every frame as raw RGBA, for piping into ffmpeg
+type frameStream struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func newFrameStream(w io.Writer) *frameStream {
+	return &frameStream{w: bufio.NewWriterSize(w, frameWidth*frameHeight*4)}
+}
+
+// write streams a frame of 0xRRGGBB pixels
+func (s *frameStream) write(pixels []uint32) error {
+	if len(pixels) < frameWidth*frameHeight {
+		return fmt.Errorf("frame too small: %d pixels", len(pixels))
+	}
+	s.buf = appendRGBA(s.buf[:0], pixels[:frameWidth*frameHeight])
+	_, err := s.w.Write(s.buf)
+	return err
+}
+
+// close flushes the frames still buffered
+func (s *frameStream) close() error {
+	return s.w.Flush()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testPixels is a frame with a different color in each corner
+func testPixels() []uint32 {
+	pixels := make([]uint32, frameWidth*frameHeight)
+	pixels[0] = 0xFF0000
+	pixels[frameWidth-1] = 0x00FF00
+	pixels[(frameHeight-1)*frameWidth] = 0x0000FF
+	pixels[frameWidth*frameHeight-1] = 0x123456
+	return pixels
+}
+
+func TestEncodeFrame(t *testing.T) {
+	pixels := testPixels()
+
+	var buf bytes.Buffer
+	if err := encodeFrame(&buf, frameFormatRGBA, pixels); err != nil {
+		t.Fatalf("rgba: %v", err)
+	}
+	if got := buf.Bytes(); len(got) != frameWidth*frameHeight*4 || !bytes.Equal(got[:4], []byte{0xFF, 0, 0, 0xFF}) {
+		t.Errorf("rgba: %d bytes starting %v", len(got), got[:4])
+	}
+
+	buf.Reset()
+	if err := encodeFrame(&buf, frameFormatPNG, pixels); err != nil {
+		t.Fatalf("png: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("png decode: %v", err)
+	}
+	if r, g, b, _ := img.At(frameWidth-1, 0).RGBA(); r != 0 || g != 0xFFFF || b != 0 {
+		t.Errorf("png top right = %04X %04X %04X, want green", r, g, b)
+	}
+
+	buf.Reset()
+	if err := encodeFrame(&buf, frameFormatBMP, pixels); err != nil {
+		t.Fatalf("bmp: %v", err)
+	}
+	bmp := buf.Bytes()
+	if string(bmp[:2]) != "BM" || int(binary.LittleEndian.Uint32(bmp[2:])) != len(bmp) {
+		t.Fatalf("bmp header %q, size %d of %d", bmp[:2], binary.LittleEndian.Uint32(bmp[2:]), len(bmp))
+	}
+	offset := binary.LittleEndian.Uint32(bmp[10:])
+	// Rows are bottom up in BGR order: the first pixel is the bottom left
+	if got := bmp[offset : offset+3]; !bytes.Equal(got, []byte{0xFF, 0, 0}) {
+		t.Errorf("bmp bottom left = %v, want blue", got)
+	}
+	if got := bmp[len(bmp)-3:]; !bytes.Equal(got, []byte{0, 0xFF, 0}) {
+		t.Errorf("bmp top right = %v, want green", got)
+	}
+
+	buf.Reset()
+	if err := encodeFrame(&buf, frameFormatPPM, pixels); err != nil {
+		t.Fatalf("ppm: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("P3\n256 240\n255\n255 0 0 ")) {
+		t.Errorf("ppm starts %q", buf.Bytes()[:24])
+	}
+
+	if err := encodeFrame(&buf, frameFormatPNG, pixels[:10]); err == nil {
+		t.Error("short frame encoded")
+	}
+}
+
+func TestFrameDumper(t *testing.T) {
+	if _, err := newFrameDumper("tiff", t.TempDir()); err == nil {
+		t.Error("tiff accepted")
+	}
+
+	dir := filepath.Join(t.TempDir(), "frames")
+	d, err := newFrameDumper("PNG", dir)
+	if err != nil {
+		t.Fatalf("newFrameDumper: %v", err)
+	}
+	path, err := d.dump(testPixels(), 7)
+	if err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	if want := filepath.Join(dir, "frame_007.png"); path != want {
+		t.Errorf("path = %s, want %s", path, want)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+	if len(d.saved) != 1 {
+		t.Errorf("saved = %v", d.saved)
+	}
+}
+
+func TestFrameStream(t *testing.T) {
+	var buf bytes.Buffer
+	s := newFrameStream(&buf)
+	for i := 0; i < 3; i++ {
+		if err := s.write(testPixels()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.Len(), 3*frameWidth*frameHeight*4; got != want {
+		t.Errorf("streamed %d bytes, want %d", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+// Package main implements the info subcommand, which shows what a ROM's
+// header says about its cartridge.
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"os"
+
+	"gones/internal/cartridge"
+	"gones/internal/library"
+)
+
+// runInfo runs `gones info` and returns the exit status
+func runInfo(args []string) int {
+	flags := flag.NewFlagSet("info", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gones info ROM...")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Shows each ROM's header format, mapper, PRG/CHR sizes, mirroring, battery and")
+		fmt.Fprintln(flags.Output(), "SHA-256 (the hash game profiles are named by), and the CRC32 and SHA-1 without")
+		fmt.Fprintln(flags.Output(), "the header, as No-Intro's database lists them.")
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	status := 0
+	for i, path := range flags.Args() {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := printROMInfo(path); err != nil {
+			fmt.Fprintf(os.Stderr, "info: %s: %v\n", path, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// printROMInfo prints the header information of a ROM
+func printROMInfo(path string) error {
+	data, err := cartridge.ReadROM(path)
+	if err != nil {
+		return err
+	}
+	cart, err := cartridge.LoadFromReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	format := "iNES"
+	if data[7]&0x0C == 0x08 {
+		format = "NES 2.0"
+	}
+	chr := fmt.Sprintf("%d KB", cart.CHRSize()/1024)
+	if cart.CHRSize() == 0 {
+		chr = "8 KB RAM"
+	}
+
+	fmt.Printf("File:      %s\n", path)
+	fmt.Printf("Size:      %d bytes\n", len(data))
+	crc, sha := library.Hash(data)
+	fmt.Printf("SHA-256:   %x\n", sha256.Sum256(data))
+	fmt.Printf("CRC32:     %s (without header)\n", crc)
+	fmt.Printf("SHA-1:     %s (without header)\n", sha)
+	fmt.Printf("Format:    %s\n", format)
+	fmt.Printf("Mapper:    %d\n", cart.MapperID())
+	fmt.Printf("PRG ROM:   %d KB\n", cart.PRGSize()/1024)
+	fmt.Printf("CHR:       %s\n", chr)
+	fmt.Printf("Mirroring: %s\n", mirroringName(cart.GetMirrorMode()))
+	fmt.Printf("Battery:   %s\n", yesNo(cart.HasBattery()))
+	if data[6]&0x04 != 0 {
+		fmt.Println("Trainer:   yes (512 bytes)")
+	}
+	return nil
+}
+
+// mirroringName names a nametable mirroring mode
+func mirroringName(mode cartridge.MirrorMode) string {
+	switch mode {
+	case cartridge.MirrorHorizontal:
+		return "horizontal"
+	case cartridge.MirrorVertical:
+		return "vertical"
+	case cartridge.MirrorSingleScreen0, cartridge.MirrorSingleScreen1:
+		return "single screen"
+	case cartridge.MirrorFourScreen:
+		return "four screen (2 KB VRAM on the cartridge)"
+	default:
+		return fmt.Sprintf("unknown (%d)", mode)
+	}
+}
+
+// yesNo returns "yes" or "no"
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,84 @@
+// Package main implements the library subcommand, which scans the ROM
+// directories and names the ROMs from the hash database.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"gones/internal/app"
+)
+
+// runLibrary runs `gones library` and returns the exit status
+func runLibrary(args []string) int {
+	flags := flag.NewFlagSet("library", flag.ContinueOnError)
+	configFile := flags.String("config", "", "Path to configuration file")
+	portable := flags.Bool("portable", false, "Use the config next to the executable, as gones -portable does")
+	database := flags.String("database", "", "No-Intro style DAT file or directory of them (default from the config)")
+	jsonOut := flags.Bool("json", false, "Write the library to stdout as JSON instead of a table")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gones library [options] [DIR...]")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Scans the ROM directories (library.dirs in the config, or the ROMs directory),")
+		fmt.Fprintln(flags.Output(), "or each DIR, hashes the ROMs new or changed since the last scan and names them")
+		fmt.Fprintln(flags.Output(), "from the database. The result is cached in the config directory's library.json,")
+		fmt.Fprintln(flags.Output(), "which the ROM browser shows the titles from.")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	app.SetPortable(*portable)
+
+	config := app.NewConfig()
+	configPath := *configFile
+	if configPath == "" {
+		configPath = app.GetDefaultConfigPath()
+	}
+	if _, err := os.Stat(configPath); err == nil || *configFile != "" {
+		if err := config.LoadFromFile(configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "library: %v\n", err)
+			return 1
+		}
+	}
+	if *database != "" {
+		config.Library.Database = *database
+	}
+	if _, err := os.Stat(config.Library.Database); err != nil {
+		fmt.Fprintf(os.Stderr, "library: no ROM database at %s, so the ROMs keep their file names\n", config.Library.Database)
+	}
+
+	lib := config.OpenLibrary()
+	result, err := config.ScanLibrary(lib, flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "library: %v\n", err)
+		return 1
+	}
+
+	entries := lib.Entries()
+	if *jsonOut {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "library: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TITLE\tREGION\tCRC32\tPATH")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Name(), entry.Region, entry.CRC32, entry.Path)
+	}
+	w.Flush()
+	fmt.Printf("\n📚 %d ROMs, %d identified, %d hashed\n", result.ROMs, result.Identified, result.Hashed)
+	return 0
+}
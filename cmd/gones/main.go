@@ -2,43 +2,163 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
 
 	"gones/internal/app"
+	"gones/internal/input"
+	"gones/internal/record"
+	"gones/internal/scroll"
+	"gones/internal/verify"
 	"gones/internal/version"
 )
 
 func main() {
-	// Parse command line flags
+	os.Exit(runCommand(os.Args[1:]))
+}
+
+// runRun runs `gones run`, the emulator itself, and returns the exit status.
+// It is also what gones runs without a subcommand, so `gones game.nes` and
+// `gones -rom game.nes` work as before.
+func runRun(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
 	var (
-		romFile    = flag.String("rom", "", "Path to NES ROM file (optional for GUI mode)")
-		configFile = flag.String("config", "", "Path to configuration file")
-		debug      = flag.Bool("debug", false, "Enable debug mode")
-		nogui      = flag.Bool("nogui", false, "Run without GUI (headless mode)")
-		help       = flag.Bool("help", false, "Show help message")
-		version    = flag.Bool("version", false, "Show version information")
+		romFile    = flags.String("rom", "", "Path to NES ROM file (optional for GUI mode)")
+		patchFile  = flags.String("patch", "", "IPS or BPS patch to apply to the ROM as it loads (default: a .ips or .bps file named like the ROM)")
+		configFile = flags.String("config", "", "Path to configuration file")
+		portable   = flags.Bool("portable", false, "Keep the config, saves, states and screenshots next to the executable instead of in the user directories")
+		debug      = flags.Bool("debug", false, "Enable debug mode")
+		nogui      = flags.Bool("nogui", false, "Run without GUI (headless mode)")
+		help       = flags.Bool("help", false, "Show help message")
+		version    = flags.Bool("version", false, "Show version information")
+		zapper     = flags.Bool("zapper", false, "Connect a Zapper light gun to port 2 (mouse aims, click fires)")
+		fourScore  = flags.Bool("fourscore", false, "Connect a Four Score adapter for 3-4 players")
+		port2      = flags.String("port2", "", "Device on port 2: controller, zapper or arkanoid")
+		inputFile  = flags.String("input-script", "", "JSON/CSV script of per-frame controller states (played back as a movie in GUI mode)")
+		movieFile  = flags.String("record-movie", "", "Record the controllers to an input script in GUI mode, from power on or the -load-state (which JSON scripts embed)")
+		recordFile = flags.String("record", "", "Record video to a .gif, .png (APNG) or .mp4/.mkv/.webm (needs ffmpeg) file")
+		frames     = flags.Int("frames", 0, "Frames to run in headless mode (default 120, or the input script length)")
+		speed      = flags.String("speed", "", "Emulation speed, e.g. 0.25, 0.5, 2, 4, or max for unthrottled")
+		noThrottle = flags.Bool("no-throttle", false, "Run as fast as the host allows, without frame pacing, VSync or audio (for benchmarks, dumps and bots)")
+		regionName = flags.String("region", "", "Console region: auto (from the ROM header and file name), ntsc, pal or dendy (default from the config)")
+		ramPattern = flags.String("ram-pattern", "", "RAM contents at power on: mixed, zero, ff, alternating or random (default from the config)")
+		ramSeed    = flags.Int64("ram-seed", 0, "Seed of -ram-pattern random, to repeat a run (default from the config, or a new one shown at start)")
+		noCompat   = flags.Bool("no-compat", false, "Load ROMs as their headers say, without the fixes of the compatibility database")
+		codeData   = flags.Bool("cdl", false, "Log which PRG/CHR bytes are code, data or drawn to <save_data>/<rom>.cdl")
+		traceFile  = flags.String("trace", "", "Trace CPU instructions to a file (filters in debug.trace of the config)")
+		scrollFile = flags.String("scroll-log", "", "Write the scroll of every scanline of every frame to a CSV file, for debugging splits")
+		remoteAddr = flags.String("debug-server", "", "Let debuggers attach over JSON-RPC (TCP or WebSocket) on an address such as 127.0.0.1:6502")
+		stateFile  = flags.String("load-state", "", "Load a save state file after the ROM, such as the state.save of a crash report")
+		apiAddr    = flags.String("api", "", "Serve the HTTP control API for bots and automation on an address such as 127.0.0.1:6580")
+		metricsAddr = flags.String("metrics", "", "Serve Prometheus metrics at /metrics on an address such as 127.0.0.1:9650")
+		dumpFrames  = flags.String("dump-frames", "31,61,120", "Frames to save as frame_NNN.<format> in headless mode, comma separated (\"\" for none, all for an image sequence)")
+		frameFormat = flags.String("frame-format", frameFormatPPM, "Format of dumped frames: ppm, png, bmp or rgba (raw 256x240 RGBA)")
+		frameDir    = flags.String("frame-dir", ".", "Directory to save dumped frames in, created if needed")
+		frameStdout = flags.Bool("frame-stdout", false, "Stream every headless frame to stdout as raw 256x240 RGBA, for piping into ffmpeg; messages go to stderr")
+		goldenFile  = flags.String("golden", "", "Compare SHA-256 hashes of headless frames with a golden file, exiting 1 if any differ")
+		updateGolden = flags.Bool("update-golden", false, "Write the -golden file from this run (of -dump-frames, or the frames it already has)")
+		determinism = flags.String("determinism", "", "Run -rom and -input-script twice (sequential, or parallel on two goroutines) and report the first frame whose state differs")
+		seconds     = flags.Float64("seconds", 0, "Seconds to run in headless mode, instead of -frames")
+		quietFlag   = flags.Bool("quiet", false, "Print only warnings, errors and results in headless mode")
+		jsonOut     = flags.Bool("json", false, "Write the result of a headless run to stdout as JSON (every frame's hash, how it ended, speed); messages go to stderr")
+		netplayHost = flags.String("netplay-host", "", "Host a netplay session of the ROM as player 1 on a UDP address such as :7845")
+		netplayJoin = flags.String("netplay-join", "", "Join the netplay session hosted on an address such as 192.168.1.20:7845 as player 2")
+		netplayDelay = flags.Int("netplay-delay", -1, "Netplay input delay in frames when hosting (default from the config)")
+		netplayRollback = flags.Int("netplay-rollback", -1, "Frames the host's session may roll back, 0 for lockstep (default from the config)")
+		secondROM   = flags.String("second-rom", "", "Run a second ROM side by side in a window of its own (needs video.backend sdl2)")
+		nametables  = flags.Bool("nametable-viewer", false, "Show the game's nametables in a window of their own (needs video.backend sdl2)")
+		exitAt      exitConditions
 	)
-	flag.Parse()
+	flags.StringVar(dumpFrames, "dump-frame", "31,61,120", "Same as -dump-frames")
+	flags.Var(&exitAt, "exit-at-addr", "Stop headless runs when memory holds a value, as hex ADDR=VALUE such as 6000=00 (repeatable; exits 1 if never met)")
+	flags.Var(&exitAt, "exit-when", "Stop headless runs with an exit status when a condition is met: CONDITION[=>STATUS] with $6000==00 (or !=, <, <=, >, >=) or text:Passed for nametable text (repeatable; exits 1 if none is met)")
+	flags.Usage = func() { printUsage(flags) }
+	if err := parseRunFlags(flags, args, romFile); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	app.SetPortable(*portable)
+	quiet = *quietFlag && *nogui
+	var streamOut, reportOut io.Writer
+	if *frameStdout {
+		if !*nogui {
+			fmt.Fprintln(os.Stderr, "-frame-stdout is only used in headless mode (-nogui)")
+			return 2
+		}
+		// Keep every message out of the frame stream
+		streamOut = os.Stdout
+		os.Stdout = os.Stderr
+	}
+	if *jsonOut {
+		if !*nogui || *frameStdout {
+			fmt.Fprintln(os.Stderr, "-json is only used in headless mode (-nogui), and not with -frame-stdout")
+			return 2
+		}
+		// Keep every message out of the JSON
+		reportOut = os.Stdout
+		os.Stdout = os.Stderr
+	}
+	if *netplayHost != "" || *netplayJoin != "" {
+		switch {
+		case *nogui:
+			fmt.Fprintln(os.Stderr, "Netplay needs the GUI: -netplay-host and -netplay-join cannot be used with -nogui")
+			return 2
+		case *netplayHost != "" && *netplayJoin != "":
+			fmt.Fprintln(os.Stderr, "-netplay-host and -netplay-join cannot be combined")
+			return 2
+		case *romFile == "":
+			fmt.Fprintln(os.Stderr, "Netplay needs the ROM (-rom) both players play")
+			return 2
+		case *inputFile != "" || *stateFile != "" || *movieFile != "":
+			fmt.Fprintln(os.Stderr, "Netplay starts from power on: -input-script, -load-state and -record-movie cannot be used with it")
+			return 2
+		}
+	}
+	if *patchFile != "" && *romFile == "" {
+		fmt.Fprintln(os.Stderr, "-patch needs the ROM (-rom) to patch")
+		return 2
+	}
+	if *seconds != 0 {
+		if *seconds < 0 || *frames != 0 {
+			fmt.Fprintln(os.Stderr, "-seconds must be positive and cannot be combined with -frames")
+			return 2
+		}
+	}
 
 	if *help {
-		printUsage()
-		os.Exit(0)
+		printUsage(flags)
+		return 0
 	}
 
 	if *version {
 		printVersion()
-		os.Exit(0)
+		return 0
 	}
 
-	// Set up graceful shutdown
-	setupGracefulShutdown()
+	if *determinism != "" {
+		return runDeterminismCheck(*romFile, *inputFile, *frames, *determinism)
+	}
+
+	// Ctrl+C ends the run through ctx, so everything is saved and closed
+	ctx, stop := shutdownContext()
+	defer stop()
 
-	fmt.Println("🎮 gones - Go NES Emulator Starting...")
+	progressf("🎮 gones - Go NES Emulator Starting...\n")
 
 	// Determine config file path
 	configPath := *configFile
@@ -56,7 +176,7 @@ func main() {
 	if *nogui {
 		config := application.GetConfig()
 		config.Video.Backend = "headless"
-		fmt.Println("🖥️  Headless mode requested")
+		progressf("🖥️  Headless mode requested\n")
 	}
 	defer func() {
 		if err := application.Cleanup(); err != nil {
@@ -69,16 +189,93 @@ func main() {
 		config := application.GetConfig()
 		config.UpdateDebug(true, true, true)
 		application.ApplyDebugSettings()
-		fmt.Println("🐛 Debug mode enabled")
+		progressf("🐛 Debug mode enabled\n")
+	}
+
+	if *zapper {
+		application.SetZapperEnabled(true)
+		progressf("🔫 Zapper connected to port 2\n")
+	}
+
+	if *port2 != "" {
+		if err := application.SetPort2Device(*port2); err != nil {
+			log.Fatalf("Invalid -port2: %v", err)
+		}
+		progressf("🔌 Port 2: %s\n", application.GetPort2Device())
+	}
+
+	if *fourScore {
+		application.SetFourScoreEnabled(true)
+		progressf("🎮 Four Score connected (players 3 and 4 enabled)\n")
+	}
+
+	if *speed != "" {
+		value, err := app.ParseSpeed(*speed)
+		if err != nil {
+			log.Fatalf("Invalid -speed: %v", err)
+		}
+		application.SetSpeed(value)
+		progressf("⏩ Speed: %s\n", app.FormatSpeed(value))
+	}
+
+	if *noThrottle {
+		application.SetUnthrottled(true)
+		progressf("⏩ Unthrottled: no frame pacing, VSync or audio\n")
+	}
+
+	if *regionName != "" {
+		if err := application.SetRegion(*regionName); err != nil {
+			log.Fatalf("Invalid -region: %v", err)
+		}
+	}
+
+	if *ramPattern != "" {
+		if err := application.SetRAMPattern(*ramPattern); err != nil {
+			log.Fatalf("Invalid -ram-pattern: %v", err)
+		}
+	}
+
+	if *ramSeed != 0 {
+		application.SetRAMSeed(*ramSeed)
+	}
+
+	if *noCompat {
+		application.SetCompatDatabase(false)
+	}
+
+	if *codeData {
+		application.SetCodeDataLogging(true)
+		progressf("📝 Code/data logger enabled\n")
+	}
+
+	if *remoteAddr != "" {
+		application.SetRemoteListen(*remoteAddr)
+	}
+
+	if *apiAddr != "" {
+		application.SetAPIListen(*apiAddr)
+	}
+
+	if *metricsAddr != "" {
+		application.SetMetricsListen(*metricsAddr)
+	}
+
+	if *traceFile != "" {
+		application.SetTraceFile(*traceFile)
+		progressf("📜 Tracing to %s\n", *traceFile)
+	}
+
+	if *patchFile != "" {
+		application.SetPatch(*romFile, *patchFile)
 	}
 
 	// Load ROM if specified
 	if *romFile != "" {
-		fmt.Printf("📁 Loading ROM: %s\n", *romFile)
+		progressf("📁 Loading ROM: %s\n", *romFile)
 		if err := application.LoadROM(*romFile); err != nil {
 			log.Fatalf("Failed to load ROM: %v", err)
 		}
-		fmt.Println("✅ ROM loaded successfully")
+		progressf("✅ ROM loaded successfully\n")
 		
 		// Re-apply debug settings after ROM load (PPU might be recreated)
 		if *debug {
@@ -86,26 +283,233 @@ func main() {
 		}
 	}
 
+	// Seconds of the ROM's frame rate, 50 a second for PAL games
+	if *seconds != 0 {
+		*frames = int(math.Ceil(*seconds * application.FrameRate()))
+	}
+
+	if *stateFile != "" {
+		if *romFile == "" {
+			log.Fatal("-load-state needs the state's ROM (-rom)")
+		}
+		if err := application.ImportState(*stateFile); err != nil {
+			log.Fatalf("Failed to load state: %v", err)
+		}
+		progressf("💾 State loaded: %s\n", *stateFile)
+	}
+
+	status := 0
 	if *nogui {
 		// Run in headless mode (for testing or automation)
-		fmt.Println("Running in headless mode...")
-		if *romFile == "" {
-			log.Fatal("ROM file required for headless mode")
+		progressf("Running in headless mode...\n")
+		config := application.GetConfig()
+		serving := config.Debug.Remote.Enabled || config.API.Enabled || config.Metrics.Enabled
+		if *romFile == "" && !config.API.Enabled {
+			log.Fatal("ROM file required for headless mode (or -api to load one later)")
+		}
+		if serving {
+			// Run until a client quits (or -frames), paced like the GUI
+			if *inputFile != "" || *recordFile != "" || *goldenFile != "" || *jsonOut {
+				fmt.Println("⚠️  -input-script, -record, -golden and -json are not used with the debug server, control API or metrics")
+			}
+			if *scrollFile != "" {
+				if err := application.StartScrollLog(*scrollFile); err != nil {
+					log.Fatalf("Failed to start the scroll log: %v", err)
+				}
+				progressf("📈 Logging scroll to %s\n", *scrollFile)
+			}
+			if err := application.Serve(ctx, *frames); err != nil {
+				log.Fatalf("Serving clients failed: %v", err)
+			}
+			fmt.Println("👋 Emulator shutting down...")
+			return 0
+		}
+		var script *input.Script
+		if *inputFile != "" {
+			script, err = input.LoadScript(*inputFile)
+			if err != nil {
+				log.Fatalf("Failed to load input script: %v", err)
+			}
+			if err := application.LoadMovieState(script); err != nil {
+				log.Fatalf("Failed to start the input script: %v", err)
+			}
+			progressf("🎮 Input script: %s (%d frames)\n", *inputFile, script.Length())
+			if (script.UsesPlayer(2) || script.UsesPlayer(3)) && !application.IsFourScoreEnabled() {
+				fmt.Println("⚠️  Input script drives players 3/4 but the Four Score is off (use -fourscore)")
+			}
+		}
+		var recorder *record.Recorder
+		if *recordFile != "" {
+			recorder, err = record.Create(*recordFile, record.Options{
+				FrameRate:  application.FrameRate(),
+				SampleRate: application.GetBus().APU.GetSampleRate(),
+			})
+			if err != nil {
+				log.Fatalf("Failed to start recording: %v", err)
+			}
+			progressf("🎬 Recording to %s\n", *recordFile)
+		}
+		var scrollLog *scroll.Log
+		if *scrollFile != "" {
+			scrollLog, err = scroll.Create(*scrollFile)
+			if err != nil {
+				log.Fatalf("Failed to start the scroll log: %v", err)
+			}
+			application.GetBus().PPU.SetScrollTracking(true)
+			progressf("📈 Logging scroll to %s\n", *scrollFile)
+		}
+		dumpAll := strings.EqualFold(strings.TrimSpace(*dumpFrames), "all")
+		var dumps []int
+		if !dumpAll {
+			dumps, err = parseFrameList(*dumpFrames)
+			if err != nil {
+				log.Fatalf("Invalid -dump-frames: %v", err)
+			}
+		}
+		options := headlessOptions{
+			rom:        *romFile,
+			script:     script,
+			recorder:   recorder,
+			scrollLog:  scrollLog,
+			frames:     *frames,
+			dumpFrames: dumps,
+			dumpAll:    dumpAll,
+			exitConditions: exitAt,
 		}
-		runHeadlessMode(application)
+		if dumpAll || len(dumps) > 0 {
+			options.dumper, err = newFrameDumper(*frameFormat, *frameDir)
+			if err != nil {
+				log.Fatalf("Invalid frame dump options: %v", err)
+			}
+		}
+		if streamOut != nil {
+			options.stream = newFrameStream(streamOut)
+		}
+		if *goldenFile != "" {
+			// Golden runs only dump frames when asked to
+			explicit := flagGiven(flags, "dump-frames") || flagGiven(flags, "dump-frame")
+			if !explicit {
+				options.dumpFrames = nil
+				options.dumper = nil
+			}
+			options.goldenPath = *goldenFile
+			options.updateGolden = *updateGolden
+			golden, err := verify.LoadGolden(*goldenFile)
+			switch {
+			case *updateGolden && (explicit || err != nil):
+				options.hashFrames = dumps
+			case err != nil:
+				log.Fatalf("%v (create it with -update-golden)", err)
+			default:
+				options.golden = golden
+				options.hashFrames = golden.FrameNumbers()
+			}
+		} else if *updateGolden {
+			log.Fatal("-update-golden needs the golden file to write (-golden)")
+		}
+		if reportOut != nil {
+			options.report = newRunReport(*romFile, reportOut)
+			options.report.RAMSeed = application.RAMSeed()
+		}
+		status = runHeadlessMode(ctx, application, options)
 	} else {
+		if *inputFile != "" {
+			if *romFile == "" {
+				log.Fatal("ROM file required for -input-script")
+			}
+			script, err := input.LoadScript(*inputFile)
+			if err != nil {
+				log.Fatalf("Failed to load input script: %v", err)
+			}
+			if err := application.SetMovie(script); err != nil {
+				log.Fatalf("Failed to play the movie: %v", err)
+			}
+			fmt.Printf("🎬 Playing movie %s (%d frames, Alt+F12 to branch)\n", *inputFile, script.Length())
+		}
+		if *movieFile != "" {
+			if *romFile == "" || *inputFile != "" {
+				log.Fatal("-record-movie needs a ROM (-rom) and no -input-script (branch the movie with Alt+F12 instead)")
+			}
+			if err := application.StartMovieRecording(*movieFile, *stateFile == ""); err != nil {
+				log.Fatalf("Failed to record the movie: %v", err)
+			}
+			fmt.Printf("🎬 Recording a movie to %s (Alt+F12 to save)\n", *movieFile)
+		}
+		if *frames != 0 {
+			fmt.Println("⚠️  -frames is only used in headless mode (-nogui)")
+		}
+		if *goldenFile != "" {
+			fmt.Println("⚠️  -golden is only used in headless mode (-nogui)")
+		}
+		if *recordFile != "" {
+			if *romFile == "" {
+				log.Fatal("ROM file required for -record")
+			}
+			if err := application.StartRecording(*recordFile); err != nil {
+				log.Fatalf("Failed to start recording: %v", err)
+			}
+			fmt.Printf("🎬 Recording to %s (Shift+F12 to stop)\n", *recordFile)
+		}
+		if *scrollFile != "" {
+			if *romFile == "" {
+				log.Fatal("ROM file required for -scroll-log")
+			}
+			if err := application.StartScrollLog(*scrollFile); err != nil {
+				log.Fatalf("Failed to start the scroll log: %v", err)
+			}
+			fmt.Printf("📈 Logging scroll to %s\n", *scrollFile)
+		}
+		if err := startNetplay(application, *netplayHost, *netplayJoin, *netplayDelay, *netplayRollback); err != nil {
+			log.Fatalf("Netplay failed: %v", err)
+		}
+		if *secondROM != "" {
+			if _, err := application.OpenGameInstance(*secondROM); err != nil {
+				log.Fatalf("Failed to open -second-rom: %v", err)
+			}
+		}
+		if *nametables {
+			if _, err := application.OpenNametableViewer(); err != nil {
+				log.Fatalf("Failed to open the nametable viewer: %v", err)
+			}
+		}
 		// Run full GUI application
 		fmt.Println("🖥️  Starting GUI mode...")
-		if err := runGUIMode(application); err != nil {
+		if err := runGUIMode(ctx, application); err != nil {
 			log.Fatalf("GUI mode failed: %v", err)
 		}
 	}
 
-	fmt.Println("👋 Emulator shutting down...")
+	progressf("👋 Emulator shutting down...\n")
+	return status
 }
 
-// runGUIMode runs the full GUI application
-func runGUIMode(application *app.Application) error {
+// parseRunFlags parses the run flags, which may come before or after a ROM
+// given as an argument instead of with -rom
+func parseRunFlags(flags *flag.FlagSet, args []string, romFile *string) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return nil
+	}
+	if *romFile != "" {
+		fmt.Fprintf(flags.Output(), "ROM given twice: -rom %s and %s\n", *romFile, flags.Arg(0))
+		return errors.New("ROM given twice")
+	}
+	*romFile = flags.Arg(0)
+	if err := flags.Parse(flags.Args()[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.Output(), "Unexpected arguments: %s\n", strings.Join(flags.Args(), " "))
+		return errors.New("unexpected arguments")
+	}
+	return nil
+}
+
+// runGUIMode runs the full GUI application until it is closed or ctx is
+// cancelled
+func runGUIMode(ctx context.Context, application *app.Application) error {
 	fmt.Println("🚀 Initializing GUI application...")
 
 	// Display startup information
@@ -123,7 +527,7 @@ func runGUIMode(application *app.Application) error {
 
 	// Start the application
 	fmt.Println("🎯 Starting main application loop...")
-	if err := application.Run(); err != nil {
+	if err := application.Run(ctx); err != nil {
 		return fmt.Errorf("application run failed: %v", err)
 	}
 
@@ -136,76 +540,292 @@ func runGUIMode(application *app.Application) error {
 	return nil
 }
 
-// runHeadlessMode runs the emulator without GUI (for testing/automation)
-func runHeadlessMode(application *app.Application) {
-	fmt.Println("Running emulator in headless mode...")
-	fmt.Println("実行中: 120フレーム（約2秒）でフレームバッファをダンプします")
+// headlessOptions configures a headless run
+type headlessOptions struct {
+	rom          string
+	script       *input.Script    // Controller states applied before each frame
+	recorder     *record.Recorder // Records every frame and its audio
+	scrollLog    *scroll.Log      // Logs the scroll of every frame's scanlines
+	frames       int              // Run length, if positive
+	dumpFrames   []int            // Frames saved as frame_NNN.<format>
+	dumpAll      bool             // Save every frame, as an image sequence
+	dumper       *frameDumper     // Writes the saved frames, nil when none are
+	stream       *frameStream     // Streams every frame to stdout (-frame-stdout)
+	hashFrames   []int            // Frames hashed for the golden file
+	golden       *verify.Golden   // Hashes to compare with, nil when updating
+	goldenPath   string
+	updateGolden bool
+	exitConditions exitConditions // Stop when any is met
+	report       *runReport       // JSON result, nil without -json
+}
 
-	// ヘッドレスモードで実際にエミュレーションを実行
+// runHeadlessMode runs the emulator without GUI (for testing/automation) and
+// returns the exit status. The run lasts 120 frames, at least until the
+// script ends and the last frame dumped or hashed, unless frames is given,
+// and stops early when an exit condition is met. Frames count from 1.
+func runHeadlessMode(ctx context.Context, application *app.Application, options headlessOptions) int {
+	script, recorder, frames, report := options.script, options.recorder, options.frames, options.report
 	bus := application.GetBus()
 	if bus == nil {
-		fmt.Println("❌ バスが初期化されていません")
-		return
+		err := errors.New("emulator bus is not initialized")
+		report.finish(0, 1, err)
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
 	}
 
-	// 120フレーム実行（約2秒間）
+	// Write a crash report instead of a bare Go panic
+	ran := 0
+	defer func() {
+		if r := recover(); r != nil {
+			err := application.ReportCrash(fmt.Sprintf("panic: %v", r), debug.Stack())
+			report.finish(ran, 1, err)
+			log.Fatalf("❌ %v", err)
+		}
+	}()
+
 	targetFrames := 120
+	if script != nil && script.Length() > targetFrames {
+		targetFrames = script.Length()
+	}
+	for _, list := range [][]int{options.dumpFrames, options.hashFrames} {
+		if len(list) > 0 && list[len(list)-1] > targetFrames {
+			targetFrames = list[len(list)-1]
+		}
+	}
+	if frames > 0 {
+		targetFrames = frames
+	}
+	progressf("Running %d frames (%.1f seconds)...\n", targetFrames, float64(targetFrames)/bus.GetFrameRate())
+
+	hashes := make(map[int]string)
+	var crashed error
+	var exitMet *exitCondition
+	interrupted := false
 	for frame := 0; frame < targetFrames; frame++ {
-		// 1フレーム分のサイクル実行
-		for cycles := 0; cycles < 29780; cycles++ {
-			bus.Step()
+		if ctx.Err() != nil {
+			interrupted = true
+			break
 		}
 
-		// 特定フレームでフレームバッファを出力
-		if frame == 30 || frame == 60 || frame == 119 {
-			fmt.Printf("📸 フレーム %d のスクリーンショット作成中...\n", frame+1)
-			saveFrameBufferAsPPM(bus.PPU.GetFrameBuffer(), fmt.Sprintf("frame_%03d.ppm", frame+1))
-			analyzeFrameBuffer(bus.PPU.GetFrameBuffer(), frame+1)
+		// Apply scripted controller states for this frame
+		if script != nil {
+			for player, buttons := range script.Buttons(frame) {
+				bus.SetControllerButtons(player+1, buttons)
+			}
+		}
+
+		bus.Run(1)
+		ran = frame + 1
+		if err := application.CheckJam(); err != nil {
+			crashed = err
+			break
+		}
+
+		// Record the finished frame
+		if recorder != nil {
+			err := recorder.AddFrame(bus.GetFrameBuffer())
+			if err == nil {
+				err = recorder.AddAudio(bus.GetAudioSamples())
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Recording stopped: %v\n", err)
+				recorder.Close()
+				recorder = nil
+			}
+		}
+		if options.scrollLog != nil {
+			if err := options.scrollLog.AddFrame(bus.PPU.GetFrameCount(), bus.PPU.ScrollLines()); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Scroll log stopped: %v\n", err)
+				options.scrollLog.Close()
+				options.scrollLog = nil
+			}
+		}
+
+		if options.dumper != nil && (options.dumpAll || containsFrame(options.dumpFrames, frame+1)) {
+			if path, err := options.dumper.dump(bus.GetFrameBuffer(), frame+1); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			} else if !options.dumpAll {
+				progressf("📸 Frame saved: %s\n", path)
+				analyzeFrameBuffer(bus.PPU.GetFrameBuffer(), frame+1)
+			}
+		}
+		if options.stream != nil {
+			if err := options.stream.write(bus.GetFrameBuffer()); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Frame stream stopped: %v\n", err)
+				options.stream = nil
+			}
+		}
+		if report != nil || containsFrame(options.hashFrames, frame+1) {
+			hash := verify.ScreenHash(bus.GetFrameBuffer())
+			if containsFrame(options.hashFrames, frame+1) {
+				hashes[frame+1] = hash
+			}
+			if report != nil {
+				report.addFrame(frame+1, hash)
+			}
+		}
+
+		nametableText := func() string { return verify.NametableText(bus.PPU.GetMemory()) }
+		if condition, ok := options.exitConditions.met(bus.Memory.Peek, nametableText); ok {
+			exitMet = &condition
+			break
 		}
 
-		// 進捗表示
 		if frame%30 == 29 {
-			fmt.Printf("⏱️  %d/%d フレーム完了\n", frame+1, targetFrames)
+			progressf("⏱️  %d/%d frames done\n", frame+1, targetFrames)
+		}
+	}
+
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Recording failed: %v\n", err)
+		} else {
+			progressf("🎬 Recording saved: %s (%d frames)\n", recorder.Path(), recorder.Frames())
+		}
+	}
+	if scrollLog := options.scrollLog; scrollLog != nil {
+		if err := scrollLog.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Scroll log failed: %v\n", err)
+		} else {
+			progressf("📈 Scroll log saved: %s (%d frames)\n", scrollLog.Path(), scrollLog.Frames())
 		}
 	}
+	if crashed != nil {
+		report.finish(ran, 1, crashed)
+		log.Fatalf("❌ %v", crashed)
+	}
 
-	fmt.Println("✅ ヘッドレスモード完了")
-	fmt.Println("📁 生成されたファイル:")
-	fmt.Println("   - frame_031.ppm (フレーム31のスクリーンショット)")
-	fmt.Println("   - frame_061.ppm (フレーム61のスクリーンショット)")
-	fmt.Println("   - frame_120.ppm (フレーム120のスクリーンショット)")
-	fmt.Println("💡 PPMファイルは画像ビューアで開くか、ImageMagick等で変換できます")
+	if options.stream != nil {
+		if err := options.stream.close(); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Frame stream failed: %v\n", err)
+		}
+	}
+	if interrupted {
+		err := fmt.Errorf("interrupted after %d frames", ran)
+		report.finish(ran, exitInterrupted, err)
+		fmt.Fprintf(os.Stderr, "🛑 Headless run %v\n", err)
+		return exitInterrupted
+	}
+
+	progressf("✅ Headless run finished after %d frames\n", ran)
+	if options.dumper != nil && len(options.dumper.saved) > 0 {
+		saved := options.dumper.saved
+		if report != nil {
+			report.SavedFrames = saved
+		}
+		if options.dumpAll {
+			progressf("📁 %d frames saved: %s to %s\n", len(saved), saved[0], saved[len(saved)-1])
+		} else {
+			progressf("📁 Frames saved:\n")
+			for _, path := range saved {
+				progressf("   - %s\n", path)
+			}
+		}
+	}
+
+	if options.goldenPath != "" {
+		if report != nil {
+			report.Golden = options.goldenPath
+		}
+		if err := checkGolden(options, hashes); err != nil {
+			report.finish(ran, 1, err)
+			log.Fatalf("❌ %v", err)
+		}
+	}
+
+	status := 0
+	if len(options.exitConditions) > 0 {
+		if exitMet == nil {
+			fmt.Fprintf(os.Stderr, "⌛ No exit condition (%s) met in %d frames\n", options.exitConditions, ran)
+			status = 1
+		} else {
+			fmt.Printf("🏁 Exit condition %s met at frame %d\n", exitMet, ran)
+			status = exitMet.code
+			if report != nil {
+				report.ExitCondition = exitMet.String()
+			}
+		}
+	}
+	report.finish(ran, status, nil)
+	return status
 }
 
-// saveFrameBufferAsPPM saves the frame buffer as a PPM image file
-func saveFrameBufferAsPPM(frameBuffer [256 * 240]uint32, filename string) {
-	file, err := os.Create(filename)
-	if err != nil {
-		fmt.Printf("❌ ファイル作成エラー %s: %v\n", filename, err)
-		return
+// checkGolden compares the frame hashes of a headless run with the golden
+// file, or writes them to it with -update-golden
+func checkGolden(options headlessOptions, hashes map[int]string) error {
+	if options.updateGolden {
+		golden := &verify.Golden{ROM: filepath.Base(options.rom), Frames: hashes}
+		if err := golden.Save(options.goldenPath); err != nil {
+			return err
+		}
+		fmt.Printf("🏅 Golden file written: %s (%d frames)\n", options.goldenPath, len(hashes))
+		return nil
 	}
-	defer file.Close()
 
-	// PPM header
-	fmt.Fprintf(file, "P3\n256 240\n255\n")
+	golden := options.golden
+	if golden.ROM != "" && golden.ROM != filepath.Base(options.rom) {
+		fmt.Printf("⚠️  Golden file %s was recorded with %s\n", options.goldenPath, golden.ROM)
+	}
+	mismatches := golden.Compare(hashes)
+	if len(mismatches) == 0 {
+		fmt.Printf("🏅 %d frames match %s\n", len(golden.Frames), options.goldenPath)
+		return nil
+	}
+	for _, m := range mismatches {
+		fmt.Printf("   ❌ %s\n", m)
+	}
+	return fmt.Errorf("%d of %d frames differ from %s (check them with -dump-frames, then -update-golden)",
+		len(mismatches), len(golden.Frames), options.goldenPath)
+}
+
+// parseFrameList parses a comma separated list of frame numbers, returning
+// them sorted
+func parseFrameList(list string) ([]int, error) {
+	var frames []int
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		frame, err := strconv.Atoi(field)
+		if err != nil || frame < 1 {
+			return nil, fmt.Errorf("%q is not a frame number (frames count from 1)", field)
+		}
+		if !containsFrame(frames, frame) {
+			frames = append(frames, frame)
+		}
+	}
+	sort.Ints(frames)
+	return frames, nil
+}
 
-	// RGB data
-	for y := 0; y < 240; y++ {
-		for x := 0; x < 256; x++ {
-			pixel := frameBuffer[y*256+x]
-			r := (pixel >> 16) & 0xFF
-			g := (pixel >> 8) & 0xFF
-			b := pixel & 0xFF
-			fmt.Fprintf(file, "%d %d %d ", r, g, b)
+// containsFrame reports whether frame is in frames
+func containsFrame(frames []int, frame int) bool {
+	for _, f := range frames {
+		if f == frame {
+			return true
 		}
-		fmt.Fprintf(file, "\n")
 	}
+	return false
+}
 
-	fmt.Printf("✅ %s 保存完了\n", filename)
+// flagGiven reports whether a flag was set on the command line
+func flagGiven(flags *flag.FlagSet, name string) bool {
+	given := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
 }
 
-// analyzeFrameBuffer analyzes the frame buffer content
+// analyzeFrameBuffer prints how many colors a frame has and its most common
+// ones
 func analyzeFrameBuffer(frameBuffer [256 * 240]uint32, frame int) {
+	if quiet {
+		return
+	}
 	colorCounts := make(map[uint32]int)
 	for _, pixel := range frameBuffer {
 		colorCounts[pixel]++
@@ -218,36 +838,67 @@ func analyzeFrameBuffer(frameBuffer [256 * 240]uint32, frame int) {
 		}
 	}
 
-	fmt.Printf("   フレーム %d: %d個の異なる色, %d個の非黒ピクセル (%.1f%%)\n",
+	fmt.Printf("   Frame %d: %d colors, %d non-black pixels (%.1f%%)\n",
 		frame, len(colorCounts), nonBlackPixels,
 		float64(nonBlackPixels)/float64(256*240)*100)
 
-	// 主要な色を表示
+	// Most common colors
 	if len(colorCounts) > 1 {
-		fmt.Printf("   主要色: ")
-		count := 0
-		for color, pixels := range colorCounts {
-			if count >= 3 {
-				break
-			}
-			percentage := float64(pixels) / float64(256*240) * 100
-			fmt.Printf("0x%06X(%.1f%%) ", color, percentage)
-			count++
+		colors := make([]uint32, 0, len(colorCounts))
+		for color := range colorCounts {
+			colors = append(colors, color)
+		}
+		sort.Slice(colors, func(i, j int) bool { return colorCounts[colors[i]] > colorCounts[colors[j]] })
+		fmt.Printf("   Main colors: ")
+		for _, color := range colors[:min(3, len(colors))] {
+			fmt.Printf("0x%06X(%.1f%%) ", color, float64(colorCounts[color])/float64(256*240)*100)
 		}
 		fmt.Println()
 	}
 }
 
-// setupGracefulShutdown sets up signal handling for graceful shutdown
-func setupGracefulShutdown() {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+// exitInterrupted is the exit status of a headless run cut short by Ctrl+C,
+// as shells report a process killed by SIGINT
+const exitInterrupted = 130
 
+// shutdownContext returns a context cancelled by the first interrupt (Ctrl+C)
+// or SIGTERM, for the run to end and clean up: battery saves flushed,
+// recordings finished, servers closed. A second interrupt exits at once, in
+// case cleaning up hangs. stop releases the signals.
+func shutdownContext() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		<-c
-		fmt.Println("\n🛑 Interrupt received, shutting down gracefully...")
-		os.Exit(0)
+		select {
+		case _, ok := <-signals:
+			if !ok {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+		fmt.Fprintln(os.Stderr, "\n🛑 Interrupt received, shutting down gracefully (again to quit now)...")
+		cancel()
+		if _, ok := <-signals; ok {
+			os.Exit(exitInterrupted)
+		}
 	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+		close(signals)
+	}
+}
+
+// quiet silences progress messages (-quiet in headless mode)
+var quiet bool
+
+// progressf prints a progress message unless quiet
+func progressf(format string, a ...any) {
+	if !quiet {
+		fmt.Printf(format, a...)
+	}
 }
 
 // enabledString returns "enabled" or "disabled" based on boolean value
@@ -262,7 +913,7 @@ func printVersion() {
 	version.PrintBuildInfo()
 }
 
-func printUsage() {
+func printUsage(flags *flag.FlagSet) {
 	fmt.Println("gones - Go NES Emulator")
 	fmt.Println()
 	fmt.Println("DESCRIPTION:")
@@ -272,18 +923,58 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("USAGE:")
 	fmt.Println("  gones [options]                    # Start GUI mode without ROM")
-	fmt.Println("  gones -rom <file> [options]        # Start with ROM loaded")
+	fmt.Println("  gones [options] <file>             # Start with ROM loaded (same as gones run)")
 	fmt.Println("  gones -nogui -rom <file> [options] # Run headless mode")
+	fmt.Println("  gones <command> [arguments]        # Run a command (gones help <command>)")
 	fmt.Println()
-	fmt.Println("OPTIONS:")
-	flag.PrintDefaults()
+	fmt.Println("COMMANDS:")
+	printCommands()
+	fmt.Println()
+	fmt.Println("OPTIONS (run):")
+	flags.SetOutput(os.Stdout) // With the rest of the usage; parse errors are already out
+	flags.PrintDefaults()
 	fmt.Println()
 	fmt.Println("EXAMPLES:")
 	fmt.Println("  gones                              # Start GUI, load ROM from menu")
-	fmt.Println("  gones -rom game.nes                # Start with ROM loaded")
+	fmt.Println("  gones game.nes                     # Start with ROM loaded")
+	fmt.Println("  gones game.zip                     # Load the first .nes file of a zip archive")
+	fmt.Println("  gones -rom game.nes -patch translation.ips # Apply an IPS/BPS patch as the ROM loads")
+	fmt.Println("  gones info game.nes                # Show the ROM's mapper, sizes and hash")
 	fmt.Println("  gones -rom game.nes -debug         # Start with debug info enabled")
 	fmt.Println("  gones -config custom.json          # Use custom configuration")
 	fmt.Println("  gones -nogui -rom test.nes         # Run headless for testing")
+	fmt.Println("  gones -nogui -rom test.nes -input-script inputs.json # Drive input from a script")
+	fmt.Println("  gones -rom duckhunt.nes -zapper    # Play with the Zapper")
+	fmt.Println("  gones -rom gauntlet2.nes -fourscore # Four player game")
+	fmt.Println("  gones -rom arkanoid.nes -port2 arkanoid # Play with the Vaus paddle")
+	fmt.Println("  gones -rom game.nes -nametable-viewer # Watch the nametables in a second window (SDL2)")
+	fmt.Println("  gones -rom smb.nes -second-rom zelda.nes # Two games side by side (SDL2)")
+	fmt.Println("  gones -nogui -rom game.nes -record video.gif -frames 600 # Record 10 seconds")
+	fmt.Println("  gones -nogui -rom game.nes -cdl -frames 3600 # Log a minute of code/data use")
+	fmt.Println("  gones -nogui -rom game.nes -trace cpu.log -frames 60 # Trace the first second")
+	fmt.Println("  gones -nogui -rom game.nes -scroll-log scroll.csv -frames 60 # Log a second of scanline scroll")
+	fmt.Println("  gones -nogui -quiet -seconds 30 -exit-at-addr 6000=00 test.nes # Exit 0 once $6000 is 0")
+	fmt.Println("  gones -nogui -quiet -seconds 30 -exit-when text:Passed -exit-when 'text:Failed=>3' test.nes # Exit by screen text")
+	fmt.Println("  gones -nogui -dump-frames all -frame-format png -frame-dir frames game.nes # Image sequence")
+	fmt.Println("  gones -nogui -quiet -json -seconds 5 game.nes > run.json # Frame hashes and results for CI")
+	fmt.Println("  gones -nogui -frame-stdout -seconds 10 game.nes | ffmpeg -f rawvideo -pix_fmt rgba -s 256x240 -r 60.0988 -i - out.mp4")
+	fmt.Println("  gones -nogui -rom game.nes -debug-server 127.0.0.1:6502 # Run until a debugger quits")
+	fmt.Println("  gones -nogui -api 127.0.0.1:6580 # Let bots load ROMs and play over HTTP")
+	fmt.Println("  gones -rom game.nes -api 127.0.0.1:6580 -no-throttle # Let bots play as fast as the host can")
+	fmt.Println("  gones -nogui -rom game.nes -metrics 0.0.0.0:9650 # Monitor a long run with Prometheus")
+	fmt.Println("  gones -rom game.nes -load-state crashes/game_<time>/state.save # Resume from a crash report")
+	fmt.Println("  gones verify roms/cpu_instrs roms/nestest.nes # Run test ROMs, exit 1 if any fails")
+	fmt.Println("  gones verify -json roms/ > results.json # The same, as JSON for dashboards")
+	fmt.Println("  gones -nogui -rom game.nes -golden game.golden.json -update-golden -dump-frames 60,300 # Record frame hashes")
+	fmt.Println("  gones -nogui -rom game.nes -golden game.golden.json # Check rendering against them in CI")
+	fmt.Println("  gones -rom game.nes -input-script tas.json -determinism parallel # Check runs replay identically")
+	fmt.Println("  gones bench -frames 6000 -cpuprofile cpu.prof game.nes # Profile the emulator core")
+	fmt.Println("  gones batch -frames 900 roms/       # Find the ROMs that crash or show nothing")
+	fmt.Println("  gones record -frames 600 game.nes video.mp4 # Record 10 seconds headless")
+	fmt.Println("  gones play-movie game.nes tas.json # Watch a movie, then take over")
+	fmt.Println("  gones netplay host game.nes        # Host a two-player netplay session")
+	fmt.Println("  gones netplay join 192.168.1.20 game.nes # Join it as player 2")
+	fmt.Println("  gones nsf -track 3 -wav track3.wav music.nsf # Render an NSF track to WAV")
 	fmt.Println()
 	fmt.Println("CONTROLS (Default):")
 	fmt.Println("  Player 1:")
@@ -293,18 +984,73 @@ func printUsage() {
 	fmt.Println("    Enter             - Start")
 	fmt.Println("    Space             - Select")
 	fmt.Println()
-	fmt.Println("  Special Keys:")
-	fmt.Println("    Escape (2x)       - Quit (double-tap within 3 seconds)")
-	fmt.Println("    F1-F10            - Save States")
-	fmt.Println("    Shift+F1-F10      - Load States")
+	fmt.Println("  Player 3 (-fourscore):")
+	fmt.Println("    Numpad 8/2/4/6    - D-Pad")
+	fmt.Println("    Numpad 3 / 1      - A / B Button")
+	fmt.Println("    Numpad Enter / +  - Start / Select")
+	fmt.Println()
+	fmt.Println("  Gamepads (hot-plug, assigned to Players 1-4 in connection order):")
+	fmt.Println("    D-Pad / Left Stick - D-Pad")
+	fmt.Println("    East / South       - A / B Button")
+	fmt.Println("    Start / Back       - Start / Select")
+	fmt.Println()
+	fmt.Println("  Zapper (-zapper, port 2):")
+	fmt.Println("    Mouse              - Aim")
+	fmt.Println("    Left Click         - Fire")
+	fmt.Println("    Right Click        - Fire off screen")
+	fmt.Println()
+	fmt.Println("  Arkanoid paddle (-port2 arkanoid):")
+	fmt.Println("    Mouse X            - Turn knob")
+	fmt.Println("    Left Click         - Button")
+	fmt.Println()
+	fmt.Println("  Special Keys (defaults; rebind them in the hotkeys config section or the HOTKEYS menu):")
+	fmt.Println("    Escape            - Pause menu (load ROM, save/load state, settings, quit)")
+	fmt.Println("    P                 - Pause/resume")
+	fmt.Println("    Ctrl+R            - Reset (the console's reset button; RAM is kept)")
+	fmt.Println("    Ctrl+Shift+R      - Power cycle (RAM starts over from emulation.ram_pattern)")
+	fmt.Println("    F1-F10            - Save State (opens slot picker, press again to confirm)")
+	fmt.Println("    Shift+F1-F10      - Load State (opens slot picker, press again to confirm)")
+	fmt.Println("    Ctrl+F1-F5        - Window size 1x-5x (saved to window.width/height)")
+	fmt.Println("    Ctrl+F6           - Memory viewer (A edit, B bookmark, Select freeze, F5 run, F10/F11 step)")
+	fmt.Println("    Ctrl+F7           - RAM search (find the address of lives, health, ...)")
+	fmt.Println("    Ctrl+F8           - Event viewer (register writes by scanline/cycle, Select filters)")
+	fmt.Println("    Ctrl+F9           - Start/stop the CPU trace logger")
+	fmt.Println("    Ctrl+F10          - Cycle layers drawn (background only, sprites only, each sprite palette)")
+	fmt.Println("    Alt+F8            - Graph the scroll of each scanline (see also -scroll-log)")
+	fmt.Println("    Alt+F9            - Performance overlay (FPS, frame times, audio queue, A/V drift)")
+	fmt.Println("    Alt+F10           - Audio visualizer (an oscilloscope of each sound channel)")
 	fmt.Println("    F11               - Toggle Fullscreen")
-	fmt.Println("    F12               - Screenshot")
+	fmt.Println("    Shift+F11         - Cycle upscaler (none, scale2x/3x, hq2x, xbrz2x/3x)")
+	fmt.Println("    Ctrl+F11          - Cycle aspect mode (original, integer, 8:7, 4:3, 16:9, fill)")
+	fmt.Println("    F12               - Screenshot (PNG, see video.raw_screenshots)")
+	fmt.Println("    Shift+F12         - Start/stop recording (see video.record_format)")
+	fmt.Println("    Ctrl+F12          - Save the last seconds of gameplay (video.replay_seconds)")
+	fmt.Println("    Alt+F12           - Record an input movie from here, branch the movie playing, or save it")
+	fmt.Println("    Tab (hold)        - Fast-forward (emulation.fast_forward_speed, audio.fast_forward)")
+	fmt.Println("    M (hold)          - Blow into the Famicom microphone (or input.microphone_threshold)")
+	fmt.Println("    Minus / Equal     - Slower / faster (0.25x, 0.5x, 1x, 2x, 4x, max)")
 	fmt.Println()
+	paths := app.NewConfig().Paths
 	fmt.Println("CONFIGURATION:")
-	fmt.Println("  Config file: ./config/gones.json")
-	fmt.Println("  ROMs:        ./roms/")
-	fmt.Println("  Save States: ./states/")
-	fmt.Println("  Screenshots: ./screenshots/")
+	fmt.Println("  Config file: " + app.GetDefaultConfigPath())
+	fmt.Println("  ROMs:        " + paths.ROMs)
+	fmt.Println("  Save States: " + paths.SaveStates)
+	fmt.Println("  Screenshots: " + paths.Screenshots)
+	fmt.Println("  Recordings:  " + paths.Recordings)
+	fmt.Println("  (-portable, or a config directory next to the executable, keeps all of")
+	fmt.Println("  them next to the executable; the \"paths\" section moves them)")
+	fmt.Println("  Controls:    \"input\" section (comma separated keys per button,")
+	fmt.Println("               \"game_bindings\" for per-game overrides)")
+	fmt.Println("  Game profiles: <config dir>/games/<rom sha256>.json (bindings, autofire,")
+	fmt.Println("               port2_device, four_score), loaded with the ROM")
+	fmt.Println()
+	fmt.Println("INPUT SCRIPTS (-input-script, gones play-movie):")
+	fmt.Println("  JSON: [{\"frame\": 0, \"p1\": \"Start\"}, {\"frame\": 5, \"p1\": \"\"}, {\"frame\": 60, \"p1\": \"Right+A\"}]")
+	fmt.Println("  CSV:  frame,p1,p2,p3,p4 rows, e.g. 60,Right+A,  or  60,R......A,")
+	fmt.Println("  Each entry holds until the next one; states are button names joined")
+	fmt.Println("  by '+' or FM2-style RLDUTSBA masks")
+	fmt.Println("  JSON movies can start from a savestate: {\"rom\": <sha256>, \"state\": <base64>, \"frames\": [...]},")
+	fmt.Println("  as Alt+F12 and -record-movie with -load-state record them")
 	fmt.Println()
 	fmt.Println("SUPPORTED FORMATS:")
 	fmt.Println("  - iNES (.nes)")
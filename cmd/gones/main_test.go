@@ -1,8 +1,135 @@
 package main
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
 
 func TestMain(t *testing.T) {
 	// Simple test to verify the package builds
 	t.Log("gones main package test")
 }
+
+func TestParseRunFlags(t *testing.T) {
+	tests := []struct {
+		args    []string
+		rom     string
+		nogui   bool
+		wantErr bool
+	}{
+		{args: []string{"game.nes"}, rom: "game.nes"},
+		{args: []string{"-nogui", "game.nes"}, rom: "game.nes", nogui: true},
+		{args: []string{"game.nes", "-nogui"}, rom: "game.nes", nogui: true},
+		{args: []string{"-rom", "game.nes", "-nogui"}, rom: "game.nes", nogui: true},
+		{args: []string{"-rom", "a.nes", "b.nes"}, wantErr: true},
+		{args: []string{"a.nes", "b.nes"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		flags := flag.NewFlagSet("run", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		rom := flags.String("rom", "", "")
+		nogui := flags.Bool("nogui", false, "")
+		err := parseRunFlags(flags, tt.args, rom)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: err = %v, want error %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (*rom != tt.rom || *nogui != tt.nogui) {
+			t.Errorf("%v: rom %q nogui %v, want %q %v", tt.args, *rom, *nogui, tt.rom, tt.nogui)
+		}
+	}
+}
+
+func TestFindCommand(t *testing.T) {
+	for _, name := range []string{"run", "info", "verify", "bench", "batch", "record", "play-movie", "nsf", "help"} {
+		if findCommand(name) == nil {
+			t.Errorf("no %s command", name)
+		}
+	}
+	if cmd := findCommand("game.nes"); cmd != nil {
+		t.Errorf("game.nes found as the %s command", cmd.name)
+	}
+}
+
+func TestParseExitCondition(t *testing.T) {
+	tests := map[string]exitCondition{
+		"6000=00":         {address: 0x6000, op: "==", value: 0x00},
+		"$0010==$42":      {address: 0x0010, op: "==", value: 0x42},
+		"0x7ff=0x80":      {address: 0x07FF, op: "==", value: 0x80},
+		" 10 = FF ":       {address: 0x0010, op: "==", value: 0xFF},
+		"$6000!=80=>2":    {address: 0x6000, op: "!=", value: 0x80, code: 2},
+		"10<=3 => 4":      {address: 0x0010, op: "<=", value: 0x03, code: 4},
+		"10>3":            {address: 0x0010, op: ">", value: 0x03},
+		"text:Passed":     {text: "Passed"},
+		"text:Failed=>1":  {text: "Failed", code: 1},
+		"text:a == b=>10": {text: "a == b", code: 10},
+	}
+	for s, want := range tests {
+		got, err := parseExitCondition(s)
+		if err != nil || got != want {
+			t.Errorf("parseExitCondition(%q) = %+v, %v, want %+v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"6000", "6000=100", "G000=00", "=00", "text:", "10==1=>x", "10==1=>200"} {
+		if _, err := parseExitCondition(s); err == nil {
+			t.Errorf("parseExitCondition(%q) succeeded", s)
+		}
+	}
+}
+
+func TestExitConditionsMet(t *testing.T) {
+	conditions := exitConditions{
+		{address: 0x10, op: "==", value: 0x42},
+		{text: "Failed", code: 3},
+		{address: 0x11, op: ">=", value: 0x05, code: 2},
+	}
+	memory := map[uint16]uint8{0x10: 0x41, 0x11: 0x05}
+	peek := func(address uint16) (uint8, bool) { return memory[address], true }
+	screen, reads := "", 0
+	text := func() string { reads++; return screen }
+
+	if got, ok := conditions.met(peek, text); !ok || got != conditions[2] {
+		t.Errorf("met = %v, %v, want %v", got, ok, conditions[2])
+	}
+	screen = "TEST\nFailed #2\n"
+	if got, ok := conditions.met(peek, text); !ok || got.code != 3 {
+		t.Errorf("met = %v, %v, want the text condition", got, ok)
+	}
+	memory[0x11], screen = 0x04, "Passed"
+	if got, ok := conditions.met(peek, text); ok {
+		t.Errorf("met = %v with no condition holding", got)
+	}
+	if reads != 3 {
+		t.Errorf("text read %d times in 3 checks", reads)
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	var none *runReport
+	none.finish(10, 0, nil) // Runs without -json
+
+	var out strings.Builder
+	report := newRunReport("game.nes", &out)
+	report.addFrame(1, "aa")
+	report.addFrame(2, "bb")
+	report.finish(2, 1, errors.New("CPU jammed"))
+
+	var decoded struct {
+		ROM         string      `json:"rom"`
+		Frames      int         `json:"frames"`
+		ExitStatus  int         `json:"exit_status"`
+		Error       string      `json:"error"`
+		FrameHashes []frameHash `json:"frame_hashes"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if decoded.ROM != "game.nes" || decoded.Frames != 2 || decoded.ExitStatus != 1 || decoded.Error != "CPU jammed" ||
+		len(decoded.FrameHashes) != 2 || decoded.FrameHashes[1] != (frameHash{2, "bb"}) {
+		t.Errorf("report = %s", out.String())
+	}
+}
@@ -0,0 +1,92 @@
+// Package main implements `gones netplay` and the netplay flags of run.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"gones/internal/app"
+	"gones/internal/netplay"
+)
+
+// startNetplay hosts or joins the session given by the run flags, if any.
+// A negative delay or rollback keeps the config's.
+func startNetplay(application *app.Application, host, join string, delay, rollback int) error {
+	if host == "" && join == "" {
+		return nil
+	}
+	if host == "" {
+		return application.JoinNetplay(join)
+	}
+	config := application.GetConfig()
+	if delay >= 0 {
+		config.Netplay.InputDelay = delay
+	}
+	if rollback >= 0 {
+		config.Netplay.MaxRollback = rollback
+	}
+	if _, err := strconv.Atoi(host); err == nil {
+		host = ":" + host // A port alone
+	}
+	return application.HostNetplay(host)
+}
+
+// runNetplay runs `gones netplay host|join`, the GUI with a netplay session
+func runNetplay(args []string) int {
+	flags := flag.NewFlagSet("netplay", flag.ContinueOnError)
+	port := flags.Int("port", netplay.DefaultPort, "UDP port to host on")
+	delay := flags.Int("delay", -1, fmt.Sprintf("Input delay in frames, 0-%d, when hosting (default from the config)", netplay.MaxInputDelay))
+	rollback := flags.Int("rollback", -1, fmt.Sprintf("Frames the session may roll back, 0-%d, 0 for lockstep, when hosting (default from the config)", netplay.MaxMaxRollback))
+	configFile := flags.String("config", "", "Path to configuration file")
+	portable := flags.Bool("portable", false, "Keep everything next to the executable, as gones -portable does")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gones netplay host [options] ROM")
+		fmt.Fprintln(flags.Output(), "       gones netplay join [options] ADDRESS ROM")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Plays a ROM with another player over the network (UDP). The host is player 1 and")
+		fmt.Fprintln(flags.Output(), "the one joining player 2; both need the same ROM, and the game starts from power")
+		fmt.Fprintln(flags.Output(), "on when player 2 joins. Each side plays with the player 1 controls. The host's")
+		fmt.Fprintln(flags.Output(), "input delay and rollback are used by both.")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 || (args[0] != "host" && args[0] != "join") {
+		flags.Usage()
+		if len(args) > 0 && (args[0] == "-help" || args[0] == "--help" || args[0] == "-h") {
+			return 0
+		}
+		return 2
+	}
+	mode := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	runArgs := []string{}
+	if *configFile != "" {
+		runArgs = append(runArgs, "-config", *configFile)
+	}
+	if *portable {
+		runArgs = append(runArgs, "-portable")
+	}
+	switch {
+	case mode == "host" && flags.NArg() == 1:
+		runArgs = append(runArgs, "-netplay-host", fmt.Sprintf(":%d", *port),
+			"-netplay-delay", strconv.Itoa(*delay), "-netplay-rollback", strconv.Itoa(*rollback))
+	case mode == "join" && flags.NArg() == 2:
+		if flagGiven(flags, "port") || flagGiven(flags, "delay") || flagGiven(flags, "rollback") {
+			fmt.Fprintln(flags.Output(), "-port, -delay and -rollback are set by the host")
+			return 2
+		}
+		runArgs = append(runArgs, "-netplay-join", flags.Arg(0))
+	default:
+		flags.Usage()
+		return 2
+	}
+	return runRun(append(runArgs, "-rom", flags.Arg(flags.NArg()-1)))
+}
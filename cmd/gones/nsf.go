@@ -0,0 +1,127 @@
+// Package main implements the nsf subcommand, which shows NSF music files and
+// renders their songs to WAV.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gones/internal/nsf"
+	"gones/internal/record"
+)
+
+// runNSF runs `gones nsf` and returns the exit status
+func runNSF(args []string) int {
+	flags := flag.NewFlagSet("nsf", flag.ContinueOnError)
+	track := flags.Int("track", 0, "Song to render, counting from 1 (default the file's first song)")
+	seconds := flags.Float64("seconds", 180, "Length to render")
+	wavFile := flags.String("wav", "", "Render the song to a WAV file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gones nsf [options] FILE")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Shows an NSF file's title, artist and songs, and renders a song to WAV with")
+		fmt.Fprintln(flags.Output(), "-wav, with the expansion sound chips the file asks for.")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	file, err := nsf.Load(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nsf: %v\n", err)
+		return 1
+	}
+	printNSFInfo(flags.Arg(0), file)
+	if *wavFile == "" {
+		return 0
+	}
+
+	song := *track
+	if song == 0 {
+		song = file.StartSong
+	}
+	if *seconds <= 0 {
+		fmt.Fprintln(os.Stderr, "nsf: -seconds must be positive")
+		return 2
+	}
+	player, err := nsf.NewPlayer(file, song)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nsf: %v\n", err)
+		return 2
+	}
+	printNowPlaying(file, song)
+	if err := renderNSF(player, *wavFile, *seconds); err != nil {
+		fmt.Fprintf(os.Stderr, "nsf: %v\n", err)
+		return 1
+	}
+	fmt.Printf("🎵 Song %d rendered to %s (%.0f seconds)\n", song, *wavFile, *seconds)
+	return 0
+}
+
+// printNSFInfo prints an NSF file's header
+func printNSFInfo(path string, file *nsf.File) {
+	region := "NTSC"
+	switch {
+	case file.Region&0x02 != 0:
+		region = "NTSC and PAL"
+	case file.PAL():
+		region = "PAL"
+	}
+	chips := "none"
+	if names := file.ExpansionChips(); len(names) > 0 {
+		chips = strings.Join(names, ", ")
+	}
+
+	fmt.Printf("File:      %s\n", path)
+	fmt.Printf("Title:     %s\n", file.Title)
+	fmt.Printf("Artist:    %s\n", file.Artist)
+	fmt.Printf("Copyright: %s\n", file.Copyright)
+	fmt.Printf("Songs:     %d (first %d)\n", file.Songs, file.StartSong)
+	fmt.Printf("Region:    %s\n", region)
+	fmt.Printf("Load:      $%04X, init $%04X, play $%04X\n", file.LoadAddress, file.InitAddress, file.PlayAddress)
+	fmt.Printf("Banks:     %s\n", yesNo(file.Bankswitched()))
+	fmt.Printf("Expansion: %s\n", chips)
+}
+
+// printNowPlaying prints the song being rendered with the music's title,
+// artist and sound chips
+func printNowPlaying(file *nsf.File, song int) {
+	fmt.Printf("\n▶ Song %d/%d", song, file.Songs)
+	for _, field := range []string{file.Title, file.Artist, file.Copyright} {
+		if field != "" {
+			fmt.Printf(" · %s", field)
+		}
+	}
+	if names := file.ExpansionChips(); len(names) > 0 {
+		fmt.Printf(" [APU + %s]", strings.Join(names, " + "))
+	}
+	fmt.Println()
+}
+
+// renderNSF writes seconds of the player's song to a WAV file
+func renderNSF(player *nsf.Player, path string, seconds float64) error {
+	recorder, err := record.Create(path, record.Options{SampleRate: player.SampleRate()})
+	if err != nil {
+		return err
+	}
+	frames := int(seconds * record.NTSCFrameRate)
+	for i := 0; i < frames; i++ {
+		if err := recorder.AddAudio(player.Frame()); err != nil {
+			recorder.Close()
+			return err
+		}
+	}
+	return recorder.Close()
+}
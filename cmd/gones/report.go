@@ -0,0 +1,69 @@
+// Package main implements the JSON result of headless runs (-json): the
+// frames run, the hash of every frame, how the run ended and its speed, for
+// CI pipelines and dashboards.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// frameHash is the screen hash of a frame
+type frameHash struct {
+	Frame int    `json:"frame"`
+	Hash  string `json:"hash"` // Hex SHA-256 of the RGB pixels, as in golden files
+}
+
+// runReport is the JSON result of a headless run
+type runReport struct {
+	ROM           string      `json:"rom"`
+	RAMSeed       int64       `json:"ram_seed,omitempty"` // Seed of random power-on RAM, to repeat the run
+	Frames        int         `json:"frames"`             // Frames run
+	Seconds       float64     `json:"seconds"`            // Wall-clock time
+	FPS           float64     `json:"fps"`
+	ExitStatus    int         `json:"exit_status"`
+	ExitCondition string      `json:"exit_condition,omitempty"` // The -exit-when condition met
+	Error         string      `json:"error,omitempty"`          // Why the run failed
+	Golden        string      `json:"golden,omitempty"`         // Golden file checked or written
+	SavedFrames   []string    `json:"saved_frames,omitempty"`
+	FrameHashes   []frameHash `json:"frame_hashes"`
+
+	out   io.Writer
+	start time.Time
+}
+
+// newRunReport starts timing a run whose report is written to out
+func newRunReport(rom string, out io.Writer) *runReport {
+	return &runReport{ROM: rom, FrameHashes: []frameHash{}, out: out, start: time.Now()}
+}
+
+// addFrame records a frame's hash
+func (r *runReport) addFrame(frame int, hash string) {
+	r.FrameHashes = append(r.FrameHashes, frameHash{frame, hash})
+}
+
+// finish records how the run ended and writes the report. It does nothing
+// on a nil report, so runs without -json can call it too.
+func (r *runReport) finish(frames, status int, err error) {
+	if r == nil {
+		return
+	}
+	r.Frames, r.ExitStatus = frames, status
+	if err != nil {
+		r.Error = err.Error()
+	}
+	elapsed := time.Since(r.start)
+	r.Seconds = elapsed.Seconds()
+	if elapsed > 0 {
+		r.FPS = float64(frames) / elapsed.Seconds()
+	}
+
+	encoder := json.NewEncoder(r.out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write the JSON result: %v\n", err)
+	}
+}
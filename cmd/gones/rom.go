@@ -0,0 +1,232 @@
+// Package main implements `gones rom`, which splits ROMs into their PRG and
+// CHR ROM and converts iNES 1.0 headers to NES 2.0.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gones/internal/app"
+	"gones/internal/cartridge"
+	"gones/internal/library"
+	"gones/internal/region"
+)
+
+// runROM runs `gones rom split|convert` and returns the exit status
+func runROM(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "split":
+			return runROMSplit(args[1:])
+		case "convert":
+			return runROMConvert(args[1:])
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Usage: gones rom split [options] ROM")
+	fmt.Fprintln(os.Stderr, "       gones rom convert [options] ROM OUTPUT")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "split writes a ROM's PRG ROM, CHR ROM and trainer to separate files; convert")
+	fmt.Fprintln(os.Stderr, "rewrites an iNES 1.0 header as NES 2.0. See gones rom split|convert -help.")
+	if len(args) > 0 && (args[0] == "-help" || args[0] == "--help" || args[0] == "-h") {
+		return 0
+	}
+	return 2
+}
+
+// runROMSplit runs `gones rom split`
+func runROMSplit(args []string) int {
+	flags := flag.NewFlagSet("rom split", flag.ContinueOnError)
+	outDir := flags.String("o", "", "Directory to write the files to (default next to the ROM)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gones rom split [options] ROM")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Writes the PRG ROM, CHR ROM and trainer of a ROM to NAME.prg, NAME.chr and")
+		fmt.Fprintln(flags.Output(), "NAME.trainer, without the header. Games with CHR RAM have no .chr file.")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	data, err := cartridge.ReadROM(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rom split: %v\n", err)
+		return 1
+	}
+	seg, err := cartridge.SplitROM(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rom split: %s: %v\n", path, err)
+		return 1
+	}
+
+	dir := *outDir
+	if dir == "" {
+		dir = filepath.Dir(path)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "rom split: %v\n", err)
+		return 1
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, part := range []struct {
+		ext, what string
+		data      []byte
+	}{
+		{".prg", "PRG ROM", seg.PRG},
+		{".chr", "CHR ROM", seg.CHR},
+		{".trainer", "Trainer", seg.Trainer},
+	} {
+		if part.data == nil {
+			continue
+		}
+		out := filepath.Join(dir, name+part.ext)
+		if err := os.WriteFile(out, part.data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "rom split: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%-8s %5d KB  %s\n", part.what+":", len(part.data)/1024, out)
+	}
+	if seg.CHR == nil {
+		fmt.Println("CHR:     RAM, nothing to write")
+	}
+	return 0
+}
+
+// runROMConvert runs `gones rom convert`
+func runROMConvert(args []string) int {
+	flags := flag.NewFlagSet("rom convert", flag.ContinueOnError)
+	configFile := flags.String("config", "", "Path to configuration file")
+	portable := flags.Bool("portable", false, "Use the config next to the executable, as gones -portable does")
+	database := flags.String("database", "", "No-Intro style DAT file or directory of them (default from the config)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gones rom convert [options] ROM OUTPUT")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Writes the ROM to OUTPUT with its iNES 1.0 header rewritten as NES 2.0. The")
+		fmt.Fprintln(flags.Output(), "mapper, mirroring, battery and trainer carry over; the TV system comes from the")
+		fmt.Fprintln(flags.Output(), "game's title in the ROM database, else the file name, else the old header.")
+		fmt.Fprintln(flags.Output(), "RAM sizes are the iNES defaults (8 KB PRG RAM, 8 KB CHR RAM without CHR ROM)")
+		fmt.Fprintln(flags.Output(), "and submappers 0, as iNES 1.0 does not tell them. OUTPUT may be the ROM itself.")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	app.SetPortable(*portable)
+
+	config := app.NewConfig()
+	configPath := *configFile
+	if configPath == "" {
+		configPath = app.GetDefaultConfigPath()
+	}
+	if _, err := os.Stat(configPath); err == nil || *configFile != "" {
+		if err := config.LoadFromFile(configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "rom convert: %v\n", err)
+			return 1
+		}
+	}
+	if *database != "" {
+		config.Library.Database = *database
+	}
+
+	path, output := flags.Arg(0), flags.Arg(1)
+	data, err := cartridge.ReadROM(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rom convert: %v\n", err)
+		return 1
+	}
+	cart, err := cartridge.LoadFromReader(bytes.NewReader(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rom convert: %s: %v\n", path, err)
+		return 1
+	}
+
+	names := []string{filepath.Base(path)}
+	db, err := library.LoadDatabase(config.Library.Database)
+	switch {
+	case err == nil:
+		if game, ok := db.Lookup(library.Hash(data)); ok {
+			fmt.Printf("Game:      %s\n", game.Title)
+			names = append([]string{game.Title}, names...)
+		}
+	case os.IsNotExist(err):
+		fmt.Fprintf(os.Stderr, "rom convert: no ROM database at %s, so the TV system comes from the file name\n", config.Library.Database)
+	default:
+		fmt.Fprintf(os.Stderr, "rom convert: failed to load the ROM database: %v\n", err)
+		return 1
+	}
+	r, multi, source := romTiming(names, cart)
+
+	converted, err := cartridge.ConvertToNES2(data, r, multi)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rom convert: %s: %v\n", path, err)
+		return 1
+	}
+	if err := os.WriteFile(output, converted, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "rom convert: %v\n", err)
+		return 1
+	}
+	timing := r.String()
+	if multi {
+		timing = "multi-region"
+	}
+	fmt.Printf("Mapper:    %d\n", cart.MapperID())
+	fmt.Printf("TV system: %s (%s)\n", timing, source)
+	fmt.Printf("Wrote NES 2.0 ROM to %s\n", output)
+	return 0
+}
+
+// romTiming returns the TV system of a ROM from the region tags of the first
+// of names that has one, else from its header: a region, or multi for games
+// sold in both NTSC and PAL regions such as "(USA, Europe)" and "(World)".
+// source says what decided it.
+func romTiming(names []string, cart *cartridge.Cartridge) (r region.Region, multi bool, source string) {
+	for i, name := range names {
+		source = "file name"
+		if i < len(names)-1 {
+			source = "database title"
+		}
+		if r, ok := region.FromName(name); ok {
+			return r, false, source
+		}
+		tag := library.Region(name)
+		if tag == "World" {
+			return region.NTSC, true, source
+		}
+		found := map[region.Region]bool{}
+		for _, part := range strings.Split(tag, ",") {
+			if r, ok := region.FromName("(" + strings.TrimSpace(part) + ")"); ok {
+				found[r] = true
+			}
+		}
+		if len(found) > 1 {
+			return region.NTSC, true, source
+		}
+	}
+	if r, ok := cart.Region(); ok {
+		return r, false, "header"
+	}
+	return region.NTSC, false, "default"
+}
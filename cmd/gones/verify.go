@@ -0,0 +1,87 @@
+// Package main implements the verify subcommand, which runs accuracy test
+// ROMs and prints a summary of which passed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"gones/internal/verify"
+)
+
+// runVerify runs `gones verify` and returns the exit status: 0 when every
+// test passed, 1 when any did not and 2 for usage errors
+func runVerify(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	frames := flags.Int("frames", verify.DefaultFrames, "Frames a test may run before it times out (tests in a manifest can set their own)")
+	method := flags.String("method", "", "Check every test by status ($6000), hash or nestest instead of guessing")
+	quiet := flags.Bool("quiet", false, "Print only the summary, not each result as it finishes")
+	jsonOut := flags.Bool("json", false, "Write the results to stdout as JSON instead of a table; progress goes to stderr")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gones verify [options] ROM|DIR|MANIFEST.json...")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Runs accuracy test ROMs headlessly and exits non-zero if any fails. Directories")
+		fmt.Fprintln(flags.Output(), "are searched for .nes files. ROMs pass by reporting 0 at $6000 (blargg's test")
+		fmt.Fprintln(flags.Output(), "ROMs), by nestest's error bytes (nestest.nes), or by a screen hash in a manifest:")
+		fmt.Fprintln(flags.Output(), `  {"tests": [{"rom": "cpu_instrs/01-basics.nes"}, {"rom": "game.nes", "frames": 120, "hash": "..."}]}`)
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Options:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	tests, err := verify.Discover(flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %v\n", err)
+		return 2
+	}
+	if len(tests) == 0 {
+		fmt.Fprintln(os.Stderr, "verify: no test ROMs found")
+		return 2
+	}
+	for i := range tests {
+		if tests[i].Frames == 0 {
+			tests[i].Frames = *frames
+		}
+		if *method != "" {
+			tests[i].Method = *method
+		}
+	}
+
+	progress := os.Stdout
+	if *jsonOut {
+		progress = os.Stderr
+	}
+	fmt.Fprintf(progress, "🧪 Running %d test ROMs\n", len(tests))
+	results := verify.RunAll(tests, func(r verify.Result) {
+		if !*quiet {
+			fmt.Fprintf(progress, "  %-7s %s\n", r.Status, r.Test.Name)
+		}
+	})
+	if *jsonOut {
+		passed, err := verify.WriteJSON(os.Stdout, results)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "verify: %v\n", err)
+			return 1
+		}
+		if !passed {
+			return 1
+		}
+		return 0
+	}
+	fmt.Println()
+	if !verify.WriteSummary(os.Stdout, results) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,304 @@
+// Package api provides the HTTP control API for bots and automation: REST
+// endpoints to load ROMs, press buttons, grab the screen and read memory,
+// built on the emulator's remote methods, which are also served over
+// WebSocket for clients that want every method and the "stopped" events.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gones/internal/metrics"
+	"gones/internal/remote"
+)
+
+// maxROMSize limits uploaded ROMs
+const maxROMSize = 8 << 20
+
+// Caller runs remote methods on the emulation thread
+type Caller interface {
+	Call(ctx context.Context, method string, params any) (any, error)
+}
+
+// Options configures the API
+type Options struct {
+	// Web page origins allowed to use the API ("*" allows any). Requests
+	// with any other Origin header are refused, so web pages cannot drive
+	// the emulator; clients without one, like scripts, are always allowed.
+	AllowedOrigins []string
+
+	// Serves /api/ws (nil leaves it out)
+	WebSocket http.Handler
+}
+
+// handler serves the API
+type handler struct {
+	caller  Caller
+	options Options
+	mux     *http.ServeMux
+}
+
+// NewHandler returns the API's HTTP handler
+func NewHandler(caller Caller, options Options) http.Handler {
+	h := &handler{caller: caller, options: options, mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("GET /api/status", h.method("status", noParams))
+	h.mux.HandleFunc("POST /api/pause", h.method("pause", noParams))
+	h.mux.HandleFunc("POST /api/resume", h.method("resume", noParams))
+	h.mux.HandleFunc("POST /api/reset", h.method("reset", noParams))
+	h.mux.HandleFunc("POST /api/power-cycle", h.method("powerCycle", noParams))
+	h.mux.HandleFunc("POST /api/frames", h.method("runFrames", bodyParams))
+	h.mux.HandleFunc("POST /api/input", h.method("setInput", bodyParams))
+	h.mux.HandleFunc("GET /api/memory", h.method("readMemory", queryParams("address", "length", "space")))
+	h.mux.HandleFunc("POST /api/memory", h.method("writeMemory", bodyParams))
+	h.mux.HandleFunc("POST /api/state/save", h.method("saveState", bodyParams))
+	h.mux.HandleFunc("POST /api/state/load", h.method("loadState", bodyParams))
+	h.mux.HandleFunc("GET /api/states", h.method("listStates", noParams))
+	h.mux.HandleFunc("POST /api/rom", h.loadROM)
+	h.mux.HandleFunc("GET /api/screen.png", h.screen)
+	h.mux.HandleFunc("POST /api/call/{method}", func(w http.ResponseWriter, r *http.Request) {
+		h.method(r.PathValue("method"), bodyParams)(w, r)
+	})
+	h.mux.HandleFunc("GET /metrics", metricsHandler(caller))
+	if options.WebSocket != nil {
+		h.mux.Handle("GET /api/ws", options.WebSocket)
+	}
+	return h
+}
+
+// NewMetricsHandler returns a handler serving only /metrics, for monitoring
+// without exposing the rest of the API
+func NewMetricsHandler(caller Caller) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", metricsHandler(caller))
+	return mux
+}
+
+// metricsHandler writes the "metrics" method's result, which is already in
+// the Prometheus text format
+func metricsHandler(caller Caller) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := caller.Call(r.Context(), "metrics", nil)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		text, ok := result.(string)
+		if !ok {
+			http.Error(w, "metrics are not text", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", metrics.ContentType)
+		io.WriteString(w, text)
+	}
+}
+
+// ServeHTTP refuses foreign web pages and answers CORS preflights before
+// routing
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if !h.originAllowed(origin) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("origin %s not allowed", origin))
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// originAllowed returns whether web pages on origin may use the API
+func (h *handler) originAllowed(origin string) bool {
+	for _, allowed := range h.options.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// paramsFunc extracts a method's params from a request
+type paramsFunc func(r *http.Request) (any, error)
+
+// noParams passes no params
+func noParams(*http.Request) (any, error) {
+	return nil, nil
+}
+
+// bodyParams passes the JSON body (which may be empty) as the params
+func bodyParams(r *http.Request) (any, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil, nil
+	}
+	if !json.Valid(body) {
+		return nil, remote.InvalidParams("body is not JSON")
+	}
+	return json.RawMessage(body), nil
+}
+
+// queryParams passes the named query parameters; numbers are passed as
+// numbers and anything else (like "$0300") as strings
+func queryParams(names ...string) paramsFunc {
+	return func(r *http.Request) (any, error) {
+		params := map[string]any{}
+		query := r.URL.Query()
+		for _, name := range names {
+			if !query.Has(name) {
+				continue
+			}
+			value := query.Get(name)
+			if n, err := strconv.Atoi(value); err == nil {
+				params[name] = n
+			} else {
+				params[name] = value
+			}
+		}
+		return params, nil
+	}
+}
+
+// method returns a handler calling a remote method and writing its result
+// as JSON
+func (h *handler) method(name string, params paramsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := params(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		result, err := h.caller.Call(r.Context(), name, p)
+		if err != nil {
+			writeError(w, errorStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// loadROM loads a ROM from a path on the emulator's machine, given as
+// {"path": ...}, or uploaded as the request body with ?name=game.nes
+func (h *handler) loadROM(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		h.method("loadROM", bodyParams)(w, r)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("upload needs ?name=<file>.nes (or send {\"path\": ...} as JSON)"))
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxROMSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(data) > maxROMSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("ROM larger than %d bytes", maxROMSize))
+		return
+	}
+	result, err := h.caller.Call(r.Context(), "loadROM", map[string]any{"name": name, "data": data})
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// screen writes the current frame as a PNG, unprocessed with ?raw=1
+func (h *handler) screen(w http.ResponseWriter, r *http.Request) {
+	raw, _ := strconv.ParseBool(r.URL.Query().Get("raw"))
+	result, err := h.caller.Call(r.Context(), "screenshot", map[string]bool{"raw": raw})
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	data, ok := result.([]byte)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("screenshot is not a PNG"))
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+}
+
+// errorStatus maps a remote method's error to an HTTP status
+func errorStatus(err error) int {
+	var rpcErr *remote.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case remote.CodeMethodNotFound:
+			return http.StatusNotFound
+		case remote.CodeInvalidParams, remote.CodeInvalidRequest, remote.CodeParseError:
+			return http.StatusBadRequest
+		}
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, remote.ErrClosed) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusConflict // The emulator's state does not allow it, like no ROM loaded
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	if v == nil {
+		v = struct{}{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes {"error": message}
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// Server is a running API server
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+}
+
+// Listen serves handler on a TCP address such as "127.0.0.1:6580"
+func Listen(address string, handler http.Handler) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", address, err)
+	}
+	s := &Server{
+		server:   &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second},
+		listener: listener,
+	}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops the server, dropping requests in progress
+func (s *Server) Close() error {
+	return s.server.Close()
+}
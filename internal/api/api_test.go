@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gones/internal/remote"
+)
+
+// fakeCaller records the last call and answers with canned results
+type fakeCaller struct {
+	method string
+	params any
+}
+
+func (f *fakeCaller) Call(ctx context.Context, method string, params any) (any, error) {
+	f.method, f.params = method, params
+	switch method {
+	case "metrics":
+		return "gones_fps 60\n", nil
+	case "screenshot":
+		return []byte("\x89PNG"), nil
+	case "resume":
+		return nil, errorString("no ROM loaded")
+	case "nope":
+		return nil, &remote.Error{Code: remote.CodeMethodNotFound, Message: "method \"nope\" not found"}
+	}
+	return map[string]int{"frame": 7}, nil
+}
+
+type errorString string
+
+func (e errorString) Error() string { return string(e) }
+
+// paramsJSON returns the params of the last call as JSON
+func (f *fakeCaller) paramsJSON() string {
+	data, _ := json.Marshal(f.params)
+	return string(data)
+}
+
+func do(h http.Handler, method, target, contentType, body string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestRoutes(t *testing.T) {
+	caller := &fakeCaller{}
+	h := NewHandler(caller, Options{})
+
+	tests := []struct {
+		method, target, body string
+		wantMethod           string
+		wantParams           string
+	}{
+		{"GET", "/api/status", "", "status", "null"},
+		{"POST", "/api/power-cycle", "", "powerCycle", "null"},
+		{"POST", "/api/input", `{"player":1,"buttons":"A+Right"}`, "setInput", `{"player":1,"buttons":"A+Right"}`},
+		{"POST", "/api/frames", `{"count":60}`, "runFrames", `{"count":60}`},
+		{"GET", "/api/memory?address=$0300&length=16", "", "readMemory", `{"address":"$0300","length":16}`},
+		{"POST", "/api/call/disassemble", `{"count":4}`, "disassemble", `{"count":4}`},
+		{"POST", "/api/rom", `{"path":"game.nes"}`, "loadROM", `{"path":"game.nes"}`},
+	}
+	for _, test := range tests {
+		contentType := ""
+		if test.body != "" {
+			contentType = "application/json"
+		}
+		w := do(h, test.method, test.target, contentType, test.body)
+		if w.Code != http.StatusOK || caller.method != test.wantMethod || caller.paramsJSON() != test.wantParams {
+			t.Errorf("%s %s: status %d, called %s(%s), want %s(%s)", test.method, test.target,
+				w.Code, caller.method, caller.paramsJSON(), test.wantMethod, test.wantParams)
+		}
+	}
+	if w := do(h, "GET", "/api/status", "", ""); w.Body.String() != "{\"frame\":7}\n" {
+		t.Errorf("status body = %q", w.Body.String())
+	}
+}
+
+func TestUploadAndScreen(t *testing.T) {
+	caller := &fakeCaller{}
+	h := NewHandler(caller, Options{})
+
+	w := do(h, "POST", "/api/rom?name=game.nes", "application/octet-stream", "NES\x1a")
+	params, _ := caller.params.(map[string]any)
+	if w.Code != http.StatusOK || params["name"] != "game.nes" || !bytes.Equal(params["data"].([]byte), []byte("NES\x1a")) {
+		t.Errorf("upload: status %d, params %v", w.Code, caller.params)
+	}
+	if w := do(h, "POST", "/api/rom", "application/octet-stream", "NES"); w.Code != http.StatusBadRequest {
+		t.Errorf("upload without a name: status %d", w.Code)
+	}
+
+	w = do(h, "GET", "/api/screen.png?raw=1", "", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || w.Body.String() != "\x89PNG" {
+		t.Errorf("screen: status %d, type %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if caller.paramsJSON() != `{"raw":true}` {
+		t.Errorf("screen params = %s", caller.paramsJSON())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	h := NewHandler(&fakeCaller{}, Options{})
+	tests := []struct {
+		method, target, body string
+		status               int
+	}{
+		{"POST", "/api/resume", "", http.StatusConflict},
+		{"POST", "/api/call/nope", "", http.StatusNotFound},
+		{"POST", "/api/input", "{not json", http.StatusBadRequest},
+		{"GET", "/api/input", "", http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		w := do(h, test.method, test.target, "", test.body)
+		if w.Code != test.status {
+			t.Errorf("%s %s: status %d, want %d", test.method, test.target, w.Code, test.status)
+		}
+	}
+	w := do(h, "POST", "/api/resume", "", "")
+	body, _ := io.ReadAll(w.Body)
+	if string(body) != "{\"error\":\"no ROM loaded\"}\n" {
+		t.Errorf("error body = %s", body)
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	h := NewHandler(&fakeCaller{}, Options{AllowedOrigins: []string{"http://localhost:3000"}})
+	if w := do(h, "POST", "/api/pause", "", "", "Origin", "http://evil.example"); w.Code != http.StatusForbidden {
+		t.Errorf("foreign origin: status %d", w.Code)
+	}
+	w := do(h, "OPTIONS", "/api/input", "", "", "Origin", "http://localhost:3000")
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" {
+		t.Errorf("preflight: status %d, headers %v", w.Code, w.Header())
+	}
+	if w := do(h, "POST", "/api/pause", "", "", "Origin", "http://localhost:3000"); w.Code != http.StatusOK {
+		t.Errorf("allowed origin: status %d", w.Code)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	caller := &fakeCaller{}
+	for _, h := range []http.Handler{NewHandler(caller, Options{}), NewMetricsHandler(caller)} {
+		w := do(h, "GET", "/metrics", "", "")
+		if w.Code != http.StatusOK || caller.method != "metrics" || w.Body.String() != "gones_fps 60\n" ||
+			!strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+			t.Errorf("metrics: status %d, type %s, body %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+	if w := do(NewMetricsHandler(caller), "POST", "/api/pause", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("metrics handler served the API: status %d", w.Code)
+	}
+}
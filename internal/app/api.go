@@ -0,0 +1,199 @@
+// Package app provides the HTTP control API's server and methods.
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gones/internal/api"
+	"gones/internal/input"
+	"gones/internal/remote"
+)
+
+// DefaultAPIListen is where the control API listens by default
+const DefaultAPIListen = "127.0.0.1:6580"
+
+// SetAPIListen turns the control API on, listening on address
+func (app *Application) SetAPIListen(address string) {
+	app.config.API.Enabled = true
+	app.config.API.Listen = address
+}
+
+// StartAPI starts the control API on api.listen. Its requests run as remote
+// methods, so it shares the remote debugging server's method set.
+func (app *Application) StartAPI() error {
+	if app.api != nil {
+		return nil
+	}
+	server := app.remoteServer()
+	handler := api.NewHandler(server, api.Options{
+		AllowedOrigins: app.config.API.AllowedOrigins,
+		WebSocket:      server,
+	})
+	apiServer, err := api.Listen(app.config.API.Listen, handler)
+	if err != nil {
+		return err
+	}
+	app.api = apiServer
+
+	warnIfExposed("Control API", apiServer.Addr())
+	fmt.Printf("🌐 Control API on http://%s/api/\n", apiServer.Addr())
+	return nil
+}
+
+// StopAPI stops the control API
+func (app *Application) StopAPI() {
+	if app.api == nil {
+		return
+	}
+	if err := app.api.Close(); err != nil && app.config.Debug.EnableLogging {
+		fmt.Printf("[APP_DEBUG] Control API: %v\n", err)
+	}
+	app.api = nil
+}
+
+// APIAddr returns the address of the control API, or "" when it is not
+// running
+func (app *Application) APIAddr() string {
+	if app.api == nil {
+		return ""
+	}
+	return app.api.Addr().String()
+}
+
+// uploadDir holds ROMs uploaded through the control API
+func uploadDir() string {
+	return filepath.Join(os.TempDir(), "gones-roms")
+}
+
+// registerAPIMethods registers the methods used by the control API for
+// automation: loading ROMs, holding buttons, running frames and grabbing
+// the screen
+func (app *Application) registerAPIMethods() {
+	s := app.remote
+
+	s.Handle("loadROM", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Path string `json:"path"`
+			Name string `json:"name"`
+			Data []byte `json:"data"`
+		}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		path := args.Path
+		switch {
+		case path != "" && args.Data != nil:
+			return nil, remote.InvalidParams("give either path or name and data")
+		case args.Data != nil:
+			name := filepath.Base(args.Name)
+			if args.Name == "" || name == "." || name == string(filepath.Separator) {
+				return nil, remote.InvalidParams("uploaded ROM needs a name")
+			}
+			if err := os.MkdirAll(uploadDir(), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create upload directory: %v", err)
+			}
+			path = filepath.Join(uploadDir(), name)
+			if err := os.WriteFile(path, args.Data, 0644); err != nil {
+				return nil, fmt.Errorf("failed to save uploaded ROM: %v", err)
+			}
+		case path == "":
+			return nil, remote.InvalidParams("path is required")
+		}
+
+		if err := app.LoadROM(path); err != nil {
+			return nil, err
+		}
+		fmt.Printf("📁 ROM loaded through the control API: %s\n", path)
+		return map[string]any{"rom": app.romPath, "frame": app.emulator.GetFrameCount()}, nil
+	})
+
+	s.Handle("setInput", func(params json.RawMessage) (any, error) {
+		args := struct {
+			Player  int             `json:"player"`
+			Buttons json.RawMessage `json:"buttons"`
+		}{Player: 1}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		if args.Player < 1 || args.Player > 4 {
+			return nil, remote.InvalidParams("player must be 1-4")
+		}
+		buttons, err := parseAPIButtons(args.Buttons)
+		if err != nil {
+			return nil, err
+		}
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		app.bus.SetControllerButtons(args.Player, buttons)
+		return nil, nil
+	})
+
+	s.Handle("runFrames", func(params json.RawMessage) (any, error) {
+		args := struct {
+			Count int `json:"count"`
+		}{Count: 1}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		if args.Count < 1 || args.Count > 100000 {
+			return nil, remote.InvalidParams("count must be 1-100000")
+		}
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		for i := 0; i < args.Count; i++ {
+			if err := app.StepFrame(); err != nil {
+				return nil, err
+			}
+			if app.emulator.Stopped() {
+				break // At a breakpoint
+			}
+		}
+		return map[string]any{
+			"frame":     app.emulator.GetFrameCount(),
+			"mid_frame": app.emulator.Stopped(),
+		}, nil
+	})
+
+	s.Handle("screenshot", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Raw bool `json:"raw"`
+		}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := app.EncodeScreenshot(&buf, args.Raw); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+// parseAPIButtons parses held buttons given as a string like "A+Right" (see
+// input.ParseScriptButtons) or a list of button names
+func parseAPIButtons(raw json.RawMessage) ([8]bool, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return [8]bool{}, nil
+	}
+	var state string
+	if err := json.Unmarshal(raw, &state); err != nil {
+		var names []string
+		if err := json.Unmarshal(raw, &names); err != nil {
+			return [8]bool{}, remote.InvalidParams("buttons must be a string or a list of names")
+		}
+		for _, name := range names {
+			state += name + "+"
+		}
+	}
+	buttons, err := input.ParseScriptButtons(state)
+	if err != nil {
+		return [8]bool{}, remote.InvalidParams("%v", err)
+	}
+	return buttons, nil
+}
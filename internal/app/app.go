@@ -2,6 +2,8 @@
 package app
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -9,12 +11,25 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
+	"sync/atomic"
 	"time"
 
+	"gones/internal/api"
 	"gones/internal/bus"
 	"gones/internal/cartridge"
+	"gones/internal/cdl"
+	"gones/internal/cheat"
+	"gones/internal/compat"
 	"gones/internal/graphics"
 	"gones/internal/input"
+	"gones/internal/library"
+	"gones/internal/metrics"
+	"gones/internal/netplay"
+	"gones/internal/record"
+	"gones/internal/remote"
+	"gones/internal/scroll"
+	"gones/internal/trace"
 )
 
 // Application represents the main NES emulator application
@@ -26,6 +41,8 @@ type Application struct {
 	graphicsBackend graphics.Backend
 	window         graphics.Window
 	videoProcessor *graphics.VideoProcessor
+	frameBuffer    [256 * 240]uint32     // Frame the overlays are drawn on, reused
+	inputEvents    []graphics.InputEvent // Events of the last poll, reused
 
 	// Application state
 	config   *Config
@@ -35,7 +52,6 @@ type Application struct {
 	// Control flags
 	running     bool
 	paused      bool
-	showMenu    bool
 	initialized bool
 	headless    bool
 
@@ -66,6 +82,11 @@ type Application struct {
 	frameTimeIndex   int               // Current index in the rolling buffer
 	frameTimeSum     time.Duration     // Sum of times in rolling buffer
 	frameVariance    float64           // Frame time variance for consistency
+
+	// Frame time metrics: recent frames for quantiles, and totals
+	frameTimes     *metrics.Window
+	frameTimeTotal time.Duration
+	framesTimed    uint64
 	
 	// Memory monitoring and periodic cleanup
 	lastMemoryCheck    time.Time
@@ -76,14 +97,169 @@ type Application struct {
 
 	// ROM management
 	romPath   string
+	romHash   string // SHA-256 of the ROM as loaded, after its patch
 	cartridge *cartridge.Cartridge
-	
-	// ESC key confirmation tracking
-	lastESCTime time.Time
+
+	// Patch set with -patch and the ROM it applies to ("" when not set)
+	patchROM  string
+	patchPath string
+
+	// Per-game input overrides from config/games/<romhash>.json (nil if none)
+	gameProfile *GameProfile
+
+	// Pause menu opened with Escape, and the directory its ROM browser last showed
+	menu    *PauseMenu
+	menuDir string
+
+	// Save state slot picker overlay
+	slotPicker *SlotPicker
+
+	// Memory viewer and hex editor opened with Ctrl+F6
+	memoryViewer *MemoryViewer
+
+	// Register write timing grid opened with Ctrl+F8
+	eventViewer *EventViewer
+
+	// Scroll graph of each scanline, toggled with Alt+F8, and the CSV log
+	// of -scroll-log (nil when off)
+	scrollOverlay bool
+	scrollLog     *scroll.Log
+
+	// Frame rate, frame time graph and audio health, toggled with Alt+F9
+	perf perfOverlay
+
+	// Oscilloscope of each sound channel, toggled with Alt+F10, and the
+	// samples of the trace being drawn
+	audioVisualizer   bool
+	visualizerSamples []float32
+
+	// RAM search opened with Ctrl+F7
+	ramSearch ramSearchState
+
+	// Enabled RAM cheats, written back before every frame (nil if none)
+	freezer *cheat.Freezer
+
+	// Code/data log of the current ROM (nil unless debug.code_data_logger is on)
+	cdl *cdl.Logger
+
+	// CPU trace logger started with Ctrl+F9 or debug.trace.enabled (nil when
+	// off), its file in streaming mode, and the crash dump ring
+	tracer     *trace.Tracer
+	traceFile  *os.File
+	crashTrace *trace.Tracer
+
+	// Debugger breakpoints, the one that stopped emulation last and the
+	// access that hit it if it is a watchpoint
+	breakpoints      []*Breakpoint
+	nextBreakpointID int
+	breakHit         *Breakpoint
+	breakAddress     uint16
+	breakValue       uint8
+
+	// Remote debugging server (nil unless started)
+	remote *remote.Server
+
+	// HTTP control API (nil unless started)
+	api *api.Server
+
+	// Prometheus metrics server (nil unless started)
+	metricsServer *api.Server
+
+	// In-progress input rebind ("press key for A"), nil when idle
+	rebind *rebindState
+
+	// Emulated play time for the current ROM (stored in save state metadata)
+	playTime time.Duration
+
+	// Play time not yet added to the ROM's entry in the recent list
+	sessionPlayTime time.Duration
+
+	// Autosave and battery RAM flush tracking
+	lastAutoSavePlayTime time.Duration
+	lastSRAMFlush        time.Time
+
+	// When the config file was last checked for edits
+	lastConfigCheck time.Time
+
+	// Window focus, and whether losing it paused emulation
+	unfocused  bool
+	autoPaused bool
+
+	// ROMs of the library directories, named from the hash database (nil
+	// without a window)
+	library *library.Library
+
+	// Active video recording (nil when not recording)
+	recorder *record.Recorder
+
+	// Recent frames for retroactive capture (nil when video.replay_seconds is 0)
+	replay       *record.ReplayBuffer
+	replaySaving atomic.Bool // A replay is being encoded in the background
+
+	// Input movie played back before each frame (nil when none) and the
+	// next frame of it to apply, or the movie being recorded and the file it
+	// is saved to
+	movie          *input.Script
+	movieFrame     int
+	movieRecording bool
+	moviePath      string
+
+	// Netplay session (nil when none), whether the other player was seen
+	// joining, the desync already reported (-1 when none), and whether the
+	// join address is being typed into the menu
+	netplay          *netplay.Session
+	netplayConnected bool
+	netplayDesync    int
+	netplayTyping    bool
+
+	// Volume-scaled samples passed to the audio output, and whether the last
+	// tick queued any (the standard loop then paces on the audio clock)
+	audioBuffer []float32
+	audioQueued bool
+	avSync      avSync
+
+	// Emulation speed: the speed chosen with -speed or the speed hotkeys,
+	// whether fast-forward is held, and the scheduler running frames at the
+	// effective speed. Unthrottled runs (-no-throttle) override all three.
+	speed       float64
+	fastForward bool
+	scheduler   *FrameScheduler
+	unthrottled bool
+
+	// Frame pacing of the standard loop when the audio clock does not pace it
+	pacer framePacer
+
+	// Microphone hotkey held
+	microphoneHeld bool
+
+	// Games and viewers open in windows of their own next to the main one
+	instances []*Instance
+
+	// Hotkey being rebound from the hotkeys menu page
+	hotkeyCapture *hotkeyCapture
+
+	// Region set with -region, over emulation.region ("" when not set)
+	regionOverride string
+
+	// Compatibility database entry of the current ROM (nil without one), and
+	// -no-compat over emulation.compat_database (nil when not set)
+	compatEntry    *compat.Entry
+	compatOverride *bool
+
+	// RAM pattern and seed set with -ram-pattern and -ram-seed, over the
+	// emulation settings ("" and nil when not set), and the seed random RAM
+	// was filled from at the last power on
+	ramPatternOverride string
+	ramSeedOverride    *int64
+	ramSeed            int64
+
+	// Emulated frame last passed to the frame blender
+	lastBlendedFrame uint64
 
 	// Input state caching to prevent redundant updates
 	lastController1State [8]bool
 	lastController2State [8]bool
+	lastController34State [2][8]bool // Four Score controllers 3 and 4
 	inputStateInitialized bool
 	
 	// Debug logging frequency control
@@ -112,11 +288,16 @@ func NewApplicationWithMode(configPath string, headless bool) (*Application, err
 		config:      NewConfig(),
 		running:     false,
 		paused:      false,
-		showMenu:    false,
+		menu:        NewPauseMenu(),
+		memoryViewer: NewMemoryViewer(),
+		eventViewer:  NewEventViewer(),
 		initialized: false,
 		headless:    headless,
 		startTime:   time.Now(),
 		lastFPSTime: time.Now(),
+		slotPicker:  NewSlotPicker(),
+		speed:       1,
+		scheduler:   NewFrameScheduler(1),
 	}
 
 	// Load configuration
@@ -149,8 +330,7 @@ func (app *Application) initializeComponents(headless bool) error {
 		return fmt.Errorf("failed to initialize graphics backend: %v", err)
 	}
 
-	// Note: Audio system will be implemented with the graphics backend in the future
-	// For now, emulator runs without audio to avoid SDL2 dependencies
+	// Audio is played by backends implementing graphics.AudioOutput (see queueAudio)
 
 	// Note: UI system removed to eliminate SDL2 dependency
 	// UI will be reimplemented using the graphics backend's UI capabilities
@@ -160,6 +340,10 @@ func (app *Application) initializeComponents(headless bool) error {
 
 	// Create state manager
 	app.states = NewStateManager(app.config.Paths.SaveStates)
+	app.states.SetCompression(app.config.Emulation.CompressStates)
+
+	// debug.show_fps starts with the performance overlay shown
+	app.perf.visible = app.config.Debug.ShowFPS
 
 	app.initialized = true
 	return nil
@@ -179,6 +363,8 @@ func (app *Application) initializeGraphicsBackend(headless bool) error {
 			backendType = graphics.BackendHeadless
 		case "terminal":
 			backendType = graphics.BackendTerminal
+		case "sdl2":
+			backendType = graphics.BackendSDL2
 		default:
 			// Default to Ebitengine for best compatibility
 			backendType = graphics.BackendEbitengine
@@ -194,18 +380,43 @@ func (app *Application) initializeGraphicsBackend(headless bool) error {
 
 	// Initialize backend
 	graphicsConfig := graphics.Config{
-		WindowTitle:  "gones - Go NES Emulator",
-		WindowWidth:  app.config.Window.Width,
-		WindowHeight: app.config.Window.Height,
-		Fullscreen:   app.config.Window.Fullscreen,
-		VSync:        app.config.Video.VSync,
-		Filter:       app.config.Video.Filter,
-		AspectRatio:  app.config.Video.AspectRatio,
-		Headless:     headless,
-		Debug:        app.config.Debug.EnableLogging,
-	}
-
-	if err := app.graphicsBackend.Initialize(graphicsConfig); err != nil {
+		WindowTitle:     "gones - Go NES Emulator",
+		WindowWidth:     app.config.Window.Width,
+		WindowHeight:    app.config.Window.Height,
+		Fullscreen:      app.config.Window.Fullscreen,
+		VSync:           app.config.Video.VSync,
+		Filter:          app.config.Video.Filter,
+		CRT:             graphics.CRTSettings(app.config.Video.CRT),
+		AspectRatio:     app.config.Video.AspectRatio,
+		TerminalMode:    app.config.Video.TerminalMode,
+		GamepadDeadzone: float64(app.config.Input.ControllerDeadzone),
+		GamepadAssignments: []string{
+			app.config.Input.Player1Gamepad,
+			app.config.Input.Player2Gamepad,
+			app.config.Input.Player3Gamepad,
+			app.config.Input.Player4Gamepad,
+		},
+		AudioEnabled:    app.config.Audio.Enabled,
+		AudioSampleRate: app.bus.APU.GetSampleRate(),
+		AudioBufferSize: app.config.Audio.BufferSize,
+		AudioLatency:    app.config.Audio.Latency,
+		Microphone:      app.config.Input.MicrophoneThreshold > 0,
+		Headless: headless,
+		Debug:    app.config.Debug.EnableLogging,
+	}
+
+	err = app.graphicsBackend.Initialize(graphicsConfig)
+	if err != nil && backendType == graphics.BackendSDL2 {
+		// SDL2 is optional (sdl2 build tag), so fall back to Ebitengine without it
+		fmt.Printf("[APP_WARNING] SDL2 backend failed (%v), falling back to Ebitengine\n", err)
+		backendType = graphics.BackendEbitengine
+		app.graphicsBackend, err = graphics.CreateBackend(backendType)
+		if err != nil {
+			return fmt.Errorf("failed to create fallback Ebitengine backend: %v", err)
+		}
+		err = app.graphicsBackend.Initialize(graphicsConfig)
+	}
+	if err != nil {
 		// If Ebitengine fails (e.g., no DISPLAY), fallback to headless mode
 		if backendType == graphics.BackendEbitengine {
 			fmt.Printf("[APP_WARNING] Ebitengine backend failed (%v), falling back to headless mode\n", err)
@@ -232,6 +443,8 @@ func (app *Application) initializeGraphicsBackend(headless bool) error {
 		if err != nil {
 			return fmt.Errorf("failed to create window: %v", err)
 		}
+		app.applyInputBindings()
+		app.library = app.config.OpenLibrary()
 	}
 
 	// Initialize video processor
@@ -240,12 +453,17 @@ func (app *Application) initializeGraphicsBackend(headless bool) error {
 		app.config.Video.Contrast,
 		app.config.Video.Saturation,
 	)
+	if err := app.videoProcessor.SetUpscaler(app.config.Video.Upscaler); err != nil {
+		fmt.Printf("[APP_WARNING] %v\n", err)
+	}
+	app.videoProcessor.SetFrameBlend(app.config.Video.FrameBlend)
+	if err := app.videoProcessor.SetColorFilter(app.config.Video.ColorFilter); err != nil {
+		fmt.Printf("[APP_WARNING] %v\n", err)
+	}
 
 	return nil
 }
 
-// Note: Audio functions removed to eliminate SDL2 dependency
-// Audio will be reimplemented using the graphics backend's audio capabilities
 
 // LoadROM loads a ROM file into the emulator
 func (app *Application) LoadROM(romPath string) error {
@@ -253,8 +471,8 @@ func (app *Application) LoadROM(romPath string) error {
 		return errors.New("application not initialized")
 	}
 
-	// Load cartridge
-	cart, err := cartridge.LoadFromFile(romPath)
+	// Load cartridge, patched before its header is parsed
+	data, patchPath, err := app.readROM(romPath)
 	if err != nil {
 		return &ApplicationError{
 			Component: "cartridge",
@@ -262,13 +480,63 @@ func (app *Application) LoadROM(romPath string) error {
 			Err:       err,
 		}
 	}
+	fixed, fixes := app.applyCompat(data)
+	cart, err := cartridge.LoadFromReader(bytes.NewReader(fixed))
+	if err != nil {
+		return &ApplicationError{
+			Component: "cartridge",
+			Operation: "load ROM",
+			Err:       err,
+		}
+	}
+	if patchPath != "" {
+		fmt.Printf("🩹 Patch: %s\n", filepath.Base(patchPath))
+	}
+	if fixes != nil {
+		fmt.Printf("🩺 Compatibility database: %s (%s)\n", fixes.Title, fixes)
+	}
+
+	// Persist battery RAM of the previous ROM before switching
+	if err := app.flushSRAM(true); err != nil {
+		fmt.Printf("[APP_ERROR] %v\n", err)
+	}
+	app.stopCDL()
+	app.stopTraces()
+	app.updateRecentROMs()
 
 	// Store cartridge and path
 	app.cartridge = cart
 	app.romPath = romPath
+	app.romHash = romDataHash(data)
+	app.states.SetROMChecksum(cart.ROMChecksum())
+	app.compatEntry = fixes
+	app.playTime = 0
+	app.lastAutoSavePlayTime = 0
+	app.ramSearch.search = nil
+	app.lastSRAMFlush = time.Now()
+	if app.replay != nil {
+		app.replay.Reset()
+	}
+
+	// Restore battery-backed save data
+	app.loadBatterySave()
 
 	// Load cartridge into bus
+	app.loadRAMPattern()
 	app.bus.LoadCartridge(cart)
+	app.loadRegion(romPath, cart)
+	app.loadGameProfile()
+	app.applyCheats()
+	app.startCDL()
+	app.startTraces()
+	inputConfig := app.inputConfig()
+	app.connectPort2Device()
+	app.bus.SetFourScore(inputConfig.FourScore)
+	app.bus.SetDMCConflicts(app.config.Emulation.DMCConflicts)
+	app.bus.SetPPUAlignment(ppuAlignment(fixes))
+	if inputConfig.FourScore && inputConfig.Port2Device != Port2Controller {
+		fmt.Printf("[APP_WARNING] Four Score and %s both enabled; the Four Score takes over port 2\n", inputConfig.Port2Device)
+	}
 
 	// Reset system
 	app.bus.Reset()
@@ -277,19 +545,24 @@ func (app *Application) LoadROM(romPath string) error {
 
 	// Update window title (if window exists)
 	if app.window != nil {
-		romName := filepath.Base(romPath)
-		title := fmt.Sprintf("gones - %s", romName)
+		title := fmt.Sprintf("gones - %s", app.romTitle(romPath))
 		app.window.SetTitle(title)
 	}
 
+	// Per-game binding overrides and profiles depend on the ROM
+	app.applyInputBindings()
+	app.updateRecentROMs()
+
 	// Start the emulator
 	app.emulator.Start()
 
 	return nil
 }
 
-// Run starts the main application loop
-func (app *Application) Run() error {
+// Run starts the main application loop, until the window is closed, Stop is
+// called or ctx is cancelled (such as by Ctrl+C). It returns nil on all of
+// them; Cleanup still has to be called to save and release everything.
+func (app *Application) Run(ctx context.Context) error {
 	if !app.initialized {
 		return errors.New("application not initialized")
 	}
@@ -297,6 +570,13 @@ func (app *Application) Run() error {
 	app.running = true
 	app.startTime = time.Now()
 	app.lastFPSTime = time.Now()
+	app.startServers()
+	app.startLibraryScan()
+
+	// Without a ROM the menu is the way to load one
+	if app.window != nil && app.cartridge == nil {
+		app.ShowMenu()
+	}
 
 	if app.config.Debug.EnableLogging {
 		fmt.Printf("[APP_DEBUG] Starting emulator with %s backend...\n", app.graphicsBackend.GetName())
@@ -311,12 +591,15 @@ func (app *Application) Run() error {
 			ebitengineWindow.SetEmulatorUpdateFunc(func() error {
 				frameStartTime := time.Now()
 				
-				// Process input events (no individual timing to reduce overhead)
+				// Process input events
+				inputStart := time.Now()
 				if err := app.processInput(); err != nil {
 					if app.config.Debug.EnableLogging {
 						fmt.Printf("[APP_ERROR] Input processing error: %v\n", err)
 					}
 				}
+				app.inputTime = time.Since(inputStart)
+				app.totalInputTime += app.inputTime
 				
 				// Update emulator state - this now runs exactly one frame
 				emulatorStart := time.Now()
@@ -324,6 +607,7 @@ func (app *Application) Run() error {
 					return err
 				}
 				app.emulatorTime = time.Since(emulatorStart)
+				app.totalEmulatorTime += app.emulatorTime
 				
 				// Render the frame
 				renderStart := time.Now()
@@ -331,6 +615,7 @@ func (app *Application) Run() error {
 					return err
 				}
 				app.renderTime = time.Since(renderStart)
+				app.totalRenderTime += app.renderTime
 				
 				// Simplified performance metrics update
 				app.updatePerformanceMetricsMinimal(frameStartTime)
@@ -339,6 +624,14 @@ func (app *Application) Run() error {
 				if app.window != nil && app.window.ShouldClose() {
 					app.Stop()
 				}
+				if ctx.Err() != nil {
+					app.Stop()
+				}
+
+				// Let Ebitengine close the window once the application stops
+				if !app.running {
+					app.window.Cleanup()
+				}
 				
 				return nil
 			})
@@ -387,9 +680,21 @@ func (app *Application) Run() error {
 		if app.window != nil && app.window.ShouldClose() {
 			app.Stop()
 		}
+		if ctx.Err() != nil {
+			app.Stop()
+		}
 
-		// Simple frame rate limiting for non-Ebitengine backends
-		time.Sleep(16 * time.Millisecond) // ~60 FPS
+		// Frame rate limiting for non-Ebitengine backends: block on the audio
+		// buffer when this tick queued audio, so video follows the audio
+		// clock and the two never drift apart. Otherwise wait until the tick
+		// is due to end on the pacer's timeline (a VSync present may have
+		// used some of it). The scheduler runs more or fewer frames per tick
+		// for other speeds. Unthrottled runs go straight on to the next tick.
+		if app.unthrottled || app.waitAudio() {
+			app.pacer.Restart()
+		} else {
+			app.pacer.Wait(frameStartTime, app.emulator.GetTargetFrameTime())
+		}
 	}
 
 	if app.config.Debug.EnableLogging {
@@ -398,25 +703,126 @@ func (app *Application) Run() error {
 	return nil
 }
 
-// updateEmulator updates the emulator state
+// updateEmulator runs the frames due on this tick at the current speed
 func (app *Application) updateEmulator() error {
+	app.audioQueued = false
+	app.serveRemote()
+	app.watchConfig()
+	app.watchFocus()
 	if !app.paused && app.cartridge != nil {
-		if err := app.emulator.Update(); err != nil {
+		var crash *CrashError
+		frames, err := app.scheduler.Tick(app.emulateFrame)
+		if err != nil && !errors.Is(err, errStopped) && !errors.As(err, &crash) {
 			return err
 		}
 
-		// Note: Audio processing will be added back when audio backend is implemented
+		// Play a frame of audio on backends that support it
+		app.audioQueued = app.queueAudio()
+		app.trackAVSync(frames)
+	} else {
+		app.trackAVSync(0)
 	}
 	return nil
 }
 
+// emulateFrame runs one frame of emulation and its per-frame bookkeeping
+func (app *Application) emulateFrame() (err error) {
+	// Write a crash report instead of taking the application down
+	defer func() {
+		if r := recover(); r != nil {
+			err = app.ReportCrash(fmt.Sprintf("panic: %v", r), debug.Stack())
+		}
+	}()
+
+	app.updateSpriteLimit()
+	if app.netplay != nil {
+		// The session runs the frame, or none while it waits for the other player
+		if !app.updateNetplay() {
+			return nil
+		}
+	} else {
+		app.applyFreezes()
+		app.applyMovie()
+		if err := app.emulator.Update(); err != nil {
+			return app.ReportCrash(err.Error(), nil)
+		}
+	}
+	if err := app.CheckJam(); err != nil {
+		return err
+	}
+	if app.emulator.Stopped() {
+		if app.breakHit != nil && app.breakHit.Kind != BreakExecute {
+			app.announceStop("watchpoint")
+		} else {
+			app.announceStop("breakpoint")
+		}
+		return errStopped
+	}
+	app.playTime += app.emulator.GetTargetFrameTime()
+	app.sessionPlayTime += app.emulator.GetTargetFrameTime()
+
+	// Periodic battery RAM flush and autosave snapshots
+	app.updateAutoSave()
+
+	// Capture the frame for an active recording and the replay buffer
+	app.recordFrame()
+	app.captureReplayFrame()
+	app.logScroll()
+	app.updateInstances()
+	return nil
+}
+
+// queueAudio sends the samples of the last frame emulated to the window's
+// audio output, scaled by the configured volume. One frame of audio is queued
+// per tick whatever the speed, which keeps the pitch: fast-forward plays the
+// last of the tick's frames, slow motion repeats a frame on the ticks that
+// emulate none. Fast-forward can be muted instead, and unthrottled runs are
+// silent. It reports whether audio was queued.
+func (app *Application) queueAudio() bool {
+	output, ok := app.window.(graphics.AudioOutput)
+	if !ok || !app.config.Audio.Enabled || app.unthrottled {
+		return false
+	}
+	if app.scheduler.FastForwarding() && app.config.Audio.FastForward == FastForwardAudioMute {
+		return false
+	}
+	samples := app.emulator.GetAudioSamples()
+	if cap(app.audioBuffer) < len(samples) {
+		app.audioBuffer = make([]float32, len(samples))
+	}
+	app.audioBuffer = app.audioBuffer[:len(samples)]
+	for i, sample := range samples {
+		app.audioBuffer[i] = sample * app.config.Audio.Volume
+	}
+	if err := output.QueueAudio(app.audioBuffer); err != nil {
+		if app.config.Debug.EnableLogging {
+			fmt.Printf("[APP_ERROR] Audio error: %v\n", err)
+		}
+		return false
+	}
+	return len(samples) > 0
+}
+
+// waitAudio blocks on the audio output's buffer after a tick that queued
+// audio. It returns false when the loop has to pace itself instead.
+func (app *Application) waitAudio() bool {
+	clock, ok := app.window.(graphics.AudioClock)
+	if !ok || !app.audioQueued {
+		return false
+	}
+	return clock.WaitAudio()
+}
+
 // processInput processes input events from graphics backend
 func (app *Application) processInput() error {
 	if app.window == nil {
 		return nil
 	}
 
-	events := app.window.PollEvents()
+	app.inputEvents = app.window.PollEvents(app.inputEvents[:0])
+	events := app.inputEvents
+	app.updateMicrophone()
+	app.pollInstances()
 
 	// Early return if no events to process - major performance optimization
 	if len(events) == 0 {
@@ -428,6 +834,8 @@ func (app *Application) processInput() error {
 	var controller2Changed bool
 	controller1Buttons := app.lastController1State // Start with cached state
 	controller2Buttons := app.lastController2State // Start with cached state
+	controller34Buttons := app.lastController34State
+	var controller34Changed [2]bool
 	
 	// Initialize input state cache on first run
 	if !app.inputStateInitialized && app.bus != nil && app.cartridge != nil {
@@ -476,8 +884,11 @@ func (app *Application) processInput() error {
 
 			// Update controller button array for atomic setting
 			if app.cartridge != nil {
-				// Check if this is a 2P controller button
-				if is2PButton(event.Button) {
+				// Four Score controllers 3 and 4
+				if player := graphics.ButtonPlayer(event.Button); player >= 2 {
+					controller34Buttons[player-2][graphics.ControllerButtonIndex(event.Button)] = event.Pressed
+					controller34Changed[player-2] = true
+				} else if is2PButton(event.Button) {
 					buttonIndex := get2PButtonIndex(event.Button)
 					if buttonIndex >= 0 {
 						controller2Buttons[buttonIndex] = event.Pressed
@@ -516,12 +927,30 @@ func (app *Application) processInput() error {
 
 		case graphics.InputEventTypeKey:
 			// Handle key events (function keys, etc.)
-			if app.handleKeyInput(event) {
+			if app.handleSpecialInput(event) || app.handleKeyInput(event) {
 				continue
 			}
+
+		case graphics.InputEventTypeMouse:
+			app.handleMouseInput(event)
+
+		case graphics.InputEventTypeGamepadDisconnected:
+			app.pauseForDisconnect(event.Player)
 		}
 	}
 
+	// A movie being played back owns the controllers
+	if app.MoviePlaying() {
+		return nil
+	}
+
+	// A netplay session sets both players' buttons, from player 1's controls
+	// here and the other player's over the network
+	if app.netplay != nil {
+		app.lastController1State = controller1Buttons
+		return nil
+	}
+
 	// Apply controller button state atomically ONLY if any buttons actually changed
 	if controller1Changed && app.bus != nil && app.cartridge != nil {
 		// Double-check that state actually changed to prevent redundant updates
@@ -552,6 +981,14 @@ func (app *Application) processInput() error {
 		}
 	}
 
+	for i, changed := range controller34Changed {
+		if changed && app.bus != nil && app.cartridge != nil &&
+			app.inputStateChanged(app.lastController34State[i], controller34Buttons[i]) {
+			app.bus.SetControllerButtons(3+i, controller34Buttons[i])
+			app.lastController34State[i] = controller34Buttons[i]
+		}
+	}
+
 	return nil
 }
 
@@ -565,53 +1002,35 @@ func (app *Application) inputStateChanged(oldState, newState [8]bool) bool {
 	return false
 }
 
-// handleSpecialInput handles special input combinations (menu, pause, etc.)
+// handleSpecialInput passes key presses to the overlay on screen, if any
 func (app *Application) handleSpecialInput(event graphics.InputEvent) bool {
 	// Only handle key press events for special combinations
 	if !event.Pressed {
 		return false
 	}
 
-	// Handle escape key for quitting - require double-tap within 3 seconds
-	if event.Type == graphics.InputEventTypeKey && event.Key == graphics.KeyEscape {
-		now := time.Now()
-		if !app.lastESCTime.IsZero() && now.Sub(app.lastESCTime) < 3*time.Second {
-			// Second ESC within 3 seconds - confirm quit
-			fmt.Println("👋 ESC double-tap confirmed - Shutting down emulator...")
-			app.Stop()
-			return true
-		} else {
-			// First ESC or too much time passed - warn user
-			fmt.Println("⚠️  ESC pressed - Press ESC again within 3 seconds to quit, or continue playing...")
-			app.lastESCTime = now
-			return true
-		}
+	// The slot picker captures all input while it is open
+	if app.slotPicker != nil && app.slotPicker.IsVisible() {
+		app.handleSlotPickerInput(event)
+		return true
 	}
 
-	// Reset ESC timer if any other key is pressed
-	if event.Type == graphics.InputEventTypeKey && event.Key != graphics.KeyEscape {
-		app.lastESCTime = time.Time{} // Reset ESC timer
+	// The pause menu likewise captures all input while it is open
+	if app.IsMenuVisible() {
+		app.handleMenuInput(event)
+		return true
 	}
 
-	// Handle function keys for save states
-	if event.Type == graphics.InputEventTypeKey {
-		switch event.Key {
-		case graphics.KeyF1, graphics.KeyF2, graphics.KeyF3, graphics.KeyF4, graphics.KeyF5,
-			 graphics.KeyF6, graphics.KeyF7, graphics.KeyF8, graphics.KeyF9, graphics.KeyF10:
-			slot := int(event.Key - graphics.KeyF1)
-			if event.Modifiers&graphics.ModifierShift != 0 {
-				// Load state
-				if err := app.LoadState(slot); err != nil {
-					fmt.Printf("Failed to load state %d: %v\n", slot, err)
-				}
-			} else {
-				// Save state
-				if err := app.SaveState(slot); err != nil {
-					fmt.Printf("Failed to save state %d: %v\n", slot, err)
-				}
-			}
-			return true
-		}
+	// So does the memory viewer, which also runs the pause/step debugger
+	if app.IsMemoryViewerVisible() {
+		app.handleMemoryViewerInput(event)
+		return true
+	}
+
+	// And the event viewer
+	if app.IsEventViewerVisible() {
+		app.handleEventViewerInput(event)
+		return true
 	}
 
 	// DISABLED: Removed Select button pause functionality to allow Select to reach the game
@@ -624,7 +1043,7 @@ func (app *Application) handleSpecialInput(event graphics.InputEvent) bool {
 	// }
 
 	// DISABLED: Select button alone for pause - this was intercepting Select button from games
-	// if event.Button == graphics.ButtonSelect && !app.showMenu {
+	// if event.Button == graphics.ButtonSelect && !app.IsMenuVisible() {
 	//	app.TogglePause()
 	//	return true
 	// }
@@ -632,12 +1051,6 @@ func (app *Application) handleSpecialInput(event graphics.InputEvent) bool {
 	return false
 }
 
-// handleKeyInput handles key input events
-func (app *Application) handleKeyInput(event graphics.InputEvent) bool {
-	// Handle other key events here
-	return false
-}
-
 // isButtonPressed checks if a button is currently pressed
 func (app *Application) isButtonPressed(button graphics.Button) bool {
 	// This is a simplified check - in a real implementation,
@@ -714,32 +1127,48 @@ func (app *Application) render() error {
 		return nil
 	}
 
-	// Render emulator output (if ROM loaded)
-	if app.cartridge != nil {
-		frameBufferSlice := app.bus.GetFrameBuffer()
-		
-		// Apply video processing if configured
-		if app.videoProcessor != nil {
-			frameBufferSlice = app.videoProcessor.ProcessFrame(frameBufferSlice)
+	// Render emulator output (if ROM loaded), or a blank screen behind the menu
+	if app.cartridge != nil || app.IsMenuVisible() {
+		frameBuffer := &app.frameBuffer
+		if app.cartridge != nil {
+			frameBufferSlice := app.bus.GetFrameBuffer()
+
+			// Apply video processing if configured
+			if app.videoProcessor != nil {
+				frameBufferSlice = app.videoProcessor.ProcessFrame(frameBufferSlice)
+				frameBufferSlice = app.blendFrame(frameBufferSlice)
+			}
+
+			// Copy into the frame the overlays are drawn on, so the PPU's
+			// own frame stays clean
+			copy(frameBuffer[:], frameBufferSlice)
+		} else {
+			*frameBuffer = [256 * 240]uint32{}
 		}
-		
-		// Convert slice to array
-		var frameBuffer [256 * 240]uint32
-		copy(frameBuffer[:], frameBufferSlice)
-		if err := app.window.RenderFrame(frameBuffer); err != nil {
-			return fmt.Errorf("failed to render NES frame: %v", err)
+
+		// Draw the save state slot picker over the game image
+		if app.slotPicker != nil {
+			app.slotPicker.Render(frameBuffer)
+		}
+		app.renderRebindPrompt(frameBuffer)
+		app.renderRecordingIndicator(frameBuffer)
+		app.renderSpeedIndicator(frameBuffer)
+		app.renderNetplayIndicator(frameBuffer)
+		app.renderMemoryViewer(frameBuffer)
+		app.renderEventViewer(frameBuffer)
+		app.renderScrollOverlay(frameBuffer)
+		app.renderPerfOverlay(frameBuffer)
+		app.renderAudioVisualizer(frameBuffer)
+		app.renderMenu(frameBuffer)
+
+		if err := app.presentFrame(frameBuffer); err != nil {
+			return err
 		}
 	}
 
-	// Render UI overlays (TODO: Update UI system for new graphics backend)
-	// if app.showMenu && app.ui != nil {
-	//     if err := app.ui.RenderMenu(); err != nil {
-	//         return fmt.Errorf("failed to render menu: %v", err)
-	//     }
-	// }
-
 	// Present frame
 	app.window.SwapBuffers()
+	app.renderInstances()
 
 	return nil
 }
@@ -751,6 +1180,7 @@ func (app *Application) updatePerformanceMetrics(frameStartTime time.Time) {
 
 	// Calculate frame time
 	frameTime := now.Sub(frameStartTime)
+	app.recordFrameTime(frameTime)
 	
 	// Initialize timing on first frame
 	if app.lastFrameTime.IsZero() {
@@ -896,6 +1326,7 @@ func (app *Application) updatePerformanceMetricsMinimal(frameStartTime time.Time
 	
 	// Calculate frame time
 	frameTime := now.Sub(frameStartTime)
+	app.recordFrameTime(frameTime)
 	
 	// Initialize timing on first frame
 	if app.lastFrameTime.IsZero() {
@@ -905,6 +1336,11 @@ func (app *Application) updatePerformanceMetricsMinimal(frameStartTime time.Time
 		app.minFrameTime = frameTime
 		app.maxFrameTime = frameTime
 		app.lastFPSLog = now
+
+		// Memory baseline for the growth metric
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+		app.initialMemoryUsage = memStats.Alloc
 		return
 	}
 	
@@ -1060,21 +1496,27 @@ func (app *Application) TogglePause() {
 	app.paused = !app.paused
 }
 
-// ShowMenu shows the menu
+// ShowMenu opens the pause menu, pausing emulation while it is shown
 func (app *Application) ShowMenu() {
-	app.showMenu = true
+	if app.menu.IsVisible() {
+		return
+	}
+	app.menu.Open(app.mainMenuPage(), app.paused)
 	app.paused = true
 }
 
-// HideMenu hides the menu
+// HideMenu closes the pause menu, saving changed settings and restoring
+// the pause state from before it was opened
 func (app *Application) HideMenu() {
-	app.showMenu = false
-	app.paused = false
+	if !app.menu.IsVisible() {
+		return
+	}
+	app.paused = app.menu.Close()
 }
 
 // ToggleMenu toggles menu visibility
 func (app *Application) ToggleMenu() {
-	if app.showMenu {
+	if app.IsMenuVisible() {
 		app.HideMenu()
 	} else {
 		app.ShowMenu()
@@ -1087,7 +1529,18 @@ func (app *Application) SaveState(slot int) error {
 		return errors.New("no ROM loaded")
 	}
 
-	return app.states.SaveState(app.bus, slot, app.romPath)
+	return app.states.SaveState(app.bus, slot, app.romPath, app.playTime)
+}
+
+// ImportState loads a save state file, such as the one in a crash report
+func (app *Application) ImportState(path string) error {
+	if app.cartridge == nil {
+		return errors.New("no ROM loaded")
+	}
+	if app.netplay != nil {
+		return errNetplayState
+	}
+	return app.states.ImportState(app.bus, path, app.romPath)
 }
 
 // LoadState loads a saved emulator state
@@ -1095,15 +1548,141 @@ func (app *Application) LoadState(slot int) error {
 	if app.cartridge == nil {
 		return errors.New("no ROM loaded")
 	}
+	if app.netplay != nil {
+		return errNetplayState
+	}
 
-	return app.states.LoadState(app.bus, slot, app.romPath)
+	if err := app.states.LoadState(app.bus, slot, app.romPath); err != nil {
+		return err
+	}
+
+	// Continue counting play time from where the state was saved
+	slots := app.states.GetSlotInfo(app.romPath)
+	if slot >= 0 && slot < len(slots) && slots[slot].Used {
+		app.playTime = slots[slot].PlayTime
+		app.lastAutoSavePlayTime = app.playTime
+	}
+
+	return nil
 }
 
-// Reset resets the emulator
+// OpenSlotPicker shows the save state slot picker with the given slot selected
+func (app *Application) OpenSlotPicker(mode SlotPickerMode, slot int) {
+	if app.cartridge == nil || app.states == nil || app.slotPicker == nil {
+		return
+	}
+
+	app.slotPicker.Open(mode, slot, app.states.GetSlotInfo(app.romPath), app.paused)
+	app.paused = true
+}
+
+// CloseSlotPicker hides the slot picker and restores the previous pause state
+func (app *Application) CloseSlotPicker() {
+	if app.slotPicker == nil || !app.slotPicker.IsVisible() {
+		return
+	}
+	app.paused = app.slotPicker.Close()
+}
+
+// IsSlotPickerVisible returns whether the slot picker is on screen
+func (app *Application) IsSlotPickerVisible() bool {
+	return app.slotPicker != nil && app.slotPicker.IsVisible()
+}
+
+// handleSlotPickerInput navigates the slot picker and performs the save/load on confirm
+func (app *Application) handleSlotPickerInput(event graphics.InputEvent) {
+	confirm := false
+
+	switch event.Type {
+	case graphics.InputEventTypeKey:
+		switch event.Key {
+		case graphics.KeyEscape:
+			app.CloseSlotPicker()
+			return
+		default:
+			// Pressing the highlighted slot's key again confirms, another key jumps to that slot
+			slot := app.config.Hotkeys.slotForKey(event.Key)
+			if slot < 0 {
+				break
+			}
+			if slot == app.slotPicker.GetSelected() {
+				confirm = true
+			} else {
+				app.slotPicker.Move(slot - app.slotPicker.GetSelected())
+			}
+		}
+
+	case graphics.InputEventTypeButton:
+		switch event.Button {
+		case graphics.ButtonUp, graphics.ButtonLeft:
+			app.slotPicker.Move(-1)
+		case graphics.ButtonDown, graphics.ButtonRight:
+			app.slotPicker.Move(1)
+		case graphics.ButtonStart, graphics.ButtonA:
+			confirm = true
+		case graphics.ButtonB:
+			app.CloseSlotPicker()
+			return
+		}
+	}
+
+	if !confirm {
+		return
+	}
+
+	slot := app.slotPicker.GetSelected()
+	if app.slotPicker.GetMode() == SlotPickerLoad {
+		if err := app.LoadState(slot); err != nil {
+			fmt.Printf("Failed to load state %d: %v\n", slot, err)
+			app.slotPicker.SetMessage(fmt.Sprintf("LOAD FAILED: SLOT %d", slot+1))
+			return
+		}
+	} else {
+		if err := app.SaveState(slot); err != nil {
+			fmt.Printf("Failed to save state %d: %v\n", slot, err)
+			app.slotPicker.SetMessage(fmt.Sprintf("SAVE FAILED: SLOT %d", slot+1))
+			return
+		}
+	}
+
+	app.CloseSlotPicker()
+}
+
+// GetPlayTime returns the emulated play time for the current ROM
+func (app *Application) GetPlayTime() time.Duration {
+	return app.playTime
+}
+
+// Reset presses the console's reset button: the CPU, PPU and APU start over,
+// while internal RAM and the cartridge (mapper registers, PRG RAM and CHR
+// RAM) keep what they hold. See PowerCycle for turning the console off and
+// on.
 func (app *Application) Reset() {
+	if app.netplay != nil {
+		fmt.Println("[APP_WARNING] The game cannot be reset during netplay")
+		return
+	}
 	if app.bus != nil {
 		app.bus.Reset()
+		fmt.Println("🔄 Reset")
+	}
+}
+
+// PowerCycle turns the console off and on: the ROM is loaded again, so
+// internal RAM starts from emulation.ram_pattern and the cartridge from its
+// power-on state. Only battery-backed RAM is kept, saved and loaded again.
+func (app *Application) PowerCycle() error {
+	if app.cartridge == nil {
+		return errors.New("no ROM loaded")
 	}
+	if app.netplay != nil {
+		return errors.New("the console cannot be power cycled during netplay")
+	}
+	if err := app.LoadROM(app.romPath); err != nil {
+		return err
+	}
+	fmt.Println("🔌 Power cycled")
+	return nil
 }
 
 // IsRunning returns whether the application is running
@@ -1118,7 +1697,7 @@ func (app *Application) IsPaused() bool {
 
 // IsMenuVisible returns whether the menu is visible
 func (app *Application) IsMenuVisible() bool {
-	return app.showMenu
+	return app.menu != nil && app.menu.IsVisible()
 }
 
 // GetFPS returns the current FPS
@@ -1212,7 +1791,30 @@ func (app *Application) Cleanup() error {
 
 	var lastErr error
 
-	// Note: Audio cleanup will be handled by the graphics backend when audio is reimplemented
+	// Persist battery RAM and the exit autosave before tearing anything down
+	app.saveOnExit()
+	app.finishMovieRecording()
+	app.StopRemote()
+	app.StopNetplay()
+
+	// Finish an active recording so the file is complete
+	if app.recorder != nil {
+		if err := app.StopRecording(); err != nil {
+			lastErr = err
+			fmt.Printf("[APP_ERROR] Recording cleanup error: %v\n", err)
+		}
+	}
+	if app.scrollLog != nil {
+		path := app.scrollLog.Path()
+		if err := app.StopScrollLog(); err != nil {
+			lastErr = err
+			fmt.Printf("[APP_ERROR] Scroll log cleanup error: %v\n", err)
+		} else {
+			fmt.Printf("📈 Scroll log saved: %s\n", path)
+		}
+	}
+
+	// Audio devices are closed with the window
 
 	// Clean up components
 	if app.states != nil {
@@ -1231,6 +1833,9 @@ func (app *Application) Cleanup() error {
 		}
 	}
 
+	// Close the extra windows before the main one
+	app.closeInstances()
+
 	// Clean up graphics window
 	if app.window != nil {
 		if err := app.window.Cleanup(); err != nil {
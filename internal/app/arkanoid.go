@@ -0,0 +1,50 @@
+// Package app provides Arkanoid paddle input handling and port 2 device selection.
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"gones/internal/graphics"
+	"gones/internal/input"
+)
+
+// handlePaddleMouse turns the paddle knob with the mouse X position; the left
+// button presses the paddle button
+func (app *Application) handlePaddleMouse(paddle *input.Arkanoid, event graphics.InputEvent) {
+	paddle.SetScreenX(event.MouseX)
+	paddle.SetButton(event.MouseButtons&graphics.MouseButtonLeft != 0)
+}
+
+// SetPort2Device selects the device plugged into port 2: "controller", "zapper" or "arkanoid"
+func (app *Application) SetPort2Device(device string) error {
+	device = strings.ToLower(device)
+	switch device {
+	case Port2Controller, Port2Zapper, Port2Arkanoid:
+	default:
+		return fmt.Errorf("unknown port 2 device: %s", device)
+	}
+
+	app.config.Input.Port2Device = device
+	app.config.Input.Zapper = device == Port2Zapper
+	if app.gameProfile != nil {
+		app.gameProfile.Port2Device = nil // An explicit choice wins over the game profile
+	}
+	app.connectPort2Device()
+	return nil
+}
+
+// GetPort2Device returns the name of the device plugged into port 2
+func (app *Application) GetPort2Device() string {
+	return app.inputConfig().Port2Device
+}
+
+// connectPort2Device plugs the configured port 2 device into the bus
+func (app *Application) connectPort2Device() {
+	if app.bus == nil {
+		return
+	}
+	device := app.inputConfig().Port2Device
+	app.bus.ConnectZapper(device == Port2Zapper)
+	app.bus.ConnectArkanoid(device == Port2Arkanoid)
+}
@@ -0,0 +1,140 @@
+// Package app provides the audio visualizer, an oscilloscope trace of each
+// APU channel and each expansion chip channel over the picture, drawn from
+// their outputs before mixing.
+package app
+
+import (
+	"fmt"
+
+	"gones/internal/apu"
+	"gones/internal/graphics"
+)
+
+const (
+	// The panel spans the bottom of the picture
+	visualizerLeft  = 2
+	visualizerWidth = graphics.OverlayWidth - 4
+
+	// visualizerFewChannels is the most channels drawn two to a row with
+	// tall traces; more, with expansion chips, are drawn four to a row with
+	// short ones
+	visualizerFewChannels = 8
+
+	// visualizerExpansionColor is the color of the expansion chips' traces
+	visualizerExpansionColor = 0x60A0FF
+)
+
+// visualizerChannels names and colors the APU's channels on the scope
+var visualizerChannels = [apu.ScopeExpansion]struct {
+	name  string
+	color uint32
+}{
+	apu.ScopePulse1:   {"PULSE 1", graphics.OverlayColorYellow},
+	apu.ScopePulse2:   {"PULSE 2", graphics.OverlayColorYellow},
+	apu.ScopeTriangle: {"TRIANGLE", graphics.OverlayColorGreen},
+	apu.ScopeNoise:    {"NOISE", graphics.OverlayColorWhite},
+	apu.ScopeDMC:      {"DMC", graphics.OverlayColorRed},
+}
+
+// ToggleAudioVisualizer handles the audio visualizer hotkey
+func (app *Application) ToggleAudioVisualizer() {
+	app.audioVisualizer = !app.audioVisualizer
+	if app.bus != nil {
+		app.bus.APU.SetScope(app.audioVisualizer)
+	}
+	if app.audioVisualizer {
+		fmt.Println("🔊 Audio visualizer on")
+	} else {
+		fmt.Println("🔊 Audio visualizer off")
+	}
+}
+
+// renderAudioVisualizer draws a trace of the last frame of each channel's
+// output, started where it rises through its middle so tones hold still
+func (app *Application) renderAudioVisualizer(frameBuffer *[256 * 240]uint32) {
+	if !app.audioVisualizer || app.bus == nil {
+		return
+	}
+	// A frame of audio samples, and as many before to find the start in
+	frame := max(int(float64(app.bus.APU.GetSampleRate())/app.bus.GetFrameRate()), 1)
+	if cap(app.visualizerSamples) < 2*frame {
+		app.visualizerSamples = make([]float32, 2*frame)
+	}
+	samples := app.visualizerSamples[:min(2*frame, apu.ScopeLength)]
+	frame = len(samples) / 2
+
+	// The expansion chip's channels follow the APU's, or a cell saying there
+	// is none
+	expansion := app.bus.APU.ScopeExpansionNames()
+	channels := apu.ScopeExpansion + max(len(expansion), 1)
+	columns, traceHeight := 2, 18
+	if channels > visualizerFewChannels {
+		columns, traceHeight = 4, 10
+	}
+	rowHeight := graphics.LineHeight + traceHeight + 2
+	height := (channels+columns-1)/columns*rowHeight + 4
+	top := graphics.OverlayHeight - height - 2
+
+	graphics.DarkenRect(frameBuffer, visualizerLeft, top, visualizerWidth, height, 2)
+	cellWidth := visualizerWidth / columns
+	for channel := 0; channel < channels; channel++ {
+		x := visualizerLeft + 2 + channel%columns*cellWidth
+		y := top + 2 + channel/columns*rowHeight
+		name, color := "NO EXPANSION", uint32(visualizerExpansionColor)
+		if channel < apu.ScopeExpansion {
+			name, color = visualizerChannels[channel].name, visualizerChannels[channel].color
+		} else if channel-apu.ScopeExpansion < len(expansion) {
+			name = expansion[channel-apu.ScopeExpansion]
+		} else {
+			graphics.DrawTextShadowed(frameBuffer, x, y, name, graphics.OverlayColorGray)
+			continue
+		}
+		graphics.DrawTextShadowed(frameBuffer, x, y, name, color)
+		if !app.bus.APU.Scope(channel, samples) {
+			continue
+		}
+		start := traceStart(samples[:frame+1])
+		drawTrace(frameBuffer, x, y+graphics.LineHeight, cellWidth-4, traceHeight, samples[start:start+frame], color)
+	}
+}
+
+// traceStart returns where a trace of the samples after the first
+// len(samples)-1 starts: the first sample rising through the middle of their
+// range, else the last, for the most recent samples
+func traceStart(samples []float32) int {
+	low, high := samples[0], samples[0]
+	for _, v := range samples {
+		low, high = min(low, v), max(high, v)
+	}
+	middle := (low + high) / 2
+	for i := 1; i < len(samples); i++ {
+		if samples[i-1] < middle && samples[i] >= middle {
+			return i
+		}
+	}
+	return len(samples) - 1
+}
+
+// drawTrace draws samples from 0 to 1 as an oscilloscope trace width by
+// height pixels, each column spanning the samples it covers
+func drawTrace(frameBuffer *[256 * 240]uint32, x, y, width, height int, samples []float32, color uint32) {
+	graphics.FillRect(frameBuffer, x, y+height/2, width, 1, graphics.OverlayColorPanel)
+	level := func(v float32) int {
+		return y + height - 1 - int(v*float32(height-1)+0.5)
+	}
+	previous := -1
+	for column := 0; column < width; column++ {
+		from := column * len(samples) / width
+		to := max((column+1)*len(samples)/width, from+1)
+		top, bottom := level(samples[from]), level(samples[from])
+		for _, v := range samples[from:to] {
+			top, bottom = min(top, level(v)), max(bottom, level(v))
+		}
+		// Join the column to the last, so steps show as lines
+		if previous >= 0 {
+			top, bottom = min(top, previous), max(bottom, previous)
+		}
+		graphics.FillRect(frameBuffer, x+column, top, 1, bottom-top+1, color)
+		previous = level(samples[to-1])
+	}
+}
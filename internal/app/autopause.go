@@ -0,0 +1,53 @@
+// Package app provides the automatic pause while the window is in the
+// background or a player's gamepad is unplugged.
+package app
+
+import (
+	"fmt"
+
+	"gones/internal/graphics"
+)
+
+// watchFocus pauses emulation when the window loses the focus, and resumes
+// it (or shows the menu) when the focus returns. Netplay keeps running, as
+// the other player would wait.
+func (app *Application) watchFocus() {
+	window, ok := app.window.(graphics.WindowFocus)
+	if !ok {
+		return
+	}
+	focused := window.IsFocused()
+	if focused != app.unfocused {
+		return // No change
+	}
+	app.unfocused = !focused
+
+	if !focused {
+		if app.config.Emulation.PauseOnFocusLoss && !app.paused && app.cartridge != nil && app.netplay == nil {
+			app.paused = true
+			app.autoPaused = true
+		}
+		return
+	}
+
+	// Only a pause nothing else took over since
+	if !app.autoPaused || !app.paused {
+		app.autoPaused = false
+		return
+	}
+	app.autoPaused = false
+	app.paused = false
+	if !app.config.Emulation.ResumeOnFocus {
+		app.ShowMenu() // Closing it resumes
+	}
+}
+
+// pauseForDisconnect opens the pause menu when a player's gamepad is
+// unplugged, so the game does not go on without them
+func (app *Application) pauseForDisconnect(player int) {
+	if !app.config.Emulation.PauseOnGamepadDisconnect || app.cartridge == nil || app.netplay != nil {
+		return
+	}
+	app.ShowMenu()
+	app.menu.SetMessage(fmt.Sprintf("PLAYER %d GAMEPAD DISCONNECTED", player+1))
+}
@@ -0,0 +1,156 @@
+// Package app provides periodic autosave and battery RAM persistence.
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// getSRAMPath returns the .sav file path for the current ROM's battery RAM
+func (app *Application) getSRAMPath() string {
+	romName := filepath.Base(app.romPath)
+	romNameWithoutExt := romName[:len(romName)-len(filepath.Ext(romName))]
+	return filepath.Join(app.config.Paths.SaveData, romNameWithoutExt+".sav")
+}
+
+// loadBatterySave loads battery RAM for the current cartridge if a .sav file exists
+func (app *Application) loadBatterySave() {
+	if app.cartridge == nil || !app.cartridge.HasBattery() {
+		return
+	}
+
+	path := app.getSRAMPath()
+	if err := app.cartridge.LoadSRAMFromFile(path); err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("[APP_WARNING] Failed to load battery save %s: %v\n", path, err)
+		}
+		return
+	}
+
+	if app.config.Debug.EnableLogging {
+		fmt.Printf("[APP_DEBUG] Loaded battery save from %s\n", path)
+	}
+}
+
+// flushSRAM writes battery RAM to disk if it changed. Unless force is set,
+// writes are rate limited by the configured flush interval.
+func (app *Application) flushSRAM(force bool) error {
+	if app.cartridge == nil || !app.cartridge.HasBattery() || !app.cartridge.IsSRAMDirty() {
+		return nil
+	}
+
+	interval := time.Duration(app.config.Emulation.SRAMFlushInterval) * time.Second
+	if !force && (interval <= 0 || time.Since(app.lastSRAMFlush) < interval) {
+		return nil
+	}
+
+	app.lastSRAMFlush = time.Now()
+	path := app.getSRAMPath()
+	if err := app.cartridge.SaveSRAMToFile(path); err != nil {
+		return fmt.Errorf("failed to flush battery save: %v", err)
+	}
+
+	if app.config.Debug.EnableLogging {
+		fmt.Printf("[APP_DEBUG] Flushed battery save to %s\n", path)
+	}
+	return nil
+}
+
+// updateAutoSave runs the periodic SRAM flush and autosave snapshot checks.
+// Called once per emulated frame.
+func (app *Application) updateAutoSave() {
+	if app.cartridge == nil {
+		return
+	}
+
+	if err := app.flushSRAM(false); err != nil {
+		fmt.Printf("[APP_ERROR] %v\n", err)
+	}
+
+	interval := time.Duration(app.config.Emulation.AutoSaveInterval) * time.Second
+	if interval <= 0 || app.states == nil {
+		return
+	}
+
+	// Autosaves are scheduled on play time so paused time doesn't trigger snapshots
+	if app.playTime-app.lastAutoSavePlayTime < interval {
+		return
+	}
+	app.lastAutoSavePlayTime = app.playTime
+
+	if err := app.AutoSave(); err != nil {
+		fmt.Printf("[APP_ERROR] Autosave failed: %v\n", err)
+	}
+}
+
+// AutoSave writes a rotating autosave snapshot immediately
+func (app *Application) AutoSave() error {
+	if app.cartridge == nil {
+		return fmt.Errorf("no ROM loaded")
+	}
+
+	index, err := app.states.SaveAutoState(app.bus, app.romPath, app.playTime, app.config.Emulation.AutoSaveSlots)
+	if err != nil {
+		return err
+	}
+
+	if app.config.Debug.EnableLogging {
+		fmt.Printf("[APP_DEBUG] Autosave written to slot %d\n", index)
+	}
+	return nil
+}
+
+// LoadAutoSave loads one of the rotating autosave snapshots
+func (app *Application) LoadAutoSave(index int) error {
+	if app.cartridge == nil {
+		return fmt.Errorf("no ROM loaded")
+	}
+
+	if err := app.states.LoadAutoState(app.bus, index, app.romPath); err != nil {
+		return err
+	}
+
+	slots := app.states.GetAutoSaveInfo(app.romPath, app.config.Emulation.AutoSaveSlots)
+	if index >= 0 && index < len(slots) && slots[index].Used {
+		app.playTime = slots[index].PlayTime
+		app.lastAutoSavePlayTime = app.playTime
+	}
+	return nil
+}
+
+// GetAutoSaveInfo returns metadata for the rotating autosave slots
+func (app *Application) GetAutoSaveInfo() []StateSlotInfo {
+	if app.states == nil || app.romPath == "" {
+		return nil
+	}
+	return app.states.GetAutoSaveInfo(app.romPath, app.config.Emulation.AutoSaveSlots)
+}
+
+// saveOnExit flushes battery RAM, the play time of the recent list, the code/data log
+// and the trace, and writes the exit autosave (if enabled)
+func (app *Application) saveOnExit() {
+	if app.cartridge == nil {
+		return
+	}
+
+	if err := app.flushSRAM(true); err != nil {
+		fmt.Printf("[APP_ERROR] %v\n", err)
+	}
+	app.updateRecentROMs()
+	if app.cdl != nil {
+		if err := app.SaveCDL(); err != nil {
+			fmt.Printf("[APP_ERROR] %v\n", err)
+		}
+	}
+	if err := app.StopTrace(); err != nil {
+		fmt.Printf("[APP_ERROR] Trace failed: %v\n", err)
+	}
+
+	if app.config.Emulation.AutoSave && app.states != nil {
+		if err := app.AutoSave(); err != nil {
+			fmt.Printf("[APP_ERROR] Exit autosave failed: %v\n", err)
+		}
+	}
+}
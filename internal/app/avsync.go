@@ -0,0 +1,74 @@
+// Package app provides audio/video sync tracking, which measures how far the
+// video has drifted from the audio device's clock and drops or repeats a
+// frame to bring the two back together.
+package app
+
+import (
+	"time"
+
+	"gones/internal/graphics"
+)
+
+const (
+	// avSyncWarmup is how many ticks at normal speed pass before drift is
+	// measured, so the audio queue has filled to its latency
+	avSyncWarmup = 60
+
+	// avDriftLimit is how far the video may drift from the audio, on top of
+	// the device buffer, before a frame is dropped or repeated
+	avDriftLimit = 50 * time.Millisecond
+)
+
+// avSync compares the frames emulated with the samples the audio device has
+// played while both run at normal speed. The device plays on its own clock;
+// when the loop is paced on another (VSync, or sleeps once the device stops
+// taking samples), the two drift apart and the audio queue overflows or
+// runs dry.
+type avSync struct {
+	ticks  int    // Ticks at normal speed so far, up to avSyncWarmup
+	played uint64 // Samples the device had played when measuring started
+	frames uint64 // Frames emulated since
+
+	drift    time.Duration // Video ahead of the audio, or behind when negative
+	dropped  uint64        // Frames skipped to catch up with the audio
+	repeated uint64        // Frames shown twice to let the audio catch up
+}
+
+// trackAVSync measures the drift after a tick that ran frames and corrects
+// it with audio.sync_correction on. Anything but a tick at normal speed with
+// audio queued starts the measurement over.
+func (app *Application) trackAVSync(frames int) {
+	sync := &app.avSync
+	position, ok := app.window.(graphics.AudioPosition)
+	if !ok || !app.audioQueued || app.scheduler.Speed() != 1 {
+		sync.ticks, sync.drift = 0, 0
+		return
+	}
+	played := position.AudioPlayed()
+	if sync.ticks < avSyncWarmup {
+		sync.ticks++
+		sync.played, sync.frames = played, 0
+		return
+	}
+
+	rate := time.Duration(app.bus.APU.GetSampleRate())
+	sync.frames += uint64(frames)
+	video := time.Duration(sync.frames) * app.emulator.GetTargetFrameTime()
+	audio := time.Duration(played-sync.played) * time.Second / rate
+	sync.drift = video - audio
+	if !app.config.Audio.SyncCorrection {
+		return
+	}
+
+	// The device takes samples a buffer at a time, so the drift measured
+	// jumps by up to a buffer
+	limit := avDriftLimit + time.Duration(app.config.Audio.BufferSize)*time.Second/rate
+	switch {
+	case sync.drift > limit:
+		app.scheduler.Nudge(-1)
+		sync.repeated++
+	case sync.drift < -limit:
+		app.scheduler.Nudge(1)
+		sync.dropped++
+	}
+}
@@ -0,0 +1,271 @@
+// Package app provides input binding configuration and runtime rebinding.
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gones/internal/graphics"
+)
+
+// RebindRequest describes which binding the next key or gamepad press replaces
+type RebindRequest struct {
+	Player     int  // 0 = player 1 ... 3 = player 4
+	Button     int  // Index in graphics.ControllerButtons (A, B, Select, Start, Up, Down, Left, Right)
+	Gamepad    bool // Rebind the gamepad button instead of the key
+	ForGame    bool // Store the binding as an override for the current ROM only
+	AllButtons bool // Continue with the following buttons after this one ("press key for ...")
+}
+
+// rebindState tracks an in-progress rebind and what to restore afterwards
+type rebindState struct {
+	request   RebindRequest
+	wasPaused bool
+}
+
+// defaultPadButtons returns the default gamepad mapping used by both players
+func defaultPadButtons() KeyMapping {
+	var m KeyMapping
+	m.setBindings(graphics.DefaultGamepadBindings())
+	return m
+}
+
+// fields returns pointers to the mapping entries in graphics.ControllerButtons order
+func (m *KeyMapping) fields() [8]*string {
+	return [8]*string{&m.A, &m.B, &m.Select, &m.Start, &m.Up, &m.Down, &m.Left, &m.Right}
+}
+
+// bindings parses the mapping into per-button binding lists
+func (m KeyMapping) bindings() graphics.ButtonBindings {
+	var b graphics.ButtonBindings
+	for i, field := range m.fields() {
+		b[i] = graphics.ParseBindingList(*field)
+	}
+	return b
+}
+
+// setBindings stores binding lists back into the mapping
+func (m *KeyMapping) setBindings(b graphics.ButtonBindings) {
+	for i, field := range m.fields() {
+		*field = graphics.FormatBindingList(b[i])
+	}
+}
+
+// applyTo replaces the buttons that have a non-empty entry in the mapping
+func (m KeyMapping) applyTo(base graphics.ButtonBindings) graphics.ButtonBindings {
+	for i, field := range m.fields() {
+		if names := graphics.ParseBindingList(*field); len(names) > 0 {
+			base[i] = names
+		}
+	}
+	return base
+}
+
+// mappings returns pointers to the keyboard and gamepad mappings of every player
+func (g *GameInputBindings) mappings() (keys, pads [graphics.MaxGamepadPlayers]*KeyMapping) {
+	return [graphics.MaxGamepadPlayers]*KeyMapping{&g.Player1Keys, &g.Player2Keys, &g.Player3Keys, &g.Player4Keys},
+		[graphics.MaxGamepadPlayers]*KeyMapping{&g.Player1PadButtons, &g.Player2PadButtons, &g.Player3PadButtons, &g.Player4PadButtons}
+}
+
+// mappings returns pointers to the keyboard and gamepad mappings of every player
+func (c *InputConfig) mappings() (keys, pads [graphics.MaxGamepadPlayers]*KeyMapping) {
+	return [graphics.MaxGamepadPlayers]*KeyMapping{&c.Player1Keys, &c.Player2Keys, &c.Player3Keys, &c.Player4Keys},
+		[graphics.MaxGamepadPlayers]*KeyMapping{&c.Player1PadButtons, &c.Player2PadButtons, &c.Player3PadButtons, &c.Player4PadButtons}
+}
+
+// gameBindingsKey returns the GameBindings key for a ROM path
+func gameBindingsKey(romPath string) string {
+	name := filepath.Base(romPath)
+	return strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+}
+
+// GetInputBindings returns the bindings for a ROM, with its per-game overrides applied
+func (c *InputConfig) GetInputBindings(romPath string) graphics.InputBindings {
+	var bindings graphics.InputBindings
+	keys, pads := c.mappings()
+	for player := 0; player < graphics.MaxGamepadPlayers; player++ {
+		bindings.Keyboard[player] = keys[player].bindings()
+		bindings.Gamepad[player] = pads[player].bindings()
+	}
+
+	if romPath == "" {
+		return bindings
+	}
+	if override := c.GameBindings[gameBindingsKey(romPath)]; override != nil {
+		override.applyTo(&bindings)
+	}
+	return bindings
+}
+
+// applyTo replaces the bindings that have a non-empty entry in the overrides
+func (g *GameInputBindings) applyTo(bindings *graphics.InputBindings) {
+	keys, pads := g.mappings()
+	for player := 0; player < graphics.MaxGamepadPlayers; player++ {
+		bindings.Keyboard[player] = keys[player].applyTo(bindings.Keyboard[player])
+		bindings.Gamepad[player] = pads[player].applyTo(bindings.Gamepad[player])
+	}
+}
+
+// applyInputBindings pushes the configured bindings for the current ROM to the window
+func (app *Application) applyInputBindings() {
+	if window, ok := app.window.(graphics.RebindableWindow); ok {
+		bindings := app.config.Input.GetInputBindings(app.romPath)
+		if app.gameProfile != nil && app.gameProfile.Bindings != nil {
+			app.gameProfile.Bindings.applyTo(&bindings)
+		}
+		window.SetInputBindings(bindings)
+	}
+}
+
+// StartRebind waits for the next key or gamepad button press and binds it to the
+// requested button. Pressing Escape cancels. The emulator is paused meanwhile.
+func (app *Application) StartRebind(request RebindRequest) error {
+	window, ok := app.window.(graphics.RebindableWindow)
+	if !ok {
+		return fmt.Errorf("input rebinding is not supported by the %s backend", app.graphicsBackend.GetName())
+	}
+	if request.Player < 0 || request.Player >= graphics.MaxGamepadPlayers {
+		return fmt.Errorf("invalid player: %d", request.Player+1)
+	}
+	if request.Button < 0 || request.Button >= len(graphics.ControllerButtons) {
+		return fmt.Errorf("invalid button index: %d", request.Button)
+	}
+	if request.ForGame && app.romPath == "" {
+		return fmt.Errorf("no ROM loaded for a per-game binding")
+	}
+
+	if app.rebind == nil {
+		app.rebind = &rebindState{wasPaused: app.paused}
+	}
+	app.rebind.request = request
+	app.paused = true
+
+	window.CaptureNextInput(app.onRebindInput)
+	return nil
+}
+
+// CancelRebind abandons an in-progress rebind
+func (app *Application) CancelRebind() {
+	if app.rebind == nil {
+		return
+	}
+	if window, ok := app.window.(graphics.RebindableWindow); ok {
+		window.CancelCapture()
+	}
+	app.finishRebind()
+}
+
+// IsRebinding returns whether the emulator is waiting for a key for a rebind
+func (app *Application) IsRebinding() bool {
+	return app.rebind != nil
+}
+
+// onRebindInput stores a captured input and moves on to the next button if requested
+func (app *Application) onRebindInput(input graphics.CapturedInput) {
+	if app.rebind == nil {
+		return
+	}
+	request := app.rebind.request
+
+	if !input.Gamepad && strings.EqualFold(input.Name, "Escape") {
+		app.finishRebind()
+		return
+	}
+	if input.Gamepad != request.Gamepad {
+		// Wrong device for this binding, keep waiting
+		app.StartRebind(request)
+		return
+	}
+
+	mapping := app.rebindMapping(request)
+	bindings := mapping.bindings()
+	if request.ForGame {
+		// Start from the effective bindings so the override covers the whole controller
+		effective := app.config.Input.GetInputBindings(app.romPath)
+		bindings = effective.Keyboard[request.Player]
+		if request.Gamepad {
+			bindings = effective.Gamepad[request.Player]
+		}
+	}
+	bindings.Bind(request.Button, input.Name)
+	mapping.setBindings(bindings)
+
+	fmt.Printf("[APP_DEBUG] Player %d %s bound to %s\n",
+		request.Player+1, graphics.ControllerButtonName(request.Button), input.Name)
+	app.applyInputBindings()
+
+	if request.AllButtons && request.Button+1 < len(graphics.ControllerButtons) {
+		request.Button++
+		app.StartRebind(request)
+		return
+	}
+
+	app.finishRebind()
+}
+
+// rebindMapping returns the config mapping a rebind request writes to
+func (app *Application) rebindMapping(request RebindRequest) *KeyMapping {
+	input := &app.config.Input
+	keys, pads := input.mappings()
+	if request.ForGame {
+		if input.GameBindings == nil {
+			input.GameBindings = make(map[string]*GameInputBindings)
+		}
+		gameKey := gameBindingsKey(app.romPath)
+		override := input.GameBindings[gameKey]
+		if override == nil {
+			override = &GameInputBindings{}
+			input.GameBindings[gameKey] = override
+		}
+		keys, pads = override.mappings()
+	}
+	if request.Gamepad {
+		return pads[request.Player]
+	}
+	return keys[request.Player]
+}
+
+// finishRebind ends the rebind, restores the pause state and saves the config
+func (app *Application) finishRebind() {
+	if app.rebind == nil {
+		return
+	}
+	app.paused = app.rebind.wasPaused
+	app.rebind = nil
+
+	if err := app.config.Save(); err != nil {
+		fmt.Printf("[APP_WARNING] Could not save input bindings: %v\n", err)
+	}
+}
+
+// renderRebindPrompt draws the "press key for ..." prompt over the frame buffer
+func (app *Application) renderRebindPrompt(frameBuffer *[256 * 240]uint32) {
+	if app.rebind == nil {
+		return
+	}
+	request := app.rebind.request
+
+	device := "KEY"
+	if request.Gamepad {
+		device = "BUTTON"
+	}
+	line1 := fmt.Sprintf("PLAYER %d: PRESS %s FOR %s", request.Player+1, device, graphics.ControllerButtonName(request.Button))
+	line2 := "ESC TO CANCEL"
+	if request.ForGame {
+		line2 = "THIS GAME ONLY - ESC TO CANCEL"
+	}
+
+	width := graphics.TextWidth(line1)
+	if w := graphics.TextWidth(line2); w > width {
+		width = w
+	}
+	x := (graphics.OverlayWidth - width) / 2
+	y := graphics.OverlayHeight/2 - graphics.LineHeight
+
+	graphics.DarkenRect(frameBuffer, 0, 0, graphics.OverlayWidth, graphics.OverlayHeight, 1)
+	graphics.FillRect(frameBuffer, x-6, y-6, width+12, 2*graphics.LineHeight+9, graphics.OverlayColorPanel)
+	graphics.DrawRect(frameBuffer, x-6, y-6, width+12, 2*graphics.LineHeight+9, graphics.OverlayColorGray)
+	graphics.DrawText(frameBuffer, x, y, line1, graphics.OverlayColorYellow)
+	graphics.DrawText(frameBuffer, x, y+graphics.LineHeight+2, line2, graphics.OverlayColorGray)
+}
@@ -0,0 +1,126 @@
+// Package app provides the debugger's breakpoints and watchpoints.
+package app
+
+import (
+	"fmt"
+)
+
+// BreakpointKind is what a breakpoint stops on
+type BreakpointKind string
+
+const (
+	// BreakExecute stops before an instruction in the range runs
+	BreakExecute BreakpointKind = "execute"
+	// BreakRead stops after the CPU reads the range, instruction fetches included
+	BreakRead BreakpointKind = "read"
+	// BreakWrite stops after the CPU writes the range
+	BreakWrite BreakpointKind = "write"
+)
+
+// Breakpoint stops emulation when the CPU executes, reads or writes an
+// inclusive range of addresses
+type Breakpoint struct {
+	ID      int            `json:"id"`
+	Kind    BreakpointKind `json:"kind"`
+	Start   uint16         `json:"start"`
+	End     uint16         `json:"end"`
+	Enabled bool           `json:"enabled"`
+	Hits    int            `json:"hits"`
+}
+
+// contains returns whether address is in the breakpoint's range
+func (b *Breakpoint) contains(address uint16) bool {
+	return address >= b.Start && address <= b.End
+}
+
+// AddBreakpoint adds an enabled breakpoint on start-end and returns it
+func (app *Application) AddBreakpoint(kind BreakpointKind, start, end uint16) (*Breakpoint, error) {
+	switch kind {
+	case BreakExecute, BreakRead, BreakWrite:
+	default:
+		return nil, fmt.Errorf("unknown breakpoint kind %q", kind)
+	}
+	if end < start {
+		return nil, fmt.Errorf("breakpoint range $%04X-$%04X is reversed", start, end)
+	}
+
+	app.nextBreakpointID++
+	breakpoint := &Breakpoint{ID: app.nextBreakpointID, Kind: kind, Start: start, End: end, Enabled: true}
+	app.breakpoints = append(app.breakpoints, breakpoint)
+	app.installDebugHooks()
+	return breakpoint, nil
+}
+
+// RemoveBreakpoint removes a breakpoint by ID
+func (app *Application) RemoveBreakpoint(id int) error {
+	for i, breakpoint := range app.breakpoints {
+		if breakpoint.ID == id {
+			app.breakpoints = append(app.breakpoints[:i], app.breakpoints[i+1:]...)
+			app.installDebugHooks()
+			return nil
+		}
+	}
+	return fmt.Errorf("no breakpoint %d", id)
+}
+
+// EnableBreakpoint turns a breakpoint on or off without removing it
+func (app *Application) EnableBreakpoint(id int, enabled bool) error {
+	for _, breakpoint := range app.breakpoints {
+		if breakpoint.ID == id {
+			breakpoint.Enabled = enabled
+			app.installDebugHooks()
+			return nil
+		}
+	}
+	return fmt.Errorf("no breakpoint %d", id)
+}
+
+// Breakpoints returns the breakpoints in the order they were added
+func (app *Application) Breakpoints() []*Breakpoint {
+	return app.breakpoints
+}
+
+// hasBreakpoints returns whether any enabled breakpoint is of one of kinds
+func (app *Application) hasBreakpoints(kinds ...BreakpointKind) bool {
+	for _, breakpoint := range app.breakpoints {
+		if !breakpoint.Enabled {
+			continue
+		}
+		for _, kind := range kinds {
+			if breakpoint.Kind == kind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkExecuteBreakpoints is the emulator's breakpoint function
+func (app *Application) checkExecuteBreakpoints(pc uint16) bool {
+	for _, breakpoint := range app.breakpoints {
+		if breakpoint.Enabled && breakpoint.Kind == BreakExecute && breakpoint.contains(pc) {
+			breakpoint.Hits++
+			app.breakHit = breakpoint
+			return true
+		}
+	}
+	return false
+}
+
+// checkWatchpoints halts the emulator on a memory access a read or write
+// breakpoint covers
+func (app *Application) checkWatchpoints(address uint16, value uint8, write bool) {
+	kind := BreakRead
+	if write {
+		kind = BreakWrite
+	}
+	for _, breakpoint := range app.breakpoints {
+		if breakpoint.Enabled && breakpoint.Kind == kind && breakpoint.contains(address) {
+			breakpoint.Hits++
+			app.breakHit = breakpoint
+			app.breakAddress, app.breakValue = address, value
+			app.emulator.Halt()
+			return
+		}
+	}
+}
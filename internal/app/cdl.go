@@ -0,0 +1,143 @@
+// Package app provides the code/data logger and its menu page.
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gones/internal/cdl"
+)
+
+// getCDLPath returns the .cdl file path for the current ROM's code/data log
+func (app *Application) getCDLPath() string {
+	romName := filepath.Base(app.romPath)
+	romNameWithoutExt := romName[:len(romName)-len(filepath.Ext(romName))]
+	return filepath.Join(app.config.Paths.SaveData, romNameWithoutExt+".cdl")
+}
+
+// CodeDataLog returns the code/data log of the current ROM, or nil when
+// logging is off
+func (app *Application) CodeDataLog() *cdl.Logger {
+	return app.cdl
+}
+
+// SetCodeDataLogging turns the code/data logger on or off. Turning it off
+// saves the log first.
+func (app *Application) SetCodeDataLogging(enabled bool) {
+	if !enabled {
+		app.stopCDL()
+	}
+	app.config.Debug.CodeDataLogger = enabled
+	if enabled {
+		app.startCDL()
+	}
+}
+
+// startCDL starts logging the current ROM if the logger is enabled, carrying
+// on from its .cdl file
+func (app *Application) startCDL() {
+	if app.cdl != nil || !app.config.Debug.CodeDataLogger || app.cartridge == nil || app.bus == nil {
+		return
+	}
+
+	log := cdl.New(app.cartridge)
+	path := app.getCDLPath()
+	if err := log.Load(path); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("[APP_WARNING] Starting a new code/data log: %v\n", err)
+	}
+
+	app.cdl = log
+	app.installDebugHooks()
+	if app.config.Debug.EnableLogging {
+		fmt.Printf("[APP_DEBUG] Code/data logger started (%s)\n", path)
+	}
+}
+
+// stopCDL saves the log and removes its hooks
+func (app *Application) stopCDL() {
+	if app.cdl == nil {
+		return
+	}
+	if err := app.SaveCDL(); err != nil {
+		fmt.Printf("[APP_ERROR] %v\n", err)
+	}
+	app.cdl = nil
+	app.installDebugHooks()
+}
+
+// SaveCDL writes the code/data log of the current ROM to its .cdl file
+func (app *Application) SaveCDL() error {
+	if app.cdl == nil {
+		return fmt.Errorf("code/data logger is off")
+	}
+	if err := app.cdl.Save(app.getCDLPath()); err != nil {
+		return fmt.Errorf("failed to save code/data log: %v", err)
+	}
+	return nil
+}
+
+// Disassemble lists count instructions of the CPU address space from start,
+// listing bytes the code/data log saw only read as data as ".DB"
+func (app *Application) Disassemble(start uint16, count int) []cdl.Line {
+	if app.bus == nil || app.bus.Memory == nil {
+		return nil
+	}
+	read := func(address uint16) uint8 {
+		value, _ := app.bus.Memory.Peek(address)
+		return value
+	}
+	return cdl.Disassemble(app.cdl, read, start, count)
+}
+
+// cdlMenuPage builds the code/data logger page: the switch, what was logged
+// so far and the save and clear actions
+func (app *Application) cdlMenuPage() *menuPage {
+	stats := func() cdl.Stats {
+		if app.cdl == nil {
+			return cdl.Stats{}
+		}
+		return app.cdl.Stats()
+	}
+	percent := func(n, total int) string {
+		if app.cdl == nil {
+			return "-"
+		}
+		if total == 0 {
+			return "NO ROM"
+		}
+		return fmt.Sprintf("%d (%d%%)", n, n*100/total)
+	}
+
+	return &menuPage{
+		title: "CODE/DATA LOGGER",
+		items: []menuItem{
+			{
+				label:  "LOGGING",
+				value:  func() string { return onOff(app.cdl != nil) },
+				adjust: func(int) { app.SetCodeDataLogging(app.cdl == nil) },
+			},
+			{label: "PRG CODE", value: func() string { s := stats(); return percent(s.PRGCode, s.PRGSize) }},
+			{label: "PRG DATA", value: func() string { s := stats(); return percent(s.PRGData, s.PRGSize) }},
+			{label: "PRG UNREACHED", value: func() string { s := stats(); return percent(s.PRGUnreached(), s.PRGSize) }},
+			{label: "CHR RENDERED", value: func() string { s := stats(); return percent(s.CHRRendered, s.CHRSize) }},
+			{label: "CHR READ", value: func() string { s := stats(); return percent(s.CHRRead, s.CHRSize) }},
+			{label: "SAVE .CDL", action: func() {
+				if err := app.SaveCDL(); err != nil {
+					app.menu.SetMessage(strings.ToUpper(err.Error()))
+					return
+				}
+				app.menu.SetMessage("SAVED " + strings.ToUpper(filepath.Base(app.getCDLPath())))
+			}},
+			{label: "CLEAR", action: func() {
+				if app.cdl == nil {
+					app.menu.SetMessage("LOGGING IS OFF")
+					return
+				}
+				app.cdl.Clear()
+				app.menu.SetMessage("LOG CLEARED")
+			}},
+		},
+	}
+}
@@ -0,0 +1,378 @@
+// Package app provides the per-game cheat codes and their menu page.
+package app
+
+import (
+	"fmt"
+
+	"gones/internal/cheat"
+)
+
+// cheatKind selects one of the cheat lists of a game
+type cheatKind int
+
+const (
+	cheatGameGenie cheatKind = iota // Game Genie codes patching PRG reads
+	cheatRAM                        // Raw address:value cheats freezing RAM
+)
+
+// String names the kind in messages
+func (k cheatKind) String() string {
+	if k == cheatRAM {
+		return "RAM cheat"
+	}
+	return "game genie code"
+}
+
+// normalizeCheat checks a code of the given kind and returns its canonical spelling
+func normalizeCheat(kind cheatKind, code string) (string, error) {
+	if kind == cheatRAM {
+		parsed, err := cheat.ParseRAMCheat(code)
+		if err != nil {
+			return "", err
+		}
+		return parsed.String(), nil
+	}
+	decoded, err := cheat.DecodeGenie(code)
+	if err != nil {
+		return "", err
+	}
+	return decoded.Code, nil
+}
+
+// validCheatCodes normalizes the codes of a config list, dropping (with a
+// warning) those that do not parse
+func validCheatCodes(game string, kind cheatKind, codes []CheatCode) []CheatCode {
+	valid := codes[:0]
+	for _, code := range codes {
+		normalized, err := normalizeCheat(kind, code.Code)
+		if err != nil {
+			fmt.Printf("[APP_WARNING] Ignoring cheat for %s: %v\n", game, err)
+			continue
+		}
+		code.Code = normalized
+		valid = append(valid, code)
+	}
+	return valid
+}
+
+// list returns the codes of one kind
+func (c *GameCheats) list(kind cheatKind) *[]CheatCode {
+	if kind == cheatRAM {
+		return &c.RAM
+	}
+	return &c.GameGenie
+}
+
+// gameCheats returns the cheats of the current ROM, creating the entry if
+// create is set (nil if there is none or no ROM is loaded)
+func (app *Application) gameCheats(create bool) *GameCheats {
+	if app.romPath == "" {
+		return nil
+	}
+	games := &app.config.Cheats.Games
+	key := gameBindingsKey(app.romPath)
+	if (*games)[key] == nil && create {
+		if *games == nil {
+			*games = make(map[string]*GameCheats)
+		}
+		(*games)[key] = &GameCheats{}
+	}
+	return (*games)[key]
+}
+
+// findCheat returns the index of a normalized code in a list, or -1
+func findCheat(codes []CheatCode, code string) int {
+	for i, entry := range codes {
+		if entry.Code == code {
+			return i
+		}
+	}
+	return -1
+}
+
+// cheatCodes returns a copy of the current ROM's codes of one kind
+func (app *Application) cheatCodes(kind cheatKind) []CheatCode {
+	if cheats := app.gameCheats(false); cheats != nil {
+		return append([]CheatCode(nil), *cheats.list(kind)...)
+	}
+	return nil
+}
+
+// addCheat adds an enabled code to the current ROM's cheats, applies it and
+// saves the config
+func (app *Application) addCheat(kind cheatKind, code, name string) error {
+	if app.romPath == "" {
+		return fmt.Errorf("no ROM loaded")
+	}
+	normalized, err := normalizeCheat(kind, code)
+	if err != nil {
+		return err
+	}
+	list := app.gameCheats(true).list(kind)
+	if findCheat(*list, normalized) >= 0 {
+		return fmt.Errorf("%s %s already added", kind, normalized)
+	}
+	*list = append(*list, CheatCode{Code: normalized, Name: name})
+	app.applyCheats()
+	app.saveSettings()
+	return nil
+}
+
+// lookupCheat finds a code of the current ROM by any spelling of it
+func (app *Application) lookupCheat(kind cheatKind, code string) (*[]CheatCode, int, error) {
+	normalized, err := normalizeCheat(kind, code)
+	if err != nil {
+		return nil, 0, err
+	}
+	if cheats := app.gameCheats(false); cheats != nil {
+		list := cheats.list(kind)
+		if index := findCheat(*list, normalized); index >= 0 {
+			return list, index, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("%s %s not found", kind, normalized)
+}
+
+// removeCheat removes a code from the current ROM's cheats
+func (app *Application) removeCheat(kind cheatKind, code string) error {
+	list, index, err := app.lookupCheat(kind, code)
+	if err != nil {
+		return err
+	}
+	*list = append((*list)[:index], (*list)[index+1:]...)
+	app.applyCheats()
+	app.saveSettings()
+	return nil
+}
+
+// setCheatEnabled turns one of the current ROM's codes on or off
+func (app *Application) setCheatEnabled(kind cheatKind, code string, enabled bool) error {
+	list, index, err := app.lookupCheat(kind, code)
+	if err != nil {
+		return err
+	}
+	(*list)[index].Disabled = !enabled
+	app.applyCheats()
+	app.saveSettings()
+	return nil
+}
+
+// GameGenieCodes returns the Game Genie codes of the current ROM
+func (app *Application) GameGenieCodes() []CheatCode {
+	return app.cheatCodes(cheatGameGenie)
+}
+
+// AddGameGenieCode adds an enabled Game Genie code to the current ROM's
+// cheats, applies it and saves the config
+func (app *Application) AddGameGenieCode(code, name string) error {
+	return app.addCheat(cheatGameGenie, code, name)
+}
+
+// RemoveGameGenieCode removes a Game Genie code from the current ROM's cheats
+func (app *Application) RemoveGameGenieCode(code string) error {
+	return app.removeCheat(cheatGameGenie, code)
+}
+
+// SetGameGenieCodeEnabled turns one of the current ROM's Game Genie codes on or off
+func (app *Application) SetGameGenieCodeEnabled(code string, enabled bool) error {
+	return app.setCheatEnabled(cheatGameGenie, code, enabled)
+}
+
+// RAMCheats returns the raw RAM cheats of the current ROM
+func (app *Application) RAMCheats() []CheatCode {
+	return app.cheatCodes(cheatRAM)
+}
+
+// AddRAMCheat adds an enabled "AAAA:VV" cheat to the current ROM's cheats,
+// applies it and saves the config
+func (app *Application) AddRAMCheat(code, name string) error {
+	return app.addCheat(cheatRAM, code, name)
+}
+
+// RemoveRAMCheat removes a raw RAM cheat from the current ROM's cheats
+func (app *Application) RemoveRAMCheat(code string) error {
+	return app.removeCheat(cheatRAM, code)
+}
+
+// SetRAMCheatEnabled turns one of the current ROM's raw RAM cheats on or off
+func (app *Application) SetRAMCheatEnabled(code string, enabled bool) error {
+	return app.setCheatEnabled(cheatRAM, code, enabled)
+}
+
+// frozenCheat returns the index of the RAM cheat freezing address, or -1
+func (app *Application) frozenCheat(address uint16) int {
+	cheats := app.gameCheats(false)
+	if cheats == nil {
+		return -1
+	}
+	for i, entry := range cheats.RAM {
+		if parsed, err := cheat.ParseRAMCheat(entry.Code); err == nil && parsed.Address == address {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsFrozen returns whether an enabled RAM cheat freezes address
+func (app *Application) IsFrozen(address uint16) bool {
+	i := app.frozenCheat(address)
+	return i >= 0 && !app.gameCheats(false).RAM[i].Disabled
+}
+
+// FreezeAddress adds a RAM cheat holding address at its current value, or
+// sets the value of the cheat already freezing it
+func (app *Application) FreezeAddress(address uint16, value uint8) error {
+	code := cheat.RAMCheat{Address: address, Value: value}.String()
+	if i := app.frozenCheat(address); i >= 0 {
+		entry := &app.gameCheats(false).RAM[i]
+		entry.Code = code
+		entry.Disabled = false
+		app.applyCheats()
+		app.saveSettings()
+		return nil
+	}
+	return app.AddRAMCheat(code, "")
+}
+
+// UnfreezeAddress removes the RAM cheat freezing address
+func (app *Application) UnfreezeAddress(address uint16) error {
+	i := app.frozenCheat(address)
+	if i < 0 {
+		return fmt.Errorf("$%04X is not frozen", address)
+	}
+	return app.RemoveRAMCheat(app.gameCheats(false).RAM[i].Code)
+}
+
+// SetCheatsEnabled turns all cheats on or off
+func (app *Application) SetCheatsEnabled(enabled bool) {
+	app.config.Cheats.Enabled = enabled
+	app.applyCheats()
+	app.saveSettings()
+}
+
+// applyCheats installs the enabled cheats of the current ROM: Game Genie
+// codes on the PRG read path, RAM cheats in the freezer run every frame
+func (app *Application) applyCheats() {
+	app.freezer = nil
+	if app.bus == nil || app.bus.Memory == nil {
+		return
+	}
+
+	var codes []cheat.GenieCode
+	var frozen []cheat.RAMCheat
+	// Netplay sessions run without cheats, which only one side would have
+	if cheats := app.gameCheats(false); cheats != nil && app.config.Cheats.Enabled && app.netplay == nil {
+		for _, entry := range cheats.GameGenie {
+			if entry.Disabled {
+				continue
+			}
+			decoded, err := cheat.DecodeGenie(entry.Code)
+			if err != nil {
+				fmt.Printf("[APP_WARNING] Skipping cheat: %v\n", err)
+				continue
+			}
+			codes = append(codes, decoded)
+		}
+		for _, entry := range cheats.RAM {
+			if entry.Disabled {
+				continue
+			}
+			parsed, err := cheat.ParseRAMCheat(entry.Code)
+			if err != nil {
+				fmt.Printf("[APP_WARNING] Skipping cheat: %v\n", err)
+				continue
+			}
+			frozen = append(frozen, parsed)
+		}
+	}
+
+	if len(codes) == 0 {
+		app.bus.Memory.SetPRGReadHook(nil)
+	} else {
+		patches := cheat.NewGeniePatches(codes)
+		app.bus.Memory.SetPRGReadHook(patches.Apply)
+		fmt.Printf("[APP_DEBUG] %d Game Genie code(s) active\n", patches.Len())
+	}
+
+	if len(frozen) > 0 {
+		app.freezer = cheat.NewFreezer(frozen)
+		app.freezer.Apply(app.bus.Memory)
+		fmt.Printf("[APP_DEBUG] %d RAM cheat(s) active\n", app.freezer.Len())
+	}
+}
+
+// applyFreezes writes the frozen RAM cheat values before a frame runs
+func (app *Application) applyFreezes() {
+	if app.freezer != nil && app.bus.Memory != nil {
+		app.freezer.Apply(app.bus.Memory)
+	}
+}
+
+// cheatsMenuPage builds the cheats page: the master switch and a toggle for
+// each of the current game's codes
+func (app *Application) cheatsMenuPage() *menuPage {
+	cheats := &app.config.Cheats
+	page := &menuPage{
+		title: "CHEATS",
+		items: []menuItem{{
+			label: "CHEATS",
+			value: func() string { return onOff(cheats.Enabled) },
+			adjust: func(int) {
+				app.SetCheatsEnabled(!cheats.Enabled)
+			},
+		}},
+	}
+
+	for _, kind := range []cheatKind{cheatGameGenie, cheatRAM} {
+		for _, entry := range app.cheatCodes(kind) {
+			page.items = append(page.items, app.cheatMenuItem(kind, entry))
+		}
+	}
+	if len(page.items) == 1 {
+		page.items = append(page.items, menuItem{label: "NO CODES (SEE CHEATS IN THE CONFIG)"})
+	}
+	return page
+}
+
+// cheatMenuItem builds the on/off item of one code
+func (app *Application) cheatMenuItem(kind cheatKind, entry CheatCode) menuItem {
+	code := entry.Code
+	label := code
+	if entry.Name != "" {
+		label += "  " + entry.Name
+	}
+
+	// enabled looks the code up again, as other items may change the list
+	enabled := func() (bool, bool) {
+		list, index, err := app.lookupCheat(kind, code)
+		if err != nil {
+			return false, false
+		}
+		return !(*list)[index].Disabled, true
+	}
+	return menuItem{
+		label: label,
+		value: func() string {
+			on, found := enabled()
+			if !found {
+				return "-"
+			}
+			return onOff(on)
+		},
+		adjust: func(int) {
+			if on, found := enabled(); found {
+				if err := app.setCheatEnabled(kind, code, !on); err != nil {
+					app.menu.SetMessage(err.Error())
+				}
+			}
+		},
+	}
+}
+
+// onOff formats a switch for the menu
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
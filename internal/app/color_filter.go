@@ -0,0 +1,32 @@
+// Package app provides runtime selection of the color blindness filter.
+package app
+
+import (
+	"fmt"
+
+	"gones/internal/graphics"
+)
+
+// isKnownColorFilter reports whether name is one of graphics.ColorFilters
+func isKnownColorFilter(name string) bool {
+	for _, filter := range graphics.ColorFilters {
+		if name == filter {
+			return true
+		}
+	}
+	return false
+}
+
+// SetColorFilter selects the color blindness filter by name (see graphics.ColorFilters)
+func (app *Application) SetColorFilter(name string) error {
+	if !isKnownColorFilter(name) {
+		return fmt.Errorf("unknown color filter: %s", name)
+	}
+	if app.videoProcessor != nil {
+		if err := app.videoProcessor.SetColorFilter(name); err != nil {
+			return err
+		}
+	}
+	app.config.Video.ColorFilter = name
+	return nil
+}
@@ -0,0 +1,61 @@
+// Package app provides the compatibility database as ROMs load: what it
+// forces for the ROMs it lists, unless emulation.compat_database or
+// -no-compat turns it off.
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"gones/internal/compat"
+)
+
+// compatFile is the file of the config directory adding entries to the
+// shipped compatibility database, which has none yet, as an object of
+// entries by the SHA-1 of the ROM data without its header
+const compatFile = "compat.json"
+
+// SetCompatDatabase overrides emulation.compat_database for the ROMs loaded
+// from now on, without saving it
+func (app *Application) SetCompatDatabase(enabled bool) {
+	app.compatOverride = &enabled
+}
+
+// lookupCompat returns the compatibility database's entry of an iNES image,
+// or nil when it has none or is off. The entries of compat.json in the config
+// directory go over the shipped ones.
+func (app *Application) lookupCompat(data []byte) *compat.Entry {
+	enabled := app.config.Emulation.CompatDatabase
+	if app.compatOverride != nil {
+		enabled = *app.compatOverride
+	}
+	if !enabled {
+		return nil
+	}
+
+	db := compat.Builtin()
+	if err := db.LoadFile(filepath.Join(app.config.Paths.Config, compatFile)); err != nil {
+		fmt.Printf("[APP_WARNING] Compatibility database: %v\n", err)
+	}
+	entry, _ := db.Lookup(data)
+	return entry
+}
+
+// applyCompat returns an iNES image with the header fixes of its
+// compatibility database entry, and the entry (nil without one)
+func (app *Application) applyCompat(data []byte) ([]byte, *compat.Entry) {
+	entry := app.lookupCompat(data)
+	if entry == nil {
+		return data, nil
+	}
+	return entry.FixHeader(data), entry
+}
+
+// ppuAlignment returns the PPU alignment a compatibility database entry
+// forces, 0 for none
+func ppuAlignment(entry *compat.Entry) int {
+	if entry == nil {
+		return 0
+	}
+	return entry.PPUAlignment
+}
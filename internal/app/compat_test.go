@@ -0,0 +1,52 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gones/internal/cartridge"
+	"gones/internal/library"
+	"gones/internal/region"
+)
+
+func TestLoadROM_Compat(t *testing.T) {
+	rom := testROM(t)
+	_, sha := library.Hash(rom)
+	entries := `{"` + sha + `": {"title": "Test", "mapper": 4, "region": "PAL", "four_screen": true}}`
+
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{"enabled", true},
+		{"disabled", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.SetCompatDatabase(tt.enabled)
+			if err := os.WriteFile(filepath.Join(app.config.Paths.Config, compatFile), []byte(entries), 0644); err != nil {
+				t.Fatal(err)
+			}
+			loadTestROM(t, app, rom)
+
+			mapper, r := uint8(4), region.PAL
+			if !tt.enabled {
+				mapper, r = 0, region.NTSC
+			}
+			if got := app.cartridge.MapperID(); got != mapper {
+				t.Errorf("mapper %d, want %d", got, mapper)
+			}
+			if fourScreen := app.cartridge.GetMirrorMode() == cartridge.MirrorFourScreen; fourScreen != tt.enabled {
+				t.Errorf("four-screen %t, want %t", fourScreen, tt.enabled)
+			}
+			if got := app.bus.Region(); got != r {
+				t.Errorf("region %v, want %v", got, r)
+			}
+			if (app.compatEntry != nil) != tt.enabled {
+				t.Errorf("compatibility entry %v with the database enabled %t", app.compatEntry, tt.enabled)
+			}
+		})
+	}
+}
@@ -6,6 +6,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gones/internal/graphics"
+	"gones/internal/memory"
+	"gones/internal/netplay"
+	"gones/internal/record"
+	"gones/internal/trace"
 )
 
 // Config holds all application configuration
@@ -14,13 +24,20 @@ type Config struct {
 	Video     VideoConfig     `json:"video"`
 	Audio     AudioConfig     `json:"audio"`
 	Input     InputConfig     `json:"input"`
+	Hotkeys   HotkeyConfig    `json:"hotkeys"`
 	Emulation EmulationConfig `json:"emulation"`
 	Debug     DebugConfig     `json:"debug"`
+	Cheats    CheatsConfig    `json:"cheats"`
+	API       APIConfig       `json:"api"`
+	Metrics   MetricsConfig   `json:"metrics"`
+	Netplay   NetplayConfig   `json:"netplay"`
+	Library   LibraryConfig   `json:"library"`
 	Paths     PathsConfig     `json:"paths"`
 
 	// Internal state
 	configPath string
 	loaded     bool
+	modTime    time.Time // Of the file when last loaded or saved
 }
 
 // WindowConfig contains window-related configuration
@@ -37,14 +54,50 @@ type WindowConfig struct {
 type VideoConfig struct {
 	VSync        bool    `json:"vsync"`
 	FrameSkip    int     `json:"frame_skip"`
-	AspectRatio  string  `json:"aspect_ratio"` // "4:3", "16:9", "original"
-	Filter       string  `json:"filter"`       // "nearest", "linear", "cubic"
-	Backend      string  `json:"backend"`      // "ebitengine", "sdl2", "headless", "terminal"
+	AspectRatio  string  `json:"aspect_ratio"`  // "original", "integer", "8:7", "4:3", "16:9", "fill"
+	Filter       string  `json:"filter"`        // "nearest", "linear", "cubic", "crt"
+	Upscaler     string  `json:"upscaler"`      // "none", "scale2x", "scale3x", "hq2x", "xbrz2x", "xbrz3x"
+	Backend      string  `json:"backend"`       // "ebitengine", "sdl2", "headless", "terminal"
+	TerminalMode string  `json:"terminal_mode"` // "auto", "halfblock", "braille" (terminal backend)
 	Brightness   float32 `json:"brightness"`
 	Contrast     float32 `json:"contrast"`
 	Saturation   float32 `json:"saturation"`
 	ShowOverscan bool    `json:"show_overscan"`
 	CropOverscan bool    `json:"crop_overscan"`
+
+	// CRT filter intensities (0-1), used when filter is "crt"
+	CRT CRTConfig `json:"crt"`
+
+	// Save screenshots as the raw 256x240 frame instead of the processed output
+	RawScreenshots bool `json:"raw_screenshots"`
+
+	// Container of recordings started with Shift+F12: "gif", "png" (APNG), or
+	// "mp4", "mkv", "webm" (these need ffmpeg)
+	RecordFormat string `json:"record_format"`
+
+	// Seconds of recent frames kept for Ctrl+F12 replay capture (0 disables, max 60)
+	ReplaySeconds int `json:"replay_seconds"`
+
+	// Share (0-1) of the previous frame mixed into each frame, which steadies
+	// sprites that games flicker at 30 Hz (0 disables, 0.5 is an even mix)
+	FrameBlend float32 `json:"frame_blend"`
+
+	// Color blindness filter: "none", "protanopia", "deuteranopia" or
+	// "tritanopia" to simulate, or "daltonize-" plus one of those to correct
+	ColorFilter string `json:"color_filter"`
+
+	// Enhancement: draw every sprite of a scanline instead of the first
+	// eight, so games that flicker sprites past the limit show them all.
+	// Movies and netplay always run with the limit.
+	UnlimitedSprites bool `json:"unlimited_sprites"`
+}
+
+// CRTConfig contains the CRT filter intensities
+type CRTConfig struct {
+	Scanlines  float32 `json:"scanlines"`
+	ShadowMask float32 `json:"shadow_mask"`
+	Curvature  float32 `json:"curvature"`
+	Bloom      float32 `json:"bloom"`
 }
 
 // AudioConfig contains audio configuration
@@ -55,18 +108,81 @@ type AudioConfig struct {
 	Volume     float32 `json:"volume"`
 	Channels   int     `json:"channels"`
 	Latency    int     `json:"latency"` // Target latency in milliseconds
+
+	// Audio while fast-forwarding: "pitch" keeps the pitch by playing only as
+	// much audio as real time allows, "mute" silences it
+	FastForward string `json:"fast_forward"`
+
+	// Drop or repeat a video frame when the video drifts from the audio
+	// device's clock
+	SyncCorrection bool `json:"sync_correction"`
 }
 
+// Devices that can be plugged into port 2
+const (
+	Port2Controller = "controller"
+	Port2Zapper     = "zapper"
+	Port2Arkanoid   = "arkanoid"
+)
+
 // InputConfig contains input configuration
 type InputConfig struct {
 	Player1Keys        KeyMapping `json:"player1_keys"`
 	Player2Keys        KeyMapping `json:"player2_keys"`
+	Player3Keys        KeyMapping `json:"player3_keys"`
+	Player4Keys        KeyMapping `json:"player4_keys"`
+	Player1PadButtons  KeyMapping `json:"player1_pad_buttons"`
+	Player2PadButtons  KeyMapping `json:"player2_pad_buttons"`
+	Player3PadButtons  KeyMapping `json:"player3_pad_buttons"`
+	Player4PadButtons  KeyMapping `json:"player4_pad_buttons"`
 	ControllerDeadzone float32    `json:"controller_deadzone"`
 	AutofireRate       int        `json:"autofire_rate"`
 	EnableAutofire     bool       `json:"enable_autofire"`
+
+	// Gamepad assignment per player: "" for the next free gamepad, "none" to
+	// disable, or a gamepad GUID / name fragment (e.g. "xbox") to pick a device
+	Player1Gamepad string `json:"player1_gamepad"`
+	Player2Gamepad string `json:"player2_gamepad"`
+	Player3Gamepad string `json:"player3_gamepad"`
+	Player4Gamepad string `json:"player4_gamepad"`
+
+	// Connect a Four Score adapter so players 3 and 4 can join (games that
+	// support it: Gauntlet II, Super Spike V'Ball, R.C. Pro-Am II, ...)
+	FourScore bool `json:"four_score"`
+
+	// Device plugged into port 2: "controller", "zapper" (the mouse aims, left
+	// click pulls the trigger, right click fires off screen) or "arkanoid" (the
+	// mouse X position turns the paddle knob, left click presses the button)
+	Port2Device string `json:"port2_device"`
+
+	// Zapper is the older switch for port2_device "zapper"
+	Zapper bool `json:"zapper"`
+
+	// Host microphone level (0-1) from which the Famicom microphone hears
+	// sound, for games such as Zelda where blowing into it scares Pols Voice
+	// away. 0 leaves the host microphone closed; the microphone hotkey works
+	// either way. SDL2 backend only.
+	MicrophoneThreshold float64 `json:"microphone_threshold"`
+
+	// Per-game binding overrides keyed by ROM file name without extension
+	// (e.g. "smb" for smb.nes). Only the non-empty entries replace the bindings above.
+	GameBindings map[string]*GameInputBindings `json:"game_bindings,omitempty"`
 }
 
-// KeyMapping represents keyboard key mappings for NES controller
+// GameInputBindings overrides the keyboard and gamepad bindings for a single game
+type GameInputBindings struct {
+	Player1Keys       KeyMapping `json:"player1_keys"`
+	Player2Keys       KeyMapping `json:"player2_keys"`
+	Player3Keys       KeyMapping `json:"player3_keys"`
+	Player4Keys       KeyMapping `json:"player4_keys"`
+	Player1PadButtons KeyMapping `json:"player1_pad_buttons"`
+	Player2PadButtons KeyMapping `json:"player2_pad_buttons"`
+	Player3PadButtons KeyMapping `json:"player3_pad_buttons"`
+	Player4PadButtons KeyMapping `json:"player4_pad_buttons"`
+}
+
+// KeyMapping represents key (or gamepad button) mappings for a NES controller.
+// Each entry may list several inputs separated by commas, e.g. "W,ArrowUp".
 type KeyMapping struct {
 	Up     string `json:"up"`
 	Down   string `json:"down"`
@@ -80,7 +196,7 @@ type KeyMapping struct {
 
 // EmulationConfig contains emulation-specific settings
 type EmulationConfig struct {
-	Region           string  `json:"region"`         // "NTSC", "PAL", "Dendy"
+	Region           string  `json:"region"`         // "auto" (from the ROM), "NTSC", "PAL" or "Dendy"
 	FrameRate        float64 `json:"frame_rate"`     // Target frame rate
 	CycleAccuracy    bool    `json:"cycle_accuracy"` // Cycle-accurate emulation
 	EnableSound      bool    `json:"enable_sound"`
@@ -88,6 +204,33 @@ type EmulationConfig struct {
 	SaveStateSlots   int     `json:"save_state_slots"` // Number of save state slots
 	AutoSave         bool    `json:"auto_save"`        // Auto-save state on exit
 	PauseOnFocusLoss bool    `json:"pause_on_focus_loss"`
+	ResumeOnFocus    bool    `json:"resume_on_focus"` // Resume a pause_on_focus_loss pause when the focus returns, else show the menu
+
+	// Open the pause menu when a gamepad assigned to a player is unplugged
+	PauseOnGamepadDisconnect bool `json:"pause_on_gamepad_disconnect"`
+
+	// Periodic autosave snapshots and battery RAM flushing
+	AutoSaveInterval  int `json:"auto_save_interval"`  // Seconds of play between autosave snapshots (0 = disabled)
+	AutoSaveSlots     int `json:"auto_save_slots"`     // Number of rotating autosave files
+	SRAMFlushInterval int `json:"sram_flush_interval"` // Seconds between battery RAM flushes (0 = only on exit)
+
+	CompressStates bool `json:"compress_states"` // gzip save state files
+
+	// Speed while the fast-forward key (Tab) is held, 0.1-16 (0 = unthrottled)
+	FastForwardSpeed float64 `json:"fast_forward_speed"`
+
+	// What internal RAM holds at power on: "mixed" (the patterns seen on
+	// hardware), "zero", "ff", "alternating" ($00 and $FF pages) or "random"
+	RAMPattern string `json:"ram_pattern"`
+	RAMSeed    int64  `json:"ram_seed"` // Seed of the random pattern, 0 for a new one at each power on
+
+	// Have DMC sample fetches landing on controller reads delete a bit, as
+	// on NTSC consoles, which some games read the controllers twice against
+	DMCConflicts bool `json:"dmc_conflicts"`
+
+	// Force what the compatibility database lists for a ROM as it loads:
+	// mapper, region, four-screen VRAM or CPU/PPU alignment
+	CompatDatabase bool `json:"compat_database"`
 }
 
 // DebugConfig contains debugging and development options
@@ -99,6 +242,146 @@ type DebugConfig struct {
 	CPUTracing      bool   `json:"cpu_tracing"`
 	PPUDebugging    bool   `json:"ppu_debugging"`
 	MemoryDebugging bool   `json:"memory_debugging"`
+
+	// Named CPU addresses the memory viewer (Ctrl+F6) can jump to, such as
+	// {"player_x": "$0086"}, in addition to the built-in regions
+	MemoryBookmarks map[string]string `json:"memory_bookmarks"`
+
+	// Log which PRG/CHR bytes run as code, are read as data or are drawn,
+	// kept in <save_data>/<rom>.cdl across sessions
+	CodeDataLogger bool `json:"code_data_logger"`
+
+	// CPU trace logger (Ctrl+F9 starts and stops it)
+	Trace TraceConfig `json:"trace"`
+
+	// JSON-RPC server external debuggers attach to
+	Remote RemoteConfig `json:"remote"`
+}
+
+// RemoteConfig sets up the remote debugging server
+type RemoteConfig struct {
+	// Start the server with the emulator
+	Enabled bool `json:"enabled"`
+
+	// TCP address for JSON-RPC clients, one request per line, and WebSocket
+	// clients on the same port. Keep it on 127.0.0.1: anyone who can connect
+	// can read and write the emulator's memory.
+	Listen string `json:"listen"`
+
+	// Web page origins allowed to connect over WebSocket, such as
+	// "http://localhost:8080" ("*" allows any). Clients without an Origin
+	// header, like editors, are always allowed.
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+}
+
+// TraceConfig sets up the CPU trace logger
+type TraceConfig struct {
+	// Start tracing when a ROM is loaded
+	Enabled bool `json:"enabled"`
+
+	// Log file; empty writes <logs>/<rom>.trace.log
+	File string `json:"file"`
+
+	// What to log: executed instructions and/or their memory accesses
+	Instructions bool `json:"instructions"`
+	Memory       bool `json:"memory"`
+
+	// Filters, empty to log everything: instruction address ranges such as
+	// "8000-80FF", accessed address ranges, opcodes ("A9" or "JSR") and
+	// 16KB PRG ROM banks
+	Ranges       []string `json:"ranges,omitempty"`
+	AccessRanges []string `json:"access_ranges,omitempty"`
+	Opcodes      []string `json:"opcodes,omitempty"`
+	Banks        []int    `json:"banks,omitempty"`
+
+	// Keep only the last ring_size entries in memory and write them when
+	// tracing stops, instead of writing every entry (0)
+	RingSize int `json:"ring_size"`
+
+	// Keep the last instructions even when not tracing, for the trace.log
+	// of crash reports
+	CrashDump bool `json:"crash_dump"`
+}
+
+// APIConfig sets up the HTTP control API for bots and automation
+type APIConfig struct {
+	// Start the API with the emulator
+	Enabled bool `json:"enabled"`
+
+	// TCP address of the HTTP server. Keep it on 127.0.0.1: anyone who can
+	// connect can load files and read the emulator's memory.
+	Listen string `json:"listen"`
+
+	// Web page origins allowed to use the API, such as
+	// "http://localhost:3000" ("*" allows any). Clients without an Origin
+	// header, like scripts, are always allowed.
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+}
+
+// MetricsConfig sets up the Prometheus metrics endpoint. /metrics is also
+// served by the control API when it runs.
+type MetricsConfig struct {
+	// Serve /metrics on its own address with the emulator
+	Enabled bool `json:"enabled"`
+
+	// TCP address of the metrics server. Only metrics are served there, so
+	// it can be opened to a monitoring host without exposing the API.
+	Listen string `json:"listen"`
+}
+
+// NetplayConfig contains the netplay settings. The host's input delay,
+// rollback and hash interval are used by both players.
+type NetplayConfig struct {
+	// UDP port hosted on
+	Port int `json:"port"`
+
+	// Frames between pressing a button and its effect. More hides more of
+	// the network latency, at the cost of responsiveness.
+	InputDelay int `json:"input_delay"`
+
+	// Frames run ahead of the other player's inputs on predictions, undone
+	// when a prediction is wrong; 0 waits for the inputs (lockstep)
+	MaxRollback int `json:"max_rollback"`
+
+	// Frames between comparisons of the two players' game states
+	HashInterval int `json:"hash_interval"`
+
+	// Address of the host last joined, such as "192.168.1.20:7845"
+	JoinAddress string `json:"join_address,omitempty"`
+}
+
+// LibraryConfig configures the ROM library, which identifies the ROMs in
+// its directories by hash so the ROM browser can show their proper titles
+type LibraryConfig struct {
+	// Directories scanned for ROMs (paths.roms when empty)
+	Dirs []string `json:"dirs,omitempty"`
+
+	// Logiqx XML DAT file, or a directory of them, to identify ROMs with,
+	// such as No-Intro's headerless NES DAT
+	Database string `json:"database"`
+}
+
+// CheatsConfig contains the cheat codes of each game
+type CheatsConfig struct {
+	// Master switch for all cheats
+	Enabled bool `json:"enabled"`
+
+	// Cheats keyed by ROM file name without extension (e.g. "smb" for smb.nes),
+	// like input.game_bindings
+	Games map[string]*GameCheats `json:"games,omitempty"`
+}
+
+// GameCheats lists the cheats of one game
+type GameCheats struct {
+	GameGenie []CheatCode `json:"game_genie,omitempty"` // 6 or 8 letter codes
+	RAM       []CheatCode `json:"ram,omitempty"`        // "AAAA:VV", rewritten every frame
+}
+
+// CheatCode is one cheat. Cheats are active unless disabled.
+type CheatCode struct {
+	Code     string `json:"code"`
+	Name     string `json:"name,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
 }
 
 // PathsConfig contains file and directory paths
@@ -107,12 +390,15 @@ type PathsConfig struct {
 	SaveData    string `json:"save_data"`
 	SaveStates  string `json:"save_states"`
 	Screenshots string `json:"screenshots"`
+	Recordings  string `json:"recordings"`
 	Config      string `json:"config"`
 	Logs        string `json:"logs"`
+	Crashes     string `json:"crashes"` // Crash reports, one directory each
 }
 
 // NewConfig creates a new configuration with default values
 func NewConfig() *Config {
+	paths := defaultPaths()
 	config := &Config{
 		Window: WindowConfig{
 			Width:      800,
@@ -127,12 +413,23 @@ func NewConfig() *Config {
 			FrameSkip:    0,
 			AspectRatio:  "4:3",
 			Filter:       "nearest",
+			Upscaler:     graphics.UpscalerNone,
 			Backend:      "ebitengine", // Default to Ebitengine for GUI mode
+			TerminalMode: graphics.TerminalModeAuto,
 			Brightness:   1.0,
 			Contrast:     1.0,
 			Saturation:   1.0,
 			ShowOverscan: false,
 			CropOverscan: true,
+			CRT: CRTConfig{
+				Scanlines:  0.5,
+				ShadowMask: 0.3,
+				Curvature:  0.2,
+				Bloom:      0.25,
+			},
+			RecordFormat:  "gif",
+			ReplaySeconds: 10,
+			ColorFilter:   graphics.ColorFilterNone,
 		},
 		Audio: AudioConfig{
 			Enabled:    true,
@@ -141,34 +438,53 @@ func NewConfig() *Config {
 			Volume:     0.8,
 			Channels:   2,
 			Latency:    50,
+
+			FastForward:    FastForwardAudioPitch,
+			SyncCorrection: true,
 		},
 		Input: InputConfig{
 			Player1Keys: KeyMapping{
-				Up:     "W",
-				Down:   "S",
-				Left:   "A",
-				Right:  "D",
-				A:      "J",
-				B:      "K",
-				Start:  "Return",
+				Up:     "W,ArrowUp",
+				Down:   "S,ArrowDown",
+				Left:   "A,ArrowLeft",
+				Right:  "D,ArrowRight",
+				A:      "J,Z",
+				B:      "K,X",
+				Start:  "Enter",
 				Select: "Space",
 			},
 			Player2Keys: KeyMapping{
-				Up:     "Up",
-				Down:   "Down",
-				Left:   "Left",
-				Right:  "Right",
-				A:      "N",
-				B:      "M",
-				Start:  "RShift",
-				Select: "RCtrl",
+				Up:     "1",
+				Down:   "2",
+				Left:   "3",
+				Right:  "4",
+				A:      "5",
+				B:      "6",
+				Start:  "7",
+				Select: "8",
+			},
+			Player3Keys: KeyMapping{
+				Up:     "Numpad8",
+				Down:   "Numpad2",
+				Left:   "Numpad4",
+				Right:  "Numpad6",
+				A:      "Numpad3",
+				B:      "Numpad1",
+				Start:  "NumpadEnter",
+				Select: "NumpadAdd",
 			},
+			Player1PadButtons:  defaultPadButtons(),
+			Player2PadButtons:  defaultPadButtons(),
+			Player3PadButtons:  defaultPadButtons(),
+			Player4PadButtons:  defaultPadButtons(),
 			ControllerDeadzone: 0.1,
 			AutofireRate:       10,
 			EnableAutofire:     false,
+			Port2Device:        Port2Controller,
 		},
+		Hotkeys: defaultHotkeys(),
 		Emulation: EmulationConfig{
-			Region:           "NTSC",
+			Region:           RegionAuto,
 			FrameRate:        60.0,
 			CycleAccuracy:    true,
 			EnableSound:      true,
@@ -176,6 +492,22 @@ func NewConfig() *Config {
 			SaveStateSlots:   10,
 			AutoSave:         true,
 			PauseOnFocusLoss: true,
+			ResumeOnFocus:    true,
+
+			PauseOnGamepadDisconnect: true,
+
+			AutoSaveInterval:  300, // Every 5 minutes
+			AutoSaveSlots:     3,
+			SRAMFlushInterval: 10,
+
+			CompressStates: true,
+
+			FastForwardSpeed: 4,
+
+			RAMPattern: memory.RAMPatternMixed.String(),
+
+			DMCConflicts:   true,
+			CompatDatabase: true,
 		},
 		Debug: DebugConfig{
 			ShowFPS:         false,
@@ -185,22 +517,50 @@ func NewConfig() *Config {
 			CPUTracing:      false,
 			PPUDebugging:    false,
 			MemoryDebugging: false,
+			MemoryBookmarks: map[string]string{},
+			CodeDataLogger:  false,
+			Trace: TraceConfig{
+				Enabled:      false,
+				Instructions: true,
+				Memory:       false,
+				RingSize:     0,
+				CrashDump:    false,
+			},
+			Remote: RemoteConfig{
+				Enabled: false,
+				Listen:  DefaultRemoteListen,
+			},
+		},
+		Cheats: CheatsConfig{
+			Enabled: true,
+		},
+		API: APIConfig{
+			Enabled: false,
+			Listen:  DefaultAPIListen,
 		},
-		Paths: PathsConfig{
-			ROMs:        "./roms",
-			SaveData:    "./saves",
-			SaveStates:  "./states",
-			Screenshots: "./screenshots",
-			Config:      "./config",
-			Logs:        "./logs",
+		Metrics: MetricsConfig{
+			Enabled: false,
+			Listen:  DefaultMetricsListen,
 		},
+		Netplay: NetplayConfig{
+			Port:         netplay.DefaultPort,
+			InputDelay:   netplay.DefaultInputDelay,
+			MaxRollback:  netplay.DefaultMaxRollback,
+			HashInterval: netplay.DefaultHashInterval,
+		},
+		Library: LibraryConfig{
+			Database: filepath.Join(paths.Config, "dats"),
+		},
+		Paths:  paths,
 		loaded: false,
 	}
 
 	return config
 }
 
-// LoadFromFile loads configuration from a JSON file
+// LoadFromFile loads configuration from a JSON, TOML (.toml) or YAML (.yaml,
+// .yml) file. Unknown keys and out of range values are reported, and the
+// defaults used instead.
 func (c *Config) LoadFromFile(path string) error {
 	c.configPath = path
 
@@ -215,11 +575,20 @@ func (c *Config) LoadFromFile(path string) error {
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %v", err)
 	}
+	c.modTime = fileModTime(path)
 
-	// Parse JSON
-	if err := json.Unmarshal(data, c); err != nil {
+	// Parse the file and check it against the settings
+	raw, err := decodeConfig(configFormatForPath(path), data)
+	if err != nil {
 		return fmt.Errorf("failed to parse config file: %v", err)
 	}
+	for _, key := range unknownConfigKeys("", raw, reflect.TypeFor[Config]()) {
+		fmt.Printf("[APP_WARNING] %s: unknown setting %q ignored\n", path, key)
+	}
+	if err := c.applyConfig(raw); err != nil {
+		return fmt.Errorf("invalid configuration: %v", err)
+	}
+	c.resolvePortablePaths()
 
 	// Validate configuration
 	if err := c.validate(); err != nil {
@@ -235,7 +604,8 @@ func (c *Config) LoadFromFile(path string) error {
 	return nil
 }
 
-// SaveToFile saves configuration to a JSON file
+// SaveToFile saves configuration to a file, in JSON unless its extension is
+// that of TOML or YAML
 func (c *Config) SaveToFile(path string) error {
 	// Ensure directory exists
 	dir := filepath.Dir(path)
@@ -243,8 +613,7 @@ func (c *Config) SaveToFile(path string) error {
 		return fmt.Errorf("failed to create config directory: %v", err)
 	}
 
-	// Marshal to JSON with indentation
-	data, err := json.MarshalIndent(c, "", "  ")
+	data, err := encodeConfig(configFormatForPath(path), c.portableCopy())
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %v", err)
 	}
@@ -255,9 +624,30 @@ func (c *Config) SaveToFile(path string) error {
 	}
 
 	c.configPath = path
+	c.modTime = fileModTime(path)
 	return nil
 }
 
+// changedOnDisk reports whether the config file was modified since it was
+// last loaded or saved, such as by a text editor
+func (c *Config) changedOnDisk() bool {
+	if c.configPath == "" {
+		return false
+	}
+	modTime := fileModTime(c.configPath)
+	return !modTime.IsZero() && !modTime.Equal(c.modTime)
+}
+
+// fileModTime returns when a file was last modified, or the zero time when
+// it cannot be read
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
 // Save saves the configuration to the current config file
 func (c *Config) Save() error {
 	if c.configPath == "" {
@@ -275,64 +665,238 @@ func (c *Config) validate() error {
 	}
 
 	if c.Window.Scale <= 0 {
-		c.Window.Scale = 1
+		resetSetting("window.scale", &c.Window.Scale, "1 or more", 1)
 	}
 
 	// Validate video configuration
 	if c.Video.Brightness < 0.1 || c.Video.Brightness > 3.0 {
-		c.Video.Brightness = 1.0
+		resetSetting("video.brightness", &c.Video.Brightness, "0.1-3", 1.0)
 	}
 
 	if c.Video.Contrast < 0.1 || c.Video.Contrast > 3.0 {
-		c.Video.Contrast = 1.0
+		resetSetting("video.contrast", &c.Video.Contrast, "0.1-3", 1.0)
 	}
 
 	if c.Video.Saturation < 0.0 || c.Video.Saturation > 3.0 {
-		c.Video.Saturation = 1.0
+		resetSetting("video.saturation", &c.Video.Saturation, "0-3", 1.0)
+	}
+
+	if !isKnownAspectMode(c.Video.AspectRatio) {
+		resetSetting("video.aspect_ratio", &c.Video.AspectRatio, oneOf(graphics.AspectModes), graphics.Aspect4x3)
+	}
+
+	if !isKnownUpscaler(c.Video.Upscaler) {
+		resetSetting("video.upscaler", &c.Video.Upscaler, oneOf(graphics.Upscalers), graphics.UpscalerNone)
+	}
+
+	c.Video.TerminalMode = strings.ToLower(c.Video.TerminalMode)
+	if !slices.Contains(graphics.TerminalModes, c.Video.TerminalMode) {
+		resetSetting("video.terminal_mode", &c.Video.TerminalMode, oneOf(graphics.TerminalModes), graphics.TerminalModeAuto)
 	}
 
+	c.Video.RecordFormat = strings.TrimPrefix(strings.ToLower(c.Video.RecordFormat), ".")
+	if _, err := record.FormatForPath("recording." + c.Video.RecordFormat); err != nil {
+		resetSetting("video.record_format", &c.Video.RecordFormat, "a recording format", "gif")
+	}
+	if c.Video.ReplaySeconds < 0 {
+		resetSetting("video.replay_seconds", &c.Video.ReplaySeconds, "0-60", 0)
+	} else if c.Video.ReplaySeconds > 60 {
+		resetSetting("video.replay_seconds", &c.Video.ReplaySeconds, "0-60", 60)
+	}
+
+	if c.Video.FrameBlend < 0.0 || c.Video.FrameBlend > 1.0 {
+		resetSetting("video.frame_blend", &c.Video.FrameBlend, "0-1", 0.0)
+	}
+
+	if !isKnownColorFilter(c.Video.ColorFilter) {
+		resetSetting("video.color_filter", &c.Video.ColorFilter, oneOf(graphics.ColorFilters), graphics.ColorFilterNone)
+	}
+
+	crt := graphics.CRTSettings(c.Video.CRT).Clamped()
+	if CRTConfig(crt) != c.Video.CRT {
+		fmt.Printf("[APP_WARNING] Config video.crt values are limited to 0-1\n")
+	}
+	c.Video.CRT = CRTConfig(crt)
+
 	// Validate audio configuration
 	if c.Audio.SampleRate <= 0 {
-		c.Audio.SampleRate = 44100
+		resetSetting("audio.sample_rate", &c.Audio.SampleRate, "1 or more", 44100)
 	}
 
 	if c.Audio.BufferSize <= 0 {
-		c.Audio.BufferSize = 1024
+		resetSetting("audio.buffer_size", &c.Audio.BufferSize, "1 or more", 1024)
 	}
 
 	if c.Audio.Volume < 0.0 || c.Audio.Volume > 1.0 {
-		c.Audio.Volume = 0.8
+		resetSetting("audio.volume", &c.Audio.Volume, "0-1", 0.8)
 	}
 
 	if c.Audio.Channels != 1 && c.Audio.Channels != 2 {
-		c.Audio.Channels = 2
+		resetSetting("audio.channels", &c.Audio.Channels, "1 or 2", 2)
+	}
+
+	switch strings.ToLower(c.Audio.FastForward) {
+	case FastForwardAudioMute:
+		c.Audio.FastForward = FastForwardAudioMute
+	case FastForwardAudioPitch:
+		c.Audio.FastForward = FastForwardAudioPitch
+	default:
+		resetSetting("audio.fast_forward", &c.Audio.FastForward,
+			oneOf([]string{FastForwardAudioPitch, FastForwardAudioMute}), FastForwardAudioPitch)
 	}
 
 	// Validate emulation configuration
+	if region, err := ParseRegion(c.Emulation.Region); err != nil {
+		resetSetting("emulation.region", &c.Emulation.Region, oneOf([]string{RegionAuto, "NTSC", "PAL", "Dendy"}), RegionAuto)
+	} else {
+		c.Emulation.Region = region
+	}
+
+	if pattern, err := memory.ParseRAMPattern(c.Emulation.RAMPattern); err != nil {
+		resetSetting("emulation.ram_pattern", &c.Emulation.RAMPattern, oneOf(memory.RAMPatternNames()), memory.RAMPatternMixed.String())
+	} else {
+		c.Emulation.RAMPattern = pattern.String()
+	}
+
 	if c.Emulation.FrameRate <= 0 {
-		c.Emulation.FrameRate = 60.0
+		resetSetting("emulation.frame_rate", &c.Emulation.FrameRate, "above 0", 60.0)
 	}
 
 	if c.Emulation.RewindBuffer < 0 {
-		c.Emulation.RewindBuffer = 0
+		resetSetting("emulation.rewind_buffer", &c.Emulation.RewindBuffer, "0 or more", 0)
 	}
 
 	if c.Emulation.SaveStateSlots <= 0 {
-		c.Emulation.SaveStateSlots = 10
+		resetSetting("emulation.save_state_slots", &c.Emulation.SaveStateSlots, "1 or more", 10)
+	}
+
+	if c.Emulation.AutoSaveInterval < 0 {
+		resetSetting("emulation.auto_save_interval", &c.Emulation.AutoSaveInterval, "0 or more", 300)
+	}
+
+	if c.Emulation.AutoSaveSlots <= 0 {
+		resetSetting("emulation.auto_save_slots", &c.Emulation.AutoSaveSlots, "1 or more", 3)
+	}
+
+	if c.Emulation.SRAMFlushInterval < 0 {
+		resetSetting("emulation.sram_flush_interval", &c.Emulation.SRAMFlushInterval, "0 or more", 10)
+	}
+
+	if c.Emulation.FastForwardSpeed < 0 {
+		resetSetting("emulation.fast_forward_speed", &c.Emulation.FastForwardSpeed, "0 or more", 4)
+	}
+	if speed := clampSpeed(c.Emulation.FastForwardSpeed); speed != c.Emulation.FastForwardSpeed {
+		resetSetting("emulation.fast_forward_speed", &c.Emulation.FastForwardSpeed,
+			fmt.Sprintf("%g-%g", MinSpeed, MaxSpeed), speed)
+	}
+
+	// Validate debug configuration
+	for name, address := range c.Debug.MemoryBookmarks {
+		if _, err := parseMemoryAddress(address); err != nil {
+			fmt.Printf("[APP_WARNING] Ignoring memory bookmark %q: %v\n", name, err)
+			delete(c.Debug.MemoryBookmarks, name)
+		}
+	}
+	c.Debug.Trace.Ranges = validTraceRanges(c.Debug.Trace.Ranges)
+	c.Debug.Trace.AccessRanges = validTraceRanges(c.Debug.Trace.AccessRanges)
+	opcodes := c.Debug.Trace.Opcodes[:0]
+	for _, opcode := range c.Debug.Trace.Opcodes {
+		if _, err := trace.ParseOpcodes(opcode); err != nil {
+			fmt.Printf("[APP_WARNING] Ignoring trace filter: %v\n", err)
+			continue
+		}
+		opcodes = append(opcodes, opcode)
+	}
+	c.Debug.Trace.Opcodes = opcodes
+	if c.Debug.Trace.RingSize < 0 {
+		resetSetting("debug.trace.ring_size", &c.Debug.Trace.RingSize, "0 or more", 0)
+	}
+	if c.Debug.Remote.Listen == "" {
+		c.Debug.Remote.Listen = DefaultRemoteListen
+	}
+	if c.API.Listen == "" {
+		c.API.Listen = DefaultAPIListen
+	}
+	if c.Metrics.Listen == "" {
+		c.Metrics.Listen = DefaultMetricsListen
+	}
+	if c.Netplay.Port <= 0 || c.Netplay.Port > 65535 {
+		resetSetting("netplay.port", &c.Netplay.Port, "1-65535", netplay.DefaultPort)
+	}
+	if c.Netplay.InputDelay < 0 || c.Netplay.InputDelay > netplay.MaxInputDelay {
+		resetSetting("netplay.input_delay", &c.Netplay.InputDelay,
+			fmt.Sprintf("0-%d", netplay.MaxInputDelay), netplay.DefaultInputDelay)
+	}
+	if c.Netplay.MaxRollback < 0 || c.Netplay.MaxRollback > netplay.MaxMaxRollback {
+		resetSetting("netplay.max_rollback", &c.Netplay.MaxRollback,
+			fmt.Sprintf("0-%d", netplay.MaxMaxRollback), netplay.DefaultMaxRollback)
+	}
+	if c.Netplay.HashInterval <= 0 || c.Netplay.HashInterval > 0xFFFF {
+		resetSetting("netplay.hash_interval", &c.Netplay.HashInterval, "1-65535", netplay.DefaultHashInterval)
+	}
+	if c.Paths.Crashes == "" {
+		c.Paths.Crashes = defaultPaths().Crashes // Configs from before crash reports
+	}
+
+	c.Hotkeys.validate()
+
+	// Validate cheats: normalize the codes and drop those that do not parse
+	for game, cheats := range c.Cheats.Games {
+		if cheats == nil {
+			delete(c.Cheats.Games, game)
+			continue
+		}
+		cheats.GameGenie = validCheatCodes(game, cheatGameGenie, cheats.GameGenie)
+		cheats.RAM = validCheatCodes(game, cheatRAM, cheats.RAM)
 	}
 
 	// Validate input configuration
 	if c.Input.ControllerDeadzone < 0.0 || c.Input.ControllerDeadzone > 1.0 {
-		c.Input.ControllerDeadzone = 0.1
+		resetSetting("input.controller_deadzone", &c.Input.ControllerDeadzone, "0-1", 0.1)
+	}
+
+	if c.Input.MicrophoneThreshold < 0 || c.Input.MicrophoneThreshold > 1 {
+		resetSetting("input.microphone_threshold", &c.Input.MicrophoneThreshold, "0-1", 0)
 	}
 
 	if c.Input.AutofireRate <= 0 {
-		c.Input.AutofireRate = 10
+		resetSetting("input.autofire_rate", &c.Input.AutofireRate, "1 or more", 10)
+	}
+
+	switch strings.ToLower(c.Input.Port2Device) {
+	case Port2Zapper, Port2Arkanoid:
+		c.Input.Port2Device = strings.ToLower(c.Input.Port2Device)
+	default:
+		if device := strings.ToLower(c.Input.Port2Device); device != "" && device != Port2Controller {
+			fmt.Printf("[APP_WARNING] Config input.port2_device is %q, which is not %s; using a controller\n",
+				c.Input.Port2Device, oneOf([]string{Port2Controller, Port2Zapper, Port2Arkanoid}))
+		}
+		c.Input.Port2Device = Port2Controller
+		if c.Input.Zapper {
+			c.Input.Port2Device = Port2Zapper
+		}
 	}
+	c.Input.Zapper = c.Input.Port2Device == Port2Zapper
 
 	return nil
 }
 
+// resetSetting reports a setting whose value is not allowed and replaces it
+// with fallback, so a mistake in the config file does not go unnoticed
+func resetSetting[T any](key string, value *T, allowed string, fallback T) {
+	fmt.Printf("[APP_WARNING] Config %s is %#v, which is not %s; using %#v\n", key, *value, allowed, fallback)
+	*value = fallback
+}
+
+// oneOf describes the allowed values of a setting
+func oneOf(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	return "one of " + strings.Join(quoted, ", ")
+}
+
 // createDirectories creates required directories
 func (c *Config) createDirectories() error {
 	dirs := []string{
@@ -340,6 +904,7 @@ func (c *Config) createDirectories() error {
 		c.Paths.SaveData,
 		c.Paths.SaveStates,
 		c.Paths.Screenshots,
+		c.Paths.Recordings,
 		c.Paths.Config,
 		c.Paths.Logs,
 	}
@@ -373,9 +938,14 @@ func (c *Config) GetAspectRatio() float32 {
 		return 4.0 / 3.0
 	case "16:9":
 		return 16.0 / 9.0
-	case "original":
+	case "original", "integer":
 		nesWidth, nesHeight := c.GetNESResolution()
 		return float32(nesWidth) / float32(nesHeight)
+	case "8:7":
+		nesWidth, nesHeight := c.GetNESResolution()
+		return float32(nesWidth) * 8 / 7 / float32(nesHeight)
+	case "fill":
+		return float32(c.Window.Width) / float32(c.Window.Height)
 	default:
 		return 4.0 / 3.0 // Default to 4:3
 	}
@@ -448,14 +1018,28 @@ func (c *Config) UpdateDebug(showFPS, showDebugInfo, enableLogging bool) {
 	c.Debug.EnableLogging = enableLogging
 }
 
+// configFileNames are the names the config file is looked for by, in order
+var configFileNames = []string{"gones.json", "gones.toml", "gones.yaml", "gones.yml"}
+
 // GetDefaultConfigPath returns the default configuration file path
 func GetDefaultConfigPath() string {
-	return "./config/gones.json"
+	// A TOML or YAML config is used when there is no JSON one
+	dir := GetDefaultConfigDir()
+	for _, name := range configFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, configFileNames[0])
 }
 
-// GetDefaultConfigDir returns the default configuration directory
+// GetDefaultConfigDir returns the default configuration directory: gones'
+// directory in the user's config directory, or the config directory next to
+// the executable in portable mode
 func GetDefaultConfigDir() string {
-	return "./config"
+	configDir, _ := userDirs()
+	return configDir
 }
 
 // ConfigError represents configuration-related errors
@@ -0,0 +1,254 @@
+// Package app provides the config file formats: JSON, TOML and YAML files
+// hold the same settings under the same keys, so the JSON schema of Config
+// checks all three.
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFormat is the syntax of a config file
+type configFormat int
+
+const (
+	configJSON configFormat = iota
+	configTOML
+	configYAML
+)
+
+// configFormatForPath picks a config file's format from its extension;
+// files without a TOML or YAML extension are JSON
+func configFormatForPath(path string) configFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return configTOML
+	case ".yaml", ".yml":
+		return configYAML
+	}
+	return configJSON
+}
+
+// decodeConfig parses a config file into the values JSON has: maps keyed
+// by strings, slices, strings, numbers and bools
+func decodeConfig(format configFormat, data []byte) (map[string]any, error) {
+	var raw map[string]any
+	switch format {
+	case configTOML:
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	case configYAML:
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+	}
+	if raw == nil {
+		raw = map[string]any{} // An empty file keeps the defaults
+	}
+	return plainConfigValue(raw).(map[string]any), nil
+}
+
+// plainConfigValue turns the maps and slices of the TOML and YAML decoders
+// into the types the JSON decoder gives
+func plainConfigValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = plainConfigValue(item)
+		}
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = plainConfigValue(item)
+		}
+		return m
+	case []map[string]any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = plainConfigValue(item)
+		}
+		return items
+	case []any:
+		for i, item := range v {
+			v[i] = plainConfigValue(item)
+		}
+	}
+	return value
+}
+
+// applyConfig sets c from a decoded config file. The JSON decoder checks
+// the value types for every format.
+func (c *Config) applyConfig(raw map[string]any) error {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return fmt.Errorf("%s must be %s, not %s", typeErr.Field, configTypeName(typeErr.Type), typeErr.Value)
+		}
+		return err
+	}
+	return nil
+}
+
+// configTypeName names a setting's type for someone editing the file
+func configTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	}
+	return "a table"
+}
+
+// encodeConfig writes c in a config file format
+func encodeConfig(format configFormat, c *Config) ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil || format == configJSON {
+		return data, err
+	}
+
+	// TOML and YAML are written from the JSON, so the keys are the same
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw map[string]any
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+	raw = encodableConfigValue(raw).(map[string]any)
+	if format == configYAML {
+		return yaml.Marshal(raw)
+	}
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	encoder.Indent = ""
+	if err := encoder.Encode(raw); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodableConfigValue turns JSON numbers into integers or floats and drops
+// null values, which TOML cannot write
+func encodableConfigValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if item == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = encodableConfigValue(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = encodableConfigValue(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
+
+// jsonUnmarshaler is implemented by settings that parse themselves
+var jsonUnmarshaler = reflect.TypeFor[json.Unmarshaler]()
+
+// unknownConfigKeys lists the keys of a decoded config file that no setting
+// of t has, such as misspelled ones, as dotted paths
+func unknownConfigKeys(path string, value any, t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshaler) {
+		return nil
+	}
+
+	var unknown []string
+	switch t.Kind() {
+	case reflect.Struct:
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil // Wrong type, which applyConfig reports
+		}
+		for key, item := range fields {
+			field, ok := configField(t, key)
+			if !ok {
+				unknown = append(unknown, configKey(path, key))
+				continue
+			}
+			unknown = append(unknown, unknownConfigKeys(configKey(path, key), item, field.Type)...)
+		}
+	case reflect.Map:
+		entries, _ := value.(map[string]any)
+		for key, item := range entries {
+			unknown = append(unknown, unknownConfigKeys(configKey(path, key), item, t.Elem())...)
+		}
+	case reflect.Slice, reflect.Array:
+		items, _ := value.([]any)
+		for i, item := range items {
+			unknown = append(unknown, unknownConfigKeys(fmt.Sprintf("%s[%d]", path, i), item, t.Elem())...)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
+
+// configField finds the field of struct type t that a config key sets,
+// ignoring case as the JSON decoder does
+func configField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// configKey joins a key to the path of the table holding it
+func configKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,223 @@
+package app
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var configFormats = []struct {
+	name   string
+	path   string
+	format configFormat
+}{
+	{"JSON", "gones.json", configJSON},
+	{"TOML", "gones.toml", configTOML},
+	{"YAML", "gones.yml", configYAML},
+}
+
+// decodeTestConfig decodes a config file of a format and applies it over the
+// defaults, as LoadFromFile does
+func decodeTestConfig(t *testing.T, format configFormat, data []byte) (*Config, []string, error) {
+	t.Helper()
+	raw, err := decodeConfig(format, data)
+	if err != nil {
+		t.Fatalf("decodeConfig: %v\n%s", err, data)
+	}
+	unknown := unknownConfigKeys("", raw, reflect.TypeFor[Config]())
+	c := NewConfig()
+	return c, unknown, c.applyConfig(raw)
+}
+
+func TestConfigFormat_RoundTrip(t *testing.T) {
+	want := NewConfig()
+	want.Video.Upscaler = "hq2x"
+	want.Video.UnlimitedSprites = true
+	want.Audio.Volume = 0.35
+	want.Debug.MemoryBookmarks = map[string]string{"player_x": "$0086"}
+	want.Debug.Trace.Banks = []int{0, 3}
+	want.Library.Dirs = []string{"/roms", "/more roms"}
+	want.Cheats.Games = map[string]*GameCheats{
+		"smb": {
+			GameGenie: []CheatCode{{Code: "SXIOPO", Name: "Lives"}},
+			RAM:       []CheatCode{{Code: "0075:09", Disabled: true}},
+		},
+	}
+	wantJSON, _ := json.Marshal(want)
+
+	for _, tt := range configFormats {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := configFormatForPath(tt.path); got != tt.format {
+				t.Errorf("configFormatForPath(%q) = %d, want %d", tt.path, got, tt.format)
+			}
+			data, err := encodeConfig(tt.format, want)
+			if err != nil {
+				t.Fatalf("encodeConfig: %v", err)
+			}
+			got, unknown, err := decodeTestConfig(t, tt.format, data)
+			if err != nil {
+				t.Fatalf("applyConfig: %v", err)
+			}
+			if len(unknown) > 0 {
+				t.Errorf("unknown keys in the written file: %v", unknown)
+			}
+			if gotJSON, _ := json.Marshal(got); string(gotJSON) != string(wantJSON) {
+				t.Errorf("config read back differs:\n got %s\nwant %s\nfile:\n%s", gotJSON, wantJSON, data)
+			}
+		})
+	}
+}
+
+func TestConfigFormat_EmptyFileKeepsDefaults(t *testing.T) {
+	wantJSON, _ := json.Marshal(NewConfig())
+	for _, tt := range configFormats {
+		data := ""
+		if tt.format == configJSON {
+			data = "{}"
+		}
+		got, unknown, err := decodeTestConfig(t, tt.format, []byte(data))
+		if err != nil || len(unknown) > 0 {
+			t.Errorf("%s: empty file gave %v, unknown keys %v", tt.name, err, unknown)
+		}
+		if gotJSON, _ := json.Marshal(got); string(gotJSON) != string(wantJSON) {
+			t.Errorf("%s: empty file changed the defaults", tt.name)
+		}
+	}
+}
+
+func TestConfigFormat_UnknownKeys(t *testing.T) {
+	want := []string{"cheats.games.smb.game_genie[0].nmae", "video.upscaller", "vidoe"}
+	files := map[configFormat]string{
+		configJSON: `{
+			"vidoe": {"vsync": true},
+			"video": {"upscaller": "hq2x", "VSync": false},
+			"cheats": {"games": {"smb": {"game_genie": [{"code": "SXIOPO", "nmae": "Lives"}]}}}
+		}`,
+		configTOML: `
+			[vidoe]
+			vsync = true
+
+			[video]
+			upscaller = "hq2x"
+			VSync = false
+
+			[[cheats.games.smb.game_genie]]
+			code = "SXIOPO"
+			nmae = "Lives"
+		`,
+		configYAML: `
+vidoe:
+  vsync: true
+video:
+  upscaller: hq2x
+  VSync: false
+cheats:
+  games:
+    smb:
+      game_genie:
+        - code: SXIOPO
+          nmae: Lives
+`,
+	}
+
+	for _, tt := range configFormats {
+		t.Run(tt.name, func(t *testing.T) {
+			c, unknown, err := decodeTestConfig(t, tt.format, []byte(files[tt.format]))
+			if err != nil {
+				t.Fatalf("applyConfig: %v", err)
+			}
+			// Keys differing only in case set their setting, as in JSON
+			if !slices.Equal(unknown, want) {
+				t.Errorf("unknown keys %v, want %v", unknown, want)
+			}
+			if c.Video.VSync {
+				t.Error("VSync key not applied to video.vsync")
+			}
+			if cheats := c.Cheats.Games["smb"]; cheats == nil || len(cheats.GameGenie) != 1 || cheats.GameGenie[0].Code != "SXIOPO" {
+				t.Errorf("known keys beside the unknown ones not applied: %+v", c.Cheats.Games)
+			}
+		})
+	}
+}
+
+func TestConfigFormat_WrongTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[configFormat]string
+		want  string
+	}{
+		{
+			name: "bool",
+			files: map[configFormat]string{
+				configJSON: `{"video": {"vsync": "yes"}}`,
+				configTOML: "[video]\nvsync = \"yes\"",
+				configYAML: "video:\n  vsync: \"yes\"",
+			},
+			want: "video.vsync must be true or false, not string",
+		},
+		{
+			name: "whole number",
+			files: map[configFormat]string{
+				configJSON: `{"audio": {"sample_rate": 44.1}}`,
+				configTOML: "[audio]\nsample_rate = 44.1",
+				configYAML: "audio:\n  sample_rate: 44.1",
+			},
+			want: "audio.sample_rate must be a whole number, not number 44.1",
+		},
+		{
+			name: "number",
+			files: map[configFormat]string{
+				configJSON: `{"audio": {"volume": "loud"}}`,
+				configTOML: "[audio]\nvolume = \"loud\"",
+				configYAML: "audio:\n  volume: loud",
+			},
+			want: "audio.volume must be a number, not string",
+		},
+		{
+			name: "list",
+			files: map[configFormat]string{
+				configJSON: `{"library": {"dirs": "/roms"}}`,
+				configTOML: "[library]\ndirs = \"/roms\"",
+				configYAML: "library:\n  dirs: /roms",
+			},
+			want: "library.dirs must be a list, not string",
+		},
+		{
+			name: "table",
+			files: map[configFormat]string{
+				configJSON: `{"video": 2}`,
+				configTOML: "video = 2",
+				configYAML: "video: 2",
+			},
+			want: "video must be a table, not number",
+		},
+	}
+
+	for _, tt := range tests {
+		for _, format := range configFormats {
+			t.Run(tt.name+"/"+format.name, func(t *testing.T) {
+				_, _, err := decodeTestConfig(t, format.format, []byte(tt.files[format.format]))
+				if err == nil || err.Error() != tt.want {
+					t.Errorf("applyConfig error %v, want %q", err, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestConfigFormat_SyntaxErrors(t *testing.T) {
+	files := map[configFormat]string{
+		configJSON: `{"video": {"vsync": true}`,
+		configTOML: "[video\nvsync = true",
+		configYAML: "video:\n  vsync: [true",
+	}
+	for _, tt := range configFormats {
+		if _, err := decodeConfig(tt.format, []byte(files[tt.format])); err == nil {
+			t.Errorf("%s: decodeConfig of a broken file succeeded", tt.name)
+		} else if strings.TrimSpace(err.Error()) == "" {
+			t.Errorf("%s: decodeConfig gave an empty error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package app provides config hot-reload: edits to the config file made
+// while the emulator runs are picked up and applied without a restart.
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// configCheckInterval is how often the config file is checked for changes
+const configCheckInterval = time.Second
+
+// watchConfig reloads the config file when it changed on disk. Only the
+// window needs it; headless runs read the config once.
+func (app *Application) watchConfig() {
+	if app.window == nil || time.Since(app.lastConfigCheck) < configCheckInterval {
+		return
+	}
+	app.lastConfigCheck = time.Now()
+	if app.config.changedOnDisk() {
+		app.reloadConfig()
+	}
+}
+
+// reloadConfig reads the config file again and applies it to the running
+// emulator. A file that does not load leaves the settings as they were.
+func (app *Application) reloadConfig() {
+	path := app.config.GetConfigPath()
+	config := NewConfig()
+	if err := config.LoadFromFile(path); err != nil {
+		fmt.Printf("[APP_WARNING] Config %s not reloaded: %v\n", path, err)
+		app.config.modTime = config.modTime // Wait for the next edit
+		return
+	}
+
+	restart := !reflect.DeepEqual(startupSettings(app.config), startupSettings(config))
+	*app.config = *config // In place, as open menu pages point into it
+	app.applyLiveSettings()
+
+	fmt.Printf("🔄 Config reloaded from %s\n", path)
+	if restart {
+		fmt.Println("[APP_WARNING] Window, CRT, audio device, gamepad and path settings apply after a restart")
+	}
+}
+
+// applyLiveSettings pushes the video, input, emulation and cheat settings of
+// the config to the running emulator. Audio volume, muting and the
+// fast-forward settings are read every frame, so they need nothing.
+func (app *Application) applyLiveSettings() {
+	video := app.config.Video
+	if app.videoProcessor != nil {
+		app.videoProcessor.SetBrightness(video.Brightness)
+		app.videoProcessor.SetContrast(video.Contrast)
+		app.videoProcessor.SetSaturation(video.Saturation)
+		app.videoProcessor.SetFrameBlend(video.FrameBlend)
+	}
+	for _, err := range []error{
+		app.SetAspectRatio(video.AspectRatio),
+		app.SetUpscaler(video.Upscaler),
+		app.SetColorFilter(video.ColorFilter),
+	} {
+		if err != nil {
+			fmt.Printf("[APP_WARNING] %v\n", err)
+		}
+	}
+
+	app.applyInputBindings()
+	app.connectPort2Device()
+	if app.bus != nil {
+		app.bus.SetFourScore(app.inputConfig().FourScore)
+		app.bus.SetDMCConflicts(app.config.Emulation.DMCConflicts)
+	}
+	app.applyCheats()
+}
+
+// startupSettings returns the settings only read when the emulator starts
+func startupSettings(c *Config) []any {
+	return []any{
+		c.Window,
+		c.Video.VSync, c.Video.Filter, c.Video.Backend, c.Video.TerminalMode, c.Video.CRT,
+		c.Audio.SampleRate, c.Audio.BufferSize, c.Audio.Channels, c.Audio.Latency,
+		c.Input.ControllerDeadzone,
+		c.Input.Player1Gamepad, c.Input.Player2Gamepad, c.Input.Player3Gamepad, c.Input.Player4Gamepad,
+		c.Paths,
+	}
+}
@@ -0,0 +1,209 @@
+// Package app provides crash reports: a directory with the machine state, the
+// last instructions run, a save state and a screenshot of a crashed game.
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gones/internal/cpu"
+	"gones/internal/version"
+)
+
+// CrashError is returned when emulation crashes: the emulator panicked or
+// the CPU ran a JAM opcode. Emulation is paused and the crash report written.
+type CrashError struct {
+	Reason string
+	Report string // Crash report directory, "" if it could not be written
+}
+
+func (e *CrashError) Error() string {
+	if e.Report == "" {
+		return "emulation crashed: " + e.Reason
+	}
+	return fmt.Sprintf("emulation crashed: %s (crash report in %s)", e.Reason, e.Report)
+}
+
+// ReportCrash writes a crash report, pauses emulation and returns the error
+// emulation fails with. stack is the Go stack of a panic, or nil.
+func (app *Application) ReportCrash(reason string, stack []byte) error {
+	report, err := app.WriteCrashReport(reason, stack)
+	if err != nil {
+		fmt.Printf("[APP_ERROR] Crash report failed: %v\n", err)
+	}
+	fmt.Printf("💥 Emulation crashed: %s\n", reason)
+	if report != "" {
+		fmt.Printf("   Crash report: %s\n", report)
+	}
+	app.announceStop("crash")
+	return &CrashError{Reason: reason, Report: report}
+}
+
+// CheckJam reports a CPU locked up by a JAM opcode as a crash
+func (app *Application) CheckJam() error {
+	if app.bus == nil || !app.bus.CPU.Jammed() {
+		return nil
+	}
+	pc := app.bus.CPU.PC
+	opcode, _ := app.bus.Memory.Peek(pc)
+	return app.ReportCrash(fmt.Sprintf("CPU jammed by opcode $%02X at $%04X", opcode, pc), nil)
+}
+
+// WriteCrashReport writes a crash report to a new <crashes>/<rom>_<time>
+// directory and returns its path. It holds report.txt with the reason and
+// CPU/PPU state, trace.log with the last instructions when they were kept,
+// state.save to load the moment of the crash and screen.png. Parts that fail
+// are left out rather than losing the report.
+func (app *Application) WriteCrashReport(reason string, stack []byte) (string, error) {
+	dir := capturePath(app.config.Paths.Crashes, app.romPath, "", time.Now())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create crash directory: %v", err)
+	}
+
+	var notes []string
+	note := func(name string, err error) {
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if app.bus != nil && app.cartridge != nil {
+		note("state.save", safely(func() error {
+			return app.states.ExportState(app.bus, filepath.Join(dir, "state.save"), app.romPath)
+		}))
+		note("screen.png", safely(func() error {
+			return app.SaveScreenshot(filepath.Join(dir, "screen.png"), true)
+		}))
+	}
+	traced, err := app.writeCrashTrace(filepath.Join(dir, "trace.log"), reason)
+	note("trace.log", err)
+
+	file, err := os.Create(filepath.Join(dir, "report.txt"))
+	if err != nil {
+		return dir, fmt.Errorf("failed to create crash report: %v", err)
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	app.writeCrashSummary(w, reason, traced, notes, stack)
+	if err := w.Flush(); err != nil {
+		return dir, fmt.Errorf("failed to write crash report: %v", err)
+	}
+	return dir, nil
+}
+
+// safely runs a step of the crash report, turning a panic from the broken
+// machine into an error
+func safely(step func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return step()
+}
+
+// writeCrashTrace writes the last instructions to path and returns how many
+// were written. A streaming trace log is flushed instead, as it already ends
+// at the crash.
+func (app *Application) writeCrashTrace(path, reason string) (int, error) {
+	tracer := app.crashTrace
+	if tracer == nil && app.tracer != nil {
+		if !app.tracer.IsRing() {
+			return 0, app.tracer.Flush()
+		}
+		tracer = app.tracer
+	}
+	if tracer == nil {
+		return 0, nil
+	}
+	if err := writeTraceRing(tracer, path, reason); err != nil {
+		return 0, err
+	}
+	return tracer.Len(), nil
+}
+
+// writeCrashSummary writes report.txt
+func (app *Application) writeCrashSummary(w *bufio.Writer, reason string, traced int, notes []string, stack []byte) {
+	fmt.Fprintf(w, "GoNES crash report\n\n")
+	fmt.Fprintf(w, "Reason:    %s\n", reason)
+	fmt.Fprintf(w, "Time:      %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(w, "Version:   %s\n", version.GetVersion())
+	fmt.Fprintf(w, "ROM:       %s\n", app.romPath)
+	if app.cartridge != nil {
+		fmt.Fprintf(w, "Mapper:    %d\n", app.cartridge.MapperID())
+	}
+	if app.emulator != nil {
+		fmt.Fprintf(w, "Frame:     %d\n", app.emulator.GetFrameCount())
+	}
+	fmt.Fprintf(w, "Play time: %s\n", app.playTime.Round(time.Second))
+
+	if app.bus != nil && app.bus.CPU != nil && app.bus.PPU != nil {
+		c, ppu := app.bus.CPU, app.bus.PPU
+		fmt.Fprintf(w, "\nCPU\n")
+		fmt.Fprintf(w, "  PC:$%04X A:$%02X X:$%02X Y:$%02X P:$%02X SP:$%02X  Cycles:%d\n",
+			c.PC, c.A, c.X, c.Y, c.GetStatusByte(), c.SP, c.GetCycles())
+		fmt.Fprintf(w, "  Flags: %s  Jammed: %t\n", statusFlags(c.GetStatusByte()), c.Jammed())
+		if app.bus.Memory != nil {
+			peek := func(address uint16) uint8 {
+				value, _ := app.bus.Memory.Peek(address)
+				return value
+			}
+			fmt.Fprintf(w, "  Code:\n")
+			pc := c.PC
+			for i := 0; i < 8; i++ {
+				text, length := cpu.Disassemble(peek, pc)
+				fmt.Fprintf(w, "    $%04X  %s\n", pc, text)
+				pc += uint16(length)
+			}
+			fmt.Fprintf(w, "  Stack:")
+			for address := 0x0100 + int(c.SP) + 1; address <= 0x01FF; address++ {
+				fmt.Fprintf(w, " %02X", peek(uint16(address)))
+			}
+			fmt.Fprintf(w, "\n")
+		}
+
+		r := ppu.Registers()
+		fmt.Fprintf(w, "\nPPU\n")
+		fmt.Fprintf(w, "  Scanline:%d Dot:%d Frame:%d\n", ppu.GetScanline(), ppu.GetCycle(), ppu.GetFrameCount())
+		fmt.Fprintf(w, "  CTRL:$%02X MASK:$%02X STATUS:$%02X OAMADDR:$%02X\n", r.Ctrl, r.Mask, r.Status, r.OAMAddr)
+		fmt.Fprintf(w, "  V:$%04X T:$%04X X:%d W:%t\n", r.V, r.T, r.X, r.W)
+	}
+
+	fmt.Fprintf(w, "\nFiles\n")
+	switch {
+	case traced > 0:
+		fmt.Fprintf(w, "  trace.log   the last %d instructions\n", traced)
+	case app.tracer != nil:
+		fmt.Fprintf(w, "  (the trace log %s ends at the crash)\n", app.getTracePath())
+	default:
+		fmt.Fprintf(w, "  (set debug.trace.crash_dump to include the last instructions)\n")
+	}
+	if app.cartridge != nil {
+		fmt.Fprintf(w, "  state.save  the machine at the crash: gones -rom <rom> -load-state state.save\n")
+		fmt.Fprintf(w, "  screen.png  the last frame\n")
+	}
+	for _, n := range notes {
+		fmt.Fprintf(w, "  failed: %s\n", n)
+	}
+
+	if len(stack) > 0 {
+		fmt.Fprintf(w, "\nGo stack\n%s", strings.TrimRight(string(stack), "\n"))
+		fmt.Fprintf(w, "\n")
+	}
+}
+
+// statusFlags formats the status register like "NV-BDIZC", with "." for
+// clear flags
+func statusFlags(p uint8) string {
+	const names = "NV-BDIZC"
+	flags := []byte(names)
+	for i := range flags {
+		if p&(0x80>>i) == 0 && names[i] != '-' {
+			flags[i] = '.'
+		}
+	}
+	return string(flags)
+}
@@ -0,0 +1,300 @@
+// Package app provides the pause/step debugger built around the memory viewer.
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gones/internal/graphics"
+)
+
+// cpuBookmarks are the built-in regions of the CPU address space
+var cpuBookmarks = []memoryBookmark{
+	{"ZERO PAGE", 0x0000}, {"STACK", 0x0100}, {"RAM", 0x0200},
+	{"PPU REGS", 0x2000}, {"APU/IO REGS", 0x4000}, {"EXPANSION", 0x4020},
+	{"PRG RAM", 0x6000}, {"PRG ROM", 0x8000}, {"VECTORS", 0xFFFA},
+}
+
+// ppuBookmarks are the built-in regions of the PPU address space
+var ppuBookmarks = []memoryBookmark{
+	{"PATTERN 0", 0x0000}, {"PATTERN 1", 0x1000},
+	{"NAMETABLE 0", 0x2000}, {"NAMETABLE 1", 0x2400}, {"NAMETABLE 2", 0x2800}, {"NAMETABLE 3", 0x2C00},
+	{"NT MIRROR", 0x3000}, {"PALETTE", 0x3F00},
+}
+
+// parseMemoryAddress parses a hex address such as "$0300", "0x0300" or "0300"
+func parseMemoryAddress(s string) (uint16, error) {
+	s = strings.TrimSpace(s)
+	digits := strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(s, "$"), "0x"), "0X")
+	address, err := strconv.ParseUint(digits, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(address), nil
+}
+
+// memorySpaces returns the address spaces of the loaded game for the memory viewer
+func (app *Application) memorySpaces() []*memorySpace {
+	mem := app.bus.Memory
+	ppu := app.bus.PPU
+	vram := ppu.GetMemory()
+
+	bookmarks := append([]memoryBookmark(nil), cpuBookmarks...)
+	for name, address := range app.config.Debug.MemoryBookmarks {
+		if addr, err := parseMemoryAddress(address); err == nil {
+			bookmarks = append(bookmarks, memoryBookmark{strings.ToUpper(name), int(addr)})
+		}
+	}
+
+	cpu := newMemorySpace("CPU", 0x10000,
+		func(address int) (uint8, bool) { return mem.Peek(uint16(address)) },
+		func(address int, value uint8) bool {
+			if !mem.Poke(uint16(address), value) {
+				return false
+			}
+			// Keep a frozen byte at the typed value
+			if app.IsFrozen(uint16(address)) {
+				if err := app.FreezeAddress(uint16(address), value); err != nil {
+					fmt.Printf("[APP_WARNING] %v\n", err)
+				}
+			}
+			return true
+		},
+		bookmarks)
+	cpu.frozen = func(address int) bool { return app.IsFrozen(uint16(address)) }
+	spaces := []*memorySpace{cpu}
+	if vram != nil {
+		spaces = append(spaces, newMemorySpace("PPU", 0x4000,
+			func(address int) (uint8, bool) { return vram.Peek(uint16(address)), true },
+			func(address int, value uint8) bool { vram.Write(uint16(address), value); return true },
+			append([]memoryBookmark(nil), ppuBookmarks...)))
+	}
+	spaces = append(spaces, newMemorySpace("OAM", 0x100,
+		func(address int) (uint8, bool) { return ppu.ReadOAM(uint8(address)), true },
+		func(address int, value uint8) bool { ppu.WriteOAM(uint8(address), value); return true },
+		[]memoryBookmark{{"SPRITE 0", 0x00}, {"SPRITE 16", 0x40}, {"SPRITE 32", 0x80}, {"SPRITE 48", 0xC0}}))
+	if vram != nil {
+		spaces = append(spaces, newMemorySpace("PALETTE", 0x20,
+			func(address int) (uint8, bool) { return vram.Peek(0x3F00 + uint16(address)), true },
+			func(address int, value uint8) bool { vram.Write(0x3F00+uint16(address), value); return true },
+			[]memoryBookmark{{"BACKGROUND", 0x00}, {"SPRITES", 0x10}}))
+	}
+	return spaces
+}
+
+// ShowMemoryViewer opens the memory viewer, pausing emulation while it is shown
+func (app *Application) ShowMemoryViewer() {
+	if app.cartridge == nil || app.memoryViewer.IsVisible() {
+		return
+	}
+	app.memoryViewer.Open(app.memorySpaces(), app.bus.GetCycleCount(), app.paused)
+	app.paused = true
+}
+
+// HideMemoryViewer closes the memory viewer and restores the previous pause state
+func (app *Application) HideMemoryViewer() {
+	if !app.memoryViewer.IsVisible() {
+		return
+	}
+	app.stopMemoryEdit()
+	app.paused = app.memoryViewer.Close()
+}
+
+// IsMemoryViewerVisible returns whether the memory viewer is on screen
+func (app *Application) IsMemoryViewerVisible() bool {
+	return app.memoryViewer != nil && app.memoryViewer.IsVisible()
+}
+
+// StepFrame pauses emulation and runs one frame
+func (app *Application) StepFrame() error {
+	if app.cartridge == nil {
+		return nil
+	}
+	app.paused = true
+	if err := app.emulateFrame(); err != nil && !errors.Is(err, errStopped) {
+		return err
+	}
+	return nil
+}
+
+// StepInstruction pauses emulation and runs one CPU instruction
+func (app *Application) StepInstruction() error {
+	if app.cartridge == nil {
+		return nil
+	}
+	app.paused = true
+	return app.emulator.StepInstruction()
+}
+
+// handleMemoryViewerInput navigates the memory viewer and runs the debugger
+// commands: F5 runs or pauses, F10 steps a frame and F11 an instruction
+func (app *Application) handleMemoryViewerInput(event graphics.InputEvent) {
+	viewer := app.memoryViewer
+	var err error
+
+	switch event.Type {
+	case graphics.InputEventTypeKey:
+		switch event.Key {
+		case graphics.KeyEscape:
+			app.HideMemoryViewer()
+		case graphics.KeyF6:
+			if event.Modifiers&graphics.ModifierCtrl != 0 {
+				app.HideMemoryViewer()
+			}
+		case graphics.KeyTab:
+			viewer.NextSpace()
+		case graphics.KeyMinus:
+			viewer.Move(-memoryViewerPage)
+		case graphics.KeyEqual:
+			viewer.Move(memoryViewerPage)
+		case graphics.KeyF5:
+			app.paused = !app.paused
+		case graphics.KeyF10:
+			err = app.StepFrame()
+		case graphics.KeyF11:
+			err = app.StepInstruction()
+		}
+
+	case graphics.InputEventTypeButton:
+		switch event.Button {
+		case graphics.ButtonUp:
+			viewer.Move(-memoryViewerColumns)
+		case graphics.ButtonDown:
+			viewer.Move(memoryViewerColumns)
+		case graphics.ButtonLeft:
+			viewer.Move(-1)
+		case graphics.ButtonRight:
+			viewer.Move(1)
+		case graphics.ButtonA:
+			app.startMemoryEdit()
+		case graphics.ButtonB:
+			viewer.NextBookmark()
+		case graphics.ButtonSelect:
+			app.toggleFreeze()
+		}
+	}
+
+	if err != nil {
+		fmt.Printf("[APP_ERROR] Debugger step failed: %v\n", err)
+		viewer.SetMessage("STEP FAILED")
+	}
+}
+
+// toggleFreeze freezes the CPU byte under the memory viewer's cursor at its
+// current value with a RAM cheat, or removes the cheat freezing it
+func (app *Application) toggleFreeze() {
+	viewer := app.memoryViewer
+	if viewer.GetSpaceName() != "CPU" {
+		viewer.SetMessage("ONLY CPU MEMORY CAN BE FROZEN")
+		return
+	}
+	address := uint16(viewer.GetCursor())
+
+	if app.frozenCheat(address) >= 0 {
+		if err := app.UnfreezeAddress(address); err != nil {
+			viewer.SetMessage(strings.ToUpper(err.Error()))
+			return
+		}
+		viewer.SetMessage(fmt.Sprintf("$%04X UNFROZEN", address))
+		return
+	}
+
+	// Only RAM and PRG RAM can be written back every frame
+	value, ok := app.bus.Memory.Peek(address)
+	if !ok || (address >= 0x2000 && address < 0x6000) || address >= 0x8000 {
+		viewer.SetMessage(fmt.Sprintf("$%04X CANNOT BE FROZEN", address))
+		return
+	}
+	if err := app.FreezeAddress(address, value); err != nil {
+		viewer.SetMessage(strings.ToUpper(err.Error()))
+		return
+	}
+	viewer.SetMessage(fmt.Sprintf("$%04X FROZEN AT $%02X", address, value))
+}
+
+// startMemoryEdit starts typing a value for the selected byte. Digits are
+// read by capturing raw key presses, since keys bound to the controllers
+// (such as A or 1) otherwise arrive as button events.
+func (app *Application) startMemoryEdit() {
+	window, ok := app.window.(graphics.RebindableWindow)
+	if !ok {
+		app.memoryViewer.SetMessage("HEX ENTRY NOT SUPPORTED BY " + strings.ToUpper(app.graphicsBackend.GetName()))
+		return
+	}
+	if app.memoryViewer.StartEdit() {
+		window.CaptureNextInput(app.onMemoryEditInput)
+	}
+}
+
+// onMemoryEditInput takes a captured key press while editing: hex digits are
+// entered, anything else stops editing
+func (app *Application) onMemoryEditInput(input graphics.CapturedInput) {
+	digit, ok := hexDigitKey(input)
+	if !ok || !app.memoryViewer.EnterDigit(digit) {
+		app.memoryViewer.CancelEdit()
+		return
+	}
+	if window, ok := app.window.(graphics.RebindableWindow); ok {
+		window.CaptureNextInput(app.onMemoryEditInput)
+	}
+}
+
+// stopMemoryEdit abandons typing and the pending key capture
+func (app *Application) stopMemoryEdit() {
+	if !app.memoryViewer.IsEditing() {
+		return
+	}
+	if window, ok := app.window.(graphics.RebindableWindow); ok {
+		window.CancelCapture()
+	}
+	app.memoryViewer.CancelEdit()
+}
+
+// hexDigitKey returns the value of a captured hex digit key ("A", "Digit7",
+// "Keypad 7")
+func hexDigitKey(input graphics.CapturedInput) (uint8, bool) {
+	if input.Gamepad {
+		return 0, false
+	}
+	name := strings.ToLower(strings.TrimSpace(input.Name))
+	for _, prefix := range []string{"digit", "numpad", "keypad", "kp"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			name = strings.TrimSpace(strings.TrimPrefix(rest, "_"))
+			break
+		}
+	}
+	if len(name) != 1 {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(name, 16, 8)
+	if err != nil {
+		return 0, false
+	}
+	return uint8(value), true
+}
+
+// renderMemoryViewer refreshes and draws the memory viewer over the frame
+func (app *Application) renderMemoryViewer(frameBuffer *[256 * 240]uint32) {
+	if !app.IsMemoryViewerVisible() {
+		return
+	}
+	app.memoryViewer.Refresh(app.bus.GetCycleCount())
+
+	status := fmt.Sprintf("FRAME %d RUNNING", app.emulator.GetFrameCount())
+	if app.paused {
+		status = fmt.Sprintf("FRAME %d PAUSED", app.emulator.GetFrameCount())
+	}
+
+	cpu := app.emulator.GetCPUState()
+	flags := []byte("NV-BDIZC")
+	for i, set := range []bool{cpu.Flags.N, cpu.Flags.V, true, cpu.Flags.B, cpu.Flags.D, cpu.Flags.I, cpu.Flags.Z, cpu.Flags.C} {
+		if !set {
+			flags[i] = '.'
+		}
+	}
+	registers := fmt.Sprintf("PC:%04X A:%02X X:%02X Y:%02X SP:%02X %s", cpu.PC, cpu.A, cpu.X, cpu.Y, cpu.SP, flags)
+
+	app.memoryViewer.Render(frameBuffer, status, registers)
+}
@@ -0,0 +1,144 @@
+// Package app provides the directories gones keeps its files in: the
+// platform's user directories, or the directory of the executable in
+// portable mode.
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// appDirName is the name of gones' directory in the user directories
+const appDirName = "gones"
+
+// portable is set by SetPortable
+var portable bool
+
+// SetPortable turns portable mode on or off. It is called before the config
+// is loaded, as it changes the default paths.
+func SetPortable(enabled bool) {
+	portable = enabled
+}
+
+// PortableDir returns the directory of the executable, which portable mode
+// keeps everything in, and whether portable mode is on: by SetPortable, or
+// because the directory already has a config directory, as installations
+// from before the user directories do.
+func PortableDir() (string, bool) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", false
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	dir := filepath.Dir(exe)
+	if portable {
+		return dir, true
+	}
+	for _, name := range configFileNames {
+		if _, err := os.Stat(filepath.Join(dir, "config", name)); err == nil {
+			return dir, true
+		}
+	}
+	return dir, false
+}
+
+// userDirs returns the directory for the config files and the one for
+// everything else (saves, states, screenshots, ...). On Linux and the other
+// Unix systems these follow the XDG base directories, such as
+// ~/.config/gones and ~/.local/share/gones; on Windows and macOS both are
+// gones' directory in the application data. Without a home directory, as
+// in the browser, they are relative to the working directory as before.
+func userDirs() (configDir, dataDir string) {
+	if dir, ok := PortableDir(); ok {
+		return filepath.Join(dir, "config"), dir
+	}
+	userConfig, err := os.UserConfigDir()
+	if err != nil || runtime.GOOS == "js" {
+		return "./config", "."
+	}
+	configDir = filepath.Join(userConfig, appDirName)
+
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios", "plan9":
+		return configDir, configDir
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if !filepath.IsAbs(dataHome) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return configDir, configDir
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return configDir, filepath.Join(dataHome, appDirName)
+}
+
+// defaultPaths returns the default paths settings
+func defaultPaths() PathsConfig {
+	configDir, dataDir := userDirs()
+	return PathsConfig{
+		ROMs:        filepath.Join(dataDir, "roms"),
+		SaveData:    filepath.Join(dataDir, "saves"),
+		SaveStates:  filepath.Join(dataDir, "states"),
+		Screenshots: filepath.Join(dataDir, "screenshots"),
+		Recordings:  filepath.Join(dataDir, "recordings"),
+		Config:      configDir,
+		Logs:        filepath.Join(dataDir, "logs"),
+		Crashes:     filepath.Join(dataDir, "crashes"),
+	}
+}
+
+// pathSettings returns the settings that hold paths
+func (c *Config) pathSettings() []*string {
+	paths := []*string{
+		&c.Paths.ROMs, &c.Paths.SaveData, &c.Paths.SaveStates, &c.Paths.Screenshots,
+		&c.Paths.Recordings, &c.Paths.Config, &c.Paths.Logs, &c.Paths.Crashes,
+		&c.Library.Database,
+	}
+	for i := range c.Library.Dirs {
+		paths = append(paths, &c.Library.Dirs[i])
+	}
+	return paths
+}
+
+// resolvePortablePaths makes the relative paths of a config file loaded in
+// portable mode relative to the portable directory instead of the working
+// directory, so gones finds its files wherever it is started from
+func (c *Config) resolvePortablePaths() {
+	dir, ok := PortableDir()
+	if !ok {
+		return
+	}
+	for _, path := range c.pathSettings() {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+}
+
+// portableCopy returns the config as written to the file: in portable mode
+// the paths inside the portable directory are relative to it, so the
+// directory can be moved
+func (c *Config) portableCopy() *Config {
+	dir, ok := PortableDir()
+	if !ok {
+		return c
+	}
+	saved := *c
+	saved.Library.Dirs = append([]string(nil), c.Library.Dirs...)
+	for _, path := range saved.pathSettings() {
+		rel, err := filepath.Rel(dir, *path)
+		switch {
+		case err != nil || !filepath.IsAbs(*path) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)):
+		case rel == ".":
+			*path = rel
+		default:
+			*path = "." + string(filepath.Separator) + rel
+		}
+	}
+	return &saved
+}
@@ -0,0 +1,81 @@
+// Package app provides runtime selection of the display aspect mode.
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"gones/internal/graphics"
+)
+
+// isKnownAspectMode reports whether mode is one of graphics.AspectModes
+func isKnownAspectMode(mode string) bool {
+	for _, known := range graphics.AspectModes {
+		if mode == known {
+			return true
+		}
+	}
+	return false
+}
+
+// SetAspectRatio selects how the picture fits the window: "original", "integer",
+// "8:7", "4:3", "16:9" or "fill"
+func (app *Application) SetAspectRatio(mode string) error {
+	if !isKnownAspectMode(mode) {
+		return fmt.Errorf("unknown aspect ratio mode: %s", mode)
+	}
+	app.config.Video.AspectRatio = mode
+	if window, ok := app.window.(graphics.AspectRatioWindow); ok {
+		window.SetAspectRatio(mode)
+	}
+	return nil
+}
+
+// CycleAspectRatio switches to the next mode in graphics.AspectModes
+func (app *Application) CycleAspectRatio() {
+	next := graphics.AspectModes[0]
+	for i, mode := range graphics.AspectModes {
+		if mode == app.config.Video.AspectRatio {
+			next = graphics.AspectModes[(i+1)%len(graphics.AspectModes)]
+			break
+		}
+	}
+	if err := app.SetAspectRatio(next); err != nil {
+		fmt.Printf("[APP_ERROR] %v\n", err)
+		return
+	}
+	fmt.Printf("[APP_DEBUG] Aspect ratio: %s\n", next)
+}
+
+// SetWindowScale resizes the window to scale (1-5) times the NES resolution
+// and saves the new size to the config
+func (app *Application) SetWindowScale(scale int) error {
+	if scale < graphics.MinWindowScale || scale > graphics.MaxWindowScale {
+		return fmt.Errorf("window scale must be %d-%d", graphics.MinWindowScale, graphics.MaxWindowScale)
+	}
+	window, ok := app.window.(graphics.ScalableWindow)
+	if !ok {
+		return errors.New("the window cannot be resized")
+	}
+	window.SetWindowScale(scale)
+
+	width, height := app.config.GetNESResolution()
+	app.config.Window.Scale = scale
+	app.config.UpdateWindow(width*scale, height*scale, false)
+	app.saveSettings()
+	return nil
+}
+
+// ToggleFullscreen switches the window between fullscreen and windowed, and
+// saves the choice to the config
+func (app *Application) ToggleFullscreen() {
+	window, ok := app.window.(graphics.FullscreenWindow)
+	if !ok {
+		fmt.Println("[APP_WARNING] The window cannot go fullscreen")
+		return
+	}
+	fullscreen := !window.IsFullscreen()
+	window.SetFullscreen(fullscreen)
+	app.config.Window.Fullscreen = fullscreen
+	app.saveSettings()
+}
@@ -26,7 +26,6 @@ type Emulator struct {
 
 	// Frame management with pooling
 	frameComplete   bool
-	frameBuffer     []uint32
 	audioSamples    []float32
 	frameBufferPool *FrameBufferPool
 
@@ -45,6 +44,14 @@ type Emulator struct {
 	// Optimization flags
 	adaptiveTimingEnabled bool
 	performanceMode       PerformanceMode
+
+	// Debugger breakpoints: checked with PC before each instruction (nil when
+	// there are none), or raised by Halt during one. A stopped frame carries
+	// on to frameEnd when emulation continues.
+	breakpoint func(pc uint16) bool
+	halt       bool
+	stopped    bool
+	frameEnd   uint64
 }
 
 // NewEmulator creates a new emulator instance with fixed timing for accuracy
@@ -54,7 +61,6 @@ func NewEmulator(bus *bus.Bus, config *Config) *Emulator {
 		config:                config,
 		targetFrameTime:       time.Duration(16666667) * time.Nanosecond, // Precise 60 FPS (16.666ms)
 		cyclesPerFrame:        29781,                                     // NTSC: exactly 29,781 CPU cycles per frame
-		frameBuffer:           make([]uint32, 256*240),
 		audioSamples:          make([]float32, 0, 1024),
 		isRunning:             false,
 		lastResetTime:         time.Now(),
@@ -106,11 +112,8 @@ func (e *Emulator) Reset() {
 	e.frameCount = 0
 	e.averageFrameTime = 0
 	e.lastResetTime = time.Now()
-
-	// Clear frame buffer
-	for i := range e.frameBuffer {
-		e.frameBuffer[i] = 0
-	}
+	e.stopped = false
+	e.frameEnd = 0
 
 	// Clear audio samples
 	e.audioSamples = e.audioSamples[:0]
@@ -153,24 +156,40 @@ func (e *Emulator) runFrameFixed() error {
 	emulationStart := time.Now()
 
 	// Run emulation for exactly one frame (29,781 CPU cycles for NTSC)
-	// This ensures consistent real-time emulation speed
+	// This ensures consistent real-time emulation speed. A frame stopped at a
+	// breakpoint is finished instead, unless the cycle count jumped since
+	// (a reset or a loaded state).
 	startCycles := e.bus.GetCycleCount()
-	targetCycles := startCycles + e.cyclesPerFrame
-
-	// Execute exactly the target number of cycles
-	for e.bus.GetCycleCount() < targetCycles {
-		e.bus.Step()
+	if e.frameEnd <= startCycles || e.frameEnd > startCycles+e.cyclesPerFrame {
+		e.frameEnd = startCycles + e.cyclesPerFrame
+	}
+
+	// Execute exactly the target number of cycles. The instruction a frame
+	// stopped before runs without checking its breakpoint again.
+	resuming := e.stopped
+	e.stopped = false
+	for e.bus.GetCycleCount() < e.frameEnd {
+		if e.breakpoint != nil && !resuming && e.breakpoint(e.bus.CPU.PC) {
+			e.stopped = true
+			return nil
+		}
+		if e.breakpoint != nil || resuming {
+			e.bus.Step()
+		} else {
+			e.bus.RunBatch(e.frameEnd)
+		}
+		resuming = false
+		if e.halt {
+			e.halt = false
+			e.stopped = true
+			return nil
+		}
 	}
+	e.frameEnd = 0
 
 	// Update frame count
 	e.frameCount++
 
-	// Get frame buffer from PPU
-	nesFrameBuffer := e.bus.GetFrameBuffer()
-	if len(nesFrameBuffer) == len(e.frameBuffer) {
-		copy(e.frameBuffer, nesFrameBuffer)
-	}
-
 	// Get audio samples from APU
 	nesSamples := e.bus.GetAudioSamples()
 	if len(nesSamples) > 0 {
@@ -222,9 +241,9 @@ func (e *Emulator) updatePerformanceMetricsSimple(frameStartTime time.Time) {
 	}
 }
 
-// GetFrameBuffer returns the current frame buffer
+// GetFrameBuffer returns the PPU frame buffer, which is not copied
 func (e *Emulator) GetFrameBuffer() []uint32 {
-	return e.frameBuffer
+	return e.bus.GetFrameBuffer()
 }
 
 // GetAudioSamples returns the current audio samples
@@ -298,9 +317,9 @@ func (e *Emulator) GetUptime() time.Duration {
 }
 
 // SetTargetFrameRate sets the target frame rate
-func (e *Emulator) SetTargetFrameRate(fps int) {
+func (e *Emulator) SetTargetFrameRate(fps float64) {
 	if fps > 0 {
-		e.targetFrameTime = time.Duration(1000000/fps) * time.Microsecond
+		e.targetFrameTime = time.Duration(float64(time.Second) / fps)
 	}
 }
 
@@ -322,18 +341,12 @@ func (e *Emulator) StepFrame() error {
 	targetCycles := startCycles + e.cyclesPerFrame
 
 	for e.bus.GetCycleCount() < targetCycles {
-		e.bus.Step()
+		e.bus.RunBatch(targetCycles)
 	}
 
 	// Update frame count
 	e.frameCount++
 
-	// Get updated frame buffer
-	nesFrameBuffer := e.bus.GetFrameBuffer()
-	if len(nesFrameBuffer) == len(e.frameBuffer) {
-		copy(e.frameBuffer, nesFrameBuffer)
-	}
-
 	// Get updated audio samples
 	nesSamples := e.bus.GetAudioSamples()
 	if len(nesSamples) > 0 {
@@ -359,11 +372,30 @@ func (e *Emulator) StepInstruction() error {
 	}
 
 	e.bus.Step()
+	e.halt = false
 	e.cycleCount = e.bus.GetCycleCount()
 
 	return nil
 }
 
+// SetBreakpointFunc sets the function checked with PC before each instruction;
+// returning true stops the frame before the instruction. Pass nil to remove it.
+func (e *Emulator) SetBreakpointFunc(breakpoint func(pc uint16) bool) {
+	e.breakpoint = breakpoint
+}
+
+// Halt stops the frame in progress after the current instruction, for
+// breakpoints raised during one (such as on a memory access)
+func (e *Emulator) Halt() {
+	e.halt = true
+	e.bus.EndBatch()
+}
+
+// Stopped returns whether the last frame stopped at a breakpoint before its end
+func (e *Emulator) Stopped() bool {
+	return e.stopped
+}
+
 // GetCPUState returns the current CPU state for debugging
 func (e *Emulator) GetCPUState() bus.CPUState {
 	if e.bus == nil {
@@ -541,7 +573,6 @@ func (e *Emulator) Cleanup() error {
 	e.Stop()
 
 	// Clear buffers
-	e.frameBuffer = nil
 	e.audioSamples = nil
 
 	// Cleanup optimization structures
@@ -0,0 +1,302 @@
+// Package app provides the event viewer, which plots the register writes of
+// a frame on a scanline/cycle timing grid.
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"gones/internal/events"
+	"gones/internal/graphics"
+)
+
+const (
+	// The grid is drawn at 3/4 scale: 341x262 cycles fill 256x197 pixels
+	eventGridTop = 12
+	eventGridNum = 3
+	eventGridDen = 4
+
+	// eventScanlineStep is how far Up/Down move the selection
+	eventScanlineStep = 8
+)
+
+// eventColors are the dot colors of each kind of event
+var eventColors = map[events.Kind]uint32{
+	events.KindPPUControl: graphics.OverlayColorYellow,
+	events.KindPPUScroll:  graphics.OverlayColorRed,
+	events.KindPPUData:    0x60A0FF,
+	events.KindAPU:        graphics.OverlayColorGreen,
+	events.KindIO:         0xFF9040,
+	events.KindMapper:     0xFF60FF,
+}
+
+// eventLegend names the kinds in the legend, short enough to fit one line
+var eventLegend = map[events.Kind]string{
+	events.KindPPUControl: "CTRL",
+	events.KindPPUScroll:  "SCROLL",
+	events.KindPPUData:    "DATA",
+	events.KindAPU:        "APU",
+	events.KindIO:         "I/O",
+	events.KindMapper:     "MAPPER",
+}
+
+// EventViewer shows the register writes of the last frame on a grid of
+// scanlines by PPU cycles, so mid-frame writes such as scroll splits show up
+// where they hit the picture
+type EventViewer struct {
+	visible   bool
+	wasPaused bool
+
+	log      *events.Log
+	filter   int // Index in events.Kinds of the kind shown, or -1 for all
+	selected int // Index of the selected event in the shown events
+}
+
+// NewEventViewer creates a hidden event viewer
+func NewEventViewer() *EventViewer {
+	return &EventViewer{filter: -1}
+}
+
+// Open shows the events recorded by log, remembering the pause state to
+// restore on Close
+func (v *EventViewer) Open(log *events.Log, wasPaused bool) {
+	v.visible = true
+	v.wasPaused = wasPaused
+	v.log = log
+	v.selected = 0
+}
+
+// Close hides the viewer and returns the pause state from before it was opened
+func (v *EventViewer) Close() bool {
+	v.visible = false
+	v.log = nil
+	return v.wasPaused
+}
+
+// IsVisible returns whether the viewer is on screen
+func (v *EventViewer) IsVisible() bool {
+	return v.visible
+}
+
+// NextFilter cycles the kind of event shown: all, then each kind in turn
+func (v *EventViewer) NextFilter() {
+	v.filter++
+	if v.filter >= len(events.Kinds) {
+		v.filter = -1
+	}
+	v.selected = 0
+}
+
+// shown returns the events of the frame that pass the filter, in grid order
+func (v *EventViewer) shown() []events.Event {
+	if v.log == nil {
+		return nil
+	}
+	all := v.log.Events()
+	if v.filter < 0 {
+		return all
+	}
+	kind := events.Kinds[v.filter]
+	var shown []events.Event
+	for _, event := range all {
+		if event.Kind == kind {
+			shown = append(shown, event)
+		}
+	}
+	return shown
+}
+
+// Select moves the selection by delta events
+func (v *EventViewer) Select(delta int) {
+	if n := len(v.shown()); n > 0 {
+		v.selected = ((v.selected+delta)%n + n) % n
+	}
+}
+
+// SelectScanline selects the first event at least delta scanlines below (or
+// above, for a negative delta) the selected one
+func (v *EventViewer) SelectScanline(delta int) {
+	shown := v.shown()
+	if len(shown) == 0 {
+		return
+	}
+	v.selected = min(v.selected, len(shown)-1)
+	target := shown[v.selected].Row() + delta
+	if delta > 0 {
+		for i := v.selected + 1; i < len(shown); i++ {
+			if shown[i].Row() >= target {
+				v.selected = i
+				return
+			}
+		}
+		v.selected = len(shown) - 1
+		return
+	}
+	for i := v.selected - 1; i >= 0; i-- {
+		if shown[i].Row() <= target {
+			// Land on the first event of that scanline
+			for i > 0 && shown[i-1].Row() == shown[i].Row() {
+				i--
+			}
+			v.selected = i
+			return
+		}
+	}
+	v.selected = 0
+}
+
+// gridX returns the x of a PPU cycle on the grid
+func gridX(cycle int) int {
+	return cycle * eventGridNum / eventGridDen
+}
+
+// gridY returns the y of a grid row (scanline + 1) on the grid
+func gridY(row int) int {
+	return eventGridTop + row*eventGridNum/eventGridDen
+}
+
+// Render draws the grid over the frame: the visible picture area, the
+// current PPU position while paused, a dot per event and the selected
+// event's details. status describes the emulation state.
+func (v *EventViewer) Render(frameBuffer *[256 * 240]uint32, status string, scanline, cycle int, paused bool) {
+	if !v.visible || v.log == nil {
+		return
+	}
+	shown := v.shown()
+	v.selected = max(0, min(v.selected, len(shown)-1))
+
+	graphics.DarkenRect(frameBuffer, 0, 0, graphics.OverlayWidth, graphics.OverlayHeight, 3)
+	title := fmt.Sprintf("EVENTS  FRAME %d  %d WRITES", v.log.Frame(), len(shown))
+	if dropped := v.log.Dropped(); dropped > 0 {
+		title += fmt.Sprintf(" +%d", dropped)
+	}
+	graphics.DrawTextShadowed(frameBuffer, 2, 2, title, graphics.OverlayColorYellow)
+	graphics.DrawTextShadowed(frameBuffer, 254-graphics.TextWidth(status), 2, status, graphics.OverlayColorGray)
+
+	// The pre-render line is row 0, so the picture starts on row 1 and
+	// covers cycles 1-256; the rest is blanking
+	bottom := gridY(events.Scanlines)
+	graphics.FillRect(frameBuffer, 0, eventGridTop, graphics.OverlayWidth, bottom-eventGridTop, 0x101018)
+	graphics.FillRect(frameBuffer, gridX(1), gridY(1), gridX(257)-gridX(1), gridY(241)-gridY(1), graphics.OverlayColorPanel)
+	graphics.FillRect(frameBuffer, 0, gridY(242), graphics.OverlayWidth, 1, 0x404060) // Vblank starts
+
+	if paused {
+		graphics.FillRect(frameBuffer, 0, gridY(scanline+1), graphics.OverlayWidth, 1, 0x606060)
+		graphics.FillRect(frameBuffer, gridX(cycle), gridY(scanline+1)-2, 1, 5, graphics.OverlayColorWhite)
+	}
+
+	for _, event := range shown {
+		graphics.FillRect(frameBuffer, gridX(event.Cycle), gridY(event.Row()), 2, 2, eventColors[event.Kind])
+	}
+
+	y := bottom + 2
+	if len(shown) == 0 {
+		graphics.DrawTextShadowed(frameBuffer, 2, y, "NO WRITES THIS FRAME", graphics.OverlayColorGray)
+	} else {
+		event := shown[v.selected]
+		x, ey := gridX(event.Cycle), gridY(event.Row())
+		graphics.DrawRect(frameBuffer, x-2, ey-2, 6, 6, graphics.OverlayColorWhite)
+
+		info := fmt.Sprintf("SL %d CY %d  $%04X = $%02X", event.Scanline, event.Cycle, event.Address, event.Value)
+		if name := events.RegisterName(event.Address); name != "" {
+			info += "  " + name
+		} else {
+			info += "  " + strings.ToUpper(event.Kind.String())
+		}
+		graphics.DrawTextShadowed(frameBuffer, 2, y, info, eventColors[event.Kind])
+	}
+
+	x := 2
+	for i, kind := range events.Kinds {
+		color := eventColors[kind]
+		if v.filter >= 0 && v.filter != i {
+			color = 0x505050
+		}
+		x = graphics.DrawTextShadowed(frameBuffer, x, y+10, eventLegend[kind], color) + graphics.FontAdvance
+	}
+	graphics.DrawTextShadowed(frameBuffer, 2, y+20, "A/B EVENT  SELECT FILTER  F5 F10 F11", graphics.OverlayColorGray)
+}
+
+// ShowEventViewer starts recording register writes and shows them. Unlike
+// the memory viewer it leaves the game running.
+func (app *Application) ShowEventViewer() {
+	if app.cartridge == nil || app.bus == nil || app.bus.Memory == nil || app.eventViewer.IsVisible() {
+		return
+	}
+	log := events.NewLog(app.bus.PPU)
+	app.bus.Memory.SetRegisterWriteHook(log.Record)
+	app.eventViewer.Open(log, app.paused)
+}
+
+// HideEventViewer stops recording and restores the pause state from before
+// the viewer was opened
+func (app *Application) HideEventViewer() {
+	if !app.eventViewer.IsVisible() {
+		return
+	}
+	if app.bus != nil && app.bus.Memory != nil {
+		app.bus.Memory.SetRegisterWriteHook(nil)
+	}
+	app.paused = app.eventViewer.Close()
+}
+
+// IsEventViewerVisible returns whether the event viewer is on screen
+func (app *Application) IsEventViewerVisible() bool {
+	return app.eventViewer != nil && app.eventViewer.IsVisible()
+}
+
+// handleEventViewerInput moves the selection and runs the debugger commands:
+// F5 runs or pauses, F10 steps a frame and F11 an instruction
+func (app *Application) handleEventViewerInput(event graphics.InputEvent) {
+	viewer := app.eventViewer
+	var err error
+
+	switch event.Type {
+	case graphics.InputEventTypeKey:
+		switch event.Key {
+		case graphics.KeyEscape:
+			app.HideEventViewer()
+		case graphics.KeyF8:
+			if event.Modifiers&graphics.ModifierCtrl != 0 {
+				app.HideEventViewer()
+			}
+		case graphics.KeyF5:
+			app.paused = !app.paused
+		case graphics.KeyF10:
+			err = app.StepFrame()
+		case graphics.KeyF11:
+			err = app.StepInstruction()
+		}
+
+	case graphics.InputEventTypeButton:
+		switch event.Button {
+		case graphics.ButtonUp:
+			viewer.SelectScanline(-eventScanlineStep)
+		case graphics.ButtonDown:
+			viewer.SelectScanline(eventScanlineStep)
+		case graphics.ButtonLeft, graphics.ButtonB:
+			viewer.Select(-1)
+		case graphics.ButtonRight, graphics.ButtonA:
+			viewer.Select(1)
+		case graphics.ButtonSelect:
+			viewer.NextFilter()
+		}
+	}
+
+	if err != nil {
+		fmt.Printf("[APP_ERROR] Debugger step failed: %v\n", err)
+	}
+}
+
+// renderEventViewer draws the event viewer over the frame
+func (app *Application) renderEventViewer(frameBuffer *[256 * 240]uint32) {
+	if !app.IsEventViewerVisible() {
+		return
+	}
+	status := "RUNNING"
+	if app.paused {
+		status = "PAUSED"
+	}
+	ppu := app.bus.PPU
+	app.eventViewer.Render(frameBuffer, status, ppu.GetScanline(), ppu.GetCycle(), app.paused)
+}
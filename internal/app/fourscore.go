@@ -0,0 +1,18 @@
+// Package app provides Four Score adapter control.
+package app
+
+// SetFourScoreEnabled connects or removes the Four Score adapter
+func (app *Application) SetFourScoreEnabled(enabled bool) {
+	app.config.Input.FourScore = enabled
+	if app.gameProfile != nil {
+		app.gameProfile.FourScore = nil // An explicit choice wins over the game profile
+	}
+	if app.bus != nil {
+		app.bus.SetFourScore(enabled)
+	}
+}
+
+// IsFourScoreEnabled returns whether the Four Score adapter is connected
+func (app *Application) IsFourScoreEnabled() bool {
+	return app.inputConfig().FourScore
+}
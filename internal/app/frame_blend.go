@@ -0,0 +1,17 @@
+// Package app provides interframe blending of the displayed frames.
+package app
+
+// blendFrame mixes the color adjusted frame with the previous one. Frames are
+// blended once per emulated frame: renders that repeat a frame (while paused
+// or in slow motion) show the last blend instead of the frame mixed with
+// itself.
+func (app *Application) blendFrame(frameBuffer []uint32) []uint32 {
+	frame := app.emulator.GetFrameCount()
+	if frame == app.lastBlendedFrame {
+		if blended := app.videoProcessor.BlendedFrame(); blended != nil {
+			return blended
+		}
+	}
+	app.lastBlendedFrame = frame
+	return app.videoProcessor.BlendFrame(frameBuffer)
+}
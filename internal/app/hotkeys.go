@@ -0,0 +1,436 @@
+// Package app provides the hotkeys: their settings, what they do, and the
+// menu page that rebinds them.
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"gones/internal/graphics"
+)
+
+// HotkeyConfig binds the emulator's hotkeys. Each is a key with optional
+// modifiers, such as "F12" or "Ctrl+F6" (key names as in the input
+// bindings; modifiers Ctrl, Alt, Shift and Super). A setting can hold
+// several, separated by commas, or "" to leave the hotkey unbound. Keys
+// bound to controller buttons go to the game instead.
+type HotkeyConfig struct {
+	Menu          string `json:"menu"`
+	Pause         string `json:"pause"`
+	FastForward   string `json:"fast_forward"` // While held
+	SlowDown      string `json:"slow_down"`
+	SpeedUp       string `json:"speed_up"`
+	Fullscreen    string `json:"fullscreen"`
+	Upscaler      string `json:"upscaler"`     // Cycle through the upscalers
+	AspectRatio   string `json:"aspect_ratio"` // Cycle through the aspect modes
+	Screenshot    string `json:"screenshot"`
+	Record        string `json:"record"`      // Start or stop a recording
+	Movie         string `json:"movie"`       // Record an input movie, branch the one playing, or save it
+	SaveReplay    string `json:"save_replay"` // Save the last video.replay_seconds
+	MemoryViewer  string `json:"memory_viewer"`
+	RAMSearch     string `json:"ram_search"`
+	EventViewer   string `json:"event_viewer"`
+	Trace         string `json:"trace"`          // Start or stop the trace logger
+	Layers        string `json:"layers"`         // Cycle through the layers drawn
+	ScrollOverlay string `json:"scroll_overlay"` // Graph the scroll of each scanline
+	PerfOverlay   string `json:"perf_overlay"`   // Show the frame rate, frame times and audio health
+	Microphone    string `json:"microphone"`     // While held, the Famicom microphone hears sound
+
+	// Show an oscilloscope of each sound channel
+	AudioVisualizer string `json:"audio_visualizer"`
+
+	// Press the console's reset button, which keeps RAM, or turn the console
+	// off and on, which fills RAM anew
+	Reset      string `json:"reset"`
+	PowerCycle string `json:"power_cycle"`
+
+	// One hotkey per save state slot, window scale (1x, 2x, ...): the first
+	// entry is for slot 1 and 1x. The state ones open the slot picker.
+	SaveState   []string `json:"save_state"`
+	LoadState   []string `json:"load_state"`
+	WindowScale []string `json:"window_scale"`
+}
+
+// defaultHotkeys returns the built-in hotkeys
+func defaultHotkeys() HotkeyConfig {
+	h := HotkeyConfig{
+		Menu:          "Escape",
+		Pause:         "P",
+		FastForward:   "Tab",
+		SlowDown:      "Minus",
+		SpeedUp:       "Equal",
+		Fullscreen:    "F11",
+		Upscaler:      "Shift+F11",
+		AspectRatio:   "Ctrl+F11",
+		Screenshot:    "F12",
+		Record:        "Shift+F12",
+		Movie:         "Alt+F12",
+		SaveReplay:    "Ctrl+F12",
+		MemoryViewer:  "Ctrl+F6",
+		RAMSearch:     "Ctrl+F7",
+		EventViewer:   "Ctrl+F8",
+		Trace:         "Ctrl+F9",
+		Layers:        "Ctrl+F10",
+		ScrollOverlay: "Alt+F8",
+		PerfOverlay:   "Alt+F9",
+		Microphone:    "M",
+
+		AudioVisualizer: "Alt+F10",
+
+		Reset:      "Ctrl+R",
+		PowerCycle: "Ctrl+Shift+R",
+	}
+	for slot := 1; slot <= 10; slot++ {
+		h.SaveState = append(h.SaveState, fmt.Sprintf("F%d", slot))
+		h.LoadState = append(h.LoadState, fmt.Sprintf("Shift+F%d", slot))
+	}
+	for scale := graphics.MinWindowScale; scale <= graphics.MaxWindowScale; scale++ {
+		h.WindowScale = append(h.WindowScale, fmt.Sprintf("Ctrl+F%d", scale))
+	}
+	return h
+}
+
+// hotkeyAction is a hotkey setting and what its hotkeys do
+type hotkeyAction struct {
+	key     string // In the hotkeys section of the config
+	label   string // In the menu
+	setting func(h *HotkeyConfig) *string
+	run     func(app *Application)
+}
+
+// hotkeyActions lists the single hotkey settings in menu order. It is a
+// function, as the actions refer back to it through the menu.
+func hotkeyActions() []hotkeyAction {
+	return []hotkeyAction{
+		{"menu", "MENU", func(h *HotkeyConfig) *string { return &h.Menu }, (*Application).ShowMenu},
+		{"pause", "PAUSE", func(h *HotkeyConfig) *string { return &h.Pause }, (*Application).togglePauseFromHotkey},
+		{"reset", "RESET", func(h *HotkeyConfig) *string { return &h.Reset }, (*Application).Reset},
+		{"power_cycle", "POWER CYCLE", func(h *HotkeyConfig) *string { return &h.PowerCycle },
+			func(app *Application) {
+				if err := app.PowerCycle(); err != nil {
+					fmt.Printf("[APP_ERROR] %v\n", err)
+				}
+			}},
+		{"fast_forward", "FAST FORWARD (HOLD)", func(h *HotkeyConfig) *string { return &h.FastForward },
+			func(app *Application) { app.setFastForward(true) }},
+		{"slow_down", "SLOWER", func(h *HotkeyConfig) *string { return &h.SlowDown },
+			func(app *Application) { app.changeSpeed(-1) }},
+		{"speed_up", "FASTER", func(h *HotkeyConfig) *string { return &h.SpeedUp },
+			func(app *Application) { app.changeSpeed(1) }},
+		{"fullscreen", "FULLSCREEN", func(h *HotkeyConfig) *string { return &h.Fullscreen }, (*Application).ToggleFullscreen},
+		{"upscaler", "UPSCALER", func(h *HotkeyConfig) *string { return &h.Upscaler },
+			func(app *Application) {
+				app.CycleUpscaler()
+				app.saveSettings()
+			}},
+		{"aspect_ratio", "ASPECT RATIO", func(h *HotkeyConfig) *string { return &h.AspectRatio },
+			func(app *Application) {
+				app.CycleAspectRatio()
+				app.saveSettings()
+			}},
+		{"screenshot", "SCREENSHOT", func(h *HotkeyConfig) *string { return &h.Screenshot }, (*Application).takeScreenshot},
+		{"record", "RECORD VIDEO", func(h *HotkeyConfig) *string { return &h.Record }, (*Application).ToggleRecording},
+		{"movie", "RECORD/BRANCH MOVIE", func(h *HotkeyConfig) *string { return &h.Movie }, (*Application).ToggleMovieRecording},
+		{"save_replay", "SAVE REPLAY", func(h *HotkeyConfig) *string { return &h.SaveReplay }, (*Application).saveReplay},
+		{"memory_viewer", "MEMORY VIEWER", func(h *HotkeyConfig) *string { return &h.MemoryViewer }, (*Application).ShowMemoryViewer},
+		{"ram_search", "RAM SEARCH", func(h *HotkeyConfig) *string { return &h.RAMSearch }, (*Application).ShowRAMSearch},
+		{"event_viewer", "EVENT VIEWER", func(h *HotkeyConfig) *string { return &h.EventViewer }, (*Application).ShowEventViewer},
+		{"trace", "TRACE LOGGER", func(h *HotkeyConfig) *string { return &h.Trace }, (*Application).ToggleTrace},
+		{"layers", "LAYERS", func(h *HotkeyConfig) *string { return &h.Layers }, (*Application).CycleLayers},
+		{"scroll_overlay", "SCROLL OVERLAY", func(h *HotkeyConfig) *string { return &h.ScrollOverlay }, (*Application).ToggleScrollOverlay},
+		{"perf_overlay", "PERFORMANCE OVERLAY", func(h *HotkeyConfig) *string { return &h.PerfOverlay }, (*Application).TogglePerfOverlay},
+		{"audio_visualizer", "AUDIO VISUALIZER", func(h *HotkeyConfig) *string { return &h.AudioVisualizer }, (*Application).ToggleAudioVisualizer},
+		{"microphone", "MICROPHONE (HOLD)", func(h *HotkeyConfig) *string { return &h.Microphone },
+			func(app *Application) { app.setMicrophoneHeld(true) }},
+	}
+}
+
+// hotkeyList is a setting with a hotkey for each slot or scale
+type hotkeyList struct {
+	key     string
+	label   string // Of the menu page
+	entry   string // Menu label of an entry, with its number
+	setting func(h *HotkeyConfig) *[]string
+}
+
+// hotkeyLists lists the per-slot hotkey settings in menu order
+func hotkeyLists() []hotkeyList {
+	return []hotkeyList{
+		{"save_state", "SAVE STATE SLOTS", "SAVE SLOT %d", func(h *HotkeyConfig) *[]string { return &h.SaveState }},
+		{"load_state", "LOAD STATE SLOTS", "LOAD SLOT %d", func(h *HotkeyConfig) *[]string { return &h.LoadState }},
+		{"window_scale", "WINDOW SCALES", "WINDOW %dX", func(h *HotkeyConfig) *[]string { return &h.WindowScale }},
+	}
+}
+
+// parseHotkeys returns the hotkeys of a setting, skipping those that do not
+// parse (validate reports them)
+func parseHotkeys(setting string) []graphics.Hotkey {
+	var hotkeys []graphics.Hotkey
+	for _, name := range graphics.ParseBindingList(setting) {
+		if hotkey, err := graphics.ParseHotkey(name); err == nil {
+			hotkeys = append(hotkeys, hotkey)
+		}
+	}
+	return hotkeys
+}
+
+// hotkeyMatches reports whether a key press is one of a setting's hotkeys
+func hotkeyMatches(setting string, event graphics.InputEvent) bool {
+	for _, hotkey := range parseHotkeys(setting) {
+		if hotkey.Matches(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate replaces the hotkeys that do not parse with the defaults and
+// reports hotkeys bound to two things
+func (h *HotkeyConfig) validate() {
+	defaults := defaultHotkeys()
+	const allowed = "a key such as \"F1\" or \"Ctrl+F6\""
+	owners := make(map[graphics.Hotkey]string)
+	claim := func(key, setting string) {
+		for _, hotkey := range parseHotkeys(setting) {
+			if owner, taken := owners[hotkey]; taken && owner != key {
+				fmt.Printf("[APP_WARNING] Hotkey %s is bound to both hotkeys.%s and hotkeys.%s\n", hotkey, owner, key)
+			}
+			owners[hotkey] = key
+		}
+	}
+
+	for _, action := range hotkeyActions() {
+		setting := action.setting(h)
+		for _, name := range graphics.ParseBindingList(*setting) {
+			if _, err := graphics.ParseHotkey(name); err != nil {
+				resetSetting("hotkeys."+action.key, setting, allowed, *action.setting(&defaults))
+				break
+			}
+		}
+		claim(action.key, *setting)
+	}
+	for _, list := range hotkeyLists() {
+		entries := *list.setting(h)
+		for i, name := range entries {
+			key := fmt.Sprintf("%s[%d]", list.key, i)
+			if _, err := graphics.ParseHotkey(name); name != "" && err != nil {
+				fallback := ""
+				if i < len(*list.setting(&defaults)) {
+					fallback = (*list.setting(&defaults))[i]
+				}
+				resetSetting("hotkeys."+key, &entries[i], allowed, fallback)
+			}
+			claim(key, entries[i])
+		}
+	}
+}
+
+// unbind removes a hotkey from every setting, so it does one thing only
+func (h *HotkeyConfig) unbind(hotkey graphics.Hotkey) {
+	for _, action := range hotkeyActions() {
+		setting := action.setting(h)
+		var kept []string
+		for _, name := range graphics.ParseBindingList(*setting) {
+			if parsed, err := graphics.ParseHotkey(name); err != nil || parsed != hotkey {
+				kept = append(kept, name)
+			}
+		}
+		*setting = graphics.FormatBindingList(kept)
+	}
+	for _, list := range hotkeyLists() {
+		entries := *list.setting(h)
+		for i, name := range entries {
+			if parsed, err := graphics.ParseHotkey(name); err == nil && parsed == hotkey {
+				entries[i] = ""
+			}
+		}
+	}
+}
+
+// hotkeyIndex returns the index of the entry of a per-slot setting that a
+// key press matches, or -1
+func hotkeyIndex(entries []string, event graphics.InputEvent) int {
+	for i, name := range entries {
+		if hotkey, err := graphics.ParseHotkey(name); err == nil && hotkey.Matches(event) {
+			return i
+		}
+	}
+	return -1
+}
+
+// slotForKey returns the save state slot whose save or load hotkey uses a
+// key, whatever the modifiers, or -1. The slot picker takes these keys to
+// pick a slot.
+func (h *HotkeyConfig) slotForKey(key graphics.Key) int {
+	for _, entries := range [][]string{h.SaveState, h.LoadState} {
+		for i, name := range entries {
+			if hotkey, err := graphics.ParseHotkey(name); err == nil && hotkey.Key == key {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// handleKeyInput runs the hotkey of a key press. Fast-forward and the
+// microphone last until their key is released.
+func (app *Application) handleKeyInput(event graphics.InputEvent) bool {
+	hotkeys := &app.config.Hotkeys
+	if !event.Pressed {
+		// Whatever the modifiers, which may be let go of first
+		for _, hotkey := range parseHotkeys(hotkeys.FastForward) {
+			if app.fastForward && hotkey.Key == event.Key {
+				app.setFastForward(false)
+				return true
+			}
+		}
+		for _, hotkey := range parseHotkeys(hotkeys.Microphone) {
+			if app.microphoneHeld && hotkey.Key == event.Key {
+				app.setMicrophoneHeld(false)
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, action := range hotkeyActions() {
+		if hotkeyMatches(*action.setting(hotkeys), event) {
+			action.run(app)
+			return true
+		}
+	}
+	if slot := hotkeyIndex(hotkeys.SaveState, event); slot >= 0 {
+		app.OpenSlotPicker(SlotPickerSave, slot)
+		return true
+	}
+	if slot := hotkeyIndex(hotkeys.LoadState, event); slot >= 0 {
+		app.OpenSlotPicker(SlotPickerLoad, slot)
+		return true
+	}
+	if i := hotkeyIndex(hotkeys.WindowScale, event); i >= 0 {
+		scale := graphics.MinWindowScale + i
+		if err := app.SetWindowScale(scale); err != nil {
+			fmt.Printf("[APP_ERROR] %v\n", err)
+		} else {
+			fmt.Printf("🖥️  Window scale: %dx\n", scale)
+		}
+		return true
+	}
+	return false
+}
+
+// togglePauseFromHotkey pauses or resumes emulation
+func (app *Application) togglePauseFromHotkey() {
+	if app.cartridge == nil {
+		return
+	}
+	app.TogglePause()
+	if app.paused {
+		fmt.Println("⏸️  Paused")
+	} else {
+		fmt.Println("▶️  Resumed")
+	}
+}
+
+// hotkeyCapture is a hotkey being rebound from the menu
+type hotkeyCapture struct {
+	label string
+	set   func(setting string)
+}
+
+// captureHotkey rebinds a hotkey to the next key pressed in the menu.
+// Escape cancels and Backspace leaves the hotkey unbound.
+func (app *Application) captureHotkey(label string, set func(setting string)) {
+	app.hotkeyCapture = &hotkeyCapture{label: label, set: set}
+	app.menu.SetMessage("PRESS KEY FOR " + label + " - ESC CANCELS")
+}
+
+// handleHotkeyCapture takes the key press a hotkey capture waits for
+func (app *Application) handleHotkeyCapture(event graphics.InputEvent) {
+	if event.Type != graphics.InputEventTypeKey {
+		return // Keys bound to controller buttons cannot be hotkeys
+	}
+	capture := app.hotkeyCapture
+	app.hotkeyCapture = nil
+	app.menu.SetMessage("")
+
+	switch {
+	case event.Key == graphics.KeyEscape && event.Modifiers == graphics.ModifierNone:
+		return
+	case event.Key == graphics.KeyBackspace && event.Modifiers == graphics.ModifierNone:
+		capture.set("")
+	default:
+		hotkey := graphics.Hotkey{Key: event.Key, Modifiers: event.Modifiers}
+		app.config.Hotkeys.unbind(hotkey)
+		capture.set(hotkey.String())
+		fmt.Printf("[APP_DEBUG] %s bound to %s\n", strings.ToLower(capture.label), hotkey)
+	}
+	app.saveSettings()
+}
+
+// hotkeysMenuPage builds the page that rebinds the hotkeys
+func (app *Application) hotkeysMenuPage() *menuPage {
+	hotkeys := &app.config.Hotkeys
+	page := &menuPage{title: "HOTKEYS"}
+	for _, action := range hotkeyActions() {
+		setting, label := action.setting(hotkeys), action.label
+		page.items = append(page.items, menuItem{
+			label: label,
+			value: func() string { return hotkeyMenuValue(*setting) },
+			action: func() {
+				app.captureHotkey(label, func(s string) { *setting = s })
+			},
+		})
+	}
+	for _, list := range hotkeyLists() {
+		page.items = append(page.items, menuItem{label: list.label, action: func() {
+			app.menu.Push(app.hotkeyListMenuPage(list))
+		}})
+	}
+	page.items = append(page.items, menuItem{label: "RESET TO DEFAULTS", action: func() {
+		*hotkeys = defaultHotkeys()
+		app.saveSettings()
+		app.menu.SetMessage("HOTKEYS RESET")
+	}})
+	return page
+}
+
+// hotkeyListMenuPage builds the page that rebinds the per-slot hotkeys of
+// a setting
+func (app *Application) hotkeyListMenuPage(list hotkeyList) *menuPage {
+	page := &menuPage{title: list.label}
+	entries := list.setting(&app.config.Hotkeys)
+	count := app.config.Emulation.SaveStateSlots
+	if list.key == "window_scale" {
+		count = graphics.MaxWindowScale - graphics.MinWindowScale + 1
+	}
+	for i := 0; i < count; i++ {
+		label := fmt.Sprintf(list.entry, i+1)
+		page.items = append(page.items, menuItem{
+			label: label,
+			value: func() string {
+				if i < len(*entries) {
+					return hotkeyMenuValue((*entries)[i])
+				}
+				return hotkeyMenuValue("")
+			},
+			action: func() {
+				app.captureHotkey(label, func(s string) {
+					for len(*entries) <= i {
+						*entries = append(*entries, "")
+					}
+					(*entries)[i] = s
+				})
+			},
+		})
+	}
+	return page
+}
+
+// hotkeyMenuValue shows a hotkey setting in the menu
+func hotkeyMenuValue(setting string) string {
+	if setting == "" {
+		return "NONE"
+	}
+	return strings.ReplaceAll(setting, ",", " ")
+}
@@ -0,0 +1,207 @@
+// Package app provides extra instances: a second game or a nametable viewer
+// in a window of its own, next to the main one. Backends with a single
+// window (Ebitengine, the terminal) cannot open them; SDL2 can.
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"gones/internal/bus"
+	"gones/internal/cartridge"
+	"gones/internal/graphics"
+	"gones/internal/ppu"
+)
+
+// Instance is a window the application drives next to its main one: a game
+// running on a console of its own, or a view of the main game
+type Instance struct {
+	title  string
+	window graphics.Window
+	frame  [256 * 240]uint32
+
+	// The instance's own console, nil for a view
+	bus     *bus.Bus
+	buttons [4][8]bool
+
+	// Draws a view's frame, nil for a game
+	view func(frame *[256 * 240]uint32) error
+}
+
+// Title returns the instance's window title
+func (inst *Instance) Title() string {
+	return inst.title
+}
+
+// Instances returns the instances open next to the main window
+func (app *Application) Instances() []*Instance {
+	return app.instances
+}
+
+// openInstanceWindow opens a window for a new instance, if the graphics
+// backend can have one more open
+func (app *Application) openInstanceWindow(title string) (graphics.Window, error) {
+	if app.graphicsBackend == nil || app.window == nil {
+		return nil, errors.New("no window open")
+	}
+	if limit := graphics.WindowLimit(app.graphicsBackend); limit > 0 && 1+len(app.instances) >= limit {
+		return nil, fmt.Errorf("the %s backend cannot open another window (video.backend sdl2 can)", app.graphicsBackend.GetName())
+	}
+	window, err := app.graphicsBackend.CreateWindow(title, app.config.Window.Width, app.config.Window.Height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create window: %v", err)
+	}
+	return window, nil
+}
+
+// OpenGameInstance runs a second ROM in a window of its own. It has its own
+// console, played with the same controls as the main window when its window
+// has the focus; it is silent and has no save data.
+func (app *Application) OpenGameInstance(romPath string) (*Instance, error) {
+	data, _, err := app.readROM(romPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ROM: %v", err)
+	}
+	data, fixes := app.applyCompat(data)
+	cart, err := cartridge.LoadFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ROM: %v", err)
+	}
+
+	title := fmt.Sprintf("gones - %s", app.romTitle(romPath))
+	window, err := app.openInstanceWindow(title)
+	if err != nil {
+		return nil, err
+	}
+	if rebindable, ok := window.(graphics.RebindableWindow); ok {
+		rebindable.SetInputBindings(app.config.Input.GetInputBindings(romPath))
+	}
+
+	b := bus.New()
+	b.LoadCartridge(cart)
+	r, _ := app.detectRegion(romPath, cart, fixes)
+	b.SetRegion(r)
+	b.SetDMCConflicts(app.config.Emulation.DMCConflicts)
+	b.SetPPUAlignment(ppuAlignment(fixes))
+	b.PPU.SetUnlimitedSprites(app.config.Video.UnlimitedSprites)
+	b.Reset()
+
+	inst := &Instance{title: title, window: window, bus: b}
+	app.instances = append(app.instances, inst)
+	fmt.Printf("🪟 Opened %s\n", filepath.Base(romPath))
+	return inst, nil
+}
+
+// OpenNametableViewer shows the main game's four nametables, as the PPU's
+// mirroring maps them, in a window of its own
+func (app *Application) OpenNametableViewer() (*Instance, error) {
+	if app.cartridge == nil {
+		return nil, errors.New("no ROM loaded")
+	}
+	title := "gones - Nametables"
+	window, err := app.openInstanceWindow(title)
+	if err != nil {
+		return nil, err
+	}
+
+	pixels := make([]uint32, ppu.NametablesWidth*ppu.NametablesHeight)
+	inst := &Instance{title: title, window: window}
+	inst.view = func(frame *[256 * 240]uint32) error {
+		app.bus.PPU.RenderNametables(pixels)
+		if renderer, ok := inst.window.(graphics.ScaledFrameRenderer); ok {
+			return renderer.RenderScaledFrame(pixels, ppu.NametablesWidth, ppu.NametablesHeight)
+		}
+		// Halve the view to fit a NES frame
+		for y := 0; y < 240; y++ {
+			for x := 0; x < 256; x++ {
+				frame[y*256+x] = pixels[y*2*ppu.NametablesWidth+x*2]
+			}
+		}
+		return inst.window.RenderFrame(frame)
+	}
+	app.instances = append(app.instances, inst)
+	return inst, nil
+}
+
+// updateInstances runs a frame of each game instance along with the main
+// game's
+func (app *Application) updateInstances() {
+	for _, inst := range app.instances {
+		if inst.bus == nil {
+			continue
+		}
+		inst.bus.Run(1)
+		inst.bus.GetAudioSamples() // Drained; only the main game plays
+	}
+}
+
+// pollInstances handles the input sent to the instances' windows, and closes
+// those whose window was closed
+func (app *Application) pollInstances() {
+	for i := 0; i < len(app.instances); i++ {
+		inst := app.instances[i]
+		closed := inst.window.ShouldClose()
+		for _, event := range inst.window.PollEvents(nil) {
+			switch event.Type {
+			case graphics.InputEventTypeQuit:
+				closed = true
+			case graphics.InputEventTypeButton:
+				inst.setButton(event.Button, event.Pressed)
+			}
+		}
+		if closed {
+			app.CloseInstance(inst)
+			i--
+		}
+	}
+}
+
+// setButton presses or releases a controller button of a game instance
+func (inst *Instance) setButton(button graphics.Button, pressed bool) {
+	player, index := graphics.ButtonPlayer(button), graphics.ControllerButtonIndex(button)
+	if inst.bus == nil || player < 0 || index < 0 {
+		return
+	}
+	inst.buttons[player][index] = pressed
+	// The bus numbers controllers from 1
+	inst.bus.SetControllerButtons(player+1, inst.buttons[player])
+}
+
+// renderInstances draws and presents each instance's window
+func (app *Application) renderInstances() {
+	for _, inst := range app.instances {
+		var err error
+		if inst.view != nil {
+			err = inst.view(&inst.frame)
+		} else {
+			copy(inst.frame[:], inst.bus.GetFrameBuffer())
+			err = inst.window.RenderFrame(&inst.frame)
+		}
+		if err != nil && app.config.Debug.EnableLogging {
+			fmt.Printf("[APP_ERROR] %s: %v\n", inst.title, err)
+		}
+		inst.window.SwapBuffers()
+	}
+}
+
+// CloseInstance closes an instance's window and stops it
+func (app *Application) CloseInstance(inst *Instance) {
+	for i, open := range app.instances {
+		if open == inst {
+			app.instances = append(app.instances[:i], app.instances[i+1:]...)
+			break
+		}
+	}
+	if err := inst.window.Cleanup(); err != nil {
+		fmt.Printf("[APP_ERROR] %s cleanup error: %v\n", inst.title, err)
+	}
+}
+
+// closeInstances closes every instance
+func (app *Application) closeInstances() {
+	for len(app.instances) > 0 {
+		app.CloseInstance(app.instances[0])
+	}
+}
@@ -0,0 +1,57 @@
+// Package app provides the layer toggles, which draw only the background,
+// only the sprites or only one sprite palette to debug graphics.
+package app
+
+import (
+	"fmt"
+
+	"gones/internal/ppu"
+)
+
+// layerCycle is the order the layers hotkey steps through
+var layerCycle = []ppu.LayerFilter{
+	{},
+	{HideSprites: true},
+	{HideBackground: true},
+	ppu.OnlySpritePalette(0),
+	ppu.OnlySpritePalette(1),
+	ppu.OnlySpritePalette(2),
+	ppu.OnlySpritePalette(3),
+}
+
+// CycleLayers handles the layers hotkey, stepping from all layers to the
+// background only, the sprites only and each sprite palette alone
+func (app *Application) CycleLayers() {
+	next := layerCycle[0]
+	current := app.bus.PPU.LayerFilter()
+	for i, filter := range layerCycle {
+		if filter == current {
+			next = layerCycle[(i+1)%len(layerCycle)]
+			break
+		}
+	}
+	app.SetLayers(next)
+}
+
+// SetLayers sets the layers the PPU draws. Games run the same whatever is
+// drawn; only the picture changes.
+func (app *Application) SetLayers(filter ppu.LayerFilter) {
+	app.bus.PPU.SetLayerFilter(filter)
+	fmt.Printf("🧅 Layers: %s\n", filter)
+}
+
+// rpcLayers describes the layers drawn to remote clients
+func (app *Application) rpcLayers() map[string]any {
+	filter := app.bus.PPU.LayerFilter()
+	var palettes []int
+	for palette := 0; palette < 4; palette++ {
+		if !filter.HideSprites && filter.HiddenSpritePalettes&(1<<palette) == 0 {
+			palettes = append(palettes, palette)
+		}
+	}
+	return map[string]any{
+		"background":      !filter.HideBackground,
+		"sprite_palettes": palettes,
+		"layers":          filter.String(),
+	}
+}
@@ -0,0 +1,86 @@
+// Package app provides the ROM library: the ROMs of the library directories
+// identified against the hash database, which name the ROMs in the ROM
+// browser and the window title.
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gones/internal/library"
+)
+
+// LibraryDirs returns the directories the ROM library scans
+func (c *Config) LibraryDirs() []string {
+	if len(c.Library.Dirs) > 0 {
+		return c.Library.Dirs
+	}
+	return []string{c.Paths.ROMs}
+}
+
+// LibraryCachePath returns the file the scanned library is kept in
+func (c *Config) LibraryCachePath() string {
+	return filepath.Join(c.Paths.Config, "library.json")
+}
+
+// OpenLibrary reads the library as last scanned. A damaged cache gives an
+// empty library, which the next scan fills.
+func (c *Config) OpenLibrary() *library.Library {
+	lib, err := library.Load(c.LibraryCachePath())
+	if err != nil {
+		fmt.Printf("[APP_WARNING] %v\n", err)
+		return library.New()
+	}
+	return lib
+}
+
+// ScanLibrary scans dirs (the library directories when empty) into lib,
+// identifying the ROMs with the configured database, and saves the result.
+// A missing database leaves the ROMs unnamed.
+func (c *Config) ScanLibrary(lib *library.Library, dirs []string) (library.ScanResult, error) {
+	if len(dirs) == 0 {
+		dirs = c.LibraryDirs()
+	}
+	db, err := library.LoadDatabase(c.Library.Database)
+	if err != nil && !os.IsNotExist(err) {
+		return library.ScanResult{}, fmt.Errorf("failed to load the ROM database: %v", err)
+	}
+	result, err := lib.Scan(dirs, db)
+	if err != nil {
+		return result, fmt.Errorf("failed to scan the ROM library: %v", err)
+	}
+	if err := lib.Save(c.LibraryCachePath()); err != nil {
+		return result, fmt.Errorf("failed to save the ROM library: %v", err)
+	}
+	return result, nil
+}
+
+// startLibraryScan brings the library up to date in the background, so a
+// large collection does not hold up the start
+func (app *Application) startLibraryScan() {
+	if app.library == nil {
+		return
+	}
+	go func() {
+		result, err := app.config.ScanLibrary(app.library, nil)
+		if err != nil {
+			fmt.Printf("[APP_WARNING] %v\n", err)
+			return
+		}
+		if result.ROMs > 0 {
+			fmt.Printf("📚 Library: %d ROMs, %d identified\n", result.ROMs, result.Identified)
+		}
+	}()
+}
+
+// romTitle returns the name to show for a ROM file: its database title when
+// the library identified it, else the file name
+func (app *Application) romTitle(path string) string {
+	if app.library != nil {
+		if entry, ok := app.library.Lookup(path); ok && entry.Title != "" {
+			return entry.Title
+		}
+	}
+	return filepath.Base(path)
+}
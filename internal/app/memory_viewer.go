@@ -0,0 +1,387 @@
+// Package app provides the memory viewer and hex editor debug overlay.
+package app
+
+import (
+	"fmt"
+	"sort"
+
+	"gones/internal/graphics"
+)
+
+const (
+	// memoryViewerColumns and memoryViewerRows set the bytes shown at once
+	memoryViewerColumns = 8
+	memoryViewerRows    = 15
+	memoryViewerPage    = memoryViewerColumns * memoryViewerRows
+
+	// memoryHighlightRefreshes is how many refreshes with emulation progress
+	// a changed byte stays highlighted
+	memoryHighlightRefreshes = 60
+)
+
+// memoryBookmark names an address of a memory space
+type memoryBookmark struct {
+	name    string
+	address int
+}
+
+// memorySpace is an address space shown by the memory viewer
+type memorySpace struct {
+	name      string
+	size      int
+	peek      func(address int) (uint8, bool)     // Side effect free read; false if unreadable
+	poke      func(address int, value uint8) bool // Debug write; false if not writable
+	bookmarks []memoryBookmark                    // Sorted by address
+	frozen    func(address int) bool              // Whether a cheat freezes the byte (nil if none can)
+
+	cursor   int
+	top      int // First address shown
+	bookmark int // Bookmark last jumped to (-1 before the first jump)
+
+	values    []uint8  // Contents at the last refresh
+	readable  []bool   // Whether each byte could be peeked at the last refresh
+	changedAt []uint64 // Refresh that last saw each byte change (0 = never)
+}
+
+// newMemorySpace creates a space, sorting its bookmarks by address
+func newMemorySpace(name string, size int, peek func(int) (uint8, bool), poke func(int, uint8) bool, bookmarks []memoryBookmark) *memorySpace {
+	sort.SliceStable(bookmarks, func(i, j int) bool { return bookmarks[i].address < bookmarks[j].address })
+	return &memorySpace{
+		name:      name,
+		size:      size,
+		peek:      peek,
+		poke:      poke,
+		bookmarks: bookmarks,
+		bookmark:  -1,
+		values:    make([]uint8, size),
+		readable:  make([]bool, size),
+		changedAt: make([]uint64, size),
+	}
+}
+
+// snapshot reads the whole space, recording bytes that changed since the
+// last snapshot as changed in refresh
+func (s *memorySpace) snapshot(refresh uint64) {
+	for address := 0; address < s.size; address++ {
+		value, ok := s.peek(address)
+		if ok && s.readable[address] && value != s.values[address] {
+			s.changedAt[address] = refresh
+		}
+		s.values[address] = value
+		s.readable[address] = ok
+	}
+}
+
+// regionName names the address by the nearest bookmark at or below it
+func (s *memorySpace) regionName(address int) string {
+	for i := len(s.bookmarks) - 1; i >= 0; i-- {
+		bookmark := s.bookmarks[i]
+		if bookmark.address == address {
+			return bookmark.name
+		}
+		if bookmark.address < address {
+			return fmt.Sprintf("%s+$%X", bookmark.name, address-bookmark.address)
+		}
+	}
+	return ""
+}
+
+// MemoryViewer is the hex view of the CPU, PPU, OAM and palette memory
+// opened with Ctrl+F6. It pauses emulation while open and highlights the
+// bytes that changed as emulation runs or is stepped. The selected byte can
+// be edited by typing hex digits, and CPU bytes frozen with a RAM cheat.
+type MemoryViewer struct {
+	visible   bool
+	wasPaused bool
+	spaces    []*memorySpace
+	space     int
+
+	stamp     uint64 // Emulation progress (the CPU cycle count) at the last refresh
+	refreshes uint64 // Refreshes that saw emulation progress
+
+	editing    bool
+	editValue  uint8
+	editDigits int
+
+	message string
+}
+
+// NewMemoryViewer creates a hidden memory viewer
+func NewMemoryViewer() *MemoryViewer {
+	return &MemoryViewer{}
+}
+
+// Open shows the viewer on the given spaces. stamp identifies the current
+// point of emulation for Refresh.
+func (v *MemoryViewer) Open(spaces []*memorySpace, stamp uint64, wasPaused bool) {
+	v.visible = true
+	v.wasPaused = wasPaused
+	v.spaces = spaces
+	v.space = 0
+	v.stamp = stamp
+	v.refreshes = 1
+	v.editing = false
+	v.message = ""
+	for _, space := range spaces {
+		space.snapshot(0)
+	}
+}
+
+// Close hides the viewer and returns the pause state from before it was opened
+func (v *MemoryViewer) Close() bool {
+	v.visible = false
+	v.editing = false
+	v.spaces = nil
+	return v.wasPaused
+}
+
+// IsVisible returns whether the viewer is on screen
+func (v *MemoryViewer) IsVisible() bool {
+	return v.visible
+}
+
+// Refresh rereads the memory if emulation moved on from the last refresh
+func (v *MemoryViewer) Refresh(stamp uint64) {
+	if !v.visible || stamp == v.stamp {
+		return
+	}
+	v.stamp = stamp
+	v.refreshes++
+	for _, space := range v.spaces {
+		space.snapshot(v.refreshes)
+	}
+}
+
+// current returns the space shown, or nil
+func (v *MemoryViewer) current() *memorySpace {
+	if v.space >= len(v.spaces) {
+		return nil
+	}
+	return v.spaces[v.space]
+}
+
+// GetSpaceName returns the name of the space shown
+func (v *MemoryViewer) GetSpaceName() string {
+	if space := v.current(); space != nil {
+		return space.name
+	}
+	return ""
+}
+
+// GetCursor returns the selected address
+func (v *MemoryViewer) GetCursor() int {
+	if space := v.current(); space != nil {
+		return space.cursor
+	}
+	return 0
+}
+
+// NextSpace switches to the next address space
+func (v *MemoryViewer) NextSpace() {
+	if len(v.spaces) == 0 {
+		return
+	}
+	v.CancelEdit()
+	v.space = (v.space + 1) % len(v.spaces)
+	v.message = ""
+}
+
+// Move moves the cursor by delta bytes, wrapping around the space
+func (v *MemoryViewer) Move(delta int) {
+	space := v.current()
+	if space == nil {
+		return
+	}
+	v.message = ""
+	v.moveTo(space, space.cursor+delta)
+}
+
+// moveTo puts the cursor on address and scrolls it into view
+func (v *MemoryViewer) moveTo(space *memorySpace, address int) {
+	space.cursor = ((address % space.size) + space.size) % space.size
+
+	row := space.cursor - space.cursor%memoryViewerColumns
+	if row < space.top {
+		space.top = row
+	} else if row >= space.top+memoryViewerPage {
+		space.top = row - memoryViewerPage + memoryViewerColumns
+	}
+}
+
+// NextBookmark jumps to the next bookmark of the space, showing it at the top
+func (v *MemoryViewer) NextBookmark() {
+	space := v.current()
+	if space == nil || len(space.bookmarks) == 0 {
+		return
+	}
+	v.CancelEdit()
+	space.bookmark = (space.bookmark + 1) % len(space.bookmarks)
+	v.GoTo(v.space, space.bookmarks[space.bookmark].address)
+}
+
+// GoTo shows the given space with the cursor on address
+func (v *MemoryViewer) GoTo(space, address int) {
+	if space < 0 || space >= len(v.spaces) {
+		return
+	}
+	v.CancelEdit()
+	v.space = space
+	current := v.spaces[space]
+	current.top = max(0, min(address-address%memoryViewerColumns, current.size-memoryViewerPage))
+	v.moveTo(current, address)
+}
+
+// StartEdit begins typing a new value for the selected byte. It returns false
+// (with a message) if the byte cannot be written.
+func (v *MemoryViewer) StartEdit() bool {
+	space := v.current()
+	if space == nil {
+		return false
+	}
+	if space.poke == nil || !space.readable[space.cursor] {
+		v.message = fmt.Sprintf("$%04X IS READ ONLY", space.cursor)
+		return false
+	}
+	v.editing = true
+	v.editValue = 0
+	v.editDigits = 0
+	v.message = ""
+	return true
+}
+
+// EnterDigit adds a hex digit to the value being typed. The second digit
+// writes the byte and moves on to the next one, so a run of bytes can be
+// typed in one go. It returns whether editing continues.
+func (v *MemoryViewer) EnterDigit(digit uint8) bool {
+	space := v.current()
+	if !v.editing || space == nil {
+		return false
+	}
+	v.editValue = v.editValue<<4 | digit&0x0F
+	v.editDigits++
+	if v.editDigits < 2 {
+		return true
+	}
+
+	address := space.cursor
+	if !space.poke(address, v.editValue) {
+		v.message = fmt.Sprintf("$%04X IS READ ONLY", address)
+		v.editing = false
+		return false
+	}
+	if value, ok := space.peek(address); ok {
+		space.values[address] = value
+	}
+	space.changedAt[address] = v.refreshes
+	v.editDigits = 0
+	v.editValue = 0
+
+	// Move on to the next byte; an unreadable one ends the edit
+	v.Move(1)
+	if !space.readable[space.cursor] {
+		v.editing = false
+	}
+	return v.editing
+}
+
+// CancelEdit stops typing, dropping a half typed byte
+func (v *MemoryViewer) CancelEdit() {
+	v.editing = false
+	v.editDigits = 0
+}
+
+// IsEditing returns whether hex digits are being typed
+func (v *MemoryViewer) IsEditing() bool {
+	return v.editing
+}
+
+// SetMessage sets a status line shown below the bytes
+func (v *MemoryViewer) SetMessage(message string) {
+	v.message = message
+}
+
+// Render draws the viewer over the frame buffer. status is shown at the top
+// right and registers on the line below the title.
+func (v *MemoryViewer) Render(frameBuffer *[256 * 240]uint32, status, registers string) {
+	space := v.current()
+	if !v.visible || space == nil {
+		return
+	}
+
+	graphics.DarkenRect(frameBuffer, 0, 0, graphics.OverlayWidth, graphics.OverlayHeight, 3)
+	graphics.DrawTextShadowed(frameBuffer, 8, 6, "MEMORY: "+space.name, graphics.OverlayColorYellow)
+	graphics.DrawTextShadowed(frameBuffer, 248-graphics.TextWidth(status), 6, status, graphics.OverlayColorGray)
+	graphics.DrawTextShadowed(frameBuffer, 8, 18, registers, graphics.OverlayColorWhite)
+
+	header := "     "
+	for column := 0; column < memoryViewerColumns; column++ {
+		header += fmt.Sprintf(" %X ", column)
+	}
+	graphics.DrawText(frameBuffer, 8, 32, header, graphics.OverlayColorGray)
+
+	for row := 0; row < memoryViewerRows; row++ {
+		address := space.top + row*memoryViewerColumns
+		if address >= space.size {
+			break
+		}
+		y := 44 + row*graphics.LineHeight
+		graphics.DrawText(frameBuffer, 8, y, fmt.Sprintf("%04X:", address), graphics.OverlayColorGray)
+		for column := 0; column < memoryViewerColumns && address+column < space.size; column++ {
+			v.renderByte(frameBuffer, space, address+column, 8+(6+3*column)*graphics.FontAdvance, y)
+		}
+	}
+
+	y := 44 + memoryViewerRows*graphics.LineHeight + 2
+	switch {
+	case v.message != "":
+		graphics.DrawTextShadowed(frameBuffer, 8, y, truncateMenuText(v.message, 40), graphics.OverlayColorRed)
+	case v.editing:
+		graphics.DrawTextShadowed(frameBuffer, 8, y, "TYPE HEX DIGITS - ANY OTHER KEY STOPS", graphics.OverlayColorYellow)
+	default:
+		info := fmt.Sprintf("$%04X", space.cursor)
+		if space.readable[space.cursor] {
+			info += fmt.Sprintf(" = $%02X", space.values[space.cursor])
+		}
+		if region := space.regionName(space.cursor); region != "" {
+			info += "  " + region
+		}
+		graphics.DrawTextShadowed(frameBuffer, 8, y, truncateMenuText(info, 40), graphics.OverlayColorWhite)
+	}
+
+	graphics.DrawTextShadowed(frameBuffer, 8, 216, "A EDIT  B BOOKMARK  SELECT FREEZE  TAB", graphics.OverlayColorGray)
+	graphics.DrawTextShadowed(frameBuffer, 8, 226, "-/= PAGE  F5 RUN  F10 FRAME  F11 STEP", graphics.OverlayColorGray)
+}
+
+// renderByte draws one byte: frozen bytes in green, recently changed bytes in
+// red fading to yellow, the cursor on a panel, and the digits being typed
+// while editing
+func (v *MemoryViewer) renderByte(frameBuffer *[256 * 240]uint32, space *memorySpace, address, x, y int) {
+	text := "--"
+	color := graphics.OverlayColorGray
+	if space.readable[address] {
+		text = fmt.Sprintf("%02X", space.values[address])
+		color = graphics.OverlayColorWhite
+		if space.frozen != nil && space.frozen(address) {
+			color = graphics.OverlayColorGreen
+		} else if changed := space.changedAt[address]; changed != 0 {
+			switch age := v.refreshes - changed; {
+			case age == 0:
+				color = graphics.OverlayColorRed
+			case age < memoryHighlightRefreshes:
+				color = graphics.OverlayColorYellow
+			}
+		}
+	}
+
+	if address == space.cursor {
+		graphics.FillRect(frameBuffer, x-2, y-2, 2*graphics.FontAdvance+3, graphics.LineHeight, graphics.OverlayColorPanel)
+		if v.editing {
+			text = "__"
+			if v.editDigits == 1 {
+				text = fmt.Sprintf("%X_", v.editValue)
+			}
+			color = graphics.OverlayColorYellow
+		}
+	}
+	graphics.DrawText(frameBuffer, x, y, text, color)
+}
@@ -0,0 +1,232 @@
+// Package app provides the pause menu with its ROM browser and settings pages.
+package app
+
+import (
+	"fmt"
+
+	"gones/internal/graphics"
+)
+
+// menuVisibleRows is the number of items shown at once; longer pages scroll
+const menuVisibleRows = 18
+
+// menuItem is one line of a menu page
+type menuItem struct {
+	label  string
+	value  func() string   // Current setting shown on the right (nil for plain actions)
+	action func()          // Runs on confirm (nil for settings, which confirm as adjust(1))
+	adjust func(delta int) // Changes the setting with left/right (nil if not adjustable)
+}
+
+// menuPage is a titled list of items
+type menuPage struct {
+	title    string
+	items    []menuItem
+	selected int
+	scroll   int
+	changed  bool   // A setting on the page was adjusted
+	onChange func() // Runs when the page is left after a setting changed
+}
+
+// PauseMenu is the on-screen menu opened with Escape. Pages form a stack:
+// confirming an item may open a sub page, and going back returns to the
+// page below.
+type PauseMenu struct {
+	visible   bool
+	pages     []*menuPage
+	wasPaused bool
+	message   string
+}
+
+// NewPauseMenu creates a hidden menu
+func NewPauseMenu() *PauseMenu {
+	return &PauseMenu{}
+}
+
+// Open shows the menu with page as its root
+func (m *PauseMenu) Open(page *menuPage, wasPaused bool) {
+	m.visible = true
+	m.pages = []*menuPage{page}
+	m.wasPaused = wasPaused
+	m.message = ""
+}
+
+// Close hides the menu and returns the pause state from before it was opened
+func (m *PauseMenu) Close() bool {
+	for len(m.pages) > 0 {
+		m.leave()
+	}
+	m.visible = false
+	return m.wasPaused
+}
+
+// IsVisible returns whether the menu is on screen
+func (m *PauseMenu) IsVisible() bool {
+	return m.visible
+}
+
+// Push opens a sub page
+func (m *PauseMenu) Push(page *menuPage) {
+	m.pages = append(m.pages, page)
+	m.message = ""
+}
+
+// Replace swaps the current page for another, such as another directory of the ROM browser
+func (m *PauseMenu) Replace(page *menuPage) {
+	if len(m.pages) == 0 {
+		m.Push(page)
+		return
+	}
+	m.pages[len(m.pages)-1] = page
+	m.message = ""
+}
+
+// Back returns to the previous page. It returns false on the root page.
+func (m *PauseMenu) Back() bool {
+	if len(m.pages) <= 1 {
+		return false
+	}
+	m.leave()
+	m.message = ""
+	return true
+}
+
+// leave pops the current page, applying its changes
+func (m *PauseMenu) leave() {
+	page := m.pages[len(m.pages)-1]
+	m.pages = m.pages[:len(m.pages)-1]
+	if page.changed && page.onChange != nil {
+		page.onChange()
+	}
+}
+
+// current returns the page on top of the stack, or nil
+func (m *PauseMenu) current() *menuPage {
+	if len(m.pages) == 0 {
+		return nil
+	}
+	return m.pages[len(m.pages)-1]
+}
+
+// GetTitle returns the title of the current page
+func (m *PauseMenu) GetTitle() string {
+	if page := m.current(); page != nil {
+		return page.title
+	}
+	return ""
+}
+
+// GetSelectedLabel returns the label of the highlighted item
+func (m *PauseMenu) GetSelectedLabel() string {
+	page := m.current()
+	if page == nil || page.selected >= len(page.items) {
+		return ""
+	}
+	return page.items[page.selected].label
+}
+
+// Move moves the selection by delta items, wrapping around the page
+func (m *PauseMenu) Move(delta int) {
+	page := m.current()
+	if page == nil || len(page.items) == 0 {
+		return
+	}
+	page.selected = (page.selected + delta) % len(page.items)
+	if page.selected < 0 {
+		page.selected += len(page.items)
+	}
+
+	// Keep the selection in view
+	if page.selected < page.scroll {
+		page.scroll = page.selected
+	} else if page.selected >= page.scroll+menuVisibleRows {
+		page.scroll = page.selected - menuVisibleRows + 1
+	}
+}
+
+// Adjust changes the highlighted setting
+func (m *PauseMenu) Adjust(delta int) {
+	page := m.current()
+	if page == nil || page.selected >= len(page.items) {
+		return
+	}
+	if item := page.items[page.selected]; item.adjust != nil {
+		item.adjust(delta)
+		page.changed = true
+	}
+}
+
+// Confirm activates the highlighted item
+func (m *PauseMenu) Confirm() {
+	page := m.current()
+	if page == nil || page.selected >= len(page.items) {
+		return
+	}
+	item := page.items[page.selected]
+	if item.action != nil {
+		item.action()
+	} else if item.adjust != nil {
+		m.Adjust(1)
+	}
+}
+
+// SetMessage sets a status line shown at the bottom of the menu (e.g. an error)
+func (m *PauseMenu) SetMessage(message string) {
+	m.message = message
+}
+
+// Render draws the menu over the frame buffer
+func (m *PauseMenu) Render(frameBuffer *[256 * 240]uint32) {
+	page := m.current()
+	if !m.visible || page == nil {
+		return
+	}
+
+	graphics.DarkenRect(frameBuffer, 0, 0, graphics.OverlayWidth, graphics.OverlayHeight, 2)
+	graphics.DrawTextShadowed(frameBuffer, 8, 6, truncateMenuText(page.title, 30), graphics.OverlayColorYellow)
+	if len(page.items) > menuVisibleRows {
+		position := fmt.Sprintf("%d/%d", page.selected+1, len(page.items))
+		graphics.DrawTextShadowed(frameBuffer, 248-graphics.TextWidth(position), 6, position, graphics.OverlayColorGray)
+	}
+
+	listY := 22
+	end := min(len(page.items), page.scroll+menuVisibleRows)
+	for i := page.scroll; i < end; i++ {
+		item := page.items[i]
+		y := listY + (i-page.scroll)*graphics.LineHeight
+
+		color := graphics.OverlayColorGray
+		if i == page.selected {
+			graphics.FillRect(frameBuffer, 4, y-2, 248, graphics.LineHeight, graphics.OverlayColorPanel)
+			color = graphics.OverlayColorWhite
+		}
+		if item.action == nil && item.adjust == nil {
+			color = graphics.OverlayColorGray
+		}
+
+		width := 40
+		if item.value != nil {
+			value := item.value()
+			graphics.DrawText(frameBuffer, 248-graphics.TextWidth(value), y, value, graphics.OverlayColorYellow)
+			width -= len(value) + 1
+		}
+		graphics.DrawText(frameBuffer, 8, y, truncateMenuText(item.label, width), color)
+	}
+
+	if m.message != "" {
+		graphics.DrawTextShadowed(frameBuffer, 8, 212, truncateMenuText(m.message, 40), graphics.OverlayColorRed)
+	}
+	graphics.DrawTextShadowed(frameBuffer, 8, 226, "ARROWS SELECT/CHANGE  ENTER OK  ESC BACK", graphics.OverlayColorGray)
+}
+
+// truncateMenuText shortens text to at most n characters, marking the cut with ".."
+func truncateMenuText(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	if n <= 2 {
+		return string(runes[:max(0, n)])
+	}
+	return string(runes[:n-2]) + ".."
+}
@@ -0,0 +1,224 @@
+// Package app provides the pages of the pause menu and its input handling.
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"gones/internal/graphics"
+)
+
+// mainMenuPage builds the root page of the pause menu. Entries that need a
+// ROM are left out until one is loaded.
+func (app *Application) mainMenuPage() *menuPage {
+	loaded := app.cartridge != nil
+	page := &menuPage{title: "PAUSED"}
+	if !loaded {
+		page.title = "GONES - NO ROM LOADED"
+	}
+
+	recent := app.RecentROMs()
+	if loaded {
+		page.items = append(page.items, menuItem{label: "RESUME", action: app.HideMenu})
+	} else if len(recent) > 0 {
+		name := recent[0].Name()
+		page.items = append(page.items, menuItem{
+			label:  "CONTINUE",
+			value:  func() string { return truncateMenuText(name, 24) },
+			action: app.continueFromMenu,
+		})
+	}
+	page.items = append(page.items, menuItem{label: "LOAD ROM", action: func() {
+		app.menu.Push(app.romBrowserPage(app.romBrowserDir()))
+	}})
+	if len(recent) > 0 {
+		page.items = append(page.items, menuItem{label: "RECENT ROMS", action: func() { app.menu.Push(app.recentROMsPage()) }})
+	}
+	if loaded {
+		page.items = append(page.items,
+			menuItem{label: "SAVE STATE", action: func() { app.openSlotPickerFromMenu(SlotPickerSave) }},
+			menuItem{label: "LOAD STATE", action: func() { app.openSlotPickerFromMenu(SlotPickerLoad) }},
+			menuItem{label: "NAMED STATES", action: func() { app.menu.Push(app.namedStatesPage()) }},
+			menuItem{label: "RESET", action: func() {
+				app.Reset()
+				app.HideMenu()
+			}},
+			menuItem{label: "POWER CYCLE", action: func() {
+				if err := app.PowerCycle(); err != nil {
+					app.menu.SetMessage(strings.ToUpper(err.Error()))
+					return
+				}
+				app.HideMenu()
+			}},
+			menuItem{label: "CHEATS", action: func() { app.menu.Push(app.cheatsMenuPage()) }},
+			menuItem{label: "RAM SEARCH", action: func() { app.menu.Push(app.ramSearchPage()) }},
+			menuItem{label: "CODE/DATA LOGGER", action: func() { app.menu.Push(app.cdlMenuPage()) }},
+			menuItem{label: "NETPLAY", action: func() { app.menu.Push(app.netplayMenuPage()) }},
+		)
+	}
+	page.items = append(page.items,
+		menuItem{label: "VIDEO SETTINGS", action: func() { app.menu.Push(app.videoMenuPage()) }},
+		menuItem{label: "AUDIO SETTINGS", action: func() { app.menu.Push(app.audioMenuPage()) }},
+		menuItem{label: "HOTKEYS", action: func() { app.menu.Push(app.hotkeysMenuPage()) }},
+		menuItem{label: "QUIT", action: app.Stop},
+	)
+	return page
+}
+
+// videoMenuPage builds the video settings page
+func (app *Application) videoMenuPage() *menuPage {
+	video := &app.config.Video
+	return &menuPage{
+		title:    "VIDEO SETTINGS",
+		onChange: app.saveSettings,
+		items: []menuItem{
+			{
+				label: "ASPECT RATIO",
+				value: func() string { return video.AspectRatio },
+				adjust: func(delta int) {
+					if err := app.SetAspectRatio(cycleOption(graphics.AspectModes, video.AspectRatio, delta)); err != nil {
+						fmt.Printf("[APP_ERROR] %v\n", err)
+					}
+				},
+			},
+			{
+				label: "UPSCALER",
+				value: func() string { return video.Upscaler },
+				adjust: func(delta int) {
+					if err := app.SetUpscaler(cycleOption(graphics.Upscalers, video.Upscaler, delta)); err != nil {
+						fmt.Printf("[APP_ERROR] %v\n", err)
+					}
+				},
+			},
+			app.stepMenuItem("BRIGHTNESS", &video.Brightness, 0.1, 3.0, func(v float32) { app.videoProcessor.SetBrightness(v) }),
+			app.stepMenuItem("CONTRAST", &video.Contrast, 0.1, 3.0, func(v float32) { app.videoProcessor.SetContrast(v) }),
+			app.stepMenuItem("SATURATION", &video.Saturation, 0.0, 3.0, func(v float32) { app.videoProcessor.SetSaturation(v) }),
+			app.stepMenuItem("FRAME BLEND", &video.FrameBlend, 0.0, 1.0, func(v float32) { app.videoProcessor.SetFrameBlend(v) }),
+			{
+				label: "COLOR FILTER",
+				value: func() string { return video.ColorFilter },
+				adjust: func(delta int) {
+					if err := app.SetColorFilter(cycleOption(graphics.ColorFilters, video.ColorFilter, delta)); err != nil {
+						fmt.Printf("[APP_ERROR] %v\n", err)
+					}
+				},
+			},
+			{
+				label:  "UNLIMITED SPRITES",
+				value:  func() string { return onOff(video.UnlimitedSprites) },
+				adjust: func(int) { app.SetUnlimitedSprites(!video.UnlimitedSprites) },
+			},
+		},
+	}
+}
+
+// stepMenuItem builds an item stepping a video setting by 0.1 between minimum
+// and maximum, the range accepted by the config
+func (app *Application) stepMenuItem(label string, setting *float32, minimum, maximum float32, apply func(float32)) menuItem {
+	return menuItem{
+		label: label,
+		value: func() string { return fmt.Sprintf("%.1f", *setting) },
+		adjust: func(delta int) {
+			// Work in tenths so repeated steps do not drift
+			tenths := int(*setting*10+0.5) + delta
+			*setting = max(minimum, min(maximum, float32(tenths)/10))
+			if app.videoProcessor != nil {
+				apply(*setting)
+			}
+		},
+	}
+}
+
+// audioMenuPage builds the audio settings page
+func (app *Application) audioMenuPage() *menuPage {
+	audio := &app.config.Audio
+	return &menuPage{
+		title:    "AUDIO SETTINGS",
+		onChange: app.saveSettings,
+		items: []menuItem{
+			{
+				label: "AUDIO",
+				value: func() string { return onOff(audio.Enabled) },
+				adjust: func(int) { audio.Enabled = !audio.Enabled },
+			},
+			{
+				label: "VOLUME",
+				value: func() string { return fmt.Sprintf("%d%%", int(audio.Volume*100+0.5)) },
+				adjust: func(delta int) {
+					percent := int(audio.Volume*100+0.5) + delta*10
+					audio.Volume = float32(max(0, min(100, percent))) / 100
+				},
+			},
+		},
+	}
+}
+
+// cycleOption returns the option delta steps away from current, wrapping around
+func cycleOption(options []string, current string, delta int) string {
+	for i, option := range options {
+		if option == current {
+			return options[((i+delta)%len(options)+len(options))%len(options)]
+		}
+	}
+	return options[0]
+}
+
+// saveSettings writes settings changed at runtime (menu, hotkeys) to the config file
+func (app *Application) saveSettings() {
+	if app.config.GetConfigPath() == "" {
+		return
+	}
+	if err := app.config.Save(); err != nil {
+		fmt.Printf("[APP_WARNING] Failed to save settings: %v\n", err)
+	}
+}
+
+// openSlotPickerFromMenu closes the menu and opens the save state slot picker
+func (app *Application) openSlotPickerFromMenu(mode SlotPickerMode) {
+	app.HideMenu()
+	app.OpenSlotPicker(mode, 0)
+}
+
+// handleMenuInput navigates the pause menu. Escape and B go back a page; on
+// the root page they close the menu, unless there is no ROM to return to.
+func (app *Application) handleMenuInput(event graphics.InputEvent) {
+	// A hotkey being rebound takes the next key
+	if app.hotkeyCapture != nil {
+		app.handleHotkeyCapture(event)
+		return
+	}
+
+	back := false
+
+	switch event.Type {
+	case graphics.InputEventTypeKey:
+		back = event.Key == graphics.KeyEscape
+
+	case graphics.InputEventTypeButton:
+		switch event.Button {
+		case graphics.ButtonUp:
+			app.menu.Move(-1)
+		case graphics.ButtonDown:
+			app.menu.Move(1)
+		case graphics.ButtonLeft:
+			app.menu.Adjust(-1)
+		case graphics.ButtonRight:
+			app.menu.Adjust(1)
+		case graphics.ButtonStart, graphics.ButtonA:
+			app.menu.Confirm()
+		case graphics.ButtonB:
+			back = true
+		}
+	}
+
+	if back && !app.menu.Back() && app.cartridge != nil {
+		app.HideMenu()
+	}
+}
+
+// renderMenu draws the pause menu over the frame
+func (app *Application) renderMenu(frameBuffer *[256 * 240]uint32) {
+	if app.menu != nil {
+		app.menu.Render(frameBuffer)
+	}
+}
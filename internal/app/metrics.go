@@ -0,0 +1,150 @@
+// Package app provides the Prometheus metrics of a running emulator.
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"gones/internal/api"
+	"gones/internal/graphics"
+	"gones/internal/metrics"
+	"gones/internal/version"
+)
+
+// DefaultMetricsListen is where the metrics server listens by default
+const DefaultMetricsListen = "127.0.0.1:9650"
+
+// frameTimeWindow is how many recent frames the frame time quantiles cover,
+// ten seconds at 60 FPS
+const frameTimeWindow = 600
+
+// frameTimeQuantiles are the frame time quantiles reported
+var frameTimeQuantiles = []float64{0.5, 0.9, 0.99}
+
+// recordFrameTime adds a frame's processing time to the frame time metrics
+func (app *Application) recordFrameTime(frameTime time.Duration) {
+	if app.frameTimes == nil {
+		app.frameTimes = metrics.NewWindow(frameTimeWindow)
+	}
+	app.frameTimes.Add(frameTime.Seconds())
+	app.frameTimeTotal += frameTime
+	app.framesTimed++
+	app.recordPerfSample()
+}
+
+// SetMetricsListen turns the metrics server on, listening on address
+func (app *Application) SetMetricsListen(address string) {
+	app.config.Metrics.Enabled = true
+	app.config.Metrics.Listen = address
+}
+
+// StartMetrics starts the metrics server on metrics.listen
+func (app *Application) StartMetrics() error {
+	if app.metricsServer != nil {
+		return nil
+	}
+	server, err := api.Listen(app.config.Metrics.Listen, api.NewMetricsHandler(app.remoteServer()))
+	if err != nil {
+		return err
+	}
+	app.metricsServer = server
+	fmt.Printf("📈 Metrics on http://%s/metrics\n", server.Addr())
+	return nil
+}
+
+// StopMetrics stops the metrics server
+func (app *Application) StopMetrics() {
+	if app.metricsServer == nil {
+		return
+	}
+	if err := app.metricsServer.Close(); err != nil && app.config.Debug.EnableLogging {
+		fmt.Printf("[APP_DEBUG] Metrics server: %v\n", err)
+	}
+	app.metricsServer = nil
+}
+
+// MetricsAddr returns the address of the metrics server, or "" when it is
+// not running
+func (app *Application) MetricsAddr() string {
+	if app.metricsServer == nil {
+		return ""
+	}
+	return app.metricsServer.Addr().String()
+}
+
+// WriteMetrics writes the emulator's metrics in the Prometheus text format.
+// It reads emulator state, so it runs on the emulation thread.
+func (app *Application) WriteMetrics(e *metrics.Encoder) {
+	rom := ""
+	if app.cartridge != nil {
+		rom = app.romPath
+	}
+	e.Header("gones_info", metrics.Gauge, "Emulator version and loaded ROM.")
+	e.Value("gones_info", 1, "version", version.GetVersion(), "rom", rom)
+	if !app.startTime.IsZero() {
+		e.Metric("gones_uptime_seconds", metrics.Gauge, "Seconds since emulation started.", time.Since(app.startTime).Seconds())
+	}
+	paused := 0.0
+	if app.paused {
+		paused = 1
+	}
+	e.Metric("gones_paused", metrics.Gauge, "Whether emulation is paused.", paused)
+
+	// Frame rate and timing
+	e.Metric("gones_fps", metrics.Gauge, "Frames per second over the last second.", app.currentFPS)
+	e.Metric("gones_frames_total", metrics.Counter, "Frames run by the main loop.", float64(app.frameCount))
+	emulated := uint64(0)
+	if app.emulator != nil {
+		emulated = app.emulator.GetFrameCount()
+	}
+	e.Metric("gones_emulated_frames_total", metrics.Counter, "NES frames emulated since the last reset.", float64(emulated))
+	window := app.frameTimes
+	if window == nil {
+		window = metrics.NewWindow(1)
+	}
+	e.Summary("gones_frame_time_seconds",
+		fmt.Sprintf("Time to process a frame; quantiles cover the last %d frames.", frameTimeWindow),
+		window, frameTimeQuantiles, app.frameTimeTotal.Seconds(), app.framesTimed)
+	e.Header("gones_component_seconds_total", metrics.Counter, "Time spent in each part of the main loop.")
+	e.Value("gones_component_seconds_total", app.totalInputTime.Seconds(), "component", "input")
+	e.Value("gones_component_seconds_total", app.totalEmulatorTime.Seconds(), "component", "emulation")
+	e.Value("gones_component_seconds_total", app.totalRenderTime.Seconds(), "component", "render")
+
+	// Memory
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	e.Metric("gones_memory_alloc_bytes", metrics.Gauge, "Bytes of allocated heap objects.", float64(memStats.Alloc))
+	e.Metric("gones_memory_sys_bytes", metrics.Gauge, "Bytes of memory obtained from the OS.", float64(memStats.Sys))
+	if app.initialMemoryUsage != 0 {
+		e.Metric("gones_memory_growth_bytes", metrics.Gauge, "Heap growth since the first frame.",
+			float64(memStats.Alloc)-float64(app.initialMemoryUsage))
+	}
+	e.Metric("gones_gc_cycles_total", metrics.Counter, "Completed garbage collections.", float64(memStats.NumGC))
+	e.Metric("gones_goroutines", metrics.Gauge, "Running goroutines.", float64(runtime.NumGoroutine()))
+
+	// Audio
+	if stats, ok := app.window.(graphics.AudioStats); ok {
+		e.Metric("gones_audio_underruns_total", metrics.Counter, "Times the audio device ran out of samples.",
+			float64(stats.AudioUnderruns()))
+	}
+	if _, ok := app.window.(graphics.AudioPosition); ok {
+		e.Metric("gones_av_drift_seconds", metrics.Gauge, "How far the video is ahead of the audio device (negative: behind).",
+			app.avSync.drift.Seconds())
+		e.Header("gones_av_sync_corrections_total", metrics.Counter, "Frames dropped or repeated to keep the video with the audio.")
+		e.Value("gones_av_sync_corrections_total", float64(app.avSync.dropped), "kind", "drop")
+		e.Value("gones_av_sync_corrections_total", float64(app.avSync.repeated), "kind", "repeat")
+	}
+}
+
+// registerMetricsMethods registers the method /metrics is served from
+func (app *Application) registerMetricsMethods() {
+	app.remote.Handle("metrics", func(json.RawMessage) (any, error) {
+		var b strings.Builder
+		e := metrics.NewEncoder(&b)
+		app.WriteMetrics(e)
+		return b.String(), e.Err()
+	})
+}
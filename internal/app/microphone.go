@@ -0,0 +1,27 @@
+// Package app provides the Famicom microphone: it hears sound while its
+// hotkey is held or, with input.microphone_threshold set, while the host's
+// microphone is loud enough.
+package app
+
+import "gones/internal/graphics"
+
+// setMicrophoneHeld records whether the microphone hotkey is held
+func (app *Application) setMicrophoneHeld(held bool) {
+	app.microphoneHeld = held
+	app.updateMicrophone()
+}
+
+// updateMicrophone sets whether the microphone hears sound, from the hotkey
+// and the level the host's microphone captured since the last update
+func (app *Application) updateMicrophone() {
+	if app.bus == nil {
+		return
+	}
+	active := app.microphoneHeld
+	if threshold := app.config.Input.MicrophoneThreshold; threshold > 0 {
+		if microphone, ok := app.window.(graphics.MicrophoneInput); ok && microphone.MicrophoneLevel() >= threshold {
+			active = true
+		}
+	}
+	app.bus.SetMicrophone(active)
+}
@@ -0,0 +1,221 @@
+// Package app provides input movies in the GUI: playback, recording from a
+// savestate, and branching a new recording off a movie being played back.
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gones/internal/input"
+)
+
+// SetMovie plays back an input script from the next frame, in place of the
+// keyboard and gamepads until it ends. A script that starts from a savestate
+// restores it first. nil stops playback.
+func (app *Application) SetMovie(script *input.Script) error {
+	if script != nil {
+		if err := app.LoadMovieState(script); err != nil {
+			return err
+		}
+	}
+	app.movie = script
+	app.movieFrame = 0
+	app.movieRecording = false
+	return nil
+}
+
+// LoadMovieState restores the savestate an input script starts from, if it
+// has one, after checking it was taken on the loaded ROM
+func (app *Application) LoadMovieState(script *input.Script) error {
+	rom, state := script.Savestate()
+	if state == nil {
+		return nil
+	}
+	if app.cartridge == nil {
+		return errors.New("no ROM loaded")
+	}
+	if app.netplay != nil {
+		return errNetplayState
+	}
+	if rom != "" && rom != app.romHash {
+		return errors.New("the movie's savestate is for a different ROM")
+	}
+	if err := app.bus.LoadStateFromBytes(state); err != nil {
+		return fmt.Errorf("failed to load the movie's savestate: %v", err)
+	}
+	return nil
+}
+
+// MoviePlaying reports whether an input movie is being played back
+func (app *Application) MoviePlaying() bool {
+	return app.movie != nil && !app.movieRecording
+}
+
+// MovieRecording reports whether an input movie is being recorded
+func (app *Application) MovieRecording() bool {
+	return app.movieRecording
+}
+
+// StartMovieRecording records the controllers from the next frame into a
+// movie that StopMovieRecording saves to path. A movie started after power on
+// holds a savestate of the console as it is now, to start from.
+func (app *Application) StartMovieRecording(path string, fromPowerOn bool) error {
+	if app.bus == nil || app.cartridge == nil {
+		return errors.New("no ROM loaded")
+	}
+	if app.netplay != nil {
+		return errors.New("movies cannot be recorded during netplay")
+	}
+	if app.movieRecording {
+		return errors.New("already recording a movie")
+	}
+
+	movie := input.NewScript()
+	if !fromPowerOn {
+		state, err := app.bus.SaveStateToBytes()
+		if err != nil {
+			return fmt.Errorf("failed to save the movie's savestate: %v", err)
+		}
+		movie.SetSavestate(app.romHash, state)
+	}
+	if err := checkMoviePath(movie, path); err != nil {
+		return err
+	}
+	app.movie = movie
+	app.movieFrame = 0
+	app.movieRecording = true
+	app.moviePath = path
+	return nil
+}
+
+// BranchMovie stops playing back the movie at the current frame and records a
+// new movie from there: it starts like the one played back, from the same
+// savestate, and goes on with the player's input. StopMovieRecording saves it
+// to path.
+func (app *Application) BranchMovie(path string) error {
+	if !app.MoviePlaying() {
+		return errors.New("no movie playing")
+	}
+	branch := app.movie.Branch(app.movieFrame)
+	if err := checkMoviePath(branch, path); err != nil {
+		return err
+	}
+	app.movie = branch
+	app.movieRecording = true
+	// Keys held during playback went unseen; the branch starts released
+	app.lastController1State = [8]bool{}
+	app.lastController2State = [8]bool{}
+	app.lastController34State = [2][8]bool{}
+	app.moviePath = path
+	return nil
+}
+
+// checkMoviePath returns an error if a movie cannot be saved to path: CSV
+// files have no room for a savestate
+func checkMoviePath(movie *input.Script, path string) error {
+	if _, state := movie.Savestate(); state != nil && strings.EqualFold(filepath.Ext(path), ".csv") {
+		return errors.New("a movie starting from a savestate has to be saved as JSON")
+	}
+	return nil
+}
+
+// StopMovieRecording saves the movie being recorded and hands the
+// controllers back to the player
+func (app *Application) StopMovieRecording() error {
+	if !app.movieRecording {
+		return errors.New("not recording a movie")
+	}
+	movie, path := app.movie, app.moviePath
+	app.movie = nil
+	app.movieRecording = false
+	return input.SaveScript(movie, path)
+}
+
+// ToggleMovieRecording handles the movie hotkey: it saves the movie being
+// recorded, branches the one being played back, or records a new one from
+// the current state, in the recordings directory
+func (app *Application) ToggleMovieRecording() {
+	if app.movieRecording {
+		path, frames := app.moviePath, app.movie.Length()
+		if err := app.StopMovieRecording(); err != nil {
+			fmt.Printf("[APP_ERROR] Movie recording failed: %v\n", err)
+			return
+		}
+		fmt.Printf("🎬 Movie saved: %s (%d frames)\n", path, frames)
+		return
+	}
+
+	dir := app.config.Paths.Recordings
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("[APP_ERROR] Failed to create recordings directory: %v\n", err)
+		return
+	}
+	path := capturePath(dir, app.romPath, ".json", time.Now())
+	if app.MoviePlaying() {
+		frame := app.movieFrame
+		if err := app.BranchMovie(path); err != nil {
+			fmt.Printf("[APP_ERROR] Movie branch failed: %v\n", err)
+			return
+		}
+		fmt.Printf("🎬 Branched the movie at frame %d, recording to %s\n", frame, path)
+		return
+	}
+	if err := app.StartMovieRecording(path, false); err != nil {
+		fmt.Printf("[APP_ERROR] Movie recording failed: %v\n", err)
+		return
+	}
+	fmt.Printf("🎬 Recording a movie to %s\n", path)
+}
+
+// applyMovie applies the movie's controller states for the frame about to be
+// emulated, or records the player's
+func (app *Application) applyMovie() {
+	if app.movie == nil || app.bus == nil {
+		return
+	}
+	if app.movieRecording {
+		buttons := [input.ScriptPlayers][8]bool{app.lastController1State, app.lastController2State,
+			app.lastController34State[0], app.lastController34State[1]}
+		app.movie.Record(buttons)
+		// Set all four, so the console plays exactly what is recorded
+		for player, state := range buttons {
+			app.bus.SetControllerButtons(player+1, state)
+		}
+		app.movieFrame++
+		return
+	}
+	if app.movieFrame >= app.movie.Length() {
+		// Release the movie's buttons and hand control back to the player
+		for player := 1; player <= input.ScriptPlayers; player++ {
+			app.bus.SetControllerButtons(player, [8]bool{})
+		}
+		app.lastController1State = [8]bool{}
+		app.lastController2State = [8]bool{}
+		app.lastController34State = [2][8]bool{}
+		app.movie = nil
+		fmt.Println("🎬 Movie finished, controls returned to the player")
+		return
+	}
+	for player, buttons := range app.movie.Buttons(app.movieFrame) {
+		app.bus.SetControllerButtons(player+1, buttons)
+	}
+	app.movieFrame++
+}
+
+// finishMovieRecording saves the movie being recorded, when the application
+// closes or netplay takes the controllers
+func (app *Application) finishMovieRecording() {
+	if !app.movieRecording {
+		return
+	}
+	path := app.moviePath
+	if err := app.StopMovieRecording(); err != nil {
+		fmt.Printf("[APP_ERROR] Movie recording cleanup error: %v\n", err)
+		return
+	}
+	fmt.Printf("🎬 Movie saved: %s\n", path)
+}
@@ -0,0 +1,489 @@
+// Package app provides named save states, kept apart from the numbered slots
+// under a name of the player's choosing ("before boss"), and their export to
+// and import from standalone files that carry the ROM's checksum.
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gones/internal/bus"
+)
+
+const (
+	// maxStateNameLength is the longest state name, in characters, so it
+	// fits a menu line
+	maxStateNameLength = 32
+
+	// stateExportDirName is the directory in the states directory that
+	// exports are written to and the menu imports from
+	stateExportDirName = "exports"
+)
+
+// normalizeStateName trims a state name and checks it can be shown and saved
+func normalizeStateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("state name is empty")
+	}
+	if utf8.RuneCountInString(name) > maxStateNameLength {
+		return "", fmt.Errorf("state name is longer than %d characters", maxStateNameLength)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return "", errors.New("state name has control characters")
+		}
+	}
+	return name, nil
+}
+
+// stateNameSlug returns the part of a file name standing for a state name:
+// its ASCII letters and digits, lowered, and a short hash of the name, so
+// names differing only in case or punctuation get files of their own
+func stateNameSlug(name string) string {
+	var slug strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			slug.WriteRune(r)
+		case slug.Len() > 0 && !strings.HasSuffix(slug.String(), "-"):
+			slug.WriteByte('-')
+		}
+	}
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:4])
+	if text := strings.TrimSuffix(slug.String(), "-"); text != "" {
+		return text + "-" + hash
+	}
+	return hash
+}
+
+// namedStatePrefix returns the start of the file names of a ROM's named states
+func namedStatePrefix(romPath string) string {
+	romName := filepath.Base(romPath)
+	return romName[:len(romName)-len(filepath.Ext(romName))] + "_named_"
+}
+
+// getNamedStateFilePath generates the file path for a named state
+func (sm *StateManager) getNamedStateFilePath(name string, romPath string) string {
+	return filepath.Join(sm.saveDirectory, namedStatePrefix(romPath)+stateNameSlug(name)+".save")
+}
+
+// SaveNamedState saves the current state under name, replacing the state of
+// that name if there is one
+func (sm *StateManager) SaveNamedState(bus *bus.Bus, name string, romPath string, playTime time.Duration) error {
+	if !sm.initialized {
+		return fmt.Errorf("state manager not initialized")
+	}
+
+	if bus == nil {
+		return fmt.Errorf("bus cannot be nil")
+	}
+
+	name, err := normalizeStateName(name)
+	if err != nil {
+		return err
+	}
+
+	saveState, err := sm.captureState(bus, -1, romPath, playTime, name)
+	if err != nil {
+		return fmt.Errorf("failed to capture state: %v", err)
+	}
+	saveState.Name = name
+
+	if err := sm.saveToFile(saveState, sm.getNamedStateFilePath(name, romPath)); err != nil {
+		return fmt.Errorf("failed to save state: %v", err)
+	}
+
+	return nil
+}
+
+// loadNamedStateFile reads the state saved under name and checks it is for the ROM
+func (sm *StateManager) loadNamedStateFile(name string, romPath string) (*SaveState, error) {
+	filePath := sm.getNamedStateFilePath(strings.TrimSpace(name), romPath)
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("save state %q not found", name)
+	}
+
+	saveState, err := sm.loadFromFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %v", err)
+	}
+
+	if err := sm.validateSaveState(saveState, romPath); err != nil {
+		return nil, fmt.Errorf("invalid save state: %v", err)
+	}
+
+	return saveState, nil
+}
+
+// LoadNamedState loads the state saved under name
+func (sm *StateManager) LoadNamedState(bus *bus.Bus, name string, romPath string) error {
+	if !sm.initialized {
+		return fmt.Errorf("state manager not initialized")
+	}
+
+	if bus == nil {
+		return fmt.Errorf("bus cannot be nil")
+	}
+
+	saveState, err := sm.loadNamedStateFile(name, romPath)
+	if err != nil {
+		return err
+	}
+
+	if err := sm.restoreState(bus, saveState); err != nil {
+		return fmt.Errorf("failed to restore state: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteNamedState deletes the state saved under name
+func (sm *StateManager) DeleteNamedState(name string, romPath string) error {
+	if !sm.initialized {
+		return fmt.Errorf("state manager not initialized")
+	}
+
+	filePath := sm.getNamedStateFilePath(strings.TrimSpace(name), romPath)
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("save state %q not found", name)
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("failed to delete save state: %v", err)
+	}
+
+	return nil
+}
+
+// HasNamedState checks if a state is saved under name
+func (sm *StateManager) HasNamedState(name string, romPath string) bool {
+	_, err := os.Stat(sm.getNamedStateFilePath(strings.TrimSpace(name), romPath))
+	return err == nil
+}
+
+// GetNamedStateInfo returns information about the state saved under name
+func (sm *StateManager) GetNamedStateInfo(name string, romPath string) StateSlotInfo {
+	return sm.readSlotInfo(-1, sm.getNamedStateFilePath(strings.TrimSpace(name), romPath))
+}
+
+// GetNamedStates returns information about a ROM's named states, newest first
+func (sm *StateManager) GetNamedStates(romPath string) []StateSlotInfo {
+	return sm.readStateDirectory(sm.saveDirectory, namedStatePrefix(romPath), "")
+}
+
+// readStateDirectory returns information about the state files in dir whose
+// names start with prefix, newest first. States without a name are left
+// out, as are states for another ROM when checksum is given.
+func (sm *StateManager) readStateDirectory(dir, prefix, checksum string) []StateSlotInfo {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var states []StateSlotInfo
+	for _, file := range files {
+		fileName := file.Name()
+		if file.IsDir() || !strings.HasPrefix(fileName, prefix) || filepath.Ext(fileName) != ".save" {
+			continue
+		}
+		info := sm.readSlotInfo(-1, filepath.Join(dir, fileName))
+		if prefix == "" && info.Name == "" {
+			// Exports of the running game have no name; offer them by file name
+			info.Name = strings.TrimSuffix(fileName, ".save")
+		}
+		if info.Name == "" || (checksum != "" && info.ROMChecksum != checksum) {
+			continue
+		}
+		states = append(states, info)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Timestamp.After(states[j].Timestamp)
+	})
+	return states
+}
+
+// ExportNamedState writes the state saved under name to a standalone file,
+// embedding the ROM's checksum for ImportState to check
+func (sm *StateManager) ExportNamedState(name string, filePath string, romPath string) error {
+	saveState, err := sm.loadNamedStateFile(name, romPath)
+	if err != nil {
+		return err
+	}
+
+	// States saved before the checksum was kept, or matched by path, get
+	// the checksum of the ROM they were loaded with
+	saveState.ROMChecksum = sm.romChecksum
+
+	return sm.saveToFile(saveState, filePath)
+}
+
+// ImportNamedState copies a standalone state file into the ROM's named
+// states, under the name it was exported with or else its file name, and
+// returns that name. A number is added to names already taken.
+func (sm *StateManager) ImportNamedState(filePath string, romPath string) (string, error) {
+	if !sm.initialized {
+		return "", fmt.Errorf("state manager not initialized")
+	}
+
+	saveState, err := sm.loadFromFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to import state: %v", err)
+	}
+
+	if err := sm.validateImportedState(saveState); err != nil {
+		return "", fmt.Errorf("invalid imported state: %v", err)
+	}
+
+	base := saveState.Name
+	if base == "" {
+		base = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	}
+	base = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, base))
+	if runes := []rune(base); len(runes) > maxStateNameLength {
+		base = strings.TrimSpace(string(runes[:maxStateNameLength]))
+	}
+	if base == "" {
+		base = "IMPORTED"
+	}
+
+	name := base
+	for n := 2; sm.HasNamedState(name, romPath); n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		runes := []rune(base)
+		name = string(runes[:min(len(runes), maxStateNameLength-len(suffix))]) + suffix
+	}
+
+	// Slots match states by the ROM's path as well, so the path on the
+	// machine the state came from is replaced with this one
+	saveState.Name = name
+	saveState.ROMPath = romPath
+	saveState.SlotNumber = -1
+	if err := sm.saveToFile(saveState, sm.getNamedStateFilePath(name, romPath)); err != nil {
+		return "", fmt.Errorf("failed to save state: %v", err)
+	}
+
+	return name, nil
+}
+
+// GetImportableStates returns information about the state files in dir
+// that are for the loaded ROM, newest first
+func (sm *StateManager) GetImportableStates(dir string) []StateSlotInfo {
+	return sm.readStateDirectory(dir, "", sm.romChecksum)
+}
+
+// stateExportFileName returns the file name an export of a ROM's state is
+// written to, with the characters file systems refuse replaced
+func stateExportFileName(romPath, name string) string {
+	romName := filepath.Base(romPath)
+	fileName := fmt.Sprintf("%s - %s.save", romName[:len(romName)-len(filepath.Ext(romName))], name)
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, fileName)
+}
+
+// stateExportDir returns the directory exported states are written to
+func (app *Application) stateExportDir() string {
+	return filepath.Join(app.states.GetSaveDirectory(), stateExportDirName)
+}
+
+// SaveNamedState saves the current emulator state under name
+func (app *Application) SaveNamedState(name string) error {
+	if app.cartridge == nil {
+		return errors.New("no ROM loaded")
+	}
+
+	return app.states.SaveNamedState(app.bus, name, app.romPath, app.playTime)
+}
+
+// LoadNamedState loads the emulator state saved under name
+func (app *Application) LoadNamedState(name string) error {
+	if app.cartridge == nil {
+		return errors.New("no ROM loaded")
+	}
+	if app.netplay != nil {
+		return errNetplayState
+	}
+
+	if err := app.states.LoadNamedState(app.bus, name, app.romPath); err != nil {
+		return err
+	}
+
+	// Continue counting play time from where the state was saved
+	if info := app.states.GetNamedStateInfo(name, app.romPath); info.Used {
+		app.playTime = info.PlayTime
+		app.lastAutoSavePlayTime = app.playTime
+	}
+
+	return nil
+}
+
+// DeleteNamedState deletes the state saved under name
+func (app *Application) DeleteNamedState(name string) error {
+	if app.cartridge == nil {
+		return errors.New("no ROM loaded")
+	}
+
+	return app.states.DeleteNamedState(name, app.romPath)
+}
+
+// NamedStates returns the loaded ROM's named states, newest first
+func (app *Application) NamedStates() []StateSlotInfo {
+	if app.cartridge == nil {
+		return nil
+	}
+	return app.states.GetNamedStates(app.romPath)
+}
+
+// ExportState writes the state saved under name, or the current state when
+// name is empty, to a standalone file and returns its path. Without a path
+// it goes in the exports directory of the states directory.
+func (app *Application) ExportState(name, path string) (string, error) {
+	if app.cartridge == nil {
+		return "", errors.New("no ROM loaded")
+	}
+
+	if path == "" {
+		fileName := name
+		if fileName == "" {
+			fileName = time.Now().Format("2006-01-02 15-04-05")
+		}
+		path = filepath.Join(app.stateExportDir(), stateExportFileName(app.romPath, strings.TrimSpace(fileName)))
+	}
+
+	if name == "" {
+		return path, app.states.ExportState(app.bus, path, app.romPath)
+	}
+	return path, app.states.ExportNamedState(name, path, app.romPath)
+}
+
+// ImportNamedState adds a standalone state file to the loaded ROM's named
+// states and returns the name it was given
+func (app *Application) ImportNamedState(path string) (string, error) {
+	if app.cartridge == nil {
+		return "", errors.New("no ROM loaded")
+	}
+
+	return app.states.ImportNamedState(path, app.romPath)
+}
+
+// nextStateName returns a name for a state saved from the menu, which
+// cannot type one: the first "STATE n" not taken
+func (app *Application) nextStateName() string {
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("STATE %d", n)
+		if !app.states.HasNamedState(name, app.romPath) {
+			return name
+		}
+	}
+}
+
+// namedStatesPage builds the menu page listing the ROM's named states
+func (app *Application) namedStatesPage() *menuPage {
+	page := &menuPage{title: "NAMED STATES"}
+	page.items = append(page.items,
+		menuItem{label: "SAVE NEW STATE", action: func() {
+			name := app.nextStateName()
+			if err := app.SaveNamedState(name); err != nil {
+				fmt.Printf("[APP_ERROR] Failed to save state: %v\n", err)
+				app.menu.SetMessage("SAVE FAILED")
+				return
+			}
+			fmt.Printf("💾 State saved: %s\n", name)
+			app.menu.Replace(app.namedStatesPage())
+		}},
+		menuItem{label: "IMPORT", action: func() { app.menu.Push(app.importStatesPage()) }},
+	)
+
+	for _, info := range app.NamedStates() {
+		page.items = append(page.items, menuItem{
+			label:  info.Name,
+			value:  func() string { return info.Timestamp.Format("01-02 15:04") },
+			action: func() { app.menu.Push(app.namedStatePage(info.Name)) },
+		})
+	}
+	return page
+}
+
+// namedStatePage builds the menu page of one named state
+func (app *Application) namedStatePage(name string) *menuPage {
+	return &menuPage{
+		title: "STATE - " + name,
+		items: []menuItem{
+			{label: "LOAD", action: func() {
+				if err := app.LoadNamedState(name); err != nil {
+					fmt.Printf("[APP_ERROR] Failed to load state %q: %v\n", name, err)
+					app.menu.SetMessage("LOAD FAILED")
+					return
+				}
+				fmt.Printf("💾 State loaded: %s\n", name)
+				app.HideMenu()
+			}},
+			{label: "EXPORT", action: func() {
+				path, err := app.ExportState(name, "")
+				if err != nil {
+					fmt.Printf("[APP_ERROR] Failed to export state %q: %v\n", name, err)
+					app.menu.SetMessage("EXPORT FAILED")
+					return
+				}
+				fmt.Printf("💾 State exported: %s\n", path)
+				app.menu.SetMessage("EXPORTED TO " + filepath.Base(path))
+			}},
+			{label: "DELETE", action: func() {
+				if err := app.DeleteNamedState(name); err != nil {
+					fmt.Printf("[APP_ERROR] Failed to delete state %q: %v\n", name, err)
+					app.menu.SetMessage("DELETE FAILED")
+					return
+				}
+				fmt.Printf("🗑️ State deleted: %s\n", name)
+				app.menu.Back()
+				app.menu.Replace(app.namedStatesPage())
+			}},
+		},
+	}
+}
+
+// importStatesPage builds the menu page listing the exported states for
+// the ROM, which importing adds to the named states
+func (app *Application) importStatesPage() *menuPage {
+	dir := app.stateExportDir()
+	page := &menuPage{title: "IMPORT - " + stateExportDirName}
+
+	for _, info := range app.states.GetImportableStates(dir) {
+		path := info.FilePath
+		page.items = append(page.items, menuItem{label: info.Name, action: func() {
+			name, err := app.ImportNamedState(path)
+			if err != nil {
+				fmt.Printf("[APP_ERROR] Failed to import state: %v\n", err)
+				app.menu.SetMessage("IMPORT FAILED: " + filepath.Base(path))
+				return
+			}
+			fmt.Printf("💾 State imported: %s\n", name)
+			app.menu.Back()
+			app.menu.Replace(app.namedStatesPage())
+		}})
+	}
+	if len(page.items) == 0 {
+		page.items = append(page.items, menuItem{label: "NO STATES FOR THIS ROM"})
+	}
+	return page
+}
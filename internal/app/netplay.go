@@ -0,0 +1,312 @@
+// Package app provides netplay in the GUI: hosting and joining sessions, the
+// machine they drive, and their status indicator.
+package app
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gones/internal/graphics"
+	"gones/internal/input"
+	"gones/internal/netplay"
+)
+
+// errNetplayState refuses to load states during netplay, which would leave
+// the two sides running different games
+var errNetplayState = errors.New("states cannot be loaded during netplay")
+
+// netplayMachine is the emulator as driven by a netplay session
+type netplayMachine struct {
+	app *Application
+}
+
+// PowerOn reloads the ROM, so both sides start from power on
+func (m netplayMachine) PowerOn() error {
+	return m.app.LoadROM(m.app.romPath)
+}
+
+func (m netplayMachine) SaveState() ([]byte, error) {
+	return m.app.bus.SaveStateToBytes()
+}
+
+func (m netplayMachine) LoadState(data []byte) error {
+	return m.app.bus.LoadStateFromBytes(data)
+}
+
+// RunFrame runs a frame with the players' buttons. Frames run again after a
+// rollback replace the previous frame's picture and audio.
+func (m netplayMachine) RunFrame(buttons [netplay.Players][8]bool) error {
+	for player, pressed := range buttons {
+		m.app.bus.SetControllerButtons(player+1, pressed)
+	}
+	if err := m.app.emulator.Update(); err != nil {
+		return err
+	}
+	return m.app.CheckJam()
+}
+
+// netplayOptions returns the session options from the config
+func (app *Application) netplayOptions() netplay.Options {
+	return netplay.Options{
+		InputDelay:   app.config.Netplay.InputDelay,
+		MaxRollback:  app.config.Netplay.MaxRollback,
+		HashInterval: app.config.Netplay.HashInterval,
+	}
+}
+
+// netplayROMHash returns the SHA-256 of the loaded ROM, which both sides
+// need to have
+func (app *Application) netplayROMHash() ([32]byte, error) {
+	var hash [32]byte
+	if app.cartridge == nil {
+		return hash, errors.New("no ROM loaded")
+	}
+	if app.netplay != nil {
+		return hash, errors.New("a netplay session is already open")
+	}
+	// Hashed as loaded, so both sides need the same patch too
+	_, err := hex.Decode(hash[:], []byte(app.romHash))
+	return hash, err
+}
+
+// HostNetplay hosts a netplay session of the loaded ROM on a UDP address
+// such as ":7845". The host is player 1; the game restarts when player 2
+// joins.
+func (app *Application) HostNetplay(address string) error {
+	hash, err := app.netplayROMHash()
+	if err != nil {
+		return err
+	}
+	session, err := netplay.Host(address, hash, app.netplayOptions())
+	if err != nil {
+		return err
+	}
+	app.startNetplay(session)
+	fmt.Printf("🌐 Hosting netplay on %s, waiting for player 2\n", session.Status().Address)
+	return nil
+}
+
+// JoinNetplay joins the netplay session hosted on an address such as
+// "192.168.1.20:7845" as player 2. The host's ROM has to be loaded.
+func (app *Application) JoinNetplay(address string) error {
+	hash, err := app.netplayROMHash()
+	if err != nil {
+		return err
+	}
+	session, err := netplay.Join(address, hash)
+	if err != nil {
+		return err
+	}
+	app.startNetplay(session)
+	fmt.Printf("🌐 Joining netplay at %s\n", session.Status().Address)
+	return nil
+}
+
+// startNetplay hands the controllers to a session. Movies, cheats and speed
+// changes would make the two sides differ, so they are off until it ends.
+func (app *Application) startNetplay(session *netplay.Session) {
+	app.netplay = session
+	app.netplayConnected = false
+	app.netplayDesync = -1
+	app.finishMovieRecording()
+	app.movie = nil
+	app.SetSpeed(1)
+	app.applyCheats()
+	app.updateSpriteLimit()
+	if app.cartridge.HasBattery() {
+		fmt.Println("[APP_WARNING] This game has battery saves: they have to match on both sides to stay in sync")
+	}
+}
+
+// StopNetplay leaves the netplay session, if any, and hands the controllers
+// back to the player
+func (app *Application) StopNetplay() {
+	if app.netplay == nil {
+		return
+	}
+	app.netplay.Close()
+	app.netplay = nil
+	if app.bus != nil {
+		for player := 1; player <= input.ScriptPlayers; player++ {
+			app.bus.SetControllerButtons(player, [8]bool{})
+		}
+	}
+	app.lastController1State = [8]bool{}
+	app.lastController2State = [8]bool{}
+	app.applyCheats()
+	app.updateSpriteLimit()
+	fmt.Println("🌐 Netplay session closed")
+}
+
+// NetplayStatus describes the netplay session; ok is false when there is none
+func (app *Application) NetplayStatus() (status netplay.Status, ok bool) {
+	if app.netplay == nil {
+		return netplay.Status{}, false
+	}
+	return app.netplay.Status(), true
+}
+
+// updateNetplay lets the session run the next frame with the local
+// player's buttons, and reports whether it ran one. The session ends on
+// errors, such as the other player leaving.
+func (app *Application) updateNetplay() bool {
+	ran, err := app.netplay.Update(netplayMachine{app}, app.lastController1State)
+	if err != nil {
+		fmt.Printf("🌐 Netplay ended: %v\n", err)
+		app.StopNetplay()
+		return false
+	}
+
+	status := app.netplay.Status()
+	if status.Connected && !app.netplayConnected {
+		app.netplayConnected = true
+		fmt.Printf("🌐 Netplay started: you are player %d (input delay %d, %s)\n",
+			status.Player, status.Options.InputDelay, rollbackName(status.Options.MaxRollback))
+	}
+	if status.Desync >= 0 && app.netplayDesync < 0 {
+		app.netplayDesync = status.Desync
+		fmt.Printf("[APP_WARNING] Netplay desync: the game differs from the other player's since frame %d\n", status.Desync)
+	}
+	return ran
+}
+
+// rollbackName describes a rollback setting
+func rollbackName(frames int) string {
+	if frames == 0 {
+		return "lockstep"
+	}
+	return fmt.Sprintf("rollback %d", frames)
+}
+
+// netplayMenuPage builds the netplay page of the pause menu
+func (app *Application) netplayMenuPage() *menuPage {
+	settings := &app.config.Netplay
+	page := &menuPage{title: "NETPLAY", onChange: app.saveSettings}
+	if status, ok := app.NetplayStatus(); ok {
+		page.items = append(page.items,
+			menuItem{label: "STATUS", value: func() string { return netplayStatusText(status) }},
+			menuItem{label: "DISCONNECT", action: func() {
+				app.StopNetplay()
+				app.menu.Back()
+			}},
+		)
+		return page
+	}
+
+	page.items = append(page.items,
+		menuItem{label: "HOST", value: func() string { return fmt.Sprintf("PORT %d", settings.Port) }, action: func() {
+			if err := app.HostNetplay(fmt.Sprintf(":%d", settings.Port)); err != nil {
+				app.menu.SetMessage(strings.ToUpper(err.Error()))
+				return
+			}
+			app.HideMenu()
+		}},
+		menuItem{label: "JOIN", value: func() string { return settings.JoinAddress }, action: func() {
+			if settings.JoinAddress == "" {
+				app.menu.SetMessage("ENTER THE HOST'S ADDRESS FIRST")
+				return
+			}
+			if err := app.JoinNetplay(settings.JoinAddress); err != nil {
+				app.menu.SetMessage(strings.ToUpper(err.Error()))
+				return
+			}
+			app.HideMenu()
+		}},
+		menuItem{label: "ADDRESS", value: func() string {
+			if app.netplayTyping {
+				return settings.JoinAddress + "_"
+			}
+			return settings.JoinAddress
+		}, action: app.startNetplayAddressEdit},
+		menuItem{
+			label:  "INPUT DELAY",
+			value:  func() string { return fmt.Sprint(settings.InputDelay) },
+			adjust: func(delta int) { settings.InputDelay = max(0, min(netplay.MaxInputDelay, settings.InputDelay+delta)) },
+		},
+		menuItem{
+			label: "ROLLBACK",
+			value: func() string { return strings.ToUpper(rollbackName(settings.MaxRollback)) },
+			adjust: func(delta int) {
+				settings.MaxRollback = max(0, min(netplay.MaxMaxRollback, settings.MaxRollback+delta))
+			},
+		},
+	)
+	return page
+}
+
+// netplayStatusText describes a session for the menu
+func netplayStatusText(status netplay.Status) string {
+	switch {
+	case !status.Connected && status.Hosting:
+		return "WAITING FOR PLAYER 2"
+	case !status.Connected:
+		return "CONNECTING"
+	case status.Desync >= 0:
+		return fmt.Sprintf("P%d DESYNC AT %d", status.Player, status.Desync)
+	default:
+		return fmt.Sprintf("P%d FRAME %d", status.Player, status.Frame)
+	}
+}
+
+// startNetplayAddressEdit types the host's address into the menu: digits
+// and dots, Backspace to erase, and any other key to stop
+func (app *Application) startNetplayAddressEdit() {
+	window, ok := app.window.(graphics.RebindableWindow)
+	if !ok {
+		app.menu.SetMessage("SET NETPLAY.JOIN_ADDRESS IN THE CONFIG")
+		return
+	}
+	app.netplayTyping = true
+	window.CaptureNextInput(app.onNetplayAddressInput)
+}
+
+// onNetplayAddressInput takes a key typed into the address
+func (app *Application) onNetplayAddressInput(captured graphics.CapturedInput) {
+	address := &app.config.Netplay.JoinAddress
+	name := strings.ToLower(captured.Name)
+	digit, isDigit := hexDigitKey(captured)
+	switch {
+	case captured.Gamepad:
+		app.netplayTyping = false
+	case isDigit && digit < 10:
+		*address += fmt.Sprint(digit)
+	case name == "period" || name == "." || name == "numpaddecimal" || name == "kpdecimal":
+		*address += "."
+	case name == "backspace":
+		if *address != "" {
+			*address = (*address)[:len(*address)-1]
+		}
+	default:
+		app.netplayTyping = false
+	}
+	if !app.netplayTyping {
+		app.saveSettings()
+		return
+	}
+	if window, ok := app.window.(graphics.RebindableWindow); ok {
+		window.CaptureNextInput(app.onNetplayAddressInput)
+	}
+}
+
+// renderNetplayIndicator shows the session's state in the bottom-left
+// corner: waiting, the player played, or a desync in red
+func (app *Application) renderNetplayIndicator(frameBuffer *[256 * 240]uint32) {
+	status, ok := app.NetplayStatus()
+	if !ok || app.IsMenuVisible() {
+		return
+	}
+	color := graphics.OverlayColorWhite
+	switch {
+	case !status.Connected:
+		color = graphics.OverlayColorYellow
+	case status.Desync >= 0:
+		color = graphics.OverlayColorRed
+	}
+	text := "NET " + netplayStatusText(status)
+	if status.Connected && status.Desync < 0 {
+		text = fmt.Sprintf("NET P%d", status.Player)
+	}
+	graphics.DrawTextShadowed(frameBuffer, 6, graphics.OverlayHeight-14, text, color)
+}
@@ -0,0 +1,78 @@
+// Package app provides the frame pacing of the standard loop, which waits
+// for a target time per frame rather than a frame time per tick, so sleep
+// overshoot and slow ticks do not add up over a session.
+package app
+
+import (
+	"runtime"
+	"time"
+)
+
+// pacerSpin is how long before a frame is due the pacer stops sleeping and
+// yields instead: the OS can wake a sleep up to a timer tick late
+const pacerSpin = time.Millisecond
+
+// pacerMaxLag is how many frames the pacer can fall behind (a breakpoint, a
+// window drag, a slow host) before it starts a new timeline instead of
+// running the missed ticks back to back
+const pacerMaxLag = 4
+
+// framePacer paces the ticks of the standard loop against a timeline: the
+// nth tick since the timeline started ends n frame times after it began.
+// A tick that ends late leaves the next one less time, and one that sleeps
+// too long is made up by the next, so the frame rate averages out to the
+// region's (59.9397 Hz on NTSC) however long the session.
+type framePacer struct {
+	start     time.Time     // When the first tick of the timeline began; zero for none
+	frameTime time.Duration // Of the timeline
+	ticks     int64         // Ended since start
+
+	clock pacerClock // Slept on; the system clock when nil
+}
+
+// pacerClock is the clock the pacer sleeps on
+type pacerClock interface {
+	// SleepUntil returns at t, or at once if t has passed
+	SleepUntil(t time.Time)
+}
+
+// Wait sleeps until the tick that began at tickStart is due to end. A new
+// timeline starts from tickStart after Restart, when the frame time changes
+// (a region switch) or when the pacer has fallen too far behind.
+func (p *framePacer) Wait(tickStart time.Time, frameTime time.Duration) {
+	if p.start.IsZero() || frameTime != p.frameTime ||
+		tickStart.Sub(p.due()) > pacerMaxLag*frameTime {
+		p.start, p.frameTime, p.ticks = tickStart, frameTime, 0
+	}
+	p.ticks++
+	if p.clock != nil {
+		p.clock.SleepUntil(p.due())
+	} else {
+		systemClock{}.SleepUntil(p.due())
+	}
+}
+
+// Restart drops the timeline, for ticks paced by something else (the audio
+// clock) or not at all (unthrottled runs)
+func (p *framePacer) Restart() {
+	p.start = time.Time{}
+}
+
+// due returns when the last tick counted is due to end
+func (p *framePacer) due() time.Time {
+	return p.start.Add(time.Duration(p.ticks) * p.frameTime)
+}
+
+// systemClock is the system's clock
+type systemClock struct{}
+
+// SleepUntil waits until t, sleeping until shortly before and yielding the
+// rest of the way
+func (systemClock) SleepUntil(t time.Time) {
+	if d := time.Until(t) - pacerSpin; d > 0 {
+		time.Sleep(d)
+	}
+	for time.Now().Before(t) {
+		runtime.Gosched()
+	}
+}
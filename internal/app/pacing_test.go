@@ -0,0 +1,127 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a pacer clock whose time only moves when the test runs a tick
+// or the pacer sleeps
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) SleepUntil(t time.Time) {
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+// tick runs a tick taking work, paced at frameTime, and returns when it
+// ended
+func (c *fakeClock) tick(p *framePacer, work, frameTime time.Duration) time.Time {
+	tickStart := c.now
+	c.now = c.now.Add(work)
+	p.Wait(tickStart, frameTime)
+	return c.now
+}
+
+func newTestPacer() (*framePacer, *fakeClock, time.Time) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	return &framePacer{clock: clock}, clock, start
+}
+
+// ntscFrameTime is an NTSC frame (60.0988 Hz), which is not a whole number
+// of milliseconds
+const ntscFrameTime = 16639267 * time.Nanosecond
+
+func TestFramePacer_Steady(t *testing.T) {
+	tests := []struct {
+		name string
+		work func(n int) time.Duration
+	}{
+		{"idle", func(int) time.Duration { return 0 }},
+		{"busy", func(int) time.Duration { return 12 * time.Millisecond }},
+		{"uneven", func(n int) time.Duration { return time.Duration(n%7) * 2 * time.Millisecond }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, clock, start := newTestPacer()
+			for n := 1; n <= 3600; n++ {
+				// Every tick ends on the timeline: no drift over a minute
+				end := clock.tick(p, tt.work(n), ntscFrameTime)
+				if want := start.Add(time.Duration(n) * ntscFrameTime); !end.Equal(want) {
+					t.Fatalf("tick %d ended at %v, want %v", n, end.Sub(start), want.Sub(start))
+				}
+			}
+		})
+	}
+}
+
+func TestFramePacer_CatchesUp(t *testing.T) {
+	const frameTime = 16 * time.Millisecond
+	p, clock, start := newTestPacer()
+	clock.tick(p, time.Millisecond, frameTime)
+	clock.tick(p, time.Millisecond, frameTime)
+
+	// Tick 3 runs 2.5 frames long: the next ticks run without sleeping
+	// until they are back on the timeline, which keeps the frame rate
+	if end := clock.tick(p, 40*time.Millisecond, frameTime); end.Sub(start) != 72*time.Millisecond {
+		t.Fatalf("slow tick ended at %v, want 72ms", end.Sub(start))
+	}
+	for _, want := range []time.Duration{77, 82, 96, 112, 128} {
+		if end := clock.tick(p, 5*time.Millisecond, frameTime); end.Sub(start) != want*time.Millisecond {
+			t.Errorf("tick ended at %v, want %v", end.Sub(start), want*time.Millisecond)
+		}
+	}
+}
+
+func TestFramePacer_RestartsAfterLongStall(t *testing.T) {
+	const frameTime = 16 * time.Millisecond
+	p, clock, start := newTestPacer()
+	clock.tick(p, time.Millisecond, frameTime)
+
+	// A breakpoint holds tick 2 for a second: the ticks missed are dropped
+	// rather than run back to back
+	stalled := clock.tick(p, time.Second, frameTime)
+	for n := 1; n <= 3; n++ {
+		end := clock.tick(p, time.Millisecond, frameTime)
+		if want := stalled.Add(time.Duration(n) * frameTime); !end.Equal(want) {
+			t.Errorf("tick %d after the stall ended at %v, want %v", n, end.Sub(start), want.Sub(start))
+		}
+	}
+}
+
+func TestFramePacer_FrameTimeChange(t *testing.T) {
+	const ntsc, pal = 16 * time.Millisecond, 20 * time.Millisecond
+	p, clock, _ := newTestPacer()
+	for n := 0; n < 10; n++ {
+		clock.tick(p, time.Millisecond, ntsc)
+	}
+
+	// A region switch starts a timeline at the new frame time from the tick
+	// it happens on
+	switched := clock.now
+	for n := 1; n <= 10; n++ {
+		end := clock.tick(p, time.Millisecond, pal)
+		if want := switched.Add(time.Duration(n) * pal); !end.Equal(want) {
+			t.Fatalf("tick %d after the switch ended at %v after it, want %v", n, end.Sub(switched), want.Sub(switched))
+		}
+	}
+}
+
+func TestFramePacer_Restart(t *testing.T) {
+	const frameTime = 16 * time.Millisecond
+	p, clock, _ := newTestPacer()
+	clock.tick(p, time.Millisecond, frameTime)
+
+	// Ticks paced by something else move the clock on; the timeline starts
+	// again from the next paced tick rather than catching up on them
+	p.Restart()
+	clock.now = clock.now.Add(45 * time.Millisecond)
+	resumed := clock.now
+	if end := clock.tick(p, time.Millisecond, frameTime); !end.Equal(resumed.Add(frameTime)) {
+		t.Errorf("tick after Restart ended %v after it resumed, want %v", end.Sub(resumed), frameTime)
+	}
+}
@@ -0,0 +1,74 @@
+// Package app provides the IPS and BPS patches applied to ROMs as they load,
+// so translations and ROM hacks play without patching the ROM file.
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gones/internal/cartridge"
+	"gones/internal/patch"
+)
+
+// patchExtensions are the extensions of the patches found next to a ROM, in
+// the order they are looked for
+var patchExtensions = []string{".ips", ".bps"}
+
+// SetPatch applies an IPS or BPS patch to romPath whenever it loads, instead
+// of a patch found next to it
+func (app *Application) SetPatch(romPath, patchPath string) {
+	app.patchROM = romPath
+	app.patchPath = patchPath
+}
+
+// findPatch returns the patch to apply to a ROM: the one set by SetPatch,
+// else a .ips or .bps file of the same name next to it, such as game.ips for
+// game.nes or game.zip. It returns "" when there is none.
+func (app *Application) findPatch(romPath string) string {
+	if app.patchPath != "" && romPath == app.patchROM {
+		return app.patchPath
+	}
+	base := strings.TrimSuffix(romPath, filepath.Ext(romPath))
+	for _, ext := range patchExtensions {
+		for _, path := range []string{base + ext, base + strings.ToUpper(ext)} {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// readROM reads a ROM file with its patch applied, and returns the patch it
+// applied, "" without one
+func (app *Application) readROM(romPath string) ([]byte, string, error) {
+	data, err := cartridge.ReadROM(romPath)
+	if err != nil {
+		return nil, "", err
+	}
+	patchPath := app.findPatch(romPath)
+	if patchPath == "" {
+		return data, "", nil
+	}
+
+	patchData, err := os.ReadFile(patchPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read patch: %v", err)
+	}
+	patched, err := patch.Apply(data, patchData)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %v", filepath.Base(patchPath), err)
+	}
+	return patched, patchPath, nil
+}
+
+// romDataHash returns the lowercase hex SHA-256 of a ROM image, as ROMHash
+// does for a file
+func romDataHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,160 @@
+// Package app provides the performance overlay, which shows the frame rate,
+// a graph of recent frame times split into input, emulation and rendering,
+// and the health of the audio queue over the picture.
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"gones/internal/graphics"
+)
+
+const (
+	// perfHistory is how many frames the graph covers, a pixel column each
+	perfHistory = 128
+
+	// perfGraphHeight is the height of the graph, a pixel per millisecond
+	perfGraphHeight = 34
+
+	// The panel sits top left, under the speed indicator
+	perfLeft  = 2
+	perfTop   = 16
+	perfWidth = perfHistory + 20
+
+	perfColorInput     = graphics.OverlayColorYellow
+	perfColorEmulation = graphics.OverlayColorGreen
+	perfColorRender    = 0x60A0FF
+)
+
+// perfSample is where the time of one frame of the main loop went
+type perfSample struct {
+	input, emulation, render time.Duration
+}
+
+// total returns the frame's processing time
+func (s perfSample) total() time.Duration {
+	return s.input + s.emulation + s.render
+}
+
+// perfOverlay keeps the recent frame times for the performance overlay
+type perfOverlay struct {
+	visible bool
+	samples [perfHistory]perfSample
+	next    int // Index the next sample goes at
+	count   int // Samples kept, up to perfHistory
+}
+
+// add records a frame's times, replacing the oldest
+func (p *perfOverlay) add(sample perfSample) {
+	p.samples[p.next] = sample
+	p.next = (p.next + 1) % perfHistory
+	p.count = min(p.count+1, perfHistory)
+}
+
+// sample returns the i-th of the kept samples, oldest first
+func (p *perfOverlay) sample(i int) perfSample {
+	return p.samples[(p.next-p.count+i+perfHistory)%perfHistory]
+}
+
+// TogglePerfOverlay handles the performance overlay hotkey
+func (app *Application) TogglePerfOverlay() {
+	app.perf.visible = !app.perf.visible
+	if app.perf.visible {
+		fmt.Println("📊 Performance overlay on (input yellow, emulation green, render blue)")
+	} else {
+		fmt.Println("📊 Performance overlay off")
+	}
+}
+
+// recordPerfSample adds the times of the frame just processed to the graph
+func (app *Application) recordPerfSample() {
+	app.perf.add(perfSample{app.inputTime, app.emulatorTime, app.renderTime})
+}
+
+// renderPerfOverlay draws the frame rate, the time of the last frame and
+// its parts, the audio queue and A/V drift, and the frame time graph with a
+// line at the time a frame has
+func (app *Application) renderPerfOverlay(frameBuffer *[256 * 240]uint32) {
+	if !app.perf.visible {
+		return
+	}
+	height := 4*graphics.LineHeight + perfGraphHeight + 6
+	graphics.DarkenRect(frameBuffer, perfLeft, perfTop, perfWidth, height, 2)
+	x, y := perfLeft+4, perfTop+4
+
+	var last perfSample
+	if app.perf.count > 0 {
+		last = app.perf.sample(app.perf.count - 1)
+	}
+	graphics.DrawTextShadowed(frameBuffer, x, y,
+		fmt.Sprintf("FPS %.1f FRAME %.1fMS", app.currentFPS, milliseconds(last.total())), graphics.OverlayColorWhite)
+	y += graphics.LineHeight
+	next := graphics.DrawTextShadowed(frameBuffer, x, y, fmt.Sprintf("IN %.1f", milliseconds(last.input)), perfColorInput)
+	next = graphics.DrawTextShadowed(frameBuffer, next+graphics.FontAdvance, y, fmt.Sprintf("EMU %.1f", milliseconds(last.emulation)), perfColorEmulation)
+	graphics.DrawTextShadowed(frameBuffer, next+graphics.FontAdvance, y, fmt.Sprintf("REN %.1f", milliseconds(last.render)), perfColorRender)
+	y += graphics.LineHeight
+	app.renderAudioHealth(frameBuffer, x, y)
+	y += 2 * graphics.LineHeight
+
+	// Frame time graph, parts stacked from the bottom
+	bottom := y + perfGraphHeight
+	for i := 0; i < app.perf.count; i++ {
+		sample := app.perf.sample(i)
+		top := bottom
+		for _, part := range []struct {
+			time  time.Duration
+			color uint32
+		}{
+			{sample.input, perfColorInput},
+			{sample.emulation, perfColorEmulation},
+			{sample.render, perfColorRender},
+		} {
+			pixels := min(int(milliseconds(part.time)+0.5), top-y)
+			top -= pixels
+			graphics.FillRect(frameBuffer, x+i, top, 1, pixels, part.color)
+		}
+	}
+	if app.emulator != nil {
+		target := int(milliseconds(app.emulator.GetTargetFrameTime()) + 0.5)
+		graphics.FillRect(frameBuffer, x, bottom-target, perfHistory, 1, graphics.OverlayColorGray)
+	}
+}
+
+// renderAudioHealth draws the audio queued against the latency aimed for,
+// the underruns and, below, the A/V drift and the frames dropped or
+// repeated to correct it
+func (app *Application) renderAudioHealth(frameBuffer *[256 * 240]uint32, x, y int) {
+	position, ok := app.window.(graphics.AudioPosition)
+	if !ok || !app.config.Audio.Enabled || app.bus == nil {
+		graphics.DrawTextShadowed(frameBuffer, x, y, "AUDIO OFF", graphics.OverlayColorGray)
+		return
+	}
+
+	queued := time.Duration(position.AudioQueued()) * time.Second / time.Duration(app.bus.APU.GetSampleRate())
+	latency := time.Duration(app.config.Audio.Latency) * time.Millisecond
+	color := graphics.OverlayColorGreen
+	switch {
+	case queued == 0:
+		color = graphics.OverlayColorRed
+	case queued < latency/2:
+		color = graphics.OverlayColorYellow
+	}
+	text := fmt.Sprintf("AUDIO %d/%dMS", queued.Milliseconds(), latency.Milliseconds())
+	if stats, ok := app.window.(graphics.AudioStats); ok {
+		text += fmt.Sprintf(" UNDER %d", stats.AudioUnderruns())
+	}
+	graphics.DrawTextShadowed(frameBuffer, x, y, text, color)
+
+	drift := "A/V --"
+	if app.avSync.ticks >= avSyncWarmup {
+		drift = fmt.Sprintf("A/V %+dMS", app.avSync.drift.Milliseconds())
+	}
+	graphics.DrawTextShadowed(frameBuffer, x, y+graphics.LineHeight,
+		fmt.Sprintf("%s DROP %d REP %d", drift, app.avSync.dropped, app.avSync.repeated), graphics.OverlayColorWhite)
+}
+
+// milliseconds returns a duration in milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
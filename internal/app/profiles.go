@@ -0,0 +1,146 @@
+// Package app provides per-game input profiles loaded from config/games.
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gones/internal/cartridge"
+	"gones/internal/library"
+)
+
+// GameProfile overrides input settings for one game. It is read from
+// <config dir>/games/<romhash>.json after the ROM is loaded, where romhash is
+// the SHA-256 of the ROM file in lowercase hex, or else from
+// <config dir>/games/<CRC32>.json, where CRC32 is the uppercase hex CRC32
+// of the ROM without its iNES header, as in No-Intro's database, so a
+// profile also fits other dumps of the game. Omitted fields keep the global setting.
+type GameProfile struct {
+	Name           string             `json:"name,omitempty"` // For reference only
+	Bindings       *GameInputBindings `json:"bindings,omitempty"`
+	AutofireRate   *int               `json:"autofire_rate,omitempty"`
+	EnableAutofire *bool              `json:"enable_autofire,omitempty"`
+	Port2Device    *string            `json:"port2_device,omitempty"`
+	FourScore      *bool              `json:"four_score,omitempty"`
+}
+
+// ROMHash returns the lowercase hex SHA-256 of a ROM file, as used for profile names
+func ROMHash(romPath string) (string, error) {
+	data, err := cartridge.ReadROM(romPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ROM: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// GetGameProfilePath returns the profile path for a ROM
+func (c *Config) GetGameProfilePath(romPath string) (string, error) {
+	hash, err := ROMHash(romPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.Paths.Config, "games", hash+".json"), nil
+}
+
+// LoadGameProfile reads the profile for a ROM. It returns nil without an error
+// when the game has no profile.
+func (c *Config) LoadGameProfile(romPath string) (*GameProfile, error) {
+	path, err := c.GetGameProfilePath(romPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		rom, readErr := cartridge.ReadROM(romPath)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read ROM: %v", readErr)
+		}
+		crc, _ := library.Hash(rom)
+		path = filepath.Join(c.Paths.Config, "games", crc+".json")
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read game profile: %v", err)
+	}
+
+	var profile GameProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse game profile %s: %v", path, err)
+	}
+	if err := profile.validate(); err != nil {
+		return nil, fmt.Errorf("invalid game profile %s: %v", path, err)
+	}
+	return &profile, nil
+}
+
+// validate checks the profile values
+func (p *GameProfile) validate() error {
+	if p.AutofireRate != nil && *p.AutofireRate <= 0 {
+		return fmt.Errorf("autofire_rate must be positive: %d", *p.AutofireRate)
+	}
+	if p.Port2Device != nil {
+		device := strings.ToLower(*p.Port2Device)
+		switch device {
+		case Port2Controller, Port2Zapper, Port2Arkanoid:
+			p.Port2Device = &device
+		default:
+			return fmt.Errorf("unknown port2_device: %s", *p.Port2Device)
+		}
+	}
+	return nil
+}
+
+// applyTo returns a copy of the input config with the profile overrides applied
+func (p *GameProfile) applyTo(c InputConfig) InputConfig {
+	if p == nil {
+		return c
+	}
+	if p.AutofireRate != nil {
+		c.AutofireRate = *p.AutofireRate
+	}
+	if p.EnableAutofire != nil {
+		c.EnableAutofire = *p.EnableAutofire
+	}
+	if p.Port2Device != nil {
+		c.Port2Device = *p.Port2Device
+		c.Zapper = c.Port2Device == Port2Zapper
+	}
+	if p.FourScore != nil {
+		c.FourScore = *p.FourScore
+	}
+	return c
+}
+
+// loadGameProfile loads the profile of the current ROM, if any
+func (app *Application) loadGameProfile() {
+	app.gameProfile = nil
+	profile, err := app.config.LoadGameProfile(app.romPath)
+	if err != nil {
+		fmt.Printf("[APP_WARNING] %v\n", err)
+		return
+	}
+	if profile != nil {
+		fmt.Printf("[APP_DEBUG] Loaded game profile for %s\n", filepath.Base(app.romPath))
+	}
+	app.gameProfile = profile
+}
+
+// GetGameProfile returns the profile of the current ROM, or nil if it has none
+func (app *Application) GetGameProfile() *GameProfile {
+	return app.gameProfile
+}
+
+// inputConfig returns the input settings in effect for the current ROM
+func (app *Application) inputConfig() InputConfig {
+	return app.gameProfile.applyTo(app.config.Input)
+}
@@ -0,0 +1,72 @@
+// Package app provides the choice of what internal RAM holds at power on, for
+// games that behave differently depending on uninitialized RAM.
+package app
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"gones/internal/memory"
+)
+
+// SetRAMPattern overrides emulation.ram_pattern for the ROMs loaded from now
+// on, without saving it
+func (app *Application) SetRAMPattern(name string) error {
+	pattern, err := memory.ParseRAMPattern(name)
+	if err != nil {
+		return err
+	}
+	app.ramPatternOverride = pattern.String()
+	return nil
+}
+
+// SetRAMSeed overrides emulation.ram_seed, the seed of the random RAM
+// pattern, for the ROMs loaded from now on, without saving it
+func (app *Application) SetRAMSeed(seed int64) {
+	app.ramSeedOverride = &seed
+}
+
+// RAMSeed returns the seed random RAM was filled from at the last power on,
+// or 0 when RAM was not random
+func (app *Application) RAMSeed() int64 {
+	return app.ramSeed
+}
+
+// loadRAMPattern sets what internal RAM holds when the ROM about to be loaded
+// powers on. A random pattern without a seed gets a new one each time, shown
+// so that the run can be repeated with -ram-seed.
+func (app *Application) loadRAMPattern() {
+	name := app.config.Emulation.RAMPattern
+	if app.ramPatternOverride != "" {
+		name = app.ramPatternOverride
+	}
+	seed := app.config.Emulation.RAMSeed
+	if app.ramSeedOverride != nil {
+		seed = *app.ramSeedOverride
+	}
+	pattern, err := memory.ParseRAMPattern(name)
+	if err != nil {
+		pattern = memory.RAMPatternMixed // Checked when the config was loaded
+	}
+
+	if pattern == memory.RAMPatternRandom && seed == 0 && app.netplay != nil {
+		// Each side would pick a different seed
+		fmt.Println("[APP_WARNING] Random RAM needs emulation.ram_seed in netplay; using the mixed pattern")
+		pattern = memory.RAMPatternMixed
+	}
+
+	app.ramSeed = 0
+	switch pattern {
+	case memory.RAMPatternRandom:
+		for seed == 0 {
+			seed = rand.Int64()
+		}
+		app.ramSeed = seed
+		fmt.Printf("🎲 RAM: random (seed %d)\n", seed)
+	case memory.RAMPatternMixed:
+		// The default, not worth a message
+	default:
+		fmt.Printf("🎲 RAM: %s\n", pattern)
+	}
+	app.bus.SetRAMPattern(pattern, seed)
+}
@@ -0,0 +1,156 @@
+// Package app provides the RAM search page of the pause menu.
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"gones/internal/cheat"
+)
+
+// ramSearchListLimit is the most candidates listed on the RAM search page
+const ramSearchListLimit = 200
+
+// ramSearchState is the RAM search in progress and the comparison set up on
+// its page
+type ramSearchState struct {
+	search       *cheat.Search // nil until a search is started
+	comparison   cheat.Comparison
+	operand      int  // Value compared to, or the change for CompareChangedBy
+	againstValue bool // Compare to operand instead of the previous snapshot
+}
+
+// StartRAMSearch snapshots RAM and PRG RAM, starting a new RAM search
+func (app *Application) StartRAMSearch() (*cheat.Search, error) {
+	if app.cartridge == nil || app.bus == nil || app.bus.Memory == nil {
+		return nil, fmt.Errorf("no ROM loaded")
+	}
+	app.ramSearch.search = cheat.NewSearch(app.bus.Memory)
+	return app.ramSearch.search, nil
+}
+
+// RAMSearch returns the RAM search in progress, or nil
+func (app *Application) RAMSearch() *cheat.Search {
+	return app.ramSearch.search
+}
+
+// ShowRAMSearch opens the pause menu on the RAM search page
+func (app *Application) ShowRAMSearch() {
+	if app.cartridge == nil || app.menu.IsVisible() {
+		return
+	}
+	app.menu.Open(app.ramSearchPage(), app.paused)
+	app.paused = true
+}
+
+// ramSearchPage builds the RAM search page: the comparison settings, the
+// search actions and the candidates left, which open in the memory viewer
+func (app *Application) ramSearchPage() *menuPage {
+	state := &app.ramSearch
+	page := &menuPage{title: "RAM SEARCH - NOT STARTED"}
+	if state.search != nil {
+		page.title = fmt.Sprintf("RAM SEARCH - %d LEFT", state.search.Count())
+	}
+
+	// refresh rebuilds the page to show the new candidates, keeping the selection
+	refresh := func(message string) {
+		selected := app.ramSearchPage()
+		if current := app.menu.current(); current != nil {
+			selected.selected = current.selected
+			selected.scroll = current.scroll
+		}
+		app.menu.Replace(selected)
+		app.menu.SetMessage(message)
+	}
+
+	page.items = []menuItem{
+		{label: "NEW SEARCH", action: func() {
+			search, err := app.StartRAMSearch()
+			if err != nil {
+				app.menu.SetMessage(strings.ToUpper(err.Error()))
+				return
+			}
+			refresh(fmt.Sprintf("%d BYTES SNAPSHOT", search.Count()))
+		}},
+		{
+			label: "COMPARE",
+			value: func() string { return strings.ToUpper(state.comparison.String()) },
+			adjust: func(delta int) {
+				index := 0
+				for i, comparison := range cheat.Comparisons {
+					if comparison == state.comparison {
+						index = i
+					}
+				}
+				n := len(cheat.Comparisons)
+				state.comparison = cheat.Comparisons[((index+delta)%n+n)%n]
+			},
+		},
+		{
+			label: "AGAINST",
+			value: func() string {
+				if state.comparison == cheat.CompareChangedBy {
+					return "-"
+				}
+				if state.againstValue {
+					return "VALUE"
+				}
+				return "PREVIOUS"
+			},
+			adjust: func(int) { state.againstValue = !state.againstValue },
+		},
+		{
+			label: "VALUE",
+			value: func() string {
+				if state.operand < 0 {
+					return fmt.Sprintf("%d", state.operand)
+				}
+				return fmt.Sprintf("%d ($%02X)", state.operand, state.operand)
+			},
+			adjust: func(delta int) { state.operand = max(-255, min(255, state.operand+delta)) },
+		},
+		{label: "SEARCH", action: func() {
+			if state.search == nil {
+				app.menu.SetMessage("START A NEW SEARCH FIRST")
+				return
+			}
+			n := state.search.Filter(state.comparison, state.operand, !state.againstValue)
+			refresh(fmt.Sprintf("%d CANDIDATES LEFT", n))
+		}},
+		{label: "UNDO", action: func() {
+			if state.search == nil || !state.search.Undo() {
+				app.menu.SetMessage("NOTHING TO UNDO")
+				return
+			}
+			refresh(fmt.Sprintf("%d CANDIDATES LEFT", state.search.Count()))
+		}},
+	}
+
+	if state.search != nil {
+		for _, result := range state.search.Results(ramSearchListLimit) {
+			address := result.Address
+			page.items = append(page.items, menuItem{
+				label:  fmt.Sprintf("$%04X  WAS %d", address, result.Previous),
+				value:  func() string { return app.ramSearchValue(address) },
+				action: func() { app.showMemoryViewerAt(address) },
+			})
+		}
+	}
+	return page
+}
+
+// ramSearchValue formats the current value of a candidate
+func (app *Application) ramSearchValue(address uint16) string {
+	value, ok := app.bus.Memory.Peek(address)
+	if !ok {
+		return "--"
+	}
+	return fmt.Sprintf("%3d $%02X", value, value)
+}
+
+// showMemoryViewerAt closes the menu and opens the memory viewer on a CPU address
+func (app *Application) showMemoryViewerAt(address uint16) {
+	app.HideMenu()
+	app.ShowMemoryViewer()
+	app.memoryViewer.GoTo(0, int(address))
+}
@@ -0,0 +1,141 @@
+// Package app provides the list of recently played ROMs and quick resume
+// of the last one.
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxRecentROMs is how many ROMs the recent list keeps
+const maxRecentROMs = 10
+
+// RecentROM is a played ROM in the recent list
+type RecentROM struct {
+	Path       string        `json:"path"`
+	LastPlayed time.Time     `json:"last_played"`
+	PlayTime   time.Duration `json:"play_time"` // Total over all sessions
+}
+
+// Name returns the ROM's file name without the extension
+func (r RecentROM) Name() string {
+	name := filepath.Base(r.Path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// recentROMsPath returns the file the recent list is kept in
+func (c *Config) recentROMsPath() string {
+	return filepath.Join(c.Paths.Config, "recent.json")
+}
+
+// RecentROMs returns the recently played ROMs, the last played first
+func (app *Application) RecentROMs() []RecentROM {
+	data, err := os.ReadFile(app.config.recentROMsPath())
+	if err != nil {
+		return nil
+	}
+	var recent []RecentROM
+	if err := json.Unmarshal(data, &recent); err != nil {
+		fmt.Printf("[APP_WARNING] Ignoring the recent ROMs list: %v\n", err)
+		return nil
+	}
+	return recent
+}
+
+// updateRecentROMs moves the loaded ROM to the top of the recent list and
+// adds the time played since the last update. Only windowed play counts,
+// not headless runs.
+func (app *Application) updateRecentROMs() {
+	if app.window == nil || app.romPath == "" {
+		return
+	}
+	path, err := filepath.Abs(app.romPath)
+	if err != nil {
+		path = app.romPath
+	}
+
+	entry := RecentROM{Path: path}
+	recent := app.RecentROMs()
+	for i, rom := range recent {
+		if rom.Path == path {
+			entry = rom
+			recent = append(recent[:i], recent[i+1:]...)
+			break
+		}
+	}
+	entry.LastPlayed = time.Now()
+	entry.PlayTime += app.sessionPlayTime
+	app.sessionPlayTime = 0
+	recent = append([]RecentROM{entry}, recent...)
+	if len(recent) > maxRecentROMs {
+		recent = recent[:maxRecentROMs]
+	}
+
+	data, err := json.MarshalIndent(recent, "", "  ")
+	if err == nil {
+		err = os.WriteFile(app.config.recentROMsPath(), data, 0644)
+	}
+	if err != nil {
+		fmt.Printf("[APP_WARNING] Failed to save the recent ROMs list: %v\n", err)
+	}
+}
+
+// Continue loads the last played ROM and resumes from its newest autosave,
+// if it has one
+func (app *Application) Continue() error {
+	recent := app.RecentROMs()
+	if len(recent) == 0 {
+		return errors.New("no ROM played yet")
+	}
+	if err := app.LoadROM(recent[0].Path); err != nil {
+		return err
+	}
+
+	newest := -1
+	slots := app.GetAutoSaveInfo()
+	for i, slot := range slots {
+		if slot.Used && (newest < 0 || slot.Timestamp.After(slots[newest].Timestamp)) {
+			newest = i
+		}
+	}
+	if newest >= 0 {
+		if err := app.LoadAutoSave(newest); err != nil {
+			fmt.Printf("[APP_WARNING] Starting %s from power on: %v\n", recent[0].Name(), err)
+		}
+	}
+	return nil
+}
+
+// recentROMsPage builds the menu page listing the recent ROMs with their
+// play time
+func (app *Application) recentROMsPage() *menuPage {
+	page := &menuPage{title: "RECENT ROMS"}
+	for _, rom := range app.RecentROMs() {
+		path, playTime := rom.Path, rom.PlayTime
+		page.items = append(page.items, menuItem{
+			label:  rom.Name(),
+			value:  func() string { return formatPlayTime(playTime) },
+			action: func() { app.loadROMFromMenu(path) },
+		})
+	}
+	if len(page.items) == 0 {
+		page.items = append(page.items, menuItem{label: "NO ROMS PLAYED YET"})
+	}
+	return page
+}
+
+// continueFromMenu resumes the last played ROM from the menu
+func (app *Application) continueFromMenu() {
+	if err := app.Continue(); err != nil {
+		fmt.Printf("[APP_ERROR] Failed to continue: %v\n", err)
+		app.menu.SetMessage("CONTINUE FAILED")
+		return
+	}
+	app.menu.Close()
+	app.paused = false
+}
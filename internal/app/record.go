@@ -0,0 +1,104 @@
+// Package app provides video recording of the emulator output.
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gones/internal/graphics"
+	"gones/internal/record"
+)
+
+// StartRecording records the emulator output to path until StopRecording. The
+// format follows the extension (.gif, .png/.apng, or .mp4/.mkv/.webm via
+// ffmpeg). Frames are recorded as the PPU produces them, without the video
+// processor's adjustments, and audio is included when the format carries it.
+func (app *Application) StartRecording(path string) error {
+	if app.bus == nil || app.cartridge == nil {
+		return errors.New("no ROM loaded")
+	}
+	if app.recorder != nil {
+		return errors.New("already recording")
+	}
+
+	recorder, err := record.Create(path, record.Options{
+		FrameRate:  app.FrameRate(),
+		SampleRate: app.bus.APU.GetSampleRate(),
+	})
+	if err != nil {
+		return err
+	}
+	app.recorder = recorder
+	return nil
+}
+
+// StopRecording finishes the active recording
+func (app *Application) StopRecording() error {
+	if app.recorder == nil {
+		return errors.New("not recording")
+	}
+	recorder := app.recorder
+	app.recorder = nil
+	return recorder.Close()
+}
+
+// IsRecording reports whether a recording is in progress
+func (app *Application) IsRecording() bool {
+	return app.recorder != nil
+}
+
+// ToggleRecording starts a recording in the recordings directory, or stops the
+// active one. It handles the recording hotkey.
+func (app *Application) ToggleRecording() {
+	if app.recorder != nil {
+		path, frames := app.recorder.Path(), app.recorder.Frames()
+		if err := app.StopRecording(); err != nil {
+			fmt.Printf("[APP_ERROR] Recording failed: %v\n", err)
+			return
+		}
+		fmt.Printf("🎬 Recording saved: %s (%d frames)\n", path, frames)
+		return
+	}
+
+	dir := app.config.Paths.Recordings
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("[APP_ERROR] Failed to create recordings directory: %v\n", err)
+		return
+	}
+	path := capturePath(dir, app.romPath, "."+app.config.Video.RecordFormat, time.Now())
+	if err := app.StartRecording(path); err != nil {
+		fmt.Printf("[APP_ERROR] Recording failed: %v\n", err)
+		return
+	}
+	fmt.Printf("🎬 Recording to %s (Shift+F12 to stop)\n", path)
+}
+
+// recordFrame adds the frame just emulated and its audio to the active recording
+func (app *Application) recordFrame() {
+	if app.recorder == nil {
+		return
+	}
+	err := app.recorder.AddFrame(app.bus.GetFrameBuffer())
+	if err == nil {
+		err = app.recorder.AddAudio(app.emulator.GetAudioSamples())
+	}
+	if err != nil {
+		fmt.Printf("[APP_ERROR] Recording stopped: %v\n", err)
+		app.recorder.Close()
+		app.recorder = nil
+	}
+}
+
+// renderRecordingIndicator draws a "REC" marker in the top-right corner while
+// recording. It is drawn after the frame is captured so it does not appear in
+// the recording.
+func (app *Application) renderRecordingIndicator(frameBuffer *[256 * 240]uint32) {
+	if app.recorder == nil {
+		return
+	}
+	x := graphics.OverlayWidth - graphics.TextWidth("REC") - 6
+	graphics.FillRect(frameBuffer, x-8, 7, 5, 5, graphics.OverlayColorRed)
+	graphics.DrawTextShadowed(frameBuffer, x, 6, "REC", graphics.OverlayColorRed)
+}
@@ -0,0 +1,103 @@
+// Package app provides the choice of the console region a ROM runs on, so
+// PAL games run at 50 frames a second instead of NTSC's 60.
+package app
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"gones/internal/cartridge"
+	"gones/internal/compat"
+	"gones/internal/graphics"
+	"gones/internal/region"
+)
+
+// RegionAuto picks the region of each ROM from its header and name
+const RegionAuto = "auto"
+
+// ParseRegion checks a region setting: RegionAuto, or NTSC, PAL or Dendy in
+// any case. It returns the setting as the config stores it.
+func ParseRegion(name string) (string, error) {
+	if name == "" || strings.EqualFold(name, RegionAuto) {
+		return RegionAuto, nil
+	}
+	r, err := region.Parse(name)
+	if err != nil {
+		return "", fmt.Errorf("unknown region %q (use auto, NTSC, PAL or Dendy)", name)
+	}
+	return r.String(), nil
+}
+
+// SetRegion overrides emulation.region for the ROMs loaded from now on,
+// without saving it
+func (app *Application) SetRegion(name string) error {
+	name, err := ParseRegion(name)
+	if err != nil {
+		return err
+	}
+	app.regionOverride = name
+	return nil
+}
+
+// GetRegion returns the region the ROM runs on
+func (app *Application) GetRegion() region.Region {
+	return app.bus.Region()
+}
+
+// FrameRate returns the frame rate of the region the ROM runs on
+func (app *Application) FrameRate() float64 {
+	return app.bus.GetFrameRate()
+}
+
+// detectRegion returns the region to run a ROM on and what decided it: the
+// setting, else its compatibility database entry (fixes, nil without one),
+// else the TV system of the header, else the region tags of its No-Intro
+// title or file name, such as "(Europe)" or "(E)". Games that do not tell run
+// on NTSC, which most games were made for.
+func (app *Application) detectRegion(romPath string, cart *cartridge.Cartridge, fixes *compat.Entry) (region.Region, string) {
+	setting := app.config.Emulation.Region
+	if app.regionOverride != "" {
+		setting = app.regionOverride
+	}
+	if setting != RegionAuto {
+		if r, err := region.Parse(setting); err == nil {
+			return r, "set"
+		}
+	}
+
+	if fixes != nil {
+		if r, ok := fixes.TVSystem(); ok {
+			return r, "compatibility database"
+		}
+	}
+	if r, ok := cart.Region(); ok {
+		return r, "header"
+	}
+	for _, name := range []string{app.romTitle(romPath), filepath.Base(romPath)} {
+		if r, ok := region.FromName(name); ok {
+			return r, "name"
+		}
+	}
+	return region.NTSC, "default"
+}
+
+// applyRegion switches the console and the frame pacing to a region
+func (app *Application) applyRegion(r region.Region) {
+	app.bus.SetRegion(r)
+	timing := r.Timing()
+	app.emulator.SetCyclesPerFrame(uint64(math.Round(timing.CPUCyclesPerFrame())))
+	app.emulator.SetTargetFrameRate(timing.FrameRate())
+	if window, ok := app.window.(graphics.FrameRateWindow); ok {
+		window.SetFrameRate(timing.FrameRate())
+	}
+	app.replay = nil // Buffered again at the new frame rate
+}
+
+// loadRegion runs a ROM just loaded on its region
+func (app *Application) loadRegion(romPath string, cart *cartridge.Cartridge) {
+	r, source := app.detectRegion(romPath, cart, app.compatEntry)
+	app.applyRegion(r)
+	fmt.Printf("🌍 Region: %s (%s, %.2f fps)\n", r, source, r.Timing().FrameRate())
+}
@@ -0,0 +1,695 @@
+// Package app provides the remote debugging server's methods.
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"gones/internal/ppu"
+	"gones/internal/remote"
+)
+
+// DefaultRemoteListen is where the remote debugging server listens by default
+const DefaultRemoteListen = "127.0.0.1:6502"
+
+// errStopped ends a scheduler tick when a frame stops at a breakpoint
+var errStopped = errors.New("stopped at a breakpoint")
+
+// rpcAddress is an address parameter, given as a number or a hex string
+// such as "$8000"
+type rpcAddress uint16
+
+func (a *rpcAddress) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		if n < 0 || n > 0xFFFF {
+			return fmt.Errorf("address %d out of range", n)
+		}
+		*a = rpcAddress(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("address must be a number or a hex string")
+	}
+	address, err := parseMemoryAddress(s)
+	if err != nil {
+		return err
+	}
+	*a = rpcAddress(address)
+	return nil
+}
+
+// rpcRegisters is the CPU state reported to clients
+type rpcRegisters struct {
+	PC       uint16 `json:"pc"`
+	A        uint8  `json:"a"`
+	X        uint8  `json:"x"`
+	Y        uint8  `json:"y"`
+	P        uint8  `json:"p"`
+	SP       uint8  `json:"sp"`
+	Cycles   uint64 `json:"cycles"`
+	Scanline int    `json:"scanline"`
+	Dot      int    `json:"dot"`
+	Frame    uint64 `json:"frame"`
+}
+
+// rpcStop is the "stopped" notification sent when emulation stops
+type rpcStop struct {
+	Reason     string       `json:"reason"` // "breakpoint", "watchpoint", "pause" or "crash"
+	Breakpoint *Breakpoint  `json:"breakpoint,omitempty"`
+	Address    *uint16      `json:"address,omitempty"` // Accessed address of a watchpoint
+	Value      *uint8       `json:"value,omitempty"`
+	Registers  rpcRegisters `json:"registers"`
+}
+
+// decodeParams decodes a request's params into v, rejecting unknown fields
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return remote.InvalidParams("invalid params: %v", err)
+	}
+	return nil
+}
+
+// SetRemoteListen turns the remote debugging server on, listening on address
+func (app *Application) SetRemoteListen(address string) {
+	app.config.Debug.Remote.Enabled = true
+	app.config.Debug.Remote.Listen = address
+}
+
+// remoteServer returns the server that runs remote methods for debuggers and
+// the control API, creating it on first use
+func (app *Application) remoteServer() *remote.Server {
+	if app.remote == nil {
+		origins := append(append([]string(nil), app.config.Debug.Remote.AllowedOrigins...), app.config.API.AllowedOrigins...)
+		app.remote = remote.New(remote.Options{AllowedOrigins: origins})
+		app.registerRemoteMethods()
+		app.registerAPIMethods()
+		app.registerMetricsMethods()
+		app.updateScrollTracking()
+	}
+	return app.remote
+}
+
+// MethodServer returns the server that runs remote methods, for frontends in
+// the same process to queue calls (with Call) on the emulation loop, and to
+// register methods of their own before Run
+func (app *Application) MethodServer() *remote.Server {
+	return app.remoteServer()
+}
+
+// warnIfExposed warns when a server listens beyond the local machine
+func warnIfExposed(what string, addr net.Addr) {
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		if ip := net.ParseIP(host); ip != nil && !ip.IsLoopback() {
+			fmt.Printf("[APP_WARNING] %s is open to the network on %s\n", what, addr)
+		}
+	}
+}
+
+// StartRemote starts the remote debugging server on debug.remote.listen
+func (app *Application) StartRemote() error {
+	server := app.remoteServer()
+	if server.Addr() != nil {
+		return nil
+	}
+	if err := server.Listen(app.config.Debug.Remote.Listen); err != nil {
+		return err
+	}
+	warnIfExposed("Remote debugging", server.Addr())
+	fmt.Printf("🔌 Remote debugging on %s\n", server.Addr())
+	return nil
+}
+
+// StopRemote stops the remote debugging server, and the control API and
+// metrics server, which it runs the requests of
+func (app *Application) StopRemote() {
+	app.StopAPI()
+	app.StopMetrics()
+	if app.remote == nil {
+		return
+	}
+	if err := app.remote.Close(); err != nil && app.config.Debug.EnableLogging {
+		fmt.Printf("[APP_DEBUG] Remote debugging server: %v\n", err)
+	}
+	app.remote = nil
+}
+
+// RemoteAddr returns the address of the remote debugging server, or "" when
+// it is not running
+func (app *Application) RemoteAddr() string {
+	if app.remote == nil || app.remote.Addr() == nil {
+		return ""
+	}
+	return app.remote.Addr().String()
+}
+
+// startServers starts the remote debugging server, control API and metrics
+// server that are enabled
+func (app *Application) startServers() {
+	if app.config.Debug.Remote.Enabled {
+		if err := app.StartRemote(); err != nil {
+			fmt.Printf("[APP_ERROR] Remote debugging failed: %v\n", err)
+		}
+	}
+	if app.config.API.Enabled {
+		if err := app.StartAPI(); err != nil {
+			fmt.Printf("[APP_ERROR] Control API failed: %v\n", err)
+		}
+	}
+	if app.config.Metrics.Enabled {
+		if err := app.StartMetrics(); err != nil {
+			fmt.Printf("[APP_ERROR] Metrics server failed: %v\n", err)
+		}
+	}
+}
+
+// serveRemote runs the requests remote clients sent since the last tick
+func (app *Application) serveRemote() {
+	if app.remote != nil {
+		app.remote.Process()
+	}
+}
+
+// Serve runs without a window for remote debuggers, control API clients and
+// metrics scrapers: the game (once one is loaded) at normal speed, or as fast
+// as it runs when unthrottled, unless a client pauses it,
+// until a client calls "quit", frames frames have run (0 runs until quit) or
+// ctx is cancelled
+func (app *Application) Serve(ctx context.Context, frames int) error {
+	app.startServers()
+	if app.remote == nil {
+		return errors.New("no debug server, control API or metrics server is enabled")
+	}
+	app.running = true
+	app.startTime = time.Now()
+
+	frameTime := app.emulator.GetTargetFrameTime()
+	next := time.Now()
+	for ran := 0; app.running && ctx.Err() == nil && (frames <= 0 || ran < frames); {
+		if app.paused || app.cartridge == nil {
+			app.remote.Wait(frameTime)
+			next = time.Now()
+			continue
+		}
+
+		frameStart := time.Now()
+		app.remote.Process()
+		if err := app.emulateFrame(); err != nil && !errors.Is(err, errStopped) {
+			// Stay paused after a crash for clients to inspect, unless
+			// the run was to end after some frames
+			var crash *CrashError
+			if !errors.As(err, &crash) || frames > 0 {
+				return err
+			}
+		}
+		app.emulatorTime = time.Since(frameStart)
+		app.totalEmulatorTime += app.emulatorTime
+		if !app.emulator.Stopped() {
+			ran++
+			app.updatePerformanceMetricsMinimal(frameStart)
+		}
+
+		next = next.Add(frameTime)
+		if app.unthrottled {
+			next = time.Now()
+		} else if wait := time.Until(next); wait > 0 {
+			app.remote.Wait(wait)
+		} else {
+			next = time.Now()
+		}
+	}
+	return nil
+}
+
+// announceStop pauses emulation after a breakpoint or pause and tells the
+// console and remote clients where it stopped
+func (app *Application) announceStop(reason string) {
+	app.paused = true
+	stop := rpcStop{Reason: reason, Registers: app.rpcRegisters()}
+	switch reason {
+	case "breakpoint":
+		stop.Breakpoint = app.breakHit
+		fmt.Printf("🔴 Breakpoint %d at $%04X\n", app.breakHit.ID, stop.Registers.PC)
+	case "watchpoint":
+		stop.Breakpoint = app.breakHit
+		address, value := app.breakAddress, app.breakValue
+		stop.Address, stop.Value = &address, &value
+		fmt.Printf("🔴 Watchpoint %d: %s $%04X = $%02X at $%04X\n",
+			app.breakHit.ID, app.breakHit.Kind, address, value, stop.Registers.PC)
+	}
+	if app.remote != nil {
+		app.remote.Notify("stopped", stop)
+	}
+}
+
+// rpcRegisters returns the CPU state for clients
+func (app *Application) rpcRegisters() rpcRegisters {
+	if app.bus == nil || app.bus.CPU == nil {
+		return rpcRegisters{}
+	}
+	state := app.traceState(app.bus.CPU.PC)
+	return rpcRegisters{
+		PC: state.PC, A: state.A, X: state.X, Y: state.Y, P: state.P, SP: state.SP,
+		Cycles: state.Cycles, Scanline: state.Scanline, Dot: state.Dot,
+		Frame: app.emulator.GetFrameCount(),
+	}
+}
+
+// requireROM fails remote calls that need a loaded game
+func (app *Application) requireROM() error {
+	if app.cartridge == nil || app.bus == nil {
+		return errors.New("no ROM loaded")
+	}
+	return nil
+}
+
+// remoteSpace returns the memory viewer's address space with a name ("cpu",
+// "ppu", "oam" or "palette"; "" is "cpu")
+func (app *Application) remoteSpace(name string) (*memorySpace, error) {
+	if name == "" {
+		name = "cpu"
+	}
+	for _, space := range app.memorySpaces() {
+		if strings.EqualFold(space.name, name) {
+			return space, nil
+		}
+	}
+	return nil, remote.InvalidParams("unknown memory space %q", name)
+}
+
+// registerRemoteMethods registers the methods remote clients call
+func (app *Application) registerRemoteMethods() {
+	s := app.remote
+
+	s.Handle("status", func(json.RawMessage) (any, error) {
+		return map[string]any{
+			"rom":         app.romPath,
+			"paused":      app.paused,
+			"frame":       app.emulator.GetFrameCount(),
+			"mid_frame":   app.emulator.Stopped(),
+			"breakpoints": len(app.breakpoints),
+		}, nil
+	})
+
+	s.Handle("pause", func(json.RawMessage) (any, error) {
+		if !app.paused {
+			app.announceStop("pause")
+		}
+		return nil, nil
+	})
+
+	s.Handle("resume", func(json.RawMessage) (any, error) {
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		app.paused = false
+		return nil, nil
+	})
+
+	s.Handle("step", func(params json.RawMessage) (any, error) {
+		args := struct {
+			Count int `json:"count"`
+		}{Count: 1}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		if args.Count < 1 || args.Count > 1000000 {
+			return nil, remote.InvalidParams("count must be 1-1000000")
+		}
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		for i := 0; i < args.Count; i++ {
+			if err := app.StepInstruction(); err != nil {
+				return nil, err
+			}
+		}
+		return app.rpcRegisters(), nil
+	})
+
+	s.Handle("stepFrame", func(json.RawMessage) (any, error) {
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		if err := app.StepFrame(); err != nil {
+			return nil, err
+		}
+		return app.rpcRegisters(), nil
+	})
+
+	s.Handle("reset", func(json.RawMessage) (any, error) {
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		app.Reset()
+		return app.rpcRegisters(), nil
+	})
+
+	s.Handle("powerCycle", func(json.RawMessage) (any, error) {
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		if err := app.PowerCycle(); err != nil {
+			return nil, err
+		}
+		return app.rpcRegisters(), nil
+	})
+
+	s.Handle("getRegisters", func(json.RawMessage) (any, error) {
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		return app.rpcRegisters(), nil
+	})
+
+	s.Handle("setRegisters", func(params json.RawMessage) (any, error) {
+		var args struct {
+			PC *rpcAddress `json:"pc"`
+			A  *uint8      `json:"a"`
+			X  *uint8      `json:"x"`
+			Y  *uint8      `json:"y"`
+			P  *uint8      `json:"p"`
+			SP *uint8      `json:"sp"`
+		}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		cpu := app.bus.CPU
+		if args.PC != nil {
+			cpu.PC = uint16(*args.PC)
+		}
+		if args.A != nil {
+			cpu.A = *args.A
+		}
+		if args.X != nil {
+			cpu.X = *args.X
+		}
+		if args.Y != nil {
+			cpu.Y = *args.Y
+		}
+		if args.P != nil {
+			cpu.SetStatusByte(*args.P)
+		}
+		if args.SP != nil {
+			cpu.SP = *args.SP
+		}
+		return app.rpcRegisters(), nil
+	})
+
+	s.Handle("readMemory", func(params json.RawMessage) (any, error) {
+		args := struct {
+			Address rpcAddress `json:"address"`
+			Length  int        `json:"length"`
+			Space   string     `json:"space"`
+		}{Length: 1}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		space, err := app.remoteSpace(args.Space)
+		if err != nil {
+			return nil, err
+		}
+		if args.Length < 1 || int(args.Address)+args.Length > space.size {
+			return nil, remote.InvalidParams("range outside the %s space ($%X bytes)", space.name, space.size)
+		}
+
+		// Unreadable bytes, such as registers that reads would disturb, are null
+		data := make([]any, args.Length)
+		for i := range data {
+			if value, ok := space.peek(int(args.Address) + i); ok {
+				data[i] = value
+			}
+		}
+		return map[string]any{"address": uint16(args.Address), "data": data}, nil
+	})
+
+	s.Handle("writeMemory", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Address rpcAddress `json:"address"`
+			Data    []int      `json:"data"`
+			Space   string     `json:"space"`
+		}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		space, err := app.remoteSpace(args.Space)
+		if err != nil {
+			return nil, err
+		}
+		if int(args.Address)+len(args.Data) > space.size {
+			return nil, remote.InvalidParams("range outside the %s space ($%X bytes)", space.name, space.size)
+		}
+		for i, value := range args.Data {
+			if value < 0 || value > 0xFF {
+				return nil, remote.InvalidParams("byte %d is out of range", value)
+			}
+			address := int(args.Address) + i
+			if !space.poke(address, uint8(value)) {
+				return nil, fmt.Errorf("%s $%04X is not writable", space.name, address)
+			}
+		}
+		return nil, nil
+	})
+
+	s.Handle("disassemble", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Address *rpcAddress `json:"address"`
+			Count   int         `json:"count"`
+		}
+		args.Count = 16
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		if args.Count < 1 || args.Count > 4096 {
+			return nil, remote.InvalidParams("count must be 1-4096")
+		}
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		start := app.bus.CPU.PC
+		if args.Address != nil {
+			start = uint16(*args.Address)
+		}
+		type line struct {
+			Address uint16 `json:"address"`
+			Length  int    `json:"length"`
+			Text    string `json:"text"`
+		}
+		lines := []line{}
+		for _, l := range app.Disassemble(start, args.Count) {
+			lines = append(lines, line{Address: l.Address, Length: l.Length, Text: l.Text})
+		}
+		return lines, nil
+	})
+
+	s.Handle("addBreakpoint", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Kind    BreakpointKind `json:"kind"`
+			Address *rpcAddress    `json:"address"`
+			End     *rpcAddress    `json:"end"`
+		}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		if args.Address == nil {
+			return nil, remote.InvalidParams("address is required")
+		}
+		if args.Kind == "" {
+			args.Kind = BreakExecute
+		}
+		end := *args.Address
+		if args.End != nil {
+			end = *args.End
+		}
+		breakpoint, err := app.AddBreakpoint(args.Kind, uint16(*args.Address), uint16(end))
+		if err != nil {
+			return nil, remote.InvalidParams("%v", err)
+		}
+		return breakpoint, nil
+	})
+
+	s.Handle("removeBreakpoint", func(params json.RawMessage) (any, error) {
+		var args struct {
+			ID int `json:"id"`
+		}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		return nil, app.RemoveBreakpoint(args.ID)
+	})
+
+	s.Handle("enableBreakpoint", func(params json.RawMessage) (any, error) {
+		args := struct {
+			ID      int  `json:"id"`
+			Enabled bool `json:"enabled"`
+		}{Enabled: true}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		return nil, app.EnableBreakpoint(args.ID, args.Enabled)
+	})
+
+	s.Handle("listBreakpoints", func(json.RawMessage) (any, error) {
+		if app.breakpoints == nil {
+			return []*Breakpoint{}, nil
+		}
+		return app.breakpoints, nil
+	})
+
+	// States are a numbered slot, or a named state when a name is given
+	stateParams := func(params json.RawMessage) (int, string, error) {
+		var args struct {
+			Slot int    `json:"slot"`
+			Name string `json:"name"`
+		}
+		if err := decodeParams(params, &args); err != nil {
+			return 0, "", err
+		}
+		return args.Slot, args.Name, nil
+	}
+	s.Handle("saveState", func(params json.RawMessage) (any, error) {
+		slot, name, err := stateParams(params)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			return nil, app.SaveNamedState(name)
+		}
+		return nil, app.SaveState(slot)
+	})
+	s.Handle("loadState", func(params json.RawMessage) (any, error) {
+		slot, name, err := stateParams(params)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			err = app.LoadNamedState(name)
+		} else {
+			err = app.LoadState(slot)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return app.rpcRegisters(), nil
+	})
+	s.Handle("deleteState", func(params json.RawMessage) (any, error) {
+		slot, name, err := stateParams(params)
+		if err != nil {
+			return nil, err
+		}
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		if name != "" {
+			return nil, app.DeleteNamedState(name)
+		}
+		return nil, app.states.DeleteState(slot, app.romPath)
+	})
+	s.Handle("listStates", func(json.RawMessage) (any, error) {
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		slots := []StateSlotInfo{}
+		for _, slot := range app.states.GetSlotInfo(app.romPath) {
+			if slot.Used {
+				slots = append(slots, slot)
+			}
+		}
+		named := app.NamedStates()
+		if named == nil {
+			named = []StateSlotInfo{}
+		}
+		return map[string]any{"slots": slots, "named": named}, nil
+	})
+	s.Handle("exportState", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Name string `json:"name"`
+			Path string `json:"path"`
+		}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		path, err := app.ExportState(args.Name, args.Path)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"path": path}, nil
+	})
+	s.Handle("importState", func(params json.RawMessage) (any, error) {
+		var args struct {
+			Path string `json:"path"`
+		}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		if args.Path == "" {
+			return nil, remote.InvalidParams("path is required")
+		}
+		name, err := app.ImportNamedState(args.Path)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"name": name}, nil
+	})
+
+	s.Handle("getLayers", func(json.RawMessage) (any, error) {
+		return app.rpcLayers(), nil
+	})
+	s.Handle("setLayers", func(params json.RawMessage) (any, error) {
+		args := struct {
+			Background     bool  `json:"background"`
+			Sprites        *bool `json:"sprites"`
+			SpritePalettes []int `json:"sprite_palettes"`
+		}{Background: true}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		filter := ppu.LayerFilter{HideBackground: !args.Background}
+		if args.Sprites != nil && !*args.Sprites {
+			filter.HideSprites = true
+		} else if args.SpritePalettes != nil {
+			filter.HiddenSpritePalettes = 0x0F
+			for _, palette := range args.SpritePalettes {
+				if palette < 0 || palette > 3 {
+					return nil, remote.InvalidParams("sprite palettes must be 0-3")
+				}
+				filter.HiddenSpritePalettes &^= 1 << palette
+			}
+		}
+		app.SetLayers(filter)
+		return app.rpcLayers(), nil
+	})
+
+	s.Handle("getScroll", func(json.RawMessage) (any, error) {
+		if err := app.requireROM(); err != nil {
+			return nil, err
+		}
+		return app.rpcScroll(), nil
+	})
+
+	s.Handle("quit", func(json.RawMessage) (any, error) {
+		app.Stop()
+		return nil, nil
+	})
+}
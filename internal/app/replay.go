@@ -0,0 +1,66 @@
+// Package app provides retroactive capture of the last seconds of gameplay.
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gones/internal/record"
+)
+
+// captureReplayFrame adds the frame just emulated to the replay buffer
+func (app *Application) captureReplayFrame() {
+	seconds := app.config.Video.ReplaySeconds
+	if seconds <= 0 {
+		app.replay = nil
+		return
+	}
+	if app.replay == nil {
+		app.replay = record.NewReplayBuffer(float64(seconds), record.Options{FrameRate: app.FrameRate()})
+	}
+	app.replay.Add(app.bus.GetFrameBuffer())
+}
+
+// SaveReplay writes the buffered last seconds of gameplay to path (format by
+// extension, see StartRecording) and returns the number of frames written
+func (app *Application) SaveReplay(path string) (int, error) {
+	if app.replay == nil || app.replay.Len() == 0 {
+		return 0, errors.New("no frames buffered (is video.replay_seconds 0?)")
+	}
+	return app.replay.Save(path)
+}
+
+// saveReplay handles the replay hotkey. The buffer is copied and encoded in
+// the background so the game keeps running.
+func (app *Application) saveReplay() {
+	if app.replay == nil || app.replay.Len() == 0 {
+		fmt.Println("[APP_WARNING] Replay buffer is empty (is video.replay_seconds 0?)")
+		return
+	}
+	if !app.replaySaving.CompareAndSwap(false, true) {
+		fmt.Println("[APP_WARNING] A replay is still being saved")
+		return
+	}
+
+	dir := app.config.Paths.Recordings
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("[APP_ERROR] Failed to create recordings directory: %v\n", err)
+		app.replaySaving.Store(false)
+		return
+	}
+	path := capturePath(dir, app.romPath, "_replay."+app.config.Video.RecordFormat, time.Now())
+
+	replay := app.replay.Clone()
+	fmt.Printf("🎬 Saving last %.1f seconds to %s...\n", replay.Duration(), path)
+	go func() {
+		defer app.replaySaving.Store(false)
+		frames, err := replay.Save(path)
+		if err != nil {
+			fmt.Printf("[APP_ERROR] Replay capture failed: %v\n", err)
+			return
+		}
+		fmt.Printf("🎬 Replay saved: %s (%d frames)\n", path, frames)
+	}()
+}
@@ -0,0 +1,119 @@
+// Package app provides the ROM browser page of the pause menu.
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gones/internal/cartridge"
+)
+
+// romEntry is a directory or ROM file listed by the ROM browser
+type romEntry struct {
+	name string
+	path string
+	dir  bool
+}
+
+// listROMDirectory returns the subdirectories and ROM files of dir, each
+// group sorted by name. Hidden entries are skipped.
+func listROMDirectory(dir string) ([]romEntry, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []romEntry
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		isDir := file.IsDir()
+		if file.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(path); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if isDir || cartridge.IsROMFile(name) {
+			entries = append(entries, romEntry{name: name, path: path, dir: isDir})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].dir != entries[j].dir {
+			return entries[i].dir
+		}
+		return strings.ToLower(entries[i].name) < strings.ToLower(entries[j].name)
+	})
+	return entries, nil
+}
+
+// romBrowserDir returns the directory the ROM browser opens in: the last one
+// browsed, else the directory of the loaded ROM, else the ROMs directory
+func (app *Application) romBrowserDir() string {
+	if app.menuDir != "" {
+		return app.menuDir
+	}
+	if app.romPath != "" {
+		return filepath.Dir(app.romPath)
+	}
+	return app.config.Paths.ROMs
+}
+
+// romBrowserPage builds the menu page listing dir
+func (app *Application) romBrowserPage(dir string) *menuPage {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	page := &menuPage{title: "LOAD ROM - " + filepath.Base(dir)}
+
+	open := func(path string) func() {
+		return func() {
+			app.menuDir = path
+			app.menu.Replace(app.romBrowserPage(path))
+		}
+	}
+	if parent := filepath.Dir(dir); parent != dir {
+		page.items = append(page.items, menuItem{label: "../", action: open(parent)})
+	}
+
+	entries, err := listROMDirectory(dir)
+	if err != nil {
+		page.items = append(page.items, menuItem{label: "DIRECTORY NOT FOUND"})
+		return page
+	}
+	for _, entry := range entries {
+		if entry.dir {
+			page.items = append(page.items, menuItem{label: entry.name + "/", action: open(entry.path)})
+			continue
+		}
+		path := entry.path
+		page.items = append(page.items, menuItem{label: app.romTitle(path), action: func() { app.loadROMFromMenu(path) }})
+	}
+	if len(entries) == 0 {
+		page.items = append(page.items, menuItem{label: "NO ROMS HERE"})
+	}
+
+	// Start on the first entry rather than the parent directory
+	if len(page.items) > 1 && page.items[0].label == "../" {
+		page.selected = 1
+	}
+	return page
+}
+
+// loadROMFromMenu loads the ROM chosen in the browser and resumes emulation
+func (app *Application) loadROMFromMenu(path string) {
+	if err := app.LoadROM(path); err != nil {
+		fmt.Printf("[APP_ERROR] Failed to load ROM: %v\n", err)
+		app.menu.SetMessage("LOAD FAILED: " + filepath.Base(path))
+		return
+	}
+	fmt.Printf("📁 Loaded ROM: %s\n", path)
+	app.menu.Close()
+	app.paused = false
+}
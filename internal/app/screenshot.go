@@ -0,0 +1,119 @@
+// Package app provides PNG screenshots of the emulator output.
+package app
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Screenshot saves the current frame as a timestamped PNG in the screenshots
+// directory and returns its path. The video.raw_screenshots option selects the
+// unprocessed 256x240 frame instead of the color adjusted and upscaled one.
+func (app *Application) Screenshot() (string, error) {
+	if app.bus == nil || app.cartridge == nil {
+		return "", errors.New("no ROM loaded")
+	}
+
+	dir := app.config.Paths.Screenshots
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create screenshots directory: %v", err)
+	}
+	path := capturePath(dir, app.romPath, ".png", time.Now())
+
+	if err := app.SaveScreenshot(path, app.config.Video.RawScreenshots); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// SaveScreenshot writes the current frame to path as a PNG. With raw set the
+// frame is written exactly as the PPU produced it; otherwise the video
+// processor's color adjustments, frame blending and upscaler are applied.
+func (app *Application) SaveScreenshot(path string, raw bool) error {
+	if app.bus == nil || app.cartridge == nil {
+		return errors.New("no ROM loaded")
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create screenshot: %v", err)
+	}
+	defer file.Close()
+
+	return app.EncodeScreenshot(file, raw)
+}
+
+// EncodeScreenshot writes the current frame to w as a PNG, processed as for
+// SaveScreenshot
+func (app *Application) EncodeScreenshot(w io.Writer, raw bool) error {
+	if app.bus == nil || app.cartridge == nil {
+		return errors.New("no ROM loaded")
+	}
+
+	pixels := app.bus.GetFrameBuffer()
+	width, height := 256, 240
+	if !raw && app.videoProcessor != nil {
+		// A blended frame is already color adjusted, as shown on screen
+		if blended := app.videoProcessor.BlendedFrame(); blended != nil {
+			pixels = blended
+		} else {
+			pixels = app.videoProcessor.ProcessFrame(pixels)
+		}
+		pixels, width, height = app.videoProcessor.UpscaleFrame(pixels, width, height)
+	}
+	if len(pixels) < width*height {
+		return fmt.Errorf("frame buffer too small: %d pixels", len(pixels))
+	}
+
+	if err := png.Encode(w, frameToImage(pixels, width, height)); err != nil {
+		return fmt.Errorf("failed to encode screenshot: %v", err)
+	}
+	return nil
+}
+
+// frameToImage converts a 0xRRGGBB frame buffer to an image
+func frameToImage(pixels []uint32, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i, pixel := range pixels[:width*height] {
+		img.Pix[i*4] = uint8(pixel >> 16)
+		img.Pix[i*4+1] = uint8(pixel >> 8)
+		img.Pix[i*4+2] = uint8(pixel)
+		img.Pix[i*4+3] = 0xFF
+	}
+	return img
+}
+
+// capturePath returns an unused file name like "smb_2024-05-01_12-30-45.png"
+func capturePath(dir, romPath, ext string, now time.Time) string {
+	name := "gones"
+	if romPath != "" {
+		base := filepath.Base(romPath)
+		name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	stamp := now.Format("2006-01-02_15-04-05")
+
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s%s", name, stamp, ext))
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s_%s_%d%s", name, stamp, i, ext))
+	}
+}
+
+// takeScreenshot handles the screenshot hotkey
+func (app *Application) takeScreenshot() {
+	path, err := app.Screenshot()
+	if err != nil {
+		fmt.Printf("[APP_ERROR] Screenshot failed: %v\n", err)
+		return
+	}
+	fmt.Printf("📸 Screenshot saved: %s\n", path)
+}
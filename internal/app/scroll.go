@@ -0,0 +1,134 @@
+// Package app provides the scroll overlay, a graph of the scroll of every
+// scanline down the side of the picture, and the scroll log it shares the
+// tracking with.
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"gones/internal/graphics"
+	"gones/internal/scroll"
+)
+
+const (
+	// The graph is 64 pixels wide on the right: the two nametables side by
+	// side (or stacked, for Y) at 1/8 scale
+	scrollGraphLeft  = graphics.OverlayWidth - 64
+	scrollGraphScale = 8
+
+	scrollColorX     = graphics.OverlayColorRed
+	scrollColorY     = graphics.OverlayColorGreen
+	scrollColorSplit = graphics.OverlayColorYellow
+)
+
+// ToggleScrollOverlay handles the scroll overlay hotkey
+func (app *Application) ToggleScrollOverlay() {
+	app.scrollOverlay = !app.scrollOverlay
+	app.updateScrollTracking()
+	if app.scrollOverlay {
+		fmt.Println("📈 Scroll overlay on (X red, Y green, splits yellow)")
+	} else {
+		fmt.Println("📈 Scroll overlay off")
+	}
+}
+
+// StartScrollLog writes the scroll of every scanline of the frames run from
+// now on to a CSV file at path
+func (app *Application) StartScrollLog(path string) error {
+	if app.bus == nil || app.cartridge == nil {
+		return errors.New("no ROM loaded")
+	}
+	if app.scrollLog != nil {
+		return errors.New("already logging scroll")
+	}
+	log, err := scroll.Create(path)
+	if err != nil {
+		return err
+	}
+	app.scrollLog = log
+	app.updateScrollTracking()
+	return nil
+}
+
+// StopScrollLog finishes the scroll log
+func (app *Application) StopScrollLog() error {
+	if app.scrollLog == nil {
+		return errors.New("not logging scroll")
+	}
+	log := app.scrollLog
+	app.scrollLog = nil
+	app.updateScrollTracking()
+	return log.Close()
+}
+
+// updateScrollTracking tracks scroll while the overlay, the log or remote
+// clients (through getScroll) can use it
+func (app *Application) updateScrollTracking() {
+	if app.bus != nil {
+		app.bus.PPU.SetScrollTracking(app.scrollOverlay || app.scrollLog != nil || app.remote != nil)
+	}
+}
+
+// logScroll adds the frame just run to the scroll log
+func (app *Application) logScroll() {
+	if app.scrollLog == nil {
+		return
+	}
+	if err := app.scrollLog.AddFrame(app.bus.PPU.GetFrameCount(), app.bus.PPU.ScrollLines()); err != nil {
+		fmt.Printf("[APP_ERROR] Scroll log stopped: %v\n", err)
+		app.StopScrollLog()
+	}
+}
+
+// renderScrollOverlay draws the scroll of each scanline of the last frame
+// down the right of the picture: X in red, Y in green, both at 1/8 scale
+// over the two nametables, and a yellow mark at each split
+func (app *Application) renderScrollOverlay(frameBuffer *[256 * 240]uint32) {
+	if !app.scrollOverlay || app.cartridge == nil {
+		return
+	}
+	lines := app.bus.PPU.ScrollLines()
+	if lines == nil {
+		return
+	}
+
+	graphics.DarkenRect(frameBuffer, scrollGraphLeft, 0, graphics.OverlayWidth-scrollGraphLeft, graphics.OverlayHeight, 2)
+	// The second nametable starts half way across
+	graphics.FillRect(frameBuffer, scrollGraphLeft+256/scrollGraphScale, 0, 1, graphics.OverlayHeight, 0x404060)
+	for scanline, s := range lines {
+		if !s.Rendering {
+			continue
+		}
+		graphics.FillRect(frameBuffer, scrollGraphLeft+s.WorldX()/scrollGraphScale, scanline, 1, 1, scrollColorX)
+		graphics.FillRect(frameBuffer, scrollGraphLeft+s.WorldY()/scrollGraphScale, scanline, 1, 1, scrollColorY)
+	}
+	for _, scanline := range scroll.Splits(lines) {
+		graphics.FillRect(frameBuffer, scrollGraphLeft-4, scanline, 3, 1, scrollColorSplit)
+	}
+	graphics.DrawTextShadowed(frameBuffer, scrollGraphLeft+2, 2, "X", scrollColorX)
+	graphics.DrawTextShadowed(frameBuffer, scrollGraphLeft+2+2*graphics.FontAdvance, 2, "Y", scrollColorY)
+}
+
+// rpcScroll describes the scroll of each scanline of the last frame to
+// remote clients
+func (app *Application) rpcScroll() map[string]any {
+	type line struct {
+		X         int  `json:"x"`
+		Y         int  `json:"y"`
+		Nametable int  `json:"nametable"`
+		Rendering bool `json:"rendering"`
+	}
+	shown, splits := []line{}, []int{}
+	if lines := app.bus.PPU.ScrollLines(); lines != nil {
+		for _, s := range lines {
+			shown = append(shown, line{s.X, s.Y, s.Nametable, s.Rendering})
+		}
+		splits = append(splits, scroll.Splits(lines)...)
+	}
+	return map[string]any{
+		"frame":  app.emulator.GetFrameCount(),
+		"lines":  shown,
+		"splits": splits,
+	}
+}
@@ -0,0 +1,161 @@
+// Package app provides the on-screen save state slot picker.
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"gones/internal/graphics"
+)
+
+// SlotPickerMode selects whether the picker saves to or loads from the chosen slot
+type SlotPickerMode int
+
+const (
+	SlotPickerSave SlotPickerMode = iota
+	SlotPickerLoad
+)
+
+// SlotPicker is an on-screen list of save state slots showing the metadata and
+// thumbnail stored in each slot, so players can see what they're overwriting or loading.
+type SlotPicker struct {
+	visible     bool
+	mode        SlotPickerMode
+	selected    int
+	slots       []StateSlotInfo
+	wasPaused   bool
+	lastMessage string
+}
+
+// NewSlotPicker creates a hidden slot picker
+func NewSlotPicker() *SlotPicker {
+	return &SlotPicker{}
+}
+
+// Open shows the picker with the given slot preselected
+func (sp *SlotPicker) Open(mode SlotPickerMode, slot int, slots []StateSlotInfo, wasPaused bool) {
+	sp.visible = true
+	sp.mode = mode
+	sp.slots = slots
+	sp.wasPaused = wasPaused
+	sp.lastMessage = ""
+	sp.selected = 0
+	if slot >= 0 && slot < len(slots) {
+		sp.selected = slot
+	}
+}
+
+// Close hides the picker and returns the pause state from before it was opened
+func (sp *SlotPicker) Close() bool {
+	sp.visible = false
+	sp.slots = nil
+	return sp.wasPaused
+}
+
+// IsVisible returns whether the picker is on screen
+func (sp *SlotPicker) IsVisible() bool {
+	return sp.visible
+}
+
+// GetMode returns the current picker mode
+func (sp *SlotPicker) GetMode() SlotPickerMode {
+	return sp.mode
+}
+
+// GetSelected returns the highlighted slot number
+func (sp *SlotPicker) GetSelected() int {
+	return sp.selected
+}
+
+// Move moves the selection by delta slots, wrapping around the list
+func (sp *SlotPicker) Move(delta int) {
+	if len(sp.slots) == 0 {
+		return
+	}
+	sp.selected = (sp.selected + delta) % len(sp.slots)
+	if sp.selected < 0 {
+		sp.selected += len(sp.slots)
+	}
+}
+
+// SetMessage sets a status line shown at the bottom of the picker (e.g. an error)
+func (sp *SlotPicker) SetMessage(message string) {
+	sp.lastMessage = message
+}
+
+// Render draws the picker over the frame buffer
+func (sp *SlotPicker) Render(frameBuffer *[256 * 240]uint32) {
+	if !sp.visible {
+		return
+	}
+
+	// Dim the game image behind the picker
+	graphics.DarkenRect(frameBuffer, 0, 0, graphics.OverlayWidth, graphics.OverlayHeight, 2)
+
+	title := "SAVE STATE"
+	if sp.mode == SlotPickerLoad {
+		title = "LOAD STATE"
+	}
+	graphics.DrawTextShadowed(frameBuffer, 8, 6, title, graphics.OverlayColorYellow)
+
+	// Slot list on the left
+	listY := 22
+	for i, slot := range sp.slots {
+		y := listY + i*graphics.LineHeight
+		color := graphics.OverlayColorGray
+		if i == sp.selected {
+			graphics.FillRect(frameBuffer, 4, y-2, 106, graphics.LineHeight, graphics.OverlayColorPanel)
+			color = graphics.OverlayColorWhite
+		}
+
+		label := fmt.Sprintf("F%-2d EMPTY", i+1)
+		if slot.Used {
+			label = fmt.Sprintf("F%-2d %s", i+1, slot.Timestamp.Format("01-02 15:04"))
+		}
+		graphics.DrawText(frameBuffer, 8, y, label, color)
+	}
+
+	// Details panel for the selected slot on the right
+	panelX := 120
+	if sp.selected >= 0 && sp.selected < len(sp.slots) {
+		slot := sp.slots[sp.selected]
+		thumbX := panelX + 22
+		thumbY := listY - 2
+		graphics.DrawRect(frameBuffer, thumbX-1, thumbY-1, 87, 82, graphics.OverlayColorGray)
+		if slot.Thumbnail != nil {
+			graphics.DrawPixels(frameBuffer, thumbX, thumbY, slot.ThumbnailWidth, slot.ThumbnailHeight, slot.Thumbnail)
+		} else {
+			graphics.FillRect(frameBuffer, thumbX, thumbY, 85, 80, graphics.OverlayColorBlack)
+			graphics.DrawText(frameBuffer, thumbX+18, thumbY+36, "NO IMAGE", graphics.OverlayColorGray)
+		}
+
+		y := thumbY + 88
+		if slot.Used {
+			graphics.DrawText(frameBuffer, panelX, y, slot.Timestamp.Format("2006-01-02 15:04:05"), graphics.OverlayColorWhite)
+			y += graphics.LineHeight
+			graphics.DrawText(frameBuffer, panelX, y, fmt.Sprintf("FRAME %d", slot.FrameCount), graphics.OverlayColorWhite)
+			y += graphics.LineHeight
+			graphics.DrawText(frameBuffer, panelX, y, "PLAY  "+formatPlayTime(slot.PlayTime), graphics.OverlayColorWhite)
+			y += graphics.LineHeight
+			checksum := slot.ROMChecksum
+			if len(checksum) > 12 {
+				checksum = checksum[:12]
+			}
+			graphics.DrawText(frameBuffer, panelX, y, "ROM   "+checksum, graphics.OverlayColorGray)
+		} else {
+			graphics.DrawText(frameBuffer, panelX, y, "EMPTY SLOT", graphics.OverlayColorGray)
+		}
+	}
+
+	// Footer with controls and status
+	if sp.lastMessage != "" {
+		graphics.DrawTextShadowed(frameBuffer, 8, 212, sp.lastMessage, graphics.OverlayColorRed)
+	}
+	graphics.DrawTextShadowed(frameBuffer, 8, 226, "UP/DOWN SELECT  ENTER OK  ESC BACK", graphics.OverlayColorGray)
+}
+
+// formatPlayTime formats a play time as H:MM:SS
+func formatPlayTime(d time.Duration) string {
+	total := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d:%02d", total/3600, (total/60)%60, total%60)
+}
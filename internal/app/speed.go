@@ -0,0 +1,220 @@
+// Package app provides fast-forward and slow-motion speed control.
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gones/internal/graphics"
+)
+
+// SpeedUnthrottled runs as many frames as the host can emulate
+const SpeedUnthrottled = 0.0
+
+// Speed limits accepted by -speed and the fast_forward_speed setting
+const (
+	MinSpeed = 0.1
+	MaxSpeed = 16.0
+)
+
+// SpeedSteps are the speeds the Minus and Equal hotkeys step through
+var SpeedSteps = []float64{0.25, 0.5, 1, 2, 4, SpeedUnthrottled}
+
+// Fast-forward audio modes
+const (
+	FastForwardAudioPitch = "pitch" // Keep the pitch by playing one frame of audio per real frame
+	FastForwardAudioMute  = "mute"  // Silence audio while running faster than real time
+)
+
+// tickBudget is the share of a host tick spent emulating when unthrottled or
+// when a speed multiple cannot keep up; the rest is left for rendering and input
+const tickBudget = 12 * time.Millisecond
+
+// FrameScheduler decides how many emulated frames run on each host tick. The
+// host ticks at the frame rate of the console's region (Ebitengine's TPS, or
+// the sleep of the standard loop), so at speed 1 every tick runs one frame,
+// at 2 two frames and at 0.5 every other tick runs one.
+type FrameScheduler struct {
+	speed  float64 // Frames per tick; SpeedUnthrottled runs frames until tickBudget is used
+	credit float64 // Frames owed from fractional speeds
+}
+
+// NewFrameScheduler creates a scheduler running at speed
+func NewFrameScheduler(speed float64) *FrameScheduler {
+	s := &FrameScheduler{}
+	s.SetSpeed(speed)
+	return s
+}
+
+// SetSpeed changes the speed
+func (s *FrameScheduler) SetSpeed(speed float64) {
+	s.speed = clampSpeed(speed)
+	s.credit = 0
+}
+
+// Speed returns the current speed
+func (s *FrameScheduler) Speed() float64 {
+	return s.speed
+}
+
+// FastForwarding reports whether the speed is faster than real time
+func (s *FrameScheduler) FastForwarding() bool {
+	return s.speed == SpeedUnthrottled || s.speed > 1
+}
+
+// Tick runs the frames due on one host tick and returns how many ran
+func (s *FrameScheduler) Tick(runFrame func() error) (int, error) {
+	start := time.Now()
+	frames := 0
+
+	if s.speed == SpeedUnthrottled {
+		for frames == 0 || time.Since(start) < tickBudget {
+			if err := runFrame(); err != nil {
+				return frames, err
+			}
+			frames++
+		}
+		return frames, nil
+	}
+
+	s.credit += s.speed
+	for s.credit >= 1 {
+		if err := runFrame(); err != nil {
+			return frames, err
+		}
+		frames++
+		s.credit--
+
+		// Drop the frames the host cannot keep up with instead of falling behind
+		if s.credit >= 1 && time.Since(start) >= tickBudget {
+			s.credit -= float64(int(s.credit))
+		}
+	}
+	return frames, nil
+}
+
+// Nudge runs frames more (or, negative, fewer) than are due over the next
+// ticks, without changing the speed
+func (s *FrameScheduler) Nudge(frames int) {
+	s.credit += float64(frames)
+}
+
+// clampSpeed limits speed to MinSpeed-MaxSpeed, leaving SpeedUnthrottled as is
+func clampSpeed(speed float64) float64 {
+	if speed == SpeedUnthrottled {
+		return speed
+	}
+	return max(MinSpeed, min(MaxSpeed, speed))
+}
+
+// ParseSpeed parses a speed such as "2", "0.5x" or "max" (unthrottled)
+func ParseSpeed(value string) (float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "max", "unthrottled", "unlimited", "0":
+		return SpeedUnthrottled, nil
+	}
+
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+	if err != nil || speed < MinSpeed || speed > MaxSpeed {
+		return 0, fmt.Errorf("invalid speed %q (use %g-%g, or max)", value, MinSpeed, MaxSpeed)
+	}
+	return speed, nil
+}
+
+// FormatSpeed returns a speed as shown to the user ("2x", "0.5x", "max")
+func FormatSpeed(speed float64) string {
+	if speed == SpeedUnthrottled {
+		return "max"
+	}
+	return strconv.FormatFloat(speed, 'g', -1, 64) + "x"
+}
+
+// stepSpeed returns the next SpeedSteps entry above (delta > 0) or below
+// speed, stopping at either end
+func stepSpeed(speed float64, delta int) float64 {
+	// Unthrottled is the fastest step; the others sort by value
+	rank := func(s float64) float64 {
+		if s == SpeedUnthrottled {
+			return MaxSpeed + 1
+		}
+		return s
+	}
+
+	if delta > 0 {
+		for _, step := range SpeedSteps {
+			if rank(step) > rank(speed) {
+				return step
+			}
+		}
+		return SpeedSteps[len(SpeedSteps)-1]
+	}
+	for i := len(SpeedSteps) - 1; i >= 0; i-- {
+		if rank(SpeedSteps[i]) < rank(speed) {
+			return SpeedSteps[i]
+		}
+	}
+	return SpeedSteps[0]
+}
+
+// SetSpeed changes the emulation speed (SpeedUnthrottled runs as fast as possible)
+func (app *Application) SetSpeed(speed float64) {
+	app.speed = clampSpeed(speed)
+	app.applySpeed()
+}
+
+// GetSpeed returns the emulation speed set with SetSpeed or the speed hotkeys
+func (app *Application) GetSpeed() float64 {
+	return app.speed
+}
+
+// SetUnthrottled runs without any real-time pacing, for benchmarks, dumps and
+// bots: frames run unthrottled whatever the speed and fast-forward, the window
+// neither waits for VSync nor ticks at the frame rate, no audio is played and
+// the standard loop and Serve never sleep between frames
+func (app *Application) SetUnthrottled(unthrottled bool) {
+	app.unthrottled = unthrottled
+	if window, ok := app.window.(graphics.UnthrottledWindow); ok {
+		window.SetUnthrottled(unthrottled)
+	}
+	app.applySpeed()
+}
+
+// applySpeed passes the effective speed to the scheduler: unthrottled for
+// unthrottled runs, the fast-forward speed while fast-forward is held, the
+// chosen speed otherwise
+func (app *Application) applySpeed() {
+	speed := app.speed
+	switch {
+	case app.unthrottled:
+		speed = SpeedUnthrottled
+	case app.fastForward:
+		speed = app.config.Emulation.FastForwardSpeed
+	}
+	if speed != app.scheduler.Speed() {
+		app.scheduler.SetSpeed(speed)
+	}
+}
+
+// setFastForward starts or stops fast-forward
+func (app *Application) setFastForward(enabled bool) {
+	app.fastForward = enabled
+	app.applySpeed()
+}
+
+// changeSpeed steps the speed down (-1) or up (1) the speed steps
+func (app *Application) changeSpeed(delta int) {
+	app.SetSpeed(stepSpeed(app.speed, delta))
+	fmt.Printf("⏩ Speed: %s\n", FormatSpeed(app.speed))
+}
+
+// renderSpeedIndicator shows the speed in the top-left corner when it is not 1x
+func (app *Application) renderSpeedIndicator(frameBuffer *[256 * 240]uint32) {
+	speed := app.scheduler.Speed()
+	if speed == 1 || app.cartridge == nil {
+		return
+	}
+	graphics.DrawTextShadowed(frameBuffer, 6, 6, FormatSpeed(speed), graphics.OverlayColorYellow)
+}
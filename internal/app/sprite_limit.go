@@ -0,0 +1,25 @@
+// Package app provides the unlimited sprites enhancement setting, which
+// lifts the eight sprites per scanline limit outside movies and netplay.
+package app
+
+import "fmt"
+
+// SetUnlimitedSprites turns the unlimited sprites enhancement on or off
+func (app *Application) SetUnlimitedSprites(on bool) {
+	app.config.Video.UnlimitedSprites = on
+	app.updateSpriteLimit()
+	if on {
+		fmt.Println("👾 Unlimited sprites on (enhancement: every sprite of a line is drawn)")
+	} else {
+		fmt.Println("👾 Unlimited sprites off (at most eight sprites a line, as on a console)")
+	}
+}
+
+// updateSpriteLimit applies the unlimited sprites setting, keeping the limit
+// while a movie or netplay runs so the picture is the one every player and
+// every playback sees
+func (app *Application) updateSpriteLimit() {
+	if app.bus != nil {
+		app.bus.PPU.SetUnlimitedSprites(app.config.Video.UnlimitedSprites && app.movie == nil && app.netplay == nil)
+	}
+}
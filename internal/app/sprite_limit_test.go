@@ -0,0 +1,79 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gones/internal/cartridge"
+)
+
+// newTestApplication returns a headless application keeping its config and
+// saves in a temporary directory
+func newTestApplication(t *testing.T) *Application {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	app, err := NewApplicationWithMode("", true)
+	if err != nil {
+		t.Fatalf("NewApplicationWithMode: %v", err)
+	}
+	app.config.Paths.Config = dir
+	t.Cleanup(func() { app.Cleanup() })
+	return app
+}
+
+// testROM returns an NROM image of a program looping at $8000
+func testROM(t *testing.T) []byte {
+	t.Helper()
+	rom, err := cartridge.NewTestROMBuilder().
+		WithPRGSize(2).
+		WithData(0x0000, []uint8{0x4C, 0x00, 0x80}). // JMP $8000
+		WithResetVector(0x8000).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	return rom
+}
+
+// loadTestROM writes an iNES image to the temporary directory and loads it
+func loadTestROM(t *testing.T, app *Application, rom []byte) {
+	t.Helper()
+	romPath := filepath.Join(app.config.Paths.Config, "test.nes")
+	if err := os.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := app.LoadROM(romPath); err != nil {
+		t.Fatalf("LoadROM: %v", err)
+	}
+}
+
+func TestUnlimitedSpritesNetplay(t *testing.T) {
+	app := newTestApplication(t)
+	loadTestROM(t, app, testROM(t))
+	app.SetUnlimitedSprites(true)
+	if !app.bus.PPU.UnlimitedSprites() {
+		t.Fatal("unlimited sprites not applied to the PPU")
+	}
+
+	if err := app.HostNetplay("127.0.0.1:0"); err != nil {
+		t.Fatalf("HostNetplay: %v", err)
+	}
+	if app.bus.PPU.UnlimitedSprites() {
+		t.Error("unlimited sprites still on once netplay started")
+	}
+	if err := app.emulateFrame(); err != nil {
+		t.Fatalf("emulateFrame: %v", err)
+	}
+	if app.bus.PPU.UnlimitedSprites() {
+		t.Error("unlimited sprites back on during a netplay frame")
+	}
+
+	app.StopNetplay()
+	if !app.bus.PPU.UnlimitedSprites() {
+		t.Error("unlimited sprites not restored once netplay stopped")
+	}
+}
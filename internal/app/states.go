@@ -2,20 +2,40 @@
 package app
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
 	"gones/internal/bus"
+	"gones/internal/graphics"
 )
 
+// gzipMagic is the two-byte header of gzip streams, used to tell compressed
+// state files apart from older plain JSON ones
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// thumbnailScale is the downscale factor used for save state thumbnails (256x240 -> 85x80)
+const thumbnailScale = 3
+
 // StateManager manages save states
 type StateManager struct {
 	saveDirectory string
 	maxSlots      int
 	initialized   bool
+	compress      bool // Write state files gzip-compressed
+
+	// Checksum of the ROM loaded, which states are saved with and checked
+	// against (see SetROMChecksum)
+	romChecksum string
 }
 
 // SaveState represents a saved emulator state
@@ -26,6 +46,7 @@ type SaveState struct {
 	ROMPath     string    `json:"rom_path"`
 	ROMChecksum string    `json:"rom_checksum"`
 	SlotNumber  int       `json:"slot_number"`
+	Name        string    `json:"name,omitempty"` // Of a named state
 	Description string    `json:"description"`
 
 	// Emulator state
@@ -34,12 +55,21 @@ type SaveState struct {
 	APUState    APUStateData `json:"apu_state"`
 	MemoryState MemoryData   `json:"memory_state"`
 
+	// Complete machine snapshot from bus.SaveStateToBytes (base64 in JSON).
+	// States without it (older files) can only be restored by a reset.
+	MachineState []byte `json:"machine_state,omitempty"`
+
 	// Frame information
 	FrameCount uint64 `json:"frame_count"`
 	CycleCount uint64 `json:"cycle_count"`
 
-	// Screenshot (base64 encoded)
-	Screenshot string `json:"screenshot,omitempty"`
+	// Play time accumulated for this ROM when the state was saved
+	PlayTimeSeconds float64 `json:"play_time_seconds"`
+
+	// Screenshot thumbnail (base64 encoded PNG, downscaled)
+	Screenshot       string `json:"screenshot,omitempty"`
+	ScreenshotWidth  int    `json:"screenshot_width,omitempty"`
+	ScreenshotHeight int    `json:"screenshot_height,omitempty"`
 }
 
 // CPUStateData represents CPU state for save files
@@ -97,12 +127,23 @@ type MemoryData struct {
 // StateSlotInfo contains information about a save state slot
 type StateSlotInfo struct {
 	SlotNumber  int       `json:"slot_number"`
+	Name        string    `json:"name,omitempty"`
 	Used        bool      `json:"used"`
 	Timestamp   time.Time `json:"timestamp"`
 	ROMPath     string    `json:"rom_path"`
 	Description string    `json:"description"`
 	FilePath    string    `json:"file_path"`
 	FileSize    int64     `json:"file_size"`
+
+	// Metadata read from the state file
+	ROMChecksum string        `json:"rom_checksum"`
+	FrameCount  uint64        `json:"frame_count"`
+	PlayTime    time.Duration `json:"play_time"`
+
+	// Decoded thumbnail pixels (0xRRGGBB), nil if the state has no screenshot
+	Thumbnail       []uint32 `json:"-"`
+	ThumbnailWidth  int      `json:"-"`
+	ThumbnailHeight int      `json:"-"`
 }
 
 // NewStateManager creates a new state manager
@@ -111,6 +152,7 @@ func NewStateManager(saveDirectory string) *StateManager {
 		saveDirectory: saveDirectory,
 		maxSlots:      10, // Default to 10 save slots
 		initialized:   false,
+		compress:      true,
 	}
 
 	if err := manager.initialize(); err != nil {
@@ -132,8 +174,9 @@ func (sm *StateManager) initialize() error {
 	return nil
 }
 
-// SaveState saves the current emulator state to a slot
-func (sm *StateManager) SaveState(bus *bus.Bus, slot int, romPath string) error {
+// SaveState saves the current emulator state to a slot.
+// playTime is the total time spent playing the ROM and is stored as slot metadata.
+func (sm *StateManager) SaveState(bus *bus.Bus, slot int, romPath string, playTime time.Duration) error {
 	if !sm.initialized {
 		return fmt.Errorf("state manager not initialized")
 	}
@@ -146,16 +189,47 @@ func (sm *StateManager) SaveState(bus *bus.Bus, slot int, romPath string) error
 		return fmt.Errorf("bus cannot be nil")
 	}
 
+	// Capture current emulator state
+	saveState, err := sm.captureState(bus, slot, romPath, playTime,
+		fmt.Sprintf("Auto-save %s", time.Now().Format("2006-01-02 15:04:05")))
+	if err != nil {
+		return fmt.Errorf("failed to capture state: %v", err)
+	}
+
+	// Generate file path
+	filePath := sm.getSlotFilePath(slot, romPath)
+
+	// Save to file
+	if err := sm.saveToFile(saveState, filePath); err != nil {
+		return fmt.Errorf("failed to save state: %v", err)
+	}
+
+	return nil
+}
+
+// captureState builds a SaveState snapshot of the bus
+func (sm *StateManager) captureState(bus *bus.Bus, slot int, romPath string, playTime time.Duration, description string) (*SaveState, error) {
 	// Create save state
 	saveState := &SaveState{
 		Version:     "1.0",
 		Timestamp:   time.Now(),
 		ROMPath:     romPath,
-		ROMChecksum: sm.calculateROMChecksum(romPath),
+		ROMChecksum: sm.romChecksum,
 		SlotNumber:  slot,
-		Description: fmt.Sprintf("Auto-save %s", time.Now().Format("2006-01-02 15:04:05")),
+		Description: description,
 		FrameCount:  bus.GetFrameCount(),
 		CycleCount:  bus.GetCycleCount(),
+
+		PlayTimeSeconds: playTime.Seconds(),
+	}
+
+	// Capture a downscaled screenshot for the slot picker
+	if thumbnail, width, height, err := encodeThumbnail(bus.GetFrameBuffer()); err == nil {
+		saveState.Screenshot = thumbnail
+		saveState.ScreenshotWidth = width
+		saveState.ScreenshotHeight = height
+	} else {
+		fmt.Printf("Warning: failed to capture save state thumbnail: %v\n", err)
 	}
 
 	// Capture CPU state
@@ -203,18 +277,15 @@ func (sm *StateManager) SaveState(bus *bus.Bus, slot int, romPath string) error
 		OAMData:  make([]uint8, 256),  // 256 bytes OAM
 	}
 
-	// TODO: Actually read memory from bus
-	// This is simplified - you would need methods to extract memory data
-
-	// Generate file path
-	filePath := sm.getSlotFilePath(slot, romPath)
-
-	// Save to file
-	if err := sm.saveToFile(saveState, filePath); err != nil {
-		return fmt.Errorf("failed to save state: %v", err)
+	// The full machine state (RAM, VRAM, APU, mapper, ...) is stored in MachineState;
+	// the summary fields above are kept for tools that read the JSON directly
+	machineState, err := bus.SaveStateToBytes()
+	if err != nil {
+		return nil, err
 	}
+	saveState.MachineState = machineState
 
-	return nil
+	return saveState, nil
 }
 
 // LoadState loads a saved state from a slot
@@ -266,10 +337,10 @@ func (sm *StateManager) saveToFile(state *SaveState, filePath string) error {
 		return fmt.Errorf("failed to create directory: %v", err)
 	}
 
-	// Marshal to JSON
-	data, err := json.MarshalIndent(state, "", "  ")
+	// Marshal to JSON and compress
+	data, err := sm.encodeState(state)
 	if err != nil {
-		return fmt.Errorf("failed to marshal state: %v", err)
+		return err
 	}
 
 	// Write to file
@@ -288,6 +359,52 @@ func (sm *StateManager) loadFromFile(filePath string) (*SaveState, error) {
 		return nil, fmt.Errorf("failed to read file: %v", err)
 	}
 
+	return sm.decodeState(data)
+}
+
+// encodeState serializes a state to JSON, gzip-compressed if compression is enabled
+func (sm *StateManager) encodeState(state *SaveState) ([]byte, error) {
+	if !sm.compress {
+		data, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal state: %v", err)
+		}
+		return data, nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %v", err)
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress state: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress state: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeState parses a state payload, transparently handling both gzip-compressed
+// and legacy uncompressed JSON files
+func (sm *StateManager) decodeState(data []byte) (*SaveState, error) {
+	if bytes.HasPrefix(data, gzipMagic) {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open compressed state: %v", err)
+		}
+		defer reader.Close()
+
+		data, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress state: %v", err)
+		}
+	}
+
 	// Unmarshal JSON
 	var state SaveState
 	if err := json.Unmarshal(data, &state); err != nil {
@@ -297,16 +414,27 @@ func (sm *StateManager) loadFromFile(filePath string) (*SaveState, error) {
 	return &state, nil
 }
 
+// SetCompression enables or disables gzip compression for newly written states.
+// Loading always accepts both formats.
+func (sm *StateManager) SetCompression(enabled bool) {
+	sm.compress = enabled
+}
+
+// IsCompressionEnabled returns whether new states are written compressed
+func (sm *StateManager) IsCompressionEnabled() bool {
+	return sm.compress
+}
+
 // validateSaveState validates a loaded save state
 func (sm *StateManager) validateSaveState(state *SaveState, currentROMPath string) error {
 	if state.Version == "" {
 		return fmt.Errorf("missing version information")
 	}
 
-	// Check ROM compatibility (simplified)
-	if state.ROMPath != currentROMPath {
-		// In a more sophisticated implementation, you might allow loading
-		// states from the same ROM with a different path
+	// Check ROM compatibility: the same path, or the same ROM opened by
+	// another path (relative, absolute) or moved since
+	if state.ROMPath != currentROMPath &&
+		(state.ROMChecksum == "" || state.ROMChecksum != sm.romChecksum) {
 		return fmt.Errorf("save state is for a different ROM")
 	}
 
@@ -318,23 +446,34 @@ func (sm *StateManager) validateSaveState(state *SaveState, currentROMPath strin
 	return nil
 }
 
+// validateImportedState validates a standalone state file. Its ROM path is
+// one on the machine it was exported on, so only the checksum can tell
+// whether it is for the current ROM.
+func (sm *StateManager) validateImportedState(state *SaveState) error {
+	if state.Version == "" {
+		return fmt.Errorf("missing version information")
+	}
+
+	if state.ROMChecksum == "" {
+		return fmt.Errorf("state file has no ROM checksum")
+	}
+	if state.ROMChecksum != sm.romChecksum {
+		return fmt.Errorf("save state is for a different ROM")
+	}
+
+	return nil
+}
+
 // restoreState restores emulator state from a save state
 func (sm *StateManager) restoreState(bus *bus.Bus, state *SaveState) error {
-	// This is a simplified implementation - in a full implementation,
-	// you would need methods to restore all emulator state
+	if len(state.MachineState) > 0 {
+		return bus.LoadStateFromBytes(state.MachineState)
+	}
 
-	// Reset the bus first
+	// Older state files only stored a summary - the best we can do is a reset
 	bus.Reset()
 
-	// TODO: Restore actual state
-	// This would require methods to:
-	// 1. Set CPU registers and state
-	// 2. Restore PPU registers and VRAM
-	// 3. Restore APU state
-	// 4. Restore memory contents
-	// 5. Restore mapper state
-
-	fmt.Printf("State restore not fully implemented - would restore frame %d, cycle %d\n",
+	fmt.Printf("State file has no machine snapshot - reset instead of restoring frame %d, cycle %d\n",
 		state.FrameCount, state.CycleCount)
 
 	return nil
@@ -348,11 +487,146 @@ func (sm *StateManager) getSlotFilePath(slot int, romPath string) string {
 	return filepath.Join(sm.saveDirectory, fileName)
 }
 
-// calculateROMChecksum calculates a checksum for ROM verification
-func (sm *StateManager) calculateROMChecksum(romPath string) string {
-	// Simplified checksum - in a real implementation,
-	// you would calculate MD5/SHA256 of the ROM file
-	return fmt.Sprintf("checksum_%s", filepath.Base(romPath))
+// getAutoSaveFilePath generates the file path for a rotating autosave slot
+func (sm *StateManager) getAutoSaveFilePath(index int, romPath string) string {
+	romName := filepath.Base(romPath)
+	romNameWithoutExt := romName[:len(romName)-len(filepath.Ext(romName))]
+	fileName := fmt.Sprintf("%s_auto_%d.save", romNameWithoutExt, index)
+	return filepath.Join(sm.saveDirectory, fileName)
+}
+
+// SaveAutoState writes an autosave snapshot, rotating through autoSlots files.
+// The oldest (or first unused) autosave file is overwritten. Returns the index written.
+func (sm *StateManager) SaveAutoState(bus *bus.Bus, romPath string, playTime time.Duration, autoSlots int) (int, error) {
+	if !sm.initialized {
+		return -1, fmt.Errorf("state manager not initialized")
+	}
+
+	if bus == nil {
+		return -1, fmt.Errorf("bus cannot be nil")
+	}
+
+	if autoSlots <= 0 {
+		autoSlots = 1
+	}
+
+	// Pick the first unused autosave slot, or the oldest one
+	index := 0
+	var oldest time.Time
+	for i := 0; i < autoSlots; i++ {
+		stat, err := os.Stat(sm.getAutoSaveFilePath(i, romPath))
+		if err != nil {
+			index = i
+			break
+		}
+		if oldest.IsZero() || stat.ModTime().Before(oldest) {
+			oldest = stat.ModTime()
+			index = i
+		}
+	}
+
+	saveState, err := sm.captureState(bus, -1, romPath, playTime,
+		fmt.Sprintf("Autosave %s", time.Now().Format("2006-01-02 15:04:05")))
+	if err != nil {
+		return -1, fmt.Errorf("failed to capture autosave: %v", err)
+	}
+
+	if err := sm.saveToFile(saveState, sm.getAutoSaveFilePath(index, romPath)); err != nil {
+		return -1, fmt.Errorf("failed to write autosave: %v", err)
+	}
+
+	return index, nil
+}
+
+// LoadAutoState loads a rotating autosave snapshot
+func (sm *StateManager) LoadAutoState(bus *bus.Bus, index int, romPath string) error {
+	if !sm.initialized {
+		return fmt.Errorf("state manager not initialized")
+	}
+
+	if bus == nil {
+		return fmt.Errorf("bus cannot be nil")
+	}
+
+	filePath := sm.getAutoSaveFilePath(index, romPath)
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("autosave %d not found", index)
+	}
+
+	saveState, err := sm.loadFromFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to load autosave: %v", err)
+	}
+
+	if err := sm.validateSaveState(saveState, romPath); err != nil {
+		return fmt.Errorf("invalid autosave: %v", err)
+	}
+
+	return sm.restoreState(bus, saveState)
+}
+
+// GetAutoSaveInfo returns information about the rotating autosave slots
+func (sm *StateManager) GetAutoSaveInfo(romPath string, autoSlots int) []StateSlotInfo {
+	slots := make([]StateSlotInfo, 0, autoSlots)
+	for i := 0; i < autoSlots; i++ {
+		slots = append(slots, sm.readSlotInfo(i, sm.getAutoSaveFilePath(i, romPath)))
+	}
+	return slots
+}
+
+// SetROMChecksum sets the checksum of the ROM just loaded, the
+// cartridge's ROMChecksum: that of the PRG and CHR as loaded, after any
+// patch, whatever file or archive they came from
+func (sm *StateManager) SetROMChecksum(checksum string) {
+	sm.romChecksum = checksum
+}
+
+// encodeThumbnail downscales a frame buffer and encodes it as a base64 PNG
+func encodeThumbnail(frameBuffer []uint32) (string, int, int, error) {
+	if len(frameBuffer) < 256*240 {
+		return "", 0, 0, fmt.Errorf("frame buffer too small: %d pixels", len(frameBuffer))
+	}
+
+	pixels, width, height := graphics.DownscaleFrame(frameBuffer, thumbnailScale)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := pixels[y*width+x]
+			img.SetRGBA(x, y, color.RGBA{R: uint8(p >> 16), G: uint8(p >> 8), B: uint8(p), A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", 0, 0, fmt.Errorf("failed to encode thumbnail: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), width, height, nil
+}
+
+// decodeThumbnail decodes a base64 PNG thumbnail into 0xRRGGBB pixels
+func decodeThumbnail(encoded string) ([]uint32, int, int, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to decode thumbnail: %v", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to decode thumbnail image: %v", err)
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	pixels := make([]uint32, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			pixels[y*width+x] = (r>>8)<<16 | (g>>8)<<8 | b>>8
+		}
+	}
+
+	return pixels, width, height, nil
 }
 
 // GetSlotInfo returns information about all save slots
@@ -360,31 +634,47 @@ func (sm *StateManager) GetSlotInfo(romPath string) []StateSlotInfo {
 	slots := make([]StateSlotInfo, sm.maxSlots)
 
 	for i := 0; i < sm.maxSlots; i++ {
-		slotInfo := StateSlotInfo{
-			SlotNumber: i,
-			Used:       false,
-		}
+		slots[i] = sm.readSlotInfo(i, sm.getSlotFilePath(i, romPath))
+	}
 
-		filePath := sm.getSlotFilePath(i, romPath)
-		if stat, err := os.Stat(filePath); err == nil {
-			// File exists
-			slotInfo.Used = true
-			slotInfo.FilePath = filePath
-			slotInfo.FileSize = stat.Size()
-			slotInfo.Timestamp = stat.ModTime()
-
-			// Try to load basic info from the save state
-			if state, err := sm.loadFromFile(filePath); err == nil {
-				slotInfo.ROMPath = state.ROMPath
-				slotInfo.Description = state.Description
-				slotInfo.Timestamp = state.Timestamp
+	return slots
+}
+
+// readSlotInfo reads slot metadata and thumbnail from a state file
+func (sm *StateManager) readSlotInfo(slot int, filePath string) StateSlotInfo {
+	slotInfo := StateSlotInfo{
+		SlotNumber: slot,
+		Used:       false,
+	}
+
+	if stat, err := os.Stat(filePath); err == nil {
+		// File exists
+		slotInfo.Used = true
+		slotInfo.FilePath = filePath
+		slotInfo.FileSize = stat.Size()
+		slotInfo.Timestamp = stat.ModTime()
+
+		// Try to load basic info from the save state
+		if state, err := sm.loadFromFile(filePath); err == nil {
+			slotInfo.ROMPath = state.ROMPath
+			slotInfo.Name = state.Name
+			slotInfo.Description = state.Description
+			slotInfo.Timestamp = state.Timestamp
+			slotInfo.ROMChecksum = state.ROMChecksum
+			slotInfo.FrameCount = state.FrameCount
+			slotInfo.PlayTime = time.Duration(state.PlayTimeSeconds * float64(time.Second))
+
+			if state.Screenshot != "" {
+				if pixels, width, height, err := decodeThumbnail(state.Screenshot); err == nil {
+					slotInfo.Thumbnail = pixels
+					slotInfo.ThumbnailWidth = width
+					slotInfo.ThumbnailHeight = height
+				}
 			}
 		}
-
-		slots[i] = slotInfo
 	}
 
-	return slots
+	return slotInfo
 }
 
 // DeleteState deletes a save state from a slot
@@ -446,45 +736,22 @@ func (sm *StateManager) SetSaveDirectory(directory string) error {
 	return sm.initialize()
 }
 
-// ExportState exports a save state to a specific file
+// ExportState exports the current state to a standalone file, which embeds
+// the ROM's checksum for ImportState to check
 func (sm *StateManager) ExportState(bus *bus.Bus, filePath string, romPath string) error {
-	// Create temporary save state
-	saveState := &SaveState{
-		Version:     "1.0",
-		Timestamp:   time.Now(),
-		ROMPath:     romPath,
-		ROMChecksum: sm.calculateROMChecksum(romPath),
-		SlotNumber:  -1, // Export doesn't use slots
-		Description: fmt.Sprintf("Export %s", time.Now().Format("2006-01-02 15:04:05")),
-		FrameCount:  bus.GetFrameCount(),
-		CycleCount:  bus.GetCycleCount(),
-	}
-
-	// Fill in state data (simplified)
-	cpuState := bus.GetCPUState()
-	saveState.CPUState = CPUStateData{
-		PC:     cpuState.PC,
-		A:      cpuState.A,
-		X:      cpuState.X,
-		Y:      cpuState.Y,
-		SP:     cpuState.SP,
-		Cycles: cpuState.Cycles,
-		Flags: CPUFlagsData{
-			N: cpuState.Flags.N,
-			V: cpuState.Flags.V,
-			B: cpuState.Flags.B,
-			D: cpuState.Flags.D,
-			I: cpuState.Flags.I,
-			Z: cpuState.Flags.Z,
-			C: cpuState.Flags.C,
-		},
+	// Create save state (export doesn't use slots)
+	saveState, err := sm.captureState(bus, -1, romPath, 0,
+		fmt.Sprintf("Export %s", time.Now().Format("2006-01-02 15:04:05")))
+	if err != nil {
+		return fmt.Errorf("failed to capture state: %v", err)
 	}
 
 	// Save to specified file
 	return sm.saveToFile(saveState, filePath)
 }
 
-// ImportState imports a save state from a specific file
+// ImportState loads a standalone state file. Unlike the slots, which may be
+// matched by the ROM's path, it must carry the loaded ROM's checksum.
 func (sm *StateManager) ImportState(bus *bus.Bus, filePath string, romPath string) error {
 	// Load from file
 	saveState, err := sm.loadFromFile(filePath)
@@ -493,7 +760,7 @@ func (sm *StateManager) ImportState(bus *bus.Bus, filePath string, romPath strin
 	}
 
 	// Validate and restore
-	if err := sm.validateSaveState(saveState, romPath); err != nil {
+	if err := sm.validateImportedState(saveState); err != nil {
 		return fmt.Errorf("invalid imported state: %v", err)
 	}
 
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStateROMChecksum(t *testing.T) {
+	app := newTestApplication(t)
+	rom := testROM(t)
+	loadTestROM(t, app, rom)
+
+	// The checksum is taken at load: the file can be gone by the time a
+	// state is saved
+	if err := os.Remove(app.romPath); err != nil {
+		t.Fatal(err)
+	}
+	export := filepath.Join(t.TempDir(), "state.save")
+	if err := app.states.ExportState(app.bus, export, app.romPath); err != nil {
+		t.Fatalf("ExportState: %v", err)
+	}
+
+	load := func(name string, data []byte) {
+		t.Helper()
+		path := filepath.Join(app.config.Paths.Config, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := app.LoadROM(path); err != nil {
+			t.Fatalf("LoadROM(%s): %v", name, err)
+		}
+	}
+
+	// The same PRG and CHR under another header and file name
+	reheadered := append([]byte(nil), rom...)
+	reheadered[6] |= 0x01 // Vertical mirroring
+	load("reheadered.nes", reheadered)
+	if err := app.ImportState(export); err != nil {
+		t.Errorf("ImportState with the same PRG and CHR: %v", err)
+	}
+
+	// Other PRG, as a patch gives
+	patched := append([]byte(nil), rom...)
+	patched[16+0x100] ^= 0xFF
+	load("patched.nes", patched)
+	if err := app.ImportState(export); err == nil || !strings.Contains(err.Error(), "different ROM") {
+		t.Errorf("ImportState with other PRG = %v, want a different ROM error", err)
+	}
+}
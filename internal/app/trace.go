@@ -0,0 +1,289 @@
+// Package app provides the CPU trace logger and the crash trace dump.
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gones/internal/trace"
+)
+
+// crashTraceSize is how many instructions debug.trace.crash_dump keeps
+const crashTraceSize = 10000
+
+// validTraceRanges drops (with a warning) the address ranges that do not parse
+func validTraceRanges(ranges []string) []string {
+	valid := ranges[:0]
+	for _, r := range ranges {
+		if _, err := trace.ParseRange(r); err != nil {
+			fmt.Printf("[APP_WARNING] Ignoring trace filter: %v\n", err)
+			continue
+		}
+		valid = append(valid, r)
+	}
+	return valid
+}
+
+// traceOptions builds the tracer options from the config. Invalid filters
+// were dropped when the config was validated.
+func (app *Application) traceOptions() trace.Options {
+	config := &app.config.Debug.Trace
+	options := trace.Options{
+		Instructions: config.Instructions,
+		Memory:       config.Memory,
+		Banks:        config.Banks,
+		RingSize:     config.RingSize,
+	}
+	for _, s := range config.Ranges {
+		if r, err := trace.ParseRange(s); err == nil {
+			options.Ranges = append(options.Ranges, r)
+		}
+	}
+	for _, s := range config.AccessRanges {
+		if r, err := trace.ParseRange(s); err == nil {
+			options.AccessRanges = append(options.AccessRanges, r)
+		}
+	}
+	for _, s := range config.Opcodes {
+		if opcodes, err := trace.ParseOpcodes(s); err == nil {
+			options.Opcodes = append(options.Opcodes, opcodes...)
+		}
+	}
+	return options
+}
+
+// getTracePath returns the trace log file of the current ROM
+func (app *Application) getTracePath() string {
+	if app.config.Debug.Trace.File != "" {
+		return app.config.Debug.Trace.File
+	}
+	romName := filepath.Base(app.romPath)
+	romNameWithoutExt := romName[:len(romName)-len(filepath.Ext(romName))]
+	return filepath.Join(app.config.Paths.Logs, romNameWithoutExt+".trace.log")
+}
+
+// traceBank returns the 16KB PRG ROM bank a CPU address maps to
+func (app *Application) traceBank(address uint16) (int, bool) {
+	if app.cartridge == nil {
+		return 0, false
+	}
+	offset, ok := app.cartridge.PRGOffset(address)
+	return offset / 0x4000, ok
+}
+
+// SetTraceFile traces to path from the next ROM loaded, with the filters of
+// debug.trace
+func (app *Application) SetTraceFile(path string) {
+	app.config.Debug.Trace.File = path
+	app.config.Debug.Trace.Enabled = true
+}
+
+// IsTracing returns whether the trace logger is running
+func (app *Application) IsTracing() bool {
+	return app.tracer != nil
+}
+
+// StartTrace starts the trace logger with the debug.trace settings. In ring
+// mode nothing is written until StopTrace.
+func (app *Application) StartTrace() error {
+	if app.cartridge == nil || app.bus == nil || app.bus.CPU == nil {
+		return fmt.Errorf("no ROM loaded")
+	}
+	if app.tracer != nil {
+		return nil
+	}
+
+	options := app.traceOptions()
+	if options.RingSize > 0 {
+		app.tracer = trace.New(nil, options, app.traceBank)
+	} else {
+		path := app.getTracePath()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create trace directory: %v", err)
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create trace file: %v", err)
+		}
+		app.traceFile = file
+		app.tracer = trace.New(file, options, app.traceBank)
+	}
+	app.installDebugHooks()
+	return nil
+}
+
+// StopTrace stops the trace logger, writing the ring buffer out in ring mode
+func (app *Application) StopTrace() error {
+	tracer := app.tracer
+	if tracer == nil {
+		return nil
+	}
+	app.tracer = nil
+	app.installDebugHooks()
+
+	if tracer.IsRing() {
+		return writeTraceRing(tracer, app.getTracePath(), "")
+	}
+	err := tracer.Flush()
+	if closeErr := app.traceFile.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close trace file: %v", closeErr)
+	}
+	app.traceFile = nil
+	return err
+}
+
+// ToggleTrace handles the trace hotkey
+func (app *Application) ToggleTrace() {
+	if app.tracer != nil {
+		path := app.getTracePath()
+		if err := app.StopTrace(); err != nil {
+			fmt.Printf("[APP_ERROR] Trace failed: %v\n", err)
+			return
+		}
+		fmt.Printf("📜 Trace saved: %s\n", path)
+		return
+	}
+	if err := app.StartTrace(); err != nil {
+		fmt.Printf("[APP_ERROR] Trace failed: %v\n", err)
+		return
+	}
+	fmt.Printf("📜 Tracing to %s (Ctrl+F9 to stop)\n", app.getTracePath())
+}
+
+// startTraces starts the tracers the config asks for when a ROM is loaded
+func (app *Application) startTraces() {
+	if app.config.Debug.Trace.CrashDump && app.crashTrace == nil {
+		app.crashTrace = trace.New(nil, trace.Options{Instructions: true, RingSize: crashTraceSize}, app.traceBank)
+	}
+	if app.config.Debug.Trace.Enabled {
+		if err := app.StartTrace(); err != nil {
+			fmt.Printf("[APP_ERROR] Trace failed: %v\n", err)
+		}
+	}
+	app.installDebugHooks()
+}
+
+// stopTraces stops the tracers of the current ROM
+func (app *Application) stopTraces() {
+	if err := app.StopTrace(); err != nil {
+		fmt.Printf("[APP_ERROR] Trace failed: %v\n", err)
+	}
+	app.crashTrace = nil
+	app.installDebugHooks()
+}
+
+// writeTraceRing writes a ring mode tracer to a file, after a comment line
+// with the reason if there is one
+func writeTraceRing(tracer *trace.Tracer, path, reason string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create trace directory: %v", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create trace file: %v", err)
+	}
+	defer file.Close()
+
+	if reason != "" {
+		if _, err := fmt.Fprintf(file, "# %s\n", reason); err != nil {
+			return fmt.Errorf("failed to write trace: %v", err)
+		}
+	}
+	return tracer.Dump(file)
+}
+
+// traceState returns the CPU and PPU state before the instruction at pc
+func (app *Application) traceState(pc uint16) trace.CPUState {
+	app.bus.Sync() // The PPU may be behind in a batch
+	cpu, ppu := app.bus.CPU, app.bus.PPU
+	return trace.CPUState{
+		PC:       pc,
+		A:        cpu.A,
+		X:        cpu.X,
+		Y:        cpu.Y,
+		P:        cpu.GetStatusByte(),
+		SP:       cpu.SP,
+		Cycles:   cpu.GetCycles(),
+		Scanline: ppu.GetScanline(),
+		Dot:      ppu.GetCycle(),
+	}
+}
+
+// installDebugHooks routes the CPU fetch and memory access hooks to the
+// code/data logger, tracers and breakpoints in use, removing unused hooks so
+// they cost nothing. Called whenever one starts or stops and after the bus
+// is rebuilt by loading a ROM.
+func (app *Application) installDebugHooks() {
+	if app.bus == nil || app.bus.CPU == nil || app.bus.Memory == nil {
+		return
+	}
+	memory := app.bus.Memory
+	read := func(address uint16) uint8 {
+		value, _ := memory.Peek(address)
+		return value
+	}
+
+	var fetches []func(pc uint16)
+	if app.cdl != nil {
+		fetches = append(fetches, app.cdl.Fetch)
+	}
+	for _, tracer := range []*trace.Tracer{app.tracer, app.crashTrace} {
+		if tracer != nil {
+			fetches = append(fetches, func(pc uint16) { tracer.Instruction(app.traceState(pc), read) })
+		}
+	}
+	switch len(fetches) {
+	case 0:
+		app.bus.CPU.SetFetchHook(nil)
+	case 1:
+		app.bus.CPU.SetFetchHook(fetches[0])
+	default:
+		app.bus.CPU.SetFetchHook(func(pc uint16) {
+			for _, fetch := range fetches {
+				fetch(pc)
+			}
+		})
+	}
+
+	var accesses []func(address uint16, value uint8, write bool)
+	if app.tracer != nil && app.config.Debug.Trace.Memory {
+		accesses = append(accesses, app.tracer.Access)
+	}
+	if app.hasBreakpoints(BreakRead, BreakWrite) {
+		accesses = append(accesses, app.checkWatchpoints)
+	}
+	switch len(accesses) {
+	case 0:
+		memory.SetAccessHook(nil)
+	case 1:
+		memory.SetAccessHook(accesses[0])
+	default:
+		memory.SetAccessHook(func(address uint16, value uint8, write bool) {
+			for _, access := range accesses {
+				access(address, value, write)
+			}
+		})
+	}
+
+	if app.emulator != nil {
+		if app.hasBreakpoints(BreakExecute) {
+			app.emulator.SetBreakpointFunc(app.checkExecuteBreakpoints)
+		} else {
+			app.emulator.SetBreakpointFunc(nil)
+		}
+	}
+
+	vram := app.bus.PPU.GetMemory()
+	if app.cdl != nil {
+		memory.SetPRGAccessHook(app.cdl.ReadPRG)
+		if vram != nil {
+			vram.SetCHRAccessHook(app.cdl.ReadCHR)
+		}
+	} else {
+		memory.SetPRGAccessHook(nil)
+		if vram != nil {
+			vram.SetCHRAccessHook(nil)
+		}
+	}
+}
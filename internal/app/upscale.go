@@ -0,0 +1,71 @@
+// Package app provides upscaler selection and scaled frame output.
+package app
+
+import (
+	"fmt"
+
+	"gones/internal/graphics"
+)
+
+// isKnownUpscaler reports whether name is one of graphics.Upscalers
+func isKnownUpscaler(name string) bool {
+	for _, upscaler := range graphics.Upscalers {
+		if name == upscaler {
+			return true
+		}
+	}
+	return false
+}
+
+// presentFrame sends the finished frame to the window, upscaling it first when an
+// upscaler is selected and the window can show larger frames
+func (app *Application) presentFrame(frameBuffer *[256 * 240]uint32) error {
+	renderer, ok := app.window.(graphics.ScaledFrameRenderer)
+	if ok && app.videoProcessor != nil && app.videoProcessor.GetUpscaler() != graphics.UpscalerNone {
+		pixels, width, height := app.videoProcessor.UpscaleFrame(frameBuffer[:], 256, 240)
+		if err := renderer.RenderScaledFrame(pixels, width, height); err != nil {
+			return fmt.Errorf("failed to render scaled frame: %v", err)
+		}
+		return nil
+	}
+
+	if err := app.window.RenderFrame(frameBuffer); err != nil {
+		return fmt.Errorf("failed to render NES frame: %v", err)
+	}
+	return nil
+}
+
+// SetUpscaler selects the upscaler by name (see graphics.Upscalers)
+func (app *Application) SetUpscaler(name string) error {
+	if !isKnownUpscaler(name) {
+		return fmt.Errorf("unknown upscaler: %s", name)
+	}
+	if app.videoProcessor != nil {
+		if err := app.videoProcessor.SetUpscaler(name); err != nil {
+			return err
+		}
+	}
+	app.config.Video.Upscaler = name
+	return nil
+}
+
+// GetUpscaler returns the name of the selected upscaler
+func (app *Application) GetUpscaler() string {
+	return app.config.Video.Upscaler
+}
+
+// CycleUpscaler switches to the next upscaler in graphics.Upscalers
+func (app *Application) CycleUpscaler() {
+	next := graphics.Upscalers[0]
+	for i, name := range graphics.Upscalers {
+		if name == app.config.Video.Upscaler {
+			next = graphics.Upscalers[(i+1)%len(graphics.Upscalers)]
+			break
+		}
+	}
+	if err := app.SetUpscaler(next); err != nil {
+		fmt.Printf("[APP_ERROR] %v\n", err)
+		return
+	}
+	fmt.Printf("[APP_DEBUG] Upscaler: %s\n", next)
+}
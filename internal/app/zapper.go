@@ -0,0 +1,46 @@
+// Package app provides Zapper light gun input handling.
+package app
+
+import (
+	"gones/internal/graphics"
+)
+
+// handleMouseInput aims and fires the Zapper with the mouse. The left button pulls
+// the trigger at the pointer; the right button fires off screen (Duck Hunt and
+// Wild Gunman treat that as a miss, and some games use it to reload).
+// With the Arkanoid paddle on port 2 the mouse drives the paddle instead.
+func (app *Application) handleMouseInput(event graphics.InputEvent) {
+	if app.bus == nil || app.IsSlotPickerVisible() {
+		return
+	}
+	if paddle := app.bus.GetArkanoid(); paddle != nil {
+		app.handlePaddleMouse(paddle, event)
+		return
+	}
+	zapper := app.bus.GetZapper()
+	if zapper == nil {
+		return
+	}
+
+	if event.MouseButtons&graphics.MouseButtonRight != 0 {
+		zapper.SetPosition(-1, -1)
+		zapper.SetTrigger(true)
+		return
+	}
+	zapper.SetPosition(event.MouseX, event.MouseY)
+	zapper.SetTrigger(event.MouseButtons&graphics.MouseButtonLeft != 0)
+}
+
+// SetZapperEnabled plugs the Zapper into port 2 (or restores controller 2)
+func (app *Application) SetZapperEnabled(enabled bool) {
+	device := Port2Controller
+	if enabled {
+		device = Port2Zapper
+	}
+	app.SetPort2Device(device)
+}
+
+// IsZapperEnabled returns whether the Zapper is plugged into port 2
+func (app *Application) IsZapperEnabled() bool {
+	return app.GetPort2Device() == Port2Zapper
+}
@@ -1,6 +1,8 @@
 // Package apu implements the Audio Processing Unit for the NES.
 package apu
 
+import "gones/internal/region"
+
 // APU represents the NES Audio Processing Unit
 type APU struct {
 	// APU channels
@@ -20,12 +22,26 @@ type APU struct {
 	// Channel enable flags
 	channelEnable [5]bool // pulse1, pulse2, triangle, noise, dmc
 
+	// Sound chip on the cartridge, mixed with the channels (nil for none)
+	expansion ExpansionAudio
+
+	// Reads the DMC's sample bytes from CPU memory (nil reads none)
+	dmcReader func(address uint16) uint8
+
+	// Channel outputs kept for oscilloscope views (nil when off)
+	scope *channelScope
+
 	// Audio generation
 	sampleBuffer     []float32
 	sampleRate       int     // Target sample rate (e.g., 44100 Hz)
 	cpuFrequency     float64 // NES CPU frequency
 	cycleAccumulator float64 // For sample rate conversion
 
+	// Rates of the console's region
+	frameSteps   *[5]uint16 // CPU cycles of the frame counter's steps
+	noisePeriods *[16]uint16
+	dmcRates     *[16]uint16
+
 	// Timing
 	cycles uint64
 }
@@ -129,12 +145,16 @@ type DMCChannel struct {
 
 	// Internal state
 	timerCounter      uint16 // Current timer value
-	sampleBuffer      uint8  // Current sample byte
-	sampleBufferBits  uint8  // Remaining bits in sample buffer
+	sampleBuffer      uint8  // Sample byte fetched for the next output cycle
 	sampleBufferEmpty bool   // Sample buffer empty flag
 	bytesRemaining    uint16 // Bytes remaining in sample
 	currentAddress    uint16 // Current read address
 
+	// Output unit
+	shiftRegister uint8 // Bits of the byte being played
+	bitsRemaining uint8 // Bits left in the output cycle
+	silence       bool  // Output cycle started with an empty buffer
+
 	// IRQ flag
 	irqFlag bool // DMC IRQ flag
 
@@ -150,14 +170,28 @@ func New() *APU {
 		cpuFrequency:   1789773.0, // NTSC CPU frequency
 		frameMode:      false,     // Default to 4-step mode
 		frameIRQEnable: true,      // Frame IRQ enabled by default
+		frameSteps:     &frameStepsNTSC,
+		noisePeriods:   &noisePeriodTable,
+		dmcRates:       &dmcRateTable,
 	}
 
 	// Initialize noise shift register
 	apu.noise.shiftRegister = 1
+	apu.dmc = DMCChannel{sampleBufferEmpty: true, silence: true}
 
 	return apu
 }
 
+// SetRegion sets the clock and rates to those of a region's console. The
+// Dendy's APU runs at NTSC rates.
+func (apu *APU) SetRegion(r region.Region) {
+	apu.cpuFrequency = r.Timing().CPUClock
+	apu.frameSteps, apu.noisePeriods, apu.dmcRates = &frameStepsNTSC, &noisePeriodTable, &dmcRateTable
+	if r == region.PAL {
+		apu.frameSteps, apu.noisePeriods, apu.dmcRates = &frameStepsPAL, &noisePeriodTablePAL, &dmcRateTablePAL
+	}
+}
+
 // Reset resets the APU to its initial state
 func (apu *APU) Reset() {
 	// Reset all channels
@@ -165,7 +199,7 @@ func (apu *APU) Reset() {
 	apu.pulse2 = PulseChannel{}
 	apu.triangle = TriangleChannel{}
 	apu.noise = NoiseChannel{shiftRegister: 1} // Initialize LFSR
-	apu.dmc = DMCChannel{}
+	apu.dmc = DMCChannel{sampleBufferEmpty: true, silence: true}
 
 	// Reset frame counter
 	apu.frameCounter = 0
@@ -196,6 +230,9 @@ func (apu *APU) Step() {
 
 	// Step each channel's timer
 	apu.stepChannelTimers()
+	if apu.expansion != nil {
+		apu.expansion.Clock()
+	}
 
 	// Generate audio sample if needed
 	apu.generateSample()
@@ -204,18 +241,19 @@ func (apu *APU) Step() {
 // stepFrameCounter handles frame counter timing
 func (apu *APU) stepFrameCounter() {
 	apu.frameCounter++
+	steps := apu.frameSteps
 
 	if apu.frameMode {
 		// 5-step mode
 		switch apu.frameCounter {
-		case 7457:
+		case steps[0]:
 			apu.clockEnvelopeAndLinear()
-		case 14913:
+		case steps[1]:
 			apu.clockEnvelopeAndLinear()
 			apu.clockLengthAndSweep()
-		case 22371:
+		case steps[2]:
 			apu.clockEnvelopeAndLinear()
-		case 37281:
+		case steps[4]:
 			apu.clockEnvelopeAndLinear()
 			apu.clockLengthAndSweep()
 			apu.frameCounter = 0
@@ -224,27 +262,36 @@ func (apu *APU) stepFrameCounter() {
 	} else {
 		// 4-step mode
 		switch apu.frameCounter {
-		case 7457:
+		case steps[0]:
 			apu.clockEnvelopeAndLinear()
-		case 14913:
+		case steps[1]:
 			apu.clockEnvelopeAndLinear()
 			apu.clockLengthAndSweep()
-		case 22371:
+		case steps[2]:
 			apu.clockEnvelopeAndLinear()
-		case 29829:
+		case steps[3] - 1:
+			apu.raiseFrameIRQ()
+		case steps[3]:
+			apu.raiseFrameIRQ()
 			apu.clockEnvelopeAndLinear()
 			apu.clockLengthAndSweep()
-		case 29830:
-			// Frame IRQ
-			if apu.frameIRQEnable {
-				apu.frameIRQFlag = true
-			}
+		case steps[3] + 1:
+			apu.raiseFrameIRQ()
 			apu.frameCounter = 0
 			apu.frameCounterStep = 0
 		}
 	}
 }
 
+// raiseFrameIRQ sets the frame IRQ flag unless $4017 inhibits it. The
+// 4-step sequence sets it on its last three cycles, so a $4015 read on the
+// first two does not keep it cleared.
+func (apu *APU) raiseFrameIRQ() {
+	if apu.frameIRQEnable {
+		apu.frameIRQFlag = true
+	}
+}
+
 // clockEnvelopeAndLinear clocks envelope and linear counter units
 func (apu *APU) clockEnvelopeAndLinear() {
 	apu.clockPulseEnvelope(&apu.pulse1)
@@ -277,9 +324,8 @@ func (apu *APU) stepChannelTimers() {
 	if apu.channelEnable[3] {
 		apu.stepNoiseTimer(&apu.noise)
 	}
-	if apu.channelEnable[4] {
-		apu.stepDMCTimer(&apu.dmc)
-	}
+	// The DMC plays out its buffer after $4015 stops it
+	apu.stepDMCTimer(&apu.dmc)
 }
 
 // generateSample generates an audio sample and adds it to the buffer
@@ -296,9 +342,17 @@ func (apu *APU) generateSample() {
 		triangleOut := apu.getTriangleOutput(&apu.triangle)
 		noiseOut := apu.getNoiseOutput(&apu.noise)
 		dmcOut := apu.getDMCOutput(&apu.dmc)
+		var expansionOut float64
+		if apu.expansion != nil {
+			expansionOut = apu.expansion.Output()
+		}
+
+		if apu.scope != nil {
+			apu.recordScope(pulse1Out, pulse2Out, triangleOut, noiseOut, dmcOut)
+		}
 
 		// Apply NES mixer formula
-		sample := apu.mixChannels(pulse1Out, pulse2Out, triangleOut, noiseOut, dmcOut)
+		sample := apu.mixChannels(pulse1Out, pulse2Out, triangleOut, noiseOut, dmcOut, expansionOut)
 
 		// Add to sample buffer
 		apu.sampleBuffer = append(apu.sampleBuffer, sample)
@@ -370,7 +424,10 @@ func (apu *APU) GetSamples() []float32 {
 	return samples
 }
 
-// ReadStatus reads the APU status register ($4015)
+// ReadStatus reads the APU status register ($4015): which length counters
+// are running, whether the DMC has bytes left, and the frame and DMC IRQ
+// flags. Reading clears the frame IRQ flag; the DMC's stays until $4015 is
+// written or $4010 disables its IRQ.
 func (apu *APU) ReadStatus() uint8 {
 	status := uint8(0)
 
@@ -441,6 +498,26 @@ var dmcRateTable = [16]uint16{
 	190, 160, 142, 128, 106, 84, 72, 54,
 }
 
+// Noise period table (PAL)
+var noisePeriodTablePAL = [16]uint16{
+	4, 8, 14, 30, 60, 88, 118, 148,
+	188, 236, 354, 472, 708, 944, 1890, 3778,
+}
+
+// DMC rate table (PAL)
+var dmcRateTablePAL = [16]uint16{
+	398, 354, 316, 298, 276, 236, 210, 198,
+	176, 148, 132, 118, 98, 78, 66, 50,
+}
+
+// Frame counter steps: the first four of the 4-step sequence, which raises
+// the frame IRQ a cycle after the fourth, then the fifth step of the 5-step
+// sequence
+var (
+	frameStepsNTSC = [5]uint16{7457, 14913, 22371, 29829, 37281}
+	frameStepsPAL  = [5]uint16{8313, 16627, 24939, 33252, 41565}
+)
+
 // Pulse channel register write methods
 
 // writePulseControl writes to pulse control register ($4000/$4004)
@@ -467,10 +544,21 @@ func (apu *APU) writePulseTimerLow(pulse *PulseChannel, value uint8) {
 	pulse.timer = (pulse.timer & 0xFF00) | uint16(value)
 }
 
-// writePulseTimerHigh writes to pulse timer high register ($4003/$4007)
+// pulseIndex returns the channelEnable index of a pulse channel
+func (apu *APU) pulseIndex(pulse *PulseChannel) int {
+	if pulse == &apu.pulse2 {
+		return 1
+	}
+	return 0
+}
+
+// writePulseTimerHigh writes to pulse timer high register ($4003/$4007). The
+// length counter is only loaded while $4015 enables the channel.
 func (apu *APU) writePulseTimerHigh(pulse *PulseChannel, value uint8) {
 	pulse.timer = (pulse.timer & 0x00FF) | (uint16(value&0x07) << 8)
-	pulse.lengthCounter = lengthTable[(value>>3)&0x1F]
+	if apu.channelEnable[apu.pulseIndex(pulse)] {
+		pulse.lengthCounter = lengthTable[(value>>3)&0x1F]
+	}
 	pulse.envelopeStart = true
 	pulse.dutyIndex = 0 // Reset duty cycle position
 }
@@ -565,10 +653,13 @@ func (apu *APU) writeTriangleTimerLow(value uint8) {
 	apu.triangle.timer = (apu.triangle.timer & 0xFF00) | uint16(value)
 }
 
-// writeTriangleTimerHigh writes to triangle timer high register ($400B)
+// writeTriangleTimerHigh writes to triangle timer high register ($400B),
+// loading the length counter while the channel is enabled
 func (apu *APU) writeTriangleTimerHigh(value uint8) {
 	apu.triangle.timer = (apu.triangle.timer & 0x00FF) | (uint16(value&0x07) << 8)
-	apu.triangle.lengthCounter = lengthTable[(value>>3)&0x1F]
+	if apu.channelEnable[2] {
+		apu.triangle.lengthCounter = lengthTable[(value>>3)&0x1F]
+	}
 	apu.triangle.linearCounterReload = true
 }
 
@@ -629,16 +720,19 @@ func (apu *APU) writeNoisePeriod(value uint8) {
 	apu.noise.periodIndex = value & 0x0F
 }
 
-// writeNoiseLength writes to noise length register ($400F)
+// writeNoiseLength writes to noise length register ($400F), loading the
+// length counter while the channel is enabled
 func (apu *APU) writeNoiseLength(value uint8) {
-	apu.noise.lengthCounter = lengthTable[(value>>3)&0x1F]
+	if apu.channelEnable[3] {
+		apu.noise.lengthCounter = lengthTable[(value>>3)&0x1F]
+	}
 	apu.noise.envelopeStart = true
 }
 
 // stepNoiseTimer steps the noise channel timer
 func (apu *APU) stepNoiseTimer(noise *NoiseChannel) {
 	if noise.timerCounter == 0 {
-		noise.timerCounter = noisePeriodTable[noise.periodIndex]
+		noise.timerCounter = apu.noisePeriods[noise.periodIndex]
 
 		// Clock shift register
 		feedback := noise.shiftRegister & 0x01
@@ -721,55 +815,94 @@ func (apu *APU) writeDMCSampleLength(value uint8) {
 	apu.dmc.sampleLength = (uint16(value) << 4) + 1
 }
 
-// stepDMCTimer steps the DMC channel timer
+// stepDMCTimer steps the DMC channel timer, which clocks the output unit,
+// then fetches a sample byte if the buffer is empty
 func (apu *APU) stepDMCTimer(dmc *DMCChannel) {
 	if dmc.timerCounter == 0 {
-		dmc.timerCounter = dmcRateTable[dmc.rateIndex]
+		dmc.timerCounter = apu.dmcRates[dmc.rateIndex]
+		apu.clockDMCOutput(dmc)
+	} else {
+		dmc.timerCounter--
+	}
 
-		if !dmc.sampleBufferEmpty {
-			// Clock output unit
-			if dmc.sampleBufferBits == 0 {
-				// No more bits in buffer
-				dmc.sampleBufferEmpty = true
-
-				if dmc.bytesRemaining > 0 {
-					// Load next sample byte
-					// TODO: Implement CPU memory access for DMC
-					dmc.sampleBuffer = 0 // Placeholder
-					dmc.sampleBufferBits = 8
-					dmc.sampleBufferEmpty = false
-					dmc.bytesRemaining--
-					dmc.currentAddress++
-
-					if dmc.bytesRemaining == 0 {
-						if dmc.loop {
-							// Restart sample
-							dmc.currentAddress = dmc.sampleAddress
-							dmc.bytesRemaining = dmc.sampleLength
-						} else if dmc.irqEnable {
-							dmc.irqFlag = true
-						}
-					}
-				}
-			} else {
-				// Process next bit
-				if (dmc.sampleBuffer & 0x01) != 0 {
-					if dmc.outputLevel <= 125 {
-						dmc.outputLevel += 2
-					}
-				} else {
-					if dmc.outputLevel >= 2 {
-						dmc.outputLevel -= 2
-					}
-				}
-
-				dmc.sampleBuffer >>= 1
-				dmc.sampleBufferBits--
+	if dmc.sampleBufferEmpty && dmc.bytesRemaining > 0 {
+		apu.fetchDMCSample(dmc)
+	}
+}
+
+// clockDMCOutput plays the next bit of the shift register, and starts a new
+// output cycle with the sample buffer's byte after the eighth
+func (apu *APU) clockDMCOutput(dmc *DMCChannel) {
+	if !dmc.silence {
+		if (dmc.shiftRegister & 0x01) != 0 {
+			if dmc.outputLevel <= 125 {
+				dmc.outputLevel += 2
+			}
+		} else {
+			if dmc.outputLevel >= 2 {
+				dmc.outputLevel -= 2
 			}
 		}
-	} else {
-		dmc.timerCounter--
 	}
+	dmc.shiftRegister >>= 1
+
+	if dmc.bitsRemaining > 0 {
+		dmc.bitsRemaining--
+	}
+	if dmc.bitsRemaining == 0 {
+		dmc.bitsRemaining = 8
+		dmc.silence = dmc.sampleBufferEmpty
+		if !dmc.sampleBufferEmpty {
+			dmc.shiftRegister = dmc.sampleBuffer
+			dmc.sampleBufferEmpty = true
+		}
+	}
+}
+
+// fetchDMCSample reads the next sample byte into the buffer. The address
+// wraps from $FFFF to $8000.
+func (apu *APU) fetchDMCSample(dmc *DMCChannel) {
+	if apu.dmcReader == nil {
+		return
+	}
+	dmc.sampleBuffer = apu.dmcReader(dmc.currentAddress)
+	dmc.sampleBufferEmpty = false
+	dmc.currentAddress++
+	if dmc.currentAddress == 0 {
+		dmc.currentAddress = 0x8000
+	}
+	dmc.bytesRemaining--
+
+	if dmc.bytesRemaining == 0 {
+		if dmc.loop {
+			// Restart sample
+			dmc.currentAddress = dmc.sampleAddress
+			dmc.bytesRemaining = dmc.sampleLength
+		} else if dmc.irqEnable {
+			dmc.irqFlag = true
+		}
+	}
+}
+
+// DMCFetchDue reports whether the DMC fetches a sample byte within the next
+// cycles CPU cycles
+func (apu *APU) DMCFetchDue(cycles uint64) bool {
+	dmc := &apu.dmc
+	if dmc.bytesRemaining == 0 || cycles == 0 {
+		return false
+	}
+	if dmc.sampleBufferEmpty {
+		return true
+	}
+	// The buffer empties when the timer starts the next output cycle
+	return dmc.bitsRemaining <= 1 && uint64(dmc.timerCounter) < cycles
+}
+
+// SetDMCReader sets the function the DMC reads its sample bytes from CPU
+// memory with: the bus, which stalls the CPU for the fetch. It is not part of
+// the APU's state.
+func (apu *APU) SetDMCReader(read func(address uint16) uint8) {
+	apu.dmcReader = read
 }
 
 // getDMCOutput gets the current DMC channel output
@@ -832,8 +965,9 @@ func (apu *APU) writeFrameCounter(value uint8) {
 	}
 }
 
-// mixChannels applies the NES audio mixer formula
-func (apu *APU) mixChannels(pulse1, pulse2, triangle, noise, dmc uint8) float32 {
+// mixChannels applies the NES audio mixer formula, adding the output of the
+// cartridge's sound chip
+func (apu *APU) mixChannels(pulse1, pulse2, triangle, noise, dmc uint8, expansion float64) float32 {
 	// Pulse mixing
 	pulseSum := float64(pulse1 + pulse2)
 	var pulseOut float64
@@ -849,7 +983,7 @@ func (apu *APU) mixChannels(pulse1, pulse2, triangle, noise, dmc uint8) float32
 	}
 
 	// Final output
-	output := pulseOut + tndOut
+	output := pulseOut + tndOut + expansion
 
 	// Scale to -1.0 to 1.0 range
 	return float32(output/30.0 - 1.0)
@@ -0,0 +1,76 @@
+// Package apu provides the hook for the sound chips of cartridges, such as
+// the VRC6 and the FDS, whose output the console mixes with the APU's.
+package apu
+
+// ExpansionAudio is a sound chip on the cartridge. The APU clocks it with
+// its own channels and adds its output to theirs.
+type ExpansionAudio interface {
+	// Clock runs the chip for one CPU cycle
+	Clock()
+	// Output returns the chip's output, on the scale of the APU's mixer,
+	// where a pulse channel at full volume gives pulseLevel
+	Output() float64
+}
+
+// ExpansionChannels is implemented by the sound chips that show each of
+// their channels on the scope
+type ExpansionChannels interface {
+	// ChannelNames returns the names of the chip's channels, such as "VRC6 SAW"
+	ChannelNames() []string
+	// ChannelOutputs writes each channel's output, from 0 to 1 of its range,
+	// to dst, which holds one for each name
+	ChannelOutputs(dst []float32)
+}
+
+// pulseLevel is the mixer's output for a lone pulse channel at full volume.
+// The expansion chips set their levels against it.
+const pulseLevel = 95.88 / (8128.0/15 + 100)
+
+// ExpansionChips mixes several expansion chips, for NSF music written for
+// more than one
+type ExpansionChips []ExpansionAudio
+
+// Clock runs each chip for one CPU cycle
+func (chips ExpansionChips) Clock() {
+	for _, chip := range chips {
+		chip.Clock()
+	}
+}
+
+// Output returns the sum of the chips' output
+func (chips ExpansionChips) Output() float64 {
+	var output float64
+	for _, chip := range chips {
+		output += chip.Output()
+	}
+	return output
+}
+
+// SetExpansionAudio sets the sound chip mixed with the APU's channels, nil
+// for none. It is not part of the APU's state.
+func (apu *APU) SetExpansionAudio(chip ExpansionAudio) {
+	apu.expansion = chip
+	if apu.scope != nil {
+		apu.scope.setExpansion(chip)
+	}
+}
+
+// ChannelNames returns the names of the chips' channels, chip after chip
+func (chips ExpansionChips) ChannelNames() []string {
+	var names []string
+	for _, chip := range chips {
+		names = append(names, expansionChannels(chip).ChannelNames()...)
+	}
+	return names
+}
+
+// ChannelOutputs writes the outputs of the chips' channels to dst, chip
+// after chip
+func (chips ExpansionChips) ChannelOutputs(dst []float32) {
+	for _, chip := range chips {
+		channels := expansionChannels(chip)
+		n := len(channels.ChannelNames())
+		channels.ChannelOutputs(dst[:n])
+		dst = dst[n:]
+	}
+}
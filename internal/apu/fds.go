@@ -0,0 +1,243 @@
+// Package apu implements the Famicom Disk System's sound: a wavetable channel
+// whose pitch a second wavetable modulates.
+package apu
+
+// fdsLevel is the FDS's output for each step of its wave sample times its
+// gain, at the loudest master volume; at full gain it is about 2.4 times as
+// loud as an APU pulse
+const fdsLevel = 2.4 * pulseLevel / (63 * 32)
+
+// fdsMasterVolume is the output scale of each $4089 master volume setting
+var fdsMasterVolume = [4]float64{1, 2.0 / 3, 2.0 / 4, 2.0 / 5}
+
+// fdsModSteps is how each 3-bit modulation table entry moves the modulation
+// counter; 4 resets it
+var fdsModSteps = [8]int{0, 1, 2, 4, 0, -4, -2, -1}
+
+// FDSAudio is the sound of the Famicom Disk System's RP2C33, used by Zelda no
+// Densetsu and Ai Senshi Nicol. Its registers are at $4040-$407F (the wave
+// table), $4080-$408A (envelopes, pitch, modulation and volume) and
+// $4090/$4092 (the envelopes' gains, read only).
+type FDSAudio struct {
+	wave      [64]uint8
+	waveWrite bool // $4089 bit 7: wave table writable, playback held
+	master    uint8
+
+	volume      fdsEnvelope // $4080
+	modEnvelope fdsEnvelope // $4084
+	envSpeed    uint8       // $408A: scales both envelopes' speeds
+	envDisabled bool        // $4083 bit 6
+
+	frequency uint16 // $4082/$4083
+	waveHalt  bool   // $4083 bit 7
+	waveAccum uint32
+	wavePos   uint8
+	gainLatch uint8 // Volume gain, latched at the start of each wave cycle
+
+	modTable     [64]uint8
+	modPos       uint8
+	modCounter   int8   // $4085, 7-bit signed
+	modFrequency uint16 // $4086/$4087
+	modHalt      bool   // $4087 bit 7: modulation stopped, table writable
+	modAccum     uint32
+}
+
+// fdsEnvelope is one of the FDS's envelopes, which move their gain by one
+// towards 0 or 32 every 8 * (speed+1) * $408A CPU cycles
+type fdsEnvelope struct {
+	gain     uint8
+	speed    uint8
+	increase bool
+	off      bool // Gain set directly
+	timer    int
+}
+
+// NewFDSAudio creates the FDS's sound, with the envelope speed the BIOS sets
+func NewFDSAudio() *FDSAudio {
+	return &FDSAudio{envSpeed: 0xE8}
+}
+
+// write sets an envelope from $4080 or $4084
+func (e *fdsEnvelope) write(value uint8) {
+	e.off = value&0x80 != 0
+	e.increase = value&0x40 != 0
+	e.speed = value & 0x3F
+	if e.off {
+		e.gain = value & 0x3F
+	}
+	e.timer = 0
+}
+
+// clock runs the envelope for one CPU cycle
+func (e *fdsEnvelope) clock(masterSpeed uint8) {
+	if e.off {
+		return
+	}
+	if e.timer > 0 {
+		e.timer--
+		return
+	}
+	e.timer = 8 * (int(e.speed) + 1) * int(masterSpeed)
+	if e.increase && e.gain < 32 {
+		e.gain++
+	} else if !e.increase && e.gain > 0 {
+		e.gain--
+	}
+}
+
+// Write writes a sound register
+func (f *FDSAudio) Write(address uint16, value uint8) {
+	if address >= 0x4040 && address < 0x4080 {
+		if f.waveWrite {
+			f.wave[address-0x4040] = value & 0x3F
+		}
+		return
+	}
+
+	switch address {
+	case 0x4080:
+		f.volume.write(value)
+	case 0x4082:
+		f.frequency = f.frequency&0xF00 | uint16(value)
+	case 0x4083:
+		f.frequency = f.frequency&0x0FF | uint16(value&0x0F)<<8
+		f.envDisabled = value&0x40 != 0
+		f.waveHalt = value&0x80 != 0
+		if f.waveHalt {
+			f.waveAccum = 0
+			f.wavePos = 0
+		}
+	case 0x4084:
+		f.modEnvelope.write(value)
+	case 0x4085:
+		f.modCounter = int8(value<<1) >> 1
+	case 0x4086:
+		f.modFrequency = f.modFrequency&0xF00 | uint16(value)
+	case 0x4087:
+		f.modFrequency = f.modFrequency&0x0FF | uint16(value&0x0F)<<8
+		f.modHalt = value&0x80 != 0
+		if f.modHalt {
+			f.modAccum = 0
+		}
+	case 0x4088:
+		// Each entry takes two steps of the table
+		if f.modHalt {
+			f.modTable[f.modPos] = value & 0x07
+			f.modTable[(f.modPos+1)&0x3F] = value & 0x07
+			f.modPos = (f.modPos + 2) & 0x3F
+		}
+	case 0x4089:
+		f.waveWrite = value&0x80 != 0
+		f.master = value & 0x03
+	case 0x408A:
+		f.envSpeed = value
+	}
+}
+
+// Read reads the wave table and the envelopes' gains. It returns false for
+// other addresses.
+func (f *FDSAudio) Read(address uint16) (uint8, bool) {
+	switch {
+	case address >= 0x4040 && address < 0x4080:
+		return f.wave[address-0x4040], true
+	case address == 0x4090:
+		return f.volume.gain, true
+	case address == 0x4092:
+		return f.modEnvelope.gain, true
+	}
+	return 0, false
+}
+
+// Clock runs the envelopes, the modulator and the wave for one CPU cycle
+func (f *FDSAudio) Clock() {
+	if !f.waveHalt && !f.envDisabled && f.envSpeed > 0 {
+		f.volume.clock(f.envSpeed)
+		f.modEnvelope.clock(f.envSpeed)
+	}
+
+	if !f.modHalt && f.modFrequency > 0 {
+		f.modAccum += uint32(f.modFrequency)
+		if f.modAccum >= 0x10000 {
+			f.modAccum &= 0xFFFF
+			if step := f.modTable[f.modPos]; step == 4 {
+				f.modCounter = 0
+			} else {
+				f.modCounter = int8((int(f.modCounter)+fdsModSteps[step])<<1) >> 1
+			}
+			f.modPos = (f.modPos + 1) & 0x3F
+		}
+	}
+
+	if f.waveHalt || f.waveWrite {
+		return
+	}
+	pitch := int(f.frequency) + f.modPitch()
+	if pitch <= 0 {
+		return
+	}
+	f.waveAccum += uint32(pitch)
+	if f.waveAccum >= 0x10000 {
+		f.waveAccum &= 0xFFFF
+		f.wavePos = (f.wavePos + 1) & 0x3F
+		if f.wavePos == 0 {
+			f.gainLatch = min(f.volume.gain, 32)
+		}
+	}
+}
+
+// modPitch returns how much the modulator moves the wave's frequency, from
+// the modulation counter, the modulation gain and the frequency, rounded as
+// the chip rounds it
+func (f *FDSAudio) modPitch() int {
+	temp := int(f.modCounter) * int(f.modEnvelope.gain)
+	remainder := temp & 0x0F
+	temp >>= 4
+	if remainder > 0 && temp&0x80 == 0 {
+		if f.modCounter < 0 {
+			temp--
+		} else {
+			temp += 2
+		}
+	}
+	if temp >= 192 {
+		temp -= 256
+	} else if temp < -64 {
+		temp += 256
+	}
+
+	temp *= int(f.frequency)
+	remainder = temp & 0x3F
+	temp >>= 6
+	if remainder >= 32 {
+		temp++
+	}
+	return temp
+}
+
+// Output returns the wave's sample times the latched volume gain, scaled by
+// the master volume
+func (f *FDSAudio) Output() float64 {
+	return f.waveOutput() * fdsLevel
+}
+
+// waveOutput returns the wave sample times its gain and the master volume,
+// 0-63*32
+func (f *FDSAudio) waveOutput() float64 {
+	if f.waveWrite {
+		return 0
+	}
+	return float64(int(f.wave[f.wavePos])*int(f.gainLatch)) * fdsMasterVolume[f.master]
+}
+
+// fdsChannelNames names the FDS's channel on the scope
+var fdsChannelNames = []string{"FDS"}
+
+// ChannelNames returns the name of the wave channel
+func (f *FDSAudio) ChannelNames() []string {
+	return fdsChannelNames
+}
+
+// ChannelOutputs writes the wave channel's output to dst
+func (f *FDSAudio) ChannelOutputs(dst []float32) {
+	dst[0] = float32(min(f.waveOutput()/(63*32), 1))
+}
@@ -0,0 +1,161 @@
+// Package apu implements the MMC5's sound: two pulse channels like the APU's,
+// without sweep, and an 8-bit PCM channel.
+package apu
+
+// mmc5FrameCycles is the period of the MMC5's own frame sequencer, which
+// clocks the envelopes and length counters at 240 Hz
+const mmc5FrameCycles = 7457
+
+// MMC5Audio is the sound of Nintendo's MMC5, used by Just Breed and Shin 4
+// Nin Uchi Mahjong. Its registers are at $5000-$5007 (the pulses), $5010-
+// $5011 (PCM) and $5015 (channel enables).
+type MMC5Audio struct {
+	pulses  [2]mmc5Pulse
+	pcm     uint8 // $5011, raw PCM written by the CPU
+	pcmRead bool  // $5010 bit 0: PCM from CPU reads, not emulated
+
+	cycles uint16 // CPU cycles since the last frame sequencer clock
+	even   bool   // Pulse timers run every other CPU cycle, as the APU's
+}
+
+// mmc5Pulse is an MMC5 pulse channel: the APU's pulse without a sweep unit
+type mmc5Pulse struct {
+	enabled bool
+	PulseChannel
+}
+
+// NewMMC5Audio creates the MMC5's sound
+func NewMMC5Audio() *MMC5Audio {
+	return &MMC5Audio{}
+}
+
+// Write writes a sound register
+func (m *MMC5Audio) Write(address uint16, value uint8) {
+	switch address {
+	case 0x5000, 0x5004:
+		p := &m.pulses[(address>>2)&1].PulseChannel
+		p.dutyCycle = value >> 6
+		p.envelopeLoop = value&0x20 != 0
+		p.lengthHalt = p.envelopeLoop
+		p.envelopeDisable = value&0x10 != 0
+		p.volume = value & 0x0F
+	case 0x5002, 0x5006:
+		p := &m.pulses[(address>>2)&1].PulseChannel
+		p.timer = p.timer&0x700 | uint16(value)
+	case 0x5003, 0x5007:
+		pulse := &m.pulses[(address>>2)&1]
+		p := &pulse.PulseChannel
+		p.timer = p.timer&0x0FF | uint16(value&0x07)<<8
+		if pulse.enabled {
+			p.lengthCounter = lengthTable[value>>3]
+		}
+		p.envelopeStart = true
+		p.sequencerPos = 0
+	case 0x5010:
+		m.pcmRead = value&0x01 != 0
+	case 0x5011:
+		// Zero is ignored, as in read mode it would end the sample
+		if !m.pcmRead && value != 0 {
+			m.pcm = value
+		}
+	case 0x5015:
+		for i := range m.pulses {
+			m.pulses[i].enabled = value&(1<<i) != 0
+			if !m.pulses[i].enabled {
+				m.pulses[i].lengthCounter = 0
+			}
+		}
+	}
+}
+
+// Read reads $5015, whose bits 0-1 tell whether the pulses' length counters
+// are running. It returns false for other addresses.
+func (m *MMC5Audio) Read(address uint16) (uint8, bool) {
+	if address != 0x5015 {
+		return 0, false
+	}
+	var status uint8
+	for i, p := range m.pulses {
+		if p.lengthCounter > 0 {
+			status |= 1 << i
+		}
+	}
+	return status, true
+}
+
+// Clock runs the pulse timers and the frame sequencer for one CPU cycle
+func (m *MMC5Audio) Clock() {
+	m.even = !m.even
+	if m.even {
+		for i := range m.pulses {
+			p := &m.pulses[i].PulseChannel
+			if p.timerCounter == 0 {
+				p.timerCounter = p.timer
+				p.sequencerPos = (p.sequencerPos + 1) & 0x07
+			} else {
+				p.timerCounter--
+			}
+		}
+	}
+
+	m.cycles++
+	if m.cycles < mmc5FrameCycles {
+		return
+	}
+	m.cycles = 0
+	for i := range m.pulses {
+		p := &m.pulses[i].PulseChannel
+		switch {
+		case p.envelopeStart:
+			p.envelopeStart = false
+			p.envelopeCounter = 15
+			p.envelopeDivider = p.volume
+		case p.envelopeDivider > 0:
+			p.envelopeDivider--
+		default:
+			p.envelopeDivider = p.volume
+			if p.envelopeCounter > 0 {
+				p.envelopeCounter--
+			} else if p.envelopeLoop {
+				p.envelopeCounter = 15
+			}
+		}
+		if !p.lengthHalt && p.lengthCounter > 0 {
+			p.lengthCounter--
+		}
+	}
+}
+
+// Output returns the pulses, which have no minimum period unlike the APU's,
+// and the PCM channel
+func (m *MMC5Audio) Output() float64 {
+	sum := m.pulseOutput(0) + m.pulseOutput(1)
+	return float64(sum)*pulseLevel/15 + float64(m.pcm)*pulseLevel/255
+}
+
+// pulseOutput returns a pulse channel's output, 0-15
+func (m *MMC5Audio) pulseOutput(channel int) int {
+	p := &m.pulses[channel].PulseChannel
+	if p.lengthCounter == 0 || dutyTable[p.dutyCycle][p.sequencerPos] == 0 {
+		return 0
+	}
+	if p.envelopeDisable {
+		return int(p.volume)
+	}
+	return int(p.envelopeCounter)
+}
+
+// mmc5ChannelNames names the MMC5's channels on the scope
+var mmc5ChannelNames = []string{"MMC5 P1", "MMC5 P2", "MMC5 PCM"}
+
+// ChannelNames returns the names of the pulses and the PCM channel
+func (m *MMC5Audio) ChannelNames() []string {
+	return mmc5ChannelNames
+}
+
+// ChannelOutputs writes the outputs of the pulses and the PCM channel to dst
+func (m *MMC5Audio) ChannelOutputs(dst []float32) {
+	dst[0] = float32(m.pulseOutput(0)) / 15
+	dst[1] = float32(m.pulseOutput(1)) / 15
+	dst[2] = float32(m.pcm) / 255
+}
@@ -0,0 +1,137 @@
+// Package apu implements the Namco 163's sound: up to eight wavetable
+// channels playing 4-bit samples from the chip's 128 bytes of RAM.
+package apu
+
+// n163UpdateCycles is how many CPU cycles the Namco 163 takes to update one
+// channel; it updates the enabled channels in turn
+const n163UpdateCycles = 15
+
+// n163Level is the Namco 163's output for each step of a channel's signed
+// sample times its volume, when it plays alone
+const n163Level = pulseLevel / 120
+
+// N163Audio is the sound of the Namco 163, used by King of Kings and Megami
+// Tensei II. The CPU reaches its RAM through $4800, at the address set
+// through $F800. Channel n's registers are the 8 bytes at $40+8n: frequency
+// (18 bits, in bytes 0, 2 and 4), phase (24 bits, bytes 1, 3 and 5), wave
+// length (bits 2-7 of byte 4), wave address (byte 6) and volume (byte 7).
+// Channel 7's volume byte, at $7F, also holds how many channels are enabled,
+// counting down from 7.
+type N163Audio struct {
+	ram       [0x80]uint8
+	address   uint8 // $F800 bits 0-6
+	increment bool  // $F800 bit 7: step the address after each access
+
+	cycles  int    // CPU cycles into the current channel's update
+	channel int    // Channel being updated
+	outputs [8]int // Last output of each channel
+}
+
+// NewN163Audio creates the Namco 163's sound
+func NewN163Audio() *N163Audio {
+	return &N163Audio{channel: 7}
+}
+
+// Write writes $4800 (data) or $F800 (address)
+func (n *N163Audio) Write(address uint16, value uint8) {
+	switch {
+	case address >= 0x4800 && address < 0x5000:
+		n.ram[n.address] = value
+		n.step()
+	case address >= 0xF800:
+		n.address = value & 0x7F
+		n.increment = value&0x80 != 0
+	}
+}
+
+// Read reads the RAM through $4800
+func (n *N163Audio) Read(address uint16) (uint8, bool) {
+	if address < 0x4800 || address >= 0x5000 {
+		return 0, false
+	}
+	value := n.ram[n.address]
+	n.step()
+	return value, true
+}
+
+// step moves the address on after an access, if auto-increment is on
+func (n *N163Audio) step() {
+	if n.increment {
+		n.address = (n.address + 1) & 0x7F
+	}
+}
+
+// enabledChannels returns how many channels are enabled, counting down from
+// channel 7
+func (n *N163Audio) enabledChannels() int {
+	return int(n.ram[0x7F]>>4&0x07) + 1
+}
+
+// Clock updates a channel every n163UpdateCycles CPU cycles
+func (n *N163Audio) Clock() {
+	n.cycles++
+	if n.cycles < n163UpdateCycles {
+		return
+	}
+	n.cycles = 0
+
+	n.updateChannel(n.channel)
+	n.channel--
+	if n.channel < 8-n.enabledChannels() {
+		n.channel = 7
+	}
+}
+
+// updateChannel advances a channel's phase by its frequency and reads its
+// sample
+func (n *N163Audio) updateChannel(channel int) {
+	regs := n.ram[0x40+channel*8 : 0x48+channel*8]
+	frequency := uint32(regs[0]) | uint32(regs[2])<<8 | uint32(regs[4]&0x03)<<16
+	phase := uint32(regs[1]) | uint32(regs[3])<<8 | uint32(regs[5])<<16
+	length := 256 - uint32(regs[4]&0xFC)
+
+	phase = (phase + frequency) % (length << 16)
+	regs[1], regs[3], regs[5] = uint8(phase), uint8(phase>>8), uint8(phase>>16)
+
+	sampleAddress := (uint32(regs[6]) + phase>>16) & 0xFF
+	sample := n.ram[sampleAddress>>1&0x7F]
+	if sampleAddress&1 == 0 {
+		sample &= 0x0F
+	} else {
+		sample >>= 4
+	}
+	n.outputs[channel] = (int(sample) - 8) * int(regs[7]&0x0F)
+}
+
+// Output returns the average of the enabled channels, as the chip plays
+// them in turn
+func (n *N163Audio) Output() float64 {
+	enabled := n.enabledChannels()
+	var sum int
+	for channel := 8 - enabled; channel < 8; channel++ {
+		sum += n.outputs[channel]
+	}
+	return float64(sum) / float64(enabled) * n163Level
+}
+
+// n163ChannelNames names the Namco 163's channels on the scope
+var n163ChannelNames = []string{"N163 1", "N163 2", "N163 3", "N163 4", "N163 5", "N163 6", "N163 7", "N163 8"}
+
+// ChannelNames returns the names of the eight channels
+func (n *N163Audio) ChannelNames() []string {
+	return n163ChannelNames
+}
+
+// ChannelOutputs writes the channels' outputs to dst, from the signed
+// sample's lowest times the loudest volume to its highest. Disabled channels
+// are silent, in the middle.
+func (n *N163Audio) ChannelOutputs(dst []float32) {
+	enabled := n.enabledChannels()
+	for channel := range n.outputs {
+		output := 0
+		if channel >= 8-enabled {
+			output = n.outputs[channel]
+		}
+		dst[channel] = float32(output+8*15) / (16 * 15)
+	}
+}
@@ -0,0 +1,124 @@
+// Package apu provides the channel scope: the output of each channel at the
+// last audio samples, before mixing, for oscilloscope views of the sound.
+package apu
+
+// Channels of the scope
+const (
+	ScopePulse1 = iota
+	ScopePulse2
+	ScopeTriangle
+	ScopeNoise
+	ScopeDMC
+	ScopeExpansion // The first channel of the expansion chips, if any
+
+	// ScopeChannels is the most channels the scope keeps: the APU's and
+	// those of every expansion chip together, as NSF music can use them all
+	ScopeChannels = ScopeExpansion + 24
+)
+
+// ScopeLength is how many audio samples the scope keeps per channel
+const ScopeLength = 4096
+
+// scopeExpansionRange is the output of an expansion chip without channels of
+// its own shown as a full trace: four pulse channels at full volume
+const scopeExpansionRange = 4 * pulseLevel
+
+// channelScope keeps the channels' outputs, from 0 to 1 of their range
+type channelScope struct {
+	samples [ScopeChannels][ScopeLength]float32
+	next    int // Index the next sample goes at
+
+	// The expansion chip's channels, and their outputs at the last sample
+	expansion        ExpansionChannels
+	expansionNames   []string
+	expansionOutputs []float32
+}
+
+// setExpansion starts keeping the channels of an expansion chip, nil for none
+func (s *channelScope) setExpansion(chip ExpansionAudio) {
+	s.expansion, s.expansionNames, s.expansionOutputs = nil, nil, nil
+	if chip == nil {
+		return
+	}
+	s.expansion = expansionChannels(chip)
+	names := s.expansion.ChannelNames()
+	s.expansionNames = names[:min(len(names), ScopeChannels-ScopeExpansion)]
+	s.expansionOutputs = make([]float32, len(names))
+}
+
+// SetScope starts or stops keeping the channels' outputs for Scope. It is
+// not part of the APU's state.
+func (apu *APU) SetScope(enabled bool) {
+	if !enabled {
+		apu.scope = nil
+	} else if apu.scope == nil {
+		apu.scope = &channelScope{}
+		apu.scope.setExpansion(apu.expansion)
+	}
+}
+
+// Scope copies a channel's output at the last len(dst) audio samples, up to
+// ScopeLength, into dst, oldest first, from 0 to 1 of the channel's range.
+// The expansion chip's channels follow the APU's from ScopeExpansion. It
+// returns false while the scope is off or for a channel there is not.
+func (apu *APU) Scope(channel int, dst []float32) bool {
+	if apu.scope == nil || channel < 0 || channel >= ScopeExpansion+len(apu.scope.expansionNames) {
+		return false
+	}
+	n := min(len(dst), ScopeLength)
+	start := apu.scope.next - n + ScopeLength
+	for i := 0; i < n; i++ {
+		dst[i] = apu.scope.samples[channel][(start+i)%ScopeLength]
+	}
+	return true
+}
+
+// ScopeExpansionNames returns the names of the expansion chip's channels on
+// the scope, from ScopeExpansion on; none while the scope is off
+func (apu *APU) ScopeExpansionNames() []string {
+	if apu.scope == nil {
+		return nil
+	}
+	return apu.scope.expansionNames
+}
+
+// recordScope adds the channels' outputs of an audio sample to the scope
+func (apu *APU) recordScope(pulse1, pulse2, triangle, noise, dmc uint8) {
+	s := apu.scope
+	s.samples[ScopePulse1][s.next] = float32(pulse1) / 15
+	s.samples[ScopePulse2][s.next] = float32(pulse2) / 15
+	s.samples[ScopeTriangle][s.next] = float32(triangle) / 15
+	s.samples[ScopeNoise][s.next] = float32(noise) / 15
+	s.samples[ScopeDMC][s.next] = float32(dmc) / 127
+	if s.expansion != nil {
+		s.expansion.ChannelOutputs(s.expansionOutputs)
+		for i := range s.expansionNames {
+			s.samples[ScopeExpansion+i][s.next] = s.expansionOutputs[i]
+		}
+	}
+	s.next = (s.next + 1) % ScopeLength
+}
+
+// expansionChannels returns the channels of a sound chip for the scope: its
+// own, or its whole output as one channel
+func expansionChannels(chip ExpansionAudio) ExpansionChannels {
+	if channels, ok := chip.(ExpansionChannels); ok {
+		return channels
+	}
+	return wholeExpansion{chip}
+}
+
+// wholeExpansionNames names the one channel of a chip without channels of
+// its own
+var wholeExpansionNames = []string{"EXPANSION"}
+
+// wholeExpansion shows a sound chip's whole output as one channel
+type wholeExpansion struct {
+	ExpansionAudio
+}
+
+func (wholeExpansion) ChannelNames() []string { return wholeExpansionNames }
+
+func (e wholeExpansion) ChannelOutputs(dst []float32) {
+	dst[0] = float32(min(max(e.Output()/scopeExpansionRange, 0), 1))
+}
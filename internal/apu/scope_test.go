@@ -0,0 +1,153 @@
+package apu
+
+import (
+	"slices"
+	"testing"
+)
+
+// steadyChip is an expansion chip without channels of its own, always at
+// one output
+type steadyChip float64
+
+func (steadyChip) Clock() {}
+
+func (c steadyChip) Output() float64 { return float64(c) }
+
+func TestScope_Off(t *testing.T) {
+	apu := New()
+	dst := make([]float32, 4)
+	if apu.Scope(ScopePulse1, dst) {
+		t.Error("Scope succeeded with the scope off")
+	}
+
+	apu.SetScope(true)
+	if !apu.Scope(ScopePulse1, dst) {
+		t.Error("Scope failed with the scope on")
+	}
+	if apu.Scope(ScopeExpansion, dst) {
+		t.Error("Scope succeeded for an expansion channel without a chip")
+	}
+	if apu.Scope(-1, dst) {
+		t.Error("Scope succeeded for channel -1")
+	}
+
+	apu.SetScope(false)
+	if apu.Scope(ScopePulse1, dst) || apu.ScopeExpansionNames() != nil {
+		t.Error("scope still kept once turned off")
+	}
+}
+
+func TestScope_RecordsChannels(t *testing.T) {
+	apu := New()
+	apu.SetScope(true)
+	apu.recordScope(15, 0, 6, 3, 127)
+	apu.recordScope(0, 15, 0, 0, 0)
+
+	tests := []struct {
+		channel int
+		want    []float32
+	}{
+		{ScopePulse1, []float32{1, 0}},
+		{ScopePulse2, []float32{0, 1}},
+		{ScopeTriangle, []float32{6.0 / 15, 0}},
+		{ScopeNoise, []float32{3.0 / 15, 0}},
+		{ScopeDMC, []float32{1, 0}},
+	}
+	for _, tt := range tests {
+		dst := make([]float32, 2)
+		if !apu.Scope(tt.channel, dst) {
+			t.Fatalf("Scope(%d) failed", tt.channel)
+		}
+		if !slices.Equal(dst, tt.want) {
+			t.Errorf("Scope(%d) = %v, want %v", tt.channel, dst, tt.want)
+		}
+	}
+}
+
+func TestScope_WrapsAround(t *testing.T) {
+	apu := New()
+	apu.SetScope(true)
+	for i := 0; i < ScopeLength+3; i++ {
+		apu.recordScope(uint8(i%16), 0, 0, 0, 0)
+	}
+
+	// The last three samples, oldest first
+	dst := make([]float32, 3)
+	apu.Scope(ScopePulse1, dst)
+	last := ScopeLength + 2
+	want := []float32{float32((last-2)%16) / 15, float32((last-1)%16) / 15, float32(last%16) / 15}
+	if !slices.Equal(dst, want) {
+		t.Errorf("Scope = %v, want %v", dst, want)
+	}
+
+	// No more than ScopeLength
+	long := make([]float32, ScopeLength+10)
+	apu.Scope(ScopePulse1, long)
+	if long[ScopeLength] != 0 || long[ScopeLength-1] != want[2] {
+		t.Error("Scope copied more than ScopeLength samples")
+	}
+}
+
+func TestScope_ExpansionChannels(t *testing.T) {
+	vrc6 := NewVRC6Audio()
+	vrc6.Write(0x9000, 0x8F) // Pulse 1 at volume 15, ignoring its duty
+	vrc6.Write(0x9002, 0x80)
+	chips := ExpansionChips{vrc6, steadyChip(pulseLevel)}
+	wantNames := []string{"VRC6 P1", "VRC6 P2", "VRC6 SAW", "EXPANSION"}
+	wantOutputs := []float32{1, 0, 0, 0.25}
+
+	// The channels are found whether the chip or the scope comes first
+	for _, scopeFirst := range []bool{false, true} {
+		apu := New()
+		if scopeFirst {
+			apu.SetScope(true)
+			apu.SetExpansionAudio(chips)
+		} else {
+			apu.SetExpansionAudio(chips)
+			apu.SetScope(true)
+		}
+		apu.recordScope(0, 0, 0, 0, 0)
+
+		if names := apu.ScopeExpansionNames(); !slices.Equal(names, wantNames) {
+			t.Fatalf("ScopeExpansionNames() = %v, want %v", names, wantNames)
+		}
+		for i, want := range wantOutputs {
+			dst := make([]float32, 1)
+			if !apu.Scope(ScopeExpansion+i, dst) {
+				t.Fatalf("Scope(%s) failed", wantNames[i])
+			}
+			if dst[0] != want {
+				t.Errorf("Scope(%s) = %v, want %v", wantNames[i], dst[0], want)
+			}
+		}
+		if apu.Scope(ScopeExpansion+len(wantNames), make([]float32, 1)) {
+			t.Error("Scope succeeded past the last expansion channel")
+		}
+
+		apu.SetExpansionAudio(nil)
+		if apu.ScopeExpansionNames() != nil || apu.Scope(ScopeExpansion, make([]float32, 1)) {
+			t.Error("expansion channels still kept without a chip")
+		}
+	}
+}
+
+func TestScope_EveryChipFits(t *testing.T) {
+	chips := ExpansionChips{
+		NewVRC6Audio(), NewVRC7Audio(), NewFDSAudio(),
+		NewMMC5Audio(), NewN163Audio(), NewSunsoft5BAudio(),
+	}
+	names := chips.ChannelNames()
+	if ScopeExpansion+len(names) != ScopeChannels {
+		t.Errorf("every chip has %d channels, the scope keeps %d", len(names), ScopeChannels-ScopeExpansion)
+	}
+
+	// Silent chips sit at the bottom of their range, or the middle of a
+	// signed one
+	outputs := make([]float32, len(names))
+	chips.ChannelOutputs(outputs)
+	for i, output := range outputs {
+		if output != 0 && output != 0.5 {
+			t.Errorf("silent %s at %v", names[i], output)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package apu implements APU state snapshots.
+package apu
+
+import "gones/internal/savestate"
+
+// SaveState writes all channel, frame counter and timing state.
+// Buffered output samples are not part of the state.
+func (apu *APU) SaveState(w *savestate.Writer) {
+	w.WriteTag("APU ")
+
+	apu.pulse1.saveState(w)
+	apu.pulse2.saveState(w)
+	apu.triangle.saveState(w)
+	apu.noise.saveState(w)
+	apu.dmc.saveState(w)
+
+	w.WriteU16(apu.frameCounter)
+	w.WriteBool(apu.frameMode)
+	w.WriteBool(apu.frameIRQEnable)
+	w.WriteU8(apu.frameCounterStep)
+	w.WriteBool(apu.frameIRQFlag)
+
+	for _, enabled := range apu.channelEnable {
+		w.WriteBool(enabled)
+	}
+
+	w.WriteF64(apu.cycleAccumulator)
+	w.WriteU64(apu.cycles)
+}
+
+// LoadState restores state written by SaveState
+func (apu *APU) LoadState(r *savestate.Reader) error {
+	r.ExpectTag("APU ")
+
+	apu.pulse1.loadState(r)
+	apu.pulse2.loadState(r)
+	apu.triangle.loadState(r)
+	apu.noise.loadState(r)
+	apu.dmc.loadState(r)
+
+	apu.frameCounter = r.ReadU16()
+	apu.frameMode = r.ReadBool()
+	apu.frameIRQEnable = r.ReadBool()
+	apu.frameCounterStep = r.ReadU8()
+	apu.frameIRQFlag = r.ReadBool()
+
+	for i := range apu.channelEnable {
+		apu.channelEnable[i] = r.ReadBool()
+	}
+
+	apu.cycleAccumulator = r.ReadF64()
+	apu.cycles = r.ReadU64()
+
+	// Drop samples generated before the restore
+	apu.sampleBuffer = apu.sampleBuffer[:0]
+
+	return r.Err()
+}
+
+func (pulse *PulseChannel) saveState(w *savestate.Writer) {
+	w.WriteU8(pulse.dutyCycle)
+	w.WriteBool(pulse.envelopeLoop)
+	w.WriteBool(pulse.envelopeDisable)
+	w.WriteU8(pulse.volume)
+	w.WriteBool(pulse.sweepEnable)
+	w.WriteU8(pulse.sweepPeriod)
+	w.WriteBool(pulse.sweepNegate)
+	w.WriteU8(pulse.sweepShift)
+	w.WriteBool(pulse.sweepReload)
+	w.WriteU8(pulse.sweepCounter)
+	w.WriteU16(pulse.timer)
+	w.WriteU16(pulse.timerCounter)
+	w.WriteU8(pulse.lengthCounter)
+	w.WriteBool(pulse.lengthHalt)
+	w.WriteBool(pulse.envelopeStart)
+	w.WriteU8(pulse.envelopeCounter)
+	w.WriteU8(pulse.envelopeDivider)
+	w.WriteU8(pulse.dutyIndex)
+	w.WriteU8(pulse.output)
+	w.WriteU8(pulse.sequencerPos)
+}
+
+func (pulse *PulseChannel) loadState(r *savestate.Reader) {
+	pulse.dutyCycle = r.ReadU8()
+	pulse.envelopeLoop = r.ReadBool()
+	pulse.envelopeDisable = r.ReadBool()
+	pulse.volume = r.ReadU8()
+	pulse.sweepEnable = r.ReadBool()
+	pulse.sweepPeriod = r.ReadU8()
+	pulse.sweepNegate = r.ReadBool()
+	pulse.sweepShift = r.ReadU8()
+	pulse.sweepReload = r.ReadBool()
+	pulse.sweepCounter = r.ReadU8()
+	pulse.timer = r.ReadU16()
+	pulse.timerCounter = r.ReadU16()
+	pulse.lengthCounter = r.ReadU8()
+	pulse.lengthHalt = r.ReadBool()
+	pulse.envelopeStart = r.ReadBool()
+	pulse.envelopeCounter = r.ReadU8()
+	pulse.envelopeDivider = r.ReadU8()
+	pulse.dutyIndex = r.ReadU8()
+	pulse.output = r.ReadU8()
+	pulse.sequencerPos = r.ReadU8()
+}
+
+func (triangle *TriangleChannel) saveState(w *savestate.Writer) {
+	w.WriteBool(triangle.lengthCounterHalt)
+	w.WriteU8(triangle.linearCounterLoad)
+	w.WriteU16(triangle.timer)
+	w.WriteU16(triangle.timerCounter)
+	w.WriteU8(triangle.lengthCounter)
+	w.WriteU8(triangle.linearCounter)
+	w.WriteBool(triangle.linearCounterReload)
+	w.WriteU8(triangle.sequencerPos)
+	w.WriteU8(triangle.output)
+}
+
+func (triangle *TriangleChannel) loadState(r *savestate.Reader) {
+	triangle.lengthCounterHalt = r.ReadBool()
+	triangle.linearCounterLoad = r.ReadU8()
+	triangle.timer = r.ReadU16()
+	triangle.timerCounter = r.ReadU16()
+	triangle.lengthCounter = r.ReadU8()
+	triangle.linearCounter = r.ReadU8()
+	triangle.linearCounterReload = r.ReadBool()
+	triangle.sequencerPos = r.ReadU8()
+	triangle.output = r.ReadU8()
+}
+
+func (noise *NoiseChannel) saveState(w *savestate.Writer) {
+	w.WriteBool(noise.envelopeLoop)
+	w.WriteBool(noise.envelopeDisable)
+	w.WriteU8(noise.volume)
+	w.WriteBool(noise.mode)
+	w.WriteU8(noise.periodIndex)
+	w.WriteU16(noise.timerCounter)
+	w.WriteU8(noise.lengthCounter)
+	w.WriteBool(noise.lengthHalt)
+	w.WriteBool(noise.envelopeStart)
+	w.WriteU8(noise.envelopeCounter)
+	w.WriteU8(noise.envelopeDivider)
+	w.WriteU16(noise.shiftRegister)
+	w.WriteU8(noise.output)
+}
+
+func (noise *NoiseChannel) loadState(r *savestate.Reader) {
+	noise.envelopeLoop = r.ReadBool()
+	noise.envelopeDisable = r.ReadBool()
+	noise.volume = r.ReadU8()
+	noise.mode = r.ReadBool()
+	noise.periodIndex = r.ReadU8()
+	noise.timerCounter = r.ReadU16()
+	noise.lengthCounter = r.ReadU8()
+	noise.lengthHalt = r.ReadBool()
+	noise.envelopeStart = r.ReadBool()
+	noise.envelopeCounter = r.ReadU8()
+	noise.envelopeDivider = r.ReadU8()
+	noise.shiftRegister = r.ReadU16()
+	noise.output = r.ReadU8()
+}
+
+func (dmc *DMCChannel) saveState(w *savestate.Writer) {
+	w.WriteBool(dmc.irqEnable)
+	w.WriteBool(dmc.loop)
+	w.WriteU8(dmc.rateIndex)
+	w.WriteU8(dmc.outputLevel)
+	w.WriteU16(dmc.sampleAddress)
+	w.WriteU16(dmc.sampleLength)
+	w.WriteU16(dmc.timerCounter)
+	w.WriteU8(dmc.sampleBuffer)
+	w.WriteBool(dmc.sampleBufferEmpty)
+	w.WriteU16(dmc.bytesRemaining)
+	w.WriteU16(dmc.currentAddress)
+	w.WriteU8(dmc.shiftRegister)
+	w.WriteU8(dmc.bitsRemaining)
+	w.WriteBool(dmc.silence)
+	w.WriteBool(dmc.irqFlag)
+	w.WriteU8(dmc.output)
+}
+
+func (dmc *DMCChannel) loadState(r *savestate.Reader) {
+	dmc.irqEnable = r.ReadBool()
+	dmc.loop = r.ReadBool()
+	dmc.rateIndex = r.ReadU8()
+	dmc.outputLevel = r.ReadU8()
+	dmc.sampleAddress = r.ReadU16()
+	dmc.sampleLength = r.ReadU16()
+	dmc.timerCounter = r.ReadU16()
+	dmc.sampleBuffer = r.ReadU8()
+	dmc.sampleBufferEmpty = r.ReadBool()
+	dmc.bytesRemaining = r.ReadU16()
+	dmc.currentAddress = r.ReadU16()
+	dmc.shiftRegister = r.ReadU8()
+	dmc.bitsRemaining = r.ReadU8()
+	dmc.silence = r.ReadBool()
+	dmc.irqFlag = r.ReadBool()
+	dmc.output = r.ReadU8()
+}
@@ -0,0 +1,191 @@
+// Package apu implements the Sunsoft 5B's sound, a YM2149: three square
+// wave channels with a shared noise generator and envelope.
+package apu
+
+import "math"
+
+// s5bLevel is the Sunsoft 5B's output for a channel at full volume
+const s5bLevel = 1.2 * pulseLevel
+
+// s5bVolumes is the output of each 5-bit volume level, 1.5 dB apart. The
+// channels' 4-bit volumes use every other level.
+var s5bVolumes = func() [32]float64 {
+	var volumes [32]float64
+	for i := 1; i < len(volumes); i++ {
+		volumes[i] = math.Pow(10, float64(i-31)*1.5/20)
+	}
+	return volumes
+}()
+
+// Sunsoft5BAudio is the sound of the Sunsoft 5B, used by Gimmick!. The CPU
+// picks one of its 16 registers through $C000 and writes it through $E000:
+// the tone periods of channels A-C (0-5), the noise period (6), the mixer
+// (7), the channels' volumes (8-10), the envelope period (11-12) and the
+// envelope shape (13).
+type Sunsoft5BAudio struct {
+	registers [16]uint8
+	selected  uint8 // $C000
+
+	divider uint8 // Counts CPU cycles to the tone and noise clocks
+
+	tones [3]struct {
+		counter uint16
+		high    bool
+	}
+
+	noiseCounter uint8
+	noise        uint32 // 17-bit LFSR
+
+	envCounter uint16
+	envStep    uint8 // 0-31
+	envHolding bool
+	envRising  bool
+}
+
+// NewSunsoft5BAudio creates the Sunsoft 5B's sound
+func NewSunsoft5BAudio() *Sunsoft5BAudio {
+	return &Sunsoft5BAudio{noise: 1}
+}
+
+// Write writes $C000 (register select) or $E000 (register data)
+func (s *Sunsoft5BAudio) Write(address uint16, value uint8) {
+	switch address & 0xE000 {
+	case 0xC000:
+		s.selected = value & 0x0F
+	case 0xE000:
+		s.registers[s.selected] = value
+		if s.selected == 13 {
+			s.startEnvelope()
+		}
+	}
+}
+
+// startEnvelope restarts the envelope after its shape is written: attack
+// (bit 2) sets whether it rises or falls first
+func (s *Sunsoft5BAudio) startEnvelope() {
+	s.envRising = s.registers[13]&0x04 != 0
+	s.envStep = 0
+	s.envCounter = 0
+	s.envHolding = false
+}
+
+// Clock runs the tone, noise and envelope generators for one CPU cycle.
+// Tones toggle every period*16 CPU cycles, the noise and envelope steps
+// every period*32 and period*8 cycles.
+func (s *Sunsoft5BAudio) Clock() {
+	s.divider++
+
+	if s.divider&0x07 == 0 {
+		s.clockEnvelope()
+	}
+	if s.divider&0x0F != 0 {
+		return
+	}
+	for i := range s.tones {
+		t := &s.tones[i]
+		period := uint16(s.registers[i*2]) | uint16(s.registers[i*2+1]&0x0F)<<8
+		t.counter++
+		if t.counter >= period {
+			t.counter = 0
+			t.high = !t.high
+		}
+	}
+	if s.divider&0x1F == 0 {
+		s.noiseCounter++
+		if s.noiseCounter >= s.registers[6]&0x1F {
+			s.noiseCounter = 0
+			bit := (s.noise ^ s.noise>>3) & 1
+			s.noise = s.noise>>1 | bit<<16
+		}
+	}
+}
+
+// clockEnvelope steps the envelope through its 32 levels, then holds,
+// repeats or alternates as its shape says: continue (bit 3), attack (bit 2),
+// alternate (bit 1) and hold (bit 0)
+func (s *Sunsoft5BAudio) clockEnvelope() {
+	period := uint16(s.registers[11]) | uint16(s.registers[12])<<8
+	s.envCounter++
+	if s.envCounter < period || s.envHolding {
+		return
+	}
+	s.envCounter = 0
+	if s.envStep < 31 {
+		s.envStep++
+		return
+	}
+
+	shape := s.registers[13]
+	switch {
+	case shape&0x08 == 0:
+		// Ends silent
+		s.envHolding = true
+		s.envRising = false
+		s.envStep = 31
+	case shape&0x01 != 0:
+		s.envHolding = true
+		if shape&0x02 != 0 {
+			s.envRising = !s.envRising
+		}
+	default:
+		if shape&0x02 != 0 {
+			s.envRising = !s.envRising
+		}
+		s.envStep = 0
+	}
+}
+
+// envelopeLevel returns the envelope's 5-bit level
+func (s *Sunsoft5BAudio) envelopeLevel() uint8 {
+	if s.registers[13]&0x08 == 0 && s.envHolding {
+		return 0
+	}
+	if s.envRising {
+		return s.envStep
+	}
+	return 31 - s.envStep
+}
+
+// Output returns the sum of the channels: each plays its volume while its
+// tone and the noise, those the mixer enables, are high
+func (s *Sunsoft5BAudio) Output() float64 {
+	var sum float64
+	for channel := range s.tones {
+		sum += s.toneOutput(channel)
+	}
+	return sum * s5bLevel
+}
+
+// toneOutput returns a channel's output, from 0 to 1 at full volume
+func (s *Sunsoft5BAudio) toneOutput(channel int) float64 {
+	mixer := s.registers[7]
+	toneOn := s.tones[channel].high || mixer&(1<<channel) != 0
+	noiseOn := s.noise&1 != 0 || mixer&(8<<channel) != 0
+	if !toneOn || !noiseOn {
+		return 0
+	}
+	volume := s.registers[8+channel]
+	level := s.envelopeLevel()
+	if volume&0x10 == 0 {
+		level = (volume & 0x0F) * 2
+		if level > 0 {
+			level++
+		}
+	}
+	return s5bVolumes[level]
+}
+
+// s5bChannelNames names the Sunsoft 5B's channels on the scope
+var s5bChannelNames = []string{"5B A", "5B B", "5B C"}
+
+// ChannelNames returns the names of channels A-C
+func (s *Sunsoft5BAudio) ChannelNames() []string {
+	return s5bChannelNames
+}
+
+// ChannelOutputs writes the outputs of channels A-C to dst
+func (s *Sunsoft5BAudio) ChannelOutputs(dst []float32) {
+	for channel := range s.tones {
+		dst[channel] = float32(s.toneOutput(channel))
+	}
+}
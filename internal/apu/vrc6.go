@@ -0,0 +1,159 @@
+// Package apu implements the VRC6's sound: two pulse channels with eight
+// duty cycles and a sawtooth channel.
+package apu
+
+// vrc6Level is the VRC6's output for each step of its 6-bit sum; a pulse
+// channel at full volume is about as loud as an APU pulse
+const vrc6Level = pulseLevel / 15
+
+// VRC6Audio is the sound of Konami's VRC6, used by Akumajou Densetsu and
+// Madara. Its registers are at $9000-$9003 (pulse 1 and the frequency
+// control), $A000-$A002 (pulse 2) and $B000-$B002 (sawtooth).
+type VRC6Audio struct {
+	pulses [2]vrc6Pulse
+	saw    vrc6Saw
+
+	halt  bool  // $9003 bit 0: all channels stopped
+	shift uint8 // $9003 bits 1-2: periods shifted right by 4 or 8
+}
+
+// vrc6Pulse is a VRC6 pulse channel: 16 steps, high for duty+1 of them
+type vrc6Pulse struct {
+	volume    uint8
+	duty      uint8
+	digitized bool // Ignore the duty and output the volume
+	enabled   bool
+	period    uint16
+	timer     uint16
+	step      uint8
+}
+
+// vrc6Saw is the VRC6 sawtooth: an accumulator that grows by the rate every
+// other step and is cleared after 7 additions
+type vrc6Saw struct {
+	rate        uint8
+	enabled     bool
+	period      uint16
+	timer       uint16
+	step        uint8
+	accumulator uint8
+}
+
+// NewVRC6Audio creates the VRC6's sound
+func NewVRC6Audio() *VRC6Audio {
+	return &VRC6Audio{}
+}
+
+// Write writes a sound register
+func (v *VRC6Audio) Write(address uint16, value uint8) {
+	switch address {
+	case 0x9000, 0xA000:
+		p := &v.pulses[(address>>12)-9]
+		p.volume = value & 0x0F
+		p.duty = (value >> 4) & 0x07
+		p.digitized = value&0x80 != 0
+	case 0x9001, 0xA001:
+		p := &v.pulses[(address>>12)-9]
+		p.period = p.period&0xF00 | uint16(value)
+	case 0x9002, 0xA002:
+		p := &v.pulses[(address>>12)-9]
+		p.period = p.period&0x0FF | uint16(value&0x0F)<<8
+		p.enabled = value&0x80 != 0
+		if !p.enabled {
+			p.step = 0
+		}
+	case 0x9003:
+		v.halt = value&0x01 != 0
+		switch {
+		case value&0x04 != 0:
+			v.shift = 8
+		case value&0x02 != 0:
+			v.shift = 4
+		default:
+			v.shift = 0
+		}
+	case 0xB000:
+		v.saw.rate = value & 0x3F
+	case 0xB001:
+		v.saw.period = v.saw.period&0xF00 | uint16(value)
+	case 0xB002:
+		v.saw.period = v.saw.period&0x0FF | uint16(value&0x0F)<<8
+		v.saw.enabled = value&0x80 != 0
+		if !v.saw.enabled {
+			v.saw.step = 0
+			v.saw.accumulator = 0
+		}
+	}
+}
+
+// Clock runs the channels' timers for one CPU cycle
+func (v *VRC6Audio) Clock() {
+	if v.halt {
+		return
+	}
+	for i := range v.pulses {
+		p := &v.pulses[i]
+		if p.enabled && v.tick(&p.timer, p.period) {
+			p.step = (p.step + 1) & 0x0F
+		}
+	}
+	s := &v.saw
+	if s.enabled && v.tick(&s.timer, s.period) {
+		s.step++
+		switch {
+		case s.step == 14:
+			s.step = 0
+			s.accumulator = 0
+		case s.step&1 == 0:
+			s.accumulator += s.rate
+		}
+	}
+}
+
+// tick counts a channel's timer down, reloading it from the period with
+// the frequency control's shift, and returns whether it expired
+func (v *VRC6Audio) tick(timer *uint16, period uint16) bool {
+	if *timer > 0 {
+		*timer--
+		return false
+	}
+	*timer = period >> v.shift
+	return true
+}
+
+// Output returns the sum of the channels
+func (v *VRC6Audio) Output() float64 {
+	return float64(v.pulseOutput(0)+v.pulseOutput(1)+v.sawOutput()) * vrc6Level
+}
+
+// pulseOutput returns a pulse channel's output, 0-15
+func (v *VRC6Audio) pulseOutput(channel int) int {
+	p := &v.pulses[channel]
+	if p.enabled && (p.digitized || p.step <= p.duty) {
+		return int(p.volume)
+	}
+	return 0
+}
+
+// sawOutput returns the sawtooth's output, the top 5 bits of its accumulator
+func (v *VRC6Audio) sawOutput() int {
+	if v.saw.enabled {
+		return int(v.saw.accumulator >> 3)
+	}
+	return 0
+}
+
+// vrc6ChannelNames names the VRC6's channels on the scope
+var vrc6ChannelNames = []string{"VRC6 P1", "VRC6 P2", "VRC6 SAW"}
+
+// ChannelNames returns the names of the pulses and the sawtooth
+func (v *VRC6Audio) ChannelNames() []string {
+	return vrc6ChannelNames
+}
+
+// ChannelOutputs writes the outputs of the pulses and the sawtooth to dst
+func (v *VRC6Audio) ChannelOutputs(dst []float32) {
+	dst[0] = float32(v.pulseOutput(0)) / 15
+	dst[1] = float32(v.pulseOutput(1)) / 15
+	dst[2] = float32(v.sawOutput()) / 31
+}
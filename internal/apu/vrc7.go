@@ -0,0 +1,357 @@
+// Package apu implements the VRC7's sound, a cut-down YM2413 (OPLL): six FM
+// channels of two operators each, playing built-in instruments or one the
+// music defines.
+package apu
+
+import "math"
+
+// vrc7SampleCycles is how many CPU cycles the VRC7 takes for each output
+// sample: its 3.58 MHz clock divided by 72
+const vrc7SampleCycles = 36
+
+// vrc7SampleRate is the rate, in Hz, of the VRC7's output samples
+const vrc7SampleRate = 1789772.5 / vrc7SampleCycles
+
+// vrc7Level is the VRC7's output for a channel at full volume
+const vrc7Level = pulseLevel
+
+// vrc7Silence is the attenuation, in dB, at which an operator's envelope
+// ends
+const vrc7Silence = 48.0
+
+// vrc7Patches are the 15 built-in instruments, in the register layout of the
+// custom instrument at $00-$07
+var vrc7Patches = [15][8]uint8{
+	{0x03, 0x21, 0x05, 0x06, 0xE8, 0x81, 0x42, 0x27}, // Bell
+	{0x13, 0x41, 0x14, 0x0D, 0xD8, 0xF6, 0x23, 0x12}, // Guitar
+	{0x11, 0x11, 0x08, 0x08, 0xFA, 0xB2, 0x20, 0x12}, // Piano
+	{0x31, 0x61, 0x0C, 0x07, 0xA8, 0x64, 0x61, 0x27}, // Flute
+	{0x32, 0x21, 0x1E, 0x06, 0xE1, 0x76, 0x01, 0x28}, // Clarinet
+	{0x02, 0x01, 0x06, 0x00, 0xA3, 0xE2, 0xF4, 0xF4}, // Rattling bell
+	{0x21, 0x61, 0x1D, 0x07, 0x82, 0x81, 0x11, 0x07}, // Trumpet
+	{0x23, 0x21, 0x22, 0x17, 0xA2, 0x72, 0x01, 0x17}, // Reed organ
+	{0x35, 0x11, 0x25, 0x00, 0x40, 0x73, 0x72, 0x01}, // Soft bell
+	{0xB5, 0x01, 0x0F, 0x0F, 0xA8, 0xA5, 0x51, 0x02}, // Xylophone
+	{0x17, 0xC1, 0x24, 0x07, 0xF8, 0xF8, 0x22, 0x12}, // Vibraphone
+	{0x71, 0x23, 0x11, 0x06, 0x65, 0x74, 0x18, 0x16}, // Brass
+	{0x01, 0x02, 0xD3, 0x05, 0xC9, 0x95, 0x03, 0x02}, // Bass guitar
+	{0x61, 0x63, 0x0C, 0x00, 0x94, 0xC0, 0x33, 0xF6}, // Synthesizer
+	{0x21, 0x72, 0x0D, 0x00, 0xC1, 0xD5, 0x56, 0x06}, // Chorus
+}
+
+// vrc7Multipliers is the frequency multiplier of each MULT setting
+var vrc7Multipliers = [16]float64{0.5, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 10, 12, 12, 15, 15}
+
+// vrc7KeyScale is the key scale level, in dB, of the top four bits of the
+// frequency number in the highest block; each block below is 6 dB less
+var vrc7KeyScale = [16]float64{0, 9, 12, 13.875, 15, 16.125, 16.875, 17.625, 18, 18.75, 19.125, 19.5, 19.875, 20.25, 20.625, 21}
+
+// vrc7KeyScaleFactor scales vrc7KeyScale for each KSL setting: 0, 1.5, 3 and
+// 6 dB per octave
+var vrc7KeyScaleFactor = [4]float64{0, 0.25, 0.5, 1}
+
+// VRC7Audio is the sound of Konami's VRC7, used by Lagrange Point. The CPU
+// picks one of its registers through $9010 and writes it through $9030: the
+// custom instrument ($00-$07), each channel's frequency number ($10-$15),
+// key, sustain, block and frequency number bit 8 ($20-$25), and instrument
+// and volume ($30-$35).
+type VRC7Audio struct {
+	selected uint8    // $9010
+	custom   [8]uint8 // Instrument 0
+	channels [6]vrc7Channel
+
+	cycles  int        // CPU cycles into the current sample
+	lfo     float64    // Seconds, for the tremolo and vibrato
+	output  float64    // Last sample, held until the next
+	outputs [6]float64 // Each channel's last sample, from -1 to 1
+}
+
+// vrc7Channel is a channel: a modulator operator whose output moves the
+// phase of a carrier operator
+type vrc7Channel struct {
+	fnum       uint16 // 9 bits
+	block      uint8
+	key        bool
+	sustain    bool
+	instrument uint8
+	volume     uint8 // Attenuation in 3 dB steps
+
+	modulator vrc7Operator
+	carrier   vrc7Operator
+	feedback  [2]float64 // The modulator's last two outputs
+}
+
+// vrc7Stage is a stage of an operator's envelope
+type vrc7Stage uint8
+
+const (
+	vrc7Attack vrc7Stage = iota
+	vrc7Decay
+	vrc7Sustain
+	vrc7Release
+	vrc7Off
+)
+
+// vrc7Operator is an operator's phase and envelope
+type vrc7Operator struct {
+	phase       float64 // In cycles of the sine
+	attenuation float64 // Envelope, in dB
+	stage       vrc7Stage
+}
+
+// NewVRC7Audio creates the VRC7's sound
+func NewVRC7Audio() *VRC7Audio {
+	v := &VRC7Audio{}
+	for i := range v.channels {
+		c := &v.channels[i]
+		c.modulator = vrc7Operator{attenuation: vrc7Silence, stage: vrc7Off}
+		c.carrier = c.modulator
+	}
+	return v
+}
+
+// Write writes $9010 (register select) or $9030 (register data)
+func (v *VRC7Audio) Write(address uint16, value uint8) {
+	switch address {
+	case 0x9010:
+		v.selected = value
+	case 0x9030:
+		v.writeRegister(v.selected, value)
+	}
+}
+
+// writeRegister writes a register; the channel registers past $x5 do not
+// exist on the VRC7
+func (v *VRC7Audio) writeRegister(register, value uint8) {
+	if register < 0x08 {
+		v.custom[register] = value
+		return
+	}
+	channel := int(register & 0x0F)
+	if channel >= len(v.channels) {
+		return
+	}
+	c := &v.channels[channel]
+	switch register & 0xF0 {
+	case 0x10:
+		c.fnum = c.fnum&0x100 | uint16(value)
+	case 0x20:
+		c.fnum = c.fnum&0x0FF | uint16(value&0x01)<<8
+		c.block = value >> 1 & 0x07
+		c.sustain = value&0x20 != 0
+		key := value&0x10 != 0
+		if key && !c.key {
+			c.keyOn()
+		} else if !key && c.key {
+			c.modulator.stage = vrc7Release
+			c.carrier.stage = vrc7Release
+		}
+		c.key = key
+	case 0x30:
+		c.instrument = value >> 4
+		c.volume = value & 0x0F
+	}
+}
+
+// keyOn restarts the channel's operators from the start of their attack
+func (c *vrc7Channel) keyOn() {
+	for _, op := range []*vrc7Operator{&c.modulator, &c.carrier} {
+		op.phase = 0
+		op.stage = vrc7Attack
+	}
+	c.feedback = [2]float64{}
+}
+
+// patch returns the channel's instrument
+func (v *VRC7Audio) patch(c *vrc7Channel) *[8]uint8 {
+	if c.instrument == 0 {
+		return &v.custom
+	}
+	return &vrc7Patches[c.instrument-1]
+}
+
+// Clock computes a new sample every vrc7SampleCycles CPU cycles
+func (v *VRC7Audio) Clock() {
+	v.cycles++
+	if v.cycles < vrc7SampleCycles {
+		return
+	}
+	v.cycles = 0
+
+	v.lfo += 1 / vrc7SampleRate
+	// Tremolo of 4.8 dB at 3.7 Hz and vibrato of about 14 cents at 6.4 Hz
+	tremolo := 2.4 * (1 + math.Sin(2*math.Pi*3.7*v.lfo))
+	vibrato := 1 + 0.008*math.Sin(2*math.Pi*6.4*v.lfo)
+
+	var sum float64
+	for i := range v.channels {
+		v.outputs[i] = v.sample(&v.channels[i], tremolo, vibrato)
+		sum += v.outputs[i]
+	}
+	v.output = sum * vrc7Level
+}
+
+// sample steps a channel by one sample and returns its output, from -1 to 1.
+// The instrument's bytes hold, for the modulator and then the carrier:
+// tremolo, vibrato, sustained envelope, key scale rate and multiplier (bytes
+// 0-1), key scale level (bits 6-7 of bytes 2-3), attack and decay rates
+// (bytes 4-5), and sustain level and release rate (bytes 6-7). Byte 2 also
+// holds the modulator's total level, and byte 3 the half-wave rectification
+// of both and the modulator's feedback.
+func (v *VRC7Audio) sample(c *vrc7Channel, tremolo, vibrato float64) float64 {
+	if c.carrier.stage == vrc7Off {
+		return 0
+	}
+	p := v.patch(c)
+
+	// The modulator feeds back its last two outputs into its own phase
+	var feedback float64
+	if fb := p[3] & 0x07; fb > 0 {
+		feedback = math.Pi / 16 * float64(uint(1)<<(fb-1)) * (c.feedback[0] + c.feedback[1]) / 2
+	}
+	modAttenuation := float64(p[2]&0x3F)*0.75 + v.keyScale(c, p[2]>>6)
+	mod := c.modulator.step(c, p[0], p[4], p[6], tremolo, vibrato)
+	modOut := vrc7Wave(c.modulator.phase, feedback, p[3]&0x08 != 0) * vrc7Gain(mod+modAttenuation)
+	c.feedback[1], c.feedback[0] = c.feedback[0], modOut
+
+	carAttenuation := float64(c.volume)*3 + v.keyScale(c, p[3]>>6)
+	car := c.carrier.step(c, p[1], p[5], p[7], tremolo, vibrato)
+	return vrc7Wave(c.carrier.phase, modOut*4*math.Pi, p[3]&0x10 != 0) * vrc7Gain(car+carAttenuation)
+}
+
+// keyScale returns the attenuation key scale level ksl gives the channel's
+// pitch
+func (v *VRC7Audio) keyScale(c *vrc7Channel, ksl uint8) float64 {
+	level := vrc7KeyScale[c.fnum>>5] - 6*float64(7-c.block)
+	return max(level, 0) * vrc7KeyScaleFactor[ksl]
+}
+
+// step advances the operator's phase and envelope by one sample, with the
+// settings of its instrument bytes, and returns its attenuation in dB
+func (op *vrc7Operator) step(c *vrc7Channel, flags, adr, slr uint8, tremolo, vibrato float64) float64 {
+	increment := float64(c.fnum) * float64(uint(1)<<c.block) / (1 << 19) * vrc7Multipliers[flags&0x0F]
+	if flags&0x40 != 0 {
+		increment *= vibrato
+	}
+	op.phase += increment
+	op.phase -= math.Floor(op.phase)
+
+	// Key scale rate: higher notes have faster envelopes
+	rks := c.block<<1 | uint8(c.fnum>>8)
+	if flags&0x10 == 0 {
+		rks >>= 2
+	}
+	sustained := flags&0x20 != 0
+
+	switch op.stage {
+	case vrc7Attack:
+		op.attenuation -= vrc7AttackSpeed(adr>>4, rks)
+		if op.attenuation <= 0 {
+			op.attenuation = 0
+			op.stage = vrc7Decay
+		}
+	case vrc7Decay:
+		op.attenuation += vrc7DecaySpeed(adr&0x0F, rks)
+		if level := float64(slr>>4) * 3; op.attenuation >= level {
+			op.attenuation = level
+			op.stage = vrc7Sustain
+		}
+	case vrc7Sustain:
+		// Percussive instruments keep fading at the release rate
+		if !sustained {
+			op.attenuation += vrc7DecaySpeed(slr&0x0F, rks)
+		}
+	case vrc7Release:
+		rate := slr & 0x0F
+		switch {
+		case c.sustain:
+			rate = 5
+		case !sustained:
+			rate = 7
+		}
+		op.attenuation += vrc7DecaySpeed(rate, rks)
+	}
+	if op.attenuation >= vrc7Silence {
+		op.attenuation = vrc7Silence
+		if op.stage != vrc7Attack {
+			op.stage = vrc7Off
+		}
+	}
+
+	attenuation := op.attenuation
+	if flags&0x80 != 0 {
+		attenuation += tremolo
+	}
+	return attenuation
+}
+
+// vrc7Rate returns the effective rate, 0-63, of a 4-bit rate; 0 stops the
+// envelope
+func vrc7Rate(rate, rks uint8) int {
+	if rate == 0 {
+		return 0
+	}
+	return min(int(rate)*4+int(rks), 63)
+}
+
+// vrc7DecaySpeed returns how many dB a decay or release at rate falls by
+// each sample: at rate 1, about 20 seconds to silence, twice as fast for
+// each step up
+func vrc7DecaySpeed(rate, rks uint8) float64 {
+	r := vrc7Rate(rate, rks)
+	if r == 0 {
+		return 0
+	}
+	return 96 / 39.28 * math.Exp2(float64(r-4)/4) / vrc7SampleRate
+}
+
+// vrc7AttackSpeed returns how many dB an attack at rate rises by each
+// sample: at rate 1, about 1.4 seconds to full level; rate 15 is instant
+func vrc7AttackSpeed(rate, rks uint8) float64 {
+	r := vrc7Rate(rate, rks)
+	switch {
+	case r == 0:
+		return 0
+	case r >= 60:
+		return vrc7Silence
+	}
+	return 96 / 2.826 * math.Exp2(float64(r-4)/4) / vrc7SampleRate
+}
+
+// vrc7Wave returns the sine at phase (in cycles) moved by offset (in
+// radians), with its negative half cut off when rectified
+func vrc7Wave(phase, offset float64, rectified bool) float64 {
+	s := math.Sin(2*math.Pi*phase + offset)
+	if rectified && s < 0 {
+		return 0
+	}
+	return s
+}
+
+// vrc7Gain returns the gain of an attenuation in dB
+func vrc7Gain(attenuation float64) float64 {
+	if attenuation >= vrc7Silence {
+		return 0
+	}
+	return math.Pow(10, -attenuation/20)
+}
+
+// Output returns the sum of the channels
+func (v *VRC7Audio) Output() float64 {
+	return v.output
+}
+
+// vrc7ChannelNames names the VRC7's channels on the scope
+var vrc7ChannelNames = []string{"VRC7 1", "VRC7 2", "VRC7 3", "VRC7 4", "VRC7 5", "VRC7 6"}
+
+// ChannelNames returns the names of the six channels
+func (v *VRC7Audio) ChannelNames() []string {
+	return vrc7ChannelNames
+}
+
+// ChannelOutputs writes the six channels' outputs to dst
+func (v *VRC7Audio) ChannelOutputs(dst []float32) {
+	for i, output := range v.outputs {
+		dst[i] = float32((output + 1) / 2)
+	}
+}
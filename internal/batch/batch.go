@@ -0,0 +1,205 @@
+// Package batch boots every ROM in a directory headlessly and reports which
+// ones crash, use a mapper the emulator lacks or show a blank screen, to
+// track compatibility across releases.
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gones/internal/bus"
+	"gones/internal/cartridge"
+)
+
+// DefaultFrames is how long each ROM runs, ten seconds: past most title
+// screens' fade-ins
+const DefaultFrames = 600
+
+// Status is the outcome of booting a ROM
+type Status string
+
+const (
+	OK          Status = "ok"          // Ran and drew something
+	Crash       Status = "crash"       // The emulator panicked or the CPU jammed
+	Unsupported Status = "unsupported" // The mapper is not emulated, so the ROM was not run
+	Blank       Status = "blank"       // The screen was a single color at the end
+	LoadError   Status = "error"       // Not a loadable ROM
+)
+
+// statuses lists the statuses in the order the report counts them
+var statuses = []Status{OK, Crash, Unsupported, Blank, LoadError}
+
+// Result is the outcome of booting one ROM
+type Result struct {
+	ROM      string        // Path relative to the directory searched
+	Mapper   int           // Mapper number, -1 when the ROM did not load
+	Status   Status        //
+	Message  string        // What went wrong
+	Frames   int           // Frames run
+	Duration time.Duration //
+}
+
+// Find returns the .nes and .zip files under dir, in path order
+func Find(dir string) ([]string, error) {
+	var roms []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && cartridge.IsROMFile(path) {
+			roms = append(roms, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(roms)
+	return roms, nil
+}
+
+// Run boots a ROM for frames frames
+func Run(path string, frames int) (result Result) {
+	result = Result{ROM: path, Mapper: -1}
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			result.Status = Crash
+			result.Message = fmt.Sprintf("emulator panic: %v", r)
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	cart, err := cartridge.LoadFromFile(path)
+	if err != nil {
+		result.Status = LoadError
+		result.Message = fmt.Sprintf("failed to load ROM: %v", err)
+		return result
+	}
+	result.Mapper = int(cart.MapperID())
+	if !cartridge.MapperSupported(cart.MapperID()) {
+		result.Status = Unsupported
+		result.Message = fmt.Sprintf("mapper %d is not supported", cart.MapperID())
+		return result
+	}
+
+	b := bus.New()
+	b.LoadCartridge(cart)
+	b.Reset()
+	for result.Frames < frames {
+		b.Run(1)
+		result.Frames++
+		if b.CPU.Jammed() {
+			result.Status = Crash
+			result.Message = fmt.Sprintf("CPU jammed at $%04X in frame %d", b.CPU.PC, result.Frames)
+			return result
+		}
+	}
+
+	if color, blank := singleColor(b.GetFrameBuffer()); blank {
+		result.Status = Blank
+		result.Message = fmt.Sprintf("screen is all #%06X after %d frames", color, result.Frames)
+		return result
+	}
+	result.Status = OK
+	return result
+}
+
+// singleColor reports whether every pixel of a frame is the same color
+func singleColor(frame []uint32) (uint32, bool) {
+	if len(frame) == 0 {
+		return 0, true
+	}
+	for _, pixel := range frame[1:] {
+		if pixel != frame[0] {
+			return 0, false
+		}
+	}
+	return frame[0], true
+}
+
+// RunDir boots every ROM under dir in order, calling done, if not nil, after
+// each one. ROM paths in the results are relative to dir.
+func RunDir(dir string, frames int, done func(Result)) ([]Result, error) {
+	roms, err := Find(dir)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, 0, len(roms))
+	for _, rom := range roms {
+		result := Run(rom, frames)
+		if name, err := filepath.Rel(dir, rom); err == nil {
+			result.ROM = name
+		}
+		results = append(results, result)
+		if done != nil {
+			done(result)
+		}
+	}
+	return results, nil
+}
+
+// WriteReport writes a table of results followed by the count of each
+// status, and reports whether every ROM booted fine
+func WriteReport(w io.Writer, results []Result) bool {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROM\tSTATUS\tMAPPER\tFRAMES\tTIME\tDETAIL")
+	counts := make(map[Status]int)
+	for _, r := range results {
+		counts[r.Status]++
+		mapper := "-"
+		if r.Mapper >= 0 {
+			mapper = fmt.Sprint(r.Mapper)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.2fs\t%s\n", r.ROM, r.Status, mapper, r.Frames, r.Duration.Seconds(), r.Message)
+	}
+	tw.Flush()
+
+	parts := make([]string, len(statuses))
+	for i, status := range statuses {
+		parts[i] = fmt.Sprintf("%d %s", counts[status], status)
+	}
+	fmt.Fprintf(w, "\n%s (%d ROMs)\n", strings.Join(parts, ", "), len(results))
+	return counts[OK] == len(results)
+}
+
+// jsonResult is a result in the JSON report
+type jsonResult struct {
+	ROM     string  `json:"rom"`
+	Mapper  int     `json:"mapper"` // -1 when the ROM did not load
+	Status  Status  `json:"status"`
+	Message string  `json:"message,omitempty"`
+	Frames  int     `json:"frames"`
+	Seconds float64 `json:"seconds"`
+}
+
+// WriteJSON writes the results and the count of each status as a JSON
+// object, for CI pipelines and dashboards, and reports whether every ROM
+// booted fine
+func WriteJSON(w io.Writer, results []Result) (bool, error) {
+	report := struct {
+		OK      bool           `json:"ok"` // Every ROM booted fine
+		Total   int            `json:"total"`
+		Counts  map[Status]int `json:"counts"`
+		Results []jsonResult   `json:"results"`
+	}{Total: len(results), Counts: make(map[Status]int), Results: make([]jsonResult, len(results))}
+	for _, status := range statuses {
+		report.Counts[status] = 0
+	}
+	for i, r := range results {
+		report.Counts[r.Status]++
+		report.Results[i] = jsonResult{r.ROM, r.Mapper, r.Status, r.Message, r.Frames, r.Duration.Seconds()}
+	}
+	report.OK = report.Counts[OK] == len(results)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return report.OK, encoder.Encode(report)
+}
@@ -0,0 +1,117 @@
+package batch
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gones/internal/cartridge"
+)
+
+// writeROM writes an NROM image running code from $8000
+func writeROM(t *testing.T, path string, builder *cartridge.TestROMBuilder, code []uint8) {
+	t.Helper()
+	rom, err := builder.WithInstructions(code).WithResetVector(0x8000).Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// drawingCode waits for the PPU to warm up, then sets a black and white
+// palette and shows the background, whose tiles are half color 1
+var drawingCode = []uint8{
+	0x2C, 0x02, 0x20, // BIT $2002 (clear the vblank flag)
+	0x2C, 0x02, 0x20, 0x10, 0xFB, // BIT $2002; BPL *-3
+	0x2C, 0x02, 0x20, 0x10, 0xFB, // BIT $2002; BPL *-3
+	0xA9, 0x3F, 0x8D, 0x06, 0x20, // LDA #$3F; STA $2006
+	0xA9, 0x00, 0x8D, 0x06, 0x20, // LDA #$00; STA $2006
+	0xA9, 0x0F, 0x8D, 0x07, 0x20, // LDA #$0F; STA $2007
+	0xA9, 0x30, 0x8D, 0x07, 0x20, // LDA #$30; STA $2007
+	0xA9, 0x00, 0x8D, 0x05, 0x20, 0x8D, 0x05, 0x20, // LDA #0; STA $2005; STA $2005
+	0xA9, 0x0A, 0x8D, 0x01, 0x20, // LDA #$0A; STA $2001
+	0x4C, 0x2E, 0x80, // JMP *
+}
+
+func TestRunDir(t *testing.T) {
+	dir := t.TempDir()
+	chr := make([]uint8, 0x2000)
+	for i := 0; i < 8; i++ {
+		chr[i] = 0xF0 // Tile 0, plane 0: left half color 1
+	}
+	writeROM(t, filepath.Join(dir, "drawing.nes"), cartridge.NewTestROMBuilder().WithCHRData(chr), drawingCode)
+	writeROM(t, filepath.Join(dir, "blank.nes"), cartridge.NewTestROMBuilder(), []uint8{0x4C, 0x00, 0x80})
+	writeROM(t, filepath.Join(dir, "sub", "jam.nes"), cartridge.NewTestROMBuilder(), []uint8{0xEA, 0x02})
+	writeROM(t, filepath.Join(dir, "mmc5.NES"), cartridge.NewTestROMBuilder().WithMapper(5), []uint8{0x4C, 0x00, 0x80})
+	if err := os.WriteFile(filepath.Join(dir, "broken.nes"), []byte("not a ROM"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("skipped"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var done []string
+	results, err := RunDir(dir, 5, func(r Result) { done = append(done, r.ROM) })
+	if err != nil {
+		t.Fatalf("RunDir: %v", err)
+	}
+	want := []struct {
+		rom    string
+		status Status
+		mapper int
+	}{
+		{"blank.nes", Blank, 0},
+		{"broken.nes", LoadError, -1},
+		{"drawing.nes", OK, 0},
+		{"mmc5.NES", Unsupported, 5},
+		{filepath.Join("sub", "jam.nes"), Crash, 0},
+	}
+	if len(results) != len(want) || len(done) != len(want) {
+		t.Fatalf("got %d results (%d reported), want %d: %+v", len(results), len(done), len(want), results)
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.ROM != w.rom || r.Status != w.status || r.Mapper != w.mapper {
+			t.Errorf("result %d = %s %s mapper %d (%s), want %s %s mapper %d",
+				i, r.ROM, r.Status, r.Mapper, r.Message, w.rom, w.status, w.mapper)
+		}
+	}
+	if results[2].Frames != 5 || !strings.Contains(results[4].Message, "jammed") {
+		t.Errorf("drawing ran %d frames; jam message %q", results[2].Frames, results[4].Message)
+	}
+
+	var report strings.Builder
+	if WriteReport(&report, results) {
+		t.Error("WriteReport reported every ROM fine")
+	}
+	if !strings.Contains(report.String(), "1 ok, 1 crash, 1 unsupported, 1 blank, 1 error (5 ROMs)") {
+		t.Errorf("report:\n%s", report.String())
+	}
+	if !WriteReport(&report, results[2:3]) {
+		t.Error("WriteReport did not report a fine ROM as fine")
+	}
+
+	report.Reset()
+	ok, err := WriteJSON(&report, results)
+	if err != nil || ok {
+		t.Fatalf("WriteJSON = %v, %v; want false, nil", ok, err)
+	}
+	var decoded struct {
+		Total   int            `json:"total"`
+		Counts  map[string]int `json:"counts"`
+		Results []Result       `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(report.String()), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, report.String())
+	}
+	if decoded.Total != 5 || decoded.Counts["crash"] != 1 || decoded.Results[3].Mapper != 5 || decoded.Results[3].Status != Unsupported {
+		t.Errorf("JSON report:\n%s", report.String())
+	}
+}
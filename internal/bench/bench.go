@@ -0,0 +1,209 @@
+// Package bench benchmarks the emulator core: it runs a ROM unthrottled
+// without video or audio output and reports the emulation speed, where the
+// time went and how much was allocated.
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"gones/internal/bus"
+	"gones/internal/cartridge"
+	"gones/internal/metrics"
+)
+
+// Defaults
+const (
+	DefaultFrames = 3600 // One minute of NES time
+	DefaultWarmup = 60
+)
+
+// timingSample is how many CPU steps pass per step timed by component
+const timingSample = 64
+
+// ntscFrameTime is the duration of a frame on an NTSC NES
+const ntscFrameTime = time.Second * 10000 / 600988
+
+// frameTimeQuantiles are the frame time quantiles reported
+var frameTimeQuantiles = []float64{0.5, 0.99}
+
+// Options configures a benchmark
+type Options struct {
+	Frames int // Frames measured (default DefaultFrames)
+	Warmup int // Frames run before measuring, negative for none (default DefaultWarmup)
+}
+
+// Result is the outcome of a benchmark
+type Result struct {
+	ROM        string
+	Frames     int
+	Elapsed    time.Duration
+	FrameTimes []float64          // Frame time quantiles in seconds, for frameTimeQuantiles
+	Slowest    time.Duration      // Longest frame
+	Components bus.ComponentTimes // Estimated from a sample of steps
+	Allocs     uint64             // Heap allocations while measuring
+	Bytes      uint64             // Bytes allocated while measuring
+	GCs        uint32             // Garbage collections while measuring
+}
+
+// FPS returns the emulated frames per second
+func (r *Result) FPS() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Frames) / r.Elapsed.Seconds()
+}
+
+// Run benchmarks a ROM
+func Run(rom string, options Options) (result *Result, err error) {
+	frames, warmup := options.Frames, options.Warmup
+	if frames <= 0 {
+		frames = DefaultFrames
+	}
+	switch {
+	case warmup == 0:
+		warmup = DefaultWarmup
+	case warmup < 0:
+		warmup = 0
+	}
+
+	cart, err := cartridge.LoadFromFile(rom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ROM: %v", err)
+	}
+	b := bus.New()
+	b.LoadCartridge(cart)
+	b.Reset()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("emulator panic: %v", r)
+		}
+	}()
+
+	b.Run(warmup)
+
+	window := metrics.NewWindow(frames)
+	result = &Result{ROM: rom, Frames: frames}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.EnableComponentTiming(timingSample)
+	start := time.Now()
+	for i := 0; i < frames; i++ {
+		frameStart := time.Now()
+		b.Run(1)
+		b.GetAudioSamples() // Drained every frame, like the audio output does
+		frameTime := time.Since(frameStart)
+		window.Add(frameTime.Seconds())
+		result.Slowest = max(result.Slowest, frameTime)
+	}
+	result.Elapsed = time.Since(start)
+	runtime.ReadMemStats(&after)
+	result.Components = b.ComponentTimes()
+	b.EnableComponentTiming(0)
+
+	result.FrameTimes = window.Quantiles(frameTimeQuantiles...)
+	result.Allocs = after.Mallocs - before.Mallocs
+	result.Bytes = after.TotalAlloc - before.TotalAlloc
+	result.GCs = after.NumGC - before.NumGC
+	return result, nil
+}
+
+// Write writes a report of the result
+func (r *Result) Write(w io.Writer) {
+	fmt.Fprintf(w, "Frames:      %d in %.2fs\n", r.Frames, r.Elapsed.Seconds())
+	fmt.Fprintf(w, "Speed:       %.1f FPS (%.1fx real time)\n", r.FPS(), r.FPS()*ntscFrameTime.Seconds())
+	fmt.Fprintf(w, "Frame time:  p50 %s  p99 %s  max %s\n",
+		milliseconds(r.FrameTimes[0]), milliseconds(r.FrameTimes[1]), milliseconds(r.Slowest.Seconds()))
+
+	c := r.Components
+	fmt.Fprintf(w, "Components:  CPU %s  PPU %s  APU %s  (of emulation time, sampled 1 in %d steps)\n",
+		share(c, c.CPU), share(c, c.PPU), share(c, c.APU), timingSample)
+
+	perFrame := func(n uint64) float64 { return float64(n) / float64(r.Frames) }
+	fmt.Fprintf(w, "Allocations: %.1f per frame, %s per frame, %d GCs\n",
+		perFrame(r.Allocs), formatBytes(perFrame(r.Bytes)), r.GCs)
+}
+
+// jsonResult is the JSON form of a result. Frame times are in milliseconds,
+// component shares are fractions of the emulation time and allocations are
+// per frame.
+type jsonResult struct {
+	ROM            string  `json:"rom"`
+	Frames         int     `json:"frames"`
+	Seconds        float64 `json:"seconds"`
+	FPS            float64 `json:"fps"`
+	RealTime       float64 `json:"real_time"` // Speed as a multiple of an NTSC NES
+	FrameTimeP50   float64 `json:"frame_time_p50_ms"`
+	FrameTimeP99   float64 `json:"frame_time_p99_ms"`
+	FrameTimeMax   float64 `json:"frame_time_max_ms"`
+	CPUShare       float64 `json:"cpu_share"`
+	PPUShare       float64 `json:"ppu_share"`
+	APUShare       float64 `json:"apu_share"`
+	AllocsPerFrame float64 `json:"allocs_per_frame"`
+	BytesPerFrame  float64 `json:"bytes_per_frame"`
+	GCs            uint32  `json:"gcs"`
+}
+
+// WriteJSON writes the result as a JSON object, for CI pipelines and
+// dashboards
+func (r *Result) WriteJSON(w io.Writer) error {
+	c := r.Components
+	perFrame := func(n uint64) float64 { return float64(n) / float64(r.Frames) }
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(jsonResult{
+		ROM:            r.ROM,
+		Frames:         r.Frames,
+		Seconds:        r.Elapsed.Seconds(),
+		FPS:            r.FPS(),
+		RealTime:       r.FPS() * ntscFrameTime.Seconds(),
+		FrameTimeP50:   r.FrameTimes[0] * 1000,
+		FrameTimeP99:   r.FrameTimes[1] * 1000,
+		FrameTimeMax:   r.Slowest.Seconds() * 1000,
+		CPUShare:       fraction(c, c.CPU),
+		PPUShare:       fraction(c, c.PPU),
+		APUShare:       fraction(c, c.APU),
+		AllocsPerFrame: perFrame(r.Allocs),
+		BytesPerFrame:  perFrame(r.Bytes),
+		GCs:            r.GCs,
+	})
+}
+
+// share formats a component's share of the time spent in all of them. The
+// shares hold up better than the times themselves, which the sampling and
+// the clock reads skew.
+func share(c bus.ComponentTimes, d time.Duration) string {
+	if c.CPU+c.PPU+c.APU <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*fraction(c, d))
+}
+
+// fraction returns a component's share of the time spent in all of them, 0
+// when none was timed
+func fraction(c bus.ComponentTimes, d time.Duration) float64 {
+	total := c.CPU + c.PPU + c.APU
+	if total <= 0 {
+		return 0
+	}
+	return d.Seconds() / total.Seconds()
+}
+
+func milliseconds(seconds float64) string {
+	return fmt.Sprintf("%.3fms", seconds*1000)
+}
+
+func formatBytes(n float64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", n/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", n/(1<<10))
+	default:
+		return fmt.Sprintf("%.0f B", n)
+	}
+}
@@ -0,0 +1,61 @@
+package bench
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gones/internal/cartridge"
+)
+
+func TestRun(t *testing.T) {
+	data, err := cartridge.NewTestROMBuilder().
+		WithInstructions([]uint8{0xE6, 0x10, 0x4C, 0x00, 0x80}). // INC $10; JMP $8000
+		WithResetVector(0x8000).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rom := filepath.Join(t.TempDir(), "loop.nes")
+	if err := os.WriteFile(rom, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Run(rom, Options{Frames: 30, Warmup: -1})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Frames != 30 || result.FPS() <= 0 || result.Components.Steps == 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if result.FrameTimes[0] > result.FrameTimes[1] || result.FrameTimes[1] > result.Slowest.Seconds() {
+		t.Errorf("frame times out of order: %v, slowest %v", result.FrameTimes, result.Slowest)
+	}
+
+	var b strings.Builder
+	result.Write(&b)
+	for _, want := range []string{"Frames:      30 in", "FPS", "CPU ", "PPU ", "per frame"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, b.String())
+		}
+	}
+
+	b.Reset()
+	if err := result.WriteJSON(&b); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var report map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, b.String())
+	}
+	shares := report["cpu_share"].(float64) + report["ppu_share"].(float64) + report["apu_share"].(float64)
+	if report["frames"] != 30.0 || report["fps"].(float64) <= 0 || shares < 0.99 || shares > 1.01 {
+		t.Errorf("JSON report = %s", b.String())
+	}
+
+	if _, err := Run(filepath.Join(t.TempDir(), "missing.nes"), Options{}); err == nil {
+		t.Error("missing ROM accepted")
+	}
+}
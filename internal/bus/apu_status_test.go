@@ -0,0 +1,70 @@
+package bus
+
+import "testing"
+
+func TestAPUStatus_LengthCounters(t *testing.T) {
+	bus := newDMCTestBus(t, false)
+	bus.RunCycles(100) // Past the program's register writes
+
+	status := func() uint8 { return bus.Memory.Read(0x4015) & 0x1F }
+
+	bus.Memory.Write(0x4015, 0x0F)
+	for _, address := range []uint16{0x4003, 0x4007, 0x400B, 0x400F} {
+		bus.Memory.Write(address, 0x08) // Length 254
+	}
+	if got := status(); got != 0x0F {
+		t.Errorf("$4015 with the length counters loaded = $%02X, want $0F", got)
+	}
+
+	bus.Memory.Write(0x4015, 0x05)
+	if got := status(); got != 0x05 {
+		t.Errorf("$4015 after disabling pulse 2 and noise = $%02X, want $05", got)
+	}
+
+	// Disabled channels ignore length loads
+	bus.Memory.Write(0x4007, 0x08)
+	bus.Memory.Write(0x400F, 0x08)
+	if got := status(); got != 0x05 {
+		t.Errorf("$4015 after loading disabled channels = $%02X, want $05", got)
+	}
+}
+
+func TestAPUStatus_FrameIRQ(t *testing.T) {
+	bus := newDMCTestBus(t, false)
+	bus.RunCycles(100)
+
+	bus.Memory.Write(0x4017, 0x00) // 4-step, IRQ on
+	bus.RunCycles(30000)
+	if got := bus.Memory.Read(0x4015) & 0x40; got == 0 {
+		t.Fatal("frame IRQ flag not set after a 4-step sequence")
+	}
+	if got := bus.Memory.Read(0x4015) & 0x40; got != 0 {
+		t.Error("frame IRQ flag still set after reading $4015")
+	}
+
+	bus.Memory.Write(0x4017, 0x40) // IRQ inhibited
+	bus.RunCycles(30000)
+	if got := bus.Memory.Read(0x4015) & 0x40; got != 0 {
+		t.Error("frame IRQ flag set while $4017 inhibits it")
+	}
+}
+
+func TestAPUStatus_DMCIRQ(t *testing.T) {
+	bus := newDMCTestBus(t, false)
+	bus.RunCycles(100)
+
+	bus.Memory.Write(0x4010, 0x8F) // IRQ on, fastest rate
+	bus.Memory.Write(0x4013, 0x00) // 1 byte
+	bus.Memory.Write(0x4015, 0x10)
+	bus.RunCycles(10)
+
+	for i := 0; i < 2; i++ {
+		if got := bus.Memory.Read(0x4015); got&0x90 != 0x80 {
+			t.Errorf("$4015 read %d after the sample ended = $%02X, want the DMC IRQ flag and no bytes left", i+1, got)
+		}
+	}
+	bus.Memory.Write(0x4015, 0x00)
+	if got := bus.Memory.Read(0x4015) & 0x80; got != 0 {
+		t.Error("DMC IRQ flag still set after writing $4015")
+	}
+}
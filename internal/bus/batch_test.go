@@ -0,0 +1,94 @@
+package bus
+
+import (
+	"testing"
+
+	"gones/internal/cartridge"
+	"gones/internal/region"
+)
+
+// newBatchTestBus returns a bus running a loop that polls $2002 between
+// delays, with an NMI handler counting frames
+func newBatchTestBus(t *testing.T, r region.Region) *Bus {
+	t.Helper()
+
+	cart, err := cartridge.NewTestROMBuilder().
+		WithPRGSize(1).
+		WithCHRSize(1).
+		WithResetVector(0x8000).
+		WithNMIVector(0x8020).
+		WithData(0x0000, []uint8{
+			0xA9, 0x80, // LDA #$80
+			0x8D, 0x00, 0x20, // STA $2000 (NMI on)
+			0xE6, 0x10, // loop: INC $10
+			0xA2, 0x40, // LDX #$40
+			0xCA,       // delay: DEX
+			0xD0, 0xFD, // BNE delay
+			0x2C, 0x02, 0x20, // BIT $2002
+			0x4C, 0x05, 0x80, // JMP loop
+		}).
+		WithData(0x0020, []uint8{
+			0xE6, 0x11, // INC $11
+			0xAD, 0x02, 0x20, // LDA $2002
+			0x40, // RTI
+		}).
+		BuildCartridge()
+	if err != nil {
+		t.Fatalf("Failed to create test cartridge: %v", err)
+	}
+
+	bus := New()
+	bus.LoadCartridge(cart)
+	bus.Reset()
+	bus.SetRegion(r)
+	return bus
+}
+
+func TestRunBatch_MatchesStep(t *testing.T) {
+	for _, r := range []region.Region{region.NTSC, region.PAL} {
+		t.Run(r.String(), func(t *testing.T) {
+			batched := newBatchTestBus(t, r)
+			stepped := newBatchTestBus(t, r)
+
+			const frames = 5
+			batched.Run(frames)
+			for stepped.GetFrameCount() < frames {
+				stepped.Step()
+			}
+
+			if got, want := batched.GetCycleCount(), stepped.GetCycleCount(); got != want {
+				t.Errorf("Batched run took %d CPU cycles, stepped %d", got, want)
+			}
+			if batched.CPU.PC != stepped.CPU.PC || batched.CPU.X != stepped.CPU.X {
+				t.Errorf("Batched CPU at PC $%04X X $%02X, stepped at PC $%04X X $%02X",
+					batched.CPU.PC, batched.CPU.X, stepped.CPU.PC, stepped.CPU.X)
+			}
+			for _, address := range []uint16{0x0010, 0x0011} {
+				if got, want := batched.Memory.Read(address), stepped.Memory.Read(address); got != want {
+					t.Errorf("Batched run left $%04X = %d, stepped %d", address, got, want)
+				}
+			}
+			if batched.Memory.Read(0x0011) == 0 {
+				t.Error("Expected the NMI handler to run")
+			}
+			if batched.PPU.GetScanline() != stepped.PPU.GetScanline() || batched.PPU.GetCycle() != stepped.PPU.GetCycle() {
+				t.Errorf("Batched PPU at %d,%d, stepped at %d,%d", batched.PPU.GetScanline(), batched.PPU.GetCycle(),
+					stepped.PPU.GetScanline(), stepped.PPU.GetCycle())
+			}
+		})
+	}
+}
+
+func TestRunBatch_StopsAtTarget(t *testing.T) {
+	bus := newBatchTestBus(t, region.NTSC)
+	target := bus.GetCycleCount() + 1000
+	for bus.GetCycleCount() < target {
+		bus.RunBatch(target)
+	}
+	if overshoot := bus.GetCycleCount() - target; overshoot > 7 {
+		t.Errorf("Ran %d CPU cycles past the target", overshoot)
+	}
+	if got, want := bus.PPU.GetCycleCount(), bus.GetCycleCount()*3; got != want {
+		t.Errorf("PPU ran %d cycles for %d CPU cycles, want %d", got, bus.GetCycleCount(), want)
+	}
+}
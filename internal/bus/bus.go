@@ -3,6 +3,8 @@ package bus
 
 import (
 	"fmt"
+	"math"
+	"time"
 	
 	"gones/internal/apu"
 	"gones/internal/cartridge"
@@ -10,6 +12,7 @@ import (
 	"gones/internal/input"
 	"gones/internal/memory"
 	"gones/internal/ppu"
+	"gones/internal/region"
 )
 
 // Bus connects all NES components together
@@ -21,6 +24,26 @@ type Bus struct {
 	Memory *memory.Memory
 	Input  *input.InputState
 
+	// Currently inserted cartridge (nil until LoadCartridge)
+	cartridge memory.CartridgeInterface
+
+	// Signals of the cartridge's mapper the bus handles (nil when it takes
+	// none); the PPU sends the others (see connectMapper)
+	mapperClock cartridge.CPUClocked
+	mapperIRQ   cartridge.IRQSource
+
+	// Events due on the master clock, and the mapper scheduling them (nil
+	// when it schedules none)
+	scheduler    Scheduler
+	mapperEvents cartridge.EventScheduled
+
+	// What internal RAM holds at power on (see SetRAMPattern)
+	ramPattern memory.RAMPattern
+	ramSeed    int64
+
+	// PPU dots the PPU starts ahead of the CPU (see SetPPUAlignment)
+	ppuAlignment int
+
 	// System state
 	totalCycles uint64
 	cpuCycles   uint64
@@ -30,11 +53,26 @@ type Bus struct {
 	// Timing coordination
 	dmaSuspendCycles uint64
 	dmaInProgress    bool
+	dmcStallCycles   uint64 // Left of the CPU stall for DMC sample fetches
 	nmiPending       bool
 
+	// Whether a DMC fetch during a controller read clocks the controller
+	// again, as on the NTSC 2A03 (see SetDMCConflicts)
+	dmcConflicts bool
+
+	// Whether the PPU ignores some register writes for a while after reset
+	// (see SetPPUWarmUp)
+	ppuWarmUp bool
+
+	// Batched stepping: CPU cycles run ahead of the PPU and APU, and whether
+	// the batch in progress ends after the current instruction
+	pendingCycles uint64
+	batchEnd      bool
+
 	// Frame timing (NTSC: 262 scanlines, 341 PPU cycles/scanline)
 	cyclesPerFrame uint64 // 89342 PPU cycles = 29780.67 CPU cycles
 	oddFrame       bool
+	region         region.Region
 
 	// Execution logging for testing
 	executionLog   []BusExecutionEvent
@@ -43,6 +81,12 @@ type Bus struct {
 	// Memory monitoring for debugging
 	memoryWatchpoints map[uint16]uint8 // Address -> previous value
 	watchpointLogging bool
+
+	// Input debug logging, including the once a second frame sync message
+	inputDebug bool
+
+	// Sampled component timing for benchmarks (nil when off)
+	timing *componentTiming
 }
 
 // New creates a new system bus with all components
@@ -55,6 +99,9 @@ func New() *Bus {
 		// NTSC timing: 89342 PPU cycles per frame
 		cyclesPerFrame: 89342,
 
+		dmcConflicts: true,
+		ppuWarmUp:    true,
+
 		// Initialize memory monitoring
 		memoryWatchpoints: make(map[uint16]uint8),
 		watchpointLogging: false,
@@ -73,6 +120,9 @@ func New() *Bus {
 	bus.PPU.SetNMICallback(bus.triggerNMI)
 	bus.PPU.SetFrameCompleteCallback(bus.handleFrameComplete)
 	bus.Memory.SetDMACallback(bus.TriggerOAMDMA)
+	bus.Memory.SetSyncHook(bus.Sync)
+	bus.Memory.SetControllerReadHook(bus.controllerRead)
+	bus.APU.SetDMCReader(bus.dmcRead)
 
 	// Reset all components to proper initial state
 	bus.Reset()
@@ -84,6 +134,9 @@ func New() *Bus {
 func (b *Bus) Reset() {
 	b.CPU.Reset()
 	b.PPU.Reset()
+	if b.ppuWarmUp {
+		b.PPU.StartWarmUp()
+	}
 	b.APU.Reset()
 	b.Input.Reset()
 
@@ -94,11 +147,19 @@ func (b *Bus) Reset() {
 	b.frameCount = 0
 	b.dmaSuspendCycles = 0
 	b.dmaInProgress = false
+	b.dmcStallCycles = 0
 	b.nmiPending = false
+	b.pendingCycles = 0
 	b.oddFrame = false
 
 	// Synchronize PPU frame count with bus
 	b.PPU.SetFrameCount(0)
+	for i := 0; i < b.ppuAlignment; i++ {
+		b.PPU.Step()
+	}
+
+	// The clock starts again, so events are scheduled anew
+	b.restartEvents()
 
 	// Clear execution log
 	b.executionLog = make([]BusExecutionEvent, 0)
@@ -139,78 +200,238 @@ func (b *Bus) synchronizeInputStates() {
 	// but this provides a hook for future enhancements if needed
 	
 	// For debugging: log frame sync events occasionally
-	if b.frameCount%60 == 0 { // Once per second at 60fps
+	if b.inputDebug && b.frameCount%60 == 0 { // Once per second at 60fps
 		fmt.Printf("[FRAME_SYNC] Frame %d: Input synchronized\n", b.frameCount)
 	}
 }
 
 // Step executes one CPU instruction and advances other components accordingly
 func (b *Bus) Step() {
-	var cpuCycles uint64
-
 	// Capture pre-step state for logging
 	preFrameCount := b.frameCount
 	prePC := b.CPU.PC
 	var preOpcode uint8
 	if b.Memory != nil {
-		preOpcode = b.Memory.Read(prePC)
+		// Peek so the logging read does not reach the memory access hooks
+		preOpcode, _ = b.Memory.Peek(prePC)
+	}
+
+	// Time the components of a sample of steps when benchmarking
+	timed := b.timing != nil && b.timing.timeStep()
+	var mark time.Time
+	if timed {
+		mark = time.Now()
+	}
+
+	b.pendingCycles += b.stepCPU()
+	if timed {
+		mark = b.timing.lap(&b.timing.times.CPU, mark)
+	}
+	b.catchUp(timed, mark)
+
+	// Frame completion is now handled by PPU callback for precise timing
+
+	// Check memory watchpoints for changes (reduced frequency for better performance)
+	if b.watchpointLogging && b.frameCount%300 == 0 { // Check every 5 seconds at 60fps
+		b.CheckMemoryWatchpoints()
+	}
+
+	// Log execution if enabled
+	if b.loggingEnabled {
+		timing := b.region.Timing()
+		event := BusExecutionEvent{
+			StepNumber:    len(b.executionLog) + 1,
+			CPUCycles:     b.cpuCycles,
+			PPUCycles:     b.cpuCycles * timing.PPUCycles / timing.CPUCycles,
+			FrameCount:    b.frameCount,
+			DMAActive:     b.dmaInProgress,
+			NMIProcessed:  b.frameCount > preFrameCount, // Frame count increased
+			PCValue:       prePC,
+			InstructionOp: preOpcode,
+		}
+		b.executionLog = append(b.executionLog, event)
+	}
+}
+
+// stepCPU executes one CPU instruction, or one cycle of a DMA the CPU is
+// suspended for, without advancing the PPU and APU. It returns the CPU cycles
+// taken.
+func (b *Bus) stepCPU() uint64 {
+	if b.scheduler.Len() > 0 {
+		b.scheduler.RunDue(b.masterClock())
+	}
+
+	// The CPU is stalled while the DMC fetches a sample byte
+	if b.dmcStallCycles > 0 {
+		b.dmcStallCycles--
+		return 1
 	}
 
 	// Check if CPU is suspended for DMA
 	if b.dmaSuspendCycles > 0 {
 		// CPU is suspended, consume DMA cycles
-		cpuCycles = 1
 		b.dmaSuspendCycles--
 		if b.dmaSuspendCycles == 0 {
 			b.dmaInProgress = false
 		}
-	} else {
-		// Handle pending NMI before executing instruction
-		if b.nmiPending {
-			b.CPU.TriggerNMI()
-			b.nmiPending = false
-		}
+		return 1
+	}
+
+	// Handle pending NMI before executing instruction
+	if b.nmiPending {
+		b.CPU.TriggerNMI()
+		b.nmiPending = false
+	}
 
-		// Execute one CPU instruction
-		cpuCycles = b.CPU.Step()
+	// The mapper's IRQ line, which the CPU checks after the instruction
+	if b.mapperIRQ != nil {
+		b.CPU.SetIRQ(b.mapperIRQ.IRQ())
 	}
 
-	// PPU runs at exactly 3x CPU speed (cycle-accurate)
-	ppuCyclesToRun := cpuCycles * 3
+	// Execute one CPU instruction
+	return b.CPU.Step()
+}
+
+// catchUp runs the PPU and APU for the CPU cycles they are behind. When timed
+// it adds their times, from mark, to the component timing.
+func (b *Bus) catchUp(timed bool, mark time.Time) {
+	cpuCycles := b.pendingCycles
+	b.pendingCycles = 0
+
+	// PPU runs at exactly 3x CPU speed (cycle-accurate), 3.2x on PAL. The
+	// PPU cycles due are worked out from the CPU cycle count, so PAL's
+	// fractions carry over without any state of their own.
+	timing := b.region.Timing()
+	ppuCyclesToRun := (b.cpuCycles+cpuCycles)*timing.PPUCycles/timing.CPUCycles - b.cpuCycles*timing.PPUCycles/timing.CPUCycles
 	for i := uint64(0); i < ppuCyclesToRun; i++ {
 		b.PPU.Step()
 		b.ppuCycles++
 	}
+	if timed {
+		mark = b.timing.lap(&b.timing.times.PPU, mark)
+	}
 
 	// APU runs at CPU speed
 	for i := uint64(0); i < cpuCycles; i++ {
 		b.APU.Step()
 	}
 
+	// And so do mappers clocked by the CPU
+	if b.mapperClock != nil {
+		for i := uint64(0); i < cpuCycles; i++ {
+			b.mapperClock.ClockCPU()
+		}
+	}
+	if timed {
+		b.timing.lap(&b.timing.times.APU, mark)
+	}
+
 	// Update counters
 	b.cpuCycles += cpuCycles
 	b.totalCycles += cpuCycles
+}
 
-	// Frame completion is now handled by PPU callback for precise timing
+// Sync brings the PPU and APU up to the CPU and ends the batch in progress
+// after the current instruction. Memory calls it before each register
+// access, which may change when the next event is due, and debuggers before
+// they look at the PPU mid-batch.
+func (b *Bus) Sync() {
+	if b.pendingCycles > 0 {
+		b.catchUp(false, time.Time{})
+	}
+	b.batchEnd = true
+}
+
+// RunBatch runs CPU instructions ahead of the PPU and APU, then brings them
+// up to date in one go, which is faster than stepping them after every
+// instruction. The batch ends after the instruction that reaches the CPU
+// cycle count until, the start of vblank or the end of the frame, or that
+// accesses a register, so everything the game and callers can see happens
+// in the same order as with Step. It also ends where a scheduled event is
+// due. Debug logging and component timing make it a single Step, and so do
+// mappers raising IRQs outside scheduled events for now (see
+// cartridge.EventScheduled), which the CPU has to see on the instruction it
+// would without batching.
+func (b *Bus) RunBatch(until uint64) {
+	if b.loggingEnabled || b.watchpointLogging || b.timing != nil || b.mapperIRQUnscheduled() {
+		b.Step()
+		return
+	}
 
-	// Check memory watchpoints for changes (reduced frequency for better performance)
-	if b.watchpointLogging && b.frameCount%300 == 0 { // Check every 5 seconds at 60fps
-		b.CheckMemoryWatchpoints()
+	// Events due now run first, as they may schedule others sooner
+	b.scheduler.RunDue(b.masterClock())
+	budget := b.cyclesToNextEvent()
+	if b.cpuCycles < until && until-b.cpuCycles < budget {
+		budget = until - b.cpuCycles
 	}
 
-	// Log execution if enabled
-	if b.loggingEnabled {
-		event := BusExecutionEvent{
-			StepNumber:    len(b.executionLog) + 1,
-			CPUCycles:     b.cpuCycles,
-			PPUCycles:     b.cpuCycles * 3, // PPU runs at 3x CPU speed
-			FrameCount:    b.frameCount,
-			DMAActive:     b.dmaInProgress,
-			NMIProcessed:  b.frameCount > preFrameCount, // Frame count increased
-			PCValue:       prePC,
-			InstructionOp: preOpcode,
+	b.batchEnd = false
+	for !b.batchEnd && b.pendingCycles < budget {
+		cycles := b.stepCPU()
+		if cycles == 0 {
+			break
 		}
-		b.executionLog = append(b.executionLog, event)
+		b.pendingCycles += cycles
+	}
+	b.catchUp(false, time.Time{})
+}
+
+// EndBatch ends the batch RunBatch is running after the current instruction,
+// for debuggers that stop on a memory access
+func (b *Bus) EndBatch() {
+	b.batchEnd = true
+}
+
+// cyclesToNextEvent returns how many CPU cycles can run before the PPU may
+// reach its next event or a scheduled event is due. It errs a cycle early
+// for the PPU, as PAL's PPU cycles per CPU cycle are not whole.
+func (b *Bus) cyclesToNextEvent() uint64 {
+	timing := b.region.Timing()
+	cycles := uint64(b.PPU.CyclesToNextEvent()) * timing.CPUCycles / timing.PPUCycles
+	if cycles <= 1 {
+		cycles = 1
+	} else {
+		cycles--
+	}
+	if next, ok := b.scheduler.Next(); ok {
+		due := uint64(1)
+		if now := b.masterClock(); next > now {
+			due = (next - now + timing.MasterCycles - 1) / timing.MasterCycles
+		}
+		cycles = min(cycles, due)
+	}
+	return cycles
+}
+
+// masterClock returns the master clock cycle the current instruction
+// started on
+func (b *Bus) masterClock() uint64 {
+	return (b.cpuCycles + b.pendingCycles) * b.region.Timing().MasterCycles
+}
+
+// After schedules fire to run once cpuCycles CPU cycles have passed since
+// the start of the current instruction, and returns a function cancelling
+// it. It is the scheduler mappers get (see cartridge.EventScheduled).
+func (b *Bus) After(cpuCycles uint64, fire func()) (cancel func()) {
+	id := b.scheduler.Schedule(b.masterClock()+cpuCycles*b.region.Timing().MasterCycles, fire)
+	return func() { b.scheduler.Cancel(id) }
+}
+
+// mapperIRQUnscheduled reports whether the mapper has an IRQ that can
+// change outside its scheduled events, which batches would see late
+func (b *Bus) mapperIRQUnscheduled() bool {
+	if b.mapperIRQ == nil {
+		return false
+	}
+	return b.mapperEvents == nil || !b.mapperEvents.IRQScheduled()
+}
+
+// restartEvents drops the scheduled events, whose cycles no longer hold
+// after a reset or state load, and has the mapper schedule its own again
+func (b *Bus) restartEvents() {
+	b.scheduler.Clear()
+	if b.mapperEvents != nil {
+		b.mapperEvents.SetScheduler(b)
 	}
 }
 
@@ -237,58 +458,190 @@ func (b *Bus) TriggerOAMDMA(sourcePage uint8) {
 	}
 }
 
+// dmcRead reads a sample byte for the APU's DMC. The fetch stalls the CPU for
+// 4 cycles, 2 when it lands on an OAM DMA, which it borrows cycles from.
+// Samples lie at $8000-$FFFF, and are read from the cartridge itself: the
+// fetch is not a CPU access, so it leaves the open bus value, the access
+// hooks and Game Genie codes alone.
+func (b *Bus) dmcRead(address uint16) uint8 {
+	if b.dmaInProgress {
+		b.dmcStallCycles += 2
+	} else {
+		b.dmcStallCycles += 4
+	}
+	if b.cartridge == nil {
+		return 0
+	}
+	return b.cartridge.ReadPRG(address)
+}
+
+// controllerRead is called before the CPU reads a controller port. On the
+// NTSC 2A03, a DMC fetch landing on the read makes the CPU repeat it, which
+// clocks the controller's shift register once more: the game loses a bit and
+// reads the next one, which is why some games read the controllers until
+// two reads agree. The PAL 2A07 fixed it, so only NTSC consoles have it.
+func (b *Bus) controllerRead(address uint16) {
+	if !b.dmcConflicts || b.region != region.NTSC {
+		return
+	}
+	// The read is the last cycle of LDA absolute, the usual way of reading
+	// the ports; the APU has been brought up to the start of the instruction
+	if b.APU.DMCFetchDue(dmcConflictWindow) {
+		b.Input.Read(address)
+	}
+}
+
+// dmcConflictWindow is how many CPU cycles into a controller read's
+// instruction a DMC fetch conflicts with it
+const dmcConflictWindow = 4
+
+// SetDMCConflicts sets whether DMC fetches during controller reads delete
+// bits, as on NTSC consoles (the default), or leave them alone, as on PAL
+// consoles and most emulators
+func (b *Bus) SetDMCConflicts(enabled bool) {
+	b.dmcConflicts = enabled
+}
+
 // LoadCartridge loads a cartridge into the system
 func (b *Bus) LoadCartridge(cart memory.CartridgeInterface) {
+	b.cartridge = cart
+
 	// Update memory with cartridge
 	b.Memory = memory.New(b.PPU, b.APU, cart)
 	
 	// Re-establish input system connection
 	b.Memory.SetInputSystem(b.Input)
+	b.Memory.FillRAM(b.ramPattern, b.ramSeed)
 	
 	b.CPU = cpu.New(b.Memory)
 
-	// Create PPU memory with proper mirroring mode
-	// We need to cast to check if the cartridge has mirroring info
-	var mirrorMode memory.MirrorMode
+	// Create PPU memory with the cartridge's mirroring, which its mapper may
+	// switch later
+	mirrorMode := memory.MirrorHorizontal
 	if cartridge, ok := cart.(*cartridge.Cartridge); ok {
-		// Convert cartridge mirror mode to memory mirror mode
-		switch cartridge.GetMirrorMode() {
-		case 0: // MirrorHorizontal
-			mirrorMode = memory.MirrorHorizontal
-		case 1: // MirrorVertical
-			mirrorMode = memory.MirrorVertical
-		case 2: // MirrorSingleScreen0
-			mirrorMode = memory.MirrorSingleScreen0
-		case 3: // MirrorSingleScreen1
-			mirrorMode = memory.MirrorSingleScreen1
-		case 4: // MirrorFourScreen
-			mirrorMode = memory.MirrorFourScreen
-		default:
-			mirrorMode = memory.MirrorHorizontal // Default to horizontal
-		}
-	} else {
-		mirrorMode = memory.MirrorHorizontal // Default to horizontal
+		mirrorMode = memoryMirrorMode(cartridge.GetMirrorMode())
 	}
-
-	// Create and set PPU memory
 	ppuMemory := memory.NewPPUMemory(cart, mirrorMode)
 	b.PPU.SetMemory(ppuMemory)
+	if switcher, ok := cart.(cartridge.MirroringSwitcher); ok {
+		switcher.SetMirroringCallback(func(mode cartridge.MirrorMode) {
+			ppuMemory.SetMirroring(memoryMirrorMode(mode))
+		})
+	}
+	if switcher, ok := cart.(cartridge.NametableSwitcher); ok {
+		switcher.SetNametableCallback(func(pages [4]uint8) {
+			ppuMemory.SetNametablePages(memoryNametablePages(pages))
+		})
+	}
 
 	// Re-establish callbacks after recreating memory and CPU
 	b.PPU.SetNMICallback(b.triggerNMI)
 	b.Memory.SetDMACallback(b.TriggerOAMDMA)
+	b.Memory.SetSyncHook(b.Sync)
+	b.Memory.SetControllerReadHook(b.controllerRead)
+	b.connectMapper(cart)
 
 	// Reset the CPU to properly initialize PC from reset vector
 	b.CPU.Reset()
 }
 
+// memoryMirrorMode converts a cartridge mirroring mode to the PPU memory's
+func memoryMirrorMode(mode cartridge.MirrorMode) memory.MirrorMode {
+	switch mode {
+	case cartridge.MirrorVertical:
+		return memory.MirrorVertical
+	case cartridge.MirrorSingleScreen0:
+		return memory.MirrorSingleScreen0
+	case cartridge.MirrorSingleScreen1:
+		return memory.MirrorSingleScreen1
+	case cartridge.MirrorFourScreen:
+		return memory.MirrorFourScreen
+	default:
+		return memory.MirrorHorizontal
+	}
+}
+
+// memoryNametablePages converts the nametable pages a mapper picks to the PPU
+// memory's
+func memoryNametablePages(pages [4]uint8) [4]uint8 {
+	for i, page := range pages {
+		if page&cartridge.NametablePageCHR != 0 {
+			pages[i] = memory.NametablePageCartridge
+		} else {
+			pages[i] = page & 3
+		}
+	}
+	return pages
+}
+
+// connectMapper sends the cartridge's mapper the signals it takes: CPU
+// cycles, A12 rises and scanline starts, the scheduler, and its IRQ to the
+// CPU. The signals
+// are looked up on the cartridge itself when it is not a Cartridge.
+func (b *Bus) connectMapper(cart memory.CartridgeInterface) {
+	var mapper any = cart
+	if c, ok := cart.(*cartridge.Cartridge); ok {
+		mapper = c.Mapper()
+	}
+
+	b.mapperClock, _ = mapper.(cartridge.CPUClocked)
+	b.mapperIRQ, _ = mapper.(cartridge.IRQSource)
+	b.mapperEvents, _ = mapper.(cartridge.EventScheduled)
+	b.restartEvents()
+
+	b.PPU.SetA12Callback(nil)
+	if watcher, ok := mapper.(cartridge.A12Watcher); ok {
+		b.PPU.SetA12Callback(watcher.A12Rise)
+	}
+	b.PPU.SetScanlineCallback(nil)
+	if watcher, ok := mapper.(cartridge.ScanlineWatcher); ok {
+		b.PPU.SetScanlineCallback(watcher.StartScanline)
+	}
+}
+
+// SetRAMPattern sets what internal RAM holds at power on, from the next
+// LoadCartridge: seed picks the bytes of memory.RAMPatternRandom
+func (b *Bus) SetRAMPattern(pattern memory.RAMPattern, seed int64) {
+	b.ramPattern = pattern
+	b.ramSeed = seed
+}
+
+// SetPPUWarmUp sets whether the PPU ignores writes to $2000, $2001, $2005
+// and $2006 for about a frame after each Reset, as on a console (the
+// default), or takes them right away, for code that does not boot the way a
+// game does
+func (b *Bus) SetPPUWarmUp(enabled bool) {
+	b.ppuWarmUp = enabled
+}
+
+// SetPPUAlignment sets how many dots the PPU starts ahead of the CPU, from
+// the next Reset. Consoles power on with one of several CPU/PPU alignments,
+// and a few games only run with some of them.
+func (b *Bus) SetPPUAlignment(dots int) {
+	b.ppuAlignment = dots
+}
+
+// SetRegion switches the console to a region's timing: the PPU and CPU
+// clocks, scanlines per frame and the APU rates
+func (b *Bus) SetRegion(r region.Region) {
+	b.region = r
+	b.cyclesPerFrame = uint64(r.Timing().Scanlines * 341)
+	b.PPU.SetRegion(r)
+	b.APU.SetRegion(r)
+}
+
+// Region returns the region set with SetRegion, NTSC by default
+func (b *Bus) Region() region.Region {
+	return b.region
+}
+
 // Run runs the emulator for a specified number of frames
 func (b *Bus) Run(frames int) {
 	targetFrames := b.frameCount + uint64(frames)
 
 	// Run until we complete the target number of frames
 	for b.frameCount < targetFrames {
-		b.Step()
+		b.RunBatch(math.MaxUint64)
 	}
 }
 
@@ -297,22 +650,20 @@ func (b *Bus) RunCycles(cycles uint64) {
 	targetCycles := b.cpuCycles + cycles
 
 	for b.cpuCycles < targetCycles {
-		b.Step()
+		b.RunBatch(targetCycles)
 	}
 }
 
-// GetFrameRate returns the current frame rate based on NTSC timing
+// GetFrameRate returns the frame rate of the region's console: 60.0988 for
+// NTSC, 50.007 for PAL and the Dendy
 func (b *Bus) GetFrameRate() float64 {
-	// NTSC: CPU frequency ~1.789773 MHz, 29780.67 CPU cycles per frame
-	cpuFrequency := 1789773.0
-	cpuCyclesPerFrame := cpuFrequency / 60.098803 // NTSC frame rate
-	return cpuFrequency / cpuCyclesPerFrame
+	return b.region.Timing().FrameRate()
 }
 
-// GetFrameBuffer returns the current PPU frame buffer
+// GetFrameBuffer returns the current PPU frame buffer without copying it.
+// The PPU draws the next frame into it as the bus steps on.
 func (b *Bus) GetFrameBuffer() []uint32 {
-	frameBuffer := b.PPU.GetFrameBuffer()
-	return frameBuffer[:]
+	return b.PPU.FrameBuffer()[:]
 }
 
 // GetAudioSamples returns the current audio samples from the APU
@@ -327,7 +678,7 @@ func (b *Bus) SetAudioSampleRate(rate int) {
 
 // GetCycleCount returns the current CPU cycle count
 func (b *Bus) GetCycleCount() uint64 {
-	return b.cpuCycles
+	return b.cpuCycles + b.pendingCycles
 }
 
 // GetFrameCount returns the current frame count
@@ -374,11 +725,27 @@ func (b *Bus) SetControllerButtons(controller int, buttons [8]bool) {
 		// fmt.Printf("[BUS_DEBUG] SetControllerButtons: controller=%d, buttons=[A:%t B:%t Sel:%t Start:%t U:%t D:%t L:%t R:%t]\n", 
 		//	controller, buttons[0], buttons[1], buttons[2], buttons[3], buttons[4], buttons[5], buttons[6], buttons[7])
 		b.Input.SetButtons2(buttons)
+	case 3: // Controller 3 (Four Score)
+		b.Input.SetButtons3(buttons)
+	case 4: // Controller 4 (Four Score)
+		b.Input.SetButtons4(buttons)
 	}
 }
 
+// SetFourScore connects or disconnects the Four Score four-player adapter
+func (b *Bus) SetFourScore(enabled bool) {
+	b.Input.SetFourScore(enabled)
+}
+
+// SetMicrophone sets whether the Famicom microphone on controller 2 hears
+// sound
+func (b *Bus) SetMicrophone(active bool) {
+	b.Input.SetMicrophone(active)
+}
+
 // EnableInputDebug enables debug logging for input system
 func (b *Bus) EnableInputDebug(enable bool) {
+	b.inputDebug = enable
 	b.Input.EnableDebug(enable)
 }
 
@@ -387,13 +754,54 @@ func (b *Bus) GetInputState() *input.InputState {
 	return b.Input
 }
 
+// ConnectZapper plugs a Zapper light gun into port 2 (or restores controller 2)
+func (b *Bus) ConnectZapper(connected bool) {
+	if !connected {
+		if _, ok := b.Input.GetPort2Device().(*input.Zapper); ok {
+			b.Input.SetPort2Device(nil)
+		}
+		return
+	}
+	zapper := b.GetZapper()
+	if zapper == nil {
+		zapper = input.NewZapper()
+		b.Input.SetPort2Device(zapper)
+	}
+	zapper.SetLightSource(b.PPU)
+}
+
+// GetZapper returns the Zapper on port 2, or nil if none is connected
+func (b *Bus) GetZapper() *input.Zapper {
+	zapper, _ := b.Input.GetPort2Device().(*input.Zapper)
+	return zapper
+}
+
+// ConnectArkanoid plugs an Arkanoid paddle into port 2 (or restores controller 2)
+func (b *Bus) ConnectArkanoid(connected bool) {
+	if !connected {
+		if _, ok := b.Input.GetPort2Device().(*input.Arkanoid); ok {
+			b.Input.SetPort2Device(nil)
+		}
+		return
+	}
+	if b.GetArkanoid() == nil {
+		b.Input.SetPort2Device(input.NewArkanoid())
+	}
+}
+
+// GetArkanoid returns the Arkanoid paddle on port 2, or nil if none is connected
+func (b *Bus) GetArkanoid() *input.Arkanoid {
+	paddle, _ := b.Input.GetPort2Device().(*input.Arkanoid)
+	return paddle
+}
+
 // Frame executes one complete frame worth of cycles
 func (b *Bus) Frame() {
-	// NTSC: 29,781 CPU cycles per frame (89,342 PPU cycles / 3)
-	targetCycles := b.cpuCycles + 29781
+	// NTSC: 29,781 CPU cycles per frame (89,342 PPU cycles / 3), PAL 33,248
+	targetCycles := b.cpuCycles + uint64(math.Round(b.region.Timing().CPUCyclesPerFrame()))
 
 	for b.cpuCycles < targetCycles {
-		b.Step()
+		b.RunBatch(targetCycles)
 	}
 }
 
@@ -261,6 +261,7 @@ func TestCPUPPUSyncWithInterrupts(t *testing.T) {
 		cart := cartridge.NewMockCartridge()
 		cart.LoadPRG(romData)
 		bus.LoadCartridge(cart)
+		bus.SetPPUWarmUp(false)
 		bus.Reset()
 		
 		bus.EnableExecutionLogging()
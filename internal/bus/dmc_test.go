@@ -0,0 +1,120 @@
+package bus
+
+import (
+	"testing"
+
+	"gones/internal/cartridge"
+	"gones/internal/region"
+)
+
+// newDMCTestBus returns a bus running a loop counting in $10, after playing a
+// one byte DMC sample from $C000 if dmc is set
+func newDMCTestBus(t *testing.T, dmc bool) *Bus {
+	t.Helper()
+
+	enable := uint8(0x00)
+	if dmc {
+		enable = 0x10
+	}
+	cart, err := cartridge.NewTestROMBuilder().
+		WithPRGSize(1).
+		WithCHRSize(1).
+		WithResetVector(0x8000).
+		WithData(0x0000, []uint8{
+			0xA9, 0x0F, // LDA #$0F
+			0x8D, 0x10, 0x40, // STA $4010 (fastest rate)
+			0xA9, 0x00, // LDA #$00
+			0x8D, 0x12, 0x40, // STA $4012 (sample at $C000)
+			0x8D, 0x13, 0x40, // STA $4013 (1 byte)
+			0xA9, enable, // LDA #enable
+			0x8D, 0x15, 0x40, // STA $4015
+			0xE6, 0x10, // loop: INC $10
+			0x4C, 0x12, 0x80, // JMP loop
+		}).
+		BuildCartridge()
+	if err != nil {
+		t.Fatalf("Failed to create test cartridge: %v", err)
+	}
+
+	bus := New()
+	bus.LoadCartridge(cart)
+	bus.Reset()
+	return bus
+}
+
+func TestDMCFetch_ReadsSampleAndStallsCPU(t *testing.T) {
+	cycles := func(dmc bool) (uint64, []uint16) {
+		bus := newDMCTestBus(t, dmc)
+		var seen []uint16
+		bus.Memory.SetAccessHook(func(address uint16, value uint8, write bool) {
+			if address >= 0xC000 && !write {
+				seen = append(seen, address)
+			}
+		})
+		bus.Memory.SetPRGReadHook(func(address uint16, value uint8) uint8 {
+			if address >= 0xC000 {
+				seen = append(seen, address)
+			}
+			return value
+		})
+		for bus.Memory.Read(0x10) < 20 {
+			bus.Step()
+		}
+		return bus.GetCycleCount(), seen
+	}
+
+	// The fetch is not a CPU access: the trace and Game Genie hooks don't
+	// see it
+	plain, _ := cycles(false)
+	played, seen := cycles(true)
+	if len(seen) != 0 {
+		t.Errorf("hooks saw the DMC fetch from %04X", seen)
+	}
+	if played != plain+4 {
+		t.Errorf("20 loops took %d cycles with a DMC fetch, want %d + 4", played, plain)
+	}
+
+	// The sample is $A9 from the cartridge, the LDA at $8000 mirrored at
+	// $C000: its bits step the output up and down to 2
+	bus := newDMCTestBus(t, true)
+	for bus.Memory.Read(0x10) < 200 {
+		bus.Step()
+	}
+	if level := bus.APU.GetChannelOutput(4); level != 2 {
+		t.Errorf("DMC output %d after playing the sample, want 2", level)
+	}
+}
+
+func TestDMCConflict_DeletesControllerBit(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		region    region.Region
+		conflicts bool
+		want      uint8
+	}{
+		{"NTSC", region.NTSC, true, 1},
+		{"NTSC without conflicts", region.NTSC, false, 0},
+		{"PAL", region.PAL, true, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bus := newDMCTestBus(t, false)
+			bus.SetRegion(tc.region)
+			bus.SetDMCConflicts(tc.conflicts)
+			bus.SetControllerButtons(1, [8]bool{false, true}) // B only
+
+			bus.Memory.Write(0x4016, 1)
+			bus.Memory.Write(0x4016, 0)
+			// A sample byte is due as soon as the DMC starts
+			bus.Memory.Write(0x4013, 0)
+			bus.Memory.Write(0x4015, 0x10)
+			if !bus.APU.DMCFetchDue(dmcConflictWindow) {
+				t.Fatal("no DMC fetch due after starting it")
+			}
+
+			// The conflict skips A and reads B
+			if got := bus.Memory.Read(0x4016) & 1; got != tc.want {
+				t.Errorf("first read = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
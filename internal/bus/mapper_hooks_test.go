@@ -0,0 +1,435 @@
+package bus
+
+import (
+	"reflect"
+	"testing"
+
+	"gones/internal/cartridge"
+	"gones/internal/memory"
+)
+
+// counterCartridge is a cartridge taking every mapper signal, with an IRQ
+// raised on every tenth A12 rise and acknowledged by writing $E000
+type counterCartridge struct {
+	memory.CartridgeInterface
+
+	clocks    uint64
+	scanlines int
+	frames    int
+	a12Rises  int
+	irq       bool
+	acks      int
+}
+
+func (c *counterCartridge) ClockCPU() { c.clocks++ }
+
+func (c *counterCartridge) StartScanline(scanline int, rendering bool) {
+	c.scanlines++
+	if scanline == -1 {
+		c.frames++
+	}
+}
+
+func (c *counterCartridge) A12Rise() {
+	c.a12Rises++
+	if c.a12Rises%10 == 0 {
+		c.irq = true
+	}
+}
+
+func (c *counterCartridge) IRQ() bool { return c.irq }
+
+func (c *counterCartridge) WritePRG(address uint16, value uint8) {
+	if address == 0xE000 {
+		c.irq = false
+		c.acks++
+		return
+	}
+	c.CartridgeInterface.WritePRG(address, value)
+}
+
+func TestMapperSignals(t *testing.T) {
+	rom, err := cartridge.NewTestROMBuilder().
+		WithPRGSize(1).
+		WithCHRSize(1).
+		WithResetVector(0x8000).
+		WithIRQVector(0x8020).
+		WithData(0x0000, []uint8{
+			0xA9, 0x08, // LDA #$08
+			0x8D, 0x00, 0x20, // STA $2000 (sprites at $1000)
+			0xA9, 0x18, // LDA #$18
+			0x8D, 0x01, 0x20, // STA $2001 (rendering on)
+			0x58,       // CLI
+			0xE6, 0x10, // loop: INC $10
+			0x4C, 0x0B, 0x80, // JMP loop
+		}).
+		WithData(0x0020, []uint8{
+			0xE6, 0x11, // INC $11
+			0x8D, 0x00, 0xE0, // STA $E000 (acknowledge)
+			0x40, // RTI
+		}).
+		BuildCartridge()
+	if err != nil {
+		t.Fatalf("Failed to create test cartridge: %v", err)
+	}
+	cart := &counterCartridge{CartridgeInterface: rom}
+
+	bus := New()
+	bus.LoadCartridge(cart)
+	bus.SetPPUWarmUp(false)
+	bus.Reset()
+	bus.Run(1)
+
+	*cart = counterCartridge{CartridgeInterface: rom, irq: cart.irq}
+	start, handledBefore := bus.GetCycleCount(), bus.Memory.Read(0x0011)
+	bus.Run(2)
+
+	if cycles := bus.GetCycleCount() - start; cart.clocks != cycles {
+		t.Errorf("mapper clocked %d times in %d CPU cycles", cart.clocks, cycles)
+	}
+	if cart.frames != 2 || cart.scanlines != 2*262 {
+		t.Errorf("mapper saw %d scanlines in %d frames, want %d in 2", cart.scanlines, cart.frames, 2*262)
+	}
+	// Every rendered line, the pre-render one included, fetches sprites from $1000
+	if cart.a12Rises != 2*241 {
+		t.Errorf("A12 rose %d times in 2 frames, want %d", cart.a12Rises, 2*241)
+	}
+	// The last IRQ may still be waiting for the end of an instruction
+	raised := cart.a12Rises / 10
+	if handled := int(bus.Memory.Read(0x0011) - handledBefore); cart.acks < raised-1 || cart.acks > raised || handled != cart.acks {
+		t.Errorf("%d IRQs raised, %d acknowledged and %d handled", raised, cart.acks, handled)
+	}
+}
+
+func TestMapperMirroring(t *testing.T) {
+	bus := newStateTestBus(t)
+	cart := bus.cartridge.(*cartridge.Cartridge)
+	vram := bus.PPU.GetMemory()
+
+	vram.Write(0x2000, 0xAA)
+	if got := vram.Read(0x2400); got != 0xAA {
+		t.Fatalf("Horizontal mirroring: $2400 = $%02X, want $AA", got)
+	}
+
+	cart.SetMirroring(cartridge.MirrorVertical)
+	if vram.Mirroring() != memory.MirrorVertical {
+		t.Fatalf("PPU mirroring %d after the mapper switched to vertical", vram.Mirroring())
+	}
+	if got := vram.Read(0x2800); got != 0xAA {
+		t.Errorf("Vertical mirroring: $2800 = $%02X, want $AA", got)
+	}
+	if got := vram.Read(0x2400); got == 0xAA {
+		t.Error("Vertical mirroring: $2400 still mirrors $2000")
+	}
+
+	cart.SetMirroring(cartridge.MirrorSingleScreen1)
+	vram.Write(0x2C00, 0x55)
+	if got := vram.Read(0x2000); got != 0x55 {
+		t.Errorf("Single screen B: $2000 = $%02X, want $55", got)
+	}
+
+	// The mode is saved with the state
+	data, err := bus.SaveStateToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cart.SetMirroring(cartridge.MirrorHorizontal)
+	if err := bus.LoadStateFromBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	if vram := bus.PPU.GetMemory(); vram.Mirroring() != memory.MirrorSingleScreen1 || cart.GetMirrorMode() != cartridge.MirrorSingleScreen1 {
+		t.Errorf("Mirroring after loading the state: PPU %d, cartridge %d", vram.Mirroring(), cart.GetMirrorMode())
+	}
+}
+
+func TestFourScreenMirroring(t *testing.T) {
+	cart, err := cartridge.NewTestROMBuilder().
+		WithPRGSize(1).
+		WithCHRSize(1).
+		WithMirroring(cartridge.MirrorFourScreen).
+		WithResetVector(0x8000).
+		WithData(0x0000, []uint8{0x4C, 0x00, 0x80}). // JMP $8000
+		BuildCartridge()
+	if err != nil {
+		t.Fatalf("Failed to create test cartridge: %v", err)
+	}
+	bus := New()
+	bus.LoadCartridge(cart)
+	bus.Reset()
+	vram := bus.PPU.GetMemory()
+
+	for i, address := range []uint16{0x2000, 0x2400, 0x2800, 0x2C00} {
+		vram.Write(address+0x21, uint8(0x10+i))
+	}
+	for i, address := range []uint16{0x2000, 0x2400, 0x2800, 0x2C00} {
+		if got := vram.Read(address + 0x21); got != uint8(0x10+i) {
+			t.Errorf("Nametable %d holds $%02X, want $%02X", i, got, 0x10+i)
+		}
+		if got := vram.Read(address + 0x1021); got != uint8(0x10+i) {
+			t.Errorf("Mirror of nametable %d at $%04X holds $%02X", i, address+0x1000, got)
+		}
+	}
+
+	// The cartridge's VRAM is wired in place of the mirroring the mapper controls
+	cart.SetMirroring(cartridge.MirrorVertical)
+	if vram.Mirroring() != memory.MirrorFourScreen || vram.Read(0x2821) != 0x12 {
+		t.Errorf("Mapper switched a four-screen cartridge to mirroring %d", vram.Mirroring())
+	}
+}
+
+func TestMapperNametablePages(t *testing.T) {
+	cart, err := cartridge.NewTestROMBuilder().
+		WithMapper(118).
+		WithPRGSize(2).
+		WithCHRSize(16).
+		WithResetVector(0xC000).
+		WithData(0x4000, []uint8{0x4C, 0x00, 0xC0}). // JMP $C000
+		BuildCartridge()
+	if err != nil {
+		t.Fatalf("Failed to create test cartridge: %v", err)
+	}
+	bus := New()
+	bus.LoadCartridge(cart)
+	bus.Reset()
+	vram := bus.PPU.GetMemory()
+
+	// TxSROM powers on with every nametable on page 0
+	if vram.Mirroring() != memory.MirrorMapped {
+		t.Fatalf("PPU mirroring %d for a TxSROM board, want mapped", vram.Mirroring())
+	}
+	vram.Write(0x2000, 0xAA)
+	if got := vram.Read(0x2C00); got != 0xAA {
+		t.Errorf("Nametable 3 holds $%02X at power on, want page 0's $AA", got)
+	}
+
+	// R1 ($8001 after selecting it) puts nametables 2-3 on page 1
+	bus.Memory.Write(0x8000, 0x01)
+	bus.Memory.Write(0x8001, 0x80)
+	vram.Write(0x2800, 0x55)
+	if got := vram.Read(0x2000); got != 0xAA {
+		t.Errorf("Nametable 0 holds $%02X, want $AA", got)
+	}
+	if got := vram.Read(0x2C00); got != 0x55 {
+		t.Errorf("Nametable 3 holds $%02X, want page 1's $55", got)
+	}
+
+	// The pages are saved with the state
+	data, err := bus.SaveStateToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.Memory.Write(0x8001, 0x00)
+	if err := bus.LoadStateFromBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := bus.PPU.GetMemory().Read(0x2C00); got != 0x55 {
+		t.Errorf("Nametable 3 holds $%02X after loading the state, want $55", got)
+	}
+}
+
+// timerCartridge raises its IRQ 1000 CPU cycles after each write to $E000,
+// which also acknowledges the last one, through scheduled events
+type timerCartridge struct {
+	memory.CartridgeInterface
+
+	bus       *Bus
+	scheduler cartridge.Scheduler
+	cancel    func()
+	irq       bool
+	fired     []uint64 // CPU cycles the IRQ was raised on
+	restarts  int
+}
+
+func (c *timerCartridge) SetScheduler(s cartridge.Scheduler) {
+	c.scheduler = s
+	c.cancel = nil
+	c.restarts++
+}
+
+func (c *timerCartridge) IRQScheduled() bool { return true }
+
+func (c *timerCartridge) IRQ() bool { return c.irq }
+
+func (c *timerCartridge) WritePRG(address uint16, value uint8) {
+	if address != 0xE000 {
+		c.CartridgeInterface.WritePRG(address, value)
+		return
+	}
+	c.irq = false
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.cancel = c.scheduler.After(1000, func() {
+		c.irq = true
+		c.fired = append(c.fired, c.bus.GetCycleCount())
+	})
+}
+
+func TestScheduledMapperIRQ(t *testing.T) {
+	run := func(batched bool) *timerCartridge {
+		rom, err := cartridge.NewTestROMBuilder().
+			WithPRGSize(1).
+			WithCHRSize(1).
+			WithResetVector(0x8000).
+			WithIRQVector(0x8020).
+			WithData(0x0000, []uint8{
+				0x8D, 0x00, 0xE0, // STA $E000 (start the timer)
+				0x58,       // CLI
+				0xE6, 0x10, // loop: INC $10
+				0x4C, 0x04, 0x80, // JMP loop
+			}).
+			WithData(0x0020, []uint8{
+				0xE6, 0x11, // INC $11
+				0x8D, 0x00, 0xE0, // STA $E000 (acknowledge and restart)
+				0x40, // RTI
+			}).
+			BuildCartridge()
+		if err != nil {
+			t.Fatalf("Failed to create test cartridge: %v", err)
+		}
+		bus := New()
+		cart := &timerCartridge{CartridgeInterface: rom, bus: bus}
+		bus.LoadCartridge(cart)
+		bus.Reset()
+		if !batched {
+			bus.EnableExecutionLogging() // Steps one instruction at a time
+		}
+		bus.Run(2)
+
+		if handled := int(bus.Memory.Read(0x0011)); handled < len(cart.fired)-1 || handled > len(cart.fired) {
+			t.Errorf("%d IRQs raised and %d handled", len(cart.fired), handled)
+		}
+		return cart
+	}
+
+	stepped, batched := run(false), run(true)
+	if len(batched.fired) < 50 {
+		t.Fatalf("Only %d IRQs in 2 frames", len(batched.fired))
+	}
+	// Every IRQ is raised on the cycle it would be without batching
+	if !reflect.DeepEqual(stepped.fired, batched.fired) {
+		t.Errorf("IRQ cycles differ when batched:\n%v\n%v", stepped.fired, batched.fired)
+	}
+	for i := 1; i < len(batched.fired); i++ {
+		if gap := batched.fired[i] - batched.fired[i-1]; gap < 1000 || gap > 1030 {
+			t.Fatalf("IRQ %d raised %d cycles after the last", i, gap)
+		}
+	}
+	// Resetting drops the events for the mapper to schedule again
+	if batched.restarts != 2 {
+		t.Errorf("Mapper given the scheduler %d times, want 2 (insert and reset)", batched.restarts)
+	}
+}
+
+func TestJYCounterIRQBatched(t *testing.T) {
+	run := func(batched bool) (handled uint8, table []uint8) {
+		cart, err := cartridge.NewTestROMBuilder().
+			WithMapper(90).
+			WithPRGSize(2).
+			WithCHRSize(1).
+			WithResetVector(0x8000).
+			WithIRQVector(0x8020).
+			WithData(0x0000, []uint8{
+				0xA9, 0x44, // LDA #$44 (count CPU cycles up, 3-bit prescaler)
+				0x8D, 0x01, 0xC0, // STA $C001
+				0xA9, 0x00, // LDA #0
+				0x8D, 0x04, 0xC0, // STA $C004
+				0xA9, 0xC0, // LDA #$C0
+				0x8D, 0x05, 0xC0, // STA $C005
+				0x8D, 0x03, 0xC0, // STA $C003 (enable)
+				0x58,       // CLI
+				0xE6, 0x10, // loop: INC $10
+				0x4C, 0x13, 0x80, // JMP loop
+			}).
+			WithData(0x0020, []uint8{
+				0xA6, 0x11, // LDX $11
+				0xA5, 0x10, // LDA $10 (where the loop was)
+				0x9D, 0x00, 0x02, // STA $0200,X
+				0xE6, 0x11, // INC $11
+				0xA9, 0xC0, // LDA #$C0
+				0x8D, 0x05, 0xC0, // STA $C005
+				0x8D, 0x02, 0xC0, // STA $C002 (acknowledge)
+				0x8D, 0x03, 0xC0, // STA $C003
+				0x40, // RTI
+			}).
+			BuildCartridge()
+		if err != nil {
+			t.Fatalf("Failed to create test cartridge: %v", err)
+		}
+		bus := New()
+		bus.LoadCartridge(cart)
+		bus.Reset()
+		if !batched {
+			bus.EnableExecutionLogging() // Steps one instruction at a time
+		}
+		bus.Run(2)
+		table = make([]uint8, 0x100)
+		for i := range table {
+			table[i] = bus.Memory.Read(0x0200 + uint16(i))
+		}
+		handled = bus.Memory.Read(0x0011)
+
+		// In the loop, batches run several instructions at a time
+		if batched {
+			longest := uint64(0)
+			for i := 0; i < 20; i++ {
+				start := bus.GetCycleCount()
+				bus.RunBatch(start + 100)
+				longest = max(longest, bus.GetCycleCount()-start)
+			}
+			if longest < 50 {
+				t.Errorf("Longest batch ran %d cycles with the counter counting CPU cycles", longest)
+			}
+		}
+		return handled, table
+	}
+
+	steppedIRQs, stepped := run(false)
+	batchedIRQs, batched := run(true)
+	if steppedIRQs < 50 {
+		t.Fatalf("Only %d IRQs in 2 frames", steppedIRQs)
+	}
+	// Every IRQ interrupts the loop where it would without batching
+	if batchedIRQs != steppedIRQs || !reflect.DeepEqual(stepped, batched) {
+		t.Errorf("%d IRQs batched, %d stepped, interrupting the loop at:\n%v\n%v", batchedIRQs, steppedIRQs, batched, stepped)
+	}
+}
+
+func TestMapperCHRNametables(t *testing.T) {
+	chr := make([]uint8, 0x2000)
+	chr[0x0C10] = 0x77 // Bank 3
+	cart, err := cartridge.NewTestROMBuilder().
+		WithMapper(211).
+		WithPRGSize(2).
+		WithCHRSize(1).
+		WithCHRData(chr).
+		WithResetVector(0xC000).
+		WithData(0x4000, []uint8{0x4C, 0x00, 0xC0}). // JMP $C000
+		BuildCartridge()
+	if err != nil {
+		t.Fatalf("Failed to create test cartridge: %v", err)
+	}
+	bus := New()
+	bus.LoadCartridge(cart)
+	bus.Reset()
+	vram := bus.PPU.GetMemory()
+
+	// Bit 7 of $B000 puts nametable 0 on CHR ROM bank 3, which ignores writes
+	bus.Memory.Write(0xB000, 0x83)
+	bus.Memory.Write(0xB001, 0x00)
+	vram.Write(0x2410, 0x11)
+	vram.Write(0x2010, 0x22)
+	if got := vram.Read(0x2010); got != 0x77 {
+		t.Errorf("Nametable 0 reads $%02X, want CHR ROM's $77", got)
+	}
+	if got := vram.Read(0x2410); got != 0x11 {
+		t.Errorf("Nametable 1 reads $%02X, want VRAM's $11", got)
+	}
+
+	// The multiplier is in the expansion area
+	bus.Memory.Write(0x5800, 12)
+	bus.Memory.Write(0x5801, 11)
+	if got := bus.Memory.Read(0x5800); got != 132 {
+		t.Errorf("$5800 reads %d after 12 x 11, want 132", got)
+	}
+}
@@ -0,0 +1,55 @@
+package bus
+
+import (
+	"math"
+	"testing"
+
+	"gones/internal/region"
+)
+
+func TestSetRegion_FrameLength(t *testing.T) {
+	for _, r := range []region.Region{region.NTSC, region.PAL, region.Dendy} {
+		t.Run(r.String(), func(t *testing.T) {
+			bus := newStateTestBus(t)
+			bus.SetRegion(r)
+			bus.Run(1)
+
+			const frames = 10
+			start := bus.GetCycleCount()
+			bus.Run(frames)
+			got := float64(bus.GetCycleCount()-start) / frames
+			if want := r.Timing().CPUCyclesPerFrame(); math.Abs(got-want) > 1 {
+				t.Errorf("%.1f CPU cycles per frame, want %.1f", got, want)
+			}
+		})
+	}
+}
+
+func TestSetRegion_VBlankScanline(t *testing.T) {
+	for r, want := range map[region.Region]int{region.NTSC: 241, region.PAL: 241, region.Dendy: 291} {
+		bus := newStateTestBus(t)
+		bus.SetRegion(r)
+		bus.Run(1)
+		for !bus.PPU.IsVBlank() {
+			bus.Step()
+		}
+		if got := bus.PPU.GetScanline(); got != want {
+			t.Errorf("%v vblank started on scanline %d, want %d", r, got, want)
+		}
+	}
+}
+
+func TestSetPPUAlignment(t *testing.T) {
+	bus := newStateTestBus(t)
+	bus.RunCycles(1000)
+	aligned := bus.PPU.GetCycleCount()
+
+	for dots := uint64(1); dots <= 2; dots++ {
+		bus.SetPPUAlignment(int(dots))
+		bus.Reset()
+		bus.RunCycles(1000)
+		if got := bus.PPU.GetCycleCount(); got != aligned+dots {
+			t.Errorf("PPU at dot %d after 1000 CPU cycles with alignment %d, want %d", got, dots, aligned+dots)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package bus provides the event scheduler, which runs callbacks when the
+// master clock reaches a cycle, so that mappers and other components with
+// timed side effects need no check on every cycle.
+package bus
+
+import "container/heap"
+
+// EventID identifies a scheduled event, to cancel it
+type EventID uint64
+
+// event is a callback due at a master clock cycle
+type event struct {
+	cycle uint64
+	id    EventID // Orders events due on the same cycle, first scheduled first
+	fire  func()
+}
+
+// eventQueue is a min-heap of events by cycle, then id
+type eventQueue []event
+
+func (q eventQueue) Len() int { return len(q) }
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].cycle != q[j].cycle {
+		return q[i].cycle < q[j].cycle
+	}
+	return q[i].id < q[j].id
+}
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *eventQueue) Push(x any)   { *q = append(*q, x.(event)) }
+func (q *eventQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// Scheduler is a priority queue of events keyed by master clock cycle. The
+// zero value is empty and ready to use. Events are not part of save states:
+// their owners schedule them again from their own state after a load.
+type Scheduler struct {
+	queue  eventQueue
+	lastID EventID
+}
+
+// Schedule queues fire to run once the clock reaches cycle and returns the
+// event's ID. Events due on the same cycle run in the order scheduled.
+func (s *Scheduler) Schedule(cycle uint64, fire func()) EventID {
+	s.lastID++
+	heap.Push(&s.queue, event{cycle: cycle, id: s.lastID, fire: fire})
+	return s.lastID
+}
+
+// Cancel removes an event that has not run yet, reporting whether it found it
+func (s *Scheduler) Cancel(id EventID) bool {
+	for i, e := range s.queue {
+		if e.id == id {
+			heap.Remove(&s.queue, i)
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the cycle of the earliest event, or false when none is queued
+func (s *Scheduler) Next() (uint64, bool) {
+	if len(s.queue) == 0 {
+		return 0, false
+	}
+	return s.queue[0].cycle, true
+}
+
+// RunDue runs the events due by cycle in order, including those they
+// schedule that are due too, and returns how many ran
+func (s *Scheduler) RunDue(cycle uint64) int {
+	ran := 0
+	for len(s.queue) > 0 && s.queue[0].cycle <= cycle {
+		e := heap.Pop(&s.queue).(event)
+		e.fire()
+		ran++
+	}
+	return ran
+}
+
+// Len returns the number of events queued
+func (s *Scheduler) Len() int {
+	return len(s.queue)
+}
+
+// Clear removes every event
+func (s *Scheduler) Clear() {
+	s.queue = nil
+}
@@ -0,0 +1,51 @@
+package bus
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScheduler(t *testing.T) {
+	var s Scheduler
+	var ran []string
+	add := func(cycle uint64, name string) EventID {
+		return s.Schedule(cycle, func() { ran = append(ran, name) })
+	}
+
+	add(30, "c")
+	add(10, "a")
+	add(20, "b1")
+	add(20, "b2")
+	dropped := add(15, "dropped")
+	s.Schedule(25, func() {
+		ran = append(ran, "chain")
+		add(26, "chained") // Due by the end of the run below
+		add(40, "later")
+	})
+
+	if next, ok := s.Next(); !ok || next != 10 {
+		t.Errorf("Next() = %d, %v, want 10", next, ok)
+	}
+	if !s.Cancel(dropped) || s.Cancel(dropped) {
+		t.Error("Cancel did not remove the event exactly once")
+	}
+
+	if n := s.RunDue(9); n != 0 {
+		t.Errorf("%d events ran before any was due", n)
+	}
+	if n := s.RunDue(30); n != 6 {
+		t.Errorf("RunDue(30) ran %d events, want 6", n)
+	}
+	want := []string{"a", "b1", "b2", "chain", "chained", "c"}
+	if !reflect.DeepEqual(ran, want) {
+		t.Errorf("Events ran in order %v, want %v", ran, want)
+	}
+	if next, ok := s.Next(); !ok || next != 40 || s.Len() != 1 {
+		t.Errorf("Next() = %d, %v with %d queued, want 40 with 1", next, ok, s.Len())
+	}
+
+	s.Clear()
+	if _, ok := s.Next(); ok || s.Len() != 0 {
+		t.Error("Events left after Clear")
+	}
+}
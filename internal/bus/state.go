@@ -0,0 +1,139 @@
+// Package bus implements in-memory save states for the whole system.
+package bus
+
+import (
+	"fmt"
+
+	"gones/internal/savestate"
+)
+
+const (
+	// stateMagic identifies a bus state payload
+	stateMagic = "GNST"
+	// stateVersion is bumped whenever the payload layout changes
+	stateVersion = 8
+)
+
+// SaveStateToBytes snapshots the complete machine state (CPU, PPU, APU, RAM,
+// controllers and cartridge RAM/mapper registers) without touching the filesystem.
+// The result can be passed to LoadStateFromBytes on a bus with the same ROM loaded.
+func (b *Bus) SaveStateToBytes() ([]byte, error) {
+	w := savestate.NewWriter(256 * 1024)
+
+	w.WriteTag(stateMagic)
+	w.WriteU16(stateVersion)
+
+	// Bus timing state
+	w.WriteU64(b.totalCycles)
+	w.WriteU64(b.cpuCycles)
+	w.WriteU64(b.ppuCycles)
+	w.WriteU64(b.frameCount)
+	w.WriteU64(b.dmaSuspendCycles)
+	w.WriteBool(b.dmaInProgress)
+	w.WriteU64(b.dmcStallCycles)
+	w.WriteBool(b.nmiPending)
+	w.WriteBool(b.oddFrame)
+
+	b.CPU.SaveState(w)
+	b.PPU.SaveState(w)
+	b.APU.SaveState(w)
+	b.Memory.SaveState(w)
+	b.Input.SaveState(w)
+
+	// Cartridge RAM and mapper registers
+	stateful, ok := b.cartridge.(savestate.Stateful)
+	w.WriteBool(ok)
+	if ok {
+		stateful.SaveState(w)
+	}
+
+	return w.Bytes(), nil
+}
+
+// LoadStateFromBytes restores a snapshot created by SaveStateToBytes.
+// If the payload is invalid the previous machine state is left intact.
+func (b *Bus) LoadStateFromBytes(data []byte) error {
+	r := savestate.NewReader(data)
+
+	r.ExpectTag(stateMagic)
+	if r.Err() != nil {
+		return fmt.Errorf("not a gones state: %v", r.Err())
+	}
+	if version := r.ReadU16(); version != stateVersion {
+		return fmt.Errorf("unsupported state version %d (expected %d)", version, stateVersion)
+	}
+
+	// Keep a backup so a corrupt payload can't leave the machine half restored
+	backup, err := b.SaveStateToBytes()
+	if err != nil {
+		return fmt.Errorf("failed to back up current state: %v", err)
+	}
+
+	if err := b.applyState(r); err != nil {
+		if restoreErr := b.applyState(skipHeader(backup)); restoreErr != nil {
+			return fmt.Errorf("failed to load state: %v (and failed to restore previous state: %v)", err, restoreErr)
+		}
+		return fmt.Errorf("failed to load state: %v", err)
+	}
+
+	return nil
+}
+
+// skipHeader returns a reader positioned after the magic and version fields
+func skipHeader(data []byte) *savestate.Reader {
+	r := savestate.NewReader(data)
+	r.ExpectTag(stateMagic)
+	r.ReadU16()
+	return r
+}
+
+// applyState reads component state in the order written by SaveStateToBytes
+func (b *Bus) applyState(r *savestate.Reader) error {
+	b.totalCycles = r.ReadU64()
+	b.cpuCycles = r.ReadU64()
+	b.ppuCycles = r.ReadU64()
+	b.frameCount = r.ReadU64()
+	b.dmaSuspendCycles = r.ReadU64()
+	b.dmaInProgress = r.ReadBool()
+	b.dmcStallCycles = r.ReadU64()
+	b.nmiPending = r.ReadBool()
+	b.oddFrame = r.ReadBool()
+
+	if err := b.CPU.LoadState(r); err != nil {
+		return fmt.Errorf("CPU: %v", err)
+	}
+	if err := b.PPU.LoadState(r); err != nil {
+		return fmt.Errorf("PPU: %v", err)
+	}
+	if err := b.APU.LoadState(r); err != nil {
+		return fmt.Errorf("APU: %v", err)
+	}
+	if err := b.Memory.LoadState(r); err != nil {
+		return fmt.Errorf("RAM: %v", err)
+	}
+	if err := b.Input.LoadState(r); err != nil {
+		return fmt.Errorf("input: %v", err)
+	}
+
+	hasCartridgeState := r.ReadBool()
+	stateful, ok := b.cartridge.(savestate.Stateful)
+	if hasCartridgeState != ok {
+		return fmt.Errorf("cartridge state mismatch")
+	}
+	if ok {
+		if err := stateful.LoadState(r); err != nil {
+			return fmt.Errorf("cartridge: %v", err)
+		}
+	}
+
+	if r.Remaining() != 0 {
+		return fmt.Errorf("%d unexpected trailing bytes", r.Remaining())
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	// The mapper schedules its events again from its loaded registers
+	b.restartEvents()
+	return nil
+}
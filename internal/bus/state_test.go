@@ -0,0 +1,115 @@
+package bus
+
+import (
+	"testing"
+
+	"gones/internal/cartridge"
+)
+
+// newStateTestBus creates a bus running a small loop that keeps changing RAM and SRAM
+func newStateTestBus(t *testing.T) *Bus {
+	t.Helper()
+
+	cart, err := cartridge.NewTestROMBuilder().
+		WithPRGSize(1).
+		WithCHRSize(1).
+		WithResetVector(0x8000).
+		WithData(0x0000, []uint8{
+			0xE6, 0x10, // INC $10
+			0xEE, 0x00, 0x60, // INC $6000
+			0x4C, 0x00, 0x80, // JMP $8000
+		}).
+		BuildCartridge()
+	if err != nil {
+		t.Fatalf("Failed to create test cartridge: %v", err)
+	}
+
+	bus := New()
+	bus.LoadCartridge(cart)
+	bus.Reset()
+	return bus
+}
+
+func TestSaveStateToBytes_RoundTripIsDeterministic(t *testing.T) {
+	bus := newStateTestBus(t)
+	bus.Run(3)
+
+	snapshot, err := bus.SaveStateToBytes()
+	if err != nil {
+		t.Fatalf("SaveStateToBytes failed: %v", err)
+	}
+
+	bus.Run(2)
+	expectedPC := bus.CPU.PC
+	expectedCycles := bus.GetCycleCount()
+	expectedRAM := bus.Memory.Read(0x0010)
+	expectedSRAM := bus.Memory.Read(0x6000)
+
+	if err := bus.LoadStateFromBytes(snapshot); err != nil {
+		t.Fatalf("LoadStateFromBytes failed: %v", err)
+	}
+	bus.Run(2)
+
+	if bus.CPU.PC != expectedPC {
+		t.Errorf("PC = 0x%04X, want 0x%04X", bus.CPU.PC, expectedPC)
+	}
+	if bus.GetCycleCount() != expectedCycles {
+		t.Errorf("Cycle count = %d, want %d", bus.GetCycleCount(), expectedCycles)
+	}
+	if got := bus.Memory.Read(0x0010); got != expectedRAM {
+		t.Errorf("RAM $10 = 0x%02X, want 0x%02X", got, expectedRAM)
+	}
+	if got := bus.Memory.Read(0x6000); got != expectedSRAM {
+		t.Errorf("SRAM $6000 = 0x%02X, want 0x%02X", got, expectedSRAM)
+	}
+}
+
+func TestLoadStateFromBytes_RestoresIntoFreshBus(t *testing.T) {
+	source := newStateTestBus(t)
+	source.Run(2)
+	source.Memory.Write(0x0123, 0xAB)
+
+	snapshot, err := source.SaveStateToBytes()
+	if err != nil {
+		t.Fatalf("SaveStateToBytes failed: %v", err)
+	}
+
+	target := newStateTestBus(t)
+	if err := target.LoadStateFromBytes(snapshot); err != nil {
+		t.Fatalf("LoadStateFromBytes failed: %v", err)
+	}
+
+	if target.Memory.Read(0x0123) != 0xAB {
+		t.Errorf("RAM $0123 = 0x%02X, want 0xAB", target.Memory.Read(0x0123))
+	}
+	if target.GetFrameCount() != source.GetFrameCount() {
+		t.Errorf("Frame count = %d, want %d", target.GetFrameCount(), source.GetFrameCount())
+	}
+	if target.CPU.PC != source.CPU.PC {
+		t.Errorf("PC = 0x%04X, want 0x%04X", target.CPU.PC, source.CPU.PC)
+	}
+}
+
+func TestLoadStateFromBytes_RejectsInvalidData(t *testing.T) {
+	bus := newStateTestBus(t)
+	bus.Run(1)
+	bus.Memory.Write(0x0200, 0x77)
+
+	snapshot, err := bus.SaveStateToBytes()
+	if err != nil {
+		t.Fatalf("SaveStateToBytes failed: %v", err)
+	}
+
+	if err := bus.LoadStateFromBytes([]byte("not a state")); err == nil {
+		t.Error("Expected error for invalid magic")
+	}
+
+	// Truncated payload must fail and leave the current state untouched
+	bus.Memory.Write(0x0200, 0x99)
+	if err := bus.LoadStateFromBytes(snapshot[:len(snapshot)/2]); err == nil {
+		t.Error("Expected error for truncated state")
+	}
+	if bus.Memory.Read(0x0200) != 0x99 {
+		t.Errorf("RAM $0200 = 0x%02X after failed load, want 0x99", bus.Memory.Read(0x0200))
+	}
+}
@@ -0,0 +1,72 @@
+// Package bus provides sampled timing of the CPU, PPU and APU, for
+// benchmarks that break down where emulation time goes.
+package bus
+
+import "time"
+
+// ComponentTimes is the time spent in each component
+type ComponentTimes struct {
+	CPU   time.Duration
+	PPU   time.Duration
+	APU   time.Duration
+	Steps uint64 // Steps timed
+}
+
+// componentTiming times one in every sample steps
+type componentTiming struct {
+	sample   int
+	tick     int
+	overhead time.Duration // Cost of reading the clock, taken off each lap
+	times    ComponentTimes
+}
+
+// EnableComponentTiming times the CPU, PPU and APU on one in every sample
+// steps, 1 timing every step; reading the clock on every step would slow
+// emulation noticeably. 0 turns timing off. Times restart from zero.
+func (b *Bus) EnableComponentTiming(sample int) {
+	if sample <= 0 {
+		b.timing = nil
+		return
+	}
+	b.timing = &componentTiming{sample: sample, overhead: clockOverhead()}
+}
+
+// clockOverhead measures how long reading the clock takes. A step can take
+// little more than a few clock reads, so their cost would swamp the times.
+func clockOverhead() time.Duration {
+	const reads = 1000
+	start := time.Now()
+	for i := 0; i < reads; i++ {
+		time.Now()
+	}
+	return time.Since(start) / reads
+}
+
+// ComponentTimes returns the time spent in each component since timing was
+// enabled, estimated from the steps timed
+func (b *Bus) ComponentTimes() ComponentTimes {
+	if b.timing == nil {
+		return ComponentTimes{}
+	}
+	t, scale := b.timing.times, time.Duration(b.timing.sample)
+	return ComponentTimes{CPU: t.CPU * scale, PPU: t.PPU * scale, APU: t.APU * scale, Steps: t.Steps}
+}
+
+// timeStep reports whether to time this step
+func (t *componentTiming) timeStep() bool {
+	t.tick++
+	if t.tick < t.sample {
+		return false
+	}
+	t.tick = 0
+	t.times.Steps++
+	return true
+}
+
+// lap adds the time since mark, less the clock overhead, to total and
+// returns the new mark
+func (t *componentTiming) lap(total *time.Duration, mark time.Time) time.Time {
+	now := time.Now()
+	*total += max(now.Sub(mark)-t.overhead, 0)
+	return now
+}
@@ -0,0 +1,33 @@
+package bus
+
+import "testing"
+
+func TestComponentTiming(t *testing.T) {
+	bus := newStateTestBus(t)
+	bus.Run(1)
+	if times := bus.ComponentTimes(); times != (ComponentTimes{}) {
+		t.Errorf("times without timing = %+v", times)
+	}
+
+	bus.EnableComponentTiming(4)
+	before := bus.GetCycleCount()
+	for i := 0; i < 400; i++ {
+		bus.Step()
+	}
+	times := bus.ComponentTimes()
+	if times.Steps != 100 {
+		t.Errorf("Steps = %d, want 100 (one in four)", times.Steps)
+	}
+	// Clock overhead is taken off each lap, so a component can come out at 0
+	if times.CPU < 0 || times.PPU < 0 || times.APU < 0 || times.CPU+times.PPU+times.APU == 0 {
+		t.Errorf("component times not measured: %+v", times)
+	}
+	if bus.GetCycleCount() <= before {
+		t.Error("emulation did not advance while timed")
+	}
+
+	bus.EnableComponentTiming(0)
+	if times := bus.ComponentTimes(); times != (ComponentTimes{}) {
+		t.Errorf("times after turning timing off = %+v", times)
+	}
+}
@@ -0,0 +1,95 @@
+// Package cartridge provides the reading of ROMs packed in .zip archives, so
+// compressed ROM sets load without being unpacked first.
+package cartridge
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxROMSize is the largest ROM read from an archive. The largest NES ROMs
+// are a few megabytes; the limit keeps a corrupt or hostile archive from
+// unpacking gigabytes.
+const maxROMSize = 16 << 20
+
+// zipMagic starts every .zip archive with at least one file
+const zipMagic = "PK\x03\x04"
+
+// IsROMFile reports whether a file name is one ReadROM reads: an iNES .nes
+// file or a .zip archive
+func IsROMFile(name string) bool {
+	ext := filepath.Ext(name)
+	return strings.EqualFold(ext, ".nes") || strings.EqualFold(ext, ".zip")
+}
+
+// ReadROM reads a ROM file. A .zip archive, told by its content rather than
+// its name, gives its first .nes file in archive order.
+func ReadROM(path string) ([]byte, error) {
+	if strings.EqualFold(filepath.Ext(path), ".7z") {
+		return nil, errors.New("7z archives are not supported (repack the ROM as .zip)")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(zipMagic)) {
+		return data, nil
+	}
+	rom, err := unzipROM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	return rom, nil
+}
+
+// unzipROM returns the first .nes file of a zip archive
+func unzipROM(data []byte) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %v", err)
+	}
+
+	disk := false
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		switch ext := filepath.Ext(file.Name); {
+		case strings.EqualFold(ext, ".nes"):
+			return readZipFile(file)
+		case strings.EqualFold(ext, ".fds"):
+			disk = true
+		}
+	}
+	if disk {
+		return nil, errors.New("archive holds a Famicom Disk System image, which is not supported")
+	}
+	return nil, errors.New("no .nes file in archive")
+}
+
+// readZipFile unpacks a file of a zip archive
+func readZipFile(file *zip.File) ([]byte, error) {
+	if file.UncompressedSize64 > maxROMSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", file.Name, maxROMSize)
+	}
+	r, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", file.Name, err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, maxROMSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s: %v", file.Name, err)
+	}
+	if len(data) > maxROMSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", file.Name, maxROMSize)
+	}
+	return data, nil
+}
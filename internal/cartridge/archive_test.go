@@ -0,0 +1,109 @@
+package cartridge
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZip writes a zip archive of files, in order, and returns its path
+func writeZip(t *testing.T, name string, files [][2]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.Create(file[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(file[1]))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadROM_Zip(t *testing.T) {
+	rom, err := NewTestROMBuilder().WithMapper(0).WithMirroring(MirrorVertical).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeZip(t, "game.zip", [][2]string{
+		{"readme.txt", "not a ROM"},
+		{"roms/", ""},
+		{"roms/Game (USA).NES", string(rom)},
+		{"Game (Europe).nes", "second ROM"},
+	})
+
+	data, err := ReadROM(path)
+	if err != nil {
+		t.Fatalf("ReadROM failed: %v", err)
+	}
+	if !bytes.Equal(data, rom) {
+		t.Error("ReadROM did not return the first .nes file of the archive")
+	}
+
+	cart, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if cart.GetMirrorMode() != MirrorVertical {
+		t.Errorf("Mirroring %v, want vertical", cart.GetMirrorMode())
+	}
+
+	// The archive is told by its content, so a misnamed one still loads
+	renamed := filepath.Join(t.TempDir(), "game.nes")
+	zipped, _ := os.ReadFile(path)
+	os.WriteFile(renamed, zipped, 0644)
+	if data, err := ReadROM(renamed); err != nil || !bytes.Equal(data, rom) {
+		t.Errorf("ReadROM of a zip named .nes = %d bytes, %v", len(data), err)
+	}
+}
+
+func TestReadROM_PlainFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.nes")
+	os.WriteFile(path, []byte("NES\x1Adata"), 0644)
+	data, err := ReadROM(path)
+	if err != nil || string(data) != "NES\x1Adata" {
+		t.Errorf("ReadROM = %q, %v", data, err)
+	}
+}
+
+func TestReadROM_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"no ROM", writeZip(t, "docs.zip", [][2]string{{"readme.txt", "text"}}), "no .nes file"},
+		{"disk image", writeZip(t, "disk.zip", [][2]string{{"Game.fds", "FDS\x1A"}}), "Famicom Disk System"},
+		{"7z", filepath.Join(t.TempDir(), "game.7z"), "7z archives are not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadROM(tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadROM error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsROMFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"game.nes": true, "GAME.NES": true, "set.zip": true, "Set.ZIP": true,
+		"game.sav": false, "game.7z": false, "readme": false,
+	} {
+		if got := IsROMFile(name); got != want {
+			t.Errorf("IsROMFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -2,10 +2,14 @@
 package cartridge
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"io"
-	"os"
+
+	"gones/internal/region"
 )
 
 // Cartridge represents a NES cartridge
@@ -18,15 +22,31 @@ type Cartridge struct {
 	mapperID uint8
 	mapper   Mapper
 
-	// Mirroring mode
-	mirror MirrorMode
+	// Mirroring mode, and the function SetMirroring tells of a new one
+	mirror      MirrorMode
+	onMirroring func(MirrorMode)
+
+	// Nametable pages of mappers that pick them (see SetNametablePages),
+	// and the function told of new ones
+	pages        [4]uint8
+	pagesMapped  bool
+	onNametables func(pages [4]uint8)
+
+	// Whether the header flags 2KB of nametable VRAM on the cartridge, which
+	// gives four nametables and takes the place of mapper mirroring
+	fourScreen bool
 
 	// Battery-backed RAM
 	hasBattery bool
 	sram       [0x2000]uint8
+	sramDirty  bool // Set when SRAM changes since the last flush
 
 	// CHR memory type
 	hasCHRRAM bool
+
+	// TV system the header names, if it names one
+	region      region.Region
+	regionKnown bool
 }
 
 // MirrorMode represents nametable mirroring mode
@@ -61,15 +81,34 @@ type iNESHeader struct {
 	Padding    [5]uint8
 }
 
-// LoadFromFile loads a cartridge from an iNES file
+// region returns the TV system of the header. NES 2.0 headers name it in
+// byte 12; iNES ones can only flag PAL in byte 9, which few dumps set and
+// those with junk in bytes 12-15 (such as "DiskDude!") may set by accident.
+func (h *iNESHeader) region() (region.Region, bool) {
+	if h.Flags7&0x0C == 0x08 {
+		switch h.Padding[1] & 0x03 {
+		case 0:
+			return region.NTSC, true
+		case 1:
+			return region.PAL, true
+		case 3:
+			return region.Dendy, true
+		}
+		return region.NTSC, false // Runs on either
+	}
+	if h.TVSystem1&0x01 != 0 && h.Padding[1]|h.Padding[2]|h.Padding[3]|h.Padding[4] == 0 {
+		return region.PAL, true
+	}
+	return region.NTSC, false
+}
+
+// LoadFromFile loads a cartridge from an iNES file, or a .zip archive of one
 func LoadFromFile(filename string) (*Cartridge, error) {
-	file, err := os.Open(filename)
+	data, err := ReadROM(filename)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	return LoadFromReader(file)
+	return LoadFromReader(bytes.NewReader(data))
 }
 
 // LoadFromReader loads a cartridge from an io.Reader
@@ -95,9 +134,12 @@ func LoadFromReader(r io.Reader) (*Cartridge, error) {
 		hasBattery: (header.Flags6 & 0x02) != 0,
 	}
 
+	cart.region, cart.regionKnown = header.region()
+
 	// Set mirroring mode
 	if (header.Flags6 & 0x08) != 0 {
 		cart.mirror = MirrorFourScreen
+		cart.fourScreen = true
 	} else if (header.Flags6 & 0x01) != 0 {
 		cart.mirror = MirrorVertical
 	} else {
@@ -168,20 +210,121 @@ func (c *Cartridge) WriteCHR(address uint16, value uint8) {
 	c.mapper.WriteCHR(address, value)
 }
 
+// expansionMapper is implemented by mappers with registers in the expansion
+// area ($4020-$5FFF), such as the J.Y. Company ASIC's multiplier
+type expansionMapper interface {
+	ReadExpansion(address uint16) (uint8, bool)
+	WriteExpansion(address uint16, value uint8)
+}
+
+// ReadExpansion reads the expansion area ($4020-$5FFF). It returns false,
+// leaving the address unmapped, unless the mapper has a register there.
+func (c *Cartridge) ReadExpansion(address uint16) (uint8, bool) {
+	if m, ok := c.mapper.(expansionMapper); ok {
+		return m.ReadExpansion(address)
+	}
+	return 0, false
+}
+
+// WriteExpansion writes the expansion area ($4020-$5FFF)
+func (c *Cartridge) WriteExpansion(address uint16, value uint8) {
+	if m, ok := c.mapper.(expansionMapper); ok {
+		m.WriteExpansion(address, value)
+	}
+}
+
 // GetMirrorMode returns the cartridge's mirroring mode
 func (c *Cartridge) GetMirrorMode() MirrorMode {
 	return c.mirror
 }
 
+// romOffsetMapper is implemented by mappers that can tell which ROM byte an
+// address maps to with the current banks, for the code/data logger
+type romOffsetMapper interface {
+	PRGOffset(address uint16) (int, bool)
+	CHROffset(address uint16) (int, bool)
+}
+
+// Region returns the TV system the header names, and false when it names
+// none or the game runs on either
+func (c *Cartridge) Region() (region.Region, bool) {
+	return c.region, c.regionKnown
+}
+
+// MapperID returns the iNES mapper number
+func (c *Cartridge) MapperID() uint8 {
+	return c.mapperID
+}
+
+// PRGSize returns the size of PRG ROM in bytes
+func (c *Cartridge) PRGSize() int {
+	return len(c.prgROM)
+}
+
+// CHRSize returns the size of CHR ROM in bytes (0 with CHR RAM)
+func (c *Cartridge) CHRSize() int {
+	if c.hasCHRRAM {
+		return 0
+	}
+	return len(c.chrROM)
+}
+
+// ROMChecksum returns the SHA-256, in hex, of the PRG ROM followed by the
+// CHR ROM, which identifies the game whatever its header or file. Take it
+// before running the cartridge: with CHR RAM only the PRG ROM is hashed.
+func (c *Cartridge) ROMChecksum() string {
+	h := sha256.New()
+	h.Write(c.prgROM)
+	if !c.hasCHRRAM {
+		h.Write(c.chrROM)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// PRGOffset returns the PRG ROM offset a CPU address currently maps to. It
+// returns false outside PRG ROM and for mappers that cannot tell.
+func (c *Cartridge) PRGOffset(address uint16) (int, bool) {
+	if m, ok := c.mapper.(romOffsetMapper); ok {
+		return m.PRGOffset(address)
+	}
+	return 0, false
+}
+
+// CHROffset returns the CHR ROM offset a PPU address currently maps to. It
+// returns false outside CHR ROM, with CHR RAM and for mappers that cannot tell.
+func (c *Cartridge) CHROffset(address uint16) (int, bool) {
+	if m, ok := c.mapper.(romOffsetMapper); ok && !c.hasCHRRAM {
+		return m.CHROffset(address)
+	}
+	return 0, false
+}
+
+// mappers creates the emulated mappers by ID
+var mappers = map[uint8]func(cart *Cartridge) Mapper{
+	0:   func(cart *Cartridge) Mapper { return NewMapper000(cart) },
+	4:   func(cart *Cartridge) Mapper { return NewMapper004(cart) },
+	90:  func(cart *Cartridge) Mapper { return NewMapper090(cart) },
+	118: func(cart *Cartridge) Mapper { return NewMapper118(cart) },
+	119: func(cart *Cartridge) Mapper { return NewMapper119(cart) },
+	163: func(cart *Cartridge) Mapper { return NewMapper163(cart) },
+	209: func(cart *Cartridge) Mapper { return NewMapper209(cart) },
+	211: func(cart *Cartridge) Mapper { return NewMapper211(cart) },
+}
+
+// MapperSupported reports whether a mapper is emulated. Cartridges with other
+// mappers load as NROM (mapper 0), which few of them run on.
+func MapperSupported(id uint8) bool {
+	_, ok := mappers[id]
+	return ok
+}
+
 // createMapper creates the appropriate mapper for the given ID
 func createMapper(id uint8, cart *Cartridge) Mapper {
-	switch id {
-	case 0:
-		return NewMapper000(cart)
-	default:
-		// Default to mapper 0 for unsupported mappers
-		return NewMapper000(cart)
+	if create, ok := mappers[id]; ok {
+		return create(cart)
 	}
+	// Default to mapper 0 for unsupported mappers
+	return NewMapper000(cart)
 }
 
 // MockCartridge implements CartridgeInterface for testing
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"gones/internal/region"
 )
 
 // Test data constants for iNES header construction
@@ -525,3 +527,36 @@ func TestCreateMapper_Mapper0_ShouldCreateCorrectType(t *testing.T) {
 		t.Error("Expected Mapper000 type")
 	}
 }
+
+func TestLoadFromReader_HeaderRegion(t *testing.T) {
+	tests := []struct {
+		name   string
+		flags7 uint8
+		byte9  uint8
+		byte12 uint8
+		region region.Region
+		known  bool
+	}{
+		{"iNES without TV system", 0x00, 0x00, 0x00, region.NTSC, false},
+		{"iNES PAL flag", 0x00, 0x01, 0x00, region.PAL, true},
+		{"iNES PAL flag with junk", 0x00, 0x01, 'D', region.NTSC, false},
+		{"NES 2.0 NTSC", 0x08, 0x00, 0x00, region.NTSC, true},
+		{"NES 2.0 PAL", 0x08, 0x00, 0x01, region.PAL, true},
+		{"NES 2.0 multi-region", 0x08, 0x00, 0x02, region.NTSC, false},
+		{"NES 2.0 Dendy", 0x08, 0x00, 0x03, region.Dendy, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := createValidINESHeader(1, 1, 0, 0, tt.flags7)
+			header[9], header[12] = tt.byte9, tt.byte12
+			rom := append(header, make([]byte, 16384+8192)...)
+			cart, err := LoadFromReader(bytes.NewReader(rom))
+			if err != nil {
+				t.Fatalf("LoadFromReader: %v", err)
+			}
+			if got, known := cart.Region(); got != tt.region || known != tt.known {
+				t.Errorf("Region() = %v, %v, want %v, %v", got, known, tt.region, tt.known)
+			}
+		})
+	}
+}
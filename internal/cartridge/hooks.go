@@ -0,0 +1,164 @@
+// Package cartridge provides the signals mappers with counters take from the
+// rest of the console, and the IRQ they can raise, so that MMC3, VRC and
+// FME-7 class mappers need no code of their own in the bus.
+package cartridge
+
+// A mapper implements the interfaces below for the signals it needs; the bus
+// only sends the ones a mapper takes. Cartridges other than Cartridge, such
+// as the NSF player's, can implement them too.
+
+// CPUClocked is implemented by mappers with hardware running off the CPU
+// clock, such as the VRC IRQ prescaler and the FME-7 cycle counter
+type CPUClocked interface {
+	// ClockCPU is called once per CPU cycle
+	ClockCPU()
+}
+
+// A12Watcher is implemented by mappers that watch PPU address line A12, such
+// as the MMC3, whose scanline counter is clocked when it rises. A12 rises
+// when the PPU goes from fetching the pattern table at $0000 (or a nametable)
+// to the one at $1000. Rises are filtered as the MMC3 filters them, counting
+// only those after A12 was low for a few CPU cycles, which leaves one per
+// rendered scanline when the background and sprites use different tables.
+type A12Watcher interface {
+	A12Rise()
+}
+
+// ScanlineWatcher is implemented by mappers that count scanlines themselves,
+// such as the MMC5. It is called at the start of every scanline, -1 being the
+// pre-render line, with whether the PPU is rendering.
+type ScanlineWatcher interface {
+	StartScanline(scanline int, rendering bool)
+}
+
+// IRQSource is implemented by mappers that can raise the CPU's IRQ. The
+// IRQ line is level triggered: it stays raised until the mapper clears it,
+// usually when the game acknowledges it through a mapper register.
+type IRQSource interface {
+	IRQ() bool
+}
+
+// Scheduler runs callbacks on the console's clock. A callback runs before the
+// first instruction that starts once it is due, with the PPU and APU caught
+// up to it.
+type Scheduler interface {
+	// After runs fire once cpuCycles CPU cycles have passed since the start
+	// of the current instruction, and returns a function cancelling it
+	After(cpuCycles uint64, fire func()) (cancel func())
+
+	// GetCycleCount returns the CPU cycles run up to the start of the
+	// current instruction, the clock After counts from
+	GetCycleCount() uint64
+}
+
+// EventScheduled is implemented by mappers that schedule events, such as a
+// cycle counter IRQ worked out when the counter is written instead of
+// counted down on every CPU cycle. The bus calls SetScheduler when the
+// cartridge is inserted, and again after a reset or state load, when the
+// events scheduled before are gone and the mapper schedules them anew from
+// its registers.
+//
+// IRQScheduled reports whether the mapper's IRQ, if it has one, only
+// changes in its events and on writes to its registers for now, so the bus
+// can run the CPU in batches up to the next event instead of checking the
+// IRQ after every instruction. A mapper whose IRQ counter can also count A12
+// rises reports false while it does. The bus asks before every batch.
+type EventScheduled interface {
+	SetScheduler(s Scheduler)
+	IRQScheduled() bool
+}
+
+// MirroringSwitcher is implemented by cartridges whose mapper can switch the
+// nametable mirroring while a game runs. The bus sets the callback, which
+// switches the PPU's nametables to the new mode.
+type MirroringSwitcher interface {
+	SetMirroringCallback(callback func(MirrorMode))
+}
+
+// SetMirroringCallback sets the function called with each mode SetMirroring
+// switches to. nil removes it.
+func (c *Cartridge) SetMirroringCallback(callback func(MirrorMode)) {
+	c.onMirroring = callback
+}
+
+// SetMirroring switches the nametable mirroring, for mappers that control it
+// such as the MMC1, MMC3 and AxROM. Four-screen cartridges, such as Gauntlet
+// and Rad Racer II, wire their own VRAM in place of the mirroring and ignore
+// it.
+func (c *Cartridge) SetMirroring(mode MirrorMode) {
+	if c.fourScreen {
+		return
+	}
+	c.mirror = mode
+	c.pagesMapped = false
+	if c.onMirroring != nil {
+		c.onMirroring(mode)
+	}
+}
+
+// NametableSwitcher is implemented by cartridges whose mapper drives the
+// nametable VRAM's A10 line itself, putting each of the four nametables on a
+// page of its choosing, such as TxSROM with its CHR bank bits. The bus sets
+// the callback, which points the PPU's nametables at the pages.
+type NametableSwitcher interface {
+	SetNametableCallback(callback func(pages [4]uint8))
+}
+
+// NametablePageCHR is the page of SetNametablePages for a nametable the mapper
+// maps to CHR ROM, such as those of the J.Y. Company ASIC. The PPU reads
+// such a nametable through ReadNametable.
+const NametablePageCHR = 0x80
+
+// nametableMapper is implemented by mappers that map nametables to memory of
+// their own with NametablePageCHR
+type nametableMapper interface {
+	ReadNametable(nametable int, offset uint16) uint8
+	WriteNametable(nametable int, offset uint16, value uint8)
+}
+
+// ReadNametable reads offset in a nametable the mapper put on
+// NametablePageCHR
+func (c *Cartridge) ReadNametable(nametable int, offset uint16) uint8 {
+	if m, ok := c.mapper.(nametableMapper); ok {
+		return m.ReadNametable(nametable, offset)
+	}
+	return 0
+}
+
+// WriteNametable writes offset in a nametable the mapper put on
+// NametablePageCHR
+func (c *Cartridge) WriteNametable(nametable int, offset uint16, value uint8) {
+	if m, ok := c.mapper.(nametableMapper); ok {
+		m.WriteNametable(nametable, offset, value)
+	}
+}
+
+// SetNametableCallback sets the function called with the pages
+// SetNametablePages picks. It is called at once when the mapper has picked
+// them already. nil removes it.
+func (c *Cartridge) SetNametableCallback(callback func(pages [4]uint8)) {
+	c.onNametables = callback
+	if callback != nil && c.pagesMapped {
+		callback(c.pages)
+	}
+}
+
+// SetNametablePages puts each nametable on a 1KB page of nametable VRAM, for
+// mappers wired to its A10 line, or on NametablePageCHR. Like SetMirroring,
+// it is ignored by four-screen cartridges.
+func (c *Cartridge) SetNametablePages(pages [4]uint8) {
+	if c.fourScreen {
+		return
+	}
+	c.pages = pages
+	c.pagesMapped = true
+	if c.onNametables != nil {
+		c.onNametables(pages)
+	}
+}
+
+// Mapper returns the cartridge's mapper, for the bus to find the signals it
+// takes
+func (c *Cartridge) Mapper() Mapper {
+	return c.mapper
+}
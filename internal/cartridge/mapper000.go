@@ -52,11 +52,31 @@ func (m *Mapper000) ReadPRG(address uint16) uint8 {
 	return 0
 }
 
+// PRGOffset returns the PRG ROM offset of a CPU address ($8000-$FFFF)
+func (m *Mapper000) PRGOffset(address uint16) (int, bool) {
+	if address < 0x8000 || len(m.cart.prgROM) == 0 {
+		return 0, false
+	}
+	offset := int(address - 0x8000)
+	if m.prgBanks == 1 {
+		offset &= 0x3FFF
+	}
+	return offset, offset < len(m.cart.prgROM)
+}
+
+// CHROffset returns the CHR offset of a PPU pattern table address
+func (m *Mapper000) CHROffset(address uint16) (int, bool) {
+	if address >= 0x2000 || int(address) >= len(m.cart.chrROM) {
+		return 0, false
+	}
+	return int(address), true
+}
+
 // WritePRG writes to PRG RAM
 func (m *Mapper000) WritePRG(address uint16, value uint8) {
 	if address >= 0x6000 && address < 0x8000 {
 		// PRG RAM (SRAM) - 8KB range from 0x6000-0x7FFF
-		m.cart.sram[address-0x6000] = value
+		m.cart.writeSRAM(address-0x6000, value)
 	}
 	// Writes to ROM area are ignored
 }
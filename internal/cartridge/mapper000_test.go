@@ -516,3 +516,41 @@ func TestMapper000_EdgeCase_InvalidAddresses(t *testing.T) {
 		}
 	}
 }
+
+// TestMapper000_ROMOffsets tests the ROM offsets reported to the code/data logger
+func TestMapper000_ROMOffsets(t *testing.T) {
+	cart := &Cartridge{
+		prgROM: make([]uint8, 0x4000), // 16KB, mirrored
+		chrROM: make([]uint8, 0x2000),
+	}
+	cart.mapper = NewMapper000(cart)
+
+	tests := []struct {
+		address uint16
+		offset  int
+		ok      bool
+	}{
+		{0x6000, 0, false},
+		{0x8000, 0x0000, true},
+		{0xBFFF, 0x3FFF, true},
+		{0xC010, 0x0010, true},
+	}
+	for _, test := range tests {
+		offset, ok := cart.PRGOffset(test.address)
+		if offset != test.offset || ok != test.ok {
+			t.Errorf("PRGOffset($%04X) = %d, %v, want %d, %v", test.address, offset, ok, test.offset, test.ok)
+		}
+	}
+
+	if offset, ok := cart.CHROffset(0x1234); offset != 0x1234 || !ok {
+		t.Errorf("CHROffset($1234) = %d, %v, want %d, true", offset, ok, 0x1234)
+	}
+	if _, ok := cart.CHROffset(0x2000); ok {
+		t.Error("CHROffset($2000) should be outside CHR ROM")
+	}
+
+	cart.hasCHRRAM = true
+	if _, ok := cart.CHROffset(0x0000); ok || cart.CHRSize() != 0 {
+		t.Error("CHR RAM should not be logged")
+	}
+}
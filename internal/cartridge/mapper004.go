@@ -0,0 +1,250 @@
+// Package cartridge implements the MMC3 (mapper 4) and the boards built on it.
+package cartridge
+
+import (
+	"fmt"
+
+	"gones/internal/savestate"
+)
+
+// Mapper004 implements the MMC3 (mapper 4), found on TxROM boards such as
+// those of Super Mario Bros. 3 and Kirby's Adventure. It supports:
+// - 8KB PRG ROM banks, two switchable and two fixed to the last banks, with
+// the first switchable one moved to $C000 in PRG mode 1
+// - 2KB and 1KB CHR banks, the 2KB ones moved to $1000 with CHR A12 inversion
+// - 8KB PRG RAM at $6000-$7FFF (optionally battery-backed)
+// - Horizontal/vertical mirroring control
+// - A scanline counter clocked by PPU A12 rises, raising an IRQ at zero
+//
+// TxSROM (mapper 118) and TQROM (mapper 119) are MMC3 boards with wiring of
+// their own; see NewMapper118 and NewMapper119.
+type Mapper004 struct {
+	cart *Cartridge
+
+	bankSelect uint8    // $8000: bank register to write, PRG mode, CHR A12 inversion
+	registers  [8]uint8 // R0-R7, written through $8001
+	ramProtect uint8    // $A001, kept but not enforced (see WritePRG)
+
+	// Scanline counter
+	irqLatch   uint8
+	irqCounter uint8
+	irqReload  bool
+	irqEnabled bool
+	irqPending bool
+
+	// Current banks: ROM offsets of the four 8KB PRG windows and the 1KB CHR
+	// bank numbers of the eight CHR windows, from the registers
+	prgOffsets [4]int
+	chrBanks   [8]int
+
+	// Board wiring
+	nametableBanks bool    // TxSROM: CHR bank bit 7 drives nametable A10
+	chrRAM         []uint8 // TQROM: CHR RAM in banks with bit 6 set
+}
+
+// NewMapper004 creates a new MMC3 mapper
+func NewMapper004(cart *Cartridge) *Mapper004 {
+	m := &Mapper004{cart: cart}
+	m.updateBanks()
+	return m
+}
+
+// ReadPRG reads from PRG ROM/RAM
+// Memory map:
+// 0x6000-0x7FFF: 8KB PRG RAM (SRAM)
+// 0x8000-0xFFFF: four 8KB PRG ROM windows
+func (m *Mapper004) ReadPRG(address uint16) uint8 {
+	if address >= 0x8000 {
+		if offset, ok := m.PRGOffset(address); ok {
+			return m.cart.prgROM[offset]
+		}
+		return 0
+	} else if address >= 0x6000 {
+		return m.cart.sram[address-0x6000]
+	}
+	return 0
+}
+
+// WritePRG writes to PRG RAM and the mapper registers. The registers are
+// decoded by A14-A13 and A0: $8000/$8001 bank select/data, $A000/$A001
+// mirroring/RAM protect, $C000/$C001 IRQ latch/reload and $E000/$E001 IRQ
+// disable/enable.
+func (m *Mapper004) WritePRG(address uint16, value uint8) {
+	if address < 0x8000 {
+		// PRG RAM is left writable whatever $A001 says, as on MMC6 boards
+		// sharing the mapper number and as games that forget to enable it need
+		if address >= 0x6000 {
+			m.cart.writeSRAM(address-0x6000, value)
+		}
+		return
+	}
+
+	switch address & 0xE001 {
+	case 0x8000:
+		m.bankSelect = value
+		m.updateBanks()
+	case 0x8001:
+		m.registers[m.bankSelect&7] = value
+		m.updateBanks()
+	case 0xA000:
+		// TxSROM wires nametable A10 to CHR bank bits instead
+		if m.nametableBanks {
+			return
+		}
+		if value&1 != 0 {
+			m.cart.SetMirroring(MirrorHorizontal)
+		} else {
+			m.cart.SetMirroring(MirrorVertical)
+		}
+	case 0xA001:
+		m.ramProtect = value
+	case 0xC000:
+		m.irqLatch = value
+	case 0xC001:
+		m.irqCounter = 0
+		m.irqReload = true
+	case 0xE000:
+		m.irqEnabled = false
+		m.irqPending = false
+	case 0xE001:
+		m.irqEnabled = true
+	}
+}
+
+// ReadCHR reads from CHR ROM/RAM
+func (m *Mapper004) ReadCHR(address uint16) uint8 {
+	if address >= 0x2000 {
+		return 0
+	}
+	if ram, offset, ok := m.chrRAMOffset(address); ok {
+		return ram[offset]
+	}
+	if offset, ok := m.CHROffset(address); ok {
+		return m.cart.chrROM[offset]
+	}
+	return 0
+}
+
+// WriteCHR writes to CHR RAM
+func (m *Mapper004) WriteCHR(address uint16, value uint8) {
+	if address >= 0x2000 {
+		return
+	}
+	if ram, offset, ok := m.chrRAMOffset(address); ok {
+		ram[offset] = value
+		return
+	}
+	// Writes to CHR ROM are ignored
+	if offset, ok := m.CHROffset(address); ok && m.cart.hasCHRRAM {
+		m.cart.chrROM[offset] = value
+	}
+}
+
+// PRGOffset returns the PRG ROM offset of a CPU address ($8000-$FFFF)
+func (m *Mapper004) PRGOffset(address uint16) (int, bool) {
+	if address < 0x8000 || len(m.cart.prgROM) == 0 {
+		return 0, false
+	}
+	return m.prgOffsets[(address>>13)&3] + int(address&0x1FFF), true
+}
+
+// CHROffset returns the CHR offset of a PPU pattern table address. It
+// returns false for TQROM's CHR RAM banks.
+func (m *Mapper004) CHROffset(address uint16) (int, bool) {
+	if address >= 0x2000 || len(m.cart.chrROM) == 0 {
+		return 0, false
+	}
+	bank := m.chrBanks[address>>10]
+	if m.chrRAM != nil && bank&0x40 != 0 {
+		return 0, false
+	}
+	return (bank*0x400 + int(address&0x3FF)) % len(m.cart.chrROM), true
+}
+
+// updateBanks works out the PRG and CHR windows from the registers
+func (m *Mapper004) updateBanks() {
+	prgBanks := max(len(m.cart.prgROM)/0x2000, 1)
+	bank := func(n int) int {
+		return (n % prgBanks) * 0x2000
+	}
+	secondLast := bank(prgBanks - 2)
+	m.prgOffsets = [4]int{bank(int(m.registers[6])), bank(int(m.registers[7])), secondLast, bank(prgBanks - 1)}
+	if m.bankSelect&0x40 != 0 {
+		m.prgOffsets[0], m.prgOffsets[2] = secondLast, m.prgOffsets[0]
+	}
+
+	r := m.registers
+	if m.bankSelect&0x80 == 0 {
+		m.chrBanks = [8]int{
+			int(r[0] &^ 1), int(r[0] | 1), int(r[1] &^ 1), int(r[1] | 1),
+			int(r[2]), int(r[3]), int(r[4]), int(r[5]),
+		}
+	} else {
+		m.chrBanks = [8]int{
+			int(r[2]), int(r[3]), int(r[4]), int(r[5]),
+			int(r[0] &^ 1), int(r[0] | 1), int(r[1] &^ 1), int(r[1] | 1),
+		}
+	}
+
+	if m.nametableBanks {
+		m.updateNametables()
+	}
+}
+
+// A12Rise clocks the scanline counter: at zero, or after a reload request,
+// it is reloaded from the latch, otherwise decremented. The IRQ is raised
+// when it is zero after the clock.
+func (m *Mapper004) A12Rise() {
+	if m.irqCounter == 0 || m.irqReload {
+		m.irqCounter = m.irqLatch
+		m.irqReload = false
+	} else {
+		m.irqCounter--
+	}
+	if m.irqCounter == 0 && m.irqEnabled {
+		m.irqPending = true
+	}
+}
+
+// IRQ returns whether the scanline counter's IRQ is raised
+func (m *Mapper004) IRQ() bool {
+	return m.irqPending
+}
+
+// SaveState writes the mapper registers, the scanline counter and TQROM's CHR RAM
+func (m *Mapper004) SaveState(w *savestate.Writer) {
+	w.WriteTag("MMC3")
+	w.WriteU8(m.bankSelect)
+	w.WriteBytes(m.registers[:])
+	w.WriteU8(m.ramProtect)
+	w.WriteU8(m.irqLatch)
+	w.WriteU8(m.irqCounter)
+	w.WriteBool(m.irqReload)
+	w.WriteBool(m.irqEnabled)
+	w.WriteBool(m.irqPending)
+	if m.chrRAM != nil {
+		w.WriteBytes(m.chrRAM)
+	}
+}
+
+// LoadState restores state written by SaveState
+func (m *Mapper004) LoadState(r *savestate.Reader) error {
+	r.ExpectTag("MMC3")
+	m.bankSelect = r.ReadU8()
+	r.ReadBytesInto(m.registers[:])
+	m.ramProtect = r.ReadU8()
+	m.irqLatch = r.ReadU8()
+	m.irqCounter = r.ReadU8()
+	m.irqReload = r.ReadBool()
+	m.irqEnabled = r.ReadBool()
+	m.irqPending = r.ReadBool()
+	if m.chrRAM != nil {
+		r.ReadBytesInto(m.chrRAM)
+	}
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("MMC3 state: %v", err)
+	}
+
+	m.updateBanks()
+	return nil
+}
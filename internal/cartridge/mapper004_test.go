@@ -0,0 +1,185 @@
+package cartridge
+
+import (
+	"testing"
+
+	"gones/internal/savestate"
+)
+
+// newBankedCartridge returns a cartridge with prgBanks 8KB PRG ROM banks
+// and chrBanks 1KB CHR ROM banks, every byte of a bank holding its number
+func newBankedCartridge(mapperID uint8, prgBanks, chrBanks int) *Cartridge {
+	cart := &Cartridge{
+		prgROM:   make([]uint8, prgBanks*0x2000),
+		chrROM:   make([]uint8, chrBanks*0x400),
+		mapperID: mapperID,
+	}
+	for i := range cart.prgROM {
+		cart.prgROM[i] = uint8(i / 0x2000)
+	}
+	for i := range cart.chrROM {
+		cart.chrROM[i] = uint8(i / 0x400)
+	}
+	return cart
+}
+
+// setMMC3Bank writes a bank register through $8000/$8001, keeping the mode bits
+func setMMC3Bank(m *Mapper004, modes uint8, register int, value uint8) {
+	m.WritePRG(0x8000, modes|uint8(register))
+	m.WritePRG(0x8001, value)
+}
+
+func TestMapper004PRGBanks(t *testing.T) {
+	cart := newBankedCartridge(4, 16, 8)
+	m := NewMapper004(cart)
+	setMMC3Bank(m, 0, 6, 3)
+	setMMC3Bank(m, 0, 7, 5)
+
+	for _, tc := range []struct {
+		mode uint8
+		want [4]uint8
+	}{
+		{0x00, [4]uint8{3, 5, 14, 15}},
+		{0x40, [4]uint8{14, 5, 3, 15}},
+	} {
+		m.WritePRG(0x8000, tc.mode)
+		for i, want := range tc.want {
+			address := 0x8000 + uint16(i)*0x2000
+			if got := m.ReadPRG(address + 0x123); got != want {
+				t.Errorf("PRG mode $%02X: $%04X reads bank %d, want %d", tc.mode, address, got, want)
+			}
+		}
+	}
+
+	// Bank numbers wrap around the ROM
+	setMMC3Bank(m, 0, 6, 0x13)
+	if got := m.ReadPRG(0x8000); got != 3 {
+		t.Errorf("Bank $13 of 16 reads bank %d, want 3", got)
+	}
+}
+
+func TestMapper004CHRBanks(t *testing.T) {
+	cart := newBankedCartridge(4, 4, 64)
+	m := NewMapper004(cart)
+	for register, bank := range []uint8{9, 20, 30, 31, 32, 33} {
+		setMMC3Bank(m, 0, register, bank)
+	}
+
+	for _, tc := range []struct {
+		mode uint8
+		want [8]uint8
+	}{
+		// R0 and R1 are 2KB banks and ignore bit 0
+		{0x00, [8]uint8{8, 9, 20, 21, 30, 31, 32, 33}},
+		{0x80, [8]uint8{30, 31, 32, 33, 8, 9, 20, 21}},
+	} {
+		m.WritePRG(0x8000, tc.mode)
+		for i, want := range tc.want {
+			address := uint16(i) * 0x400
+			if got := m.ReadCHR(address + 0x10); got != want {
+				t.Errorf("CHR mode $%02X: $%04X reads bank %d, want %d", tc.mode, address, got, want)
+			}
+		}
+	}
+
+	// CHR ROM is read only
+	m.WriteCHR(0x0000, 0xFF)
+	if got := m.ReadCHR(0x0000); got == 0xFF {
+		t.Error("Write to CHR ROM was stored")
+	}
+}
+
+func TestMapper004Mirroring(t *testing.T) {
+	cart := newBankedCartridge(4, 4, 8)
+	m := NewMapper004(cart)
+
+	m.WritePRG(0xA000, 0)
+	if cart.GetMirrorMode() != MirrorVertical {
+		t.Errorf("$A000 = 0 gave mirroring %d, want vertical", cart.GetMirrorMode())
+	}
+	m.WritePRG(0xA000, 1)
+	if cart.GetMirrorMode() != MirrorHorizontal {
+		t.Errorf("$A000 = 1 gave mirroring %d, want horizontal", cart.GetMirrorMode())
+	}
+}
+
+func TestMapper004IRQ(t *testing.T) {
+	m := NewMapper004(newBankedCartridge(4, 4, 8))
+	m.WritePRG(0xC000, 3) // Latch
+	m.WritePRG(0xC001, 0) // Reload
+	m.WritePRG(0xE001, 0) // Enable
+
+	// The first clock reloads the counter, three more take it to zero
+	for i := 1; i <= 4; i++ {
+		m.A12Rise()
+		if raised := m.IRQ(); raised != (i == 4) {
+			t.Fatalf("After %d A12 rises, IRQ = %v", i, raised)
+		}
+	}
+
+	// The IRQ stays raised until acknowledged through $E000
+	m.A12Rise()
+	if !m.IRQ() {
+		t.Error("IRQ cleared without an acknowledgement")
+	}
+	m.WritePRG(0xE000, 0)
+	if m.IRQ() {
+		t.Error("IRQ still raised after $E000")
+	}
+
+	// Disabled, the counter still runs but raises nothing
+	for i := 0; i < 8; i++ {
+		m.A12Rise()
+	}
+	if m.IRQ() {
+		t.Error("IRQ raised while disabled")
+	}
+
+	// A latch of zero raises the IRQ on every clock once enabled
+	m.WritePRG(0xC000, 0)
+	m.WritePRG(0xC001, 0)
+	m.WritePRG(0xE001, 0)
+	m.A12Rise()
+	if !m.IRQ() {
+		t.Error("Latch 0 did not raise the IRQ")
+	}
+}
+
+func TestMapper004PRGRAM(t *testing.T) {
+	cart := newBankedCartridge(4, 4, 8)
+	m := NewMapper004(cart)
+	m.WritePRG(0x6123, 0x42)
+	if got := m.ReadPRG(0x6123); got != 0x42 {
+		t.Errorf("PRG RAM read $%02X, want $42", got)
+	}
+	if !cart.IsSRAMDirty() {
+		t.Error("PRG RAM write did not mark SRAM dirty")
+	}
+}
+
+func TestMapper004State(t *testing.T) {
+	cart := newBankedCartridge(4, 16, 64)
+	m := NewMapper004(cart)
+	setMMC3Bank(m, 0xC0, 6, 7)
+	setMMC3Bank(m, 0xC0, 2, 40)
+	m.WritePRG(0xC000, 10)
+	m.WritePRG(0xC001, 0)
+	m.WritePRG(0xE001, 0)
+	m.A12Rise()
+	m.A12Rise()
+
+	w := savestate.NewWriter(64)
+	m.SaveState(w)
+
+	restored := NewMapper004(cart)
+	if err := restored.LoadState(savestate.NewReader(w.Bytes())); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if restored.ReadPRG(0xC000) != 7 || restored.ReadCHR(0x0000) != 40 {
+		t.Errorf("Restored banks read PRG %d and CHR %d, want 7 and 40",
+			restored.ReadPRG(0xC000), restored.ReadCHR(0x0000))
+	}
+	if restored.irqCounter != 9 || !restored.irqEnabled {
+		t.Errorf("Restored IRQ counter %d (enabled %v), want 9 (enabled)", restored.irqCounter, restored.irqEnabled)
+	}
+}
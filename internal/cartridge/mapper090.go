@@ -0,0 +1,610 @@
+// Package cartridge implements the J.Y. Company ASIC (mappers 90, 209 and
+// 211), found on late unlicensed cartridges.
+package cartridge
+
+import (
+	"fmt"
+
+	"gones/internal/savestate"
+)
+
+// J.Y. Company register bits
+const (
+	jyIRQSourceCPU      = 0    // $C001 bits 0-1: CPU cycles (M2)
+	jyIRQSourceA12      = 1    // $C001 bits 0-1: PPU A12 rises
+	jyIRQSmallPrescaler = 0x04 // $C001: 3-bit prescaler instead of 8-bit
+	jyIRQDirectionUp    = 1    // $C001 bits 6-7
+	jyIRQDirectionDown  = 2
+
+	jyModeLastPRGFromReg = 0x04 // $D000: last PRG window from register 3
+	jyModeNametablesExt  = 0x20 // $D000: extended nametable control
+	jyModeNametablesROM  = 0x40 // $D000: all nametables from CHR ROM
+	jyModePRGAt6000      = 0x80 // $D000: PRG ROM at $6000-$7FFF
+
+	jyNametableRAMSelect = 0x80 // $D002, and $B000-$B003 compared with it
+	jyOuterCHRUnblocked  = 0x20 // $D003: CHR banks not limited to 256KB blocks
+)
+
+// Mapper090 implements the J.Y. Company ASIC (mapper 90), used by late
+// unlicensed games such as Mortal Kombat 3 and Tiny Toon Adventures 6. It
+// supports:
+// - PRG ROM in 32KB, 16KB or 8KB banks, with a bit-reversed bank mode and an
+// optional ROM bank at $6000-$7FFF in place of PRG RAM
+// - CHR ROM in 8KB, 4KB, 2KB or 1KB banks of 16 bits, limited to 256KB
+// blocks picked by an outer bank unless $D003 lifts the limit
+// - Mirroring control, and on mappers 209 and 211 nametables mapped to CHR
+// ROM banks
+// - An 8x8 multiplier and an accumulator at $5800-$5803
+// - An IRQ counter that counts up or down through a 3- or 8-bit prescaler,
+// clocked by CPU cycles or PPU A12 rises
+//
+// Counting PPU reads or CPU writes, IRQ sources 2 and 3, is not emulated: the
+// counter stands still with them. The few games using them are not expected
+// to run.
+type Mapper090 struct {
+	cart *Cartridge
+
+	// Extended nametable control: never on mapper 90, when $D000 asks for it
+	// on mapper 209 and always on mapper 211
+	nametablesByMode bool
+	nametablesAlways bool
+
+	prgRegisters [4]uint8 // $8000-$8003
+	chrLow       [8]uint8 // $9000-$9007
+	chrHigh      [8]uint8 // $A000-$A007
+	ntLow        [4]uint8 // $B000-$B003
+	ntHigh       [4]uint8 // $B004-$B007
+	mode         uint8    // $D000
+	mirroring    uint8    // $D001
+	ntRAMSelect  uint8    // $D002: which value of bit 7 of $B000-$B003 picks VRAM
+	outerBank    uint8    // $D003
+
+	// Multiplier and accumulator
+	multiplicand uint8
+	multiplier   uint8
+	accumulator  uint8
+	testRegister uint8
+
+	// IRQ counter
+	irqEnabled   bool
+	irqPending   bool
+	irqMode      uint8 // $C001
+	irqPrescaler uint8
+	irqCounter   uint8
+	irqXOR       uint8 // $C006: XORed with values written to $C004/$C005
+
+	// Counting CPU cycles, the counter is brought up to date from the cycles
+	// passed when it is written or saved, and an event raises the IRQ when
+	// it wraps (see scheduleIRQ)
+	scheduler Scheduler
+	irqSynced uint64 // CPU cycle the counter was last brought up to date on
+	cancelIRQ func()
+
+	// Current banks: ROM offsets of the 8KB PRG windows at $6000 and $8000-
+	// $FFFF, and the 1KB CHR bank numbers of the eight CHR windows
+	prgRAMOffset int
+	prgOffsets   [4]int
+	chrBanks     [8]int
+}
+
+// NewMapper090 creates a J.Y. Company ASIC without extended nametable
+// control, as mapper 90 is
+func NewMapper090(cart *Cartridge) *Mapper090 {
+	return newJYMapper(&Mapper090{cart: cart})
+}
+
+// NewMapper209 creates a J.Y. Company ASIC whose nametables follow $B000-
+// $B007 when bit 5 of $D000 is set
+func NewMapper209(cart *Cartridge) *Mapper090 {
+	return newJYMapper(&Mapper090{cart: cart, nametablesByMode: true})
+}
+
+// NewMapper211 creates a J.Y. Company ASIC whose nametables always follow
+// $B000-$B007
+func NewMapper211(cart *Cartridge) *Mapper090 {
+	return newJYMapper(&Mapper090{cart: cart, nametablesAlways: true})
+}
+
+// newJYMapper maps the power-on banks of m. The nametables keep the
+// header's mirroring until the game picks them.
+func newJYMapper(m *Mapper090) *Mapper090 {
+	m.updatePRG()
+	m.updateCHR()
+	return m
+}
+
+// ReadExpansion reads the DIP switches at $5000 and the multiplier at
+// $5800-$5803
+func (m *Mapper090) ReadExpansion(address uint16) (uint8, bool) {
+	switch address & 0xF803 {
+	case 0x5000:
+		// Jumpers in bits 6-7, all open; some multicarts read them to pick a
+		// title
+		return 0, true
+	case 0x5800:
+		return uint8(uint16(m.multiplicand) * uint16(m.multiplier)), true
+	case 0x5801:
+		return uint8(uint16(m.multiplicand) * uint16(m.multiplier) >> 8), true
+	case 0x5802:
+		return m.accumulator, true
+	case 0x5803:
+		return m.testRegister, true
+	}
+	return 0, false
+}
+
+// WriteExpansion writes the multiplier's operands at $5800/$5801, adds to the
+// accumulator at $5802 and clears it at $5803
+func (m *Mapper090) WriteExpansion(address uint16, value uint8) {
+	switch address & 0xF803 {
+	case 0x5800:
+		m.multiplicand = value
+	case 0x5801:
+		m.multiplier = value
+	case 0x5802:
+		m.accumulator += value
+	case 0x5803:
+		m.accumulator = 0
+		m.testRegister = value
+	}
+}
+
+// ReadPRG reads from PRG ROM/RAM
+// Memory map:
+// 0x6000-0x7FFF: 8KB PRG RAM (SRAM), or a PRG ROM bank with $D000 bit 7
+// 0x8000-0xFFFF: four 8KB PRG ROM windows
+func (m *Mapper090) ReadPRG(address uint16) uint8 {
+	if offset, ok := m.PRGOffset(address); ok {
+		return m.cart.prgROM[offset]
+	}
+	if address >= 0x6000 && address < 0x8000 {
+		return m.cart.sram[address-0x6000]
+	}
+	return 0
+}
+
+// WritePRG writes to PRG RAM and the mapper registers, each group of which
+// is decoded by A12-A15 and the low address bits: $8000 PRG banks, $9000 and
+// $A000 the low and high bytes of the CHR banks, $B000 the nametable banks,
+// $C000 the IRQ counter and $D000 the modes.
+func (m *Mapper090) WritePRG(address uint16, value uint8) {
+	if address < 0x8000 {
+		if address >= 0x6000 && m.mode&jyModePRGAt6000 == 0 {
+			m.cart.writeSRAM(address-0x6000, value)
+		}
+		return
+	}
+
+	switch address & 0xF000 {
+	case 0x8000:
+		m.prgRegisters[address&3] = value & 0x7F
+	case 0x9000:
+		m.chrLow[address&7] = value
+	case 0xA000:
+		m.chrHigh[address&7] = value
+	case 0xB000:
+		if address&4 == 0 {
+			m.ntLow[address&3] = value
+		} else {
+			m.ntHigh[address&3] = value
+		}
+		m.updateNametables()
+		return
+	case 0xC000:
+		m.writeIRQ(address, value)
+		return
+	case 0xD000:
+		switch address & 3 {
+		case 0:
+			m.mode = value
+		case 1:
+			m.mirroring = value & 3
+		case 2:
+			m.ntRAMSelect = value & jyNametableRAMSelect
+		case 3:
+			m.outerBank = value
+		}
+	default:
+		return
+	}
+	m.updateBanks()
+}
+
+// writeIRQ writes the IRQ registers at $C000-$C007, and schedules the
+// counter's IRQ anew from them
+func (m *Mapper090) writeIRQ(address uint16, value uint8) {
+	m.syncIRQ()
+	defer m.scheduleIRQ()
+
+	switch address & 7 {
+	case 0:
+		m.irqEnabled = value&1 != 0
+		if !m.irqEnabled {
+			m.irqPending = false
+		}
+	case 1:
+		m.irqMode = value
+	case 2:
+		m.irqEnabled = false
+		m.irqPending = false
+	case 3:
+		m.irqEnabled = true
+	case 4:
+		m.irqPrescaler = value ^ m.irqXOR
+	case 5:
+		m.irqCounter = value ^ m.irqXOR
+	case 6:
+		m.irqXOR = value
+	}
+}
+
+// ReadCHR reads from CHR ROM/RAM
+func (m *Mapper090) ReadCHR(address uint16) uint8 {
+	if offset, ok := m.CHROffset(address); ok {
+		return m.cart.chrROM[offset]
+	}
+	return 0
+}
+
+// WriteCHR writes to CHR RAM
+func (m *Mapper090) WriteCHR(address uint16, value uint8) {
+	// Writes to CHR ROM are ignored
+	if offset, ok := m.CHROffset(address); ok && m.cart.hasCHRRAM {
+		m.cart.chrROM[offset] = value
+	}
+}
+
+// ReadNametable reads a nametable mapped to a CHR ROM bank, the 16-bit bank
+// number of which is in $B000-$B007
+func (m *Mapper090) ReadNametable(nametable int, offset uint16) uint8 {
+	if len(m.cart.chrROM) == 0 {
+		return 0
+	}
+	bank := int(m.ntLow[nametable]) | int(m.ntHigh[nametable])<<8
+	return m.cart.chrROM[(bank*0x400+int(offset&0x3FF))%len(m.cart.chrROM)]
+}
+
+// WriteNametable ignores writes to a nametable mapped to CHR ROM
+func (m *Mapper090) WriteNametable(nametable int, offset uint16, value uint8) {
+}
+
+// PRGOffset returns the PRG ROM offset of a CPU address ($8000-$FFFF, or
+// $6000-$7FFF when PRG ROM is mapped there)
+func (m *Mapper090) PRGOffset(address uint16) (int, bool) {
+	if len(m.cart.prgROM) == 0 {
+		return 0, false
+	}
+	switch {
+	case address >= 0x8000:
+		return m.prgOffsets[(address>>13)&3] + int(address&0x1FFF), true
+	case address >= 0x6000 && m.mode&jyModePRGAt6000 != 0:
+		return m.prgRAMOffset + int(address&0x1FFF), true
+	}
+	return 0, false
+}
+
+// CHROffset returns the CHR offset of a PPU pattern table address
+func (m *Mapper090) CHROffset(address uint16) (int, bool) {
+	if address >= 0x2000 || len(m.cart.chrROM) == 0 {
+		return 0, false
+	}
+	return (m.chrBanks[address>>10]*0x400 + int(address&0x3FF)) % len(m.cart.chrROM), true
+}
+
+// extendedNametables returns whether $B000-$B007 pick the nametables
+func (m *Mapper090) extendedNametables() bool {
+	return m.nametablesAlways || (m.nametablesByMode && m.mode&jyModeNametablesExt != 0)
+}
+
+// updateBanks works out the PRG and CHR windows and the nametables from the
+// registers
+func (m *Mapper090) updateBanks() {
+	m.updatePRG()
+	m.updateCHR()
+	m.updateNametables()
+}
+
+// updatePRG works out the PRG windows. Bank numbers are 8KB pages, to which
+// bits 1-2 of $D003 add an outer 512KB bank.
+func (m *Mapper090) updatePRG() {
+	regs := m.prgRegisters
+	if m.mode&3 == 3 {
+		for i, r := range regs {
+			regs[i] = reverseBits7(r)
+		}
+	}
+	reg := func(i int) int {
+		return int(regs[i])
+	}
+	lastFromReg := m.mode&jyModeLastPRGFromReg != 0
+
+	var pages [4]int
+	var ramPage int
+	switch m.mode & 3 {
+	case 0:
+		// 32KB
+		first := 0x3C
+		if lastFromReg {
+			first = reg(3) << 2
+		}
+		pages = [4]int{first, first + 1, first + 2, first + 3}
+		ramPage = reg(3)<<2 + 3
+	case 1:
+		// 16KB
+		last := 0x3E
+		if lastFromReg {
+			last = reg(3) << 1
+		}
+		pages = [4]int{reg(1) << 1, reg(1)<<1 + 1, last, last + 1}
+		ramPage = reg(3)<<1 + 1
+	default:
+		// 8KB
+		last := 0x3F
+		if lastFromReg {
+			last = reg(3)
+		}
+		pages = [4]int{reg(0), reg(1), reg(2), last}
+		ramPage = reg(3)
+	}
+
+	prgBanks := max(len(m.cart.prgROM)/0x2000, 1)
+	outer := int(m.outerBank&6) << 5
+	bank := func(page int) int {
+		return ((page&0x3F | outer) % prgBanks) * 0x2000
+	}
+	for i, page := range pages {
+		m.prgOffsets[i] = bank(page)
+	}
+	m.prgRAMOffset = bank(ramPage)
+}
+
+// reverseBits7 reverses the order of the low 7 bits of a PRG bank, for PRG
+// mode 3
+func reverseBits7(value uint8) uint8 {
+	var reversed uint8
+	for i := 0; i < 7; i++ {
+		if value&(1<<i) != 0 {
+			reversed |= 1 << (6 - i)
+		}
+	}
+	return reversed
+}
+
+// updateCHR works out the 1KB CHR banks from the registers of the CHR mode
+// in bits 3-4 of $D000: 8KB banks use register 0, 4KB ones registers 0 and 4,
+// 2KB ones the even registers and 1KB ones all eight.
+func (m *Mapper090) updateCHR() {
+	// log2 of the bank size in KB
+	shift := 3 - int(m.mode>>3&3)
+	bank := func(register int) int {
+		if m.outerBank&jyOuterCHRUnblocked == 0 {
+			// The low byte picks a bank in the 256KB block of $D003 bits 0
+			// and 3-4
+			block := int(m.outerBank&1 | m.outerBank&0x18>>2)
+			bits := 8 - shift
+			return int(m.chrLow[register])&(1<<bits-1) | block<<bits
+		}
+		return int(m.chrLow[register]) | int(m.chrHigh[register])<<8
+	}
+	for window := range m.chrBanks {
+		register := window >> shift << shift
+		m.chrBanks[window] = bank(register)<<shift | window&(1<<shift-1)
+	}
+}
+
+// updateNametables sends the mirroring of $D001 to the cartridge, or with
+// extended nametable control the page of each nametable: CHR ROM when $D000
+// bit 6 asks for it or bit 7 of its $B000-$B003 register differs from $D002
+// bit 7, and otherwise the VRAM page of its bit 0
+func (m *Mapper090) updateNametables() {
+	if !m.extendedNametables() {
+		m.cart.SetMirroring([4]MirrorMode{
+			MirrorVertical, MirrorHorizontal, MirrorSingleScreen0, MirrorSingleScreen1,
+		}[m.mirroring])
+		return
+	}
+
+	var pages [4]uint8
+	for i, low := range m.ntLow {
+		if m.mode&jyModeNametablesROM != 0 || low&jyNametableRAMSelect != m.ntRAMSelect {
+			pages[i] = NametablePageCHR
+		} else {
+			pages[i] = low & 1
+		}
+	}
+	m.cart.SetNametablePages(pages)
+}
+
+// SetScheduler schedules the IRQ counter's wrap, when it counts CPU cycles,
+// from the registers as they are
+func (m *Mapper090) SetScheduler(s Scheduler) {
+	m.scheduler = s
+	m.cancelIRQ = nil
+	m.irqSynced = s.GetCycleCount()
+	m.scheduleIRQ()
+}
+
+// IRQScheduled reports whether the IRQ counter's IRQ is left to its events,
+// which it is unless the counter counts A12 rises
+func (m *Mapper090) IRQScheduled() bool {
+	return m.irqMode&3 != jyIRQSourceA12
+}
+
+// syncIRQ clocks the IRQ counter for the CPU cycles passed since it last was,
+// when it counts them. The IRQ of a wrap is raised by its event, irqDue.
+func (m *Mapper090) syncIRQ() {
+	if m.scheduler == nil {
+		return
+	}
+	now := m.scheduler.GetCycleCount()
+	cycles := now - m.irqSynced
+	m.irqSynced = now
+	mask, direction, ok := m.irqClock(jyIRQSourceCPU)
+	if !ok {
+		return
+	}
+
+	period := uint64(mask) + 1
+	prescaler := uint64(m.irqPrescaler & mask)
+	if direction == jyIRQDirectionUp {
+		m.irqCounter += uint8((prescaler + cycles) / period)
+		prescaler = (prescaler + cycles) % period
+	} else {
+		m.irqCounter -= uint8((cycles + uint64(mask) - prescaler) / period)
+		prescaler = (prescaler + period - cycles%period) % period
+	}
+	m.irqPrescaler = m.irqPrescaler&^mask | uint8(prescaler)
+}
+
+// scheduleIRQ replaces the event raising the IRQ with one when the counter
+// next wraps, if it counts CPU cycles with the IRQ enabled. The counter must
+// be up to date.
+func (m *Mapper090) scheduleIRQ() {
+	if m.cancelIRQ != nil {
+		m.cancelIRQ()
+		m.cancelIRQ = nil
+	}
+	mask, direction, ok := m.irqClock(jyIRQSourceCPU)
+	if m.scheduler == nil || !ok || !m.irqEnabled {
+		return
+	}
+
+	// The prescaler wraps first, then once for each step the counter has
+	// left to wrap
+	period := uint64(mask) + 1
+	prescaler := uint64(m.irqPrescaler & mask)
+	var cycles uint64
+	if direction == jyIRQDirectionUp {
+		cycles = period - prescaler + uint64(0xFF-m.irqCounter)*period
+	} else {
+		cycles = prescaler + 1 + uint64(m.irqCounter)*period
+	}
+	m.cancelIRQ = m.scheduler.After(cycles, m.irqDue)
+}
+
+// irqDue raises the IRQ of the counter's wrap and schedules the next one
+func (m *Mapper090) irqDue() {
+	m.cancelIRQ = nil
+	m.syncIRQ()
+	m.irqPending = true
+	m.scheduleIRQ()
+}
+
+// A12Rise clocks the IRQ counter's prescaler when it counts A12 rises
+func (m *Mapper090) A12Rise() {
+	m.clockIRQ(jyIRQSourceA12)
+}
+
+// clockIRQ clocks the prescaler if the counter takes its clock from source.
+// Counting up, the counter is clocked when the prescaler wraps to zero and
+// raises the IRQ when it wraps to zero itself; counting down, both wrap to
+// all ones.
+func (m *Mapper090) clockIRQ(source uint8) {
+	mask, direction, ok := m.irqClock(source)
+	if !ok {
+		return
+	}
+
+	prescaler := m.irqPrescaler & mask
+	if direction == jyIRQDirectionUp {
+		prescaler = (prescaler + 1) & mask
+		m.irqPrescaler = m.irqPrescaler&^mask | prescaler
+		if prescaler != 0 {
+			return
+		}
+		m.irqCounter++
+		if m.irqCounter == 0 && m.irqEnabled {
+			m.irqPending = true
+		}
+		return
+	}
+
+	prescaler = (prescaler - 1) & mask
+	m.irqPrescaler = m.irqPrescaler&^mask | prescaler
+	if prescaler != mask {
+		return
+	}
+	m.irqCounter--
+	if m.irqCounter == 0xFF && m.irqEnabled {
+		m.irqPending = true
+	}
+}
+
+// irqClock returns the IRQ counter's prescaler mask and direction, and
+// whether it counts from source
+func (m *Mapper090) irqClock(source uint8) (mask, direction uint8, ok bool) {
+	direction = m.irqMode >> 6
+	if m.irqMode&3 != source || (direction != jyIRQDirectionUp && direction != jyIRQDirectionDown) {
+		return 0, 0, false
+	}
+	mask = 0xFF
+	if m.irqMode&jyIRQSmallPrescaler != 0 {
+		mask = 0x07
+	}
+	return mask, direction, true
+}
+
+// IRQ returns whether the IRQ counter's IRQ is raised
+func (m *Mapper090) IRQ() bool {
+	return m.irqPending
+}
+
+// SaveState writes the mapper registers, the multiplier and the IRQ counter
+func (m *Mapper090) SaveState(w *savestate.Writer) {
+	m.syncIRQ()
+	w.WriteTag("JYCO")
+	w.WriteBytes(m.prgRegisters[:])
+	w.WriteBytes(m.chrLow[:])
+	w.WriteBytes(m.chrHigh[:])
+	w.WriteBytes(m.ntLow[:])
+	w.WriteBytes(m.ntHigh[:])
+	w.WriteU8(m.mode)
+	w.WriteU8(m.mirroring)
+	w.WriteU8(m.ntRAMSelect)
+	w.WriteU8(m.outerBank)
+	w.WriteU8(m.multiplicand)
+	w.WriteU8(m.multiplier)
+	w.WriteU8(m.accumulator)
+	w.WriteU8(m.testRegister)
+	w.WriteBool(m.irqEnabled)
+	w.WriteBool(m.irqPending)
+	w.WriteU8(m.irqMode)
+	w.WriteU8(m.irqPrescaler)
+	w.WriteU8(m.irqCounter)
+	w.WriteU8(m.irqXOR)
+}
+
+// LoadState restores state written by SaveState
+func (m *Mapper090) LoadState(r *savestate.Reader) error {
+	r.ExpectTag("JYCO")
+	r.ReadBytesInto(m.prgRegisters[:])
+	r.ReadBytesInto(m.chrLow[:])
+	r.ReadBytesInto(m.chrHigh[:])
+	r.ReadBytesInto(m.ntLow[:])
+	r.ReadBytesInto(m.ntHigh[:])
+	m.mode = r.ReadU8()
+	m.mirroring = r.ReadU8() & 3
+	m.ntRAMSelect = r.ReadU8() & jyNametableRAMSelect
+	m.outerBank = r.ReadU8()
+	m.multiplicand = r.ReadU8()
+	m.multiplier = r.ReadU8()
+	m.accumulator = r.ReadU8()
+	m.testRegister = r.ReadU8()
+	m.irqEnabled = r.ReadBool()
+	m.irqPending = r.ReadBool()
+	m.irqMode = r.ReadU8()
+	m.irqPrescaler = r.ReadU8()
+	m.irqCounter = r.ReadU8()
+	m.irqXOR = r.ReadU8()
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("J.Y. Company state: %v", err)
+	}
+
+	// The cartridge's state has the mirroring; pages are picked anew
+	m.updatePRG()
+	m.updateCHR()
+	if m.extendedNametables() {
+		m.updateNametables()
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package cartridge
+
+import (
+	"testing"
+
+	"gones/internal/savestate"
+)
+
+func TestMapper090Multiplier(t *testing.T) {
+	m := NewMapper090(newBankedCartridge(90, 8, 8))
+	m.WriteExpansion(0x5800, 200)
+	m.WriteExpansion(0x5801, 100)
+	low, _ := m.ReadExpansion(0x5800)
+	high, _ := m.ReadExpansion(0x5801)
+	if product := uint16(high)<<8 | uint16(low); product != 20000 {
+		t.Errorf("200 x 100 = %d, want 20000", product)
+	}
+
+	m.WriteExpansion(0x5802, 0x80)
+	m.WriteExpansion(0x5802, 0x90)
+	if got, _ := m.ReadExpansion(0x5802); got != 0x10 {
+		t.Errorf("Accumulator $%02X after adding $80 and $90, want $10", got)
+	}
+	m.WriteExpansion(0x5803, 0x5A)
+	if got, _ := m.ReadExpansion(0x5802); got != 0 {
+		t.Errorf("Accumulator $%02X after $5803, want 0", got)
+	}
+	if _, ok := m.ReadExpansion(0x4800); ok {
+		t.Error("$4800 is mapped")
+	}
+}
+
+func TestMapper090PRGBanks(t *testing.T) {
+	cart := newBankedCartridge(90, 64, 8)
+	m := NewMapper090(cart)
+	for i, bank := range []uint8{4, 5, 6, 7} {
+		m.WritePRG(0x8000+uint16(i), bank)
+	}
+
+	for _, tc := range []struct {
+		mode uint8
+		want [4]uint8
+	}{
+		{0x00, [4]uint8{60, 61, 62, 63}},
+		{0x04, [4]uint8{28, 29, 30, 31}},
+		{0x01, [4]uint8{10, 11, 62, 63}},
+		{0x02, [4]uint8{4, 5, 6, 63}},
+		{0x06, [4]uint8{4, 5, 6, 7}},
+		// Mode 3 reverses the 7 bits: 4 is $10, 5 $50 and 6 $30, which
+		// wrap to 16, 16 and 48 in the 64 banks
+		{0x03, [4]uint8{16, 16, 48, 63}},
+	} {
+		m.WritePRG(0xD000, tc.mode)
+		for i, want := range tc.want {
+			address := 0x8000 + uint16(i)*0x2000
+			if got := m.ReadPRG(address + 0x42); got != want {
+				t.Errorf("PRG mode $%02X: $%04X reads bank %d, want %d", tc.mode, address, got, want)
+			}
+		}
+	}
+
+	// $6000 is PRG RAM unless $D000 bit 7 maps a ROM bank there
+	m.WritePRG(0xD000, 0x02)
+	m.WritePRG(0x6000, 0x99)
+	if got := m.ReadPRG(0x6000); got != 0x99 {
+		t.Errorf("PRG RAM reads $%02X, want $99", got)
+	}
+	m.WritePRG(0xD000, 0x82)
+	if got := m.ReadPRG(0x6000); got != 7 {
+		t.Errorf("$6000 reads bank %d with $D000 bit 7, want 7", got)
+	}
+}
+
+func TestMapper090CHRBanks(t *testing.T) {
+	cart := newBankedCartridge(90, 4, 256)
+	m := NewMapper090(cart)
+	for i := uint16(0); i < 8; i++ {
+		m.WritePRG(0x9000+i, uint8(0x10+i))
+	}
+
+	for _, tc := range []struct {
+		mode uint8
+		want [8]uint8
+	}{
+		{0x00, [8]uint8{0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87}},
+		{0x08, [8]uint8{0x40, 0x41, 0x42, 0x43, 0x50, 0x51, 0x52, 0x53}},
+		{0x10, [8]uint8{0x20, 0x21, 0x24, 0x25, 0x28, 0x29, 0x2C, 0x2D}},
+		{0x18, [8]uint8{0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17}},
+	} {
+		m.WritePRG(0xD000, tc.mode)
+		for i, want := range tc.want {
+			address := uint16(i) * 0x400
+			if got := m.ReadCHR(address); got != want {
+				t.Errorf("CHR mode $%02X: $%04X reads bank $%02X, want $%02X", tc.mode, address, got, want)
+			}
+		}
+	}
+
+	// The high byte counts once $D003 lifts the 256KB blocks
+	m.WritePRG(0xA000, 1)
+	if got := m.ReadCHR(0x0000); got != 0x10 {
+		t.Errorf("Blocked CHR bank reads $%02X, want $10", got)
+	}
+	m.WritePRG(0xD003, 0x20)
+	if offset, _ := m.CHROffset(0x0000); offset != 0x110*0x400%len(cart.chrROM) {
+		t.Errorf("Unblocked CHR bank $110 at offset $%X", offset)
+	}
+}
+
+// testScheduler is a scheduler whose clock the test moves on, a cycle at a
+// time
+type testScheduler struct {
+	cycles uint64
+	events []*testEvent
+}
+
+type testEvent struct {
+	due  uint64
+	fire func()
+}
+
+func (s *testScheduler) After(cpuCycles uint64, fire func()) func() {
+	e := &testEvent{due: s.cycles + cpuCycles, fire: fire}
+	s.events = append(s.events, e)
+	return func() { e.fire = nil }
+}
+
+func (s *testScheduler) GetCycleCount() uint64 { return s.cycles }
+
+// step moves the clock on a cycle and runs the events due
+func (s *testScheduler) step() {
+	s.cycles++
+	for _, e := range s.events {
+		if e.fire != nil && e.due <= s.cycles {
+			fire := e.fire
+			e.fire = nil
+			fire()
+		}
+	}
+}
+
+func TestMapper090IRQ(t *testing.T) {
+	m := NewMapper090(newBankedCartridge(90, 4, 8))
+	clock := &testScheduler{}
+	m.SetScheduler(clock)
+	m.WritePRG(0xC001, 0x80|jyIRQSmallPrescaler|jyIRQSourceCPU) // Down, 3-bit prescaler
+	m.WritePRG(0xC004, 0)
+	m.WritePRG(0xC005, 1)
+	m.WritePRG(0xC003, 0)
+
+	// The prescaler wraps to 7 on the first cycle and every 8 after it; the
+	// counter goes 1, 0, $FF
+	for cycle := 1; cycle <= 9; cycle++ {
+		clock.step()
+		if raised := m.IRQ(); raised != (cycle == 9) {
+			t.Fatalf("After %d cycles, IRQ = %v", cycle, raised)
+		}
+	}
+	m.WritePRG(0xC002, 0)
+	if m.IRQ() {
+		t.Error("IRQ still raised after $C002")
+	}
+
+	// Counting up A12 rises with the values XORed; CPU cycles are ignored
+	m.WritePRG(0xC001, 0x40|jyIRQSmallPrescaler|jyIRQSourceA12)
+	m.WritePRG(0xC006, 0xFF)
+	m.WritePRG(0xC004, 0xFF^6)
+	m.WritePRG(0xC005, 0xFF^0xFF)
+	m.WritePRG(0xC000, 1)
+	if m.IRQScheduled() {
+		t.Error("IRQ counting A12 rises reported as scheduled")
+	}
+	clock.step()
+	m.A12Rise()
+	if m.IRQ() {
+		t.Fatal("IRQ raised before the prescaler wrapped")
+	}
+	m.A12Rise()
+	if !m.IRQ() {
+		t.Error("IRQ not raised when the counter wrapped to 0")
+	}
+}
+
+func TestMapper090IRQSchedule(t *testing.T) {
+	// Each counter mode against one clocked every cycle, as the ASIC is
+	modes := []uint8{
+		0x40 | jyIRQSourceCPU,
+		0x40 | jyIRQSmallPrescaler | jyIRQSourceCPU,
+		0x80 | jyIRQSourceCPU,
+		0x80 | jyIRQSmallPrescaler | jyIRQSourceCPU,
+	}
+	for _, mode := range modes {
+		m := NewMapper090(newBankedCartridge(90, 4, 8))
+		clock := &testScheduler{}
+		m.SetScheduler(clock)
+		ref := NewMapper090(newBankedCartridge(90, 4, 8))
+		write := func(address uint16, value uint8) {
+			m.WritePRG(address, value)
+			ref.WritePRG(address, value)
+		}
+
+		write(0xC001, mode)
+		write(0xC004, 0x25)
+		write(0xC005, 0xFE)
+		write(0xC003, 0)
+		for cycle := 1; cycle <= 20000; cycle++ {
+			clock.step()
+			ref.clockIRQ(jyIRQSourceCPU)
+			switch cycle {
+			case 700:
+				write(0xC005, 3) // Rescheduled from a new count
+			case 5000:
+				write(0xC000, 0) // Acknowledged and disabled, still counting
+			case 9000:
+				write(0xC003, 0)
+			}
+			if m.IRQ() != ref.IRQ() {
+				t.Fatalf("Mode $%02X: IRQ = %v after %d cycles, want %v", mode, m.IRQ(), cycle, ref.IRQ())
+			}
+			// Brought up to date over every number of cycles in turn
+			if cycle%97 == 0 {
+				m.syncIRQ()
+				if m.irqPrescaler != ref.irqPrescaler || m.irqCounter != ref.irqCounter {
+					t.Fatalf("Mode $%02X: prescaler $%02X and counter $%02X after %d cycles, want $%02X and $%02X",
+						mode, m.irqPrescaler, m.irqCounter, cycle, ref.irqPrescaler, ref.irqCounter)
+				}
+			}
+			if m.IRQ() {
+				write(0xC002, 0)
+				write(0xC003, 0)
+			}
+		}
+
+		w := savestate.NewWriter(64)
+		m.SaveState(w)
+		if m.irqPrescaler != ref.irqPrescaler || m.irqCounter != ref.irqCounter {
+			t.Errorf("Mode $%02X: saved prescaler $%02X and counter $%02X, want $%02X and $%02X",
+				mode, m.irqPrescaler, m.irqCounter, ref.irqPrescaler, ref.irqCounter)
+		}
+		if !m.IRQScheduled() {
+			t.Errorf("Mode $%02X: IRQ counting CPU cycles reported as unscheduled", mode)
+		}
+	}
+}
+
+func TestMapper211Nametables(t *testing.T) {
+	cart := newBankedCartridge(211, 4, 16)
+	m := NewMapper211(cart)
+
+	var pages [4]uint8
+	cart.SetNametableCallback(func(p [4]uint8) {
+		pages = p
+	})
+
+	// Bit 7 differing from $D002 picks CHR ROM, otherwise bit 0 a VRAM page
+	m.WritePRG(0xB000, 0x00)
+	m.WritePRG(0xB001, 0x01)
+	m.WritePRG(0xB002, 0x85)
+	m.WritePRG(0xB003, 0x80)
+	if pages != [4]uint8{0, 1, NametablePageCHR, NametablePageCHR} {
+		t.Errorf("Pages %v, want [0 1 CHR CHR]", pages)
+	}
+	if got := m.ReadNametable(2, 0x10); got != 5 {
+		t.Errorf("Nametable 2 reads CHR bank %d, want 5 of $85 in 16", got)
+	}
+
+	m.WritePRG(0xD002, 0x80)
+	if pages != [4]uint8{NametablePageCHR, NametablePageCHR, 1, 0} {
+		t.Errorf("Pages %v with $D002 bit 7, want [CHR CHR 1 0]", pages)
+	}
+	m.WritePRG(0xD000, jyModeNametablesROM)
+	if pages != [4]uint8{NametablePageCHR, NametablePageCHR, NametablePageCHR, NametablePageCHR} {
+		t.Errorf("Pages %v with $D000 bit 6, want all CHR", pages)
+	}
+}
+
+func TestMapper090Mirroring(t *testing.T) {
+	// Mapper 90 ignores the nametable registers, mapper 209 until $D000 bit 5
+	for _, m := range []*Mapper090{
+		NewMapper090(newBankedCartridge(90, 4, 8)),
+		NewMapper209(newBankedCartridge(209, 4, 8)),
+	} {
+		m.WritePRG(0xB000, 0x80)
+		m.WritePRG(0xD001, 1)
+		if m.cart.pagesMapped || m.cart.GetMirrorMode() != MirrorHorizontal {
+			t.Errorf("Mapper %d: $D001 = 1 gave mirroring %d, want horizontal", m.cart.mapperID, m.cart.GetMirrorMode())
+		}
+		m.WritePRG(0xD001, 3)
+		if m.cart.GetMirrorMode() != MirrorSingleScreen1 {
+			t.Errorf("Mapper %d: $D001 = 3 gave mirroring %d, want single screen 1", m.cart.mapperID, m.cart.GetMirrorMode())
+		}
+	}
+
+	m := NewMapper209(newBankedCartridge(209, 4, 8))
+	m.WritePRG(0xB000, 0x80)
+	m.WritePRG(0xD000, jyModeNametablesExt)
+	if !m.cart.pagesMapped || m.cart.pages[0] != NametablePageCHR {
+		t.Errorf("Mapper 209: pages %v with $D000 bit 5, want CHR ROM at nametable 0", m.cart.pages)
+	}
+}
+
+func TestMapper090State(t *testing.T) {
+	cart := newBankedCartridge(90, 64, 64)
+	m := NewMapper090(cart)
+	m.WritePRG(0xD000, 0x02)
+	m.WritePRG(0x8001, 9)
+	m.WritePRG(0x9002, 33)
+	m.WritePRG(0xD000, 0x1A)
+	m.WriteExpansion(0x5802, 7)
+	m.WritePRG(0xC005, 12)
+
+	w := savestate.NewWriter(64)
+	m.SaveState(w)
+
+	restored := NewMapper090(cart)
+	if err := restored.LoadState(savestate.NewReader(w.Bytes())); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if restored.ReadPRG(0xA000) != 9 || restored.ReadCHR(0x0800) != 33 {
+		t.Errorf("Restored banks read PRG %d and CHR %d, want 9 and 33",
+			restored.ReadPRG(0xA000), restored.ReadCHR(0x0800))
+	}
+	if accumulator, _ := restored.ReadExpansion(0x5802); accumulator != 7 || restored.irqCounter != 12 {
+		t.Errorf("Restored accumulator %d and IRQ counter %d, want 7 and 12", accumulator, restored.irqCounter)
+	}
+}
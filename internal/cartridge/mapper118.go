@@ -0,0 +1,26 @@
+// Package cartridge implements TxSROM (mapper 118), the MMC3 board with
+// mapper-controlled nametables.
+package cartridge
+
+// NewMapper118 creates an MMC3 wired as TxSROM (TKSROM, TLSROM), used by
+// Armadillo and Pro Sport Hockey. Its CHR A17 output, bit 7 of a CHR bank,
+// drives nametable A10 instead of the MMC3's mirroring: each nametable is on
+// the page of the bank of the pattern table window at the same address
+// ($2000-$23FF uses $0000-$03FF's). With the 2KB banks at $0000 this puts
+// nametables 0-1 on the page of R0 and 2-3 on that of R1; with CHR A12
+// inversion R2-R5 pick a page for one nametable each.
+func NewMapper118(cart *Cartridge) *Mapper004 {
+	m := &Mapper004{cart: cart, nametableBanks: true}
+	m.updateBanks()
+	return m
+}
+
+// updateNametables sends the nametable pages picked by the CHR banks of the
+// $0000-$0FFF windows to the cartridge
+func (m *Mapper004) updateNametables() {
+	var pages [4]uint8
+	for i := range pages {
+		pages[i] = uint8(m.chrBanks[i]>>7) & 1
+	}
+	m.cart.SetNametablePages(pages)
+}
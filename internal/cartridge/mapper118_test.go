@@ -0,0 +1,46 @@
+package cartridge
+
+import "testing"
+
+func TestMapper118NametablePages(t *testing.T) {
+	cart := newBankedCartridge(118, 8, 128)
+	m := NewMapper118(cart)
+
+	var pages [4]uint8
+	calls := 0
+	cart.SetNametableCallback(func(p [4]uint8) {
+		pages = p
+		calls++
+	})
+	if calls != 1 || pages != [4]uint8{} {
+		t.Fatalf("Callback got %v in %d calls at power on, want [0 0 0 0] once", pages, calls)
+	}
+
+	// 2KB banks at $0000: R0 picks nametables 0-1, R1 nametables 2-3
+	setMMC3Bank(m, 0, 0, 0x80)
+	setMMC3Bank(m, 0, 1, 0x02)
+	if pages != [4]uint8{1, 1, 0, 0} {
+		t.Errorf("Pages %v with R0 $80 and R1 $02, want [1 1 0 0]", pages)
+	}
+
+	// With CHR A12 inversion R2-R5 pick one nametable each
+	setMMC3Bank(m, 0x80, 2, 0x80)
+	setMMC3Bank(m, 0x80, 3, 0x00)
+	setMMC3Bank(m, 0x80, 4, 0x00)
+	setMMC3Bank(m, 0x80, 5, 0x85)
+	if pages != [4]uint8{1, 0, 0, 1} {
+		t.Errorf("Pages %v with inverted CHR banks, want [1 0 0 1]", pages)
+	}
+
+	// Bit 7 is not a CHR ROM address line
+	if got := m.ReadCHR(0x0C00); got != 5 {
+		t.Errorf("Bank $85 reads CHR bank %d, want 5", got)
+	}
+
+	// The MMC3's mirroring register is not connected
+	cart.mirror = MirrorVertical
+	m.WritePRG(0xA000, 1)
+	if cart.GetMirrorMode() != MirrorVertical {
+		t.Error("$A000 changed the mirroring of a TxSROM board")
+	}
+}
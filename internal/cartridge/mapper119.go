@@ -0,0 +1,30 @@
+// Package cartridge implements TQROM (mapper 119), the MMC3 board with both
+// CHR ROM and CHR RAM.
+package cartridge
+
+// tqromCHRRAMSize is the size of TQROM's CHR RAM
+const tqromCHRRAMSize = 0x2000
+
+// NewMapper119 creates an MMC3 wired as TQROM, used by High Speed and
+// Pin*Bot. Next to up to 64KB of CHR ROM it has 8KB of CHR RAM, which a CHR
+// bank picks with bit 6 set; bits 0-2 then pick the 1KB of RAM. Games draw
+// text or animated tiles into the RAM while the rest comes from ROM.
+func NewMapper119(cart *Cartridge) *Mapper004 {
+	m := &Mapper004{cart: cart, chrRAM: make([]uint8, tqromCHRRAMSize)}
+	m.updateBanks()
+	return m
+}
+
+// chrRAMOffset returns TQROM's CHR RAM and the offset in it of a pattern
+// table address, and false when the address is in a CHR ROM bank or the
+// board has no CHR RAM of its own
+func (m *Mapper004) chrRAMOffset(address uint16) ([]uint8, int, bool) {
+	if m.chrRAM == nil {
+		return nil, 0, false
+	}
+	bank := m.chrBanks[address>>10]
+	if bank&0x40 == 0 {
+		return nil, 0, false
+	}
+	return m.chrRAM, (bank&7)*0x400 + int(address&0x3FF), true
+}
@@ -0,0 +1,50 @@
+package cartridge
+
+import (
+	"testing"
+
+	"gones/internal/savestate"
+)
+
+func TestMapper119CHRRAM(t *testing.T) {
+	cart := newBankedCartridge(119, 8, 64)
+	m := NewMapper119(cart)
+
+	// $0000-$07FF from ROM bank 2-3, $1000 and $1400 from RAM banks 1 and 2
+	setMMC3Bank(m, 0, 0, 2)
+	setMMC3Bank(m, 0, 2, 0x41)
+	setMMC3Bank(m, 0, 3, 0x42)
+
+	m.WriteCHR(0x1005, 0xAB)
+	m.WriteCHR(0x1405, 0xCD)
+	if got := m.ReadCHR(0x1005); got != 0xAB {
+		t.Errorf("CHR RAM bank 1 reads $%02X, want $AB", got)
+	}
+	if got := m.ReadCHR(0x1405); got != 0xCD {
+		t.Errorf("CHR RAM bank 2 reads $%02X, want $CD", got)
+	}
+
+	// ROM banks stay read only, and bits 3-5 do not leave the 8KB of RAM
+	m.WriteCHR(0x0005, 0xEF)
+	if got := m.ReadCHR(0x0005); got != 2 {
+		t.Errorf("CHR ROM bank 2 reads $%02X after a write, want $02", got)
+	}
+	setMMC3Bank(m, 0, 4, 0x79)
+	if got := m.ReadCHR(0x1805); got != 0xAB {
+		t.Errorf("Bank $79 reads $%02X, want RAM bank 1's $AB", got)
+	}
+	if _, ok := m.CHROffset(0x1005); ok {
+		t.Error("CHROffset reported a ROM offset for a RAM bank")
+	}
+
+	// The RAM is part of the mapper's state
+	w := savestate.NewWriter(tqromCHRRAMSize + 64)
+	m.SaveState(w)
+	restored := NewMapper119(cart)
+	if err := restored.LoadState(savestate.NewReader(w.Bytes())); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if got := restored.ReadCHR(0x1405); got != 0xCD {
+		t.Errorf("Restored CHR RAM reads $%02X, want $CD", got)
+	}
+}
@@ -0,0 +1,209 @@
+// Package cartridge implements the Nanjing FC-001 (mapper 163).
+package cartridge
+
+import (
+	"fmt"
+
+	"gones/internal/savestate"
+)
+
+// Nanjing register bits
+const (
+	nanjingCHRAutoSwitch = 0x80 // $5000: switch CHR RAM halves mid-frame
+	nanjingProtectBank   = 6    // Written to $5100, maps PRG bank 3
+)
+
+// Mapper163 implements the Nanjing FC-001 (mapper 163), used by Nanjing's
+// Chinese RPGs and ports. It supports:
+// - 32KB PRG ROM banks picked by $5000 (low bits) and $5200 (high bits)
+// - 8KB PRG RAM at $6000-$7FFF (optionally battery-backed)
+// - 8KB CHR RAM, split between the pattern tables as usual, or with $5000
+// bit 7 switched by the PPU: both tables use the first 4KB from the top of
+// the frame and the second 4KB from scanline 128, giving the screen's lower
+// half tiles of its own for text boxes
+// - The protection registers games check at $5100, $5101 and $5500
+type Mapper163 struct {
+	cart *Cartridge
+
+	registers [4]uint8 // $5000, $5100, $5200 and $5300
+	prgBank   int      // 32KB bank at $8000, usually from $5000/$5200
+	chrPages  [2]int   // 4KB CHR page of each pattern table
+
+	// Protection: $5101 going from non-zero to zero toggles what $5500 reads
+	strobe  uint8
+	trigger bool
+
+	scanline int // Current PPU scanline, for $5000 writes
+}
+
+// NewMapper163 creates a new Nanjing mapper. It powers on with $5000 all
+// ones, as the games expect.
+func NewMapper163(cart *Cartridge) *Mapper163 {
+	m := &Mapper163{cart: cart, strobe: 1, chrPages: [2]int{0, 1}}
+	m.registers[0] = 0xFF
+	m.updatePRG()
+	return m
+}
+
+// ReadExpansion reads the protection registers: $5100 reads the registers
+// combined and $5500 the toggle of $5101. The rest of $5000-$5FFF reads 4.
+func (m *Mapper163) ReadExpansion(address uint16) (uint8, bool) {
+	if address < 0x5000 {
+		return 0, false
+	}
+	r := m.registers
+	switch address & 0x7700 {
+	case 0x5100:
+		return r[3] | r[2] | r[0] | (r[1] ^ 0xFF), true
+	case 0x5500:
+		if m.trigger {
+			return r[3] | r[0], true
+		}
+		return 0, true
+	}
+	return 4, true
+}
+
+// WriteExpansion writes the registers at $5000-$5FFF, decoded by A8-A9
+func (m *Mapper163) WriteExpansion(address uint16, value uint8) {
+	switch {
+	case address < 0x5000:
+		return
+	case address == 0x5101:
+		if m.strobe != 0 && value == 0 {
+			m.trigger = !m.trigger
+		}
+		m.strobe = value
+		return
+	case address == 0x5100 && value == nanjingProtectBank:
+		m.prgBank = 3
+		return
+	}
+
+	register := (address >> 8) & 3
+	m.registers[register] = value
+	switch register {
+	case 0:
+		// Turning the switching off in the upper half of the frame restores
+		// the usual split
+		if value&nanjingCHRAutoSwitch == 0 && m.scanline < 128 {
+			m.chrPages = [2]int{0, 1}
+		}
+		m.updatePRG()
+	case 1, 2:
+		m.updatePRG()
+	}
+}
+
+// ReadPRG reads from PRG ROM/RAM
+// Memory map:
+// 0x6000-0x7FFF: 8KB PRG RAM (SRAM)
+// 0x8000-0xFFFF: 32KB PRG ROM bank
+func (m *Mapper163) ReadPRG(address uint16) uint8 {
+	if offset, ok := m.PRGOffset(address); ok {
+		return m.cart.prgROM[offset]
+	}
+	if address >= 0x6000 && address < 0x8000 {
+		return m.cart.sram[address-0x6000]
+	}
+	return 0
+}
+
+// WritePRG writes to PRG RAM. The registers are all in the expansion area.
+func (m *Mapper163) WritePRG(address uint16, value uint8) {
+	if address >= 0x6000 && address < 0x8000 {
+		m.cart.writeSRAM(address-0x6000, value)
+	}
+}
+
+// ReadCHR reads from CHR RAM
+func (m *Mapper163) ReadCHR(address uint16) uint8 {
+	if offset, ok := m.chrOffset(address); ok {
+		return m.cart.chrROM[offset]
+	}
+	return 0
+}
+
+// WriteCHR writes to CHR RAM
+func (m *Mapper163) WriteCHR(address uint16, value uint8) {
+	if offset, ok := m.chrOffset(address); ok && m.cart.hasCHRRAM {
+		m.cart.chrROM[offset] = value
+	}
+}
+
+// PRGOffset returns the PRG ROM offset of a CPU address ($8000-$FFFF)
+func (m *Mapper163) PRGOffset(address uint16) (int, bool) {
+	if address < 0x8000 || len(m.cart.prgROM) == 0 {
+		return 0, false
+	}
+	return m.prgBank*0x8000 + int(address&0x7FFF), true
+}
+
+// CHROffset returns the CHR ROM offset of a PPU pattern table address, for
+// the rare board with CHR ROM
+func (m *Mapper163) CHROffset(address uint16) (int, bool) {
+	return m.chrOffset(address)
+}
+
+// chrOffset returns the offset in CHR memory of a pattern table address
+func (m *Mapper163) chrOffset(address uint16) (int, bool) {
+	if address >= 0x2000 || len(m.cart.chrROM) == 0 {
+		return 0, false
+	}
+	return (m.chrPages[address>>12]*0x1000 + int(address&0xFFF)) % len(m.cart.chrROM), true
+}
+
+// updatePRG works out the 32KB PRG bank from $5000 and $5200
+func (m *Mapper163) updatePRG() {
+	prgBanks := max(len(m.cart.prgROM)/0x8000, 1)
+	m.prgBank = (int(m.registers[2])<<4 | int(m.registers[0]&0x0F)) % prgBanks
+}
+
+// StartScanline switches both pattern tables to the second 4KB of CHR RAM
+// at scanline 128 and back to the first at 240, when $5000 bit 7 asks for
+// it and the PPU is rendering
+func (m *Mapper163) StartScanline(scanline int, rendering bool) {
+	m.scanline = scanline
+	if m.registers[0]&nanjingCHRAutoSwitch == 0 || !rendering {
+		return
+	}
+	switch scanline {
+	case 128:
+		m.chrPages = [2]int{1, 1}
+	case 240:
+		m.chrPages = [2]int{0, 0}
+	}
+}
+
+// SaveState writes the registers, the protection toggle and the CHR pages
+func (m *Mapper163) SaveState(w *savestate.Writer) {
+	w.WriteTag("NJNG")
+	w.WriteBytes(m.registers[:])
+	w.WriteInt(m.prgBank)
+	w.WriteU8(uint8(m.chrPages[0]))
+	w.WriteU8(uint8(m.chrPages[1]))
+	w.WriteU8(m.strobe)
+	w.WriteBool(m.trigger)
+	w.WriteInt(m.scanline)
+}
+
+// LoadState restores state written by SaveState
+func (m *Mapper163) LoadState(r *savestate.Reader) error {
+	r.ExpectTag("NJNG")
+	r.ReadBytesInto(m.registers[:])
+	prgBank := r.ReadInt()
+	m.chrPages[0] = int(r.ReadU8() & 1)
+	m.chrPages[1] = int(r.ReadU8() & 1)
+	m.strobe = r.ReadU8()
+	m.trigger = r.ReadBool()
+	m.scanline = r.ReadInt()
+	if prgBank < 0 {
+		r.Fail(fmt.Errorf("PRG bank %d", prgBank))
+	}
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("Nanjing state: %v", err)
+	}
+
+	m.prgBank = prgBank % max(len(m.cart.prgROM)/0x8000, 1)
+	return nil
+}
@@ -0,0 +1,131 @@
+package cartridge
+
+import (
+	"testing"
+
+	"gones/internal/savestate"
+)
+
+// newNanjingCartridge returns a mapper 163 cartridge with prgBanks 32KB PRG
+// ROM banks, every byte of a bank holding its number, and 8KB of CHR RAM
+func newNanjingCartridge(prgBanks int) *Cartridge {
+	cart := &Cartridge{
+		prgROM:    make([]uint8, prgBanks*0x8000),
+		chrROM:    make([]uint8, 0x2000),
+		hasCHRRAM: true,
+		mapperID:  163,
+	}
+	for i := range cart.prgROM {
+		cart.prgROM[i] = uint8(i / 0x8000)
+	}
+	return cart
+}
+
+func TestMapper163PRGBanks(t *testing.T) {
+	m := NewMapper163(newNanjingCartridge(32))
+	if got := m.ReadPRG(0x8000); got != 15 {
+		t.Errorf("Power-on bank %d, want 15", got)
+	}
+
+	m.WriteExpansion(0x5000, 0x03)
+	m.WriteExpansion(0x5200, 0x01)
+	if got := m.ReadPRG(0xFFFF); got != 19 {
+		t.Errorf("$5000 = 3 and $5200 = 1 map bank %d, want 19", got)
+	}
+
+	// Writing 6 to $5100 maps bank 3 until the next bank write
+	m.WriteExpansion(0x5100, 6)
+	if got := m.ReadPRG(0x8000); got != 3 {
+		t.Errorf("$5100 = 6 maps bank %d, want 3", got)
+	}
+	m.WriteExpansion(0x5000, 0x03)
+	if got := m.ReadPRG(0x8000); got != 19 {
+		t.Errorf("Bank %d after $5000, want 19", got)
+	}
+}
+
+func TestMapper163Protection(t *testing.T) {
+	m := NewMapper163(newNanjingCartridge(2))
+	m.WriteExpansion(0x5000, 0x10)
+	m.WriteExpansion(0x5300, 0x04)
+	if got, _ := m.ReadExpansion(0x5500); got != 0 {
+		t.Errorf("$5500 reads $%02X before the toggle, want 0", got)
+	}
+
+	// $5101 going from non-zero to zero toggles $5500
+	m.WriteExpansion(0x5101, 1)
+	m.WriteExpansion(0x5101, 0)
+	if got, _ := m.ReadExpansion(0x5500); got != 0x14 {
+		t.Errorf("$5500 reads $%02X after the toggle, want $14", got)
+	}
+	m.WriteExpansion(0x5101, 0)
+	if got, _ := m.ReadExpansion(0x5500); got != 0x14 {
+		t.Errorf("$5500 reads $%02X after writing 0 twice, want $14", got)
+	}
+
+	m.WriteExpansion(0x5100, 0xF0)
+	if got, _ := m.ReadExpansion(0x5100); got != 0x1F {
+		t.Errorf("$5100 reads $%02X, want $1F", got)
+	}
+	if _, ok := m.ReadExpansion(0x4800); ok {
+		t.Error("$4800 is mapped")
+	}
+}
+
+func TestMapper163CHRSwitching(t *testing.T) {
+	m := NewMapper163(newNanjingCartridge(2))
+	m.WriteCHR(0x0000, 0x11)
+	m.WriteCHR(0x1000, 0x22)
+
+	// Switching on: the top of the frame uses the first 4KB for both tables
+	// and scanline 128 on the second
+	m.WriteExpansion(0x5000, nanjingCHRAutoSwitch)
+	for scanline := -1; scanline < 128; scanline++ {
+		m.StartScanline(scanline, true)
+	}
+	if m.ReadCHR(0x1000) != 0x22 {
+		t.Error("Tables switched before scanline 128 with the first write")
+	}
+	m.StartScanline(128, true)
+	if m.ReadCHR(0x0000) != 0x22 || m.ReadCHR(0x1000) != 0x22 {
+		t.Error("Scanline 128 did not put both tables on the second 4KB")
+	}
+	m.StartScanline(240, true)
+	if m.ReadCHR(0x0000) != 0x11 || m.ReadCHR(0x1000) != 0x11 {
+		t.Error("Scanline 240 did not put both tables on the first 4KB")
+	}
+
+	// Nothing switches with rendering off
+	m.StartScanline(128, false)
+	if m.ReadCHR(0x1000) != 0x11 {
+		t.Error("Tables switched with rendering off")
+	}
+
+	// Switching off in the upper half restores the usual split
+	m.StartScanline(10, true)
+	m.WriteExpansion(0x5000, 0)
+	if m.ReadCHR(0x0000) != 0x11 || m.ReadCHR(0x1000) != 0x22 {
+		t.Error("Clearing $5000 bit 7 did not restore the usual split")
+	}
+}
+
+func TestMapper163State(t *testing.T) {
+	cart := newNanjingCartridge(4)
+	m := NewMapper163(cart)
+	m.WriteExpansion(0x5000, nanjingCHRAutoSwitch|2)
+	m.WriteExpansion(0x5101, 1)
+	m.WriteExpansion(0x5101, 0)
+	m.StartScanline(128, true)
+
+	w := savestate.NewWriter(64)
+	m.SaveState(w)
+
+	restored := NewMapper163(cart)
+	if err := restored.LoadState(savestate.NewReader(w.Bytes())); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if restored.ReadPRG(0x8000) != 2 || restored.chrPages != [2]int{1, 1} || !restored.trigger {
+		t.Errorf("Restored PRG bank %d, CHR pages %v, toggle %v; want 2, [1 1], true",
+			restored.ReadPRG(0x8000), restored.chrPages, restored.trigger)
+	}
+}
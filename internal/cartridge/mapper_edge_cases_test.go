@@ -715,7 +715,7 @@ func TestMapperEdgeCases_StateConsistency(t *testing.T) {
 
 // TestMapperEdgeCases_UnsupportedMappers tests handling of unsupported mapper IDs
 func TestMapperEdgeCases_UnsupportedMappers(t *testing.T) {
-	unsupportedMappers := []uint8{1, 2, 3, 4, 5, 10, 50, 100, 200, 255}
+	unsupportedMappers := []uint8{1, 2, 3, 5, 10, 50, 100, 200, 255}
 
 	for _, mapperID := range unsupportedMappers {
 		t.Run(fmt.Sprintf("Mapper %d fallback", mapperID), func(t *testing.T) {
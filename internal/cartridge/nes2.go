@@ -0,0 +1,135 @@
+// Package cartridge provides the splitting of iNES images into their PRG and
+// CHR ROM, and the rewriting of iNES 1.0 headers as NES 2.0 ones.
+package cartridge
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"gones/internal/region"
+)
+
+// headerSize and trainerSize are the sizes of the iNES header and trainer
+const (
+	headerSize  = 16
+	trainerSize = 512
+)
+
+// Segments are the parts of an iNES image after its header
+type Segments struct {
+	Trainer []byte // nil without one
+	PRG     []byte
+	CHR     []byte // nil for CHR RAM
+}
+
+// IsNES2 reports whether an iNES image has an NES 2.0 header
+func IsNES2(data []byte) bool {
+	return len(data) >= headerSize && data[7]&0x0C == 0x08
+}
+
+// SplitROM returns the trainer, PRG ROM and CHR ROM of an iNES image, sized
+// by its header. Bytes after the CHR ROM are ignored.
+func SplitROM(data []byte) (Segments, error) {
+	if len(data) < headerSize || string(data[:4]) != "NES\x1A" {
+		return Segments{}, errors.New("invalid iNES file")
+	}
+	if IsNES2(data) && data[9] != 0 {
+		return Segments{}, errors.New("NES 2.0 ROM sizes over 4 MB PRG or 2 MB CHR are not supported")
+	}
+
+	var seg Segments
+	pos := headerSize
+	take := func(size int, what string) ([]byte, error) {
+		if pos+size > len(data) {
+			return nil, fmt.Errorf("file ends inside the %s (%d of %d bytes)", what, len(data)-pos, size)
+		}
+		part := data[pos : pos+size]
+		pos += size
+		return part, nil
+	}
+	var err error
+	if data[6]&0x04 != 0 {
+		if seg.Trainer, err = take(trainerSize, "trainer"); err != nil {
+			return Segments{}, err
+		}
+	}
+	if seg.PRG, err = take(int(data[4])*16384, "PRG ROM"); err != nil {
+		return Segments{}, err
+	}
+	if data[5] != 0 {
+		if seg.CHR, err = take(int(data[5])*8192, "CHR ROM"); err != nil {
+			return Segments{}, err
+		}
+	}
+	return seg, nil
+}
+
+// NES 2.0 CPU/PPU timings of byte 12
+const (
+	nes2TimingNTSC  = 0
+	nes2TimingPAL   = 1
+	nes2TimingMulti = 2 // Runs on either
+	nes2TimingDendy = 3
+)
+
+// ConvertToNES2 rewrites the iNES 1.0 header of an image as an NES 2.0 one
+// and returns the new image. The mapper, mirroring, battery, trainer and
+// console type carry over. The timing is r, or multi-region when multi is
+// set. iNES 1.0 gives no RAM sizes, so the PRG RAM is the 8 KB of byte 8 (or
+// its multiple), battery-backed with a battery, and a game without CHR ROM
+// gets 8 KB of CHR RAM. Submappers are left at 0.
+func ConvertToNES2(data []byte, r region.Region, multi bool) ([]byte, error) {
+	seg, err := SplitROM(data)
+	if err != nil {
+		return nil, err
+	}
+	if IsNES2(data) {
+		return nil, errors.New("the header is already NES 2.0")
+	}
+
+	// Junk in bytes 7-15 ("DiskDude!") means the upper mapper nibble and the
+	// PRG RAM size are junk too
+	flags7, prgRAM := data[7], data[8]
+	if data[12]|data[13]|data[14]|data[15] != 0 {
+		flags7, prgRAM = 0, 0
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, data[:7])
+	header[7] = flags7&0xF0 | 0x08
+	switch {
+	case flags7&0x01 != 0:
+		header[7] |= 1 // Vs. System
+	case flags7&0x02 != 0:
+		header[7] |= 2 // PlayChoice-10
+	}
+
+	ramSize := max(int(prgRAM), 1) * 8192
+	if data[6]&0x02 != 0 {
+		header[10] = nes2Shift(ramSize) << 4
+	} else {
+		header[10] = nes2Shift(ramSize)
+	}
+	if seg.CHR == nil {
+		header[11] = nes2Shift(8192)
+	}
+
+	switch {
+	case multi:
+		header[12] = nes2TimingMulti
+	case r == region.PAL:
+		header[12] = nes2TimingPAL
+	case r == region.Dendy:
+		header[12] = nes2TimingDendy
+	default:
+		header[12] = nes2TimingNTSC
+	}
+
+	return append(header, data[headerSize:]...), nil
+}
+
+// nes2Shift encodes a RAM size as NES 2.0 does, as 64 << shift bytes
+func nes2Shift(size int) uint8 {
+	return uint8(bits.Len(uint(size)) - 1 - 6)
+}
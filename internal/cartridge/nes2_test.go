@@ -0,0 +1,94 @@
+package cartridge
+
+import (
+	"bytes"
+	"testing"
+
+	"gones/internal/region"
+)
+
+// inesImage builds an iNES 1.0 image whose PRG bytes are 1, CHR bytes 2 and
+// trainer bytes 3
+func inesImage(prgBanks, chrBanks, flags6, flags7, prgRAM uint8) []byte {
+	data := []byte{'N', 'E', 'S', 0x1A, prgBanks, chrBanks, flags6, flags7, prgRAM, 0, 0, 0, 0, 0, 0, 0}
+	if flags6&0x04 != 0 {
+		data = append(data, bytes.Repeat([]byte{3}, trainerSize)...)
+	}
+	data = append(data, bytes.Repeat([]byte{1}, int(prgBanks)*16384)...)
+	return append(data, bytes.Repeat([]byte{2}, int(chrBanks)*8192)...)
+}
+
+func TestSplitROM(t *testing.T) {
+	seg, err := SplitROM(inesImage(2, 1, 0x04, 0, 0))
+	if err != nil {
+		t.Fatalf("SplitROM failed: %v", err)
+	}
+	if len(seg.Trainer) != trainerSize || seg.Trainer[0] != 3 {
+		t.Errorf("Trainer %d bytes", len(seg.Trainer))
+	}
+	if len(seg.PRG) != 32768 || seg.PRG[0] != 1 || seg.PRG[32767] != 1 {
+		t.Errorf("PRG ROM %d bytes", len(seg.PRG))
+	}
+	if len(seg.CHR) != 8192 || seg.CHR[0] != 2 {
+		t.Errorf("CHR ROM %d bytes", len(seg.CHR))
+	}
+
+	seg, err = SplitROM(inesImage(1, 0, 0, 0, 0))
+	if err != nil || seg.CHR != nil || seg.Trainer != nil {
+		t.Errorf("CHR RAM game split into %d CHR and %d trainer bytes, %v", len(seg.CHR), len(seg.Trainer), err)
+	}
+
+	if _, err := SplitROM(inesImage(2, 1, 0, 0, 0)[:20000]); err == nil {
+		t.Error("Expected an error for a cut off ROM")
+	}
+}
+
+func TestConvertToNES2(t *testing.T) {
+	// Mapper 66, vertical, battery, Vs. System
+	data := inesImage(2, 0, 0x23, 0x41, 0)
+	converted, err := ConvertToNES2(data, region.PAL, false)
+	if err != nil {
+		t.Fatalf("ConvertToNES2 failed: %v", err)
+	}
+	if !IsNES2(converted) {
+		t.Fatal("Converted header is not NES 2.0")
+	}
+	want := []byte{'N', 'E', 'S', 0x1A, 2, 0, 0x23, 0x49, 0, 0, 0x70, 0x07, 1, 0, 0, 0}
+	if !bytes.Equal(converted[:headerSize], want) {
+		t.Errorf("Header % X, want % X", converted[:headerSize], want)
+	}
+	if !bytes.Equal(converted[headerSize:], data[headerSize:]) {
+		t.Error("Converting changed the ROM data")
+	}
+
+	// An NROM game with a battery
+	converted, err = ConvertToNES2(inesImage(1, 1, 0x03, 0, 0), region.PAL, false)
+	if err != nil {
+		t.Fatalf("ConvertToNES2 failed: %v", err)
+	}
+	cart, err := LoadFromReader(bytes.NewReader(converted))
+	if err != nil {
+		t.Fatalf("Converted ROM does not load: %v", err)
+	}
+	if cart.MapperID() != 0 || !cart.HasBattery() || cart.GetMirrorMode() != MirrorVertical {
+		t.Errorf("Converted ROM has mapper %d, battery %v, mirroring %v", cart.MapperID(), cart.HasBattery(), cart.GetMirrorMode())
+	}
+	if r, ok := cart.Region(); !ok || r != region.PAL {
+		t.Errorf("Converted ROM region %v (%v), want PAL", r, ok)
+	}
+
+	if _, err := ConvertToNES2(converted, region.NTSC, false); err == nil {
+		t.Error("Expected an error converting an NES 2.0 ROM")
+	}
+
+	// "DiskDude!" junk clears the upper mapper nibble; multi-region timing
+	junk := inesImage(1, 1, 0x10, 'D', 0)
+	copy(junk[7:16], "DiskDude!")
+	converted, err = ConvertToNES2(junk, region.NTSC, true)
+	if err != nil {
+		t.Fatalf("ConvertToNES2 failed: %v", err)
+	}
+	if converted[7] != 0x08 || converted[10] != 0x07 || converted[11] != 0 || converted[12] != 2 {
+		t.Errorf("Header % X", converted[:headerSize])
+	}
+}
@@ -0,0 +1,70 @@
+// Package cartridge implements battery-backed SRAM persistence.
+package cartridge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeSRAM writes a byte to SRAM and marks it dirty if the value changed
+func (c *Cartridge) writeSRAM(offset uint16, value uint8) {
+	offset &= 0x1FFF
+	if c.sram[offset] != value {
+		c.sram[offset] = value
+		c.sramDirty = true
+	}
+}
+
+// HasBattery returns true if the cartridge has battery-backed SRAM
+func (c *Cartridge) HasBattery() bool {
+	return c.hasBattery
+}
+
+// GetSRAM returns a copy of the SRAM contents
+func (c *Cartridge) GetSRAM() []uint8 {
+	data := make([]uint8, len(c.sram))
+	copy(data, c.sram[:])
+	return data
+}
+
+// LoadSRAM replaces the SRAM contents (shorter data only fills the beginning)
+func (c *Cartridge) LoadSRAM(data []uint8) {
+	copy(c.sram[:], data)
+	c.sramDirty = false
+}
+
+// IsSRAMDirty returns true if SRAM was modified since the last load or flush
+func (c *Cartridge) IsSRAMDirty() bool {
+	return c.sramDirty
+}
+
+// SaveSRAMToFile writes SRAM to a .sav file and clears the dirty flag.
+// The file is written to a temporary name first so a crash mid-write can't corrupt the save.
+func (c *Cartridge) SaveSRAMToFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create save directory: %v", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, c.sram[:], 0644); err != nil {
+		return fmt.Errorf("failed to write SRAM: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace SRAM file: %v", err)
+	}
+
+	c.sramDirty = false
+	return nil
+}
+
+// LoadSRAMFromFile loads SRAM from a .sav file
+func (c *Cartridge) LoadSRAMFromFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	c.LoadSRAM(data)
+	return nil
+}
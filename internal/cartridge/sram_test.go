@@ -0,0 +1,58 @@
+package cartridge
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSRAM_WriteMarksDirty(t *testing.T) {
+	cart := &Cartridge{prgROM: make([]uint8, 0x4000), hasBattery: true}
+	cart.mapper = NewMapper000(cart)
+
+	if cart.IsSRAMDirty() {
+		t.Fatal("New cartridge SRAM should not be dirty")
+	}
+
+	// Writing the same value should not dirty SRAM
+	cart.WritePRG(0x6000, 0x00)
+	if cart.IsSRAMDirty() {
+		t.Error("Writing an unchanged value should not dirty SRAM")
+	}
+
+	cart.WritePRG(0x6010, 0x42)
+	if !cart.IsSRAMDirty() {
+		t.Error("Writing a new value should dirty SRAM")
+	}
+	if cart.ReadPRG(0x6010) != 0x42 {
+		t.Errorf("Expected SRAM value 0x42, got 0x%02X", cart.ReadPRG(0x6010))
+	}
+}
+
+func TestSRAM_SaveAndLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "saves", "game.sav")
+
+	cart := &Cartridge{prgROM: make([]uint8, 0x4000), hasBattery: true}
+	cart.mapper = NewMapper000(cart)
+	cart.WritePRG(0x6000, 0xAA)
+	cart.WritePRG(0x7FFF, 0x55)
+
+	if err := cart.SaveSRAMToFile(path); err != nil {
+		t.Fatalf("SaveSRAMToFile failed: %v", err)
+	}
+	if cart.IsSRAMDirty() {
+		t.Error("SRAM should be clean after saving")
+	}
+
+	loaded := &Cartridge{prgROM: make([]uint8, 0x4000), hasBattery: true}
+	loaded.mapper = NewMapper000(loaded)
+	if err := loaded.LoadSRAMFromFile(path); err != nil {
+		t.Fatalf("LoadSRAMFromFile failed: %v", err)
+	}
+
+	if loaded.ReadPRG(0x6000) != 0xAA || loaded.ReadPRG(0x7FFF) != 0x55 {
+		t.Error("Loaded SRAM does not match saved SRAM")
+	}
+	if loaded.IsSRAMDirty() {
+		t.Error("SRAM should be clean after loading")
+	}
+}
@@ -0,0 +1,53 @@
+// Package cartridge implements cartridge state snapshots.
+package cartridge
+
+import (
+	"fmt"
+
+	"gones/internal/savestate"
+)
+
+// SaveState writes SRAM, CHR RAM, mirroring and any mapper registers.
+// ROM contents are not included; states are only valid for the same ROM.
+func (c *Cartridge) SaveState(w *savestate.Writer) {
+	w.WriteTag("CART")
+	w.WriteU8(c.mapperID)
+	w.WriteU8(uint8(c.mirror))
+	w.WriteBytes(c.sram[:])
+
+	w.WriteBool(c.hasCHRRAM)
+	if c.hasCHRRAM {
+		w.WriteBytes(c.chrROM)
+	}
+
+	// Mappers with bank registers or IRQ counters save them here
+	if stateful, ok := c.mapper.(savestate.Stateful); ok {
+		stateful.SaveState(w)
+	}
+}
+
+// LoadState restores state written by SaveState
+func (c *Cartridge) LoadState(r *savestate.Reader) error {
+	r.ExpectTag("CART")
+	mapperID := r.ReadU8()
+	if r.Err() == nil && mapperID != c.mapperID {
+		return fmt.Errorf("state is for mapper %d, cartridge uses mapper %d", mapperID, c.mapperID)
+	}
+
+	c.mirror = MirrorMode(r.ReadU8())
+	r.ReadBytesInto(c.sram[:])
+
+	if r.ReadBool() {
+		if !c.hasCHRRAM {
+			r.Fail(fmt.Errorf("state contains CHR RAM but cartridge has CHR ROM"))
+			return r.Err()
+		}
+		r.ReadBytesInto(c.chrROM)
+	}
+
+	if stateful, ok := c.mapper.(savestate.Stateful); ok {
+		stateful.LoadState(r)
+	}
+
+	return r.Err()
+}
@@ -0,0 +1,177 @@
+// Package cdl provides a code/data logger, which records how a game used each
+// byte of its PRG and CHR ROM, and the .cdl files it is kept in.
+package cdl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PRG ROM flags. They are the low bits of the FCEUX .cdl format, so the
+// files can be shared with its tools.
+const (
+	PRGCode = 0x01 // Fetched by the CPU as an opcode or operand
+	PRGData = 0x02 // Read by the CPU as data
+)
+
+// CHR ROM flags
+const (
+	CHRRendered = 0x01 // Fetched by the PPU to draw tiles or sprites
+	CHRRead     = 0x02 // Read by the CPU through PPUDATA ($2007)
+)
+
+// fetchWindow is the length of the longest instruction: PRG reads this close
+// after the address of the instruction being fetched are its own bytes
+const fetchWindow = 3
+
+// ROM maps CPU and PPU addresses to ROM offsets. cartridge.Cartridge
+// implements it.
+type ROM interface {
+	PRGSize() int
+	CHRSize() int
+	PRGOffset(address uint16) (int, bool)
+	CHROffset(address uint16) (int, bool)
+}
+
+// Logger holds a flag byte for every PRG and CHR ROM byte. Its Fetch,
+// ReadPRG and ReadCHR methods are installed as the CPU fetch hook and the
+// memory access hooks.
+type Logger struct {
+	rom     ROM
+	prg     []uint8
+	chr     []uint8
+	fetchPC uint16 // Address of the instruction being executed
+}
+
+// New creates an empty log for rom
+func New(rom ROM) *Logger {
+	return &Logger{
+		rom: rom,
+		prg: make([]uint8, rom.PRGSize()),
+		chr: make([]uint8, rom.CHRSize()),
+	}
+}
+
+// Fetch records that the CPU starts an instruction at pc
+func (l *Logger) Fetch(pc uint16) {
+	l.fetchPC = pc
+}
+
+// ReadPRG records a CPU read of PRG ROM: the bytes of the instruction being
+// fetched are code, any other read is data
+func (l *Logger) ReadPRG(address uint16) {
+	offset, ok := l.rom.PRGOffset(address)
+	if !ok || offset >= len(l.prg) {
+		return
+	}
+	if address-l.fetchPC < fetchWindow {
+		l.prg[offset] |= PRGCode
+	} else {
+		l.prg[offset] |= PRGData
+	}
+}
+
+// ReadCHR records a pattern table read, by the PPU while rendering or by the
+// CPU through PPUDATA
+func (l *Logger) ReadCHR(address uint16, rendering bool) {
+	offset, ok := l.rom.CHROffset(address)
+	if !ok || offset >= len(l.chr) {
+		return
+	}
+	if rendering {
+		l.chr[offset] |= CHRRendered
+	} else {
+		l.chr[offset] |= CHRRead
+	}
+}
+
+// PRGFlags returns the flags of the PRG ROM byte a CPU address maps to, or
+// false if it does not map to PRG ROM
+func (l *Logger) PRGFlags(address uint16) (uint8, bool) {
+	offset, ok := l.rom.PRGOffset(address)
+	if !ok || offset >= len(l.prg) {
+		return 0, false
+	}
+	return l.prg[offset], true
+}
+
+// Stats counts the logged bytes
+type Stats struct {
+	PRGSize     int
+	PRGCode     int // Bytes executed (some may also have been read as data)
+	PRGData     int // Bytes only read as data
+	CHRSize     int
+	CHRRendered int
+	CHRRead     int // Bytes only read through PPUDATA
+}
+
+// PRGUnreached returns the number of PRG bytes never accessed
+func (s Stats) PRGUnreached() int {
+	return s.PRGSize - s.PRGCode - s.PRGData
+}
+
+// Stats counts the bytes of each kind
+func (l *Logger) Stats() Stats {
+	s := Stats{PRGSize: len(l.prg), CHRSize: len(l.chr)}
+	for _, flags := range l.prg {
+		switch {
+		case flags&PRGCode != 0:
+			s.PRGCode++
+		case flags&PRGData != 0:
+			s.PRGData++
+		}
+	}
+	for _, flags := range l.chr {
+		switch {
+		case flags&CHRRendered != 0:
+			s.CHRRendered++
+		case flags&CHRRead != 0:
+			s.CHRRead++
+		}
+	}
+	return s
+}
+
+// Clear forgets everything logged
+func (l *Logger) Clear() {
+	clear(l.prg)
+	clear(l.chr)
+}
+
+// Save writes the log to a .cdl file: the PRG flags followed by the CHR flags
+func (l *Logger) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create CDL directory: %v", err)
+	}
+
+	data := append(append([]uint8(nil), l.prg...), l.chr...)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write CDL: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace CDL file: %v", err)
+	}
+	return nil
+}
+
+// Load merges a .cdl file into the log, so logging carries on across
+// sessions. The file must match the ROM's PRG and CHR sizes.
+func (l *Logger) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) != len(l.prg)+len(l.chr) {
+		return fmt.Errorf("CDL file %s has %d bytes, expected %d for this ROM", path, len(data), len(l.prg)+len(l.chr))
+	}
+
+	for i := range l.prg {
+		l.prg[i] |= data[i]
+	}
+	for i := range l.chr {
+		l.chr[i] |= data[len(l.prg)+i]
+	}
+	return nil
+}
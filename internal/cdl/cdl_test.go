@@ -0,0 +1,141 @@
+package cdl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testROM is 16KB of PRG mirrored at $8000 and $C000, and 8KB of CHR
+type testROM struct{}
+
+func (testROM) PRGSize() int { return 0x4000 }
+func (testROM) CHRSize() int { return 0x2000 }
+
+func (testROM) PRGOffset(address uint16) (int, bool) {
+	if address < 0x8000 {
+		return 0, false
+	}
+	return int(address & 0x3FFF), true
+}
+
+func (testROM) CHROffset(address uint16) (int, bool) {
+	return int(address), address < 0x2000
+}
+
+func TestLoggerCodeAndData(t *testing.T) {
+	l := New(testROM{})
+
+	// LDA $9000 at $8000: three code bytes, then a data read
+	l.Fetch(0x8000)
+	l.ReadPRG(0x8000)
+	l.ReadPRG(0x8001)
+	l.ReadPRG(0x8002)
+	l.ReadPRG(0x9000)
+	// RAM and mirrored reads
+	l.ReadPRG(0x0200)
+	l.Fetch(0xC003)
+	l.ReadPRG(0xC003)
+
+	tests := []struct {
+		address uint16
+		flags   uint8
+	}{
+		{0x8000, PRGCode},
+		{0x8002, PRGCode},
+		{0x8003, PRGCode}, // Mirror of $C003
+		{0x9000, PRGData},
+		{0x8004, 0},
+	}
+	for _, test := range tests {
+		if flags, _ := l.PRGFlags(test.address); flags != test.flags {
+			t.Errorf("PRGFlags($%04X) = %02X, want %02X", test.address, flags, test.flags)
+		}
+	}
+	if _, ok := l.PRGFlags(0x0200); ok {
+		t.Error("RAM should not map to PRG ROM")
+	}
+
+	l.ReadCHR(0x0010, true)
+	l.ReadCHR(0x1000, false)
+	l.ReadCHR(0x2000, true) // Nametables are not CHR
+
+	stats := l.Stats()
+	if stats.PRGCode != 4 || stats.PRGData != 1 || stats.PRGUnreached() != 0x4000-5 {
+		t.Errorf("PRG stats = %+v", stats)
+	}
+	if stats.CHRRendered != 1 || stats.CHRRead != 1 {
+		t.Errorf("CHR stats = %+v", stats)
+	}
+
+	l.Clear()
+	if stats := l.Stats(); stats.PRGCode != 0 || stats.CHRRendered != 0 {
+		t.Errorf("stats after Clear = %+v", stats)
+	}
+}
+
+func TestLoggerSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "saves", "game.cdl")
+
+	l := New(testROM{})
+	l.Fetch(0x8000)
+	l.ReadPRG(0x8000)
+	l.ReadCHR(0x0001, true)
+	if err := l.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 0x6000 || data[0] != PRGCode || data[0x4001] != CHRRendered {
+		t.Fatalf("file has %d bytes, PRG[0]=%02X CHR[1]=%02X", len(data), data[0], data[0x4001])
+	}
+
+	// Loading merges with what is logged already
+	merged := New(testROM{})
+	merged.ReadPRG(0x9000)
+	if err := merged.Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if stats := merged.Stats(); stats.PRGCode != 1 || stats.PRGData != 1 || stats.CHRRendered != 1 {
+		t.Errorf("stats after Load = %+v", stats)
+	}
+
+	if err := os.WriteFile(path, []byte{1, 2, 3}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := merged.Load(path); err == nil {
+		t.Error("Load should reject a file of the wrong size")
+	}
+}
+
+func TestDisassembleUsesLog(t *testing.T) {
+	var memory [0x10000]uint8
+	copy(memory[0x8000:], []uint8{0xA9, 0x01, 0xAD, 0x34, 0x12, 0xEA})
+	read := func(address uint16) uint8 { return memory[address] }
+
+	l := New(testROM{})
+	l.Fetch(0x8000)
+	l.ReadPRG(0x8000)
+	l.ReadPRG(0x8001)
+	l.Fetch(0x9000)
+	l.ReadPRG(0x8002) // Read as a table, never executed
+
+	lines := Disassemble(l, read, 0x8000, 3)
+	want := []string{"LDA #$01", ".DB $AD", "NOP $12,X"}
+	for i, line := range lines {
+		if line.Text != want[i] {
+			t.Errorf("line %d = %q, want %q", i, line.Text, want[i])
+		}
+	}
+	if !lines[0].IsCode() || !lines[1].IsData() || !lines[2].IsUnreached() {
+		t.Errorf("lines flagged %+v", lines)
+	}
+
+	// Without a log everything is code
+	if lines := Disassemble(nil, read, 0x8002, 1); lines[0].Text != "LDA $1234" {
+		t.Errorf("unlogged line = %q, want LDA $1234", lines[0].Text)
+	}
+}
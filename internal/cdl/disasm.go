@@ -0,0 +1,62 @@
+// Package cdl provides the disassembly of PRG ROM guided by the code/data log.
+package cdl
+
+import (
+	"fmt"
+
+	"gones/internal/cpu"
+)
+
+// Line is one line of a disassembly listing
+type Line struct {
+	Address uint16
+	Length  int
+	Text    string
+	Flags   uint8 // Log flags of the first byte (0 if never reached)
+	Logged  bool  // The address maps to PRG ROM
+}
+
+// IsCode returns whether the line was executed
+func (l Line) IsCode() bool {
+	return l.Flags&PRGCode != 0
+}
+
+// IsData returns whether the line was only read as data
+func (l Line) IsData() bool {
+	return l.Flags&PRGData != 0 && l.Flags&PRGCode == 0
+}
+
+// IsUnreached returns whether the line maps to ROM that was never accessed
+func (l Line) IsUnreached() bool {
+	return l.Logged && l.Flags == 0
+}
+
+// Disassemble lists count lines from start, reading bytes with read. Bytes
+// the log saw read as data but never executed are listed as ".DB", so data
+// tables do not turn into bogus instructions; unreached bytes are
+// disassembled and flagged, since they may be code that did not run yet. A
+// nil log disassembles everything.
+func Disassemble(log *Logger, read func(address uint16) uint8, start uint16, count int) []Line {
+	lines := make([]Line, 0, count)
+	address := start
+	for len(lines) < count {
+		line := Line{Address: address}
+		if log != nil {
+			line.Flags, line.Logged = log.PRGFlags(address)
+		}
+
+		if line.IsData() {
+			line.Text, line.Length = fmt.Sprintf(".DB $%02X", read(address)), 1
+		} else {
+			line.Text, line.Length = cpu.Disassemble(read, address)
+		}
+		lines = append(lines, line)
+
+		next := address + uint16(line.Length)
+		if next < address {
+			break // Wrapped past $FFFF
+		}
+		address = next
+	}
+	return lines
+}
@@ -0,0 +1,97 @@
+// Package cheat provides Game Genie code decoding and PRG read patching.
+package cheat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// genieLetters are the Game Genie letters in the order of the nibbles they encode
+const genieLetters = "APZLGITYEOXUKSVN"
+
+// GenieCode is a decoded Game Genie code: PRG reads of Address return Value
+// instead of the ROM byte, only when the ROM byte equals Compare if
+// HasCompare is set (8-letter codes), which keeps the patch from hitting
+// other banks mapped at the same address
+type GenieCode struct {
+	Code       string // Normalized letters
+	Address    uint16 // $8000-$FFFF
+	Value      uint8
+	Compare    uint8
+	HasCompare bool
+}
+
+// DecodeGenie decodes a 6- or 8-letter Game Genie code. Case, spaces and
+// dashes are ignored.
+func DecodeGenie(code string) (GenieCode, error) {
+	letters := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(code))
+	if len(letters) != 6 && len(letters) != 8 {
+		return GenieCode{}, fmt.Errorf("game genie code must have 6 or 8 letters: %q", code)
+	}
+
+	var n [8]uint16
+	for i := 0; i < len(letters); i++ {
+		index := strings.IndexByte(genieLetters, letters[i])
+		if index < 0 {
+			return GenieCode{}, fmt.Errorf("invalid game genie letter %q in %q", letters[i], code)
+		}
+		n[i] = uint16(index)
+	}
+
+	g := GenieCode{Code: letters}
+	g.Address = 0x8000 | (n[3]&7)<<12 | (n[5]&7)<<8 | (n[4]&8)<<8 |
+		(n[2]&7)<<4 | (n[1]&8)<<4 | n[4]&7 | n[3]&8
+	value := (n[1]&7)<<4 | (n[0]&8)<<4 | n[0]&7
+	if len(letters) == 6 {
+		value |= n[5] & 8
+	} else {
+		value |= n[7] & 8
+		g.Compare = uint8((n[7]&7)<<4 | (n[6]&8)<<4 | n[6]&7 | n[5]&8)
+		g.HasCompare = true
+	}
+	g.Value = uint8(value)
+	return g, nil
+}
+
+// String formats the patch the code makes
+func (g GenieCode) String() string {
+	if g.HasCompare {
+		return fmt.Sprintf("%s: $%04X?%02X:%02X", g.Code, g.Address, g.Compare, g.Value)
+	}
+	return fmt.Sprintf("%s: $%04X:%02X", g.Code, g.Address, g.Value)
+}
+
+// GeniePatches applies a set of Game Genie codes to PRG reads
+type GeniePatches struct {
+	codes map[uint16][]GenieCode
+}
+
+// NewGeniePatches creates the patches for codes
+func NewGeniePatches(codes []GenieCode) *GeniePatches {
+	p := &GeniePatches{codes: make(map[uint16][]GenieCode)}
+	for _, code := range codes {
+		p.codes[code.Address] = append(p.codes[code.Address], code)
+	}
+	return p
+}
+
+// Len returns the number of codes
+func (p *GeniePatches) Len() int {
+	n := 0
+	for _, codes := range p.codes {
+		n += len(codes)
+	}
+	return n
+}
+
+// Apply returns the byte a PRG read of address sees with the codes applied
+// to value, the byte read from the cartridge. It has the signature of
+// memory.Memory's PRG read hook.
+func (p *GeniePatches) Apply(address uint16, value uint8) uint8 {
+	for _, code := range p.codes[address] {
+		if !code.HasCompare || code.Compare == value {
+			return code.Value
+		}
+	}
+	return value
+}
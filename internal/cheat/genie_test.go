@@ -0,0 +1,54 @@
+package cheat
+
+import "testing"
+
+func TestDecodeGenie_SixLetters(t *testing.T) {
+	// Super Mario Bros. infinite lives: LDA instead of DEC of the lives counter
+	g, err := DecodeGenie("sxio-po")
+	if err != nil {
+		t.Fatalf("DecodeGenie failed: %v", err)
+	}
+	if g.Code != "SXIOPO" || g.Address != 0x91D9 || g.Value != 0xAD || g.HasCompare {
+		t.Errorf("Unexpected decode %s (compare %v)", g, g.HasCompare)
+	}
+}
+
+func TestDecodeGenie_EightLetters(t *testing.T) {
+	g, err := DecodeGenie("AAAAANGN")
+	if err != nil {
+		t.Fatalf("DecodeGenie failed: %v", err)
+	}
+	if g.Address != 0x8700 || g.Value != 0x08 || !g.HasCompare || g.Compare != 0x7C {
+		t.Errorf("Unexpected decode %s", g)
+	}
+}
+
+func TestDecodeGenie_Invalid(t *testing.T) {
+	for _, code := range []string{"", "SXIOP", "SXIOPOO", "SXIOPB", "SXIOPO12"} {
+		if _, err := DecodeGenie(code); err == nil {
+			t.Errorf("Expected an error for %q", code)
+		}
+	}
+}
+
+func TestGeniePatches_Apply(t *testing.T) {
+	six, _ := DecodeGenie("SXIOPO")
+	eight, _ := DecodeGenie("AAAAANGN")
+	patches := NewGeniePatches([]GenieCode{six, eight})
+	if patches.Len() != 2 {
+		t.Fatalf("Expected 2 codes, got %d", patches.Len())
+	}
+
+	if got := patches.Apply(0x91D9, 0xCE); got != 0xAD {
+		t.Errorf("Expected the 6-letter code to replace the byte, got $%02X", got)
+	}
+	if got := patches.Apply(0x8700, 0x7C); got != 0x08 {
+		t.Errorf("Expected the 8-letter code to replace a matching byte, got $%02X", got)
+	}
+	if got := patches.Apply(0x8700, 0x7D); got != 0x7D {
+		t.Errorf("Expected the 8-letter code to skip other bytes, got $%02X", got)
+	}
+	if got := patches.Apply(0x9000, 0x42); got != 0x42 {
+		t.Errorf("Expected other addresses untouched, got $%02X", got)
+	}
+}
@@ -0,0 +1,71 @@
+// Package cheat provides raw RAM cheats that freeze bytes to a value.
+package cheat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Writer writes memory for debugging. memory.Memory implements it.
+type Writer interface {
+	Poke(address uint16, value uint8) bool
+}
+
+// RAMCheat freezes a CPU address to a value
+type RAMCheat struct {
+	Address uint16
+	Value   uint8
+}
+
+// ParseRAMCheat parses a raw cheat: "AAAA:VV" with optional "$" prefixes, or
+// the six hex digits "AAAAVV" of Pro Action Replay codes
+func ParseRAMCheat(code string) (RAMCheat, error) {
+	s := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "$", ""))
+	address, value, found := strings.Cut(s, ":")
+	if !found {
+		if len(s) != 6 {
+			return RAMCheat{}, fmt.Errorf("raw cheat must be AAAA:VV or AAAAVV: %q", code)
+		}
+		address, value = s[:4], s[4:]
+	}
+
+	a, err := strconv.ParseUint(address, 16, 16)
+	if err != nil {
+		return RAMCheat{}, fmt.Errorf("invalid cheat address in %q", code)
+	}
+	v, err := strconv.ParseUint(value, 16, 8)
+	if err != nil {
+		return RAMCheat{}, fmt.Errorf("invalid cheat value in %q", code)
+	}
+	return RAMCheat{Address: uint16(a), Value: uint8(v)}, nil
+}
+
+// String formats the cheat as "AAAA:VV"
+func (c RAMCheat) String() string {
+	return fmt.Sprintf("%04X:%02X", c.Address, c.Value)
+}
+
+// Freezer holds RAM cheats and writes them back to memory every frame, so
+// the game cannot change the frozen bytes for longer than a frame
+type Freezer struct {
+	cheats []RAMCheat
+}
+
+// NewFreezer creates a freezer for cheats
+func NewFreezer(cheats []RAMCheat) *Freezer {
+	return &Freezer{cheats: append([]RAMCheat(nil), cheats...)}
+}
+
+// Len returns the number of cheats
+func (f *Freezer) Len() int {
+	return len(f.cheats)
+}
+
+// Apply writes the frozen values. Addresses that cannot be written (outside
+// RAM and PRG RAM) are skipped.
+func (f *Freezer) Apply(w Writer) {
+	for _, c := range f.cheats {
+		w.Poke(c.Address, c.Value)
+	}
+}
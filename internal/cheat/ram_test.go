@@ -0,0 +1,60 @@
+package cheat
+
+import "testing"
+
+// fakeWriter records pokes into RAM
+type fakeWriter struct {
+	ram [0x800]uint8
+}
+
+func (w *fakeWriter) Poke(address uint16, value uint8) bool {
+	if address >= 0x0800 {
+		return false
+	}
+	w.ram[address] = value
+	return true
+}
+
+func TestParseRAMCheat(t *testing.T) {
+	tests := []struct {
+		code    string
+		address uint16
+		value   uint8
+	}{
+		{"0075:09", 0x0075, 0x09},
+		{"$075A:$FF", 0x075A, 0xFF},
+		{"00de63", 0x00DE, 0x63},
+		{" 7:1 ", 0x0007, 0x01},
+	}
+	for _, tt := range tests {
+		c, err := ParseRAMCheat(tt.code)
+		if err != nil {
+			t.Errorf("ParseRAMCheat(%q) failed: %v", tt.code, err)
+			continue
+		}
+		if c.Address != tt.address || c.Value != tt.value {
+			t.Errorf("ParseRAMCheat(%q) = %s", tt.code, c)
+		}
+	}
+
+	for _, code := range []string{"", "0075", "0075:", "10000:01", "0075:100", "ZZZZ:01"} {
+		if _, err := ParseRAMCheat(code); err == nil {
+			t.Errorf("Expected an error for %q", code)
+		}
+	}
+}
+
+func TestFreezer_RewritesValues(t *testing.T) {
+	mem := &fakeWriter{}
+	lives, _ := ParseRAMCheat("075A:09")
+	freezer := NewFreezer([]RAMCheat{lives, {Address: 0x8000, Value: 1}})
+
+	mem.ram[0x075A] = 2 // The game took lives away
+	freezer.Apply(mem)
+	if mem.ram[0x075A] != 9 {
+		t.Errorf("Expected the frozen value, got %d", mem.ram[0x075A])
+	}
+	if freezer.Len() != 2 {
+		t.Errorf("Expected 2 cheats, got %d", freezer.Len())
+	}
+}
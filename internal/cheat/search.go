@@ -0,0 +1,191 @@
+// Package cheat provides cheat codes and the RAM search used to find the
+// addresses behind in-game values.
+package cheat
+
+import "fmt"
+
+// Reader reads memory without side effects. memory.Memory implements it.
+type Reader interface {
+	Peek(address uint16) (uint8, bool)
+}
+
+// Region is a range of CPU addresses searched
+type Region struct {
+	Name  string
+	Start uint16
+	End   uint16 // Inclusive
+}
+
+// SearchRegions are the writable memory a game keeps its state in: the 2KB of
+// internal RAM and the cartridge's PRG RAM
+var SearchRegions = []Region{
+	{Name: "RAM", Start: 0x0000, End: 0x07FF},
+	{Name: "PRG RAM", Start: 0x6000, End: 0x7FFF},
+}
+
+// Comparison selects the candidates a search step keeps
+type Comparison int
+
+const (
+	CompareEqual     Comparison = iota // Value == operand
+	CompareNotEqual                    // Value != operand
+	CompareGreater                     // Value > operand
+	CompareLess                        // Value < operand
+	CompareChangedBy                   // Value - previous value == operand (wrapping)
+)
+
+// Comparisons lists the comparisons in menu order
+var Comparisons = []Comparison{CompareEqual, CompareNotEqual, CompareGreater, CompareLess, CompareChangedBy}
+
+// String returns the name of the comparison
+func (c Comparison) String() string {
+	switch c {
+	case CompareEqual:
+		return "equal"
+	case CompareNotEqual:
+		return "not equal"
+	case CompareGreater:
+		return "greater"
+	case CompareLess:
+		return "less"
+	case CompareChangedBy:
+		return "changed by"
+	default:
+		return fmt.Sprintf("comparison(%d)", int(c))
+	}
+}
+
+// Result is a candidate address with its value now and at the previous step
+type Result struct {
+	Address  uint16
+	Value    uint8
+	Previous uint8
+}
+
+// searchStep is the candidate set after a step, kept for Undo
+type searchStep struct {
+	addresses []uint16
+	values    []uint8 // Value of each address when the step was taken
+}
+
+// Search narrows down the addresses holding a value by repeatedly comparing
+// memory to the snapshot of the previous step: take a snapshot with Reset,
+// play until the value changes, then keep the addresses that changed the
+// same way.
+type Search struct {
+	reader  Reader
+	regions []Region
+	steps   []searchStep // Candidate sets, the current one last
+}
+
+// NewSearch creates a search over SearchRegions and takes the first snapshot
+func NewSearch(reader Reader) *Search {
+	s := &Search{reader: reader, regions: SearchRegions}
+	s.Reset()
+	return s
+}
+
+// Reset starts over with every readable address of the regions as a candidate
+func (s *Search) Reset() {
+	var step searchStep
+	for _, region := range s.regions {
+		for address := uint32(region.Start); address <= uint32(region.End); address++ {
+			if value, ok := s.reader.Peek(uint16(address)); ok {
+				step.addresses = append(step.addresses, uint16(address))
+				step.values = append(step.values, value)
+			}
+		}
+	}
+	s.steps = []searchStep{step}
+}
+
+// Filter keeps the candidates whose current value matches. Against the
+// previous snapshot (usePrevious) the operand is ignored, except for
+// CompareChangedBy which always compares the change to the operand. The
+// kept values become the snapshot for the next step. It returns the number
+// of candidates left.
+func (s *Search) Filter(comparison Comparison, operand int, usePrevious bool) int {
+	current := s.current()
+	var next searchStep
+	for i, address := range current.addresses {
+		value, ok := s.reader.Peek(address)
+		if !ok {
+			continue
+		}
+		previous := current.values[i]
+
+		var keep bool
+		switch {
+		case comparison == CompareChangedBy:
+			keep = value-previous == uint8(operand)
+		case usePrevious:
+			keep = compare(comparison, int(value), int(previous))
+		default:
+			keep = compare(comparison, int(value), operand)
+		}
+		if keep {
+			next.addresses = append(next.addresses, address)
+			next.values = append(next.values, value)
+		}
+	}
+	s.steps = append(s.steps, next)
+	return len(next.addresses)
+}
+
+// compare applies a value comparison
+func compare(comparison Comparison, value, operand int) bool {
+	switch comparison {
+	case CompareEqual:
+		return value == operand
+	case CompareNotEqual:
+		return value != operand
+	case CompareGreater:
+		return value > operand
+	case CompareLess:
+		return value < operand
+	default:
+		return false
+	}
+}
+
+// Undo returns to the candidates before the last Filter. It returns false
+// if there is no step to undo.
+func (s *Search) Undo() bool {
+	if len(s.steps) <= 1 {
+		return false
+	}
+	s.steps = s.steps[:len(s.steps)-1]
+	return true
+}
+
+// Steps returns the number of Filter steps since Reset
+func (s *Search) Steps() int {
+	return len(s.steps) - 1
+}
+
+// Count returns the number of candidates left
+func (s *Search) Count() int {
+	return len(s.current().addresses)
+}
+
+// Results returns up to limit candidates (all if limit <= 0) in address
+// order, with their current and snapshot values
+func (s *Search) Results(limit int) []Result {
+	current := s.current()
+	n := len(current.addresses)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	results := make([]Result, 0, n)
+	for i := 0; i < n; i++ {
+		address := current.addresses[i]
+		value, _ := s.reader.Peek(address)
+		results = append(results, Result{Address: address, Value: value, Previous: current.values[i]})
+	}
+	return results
+}
+
+// current returns the candidate set of the last step
+func (s *Search) current() *searchStep {
+	return &s.steps[len(s.steps)-1]
+}
@@ -0,0 +1,99 @@
+package cheat
+
+import "testing"
+
+// fakeMemory is RAM at $0000-$07FF and PRG RAM at $6000-$7FFF
+type fakeMemory struct {
+	ram    [0x800]uint8
+	prgRAM [0x2000]uint8
+}
+
+func (m *fakeMemory) Peek(address uint16) (uint8, bool) {
+	switch {
+	case address < 0x0800:
+		return m.ram[address], true
+	case address >= 0x6000 && address < 0x8000:
+		return m.prgRAM[address-0x6000], true
+	default:
+		return 0, false
+	}
+}
+
+func TestSearch_FindsDecreasingCounter(t *testing.T) {
+	mem := &fakeMemory{}
+	mem.ram[0x75] = 3 // Lives
+	mem.ram[0x76] = 3
+	mem.prgRAM[0x10] = 3
+	search := NewSearch(mem)
+	if search.Count() != 0x800+0x2000 {
+		t.Fatalf("Expected every RAM and PRG RAM byte as a candidate, got %d", search.Count())
+	}
+
+	// Lose a life: only $0075 goes from 3 to 2
+	mem.ram[0x75] = 2
+	if n := search.Filter(CompareLess, 0, true); n != 1 {
+		t.Fatalf("Expected 1 candidate after a decrease, got %d", n)
+	}
+	results := search.Results(0)
+	if results[0].Address != 0x0075 || results[0].Value != 2 || results[0].Previous != 2 {
+		t.Errorf("Unexpected result %+v", results[0])
+	}
+}
+
+func TestSearch_ValueAndChangedBy(t *testing.T) {
+	mem := &fakeMemory{}
+	mem.ram[0x10] = 5
+	mem.ram[0x20] = 5
+	mem.prgRAM[0x30] = 5
+	search := NewSearch(mem)
+
+	if n := search.Filter(CompareEqual, 5, false); n != 3 {
+		t.Fatalf("Expected 3 bytes equal to 5, got %d", n)
+	}
+
+	mem.ram[0x10] = 4    // -1
+	mem.ram[0x20] = 7    // +2
+	mem.prgRAM[0x30] = 4 // -1
+	if n := search.Filter(CompareChangedBy, -1, true); n != 2 {
+		t.Fatalf("Expected 2 bytes changed by -1, got %d", n)
+	}
+	results := search.Results(0)
+	if results[0].Address != 0x0010 || results[1].Address != 0x6030 {
+		t.Errorf("Unexpected results %+v", results)
+	}
+
+	mem.ram[0x10] = 0xFF
+	if n := search.Filter(CompareGreater, 0x80, false); n != 1 {
+		t.Errorf("Expected 1 byte above $80, got %d", n)
+	}
+	if search.Steps() != 3 {
+		t.Errorf("Expected 3 steps, got %d", search.Steps())
+	}
+}
+
+func TestSearch_UndoAndReset(t *testing.T) {
+	mem := &fakeMemory{}
+	search := NewSearch(mem)
+	all := search.Count()
+
+	mem.ram[0x01] = 9
+	search.Filter(CompareNotEqual, 0, true)
+	if search.Count() != 1 {
+		t.Fatalf("Expected 1 changed byte, got %d", search.Count())
+	}
+	if !search.Undo() || search.Count() != all {
+		t.Errorf("Expected Undo to restore %d candidates, got %d", all, search.Count())
+	}
+	if search.Undo() {
+		t.Error("Expected nothing to undo after the first snapshot")
+	}
+
+	search.Filter(CompareEqual, 9, false)
+	search.Reset()
+	if search.Count() != all || search.Steps() != 0 {
+		t.Errorf("Expected Reset to start over, got %d candidates after %d steps", search.Count(), search.Steps())
+	}
+	if results := search.Results(4); len(results) != 4 {
+		t.Errorf("Expected Results to honor the limit, got %d", len(results))
+	}
+}
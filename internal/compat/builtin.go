@@ -0,0 +1,11 @@
+// Package compat provides the entries shipped with gones, by the SHA-1 of
+// the ROM data without its iNES header.
+package compat
+
+// builtin are the shipped entries. An entry goes in once its hash has been
+// checked against a known dump and the fix tried on it, which none has been
+// yet: gones ships without entries, and the database only holds those of
+// compat.json in the config directory (see Database.LoadFile). A hash taken
+// from a list without the dump at hand would force a fix on whatever ROM
+// it turned out to be.
+var builtin = map[string]Entry{}
@@ -0,0 +1,154 @@
+// Package compat implements the compatibility database: ROMs whose iNES
+// header is wrong, or that need an emulation quirk to run as they do on a
+// console, and what to force for them. ROMs are found by the SHA-1 of their
+// data without the header, as No-Intro lists them, so a dump is found
+// whatever its header says.
+package compat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gones/internal/library"
+	"gones/internal/region"
+)
+
+// MaxPPUAlignment is the most PPU dots the PPU can start ahead of the CPU
+const MaxPPUAlignment = 2
+
+// Entry is what the database forces for a ROM. Zero fields leave the ROM's
+// header and the emulator's settings alone.
+type Entry struct {
+	Title string `json:"title"` // For the log
+
+	Mapper     *uint8 `json:"mapper,omitempty"`      // Replaces the header's mapper number
+	Region     string `json:"region,omitempty"`      // "NTSC", "PAL" or "Dendy", ahead of the header's
+	FourScreen bool   `json:"four_screen,omitempty"` // Four nametables of cartridge VRAM
+
+	// PPU dots the PPU starts ahead of the CPU at power on, for games that
+	// only run with one of the console's CPU/PPU alignments
+	PPUAlignment int `json:"ppu_alignment,omitempty"`
+}
+
+// TVSystem returns the region the entry forces, if it forces one
+func (e *Entry) TVSystem() (region.Region, bool) {
+	if e.Region == "" {
+		return region.NTSC, false
+	}
+	r, err := region.Parse(e.Region)
+	return r, err == nil
+}
+
+// String describes what the entry forces, such as "mapper 4, PAL"
+func (e *Entry) String() string {
+	var fixes []string
+	if e.Mapper != nil {
+		fixes = append(fixes, fmt.Sprintf("mapper %d", *e.Mapper))
+	}
+	if r, ok := e.TVSystem(); ok {
+		fixes = append(fixes, r.String())
+	}
+	if e.FourScreen {
+		fixes = append(fixes, "four-screen")
+	}
+	if e.PPUAlignment != 0 {
+		fixes = append(fixes, fmt.Sprintf("PPU alignment %d", e.PPUAlignment))
+	}
+	if len(fixes) == 0 {
+		return "nothing forced"
+	}
+	return strings.Join(fixes, ", ")
+}
+
+// validate returns an error if the entry forces something impossible
+func (e *Entry) validate() error {
+	if e.Region != "" {
+		if _, err := region.Parse(e.Region); err != nil {
+			return err
+		}
+	}
+	if e.PPUAlignment < 0 || e.PPUAlignment > MaxPPUAlignment {
+		return fmt.Errorf("ppu_alignment %d is not 0-%d", e.PPUAlignment, MaxPPUAlignment)
+	}
+	return nil
+}
+
+// FixHeader returns a copy of an iNES image with the mapper and four-screen
+// flag the entry forces written to its header
+func (e *Entry) FixHeader(data []byte) []byte {
+	if len(data) < 16 || string(data[:4]) != "NES\x1A" {
+		return data
+	}
+	fixed := append([]byte(nil), data...)
+	if e.Mapper != nil {
+		fixed[6] = fixed[6]&0x0F | *e.Mapper<<4
+		fixed[7] = fixed[7]&0x0F | *e.Mapper&0xF0
+	}
+	if e.FourScreen {
+		fixed[6] |= 0x08
+	}
+	return fixed
+}
+
+// Database holds the entries, by uppercase hex SHA-1
+type Database struct {
+	entries map[string]Entry
+}
+
+// Builtin returns a database of the entries shipped with gones
+func Builtin() *Database {
+	db := &Database{entries: make(map[string]Entry, len(builtin))}
+	for sha, entry := range builtin {
+		db.entries[sha] = entry
+	}
+	return db
+}
+
+// Len returns the number of entries
+func (db *Database) Len() int {
+	return len(db.entries)
+}
+
+// Add adds or replaces the entry of the ROM with a SHA-1
+func (db *Database) Add(sha1 string, entry Entry) error {
+	if err := entry.validate(); err != nil {
+		return fmt.Errorf("%s: %v", sha1, err)
+	}
+	db.entries[strings.ToUpper(sha1)] = entry
+	return nil
+}
+
+// LoadFile adds the entries of a JSON file, an object of entries by SHA-1,
+// over those already there. A missing file adds none.
+func (db *Database) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries map[string]Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for sha, entry := range entries {
+		if err := db.Add(sha, entry); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// Lookup finds the entry of an iNES image
+func (db *Database) Lookup(data []byte) (*Entry, bool) {
+	_, sha := library.Hash(data)
+	entry, ok := db.entries[sha]
+	if !ok {
+		return nil, false
+	}
+	return &entry, true
+}
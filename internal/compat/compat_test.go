@@ -0,0 +1,141 @@
+package compat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gones/internal/cartridge"
+	"gones/internal/library"
+	"gones/internal/region"
+)
+
+// testROM returns a 16 KB NROM image with a header of flags 6
+func testROM(flags6 uint8) []byte {
+	data := append([]byte("NES\x1A\x01\x00"), flags6)
+	data = append(data, make([]byte, 9)...)
+	prg := make([]byte, 16384)
+	for i := range prg {
+		prg[i] = uint8(i * 7)
+	}
+	return append(data, prg...)
+}
+
+func TestLookup_IgnoresHeader(t *testing.T) {
+	_, sha := library.Hash(testROM(0))
+	db := Builtin()
+	if err := db.Add(strings.ToLower(sha), Entry{Title: "Test", Region: "pal"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Found whatever the header says
+	for _, flags6 := range []uint8{0x00, 0x01, 0x40} {
+		entry, ok := db.Lookup(testROM(flags6))
+		if !ok || entry.Title != "Test" {
+			t.Errorf("Lookup with flags 6 $%02X = %v, %t, want the entry", flags6, entry, ok)
+		}
+	}
+	if entry, ok := db.Lookup(testROM(0)); ok {
+		if r, ok := entry.TVSystem(); !ok || r != region.PAL {
+			t.Errorf("TVSystem() = %v, %t, want PAL", r, ok)
+		}
+	}
+
+	other := testROM(0)
+	other[16] ^= 0xFF
+	if _, ok := db.Lookup(other); ok {
+		t.Error("found a ROM with other data")
+	}
+}
+
+func TestFixHeader(t *testing.T) {
+	mapper := uint8(0x4B) // 75
+	entry := Entry{Mapper: &mapper, FourScreen: true}
+	data := testROM(0x01) // Vertical mirroring, mapper 0
+	fixed := entry.FixHeader(data)
+
+	if data[6] != 0x01 {
+		t.Error("FixHeader changed the image it was given")
+	}
+	if fixed[6] != 0xB9 || fixed[7] != 0x40 {
+		t.Errorf("flags 6 and 7 = $%02X $%02X, want $B9 $40", fixed[6], fixed[7])
+	}
+	if string(fixed[16:]) != string(data[16:]) {
+		t.Error("FixHeader changed the ROM data")
+	}
+
+	cart, err := cartridge.LoadFromReader(strings.NewReader(string(fixed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.MapperID() != mapper || cart.GetMirrorMode() != cartridge.MirrorFourScreen {
+		t.Errorf("cartridge has mapper %d, mirroring %d; want %d, four-screen", cart.MapperID(), cart.GetMirrorMode(), mapper)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	db := Builtin()
+	if err := db.LoadFile(filepath.Join(dir, "missing.json")); err != nil {
+		t.Errorf("LoadFile of a missing file: %v", err)
+	}
+
+	_, sha := library.Hash(testROM(0))
+	path := filepath.Join(dir, "compat.json")
+	os.WriteFile(path, []byte(`{"`+sha+`": {"title": "Test", "mapper": 0, "ppu_alignment": 1}}`), 0644)
+	if err := db.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := db.Lookup(testROM(0x10))
+	if !ok {
+		t.Fatal("entry of the file not found")
+	}
+	if got, want := entry.String(), "mapper 0, PPU alignment 1"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{
+		`{"` + sha + `": {"region": "SECAM"}}`,
+		`{"` + sha + `": {"ppu_alignment": 3}}`,
+		`[]`,
+	} {
+		os.WriteFile(path, []byte(bad), 0644)
+		if err := db.LoadFile(path); err == nil {
+			t.Errorf("LoadFile(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestBuiltin(t *testing.T) {
+	for sha, entry := range builtin {
+		if len(sha) != 40 || strings.ToUpper(sha) != sha || strings.Trim(sha, "0123456789ABCDEF") != "" {
+			t.Errorf("%s (%s): not an uppercase hex SHA-1", sha, entry.Title)
+		}
+		if entry.Title == "" {
+			t.Errorf("%s: no title", sha)
+		}
+		if err := entry.validate(); err != nil {
+			t.Errorf("%s (%s): %v", sha, entry.Title, err)
+		}
+	}
+
+	// A listed ROM loads with what its entry forces
+	_, sha := library.Hash(testROM(0))
+	mapper := uint8(4)
+	saved := builtin
+	builtin = map[string]Entry{sha: {Title: "Test", Mapper: &mapper, FourScreen: true}}
+	defer func() { builtin = saved }()
+
+	entry, ok := Builtin().Lookup(testROM(0x01))
+	if !ok {
+		t.Fatal("listed ROM not found")
+	}
+	cart, err := cartridge.LoadFromReader(strings.NewReader(string(entry.FixHeader(testROM(0x01)))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.MapperID() != mapper || cart.GetMirrorMode() != cartridge.MirrorFourScreen {
+		t.Errorf("cartridge has mapper %d, mirroring %d; want %d, four-screen", cart.MapperID(), cart.GetMirrorMode(), mapper)
+	}
+}
@@ -97,6 +97,12 @@ type CPU struct {
 	enableLoopDetection bool
 	lastPC              uint16
 	pcStayCount         int
+
+	// Called with PC before each instruction is fetched (nil when unused)
+	fetchHook func(pc uint16)
+
+	// Set by a JAM opcode: the CPU stays stuck at PC until a reset
+	jammed bool
 }
 
 // MemoryInterface defines the interface for CPU memory access
@@ -156,13 +162,28 @@ func (cpu *CPU) Reset() {
 	cpu.cycles += 2
 	
 	// Total: 7 cycles for complete reset sequence
+	cpu.jammed = false
+}
+
+// Jammed returns whether a JAM opcode has locked up the CPU
+func (cpu *CPU) Jammed() bool {
+	return cpu.jammed
 }
 
 // Step executes a single CPU instruction and returns cycles taken.
 // This is the main execution loop called every CPU cycle.
 func (cpu *CPU) Step() uint64 {
+	// A jammed CPU does nothing while the rest of the machine keeps running
+	if cpu.jammed {
+		cpu.cycles += 2
+		return 2
+	}
+
 	// Capture PC for debugging
 	currentPC := cpu.PC
+	if cpu.fetchHook != nil {
+		cpu.fetchHook(currentPC)
+	}
 	
 	// Fetch instruction opcode from memory at PC
 	opcode := cpu.memory.Read(cpu.PC)
@@ -217,7 +238,9 @@ func (cpu *CPU) Step() uint64 {
 	
 	// Check for pending interrupts after instruction completion
 	// This implements the 1-instruction delay behavior
-	cpu.ProcessPendingInterrupts()
+	if !cpu.jammed {
+		cpu.ProcessPendingInterrupts()
+	}
 	
 	return totalCycles
 }
@@ -913,6 +936,14 @@ func (cpu *CPU) brk(address uint16) uint8 {
 
 // --- Unofficial Opcodes ---
 
+// jam stops the CPU on the JAM opcode: it fetches nothing more and ignores
+// interrupts until a reset
+func (cpu *CPU) jam() uint8 {
+	cpu.jammed = true
+	cpu.PC--
+	return 0
+}
+
 func (cpu *CPU) lax(address uint16) uint8 {
 	cpu.A = cpu.memory.Read(address)
 	cpu.X = cpu.A
@@ -1184,6 +1215,8 @@ func (cpu *CPU) executeInstruction(opcode uint8, address uint16, pageCrossed boo
 		return cpu.sre(address)
 	case 0x63, 0x67, 0x6F, 0x73, 0x77, 0x7F, 0x7B: // RRA
 		return cpu.rra(address)
+	case 0x02, 0x12, 0x22, 0x32, 0x42, 0x52, 0x62, 0x72, 0x92, 0xB2, 0xD2, 0xF2: // JAM
+		return cpu.jam()
 
 	default:
 		// Should not be reached if all opcodes are mapped
@@ -1480,10 +1513,30 @@ func (cpu *CPU) initInstructions() {
 	cpu.instructions[0x7B] = &Instruction{"RRA", 0x7B, 3, 7, AbsoluteY}
 	cpu.instructions[0x63] = &Instruction{"RRA", 0x63, 2, 8, IndexedIndirect}
 	cpu.instructions[0x73] = &Instruction{"RRA", 0x73, 2, 8, IndirectIndexed}
+
+	// JAM (also called KIL) locks up the CPU
+	for _, opcode := range jamOpcodes {
+		cpu.instructions[opcode] = &Instruction{"JAM", opcode, 1, 2, Implied}
+	}
 }
 
+// jamOpcodes are the opcodes that lock up the CPU until a reset
+var jamOpcodes = []uint8{0x02, 0x12, 0x22, 0x32, 0x42, 0x52, 0x62, 0x72, 0x92, 0xB2, 0xD2, 0xF2}
+
 // CPU Debug Methods
 
+// GetCycles returns the number of cycles run since power on
+func (cpu *CPU) GetCycles() uint64 {
+	return cpu.cycles
+}
+
+// SetFetchHook sets a function called with PC before each instruction's
+// opcode is fetched, so debuggers can tell code fetches from data reads.
+// Pass nil to remove it.
+func (cpu *CPU) SetFetchHook(hook func(pc uint16)) {
+	cpu.fetchHook = hook
+}
+
 // EnableDebugLogging enables/disables CPU instruction logging
 func (cpu *CPU) EnableDebugLogging(enable bool) {
 	cpu.enableDebugLogging = enable
@@ -0,0 +1,75 @@
+// Package cpu provides a 6502 disassembler for the debugging tools.
+package cpu
+
+import "fmt"
+
+// opcodeTable is the instruction table shared by Disassemble
+var opcodeTable = func() [256]*Instruction {
+	var cpu CPU
+	cpu.initInstructions()
+	return cpu.instructions
+}()
+
+// Disassemble decodes the instruction at pc, reading its bytes with read, and
+// returns its text (such as "LDA $0200,X") and its length in bytes. Bytes
+// that are not an opcode are shown as ".DB $xx" with a length of 1.
+func Disassemble(read func(address uint16) uint8, pc uint16) (string, int) {
+	opcode := read(pc)
+	instruction := opcodeTable[opcode]
+	if instruction == nil {
+		return fmt.Sprintf(".DB $%02X", opcode), 1
+	}
+
+	low := read(pc + 1)
+	word := uint16(read(pc+2))<<8 | uint16(low)
+	var operand string
+	switch instruction.Mode {
+	case Accumulator:
+		operand = "A"
+	case Immediate:
+		operand = fmt.Sprintf("#$%02X", low)
+	case ZeroPage:
+		operand = fmt.Sprintf("$%02X", low)
+	case ZeroPageX:
+		operand = fmt.Sprintf("$%02X,X", low)
+	case ZeroPageY:
+		operand = fmt.Sprintf("$%02X,Y", low)
+	case Relative:
+		operand = fmt.Sprintf("$%04X", pc+2+uint16(int8(low)))
+	case Absolute:
+		operand = fmt.Sprintf("$%04X", word)
+	case AbsoluteX:
+		operand = fmt.Sprintf("$%04X,X", word)
+	case AbsoluteY:
+		operand = fmt.Sprintf("$%04X,Y", word)
+	case Indirect:
+		operand = fmt.Sprintf("($%04X)", word)
+	case IndexedIndirect:
+		operand = fmt.Sprintf("($%02X,X)", low)
+	case IndirectIndexed:
+		operand = fmt.Sprintf("($%02X),Y", low)
+	}
+
+	if operand == "" {
+		return instruction.Name, int(instruction.Bytes)
+	}
+	return instruction.Name + " " + operand, int(instruction.Bytes)
+}
+
+// InstructionLength returns the length in bytes of the instruction with an
+// opcode (1 for bytes that are not an opcode)
+func InstructionLength(opcode uint8) int {
+	if instruction := opcodeTable[opcode]; instruction != nil {
+		return int(instruction.Bytes)
+	}
+	return 1
+}
+
+// Mnemonic returns the name of the instruction with an opcode, or "" for
+// bytes that are not an opcode
+func Mnemonic(opcode uint8) string {
+	if instruction := opcodeTable[opcode]; instruction != nil {
+		return instruction.Name
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package cpu
+
+import "testing"
+
+func TestDisassemble(t *testing.T) {
+	tests := []struct {
+		bytes  []uint8
+		text   string
+		length int
+	}{
+		{[]uint8{0xEA}, "NOP", 1},
+		{[]uint8{0x0A}, "ASL A", 1},
+		{[]uint8{0xA9, 0x10}, "LDA #$10", 2},
+		{[]uint8{0xB5, 0x80}, "LDA $80,X", 2},
+		{[]uint8{0xBD, 0x00, 0x02}, "LDA $0200,X", 3},
+		{[]uint8{0x6C, 0xFC, 0xFF}, "JMP ($FFFC)", 3},
+		{[]uint8{0xB1, 0x20}, "LDA ($20),Y", 2},
+		{[]uint8{0xD0, 0xFE}, "BNE $8000", 2},
+		{[]uint8{0x02}, ".DB $02", 1},
+	}
+
+	for _, test := range tests {
+		var memory [0x10000]uint8
+		copy(memory[0x8000:], test.bytes)
+		read := func(address uint16) uint8 { return memory[address] }
+
+		text, length := Disassemble(read, 0x8000)
+		if text != test.text || length != test.length {
+			t.Errorf("Disassemble(% X) = %q, %d, want %q, %d", test.bytes, text, length, test.text, test.length)
+		}
+	}
+}
+
+func TestSetFetchHook(t *testing.T) {
+	memory := NewMockMemory()
+	memory.data[0x8000] = 0xEA // NOP
+	memory.data[0x8001] = 0xA9 // LDA #$01
+	memory.data[0x8002] = 0x01
+
+	cpu := New(memory)
+	cpu.PC = 0x8000
+	var fetched []uint16
+	cpu.SetFetchHook(func(pc uint16) { fetched = append(fetched, pc) })
+	cpu.Step()
+	cpu.Step()
+
+	if len(fetched) != 2 || fetched[0] != 0x8000 || fetched[1] != 0x8001 {
+		t.Errorf("fetch hook saw %04X, want [8000 8001]", fetched)
+	}
+}
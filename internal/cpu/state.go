@@ -0,0 +1,54 @@
+// Package cpu implements CPU state snapshots.
+package cpu
+
+import "gones/internal/savestate"
+
+// SaveState writes the CPU registers, flags and interrupt lines
+func (cpu *CPU) SaveState(w *savestate.Writer) {
+	w.WriteTag("CPU ")
+	w.WriteU8(cpu.A)
+	w.WriteU8(cpu.X)
+	w.WriteU8(cpu.Y)
+	w.WriteU8(cpu.SP)
+	w.WriteU16(cpu.PC)
+
+	w.WriteBool(cpu.C)
+	w.WriteBool(cpu.Z)
+	w.WriteBool(cpu.I)
+	w.WriteBool(cpu.D)
+	w.WriteBool(cpu.B)
+	w.WriteBool(cpu.V)
+	w.WriteBool(cpu.N)
+
+	w.WriteU64(cpu.cycles)
+	w.WriteBool(cpu.nmiPending)
+	w.WriteBool(cpu.irqPending)
+	w.WriteBool(cpu.nmiPrevious)
+	w.WriteBool(cpu.interruptDelay)
+}
+
+// LoadState restores state written by SaveState
+func (cpu *CPU) LoadState(r *savestate.Reader) error {
+	r.ExpectTag("CPU ")
+	cpu.A = r.ReadU8()
+	cpu.X = r.ReadU8()
+	cpu.Y = r.ReadU8()
+	cpu.SP = r.ReadU8()
+	cpu.PC = r.ReadU16()
+
+	cpu.C = r.ReadBool()
+	cpu.Z = r.ReadBool()
+	cpu.I = r.ReadBool()
+	cpu.D = r.ReadBool()
+	cpu.B = r.ReadBool()
+	cpu.V = r.ReadBool()
+	cpu.N = r.ReadBool()
+
+	cpu.cycles = r.ReadU64()
+	cpu.nmiPending = r.ReadBool()
+	cpu.irqPending = r.ReadBool()
+	cpu.nmiPrevious = r.ReadBool()
+	cpu.interruptDelay = r.ReadBool()
+
+	return r.Err()
+}
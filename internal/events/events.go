@@ -0,0 +1,198 @@
+// Package events records the register writes of each frame with the PPU
+// position they happened at, for the event viewer's timing grid.
+package events
+
+import "fmt"
+
+const (
+	// CyclesPerScanline and Scanlines are the size of an NTSC frame's grid
+	CyclesPerScanline = 341
+	Scanlines         = 262
+
+	// MaxEventsPerFrame caps a frame's events; later writes are dropped
+	MaxEventsPerFrame = 8192
+)
+
+// Kind groups registers by what their writes do
+type Kind int
+
+const (
+	KindPPUControl Kind = iota // PPUCTRL, PPUMASK
+	KindPPUScroll              // PPUSCROLL, PPUADDR
+	KindPPUData                // PPUSTATUS, OAMADDR, OAMDATA, PPUDATA
+	KindAPU                    // Sound channels, status and frame counter
+	KindIO                     // OAM DMA, controller strobe and test registers
+	KindMapper                 // Cartridge registers
+)
+
+// Kinds lists the kinds in display order
+var Kinds = []Kind{KindPPUControl, KindPPUScroll, KindPPUData, KindAPU, KindIO, KindMapper}
+
+// String returns the name of the kind
+func (k Kind) String() string {
+	switch k {
+	case KindPPUControl:
+		return "ppu control"
+	case KindPPUScroll:
+		return "ppu scroll"
+	case KindPPUData:
+		return "ppu data"
+	case KindAPU:
+		return "apu"
+	case KindIO:
+		return "i/o"
+	case KindMapper:
+		return "mapper"
+	default:
+		return fmt.Sprintf("kind(%d)", int(k))
+	}
+}
+
+// ppuRegisterNames are the PPU registers by address & 7
+var ppuRegisterNames = [8]string{"PPUCTRL", "PPUMASK", "PPUSTATUS", "OAMADDR", "OAMDATA", "PPUSCROLL", "PPUADDR", "PPUDATA"}
+
+// Classify returns the kind of a register address
+func Classify(address uint16) Kind {
+	switch {
+	case address < 0x4000:
+		switch address & 0x0007 {
+		case 0, 1:
+			return KindPPUControl
+		case 5, 6:
+			return KindPPUScroll
+		default:
+			return KindPPUData
+		}
+	case address == 0x4014 || address == 0x4016 || (address >= 0x4018 && address < 0x4020):
+		return KindIO
+	case address < 0x4020:
+		return KindAPU
+	default:
+		return KindMapper
+	}
+}
+
+// RegisterName returns the name of a register, such as "PPUSCROLL" or
+// "PULSE1" ("" for cartridge registers, which depend on the mapper)
+func RegisterName(address uint16) string {
+	switch {
+	case address >= 0x2000 && address < 0x4000:
+		return ppuRegisterNames[address&0x0007]
+	case address >= 0x4000 && address < 0x4004:
+		return "PULSE1"
+	case address >= 0x4004 && address < 0x4008:
+		return "PULSE2"
+	case address >= 0x4008 && address < 0x400C:
+		return "TRIANGLE"
+	case address >= 0x400C && address < 0x4010:
+		return "NOISE"
+	case address >= 0x4010 && address < 0x4014:
+		return "DMC"
+	case address == 0x4014:
+		return "OAMDMA"
+	case address == 0x4015:
+		return "SNDCHN"
+	case address == 0x4016:
+		return "JOYSTROBE"
+	case address == 0x4017:
+		return "FRAMECNT"
+	default:
+		return ""
+	}
+}
+
+// Event is a register write and the PPU position it happened at
+type Event struct {
+	Scanline int // -1 (pre-render) to 260
+	Cycle    int // 0 to 340
+	Address  uint16
+	Value    uint8
+	Kind     Kind
+}
+
+// Row returns the grid row of the event, the pre-render line being row 0
+func (e Event) Row() int {
+	return e.Scanline + 1
+}
+
+// Position tells where the PPU is in the frame. ppu.PPU implements it.
+type Position interface {
+	GetFrameCount() uint64
+	GetScanline() int
+	GetCycle() int
+}
+
+// Log holds the events of the frame in progress and of the frame before it
+type Log struct {
+	ppu      Position
+	frame    uint64  // PPU frame of current
+	current  []Event // Events of the frame in progress, in order
+	previous []Event // Events of the last complete frame
+	dropped  int     // Writes of the current frame over MaxEventsPerFrame
+}
+
+// NewLog creates an empty log of the frames of ppu
+func NewLog(ppu Position) *Log {
+	return &Log{ppu: ppu, frame: ppu.GetFrameCount()}
+}
+
+// Record logs a register write at the current PPU position. It has the
+// signature of memory.Memory's register write hook.
+func (l *Log) Record(address uint16, value uint8) {
+	l.sync()
+	if len(l.current) >= MaxEventsPerFrame {
+		l.dropped++
+		return
+	}
+	l.current = append(l.current, Event{
+		Scanline: l.ppu.GetScanline(),
+		Cycle:    l.ppu.GetCycle(),
+		Address:  address,
+		Value:    value,
+		Kind:     Classify(address),
+	})
+}
+
+// sync moves on to the PPU's frame, keeping the frame before it
+func (l *Log) sync() {
+	frame := l.ppu.GetFrameCount()
+	if frame == l.frame {
+		return
+	}
+	if frame == l.frame+1 {
+		l.previous, l.current = l.current, l.previous[:0]
+	} else {
+		l.previous, l.current = nil, nil // Frames were skipped or rewound
+	}
+	l.frame = frame
+	l.dropped = 0
+}
+
+// Frame returns the PPU frame in progress
+func (l *Log) Frame() uint64 {
+	l.sync()
+	return l.frame
+}
+
+// Dropped returns the number of writes of the frame in progress that were
+// over MaxEventsPerFrame
+func (l *Log) Dropped() int {
+	l.sync()
+	return l.dropped
+}
+
+// Events returns a frame's worth of events in grid order: those of the frame
+// in progress up to the PPU position, then those of the previous frame after
+// it, so the grid always shows a whole frame, even when paused mid-frame.
+// The slice is only valid until the next Record.
+func (l *Log) Events() []Event {
+	l.sync()
+	row, cycle := l.ppu.GetScanline()+1, l.ppu.GetCycle()
+	events := l.current
+	for _, event := range l.previous {
+		if event.Row() > row || (event.Row() == row && event.Cycle > cycle) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
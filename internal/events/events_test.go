@@ -0,0 +1,100 @@
+package events
+
+import "testing"
+
+// testPPU is a PPU position set by the tests
+type testPPU struct {
+	frame    uint64
+	scanline int
+	cycle    int
+}
+
+func (p *testPPU) GetFrameCount() uint64 { return p.frame }
+func (p *testPPU) GetScanline() int      { return p.scanline }
+func (p *testPPU) GetCycle() int         { return p.cycle }
+
+func (p *testPPU) at(frame uint64, scanline, cycle int) {
+	p.frame, p.scanline, p.cycle = frame, scanline, cycle
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		address uint16
+		kind    Kind
+		name    string
+	}{
+		{0x2000, KindPPUControl, "PPUCTRL"},
+		{0x2001, KindPPUControl, "PPUMASK"},
+		{0x2005, KindPPUScroll, "PPUSCROLL"},
+		{0x3FFE, KindPPUScroll, "PPUADDR"}, // Mirror of $2006
+		{0x2007, KindPPUData, "PPUDATA"},
+		{0x4002, KindAPU, "PULSE1"},
+		{0x4015, KindAPU, "SNDCHN"},
+		{0x4017, KindAPU, "FRAMECNT"},
+		{0x4014, KindIO, "OAMDMA"},
+		{0x4016, KindIO, "JOYSTROBE"},
+		{0x8000, KindMapper, ""},
+		{0x5000, KindMapper, ""},
+	}
+	for _, test := range tests {
+		if kind := Classify(test.address); kind != test.kind {
+			t.Errorf("Classify($%04X) = %v, want %v", test.address, kind, test.kind)
+		}
+		if name := RegisterName(test.address); name != test.name {
+			t.Errorf("RegisterName($%04X) = %q, want %q", test.address, name, test.name)
+		}
+	}
+}
+
+func TestLogKeepsPreviousFrame(t *testing.T) {
+	ppu := &testPPU{}
+	log := NewLog(ppu)
+
+	// Frame 0: a scroll split at the top and the bottom of the screen
+	ppu.at(0, 10, 100)
+	log.Record(0x2005, 0x00)
+	ppu.at(0, 200, 300)
+	log.Record(0x2005, 0x80)
+
+	// Frame 1, paused on scanline 100: the first write is new, the second
+	// still comes from frame 0
+	ppu.at(1, 20, 50)
+	log.Record(0x2000, 0x90)
+	ppu.at(1, 100, 0)
+
+	events := log.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	if events[0].Address != 0x2000 || events[0].Scanline != 20 {
+		t.Errorf("first event = %+v, want the $2000 write of this frame", events[0])
+	}
+	if events[1].Value != 0x80 || events[1].Scanline != 200 || events[1].Kind != KindPPUScroll {
+		t.Errorf("second event = %+v, want the bottom split of the last frame", events[1])
+	}
+	if log.Frame() != 1 {
+		t.Errorf("Frame() = %d, want 1", log.Frame())
+	}
+
+	// Skipping frames forgets the old ones
+	ppu.at(5, 0, 0)
+	if events := log.Events(); len(events) != 0 {
+		t.Errorf("got %d events after skipping frames, want 0", len(events))
+	}
+}
+
+func TestLogCapsFrame(t *testing.T) {
+	ppu := &testPPU{}
+	log := NewLog(ppu)
+	for i := 0; i < MaxEventsPerFrame+5; i++ {
+		log.Record(0x2007, 0)
+	}
+	if len(log.Events()) != MaxEventsPerFrame || log.Dropped() != 5 {
+		t.Errorf("got %d events and %d dropped", len(log.Events()), log.Dropped())
+	}
+
+	ppu.at(1, -1, 0)
+	if log.Dropped() != 0 {
+		t.Errorf("Dropped() = %d in a new frame, want 0", log.Dropped())
+	}
+}
@@ -33,16 +33,84 @@ type Window interface {
 	// SwapBuffers presents the rendered frame
 	SwapBuffers()
 
-	// PollEvents processes input events
-	PollEvents() []InputEvent
+	// PollEvents processes input events, appending them to events and
+	// returning the result, so callers can pass the last frame's slice
+	// truncated to reuse its array
+	PollEvents(events []InputEvent) []InputEvent
 
-	// RenderFrame renders a NES frame buffer to the window
-	RenderFrame(frameBuffer [256 * 240]uint32) error
+	// RenderFrame renders a NES frame buffer to the window. The buffer is
+	// only read during the call, so callers can reuse it for the next frame.
+	RenderFrame(frameBuffer *[256 * 240]uint32) error
 
 	// Cleanup releases window resources
 	Cleanup() error
 }
 
+// AudioOutput is implemented by windows that can play the emulator's audio
+type AudioOutput interface {
+	// QueueAudio queues mono samples (-1.0 to 1.0) at Config.AudioSampleRate for playback
+	QueueAudio(samples []float32) error
+}
+
+// AudioClock is implemented by audio outputs that can pace the main loop, so
+// video follows the rate the device plays samples at instead of the wall clock
+type AudioClock interface {
+	// WaitAudio blocks until no more than the configured latency of audio is
+	// queued. It returns false if it could not wait (no device, or the device
+	// stopped consuming samples); the caller then paces with sleeps.
+	WaitAudio() bool
+}
+
+// AudioStats is implemented by audio outputs that track playback problems
+type AudioStats interface {
+	// AudioUnderruns returns how many times the device ran out of queued
+	// samples while playing
+	AudioUnderruns() uint64
+}
+
+// AudioPosition is implemented by audio outputs that know how far playback
+// has got
+type AudioPosition interface {
+	// AudioPlayed returns how many of the queued samples the device has
+	// played since it opened
+	AudioPlayed() uint64
+
+	// AudioQueued returns how many samples are queued and not yet played
+	AudioQueued() int
+}
+
+// MicrophoneInput is implemented by windows that can capture the host's
+// microphone, opened with Config.Microphone
+type MicrophoneInput interface {
+	// MicrophoneLevel returns the peak level, 0-1, of the sound captured
+	// since the last call
+	MicrophoneLevel() float64
+}
+
+// MultiWindowBackend is implemented by backends that can have more than one
+// window open. Each window's PollEvents returns the input sent to that
+// window; the first window also gets the gamepads and the audio.
+type MultiWindowBackend interface {
+	// MaxWindows returns how many windows can be open at once, 0 for no limit
+	MaxWindows() int
+}
+
+// WindowLimit returns how many windows a backend can have open at once, 0
+// for no limit. Backends that do not implement MultiWindowBackend have one.
+func WindowLimit(backend Backend) int {
+	if multi, ok := backend.(MultiWindowBackend); ok {
+		return multi.MaxWindows()
+	}
+	return 1
+}
+
+// WindowFocus is implemented by windows that know whether they have the
+// input focus
+type WindowFocus interface {
+	// IsFocused reports whether the window is in the foreground
+	IsFocused() bool
+}
+
 // Config contains configuration for graphics backends
 type Config struct {
 	// Window configuration
@@ -53,9 +121,24 @@ type Config struct {
 	VSync        bool
 
 	// Rendering configuration
-	Filter       string // "nearest", "linear"
-	AspectRatio  string // "4:3", "stretch"
+	Filter       string      // "nearest", "linear", "crt"
+	CRT          CRTSettings // Intensities for the "crt" filter
+	AspectRatio  string      // See AspectModes
+	TerminalMode string      // See TerminalModes (terminal backend)
 	
+	// Gamepad configuration
+	GamepadDeadzone    float64  // Analog stick deadzone (0.0-1.0)
+	GamepadAssignments []string // Per-player device: "" (auto), "none", or GUID/name fragment
+
+	// Audio configuration (backends implementing AudioOutput)
+	AudioEnabled    bool
+	AudioSampleRate int // Rate of the queued samples
+	AudioBufferSize int // Device buffer in samples
+	AudioLatency    int // Maximum queued audio in milliseconds
+
+	// Capture the host's microphone (backends implementing MicrophoneInput)
+	Microphone bool
+
 	// Backend-specific options
 	Headless     bool
 	Debug        bool
@@ -68,6 +151,15 @@ type InputEvent struct {
 	Button    Button
 	Pressed   bool
 	Modifiers ModifierKey
+
+	// Pointer position in NES pixels and held mouse buttons (InputEventTypeMouse).
+	// Positions outside 256x240 are off the NES picture.
+	MouseX       int
+	MouseY       int
+	MouseButtons MouseButton
+
+	// Player (0-3) whose gamepad was unplugged (InputEventTypeGamepadDisconnected)
+	Player int
 }
 
 // InputEventType represents the type of input event
@@ -77,6 +169,17 @@ const (
 	InputEventTypeKey InputEventType = iota
 	InputEventTypeButton
 	InputEventTypeQuit
+	InputEventTypeMouse
+	InputEventTypeGamepadDisconnected // A gamepad assigned to a player was unplugged
+)
+
+// MouseButton is a bit mask of mouse buttons
+type MouseButton int
+
+const (
+	MouseButtonLeft MouseButton = 1 << iota
+	MouseButtonRight
+	MouseButtonMiddle
 )
 
 // Key represents keyboard keys
@@ -119,6 +222,48 @@ const (
 	KeyF10
 	KeyF11
 	KeyF12
+	KeyTab
+	KeyMinus
+	KeyEqual
+
+	// Further keys, which hotkeys can be bound to
+	KeyB
+	KeyC
+	KeyE
+	KeyF
+	KeyG
+	KeyH
+	KeyI
+	KeyL
+	KeyM
+	KeyN
+	KeyO
+	KeyP
+	KeyQ
+	KeyR
+	KeyT
+	KeyU
+	KeyV
+	KeyY
+	Key0
+	Key9
+	KeyBackspace
+	KeyBackquote
+	KeyComma
+	KeyPeriod
+	KeySlash
+	KeySemicolon
+	KeyQuote
+	KeyBackslash
+	KeyBracketLeft
+	KeyBracketRight
+	KeyInsert
+	KeyDelete
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyPause
 )
 
 // Button represents controller buttons
@@ -143,6 +288,23 @@ const (
 	Button2Down
 	Button2Left
 	Button2Right
+	// Player 3 and 4 controller buttons (Four Score)
+	Button3A
+	Button3B
+	Button3Select
+	Button3Start
+	Button3Up
+	Button3Down
+	Button3Left
+	Button3Right
+	Button4A
+	Button4B
+	Button4Select
+	Button4Start
+	Button4Up
+	Button4Down
+	Button4Left
+	Button4Right
 )
 
 // ModifierKey represents modifier keys
@@ -163,6 +325,7 @@ const (
 	BackendEbitengine BackendType = "ebitengine"
 	BackendHeadless  BackendType = "headless"
 	BackendTerminal  BackendType = "terminal"
+	BackendSDL2      BackendType = "sdl2"
 )
 
 // CreateBackend creates a graphics backend of the specified type
@@ -174,6 +337,8 @@ func CreateBackend(backendType BackendType) (Backend, error) {
 		return NewHeadlessBackend(), nil
 	case BackendTerminal:
 		return NewTerminalBackend(), nil
+	case BackendSDL2:
+		return NewSDL2Backend(), nil
 	default:
 		// Default to Ebitengine for GUI mode
 		return NewEbitengineBackend(), nil
@@ -0,0 +1,22 @@
+package graphics
+
+import "testing"
+
+func TestWindowLimit(t *testing.T) {
+	if limit := WindowLimit(NewTerminalBackend()); limit != 1 {
+		t.Errorf("Expected the terminal backend to have 1 window, got %d", limit)
+	}
+
+	backend := NewHeadlessBackend()
+	if limit := WindowLimit(backend); limit != 0 {
+		t.Errorf("Expected the headless backend to have no window limit, got %d", limit)
+	}
+	if err := backend.Initialize(Config{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := backend.CreateWindow("gones", 256, 240); err != nil {
+			t.Fatalf("Window %d: %v", i+1, err)
+		}
+	}
+}
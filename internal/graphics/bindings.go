@@ -0,0 +1,229 @@
+// Package graphics provides input binding definitions shared by all backends.
+package graphics
+
+import "strings"
+
+// ButtonBindings lists the physical inputs bound to each NES button, indexed
+// in ControllerButtons order (A, B, Select, Start, Up, Down, Left, Right)
+type ButtonBindings [8][]string
+
+// InputBindings holds the keyboard and gamepad bindings for each player.
+//
+// Keyboard entries are key names ("W", "ArrowUp", "Enter", "ShiftRight", ...).
+// Gamepad entries are standard layout button names ("RightBottom", "CenterRight", ...),
+// which apply to gamepads with a known layout, or raw "ButtonN" names, which apply
+// to gamepads without one.
+type InputBindings struct {
+	Keyboard [MaxGamepadPlayers]ButtonBindings
+	Gamepad  [MaxGamepadPlayers]ButtonBindings
+}
+
+// CapturedInput is a physical input reported while capturing for a rebind
+type CapturedInput struct {
+	Gamepad bool   // True for a gamepad button, false for a key
+	Player  int    // Player the gamepad is assigned to (gamepad only)
+	Name    string // Binding name usable in InputBindings
+}
+
+// RebindableWindow is implemented by windows that support runtime input remapping
+type RebindableWindow interface {
+	// SetInputBindings replaces the active key and gamepad bindings
+	SetInputBindings(bindings InputBindings)
+
+	// CaptureNextInput reports the next key or gamepad button press to callback
+	// instead of processing it normally. The callback runs on the update goroutine.
+	CaptureNextInput(callback func(input CapturedInput))
+
+	// CancelCapture stops a pending capture without reporting anything
+	CancelCapture()
+}
+
+// StandardGamepadButtonNames are the binding names for the standard gamepad layout,
+// in the same order as Ebitengine's StandardGamepadButton values
+var StandardGamepadButtonNames = []string{
+	"RightBottom", "RightRight", "RightLeft", "RightTop",
+	"FrontTopLeft", "FrontTopRight", "FrontBottomLeft", "FrontBottomRight",
+	"CenterLeft", "CenterRight", "LeftStick", "RightStick",
+	"LeftTop", "LeftBottom", "LeftLeft", "LeftRight", "CenterCenter",
+}
+
+// gamepadButtonAliases maps friendlier gamepad names to standard layout names
+var gamepadButtonAliases = map[string]string{
+	"south":     "RightBottom",
+	"east":      "RightRight",
+	"west":      "RightLeft",
+	"north":     "RightTop",
+	"back":      "CenterLeft",
+	"select":    "CenterLeft",
+	"start":     "CenterRight",
+	"guide":     "CenterCenter",
+	"dpadup":    "LeftTop",
+	"dpaddown":  "LeftBottom",
+	"dpadleft":  "LeftLeft",
+	"dpadright": "LeftRight",
+}
+
+// keyNameAliases maps key names used by older configs (SDL style) to Ebitengine names
+var keyNameAliases = map[string]string{
+	"return": "Enter",
+	"esc":    "Escape",
+	"lshift": "ShiftLeft",
+	"rshift": "ShiftRight",
+	"lctrl":  "ControlLeft",
+	"rctrl":  "ControlRight",
+	"lalt":   "AltLeft",
+	"ralt":   "AltRight",
+}
+
+// DefaultInputBindings returns the built-in bindings: arrows/WASD, J/Z, K/X, Enter and
+// Space for player 1, number keys 1-8 for player 2, the numeric keypad for player 3
+// (player 4 is gamepad only), and the usual gamepad layout
+func DefaultInputBindings() InputBindings {
+	var b InputBindings
+	b.Keyboard[0] = ButtonBindings{
+		{"J", "Z"}, {"K", "X"}, {"Space"}, {"Enter"},
+		{"W", "ArrowUp"}, {"S", "ArrowDown"}, {"A", "ArrowLeft"}, {"D", "ArrowRight"},
+	}
+	b.Keyboard[1] = ButtonBindings{
+		{"5"}, {"6"}, {"8"}, {"7"},
+		{"1"}, {"2"}, {"3"}, {"4"},
+	}
+	b.Keyboard[2] = ButtonBindings{
+		{"Numpad3"}, {"Numpad1"}, {"NumpadAdd"}, {"NumpadEnter"},
+		{"Numpad8"}, {"Numpad2"}, {"Numpad4"}, {"Numpad6"},
+	}
+	for player := 0; player < MaxGamepadPlayers; player++ {
+		b.Gamepad[player] = DefaultGamepadBindings()
+	}
+	return b
+}
+
+// DefaultGamepadBindings returns the default gamepad bindings for one player.
+// Face buttons keep the NES layout: B on the left/bottom, A on the right.
+func DefaultGamepadBindings() ButtonBindings {
+	return ButtonBindings{
+		{"RightRight", "Button1"}, {"RightBottom", "Button0"},
+		{"CenterLeft", "Button8"}, {"CenterRight", "Button9"},
+		{"LeftTop"}, {"LeftBottom"}, {"LeftLeft"}, {"LeftRight"},
+	}
+}
+
+// ParseBindingList splits a comma separated binding string ("W, ArrowUp") into names
+func ParseBindingList(s string) []string {
+	var names []string
+	for _, part := range strings.Split(s, ",") {
+		if name := strings.TrimSpace(part); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// FormatBindingList joins binding names into a comma separated string
+func FormatBindingList(names []string) string {
+	return strings.Join(names, ",")
+}
+
+// NormalizeKeyName maps legacy key name aliases to the names used by the backends
+func NormalizeKeyName(name string) string {
+	if alias, ok := keyNameAliases[strings.ToLower(name)]; ok {
+		return alias
+	}
+	return name
+}
+
+// keyCodesByName maps key binding names to the keys reported as key events
+var keyCodesByName = map[string]Key{
+	"Escape": KeyEscape, "Enter": KeyEnter, "Space": KeySpace,
+	"ArrowUp": KeyUp, "ArrowDown": KeyDown, "ArrowLeft": KeyLeft, "ArrowRight": KeyRight,
+	"W": KeyW, "A": KeyA, "S": KeyS, "D": KeyD, "J": KeyJ, "K": KeyK, "X": KeyX, "Z": KeyZ,
+	"1": Key1, "2": Key2, "3": Key3, "4": Key4, "5": Key5, "6": Key6, "7": Key7, "8": Key8,
+	"F1": KeyF1, "F2": KeyF2, "F3": KeyF3, "F4": KeyF4, "F5": KeyF5, "F6": KeyF6,
+	"F7": KeyF7, "F8": KeyF8, "F9": KeyF9, "F10": KeyF10, "F11": KeyF11, "F12": KeyF12,
+	"Tab": KeyTab, "Minus": KeyMinus, "Equal": KeyEqual,
+	"B": KeyB, "C": KeyC, "E": KeyE, "F": KeyF, "G": KeyG, "H": KeyH, "I": KeyI, "L": KeyL,
+	"M": KeyM, "N": KeyN, "O": KeyO, "P": KeyP, "Q": KeyQ, "R": KeyR, "T": KeyT, "U": KeyU,
+	"V": KeyV, "Y": KeyY, "0": Key0, "9": Key9,
+	"Backspace": KeyBackspace, "Backquote": KeyBackquote, "Comma": KeyComma, "Period": KeyPeriod,
+	"Slash": KeySlash, "Semicolon": KeySemicolon, "Quote": KeyQuote, "Backslash": KeyBackslash,
+	"BracketLeft": KeyBracketLeft, "BracketRight": KeyBracketRight,
+	"Insert": KeyInsert, "Delete": KeyDelete, "Home": KeyHome, "End": KeyEnd,
+	"PageUp": KeyPageUp, "PageDown": KeyPageDown, "Pause": KeyPause,
+}
+
+// bindingKeyID returns the name used to match key bindings in backends that
+// look keys up by name: case-insensitive, with "Digit1" matching "1"
+func bindingKeyID(name string) string {
+	id := strings.ToLower(NormalizeKeyName(name))
+	if rest, ok := strings.CutPrefix(id, "digit"); ok && rest != "" {
+		return rest
+	}
+	return id
+}
+
+// NormalizeGamepadButtonName returns the canonical gamepad binding name and whether it
+// is a standard layout button. Raw names ("Button3") return their index as well.
+func NormalizeGamepadButtonName(name string) (canonical string, standard bool, index int, ok bool) {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	if alias, found := gamepadButtonAliases[lower]; found {
+		lower = strings.ToLower(alias)
+	}
+	for i, standardName := range StandardGamepadButtonNames {
+		if strings.ToLower(standardName) == lower {
+			return standardName, true, i, true
+		}
+	}
+
+	if strings.HasPrefix(lower, "button") {
+		index := 0
+		digits := lower[len("button"):]
+		if digits == "" || len(digits) > 2 {
+			return "", false, 0, false
+		}
+		for _, c := range digits {
+			if c < '0' || c > '9' {
+				return "", false, 0, false
+			}
+			index = index*10 + int(c-'0')
+		}
+		return "Button" + digits, false, index, true
+	}
+
+	return "", false, 0, false
+}
+
+// ControllerButtonIndex returns the ControllerButtons index of any player's button, or -1
+func ControllerButtonIndex(button Button) int {
+	player := ButtonPlayer(button)
+	if player < 0 {
+		return -1
+	}
+	return int(button - playerButtonBase[player])
+}
+
+// ControllerButtonName returns a short display name for a controller button index
+func ControllerButtonName(index int) string {
+	names := [8]string{"A", "B", "SELECT", "START", "UP", "DOWN", "LEFT", "RIGHT"}
+	if index < 0 || index >= len(names) {
+		return "?"
+	}
+	return names[index]
+}
+
+// Bind makes name the only input for the button, removing it from the player's
+// other buttons so a single key never triggers two buttons by accident
+func (b *ButtonBindings) Bind(index int, name string) {
+	if index < 0 || index >= len(b) {
+		return
+	}
+	for i := range b {
+		kept := b[i][:0:0]
+		for _, existing := range b[i] {
+			if !strings.EqualFold(existing, name) {
+				kept = append(kept, existing)
+			}
+		}
+		b[i] = kept
+	}
+	b[index] = []string{name}
+}
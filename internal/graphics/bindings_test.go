@@ -0,0 +1,89 @@
+package graphics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBindingList(t *testing.T) {
+	got := ParseBindingList(" W, ArrowUp ,,")
+	if !reflect.DeepEqual(got, []string{"W", "ArrowUp"}) {
+		t.Errorf("Expected [W ArrowUp], got %v", got)
+	}
+	if ParseBindingList("") != nil {
+		t.Error("Expected empty string to parse to no bindings")
+	}
+	if FormatBindingList(got) != "W,ArrowUp" {
+		t.Errorf("Expected round trip to W,ArrowUp, got %s", FormatBindingList(got))
+	}
+}
+
+func TestNormalizeKeyName(t *testing.T) {
+	if NormalizeKeyName("Return") != "Enter" {
+		t.Errorf("Expected Return to map to Enter, got %s", NormalizeKeyName("Return"))
+	}
+	if NormalizeKeyName("RCtrl") != "ControlRight" {
+		t.Errorf("Expected RCtrl to map to ControlRight, got %s", NormalizeKeyName("RCtrl"))
+	}
+	if NormalizeKeyName("W") != "W" {
+		t.Error("Expected regular key names to be unchanged")
+	}
+}
+
+func TestBindingKeyID(t *testing.T) {
+	if bindingKeyID("Digit1") != "1" {
+		t.Errorf("Expected Digit1 to match 1, got %s", bindingKeyID("Digit1"))
+	}
+	if bindingKeyID("ArrowUp") != bindingKeyID("arrowup") {
+		t.Error("Expected key IDs to be case-insensitive")
+	}
+}
+
+func TestNormalizeGamepadButtonName(t *testing.T) {
+	name, standard, index, ok := NormalizeGamepadButtonName("south")
+	if !ok || !standard || name != "RightBottom" || index != 0 {
+		t.Errorf("Expected south -> RightBottom (0), got %s %v %d %v", name, standard, index, ok)
+	}
+
+	name, standard, index, ok = NormalizeGamepadButtonName("centerright")
+	if !ok || !standard || name != "CenterRight" || index != 9 {
+		t.Errorf("Expected CenterRight (9), got %s %v %d %v", name, standard, index, ok)
+	}
+
+	name, standard, index, ok = NormalizeGamepadButtonName("Button12")
+	if !ok || standard || name != "Button12" || index != 12 {
+		t.Errorf("Expected raw Button12, got %s %v %d %v", name, standard, index, ok)
+	}
+
+	for _, invalid := range []string{"", "Button", "Buttonx", "Button123", "Trigger"} {
+		if _, _, _, ok := NormalizeGamepadButtonName(invalid); ok {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestButtonBindings_BindRemovesDuplicates(t *testing.T) {
+	b := DefaultInputBindings().Keyboard[0]
+
+	// Bind J (currently A) to Start
+	b.Bind(3, "j")
+
+	if !reflect.DeepEqual(b[3], []string{"j"}) {
+		t.Errorf("Expected Start bound only to j, got %v", b[3])
+	}
+	if !reflect.DeepEqual(b[0], []string{"Z"}) {
+		t.Errorf("Expected A to keep only Z, got %v", b[0])
+	}
+}
+
+func TestControllerButtonIndex(t *testing.T) {
+	if ControllerButtonIndex(ButtonStart) != 3 {
+		t.Errorf("Expected Start at index 3, got %d", ControllerButtonIndex(ButtonStart))
+	}
+	if ControllerButtonIndex(Button2Right) != 7 {
+		t.Errorf("Expected Button2Right at index 7, got %d", ControllerButtonIndex(Button2Right))
+	}
+	if ControllerButtonIndex(ButtonUnknown) != -1 {
+		t.Error("Expected unknown button to have no index")
+	}
+}
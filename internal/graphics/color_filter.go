@@ -0,0 +1,194 @@
+// Package graphics provides the color vision deficiency filters of the video processor.
+package graphics
+
+import (
+	"fmt"
+	"math"
+)
+
+// Color filters for Config.ColorFilter. The simulations show how the picture
+// looks with each type of color blindness; the daltonize filters shift the
+// colors a viewer with that type cannot tell apart into ones they can.
+const (
+	ColorFilterNone                  = "none"
+	ColorFilterProtanopia            = "protanopia"   // No red cones
+	ColorFilterDeuteranopia          = "deuteranopia" // No green cones
+	ColorFilterTritanopia            = "tritanopia"   // No blue cones
+	ColorFilterDaltonizeProtanopia   = "daltonize-protanopia"
+	ColorFilterDaltonizeDeuteranopia = "daltonize-deuteranopia"
+	ColorFilterDaltonizeTritanopia   = "daltonize-tritanopia"
+)
+
+// ColorFilters lists the supported color filters
+var ColorFilters = []string{
+	ColorFilterNone,
+	ColorFilterProtanopia, ColorFilterDeuteranopia, ColorFilterTritanopia,
+	ColorFilterDaltonizeProtanopia, ColorFilterDaltonizeDeuteranopia, ColorFilterDaltonizeTritanopia,
+}
+
+// colorMatrix transforms linear RGB
+type colorMatrix [3][3]float64
+
+// Simulation matrices for full severity dichromacy in linear RGB (Machado,
+// Oliveira and Fernandes 2009)
+var (
+	protanopiaMatrix = colorMatrix{
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	}
+	deuteranopiaMatrix = colorMatrix{
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	}
+	tritanopiaMatrix = colorMatrix{
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	}
+)
+
+// Daltonization moves the color difference lost by a simulation into the
+// channels the viewer still sees: red-green loss into green and blue,
+// blue-yellow loss into red and green
+var (
+	redGreenShift = colorMatrix{
+		{0, 0, 0},
+		{0.7, 1, 0},
+		{0.7, 0, 1},
+	}
+	blueYellowShift = colorMatrix{
+		{1, 0, 0.7},
+		{0, 1, 0.7},
+		{0, 0, 0},
+	}
+)
+
+// mul returns m × n
+func (m colorMatrix) mul(n colorMatrix) colorMatrix {
+	var out colorMatrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				out[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+	return out
+}
+
+// daltonizeMatrix returns the matrix adding the shifted simulation error
+// to a color: I + shift × (I - simulation)
+func daltonizeMatrix(simulation, shift colorMatrix) colorMatrix {
+	var loss colorMatrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			loss[i][j] = -simulation[i][j]
+		}
+		loss[i][i]++
+	}
+	out := shift.mul(loss)
+	for i := 0; i < 3; i++ {
+		out[i][i]++
+	}
+	return out
+}
+
+// linearOutputSteps is the resolution of the linear to sRGB table
+const linearOutputSteps = 4096
+
+// colorFilter applies a color matrix in linear RGB
+type colorFilter struct {
+	matrix   colorMatrix
+	toLinear [256]float64                 // sRGB channel value to linear
+	toSRGB   [linearOutputSteps + 1]uint8 // Linear (0-1 in steps) to sRGB channel value
+	output   []uint32
+}
+
+// newColorFilter returns the filter called name, or nil for "none"
+func newColorFilter(name string) (*colorFilter, error) {
+	var matrix colorMatrix
+	switch name {
+	case ColorFilterNone, "":
+		return nil, nil
+	case ColorFilterProtanopia:
+		matrix = protanopiaMatrix
+	case ColorFilterDeuteranopia:
+		matrix = deuteranopiaMatrix
+	case ColorFilterTritanopia:
+		matrix = tritanopiaMatrix
+	case ColorFilterDaltonizeProtanopia:
+		matrix = daltonizeMatrix(protanopiaMatrix, redGreenShift)
+	case ColorFilterDaltonizeDeuteranopia:
+		matrix = daltonizeMatrix(deuteranopiaMatrix, redGreenShift)
+	case ColorFilterDaltonizeTritanopia:
+		matrix = daltonizeMatrix(tritanopiaMatrix, blueYellowShift)
+	default:
+		return nil, fmt.Errorf("unknown color filter: %s", name)
+	}
+
+	f := &colorFilter{matrix: matrix}
+	for i := range f.toLinear {
+		f.toLinear[i] = srgbToLinear(float64(i) / 255)
+	}
+	for i := range f.toSRGB {
+		f.toSRGB[i] = uint8(math.Round(linearToSRGB(float64(i)/linearOutputSteps) * 255))
+	}
+	return f, nil
+}
+
+// apply returns the filtered frame. The returned buffer is reused by the next call.
+func (f *colorFilter) apply(frame []uint32) []uint32 {
+	if len(f.output) != len(frame) {
+		f.output = make([]uint32, len(frame))
+	}
+	m := &f.matrix
+	for i, pixel := range frame {
+		r := f.toLinear[pixel>>16&0xFF]
+		g := f.toLinear[pixel>>8&0xFF]
+		b := f.toLinear[pixel&0xFF]
+		f.output[i] = uint32(f.encode(m[0][0]*r+m[0][1]*g+m[0][2]*b))<<16 |
+			uint32(f.encode(m[1][0]*r+m[1][1]*g+m[1][2]*b))<<8 |
+			uint32(f.encode(m[2][0]*r+m[2][1]*g+m[2][2]*b))
+	}
+	return f.output
+}
+
+// encode converts a linear channel value to sRGB, clamping it to 0-1
+func (f *colorFilter) encode(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return f.toSRGB[int(v*linearOutputSteps+0.5)]
+}
+
+// srgbToLinear removes the sRGB gamma from a 0-1 channel value
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB applies the sRGB gamma to a 0-1 channel value
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// SetColorFilter selects the color filter by name (see ColorFilters); "none"
+// disables it
+func (vp *VideoProcessor) SetColorFilter(name string) error {
+	filter, err := newColorFilter(name)
+	if err != nil {
+		return err
+	}
+	vp.colorFilter = filter
+	return nil
+}
@@ -0,0 +1,74 @@
+package graphics
+
+import "testing"
+
+func TestColorFilters_KeepGrays(t *testing.T) {
+	frame := []uint32{0x000000, 0x7F7F7F, 0xFFFFFF}
+	vp := NewVideoProcessor(1, 1, 1)
+	for _, name := range ColorFilters {
+		if err := vp.SetColorFilter(name); err != nil {
+			t.Fatalf("SetColorFilter(%s) failed: %v", name, err)
+		}
+		out := vp.ProcessFrame(frame)
+		for i, pixel := range out {
+			if diff := int(pixel&0xFF) - int(frame[i]&0xFF); pixel>>16 != pixel&0xFF || diff < -1 || diff > 1 {
+				t.Errorf("%s: expected gray %06X to stay gray, got %06X", name, frame[i], pixel)
+			}
+		}
+	}
+}
+
+func TestColorFilters_SimulateRedGreenConfusion(t *testing.T) {
+	vp := NewVideoProcessor(1, 1, 1)
+	if err := vp.SetColorFilter(ColorFilterDeuteranopia); err != nil {
+		t.Fatalf("SetColorFilter failed: %v", err)
+	}
+	out := vp.ProcessFrame([]uint32{0xFF0000, 0x00FF00})
+	red, green := out[0], out[1]
+
+	// Both hues collapse onto the same yellow-brown axis: no blue, and red
+	// and green channels in similar proportion
+	if red&0xFF > 0x20 || green&0xFF > 0x40 {
+		t.Errorf("Expected little blue in the simulation, got %06X and %06X", red, green)
+	}
+	if red>>16 < red>>8&0xFF/2 || green>>16 < green>>8&0xFF/2 {
+		t.Errorf("Expected red and green to look alike, got %06X and %06X", red, green)
+	}
+}
+
+func TestColorFilters_DaltonizeSeparatesConfusedColors(t *testing.T) {
+	simulate := NewVideoProcessor(1, 1, 1)
+	simulate.SetColorFilter(ColorFilterDeuteranopia)
+	daltonize := NewVideoProcessor(1, 1, 1)
+	daltonize.SetColorFilter(ColorFilterDaltonizeDeuteranopia)
+
+	// Seen by a deuteranope, the corrected colors differ more than the originals
+	distance := func(frame []uint32) int {
+		seen := simulate.ProcessFrame(frame)
+		d := 0
+		for shift := 0; shift <= 16; shift += 8 {
+			c := int(seen[0]>>shift&0xFF) - int(seen[1]>>shift&0xFF)
+			d += c * c
+		}
+		return d
+	}
+	original := []uint32{0xC04040, 0x40A040}
+	corrected := append([]uint32(nil), daltonize.ProcessFrame(original)...)
+	if distance(corrected) <= distance(original) {
+		t.Errorf("Expected daltonization to separate the colors: %d <= %d", distance(corrected), distance(original))
+	}
+}
+
+func TestSetColorFilter_Unknown(t *testing.T) {
+	vp := NewVideoProcessor(1, 1, 1)
+	if err := vp.SetColorFilter("sepia"); err == nil {
+		t.Error("Expected an error for an unknown color filter")
+	}
+	frame := []uint32{0x123456}
+	if err := vp.SetColorFilter(ColorFilterNone); err != nil {
+		t.Fatalf("SetColorFilter(none) failed: %v", err)
+	}
+	if out := vp.ProcessFrame(frame); &out[0] != &frame[0] {
+		t.Error("Expected the frame to pass through without a filter")
+	}
+}
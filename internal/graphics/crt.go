@@ -0,0 +1,42 @@
+// Package graphics provides CRT filter settings shared by the backends.
+package graphics
+
+// FilterCRT is the Config.Filter value that selects the CRT post-processing filter
+const FilterCRT = "crt"
+
+// CRTSettings holds the CRT filter intensities, each from 0 (off) to 1 (strongest)
+type CRTSettings struct {
+	Scanlines  float32 // Dark gaps between scanlines
+	ShadowMask float32 // RGB shadow mask pattern
+	Curvature  float32 // Barrel distortion of the tube
+	Bloom      float32 // Glow around bright pixels
+}
+
+// DefaultCRTSettings returns moderate CRT filter intensities
+func DefaultCRTSettings() CRTSettings {
+	return CRTSettings{
+		Scanlines:  0.5,
+		ShadowMask: 0.3,
+		Curvature:  0.2,
+		Bloom:      0.25,
+	}
+}
+
+// Clamped returns the settings limited to the 0-1 range
+func (s CRTSettings) Clamped() CRTSettings {
+	clamp := func(v float32) float32 {
+		if v < 0 {
+			return 0
+		}
+		if v > 1 {
+			return 1
+		}
+		return v
+	}
+	return CRTSettings{
+		Scanlines:  clamp(s.Scanlines),
+		ShadowMask: clamp(s.ShadowMask),
+		Curvature:  clamp(s.Curvature),
+		Bloom:      clamp(s.Bloom),
+	}
+}
@@ -0,0 +1,11 @@
+package graphics
+
+import "testing"
+
+func TestCRTSettings_Clamped(t *testing.T) {
+	s := CRTSettings{Scanlines: -0.5, ShadowMask: 0.4, Curvature: 2, Bloom: 1}.Clamped()
+	expected := CRTSettings{Scanlines: 0, ShadowMask: 0.4, Curvature: 1, Bloom: 1}
+	if s != expected {
+		t.Errorf("Expected %+v, got %+v", expected, s)
+	}
+}
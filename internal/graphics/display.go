@@ -0,0 +1,100 @@
+// Package graphics provides the display modes that place the picture in the window.
+package graphics
+
+import "math"
+
+// Aspect ratio modes for Config.AspectRatio
+const (
+	AspectOriginal = "original" // Square pixels, largest fit
+	AspectInteger  = "integer"  // Square pixels, largest whole-number scale (pixel perfect)
+	AspectPAR      = "8:7"      // NTSC pixel aspect ratio (pixels 8/7 as wide as tall)
+	Aspect4x3      = "4:3"      // Picture stretched to a 4:3 screen
+	Aspect16x9     = "16:9"     // Picture stretched to a 16:9 screen
+	AspectFill     = "fill"     // Stretched over the whole window
+)
+
+// AspectModes lists the supported aspect ratio modes
+var AspectModes = []string{AspectOriginal, AspectInteger, AspectPAR, Aspect4x3, Aspect16x9, AspectFill}
+
+// AspectRatioWindow is implemented by windows that can change the aspect mode at runtime
+type AspectRatioWindow interface {
+	SetAspectRatio(mode string)
+}
+
+// Window scales accepted by ScalableWindow
+const (
+	MinWindowScale = 1
+	MaxWindowScale = 5
+)
+
+// ScalableWindow is implemented by windows that can be resized to a multiple
+// of the NES resolution at runtime
+type ScalableWindow interface {
+	// SetWindowScale leaves fullscreen and resizes the window to scale times
+	// 256x240 device-independent pixels, so the window has the same size on
+	// high DPI monitors (where the picture is drawn at the full resolution)
+	SetWindowScale(scale int)
+}
+
+// FullscreenWindow is implemented by windows that can switch to fullscreen
+// at runtime
+type FullscreenWindow interface {
+	SetFullscreen(fullscreen bool)
+	IsFullscreen() bool
+}
+
+// FrameRateWindow is implemented by windows whose game loop runs the
+// emulator, so that the loop ticks at the frame rate of the emulated console
+type FrameRateWindow interface {
+	SetFrameRate(fps float64)
+}
+
+// UnthrottledWindow is implemented by windows that can stop pacing their game
+// loop on VSync and the frame rate, so it runs as fast as the host allows
+type UnthrottledWindow interface {
+	SetUnthrottled(unthrottled bool)
+}
+
+// DisplayRect returns the horizontal and vertical scale and the offset that place a
+// frameWidth x frameHeight picture in the window for the given aspect mode. The
+// picture is centered; the rest of the window is left for black bars.
+func DisplayRect(mode string, windowWidth, windowHeight, frameWidth, frameHeight int) (scaleX, scaleY, offsetX, offsetY float64) {
+	if windowWidth <= 0 || windowHeight <= 0 || frameWidth <= 0 || frameHeight <= 0 {
+		return 0, 0, 0, 0
+	}
+	ww, wh := float64(windowWidth), float64(windowHeight)
+	fw, fh := float64(frameWidth), float64(frameHeight)
+	fit := math.Min(ww/fw, wh/fh)
+
+	switch mode {
+	case AspectFill, "stretch":
+		scaleX, scaleY = ww/fw, wh/fh
+	case AspectInteger:
+		scale := math.Floor(fit)
+		if scale < 1 {
+			scale = fit // Window smaller than the picture: shrink to fit
+		}
+		scaleX, scaleY = scale, scale
+	default:
+		// Width of a pixel relative to its height
+		pixelAspect := 1.0
+		switch mode {
+		case AspectPAR:
+			pixelAspect = 8.0 / 7.0
+		case Aspect4x3:
+			pixelAspect = (4.0 / 3.0) / (fw / fh)
+		case Aspect16x9:
+			pixelAspect = (16.0 / 9.0) / (fw / fh)
+		}
+		scale := math.Min(ww/(fw*pixelAspect), wh/fh)
+		scaleX, scaleY = scale*pixelAspect, scale
+	}
+
+	offsetX = (ww - fw*scaleX) / 2
+	offsetY = (wh - fh*scaleY) / 2
+	if mode == AspectInteger {
+		// Keep pixels on whole screen pixels
+		offsetX, offsetY = math.Floor(offsetX), math.Floor(offsetY)
+	}
+	return scaleX, scaleY, offsetX, offsetY
+}
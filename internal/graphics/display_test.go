@@ -0,0 +1,48 @@
+package graphics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDisplayRect_Integer(t *testing.T) {
+	// 800x600 fits 3x (768x720 is too tall), so 2x with bars on every side
+	scaleX, scaleY, offsetX, offsetY := DisplayRect(AspectInteger, 800, 600, 256, 240)
+	if scaleX != 2 || scaleY != 2 {
+		t.Errorf("Expected 2x integer scale, got %.2fx%.2f", scaleX, scaleY)
+	}
+	if offsetX != 144 || offsetY != 60 {
+		t.Errorf("Expected offset (144, 60), got (%.1f, %.1f)", offsetX, offsetY)
+	}
+}
+
+func TestDisplayRect_AspectModes(t *testing.T) {
+	tests := []struct {
+		mode   string
+		aspect float64 // Expected displayed width / height
+	}{
+		{AspectOriginal, 256.0 / 240.0},
+		{AspectPAR, 256.0 * 8 / 7 / 240.0},
+		{Aspect4x3, 4.0 / 3.0},
+		{Aspect16x9, 16.0 / 9.0},
+	}
+	for _, tt := range tests {
+		scaleX, scaleY, _, offsetY := DisplayRect(tt.mode, 1920, 1080, 256, 240)
+		aspect := (256 * scaleX) / (240 * scaleY)
+		if math.Abs(aspect-tt.aspect) > 1e-9 {
+			t.Errorf("%s: expected aspect %.4f, got %.4f", tt.mode, tt.aspect, aspect)
+		}
+		if 240*scaleY > 1080+1e-9 || offsetY < -1e-9 {
+			t.Errorf("%s: picture doesn't fit the window", tt.mode)
+		}
+	}
+}
+
+func TestDisplayRect_Fill(t *testing.T) {
+	scaleX, scaleY, offsetX, offsetY := DisplayRect(AspectFill, 1000, 500, 256, 240)
+	if math.Abs(256*scaleX-1000) > 1e-9 || math.Abs(240*scaleY-500) > 1e-9 ||
+		math.Abs(offsetX) > 1e-9 || math.Abs(offsetY) > 1e-9 {
+		t.Errorf("Expected the picture to cover the window, got scale %.3fx%.3f offset (%.1f, %.1f)",
+			scaleX, scaleY, offsetX, offsetY)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"image"
 	"image/color"
 	"log"
+	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -30,12 +31,14 @@ type EbitengineWindow struct {
 	running            bool
 	events             []InputEvent
 	emulatorUpdateFunc func() error
+	frameRate          float64 // Ticks per second set with SetFrameRate
+	unthrottled        bool
 }
 
 // EbitengineGame implements ebiten.Game for the NES emulator
 type EbitengineGame struct {
 	window       *EbitengineWindow
-	frameBuffer  [256 * 240]uint32
+	frameBuffer  *[256 * 240]uint32 // Last frame rendered, not copied
 	frameImage   *ebiten.Image
 	nesWidth     int
 	nesHeight    int
@@ -49,6 +52,29 @@ type EbitengineGame struct {
 	
 	// Reusable image buffer to prevent memory leaks
 	imageBuffer *image.RGBA
+
+	// Gamepad input (nil disables gamepad polling)
+	gamepads *ebitengineGamepads
+
+	// Last reported mouse state (NES coordinates)
+	mouseX, mouseY int
+	mouseButtons   MouseButton
+
+	// Keyboard bindings and pending rebind capture
+	keyBindings map[ebiten.Key][]Button
+	capture     func(input CapturedInput)
+	captureKeys []ebiten.Key // Reusable buffer for just pressed keys
+
+	// CRT post-processing (nil draws the frame directly)
+	crt *crtFilter
+
+	// Aspect ratio mode (see DisplayRect)
+	aspectMode string
+
+	// Upscaled frame shown instead of frameImage while upscaled is set
+	upscaled       bool
+	upscaledImage  *ebiten.Image
+	upscaledBuffer *image.RGBA
 }
 
 // NewEbitengineBackend creates a new Ebitengine graphics backend
@@ -77,6 +103,10 @@ func (b *EbitengineBackend) CreateWindow(title string, width, height int) (Windo
 	if b.config.Headless {
 		return nil, fmt.Errorf("cannot create window in headless mode")
 	}
+	if b.game != nil {
+		// Ebitengine runs a single game in a single window
+		return nil, fmt.Errorf("the Ebitengine backend has a single window")
+	}
 
 	// Calculate appropriate scale for NES resolution (256x240)
 	scale := 1
@@ -96,6 +126,8 @@ func (b *EbitengineBackend) CreateWindow(title string, width, height int) (Windo
 		frameImage:        ebiten.NewImage(256, 240),
 		previousKeyStates: make(map[ebiten.Key]bool),
 		imageBuffer:       image.NewRGBA(image.Rect(0, 0, 256, 240)), // Pre-allocate reusable buffer
+		gamepads:          newEbitengineGamepads(b.config),
+		aspectMode:        b.config.AspectRatio,
 	}
 
 	window := &EbitengineWindow{
@@ -108,6 +140,15 @@ func (b *EbitengineBackend) CreateWindow(title string, width, height int) (Windo
 	}
 
 	game.window = window
+	game.setInputBindings(DefaultInputBindings())
+	if b.config.Filter == FilterCRT {
+		crt, err := newCRTFilter(b.config.CRT)
+		if err != nil {
+			log.Printf("[Ebitengine] CRT shader failed to compile, drawing without filter: %v", err)
+		} else {
+			game.crt = crt
+		}
+	}
 	b.game = game
 
 	// Configure Ebitengine
@@ -174,15 +215,20 @@ func (w *EbitengineWindow) ShouldClose() bool {
 	return !w.running
 }
 
+// IsFocused reports whether the window is in the foreground
+func (w *EbitengineWindow) IsFocused() bool {
+	return ebiten.IsFocused()
+}
+
 // SwapBuffers is handled automatically by Ebitengine
 func (w *EbitengineWindow) SwapBuffers() {
 	// Ebitengine handles buffer swapping automatically
 }
 
-// PollEvents processes input events and returns them
-func (w *EbitengineWindow) PollEvents() []InputEvent {
-	events := w.events
-	w.events = nil // Clear events after returning them
+// PollEvents appends the input events since the last call to events
+func (w *EbitengineWindow) PollEvents(events []InputEvent) []InputEvent {
+	events = append(events, w.events...)
+	w.events = w.events[:0] // Clear events after returning them, keeping the array
 
 	// Debug log for polled events (disabled for performance - uncomment if needed for debugging)
 	// if len(events) > 0 && len(events) <= 2 { // Only log when there are 1-2 events to avoid spam
@@ -198,27 +244,24 @@ func (w *EbitengineWindow) PollEvents() []InputEvent {
 }
 
 // RenderFrame renders a NES frame buffer to the window
-func (w *EbitengineWindow) RenderFrame(frameBuffer [256 * 240]uint32) error {
+func (w *EbitengineWindow) RenderFrame(frameBuffer *[256 * 240]uint32) error {
 	if w.game == nil {
 		return fmt.Errorf("game not initialized")
 	}
 
-	// Copy frame buffer to game
+	// Keep the frame for testing; it is not copied
 	w.game.frameBuffer = frameBuffer
+	w.game.upscaled = false
 
-	// Convert frame buffer to Ebitengine image using reusable buffer
-	img := w.game.imageBuffer // Reuse pre-allocated buffer
+	// Convert frame buffer to Ebitengine image using reusable buffer, whose
+	// rows are exactly 256 RGBA pixels
+	pix := w.game.imageBuffer.Pix // Reuse pre-allocated buffer
 	nonBlackCount := 0
-	for y := 0; y < 240; y++ {
-		for x := 0; x < 256; x++ {
-			pixel := frameBuffer[y*256+x]
-			r := uint8((pixel >> 16) & 0xFF)
-			g := uint8((pixel >> 8) & 0xFF)
-			b := uint8(pixel & 0xFF)
-			img.SetRGBA(x, y, color.RGBA{R: r, G: g, B: b, A: 255})
-			if pixel != 0x000000 {
-				nonBlackCount++
-			}
+	for i, pixel := range frameBuffer {
+		p := pix[i*4 : i*4+4 : i*4+4]
+		p[0], p[1], p[2], p[3] = uint8(pixel>>16), uint8(pixel>>8), uint8(pixel), 255
+		if pixel != 0x000000 {
+			nonBlackCount++
 		}
 	}
 
@@ -229,7 +272,7 @@ func (w *EbitengineWindow) RenderFrame(frameBuffer [256 * 240]uint32) error {
 		log.Printf("[Ebitengine] RenderFrame: %d non-black pixels (frame %d)", nonBlackCount, frameNum)
 	}
 
-	w.game.frameImage.ReplacePixels(img.Pix)
+	w.game.frameImage.ReplacePixels(pix)
 	return nil
 }
 
@@ -273,6 +316,11 @@ func (g *EbitengineGame) Update() error {
 		}
 	}
 
+	// Close the window once the application has stopped (e.g. Quit in the menu)
+	if !g.window.running {
+		return ebiten.Termination
+	}
+
 	return nil
 }
 
@@ -289,172 +337,272 @@ func (g *EbitengineGame) Draw(screen *ebiten.Image) {
 
 	// Calculate drawing options for proper scaling and centering
 	op := &ebiten.DrawImageOptions{}
-
-	// Calculate scale to fit the window while maintaining aspect ratio
-	scaleX := float64(g.windowWidth) / float64(g.nesWidth)
-	scaleY := float64(g.windowHeight) / float64(g.nesHeight)
-
-	// Use the smaller scale to maintain aspect ratio
-	scale := scaleX
-	if scaleY < scaleX {
-		scale = scaleY
+	scaleX, scaleY, offsetX, offsetY := g.frameTransform()
+
+	// An upscaled frame covers the same area as the NES frame
+	frame := g.frameImage
+	if g.upscaled {
+		frame = g.upscaledImage
+		scaleX *= float64(g.nesWidth) / float64(frame.Bounds().Dx())
+		scaleY *= float64(g.nesHeight) / float64(frame.Bounds().Dy())
 	}
 
-	// Center the image
-	offsetX := (float64(g.windowWidth) - float64(g.nesWidth)*scale) / 2
-	offsetY := (float64(g.windowHeight) - float64(g.nesHeight)*scale) / 2
-
-	op.GeoM.Scale(scale, scale)
+	op.GeoM.Scale(scaleX, scaleY)
 	op.GeoM.Translate(offsetX, offsetY)
 
 	// Draw the NES frame
-	screen.DrawImage(g.frameImage, op)
+	if g.crt != nil {
+		g.crt.draw(screen, frame, scaleX, scaleY, offsetX, offsetY)
+	} else {
+		screen.DrawImage(frame, op)
+	}
 
 	// Debug: Log very rarely to avoid performance impact
 	g.drawCount++
 	if g.drawCount%1800 == 0 { // Log every 1800 frames (about once per 30 seconds)
-		log.Printf("[Ebitengine] Drawing frame %d - %dx%d scaled %.2fx at offset (%.1f,%.1f)",
-			g.drawCount, g.nesWidth, g.nesHeight, scale, offsetX, offsetY)
+		log.Printf("[Ebitengine] Drawing frame %d - %dx%d scaled %.2fx%.2f at offset (%.1f,%.1f)",
+			g.drawCount, g.nesWidth, g.nesHeight, scaleX, scaleY, offsetX, offsetY)
 	}
 }
 
+// frameTransform returns the scale and offset used to draw the NES picture
+// centered in the window according to the aspect mode
+func (g *EbitengineGame) frameTransform() (scaleX, scaleY, offsetX, offsetY float64) {
+	return DisplayRect(g.aspectMode, g.windowWidth, g.windowHeight, g.nesWidth, g.nesHeight)
+}
+
 // Layout implements ebiten.Game.Layout
 func (g *EbitengineGame) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	// Update window dimensions
-	g.windowWidth = outsideWidth
-	g.windowHeight = outsideHeight
+	// The window size is in device-independent pixels; size the screen in
+	// device pixels so high DPI monitors get a full resolution picture
+	scale := ebiten.Monitor().DeviceScaleFactor()
+	g.windowWidth = int(math.Ceil(float64(outsideWidth) * scale))
+	g.windowHeight = int(math.Ceil(float64(outsideHeight) * scale))
 
 	// Return the screen size - we'll handle scaling in Draw()
-	return outsideWidth, outsideHeight
+	return g.windowWidth, g.windowHeight
 }
 
+// ebitenKeys maps the Ebitengine keys reported as key events to Keys
+var ebitenKeys = func() map[ebiten.Key]Key {
+	keys := make(map[ebiten.Key]Key, len(keyCodesByName))
+	for name, key := range keyCodesByName {
+		var ebitenKey ebiten.Key
+		if err := ebitenKey.UnmarshalText([]byte(name)); err == nil {
+			keys[ebitenKey] = key
+		}
+	}
+	return keys
+}()
+
 // processInput processes keyboard and controller input
 func (g *EbitengineGame) processInput() {
 	if g.window == nil {
 		return
 	}
 
-	var events []InputEvent
-
-	// Check for quit events
-	if ebiten.IsKeyPressed(ebiten.KeyEscape) || inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-		events = append(events, InputEvent{
-			Type:    InputEventTypeQuit,
-			Pressed: true,
-		})
+	// A pending rebind capture swallows input until something is pressed
+	if g.capture != nil {
+		g.processCapture()
+		return
 	}
 
-	// Process keyboard input
-	keyMappings := map[ebiten.Key]Key{
-		ebiten.KeyEscape:     KeyEscape,
-		ebiten.KeyEnter:      KeyEnter,
-		ebiten.KeySpace:      KeySpace,
-		ebiten.KeyArrowUp:    KeyUp,
-		ebiten.KeyArrowDown:  KeyDown,
-		ebiten.KeyArrowLeft:  KeyLeft,
-		ebiten.KeyArrowRight: KeyRight,
-		ebiten.KeyW:          KeyW,
-		ebiten.KeyA:          KeyA,
-		ebiten.KeyS:          KeyS,
-		ebiten.KeyD:          KeyD,
-		ebiten.KeyJ:          KeyJ,
-		ebiten.KeyK:          KeyK,
-		ebiten.KeyX:          KeyX,
-		ebiten.KeyZ:          KeyZ,
-		// Number keys for Player 2 controller
-		ebiten.Key1:          Key1,
-		ebiten.Key2:          Key2,
-		ebiten.Key3:          Key3,
-		ebiten.Key4:          Key4,
-		ebiten.Key5:          Key5,
-		ebiten.Key6:          Key6,
-		ebiten.Key7:          Key7,
-		ebiten.Key8:          Key8,
-		ebiten.KeyF1:         KeyF1,
-		ebiten.KeyF2:         KeyF2,
-		ebiten.KeyF3:         KeyF3,
-		ebiten.KeyF4:         KeyF4,
-		ebiten.KeyF5:         KeyF5,
-		ebiten.KeyF6:         KeyF6,
-		ebiten.KeyF7:         KeyF7,
-		ebiten.KeyF8:         KeyF8,
-		ebiten.KeyF9:         KeyF9,
-		ebiten.KeyF10:        KeyF10,
-		ebiten.KeyF11:        KeyF11,
-		ebiten.KeyF12:        KeyF12,
-	}
-
-	// Optimized key change detection - only check keys that actually changed
-	var rawKeyEvents []InputEvent
-	for ebitenKey, key := range keyMappings {
+	// Current modifier state (used for Shift+F1-F10 and similar hotkeys)
+	modifiers := currentModifiers()
+
+	// Optimized key change detection - only check keys that actually changed.
+	// Keys bound to controller buttons are reported as button events only.
+	// Events go straight onto the queue PollEvents empties.
+	finalEvents := g.window.events
+	for ebitenKey, key := range ebitenKeys {
+		if _, bound := g.keyBindings[ebitenKey]; bound {
+			continue
+		}
 		// Use Ebitengine's efficient key change detection
 		if inpututil.IsKeyJustPressed(ebitenKey) {
-			rawKeyEvents = append(rawKeyEvents, InputEvent{
-				Type:    InputEventTypeKey,
-				Key:     key,
-				Pressed: true,
+			finalEvents = append(finalEvents, InputEvent{
+				Type:      InputEventTypeKey,
+				Key:       key,
+				Pressed:   true,
+				Modifiers: modifiers,
 			})
 			g.previousKeyStates[ebitenKey] = true
 		} else if inpututil.IsKeyJustReleased(ebitenKey) {
-			rawKeyEvents = append(rawKeyEvents, InputEvent{
-				Type:    InputEventTypeKey,
-				Key:     key,
-				Pressed: false,
+			finalEvents = append(finalEvents, InputEvent{
+				Type:      InputEventTypeKey,
+				Key:       key,
+				Pressed:   false,
+				Modifiers: modifiers,
 			})
 			g.previousKeyStates[ebitenKey] = false
 		}
 	}
 
-	// Map keys to NES controller buttons
-	var finalEvents []InputEvent
-	buttonMappings := map[Key]Button{
-		// Player 1 controller (existing mappings)
-		KeyUp:    ButtonUp,
-		KeyDown:  ButtonDown,
-		KeyLeft:  ButtonLeft,
-		KeyRight: ButtonRight,
-		KeyW:     ButtonUp,
-		KeyS:     ButtonDown,
-		KeyA:     ButtonLeft,
-		KeyD:     ButtonRight,
-		KeyJ:     ButtonA,
-		KeyK:     ButtonB,
-		KeyEnter: ButtonStart,
-		KeySpace: ButtonSelect,
-		// Player 2 controller (number keys 1-8)
-		Key1:     Button2Up,
-		Key2:     Button2Down,
-		Key3:     Button2Left,
-		Key4:     Button2Right,
-		Key5:     Button2A,
-		Key6:     Button2B,
-		Key7:     Button2Start,
-		Key8:     Button2Select,
-	}
-
-	// Convert key events to button events
-	for _, event := range rawKeyEvents {
-		// ボタンにマッピングできるキーなら、ボタンイベントに変換して追加
-		if button, exists := buttonMappings[event.Key]; exists {
+	// Map bound keys to NES controller buttons
+	for ebitenKey, buttons := range g.keyBindings {
+		var pressed bool
+		if inpututil.IsKeyJustPressed(ebitenKey) {
+			pressed = true
+		} else if !inpututil.IsKeyJustReleased(ebitenKey) {
+			continue
+		}
+		g.previousKeyStates[ebitenKey] = pressed
+		for _, button := range buttons {
 			finalEvents = append(finalEvents, InputEvent{
 				Type:    InputEventTypeButton,
 				Button:  button,
-				Pressed: event.Pressed,
+				Pressed: pressed,
 			})
-			// Debug logging disabled for performance - uncomment if needed for debugging
-			// log.Printf("[INPUT_DEBUG] Mapped Key %v to Button %v (Pressed: %t)", event.Key, button, event.Pressed)
-
-		} else {
-			// マッピングできないイベント（Quitなど）は、そのまま追加
-			finalEvents = append(finalEvents, event)
 		}
 	}
 
+	// Mouse position and buttons (used by the Zapper)
+	finalEvents = g.appendMouseEvent(finalEvents)
+
+	// Gamepads produce button events directly
+	if g.gamepads != nil {
+		finalEvents = g.gamepads.poll(finalEvents)
+	}
+
 	// Store events for retrieval by PollEvents
-	g.window.events = append(g.window.events, finalEvents...)
+	g.window.events = finalEvents
+}
+
+// appendMouseEvent appends a mouse event if the pointer moved or a button changed
+func (g *EbitengineGame) appendMouseEvent(events []InputEvent) []InputEvent {
+	cursorX, cursorY := ebiten.CursorPosition()
+	x, y := -1, -1
+	if scaleX, scaleY, offsetX, offsetY := g.frameTransform(); scaleX > 0 && scaleY > 0 {
+		x = int(math.Floor((float64(cursorX) - offsetX) / scaleX))
+		y = int(math.Floor((float64(cursorY) - offsetY) / scaleY))
+	}
+
+	var buttons MouseButton
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		buttons |= MouseButtonLeft
+	}
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) {
+		buttons |= MouseButtonRight
+	}
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonMiddle) {
+		buttons |= MouseButtonMiddle
+	}
+
+	if x == g.mouseX && y == g.mouseY && buttons == g.mouseButtons {
+		return events
+	}
+	g.mouseX, g.mouseY, g.mouseButtons = x, y, buttons
+
+	return append(events, InputEvent{
+		Type:         InputEventTypeMouse,
+		Pressed:      buttons&MouseButtonLeft != 0,
+		MouseX:       x,
+		MouseY:       y,
+		MouseButtons: buttons,
+	})
+}
+
+// currentModifiers returns the modifier keys currently held down
+func currentModifiers() ModifierKey {
+	modifiers := ModifierNone
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		modifiers |= ModifierShift
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyControl) {
+		modifiers |= ModifierCtrl
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyAlt) {
+		modifiers |= ModifierAlt
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyMeta) {
+		modifiers |= ModifierSuper
+	}
+	return modifiers
 }
 
 // Debug logging for development
 func (g *EbitengineGame) logDebug(msg string) {
 	log.Printf("[Ebitengine] %s", msg)
 }
+
+// RenderScaledFrame displays an upscaled frame in place of the NES picture
+func (w *EbitengineWindow) RenderScaledFrame(pixels []uint32, width, height int) error {
+	if w.game == nil {
+		return fmt.Errorf("game not initialized")
+	}
+	if len(pixels) < width*height {
+		return fmt.Errorf("scaled frame too small: %d pixels for %dx%d", len(pixels), width, height)
+	}
+
+	g := w.game
+	if g.upscaledImage == nil || g.upscaledImage.Bounds().Dx() != width || g.upscaledImage.Bounds().Dy() != height {
+		if g.upscaledImage != nil {
+			g.upscaledImage.Deallocate()
+		}
+		g.upscaledImage = ebiten.NewImage(width, height)
+		g.upscaledBuffer = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+
+	pix := g.upscaledBuffer.Pix
+	for i, pixel := range pixels[:width*height] {
+		pix[i*4] = uint8(pixel >> 16)
+		pix[i*4+1] = uint8(pixel >> 8)
+		pix[i*4+2] = uint8(pixel)
+		pix[i*4+3] = 0xFF
+	}
+	g.upscaledImage.WritePixels(pix)
+	g.upscaled = true
+	return nil
+}
+
+// SetWindowScale leaves fullscreen and resizes the window to a multiple of 256x240
+func (w *EbitengineWindow) SetWindowScale(scale int) {
+	scale = max(MinWindowScale, min(MaxWindowScale, scale))
+	w.width, w.height = 256*scale, 240*scale
+	ebiten.SetFullscreen(false)
+	ebiten.SetWindowSize(w.width, w.height)
+}
+
+// SetFullscreen switches between fullscreen and the window
+func (w *EbitengineWindow) SetFullscreen(fullscreen bool) {
+	ebiten.SetFullscreen(fullscreen)
+}
+
+// IsFullscreen reports whether the window is fullscreen
+func (w *EbitengineWindow) IsFullscreen() bool {
+	return ebiten.IsFullscreen()
+}
+
+// SetFrameRate sets the ticks per second of the game loop, which emulates a
+// frame on each: 60 for NTSC games and 50 for PAL ones
+func (w *EbitengineWindow) SetFrameRate(fps float64) {
+	w.frameRate = fps
+	if !w.unthrottled {
+		ebiten.SetTPS(int(math.Round(fps)))
+	}
+}
+
+// SetUnthrottled turns VSync off and runs one tick per frame drawn, as often
+// as the host can draw them, or goes back to the configured VSync and the
+// frame rate
+func (w *EbitengineWindow) SetUnthrottled(unthrottled bool) {
+	w.unthrottled = unthrottled
+	if unthrottled {
+		ebiten.SetVsyncEnabled(false)
+		ebiten.SetTPS(ebiten.SyncWithFPS)
+		return
+	}
+	ebiten.SetVsyncEnabled(w.backend.config.VSync)
+	fps := w.frameRate
+	if fps == 0 {
+		fps = ebiten.DefaultTPS
+	}
+	ebiten.SetTPS(int(math.Round(fps)))
+}
+
+// SetAspectRatio changes how the picture is fitted into the window (see DisplayRect)
+func (w *EbitengineWindow) SetAspectRatio(mode string) {
+	if w.game != nil {
+		w.game.aspectMode = mode
+	}
+}
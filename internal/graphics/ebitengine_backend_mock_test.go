@@ -79,11 +79,11 @@ func (w *MockWindow) ShouldClose() bool {
 
 func (w *MockWindow) SwapBuffers() {}
 
-func (w *MockWindow) PollEvents() []InputEvent {
-	return nil
+func (w *MockWindow) PollEvents(events []InputEvent) []InputEvent {
+	return events
 }
 
-func (w *MockWindow) RenderFrame(frameBuffer [256 * 240]uint32) error {
+func (w *MockWindow) RenderFrame(frameBuffer *[256 * 240]uint32) error {
 	if w.renderError != nil {
 		return w.renderError
 	}
@@ -91,7 +91,7 @@ func (w *MockWindow) RenderFrame(frameBuffer [256 * 240]uint32) error {
 		return errors.New("game not initialized")
 	}
 	
-	w.game.frameBuffer = frameBuffer
+	w.game.frameBuffer = *frameBuffer
 	w.game.renderCalled = true
 	return nil
 }
@@ -148,7 +148,7 @@ func TestRenderingPipeline_MockBackend_FailsWithoutRenderCalls(t *testing.T) {
 		frameBuffer[i] = 0xFF0000FF // Red
 	}
 	
-	err = window.RenderFrame(frameBuffer)
+	err = window.RenderFrame(&frameBuffer)
 	if err != nil {
 		t.Fatalf("RenderFrame failed: %v", err)
 	}
@@ -221,7 +221,7 @@ func TestRenderingPipeline_MockBackend_FailsWithBrokenWindow(t *testing.T) {
 	}
 	
 	var frameBuffer [256 * 240]uint32
-	err := brokenWindow.RenderFrame(frameBuffer)
+	err := brokenWindow.RenderFrame(&frameBuffer)
 	if err == nil {
 		t.Fatal("Expected error when rendering with nil game")
 	}
@@ -264,7 +264,7 @@ func TestRenderingPipeline_MockBackend_FrameBufferIntegrity(t *testing.T) {
 			frameBuffer[j] = pattern
 		}
 		
-		err = window.RenderFrame(frameBuffer)
+		err = window.RenderFrame(&frameBuffer)
 		if err != nil {
 			t.Fatalf("Frame %d render failed: %v", i, err)
 		}
@@ -309,7 +309,7 @@ func TestRenderingPipeline_MockBackend_ErrorHandling(t *testing.T) {
 	mockWindow.renderError = errors.New("render failed")
 	
 	var frameBuffer [256 * 240]uint32
-	err = window.RenderFrame(frameBuffer)
+	err = window.RenderFrame(&frameBuffer)
 	if err == nil {
 		t.Fatal("Expected render to fail")
 	}
@@ -376,7 +376,7 @@ func TestRenderingPipeline_VerifyRenderRequirements(t *testing.T) {
 		
 		// Call RenderFrame
 		var frameBuffer [256 * 240]uint32
-		err = window.RenderFrame(frameBuffer)
+		err = window.RenderFrame(&frameBuffer)
 		if err != nil {
 			t.Fatalf("RenderFrame failed: %v", err)
 		}
@@ -410,7 +410,7 @@ func TestRenderingPipeline_VerifyRenderRequirements(t *testing.T) {
 		}
 		
 		// Render frame
-		err = window.RenderFrame(frameBuffer)
+		err = window.RenderFrame(&frameBuffer)
 		if err != nil {
 			t.Fatalf("RenderFrame failed: %v", err)
 		}
@@ -42,8 +42,8 @@ func (w *EbitengineWindow) SetTitle(title string) {}
 func (w *EbitengineWindow) GetSize() (width, height int) { return 0, 0 }
 func (w *EbitengineWindow) ShouldClose() bool { return true }
 func (w *EbitengineWindow) SwapBuffers() {}
-func (w *EbitengineWindow) PollEvents() []InputEvent { return nil }
-func (w *EbitengineWindow) RenderFrame(frameBuffer [256 * 240]uint32) error {
+func (w *EbitengineWindow) PollEvents(events []InputEvent) []InputEvent { return events }
+func (w *EbitengineWindow) RenderFrame(frameBuffer *[256 * 240]uint32) error {
 	return fmt.Errorf("Ebitengine backend not available in headless build")
 }
 func (w *EbitengineWindow) Cleanup() error { return nil }
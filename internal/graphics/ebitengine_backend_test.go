@@ -202,7 +202,7 @@ func TestEbitengineWindow_RenderFrame(t *testing.T) {
 	}
 	
 	// Test frame rendering
-	err = window.RenderFrame(frameBuffer)
+	err = window.RenderFrame(&frameBuffer)
 	if err != nil {
 		t.Fatalf("RenderFrame failed: %v", err)
 	}
@@ -230,7 +230,7 @@ func TestEbitengineWindow_RenderFrame_NilGame(t *testing.T) {
 	}
 	
 	var frameBuffer [256 * 240]uint32
-	err := window.RenderFrame(frameBuffer)
+	err := window.RenderFrame(&frameBuffer)
 	if err == nil {
 		t.Fatal("Expected error when rendering with nil game")
 	}
@@ -465,13 +465,13 @@ func TestEbitengineWindow_PollEvents(t *testing.T) {
 	}
 	
 	// First poll should return events
-	events := window.PollEvents()
+	events := window.PollEvents(nil)
 	if len(events) != 2 {
 		t.Errorf("Expected 2 events, got %d", len(events))
 	}
 	
 	// Second poll should return empty slice
-	events = window.PollEvents()
+	events = window.PollEvents(nil)
 	if len(events) != 0 {
 		t.Errorf("Expected 0 events after clearing, got %d", len(events))
 	}
@@ -544,7 +544,7 @@ func BenchmarkEbitengineWindow_RenderFrame(b *testing.B) {
 	b.ResetTimer()
 	
 	for i := 0; i < b.N; i++ {
-		err = window.RenderFrame(frameBuffer)
+		err = window.RenderFrame(&frameBuffer)
 		if err != nil {
 			b.Fatalf("RenderFrame failed: %v", err)
 		}
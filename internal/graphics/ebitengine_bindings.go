@@ -0,0 +1,92 @@
+//go:build !headless
+// +build !headless
+
+package graphics
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// SetInputBindings replaces the active key and gamepad bindings
+func (w *EbitengineWindow) SetInputBindings(bindings InputBindings) {
+	if w.game != nil {
+		w.game.setInputBindings(bindings)
+	}
+}
+
+// CaptureNextInput reports the next key or gamepad button press to callback
+func (w *EbitengineWindow) CaptureNextInput(callback func(input CapturedInput)) {
+	if w.game != nil {
+		w.game.capture = callback
+	}
+}
+
+// CancelCapture stops a pending capture
+func (w *EbitengineWindow) CancelCapture() {
+	if w.game != nil {
+		w.game.capture = nil
+	}
+}
+
+// setInputBindings resolves binding names to Ebitengine keys and gamepad buttons
+func (g *EbitengineGame) setInputBindings(bindings InputBindings) {
+	keyBindings := make(map[ebiten.Key][]Button)
+	for player := 0; player < MaxGamepadPlayers; player++ {
+		for i, names := range bindings.Keyboard[player] {
+			button := PlayerButton(player, ControllerButtons[i])
+			for _, name := range names {
+				var key ebiten.Key
+				if err := key.UnmarshalText([]byte(NormalizeKeyName(name))); err != nil {
+					log.Printf("[Ebitengine] Ignoring unknown key binding %q for player %d", name, player+1)
+					continue
+				}
+				keyBindings[key] = append(keyBindings[key], button)
+			}
+		}
+	}
+
+	// Release buttons held through keys whose binding is going away
+	if g.window != nil {
+		for key, buttons := range g.keyBindings {
+			if !ebiten.IsKeyPressed(key) {
+				continue
+			}
+			for _, button := range buttons {
+				g.window.events = append(g.window.events, InputEvent{
+					Type:    InputEventTypeButton,
+					Button:  button,
+					Pressed: false,
+				})
+			}
+		}
+	}
+
+	g.keyBindings = keyBindings
+	if g.gamepads != nil {
+		g.gamepads.setBindings(bindings.Gamepad)
+	}
+}
+
+// processCapture reports the first key or gamepad button pressed this frame
+func (g *EbitengineGame) processCapture() {
+	var input CapturedInput
+	g.captureKeys = inpututil.AppendJustPressedKeys(g.captureKeys[:0])
+	if len(g.captureKeys) > 0 {
+		input = CapturedInput{Name: g.captureKeys[0].String()}
+	} else if g.gamepads != nil {
+		var ok bool
+		if input, ok = g.gamepads.captureButton(); !ok {
+			return
+		}
+	} else {
+		return
+	}
+
+	// Clear first so the callback can start another capture
+	callback := g.capture
+	g.capture = nil
+	callback(input)
+}
@@ -0,0 +1,104 @@
+//go:build !headless
+// +build !headless
+
+package graphics
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// crtShaderSource is the Kage CRT shader. The source image is the 256x240 NES
+// frame; scanlines follow NES lines and the shadow mask follows screen pixels.
+const crtShaderSource = `
+//kage:unit pixels
+
+package main
+
+var Scanlines float
+var ShadowMask float
+var Curvature float
+var Bloom float
+
+func Fragment(dstPos vec4, srcPos vec2, color vec4) vec4 {
+	origin := imageSrc0Origin()
+	size := imageSrc0Size()
+
+	// Barrel distortion: sample further out the further from the center
+	uv := (srcPos-origin)/size*2 - 1
+	uv *= 1 + Curvature*0.2*dot(uv, uv)
+	if abs(uv.x) > 1 || abs(uv.y) > 1 {
+		return vec4(0, 0, 0, 1)
+	}
+	pos := origin + (uv+1)/2*size
+	rgb := imageSrc0At(pos).rgb
+
+	// Bloom: bright neighbours bleed into this pixel
+	if Bloom > 0 {
+		glow := imageSrc0At(pos+vec2(1, 0)).rgb + imageSrc0At(pos-vec2(1, 0)).rgb +
+			imageSrc0At(pos+vec2(0, 1)).rgb + imageSrc0At(pos-vec2(0, 1)).rgb
+		glow /= 4
+		rgb += Bloom * 0.5 * glow * glow
+	}
+
+	// Scanlines: darken towards the top and bottom edge of each NES line
+	d := abs(fract(pos.y-origin.y)-0.5) * 2
+	rgb *= 1 - Scanlines*0.7*d*d
+
+	// Shadow mask: an RGB triad per three screen pixels
+	m := mod(floor(dstPos.x), 3)
+	mask := vec3(step(0.5, abs(m)), step(0.5, abs(m-1)), step(0.5, abs(m-2)))
+	rgb *= 1 - ShadowMask*0.5*mask
+
+	// Compensate for the light lost to the mask and scanlines
+	rgb *= 1 + ShadowMask*0.25 + Scanlines*0.2
+	return vec4(clamp(rgb, 0, 1), 1) * color
+}
+`
+
+// crtFilter draws the NES frame through the CRT shader
+type crtFilter struct {
+	shader   *ebiten.Shader
+	vertices []ebiten.Vertex
+	indices  []uint16
+	options  ebiten.DrawTrianglesShaderOptions
+}
+
+// newCRTFilter compiles the CRT shader with the given intensities
+func newCRTFilter(settings CRTSettings) (*crtFilter, error) {
+	shader, err := ebiten.NewShader([]byte(crtShaderSource))
+	if err != nil {
+		return nil, err
+	}
+
+	settings = settings.Clamped()
+	f := &crtFilter{
+		shader:   shader,
+		vertices: make([]ebiten.Vertex, 4),
+		indices:  []uint16{0, 1, 2, 1, 2, 3},
+	}
+	f.options.Uniforms = map[string]any{
+		"Scanlines":  settings.Scanlines,
+		"ShadowMask": settings.ShadowMask,
+		"Curvature":  settings.Curvature,
+		"Bloom":      settings.Bloom,
+	}
+	return f, nil
+}
+
+// draw renders frame scaled and offset onto screen
+func (f *crtFilter) draw(screen, frame *ebiten.Image, scaleX, scaleY, offsetX, offsetY float64) {
+	bounds := frame.Bounds()
+	width, height := float32(bounds.Dx()), float32(bounds.Dy())
+	corners := [4][2]float32{{0, 0}, {width, 0}, {0, height}, {width, height}}
+	for i, corner := range corners {
+		f.vertices[i] = ebiten.Vertex{
+			DstX:   float32(offsetX) + corner[0]*float32(scaleX),
+			DstY:   float32(offsetY) + corner[1]*float32(scaleY),
+			SrcX:   float32(bounds.Min.X) + corner[0],
+			SrcY:   float32(bounds.Min.Y) + corner[1],
+			ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1,
+		}
+	}
+	f.options.Images[0] = frame
+	screen.DrawTrianglesShader(f.vertices, f.indices, f.shader, &f.options)
+}
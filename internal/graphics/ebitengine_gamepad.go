@@ -0,0 +1,171 @@
+//go:build !headless
+// +build !headless
+
+package graphics
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// ebitengineGamepads turns Ebitengine gamepad state into NES button events,
+// handling hot-plugging and per-player device assignment
+type ebitengineGamepads struct {
+	assigner *GamepadAssigner
+	deadzone float64
+	states   map[ebiten.GamepadID][8]bool
+	bindings [MaxGamepadPlayers][8][]gamepadInput
+	ids      []ebiten.GamepadID // Reusable buffer for connected IDs
+}
+
+// gamepadInput is a resolved gamepad binding
+type gamepadInput struct {
+	standard bool // Standard layout button (else a raw button index)
+	index    int
+}
+
+// newEbitengineGamepads creates the gamepad handler from the backend config
+func newEbitengineGamepads(config Config) *ebitengineGamepads {
+	deadzone := config.GamepadDeadzone
+	if deadzone < 0 || deadzone >= 1 {
+		deadzone = 0.1
+	}
+	gp := &ebitengineGamepads{
+		assigner: NewGamepadAssigner(config.GamepadAssignments),
+		deadzone: deadzone,
+		states:   make(map[ebiten.GamepadID][8]bool),
+	}
+	gp.setBindings(DefaultInputBindings().Gamepad)
+	return gp
+}
+
+// setBindings resolves gamepad binding names for each player
+func (gp *ebitengineGamepads) setBindings(bindings [MaxGamepadPlayers]ButtonBindings) {
+	for player := range bindings {
+		for i, names := range bindings[player] {
+			var inputs []gamepadInput
+			for _, name := range names {
+				_, standard, index, ok := NormalizeGamepadButtonName(name)
+				if !ok {
+					log.Printf("[Ebitengine] Ignoring unknown gamepad binding %q for player %d", name, player+1)
+					continue
+				}
+				inputs = append(inputs, gamepadInput{standard: standard, index: index})
+			}
+			gp.bindings[player][i] = inputs
+		}
+	}
+}
+
+// poll appends button events for every change in gamepad state since the last frame
+func (gp *ebitengineGamepads) poll(events []InputEvent) []InputEvent {
+	// Newly connected gamepads (including those present at startup)
+	gp.ids = inpututil.AppendJustConnectedGamepadIDs(gp.ids[:0])
+	for _, id := range gp.ids {
+		info := GamepadInfo{
+			ID:   int(id),
+			Name: ebiten.GamepadName(id),
+			GUID: ebiten.GamepadSDLID(id),
+		}
+		gp.states[id] = [8]bool{}
+		if player := gp.assigner.Connect(info); player >= 0 {
+			log.Printf("[Ebitengine] Gamepad connected: %s (id %d) -> player %d", info.Name, id, player+1)
+		} else {
+			log.Printf("[Ebitengine] Gamepad connected: %s (id %d) - not assigned", info.Name, id)
+		}
+	}
+
+	// Disconnected gamepads release everything they were holding
+	for id, state := range gp.states {
+		if !inpututil.IsGamepadJustDisconnected(id) {
+			continue
+		}
+		if player := gp.assigner.PlayerFor(int(id)); player >= 0 {
+			events = appendGamepadChanges(events, player, state, [8]bool{})
+			events = append(events, InputEvent{Type: InputEventTypeGamepadDisconnected, Player: player})
+		}
+		delete(gp.states, id)
+		player := gp.assigner.Disconnect(int(id))
+		log.Printf("[Ebitengine] Gamepad disconnected (id %d)", id)
+		if pad, ok := gp.assigner.GamepadFor(player); ok {
+			log.Printf("[Ebitengine] Gamepad %s (id %d) -> player %d", pad.Name, pad.ID, player+1)
+		}
+	}
+
+	for player := 0; player < MaxGamepadPlayers; player++ {
+		pad, ok := gp.assigner.GamepadFor(player)
+		if !ok {
+			continue
+		}
+		id := ebiten.GamepadID(pad.ID)
+		current := gp.readState(id, player)
+		events = appendGamepadChanges(events, player, gp.states[id], current)
+		gp.states[id] = current
+	}
+
+	return events
+}
+
+// readState returns the NES button state of a gamepad in ControllerButtons order.
+// Standard layout bindings apply to known gamepads, raw button bindings to the rest.
+func (gp *ebitengineGamepads) readState(id ebiten.GamepadID, player int) [8]bool {
+	var state [8]bool
+	var x, y float64
+
+	standardLayout := ebiten.IsStandardGamepadLayoutAvailable(id)
+	for i, inputs := range gp.bindings[player] {
+		for _, input := range inputs {
+			if input.standard != standardLayout {
+				continue
+			}
+			if input.standard {
+				state[i] = state[i] || ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButton(input.index))
+			} else {
+				state[i] = state[i] || ebiten.IsGamepadButtonPressed(id, ebiten.GamepadButton(input.index))
+			}
+		}
+	}
+
+	// The left stick (or first two axes) always drives the D-pad
+	if standardLayout {
+		x = ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+		y = ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
+	} else if ebiten.GamepadAxisCount(id) >= 2 {
+		x = ebiten.GamepadAxisValue(id, 0)
+		y = ebiten.GamepadAxisValue(id, 1)
+	}
+
+	up, down, left, right := StickToDPad(x, y, gp.deadzone)
+	state[4] = state[4] || up
+	state[5] = state[5] || down
+	state[6] = state[6] || left
+	state[7] = state[7] || right
+
+	return state
+}
+
+// captureButton returns the first button pressed this frame on an assigned gamepad
+func (gp *ebitengineGamepads) captureButton() (CapturedInput, bool) {
+	for player := 0; player < MaxGamepadPlayers; player++ {
+		pad, ok := gp.assigner.GamepadFor(player)
+		if !ok {
+			continue
+		}
+		id := ebiten.GamepadID(pad.ID)
+		if ebiten.IsStandardGamepadLayoutAvailable(id) {
+			for _, button := range inpututil.AppendJustPressedStandardGamepadButtons(id, nil) {
+				if int(button) < len(StandardGamepadButtonNames) {
+					return CapturedInput{Gamepad: true, Player: player, Name: StandardGamepadButtonNames[button]}, true
+				}
+			}
+			continue
+		}
+		for _, button := range inpututil.AppendJustPressedGamepadButtons(id, nil) {
+			return CapturedInput{Gamepad: true, Player: player, Name: fmt.Sprintf("Button%d", button)}, true
+		}
+	}
+	return CapturedInput{}, false
+}
@@ -98,7 +98,7 @@ func TestRenderingPipeline_FailsWithoutFrameBuffer(t *testing.T) {
 		frameBuffer[i] = 0xFF0000FF // Red
 	}
 	
-	err := window.RenderFrame(frameBuffer)
+	err := window.RenderFrame(&frameBuffer)
 	if err == nil {
 		t.Fatal("Expected error when rendering with nil game, got nil")
 	}
@@ -135,7 +135,7 @@ func TestRenderingPipeline_FrameBufferNotTransferred(t *testing.T) {
 	}
 	
 	// Render the frame
-	err = window.RenderFrame(originalFrameBuffer)
+	err = window.RenderFrame(&originalFrameBuffer)
 	if err != nil {
 		t.Fatalf("Frame render failed: %v", err)
 	}
@@ -230,7 +230,7 @@ func TestRenderingPipeline_DetectsFrameBufferCorruption(t *testing.T) {
 		frameBuffer1[i] = 0xAABBCCDD
 	}
 	
-	err = window.RenderFrame(frameBuffer1)
+	err = window.RenderFrame(&frameBuffer1)
 	if err != nil {
 		t.Fatalf("First frame render failed: %v", err)
 	}
@@ -241,7 +241,7 @@ func TestRenderingPipeline_DetectsFrameBufferCorruption(t *testing.T) {
 		frameBuffer2[i] = 0x11223344
 	}
 	
-	err = window.RenderFrame(frameBuffer2)
+	err = window.RenderFrame(&frameBuffer2)
 	if err != nil {
 		t.Fatalf("Second frame render failed: %v", err)
 	}
@@ -0,0 +1,68 @@
+// Package graphics provides interframe blending for the video processor.
+package graphics
+
+// frameBlender mixes each frame with the one before it, like the slow phosphor
+// decay of a CRT. Games that alternate sprites every other frame to get around
+// the eight sprites per scanline limit then show them steady and half
+// transparent instead of flickering at 30 Hz.
+type frameBlender struct {
+	weight   uint32   // Share of the previous frame, 0-256
+	previous []uint32 // Last frame passed to blend
+	blended  []uint32 // Last result (nil before the first frame)
+}
+
+// newFrameBlender creates a blender mixing in weight (0-1) of the previous frame
+func newFrameBlender(weight float32) *frameBlender {
+	return &frameBlender{weight: uint32(clamp(weight, 0, 1)*256 + 0.5)}
+}
+
+// blend returns frame mixed with the previous frame and remembers frame for
+// the next call. The first frame is returned unchanged. The returned buffer
+// is reused by the next call.
+func (b *frameBlender) blend(frame []uint32) []uint32 {
+	if len(b.previous) != len(frame) {
+		b.previous = make([]uint32, len(frame))
+		b.blended = make([]uint32, len(frame))
+		copy(b.previous, frame)
+	}
+
+	current := 256 - b.weight
+	for i, pixel := range frame {
+		prev := b.previous[i]
+		r := ((pixel>>16&0xFF)*current + (prev>>16&0xFF)*b.weight) >> 8
+		g := ((pixel>>8&0xFF)*current + (prev>>8&0xFF)*b.weight) >> 8
+		bl := ((pixel&0xFF)*current + (prev&0xFF)*b.weight) >> 8
+		b.blended[i] = r<<16 | g<<8 | bl
+	}
+	copy(b.previous, frame)
+	return b.blended
+}
+
+// SetFrameBlend sets the share (0-1) of the previous frame BlendFrame mixes
+// into each frame; 0 disables blending
+func (vp *VideoProcessor) SetFrameBlend(weight float32) {
+	if weight <= 0 {
+		vp.blender = nil
+		return
+	}
+	vp.blender = newFrameBlender(weight)
+}
+
+// BlendFrame mixes frameBuffer with the frame passed to the previous call when
+// frame blending is enabled, and returns frameBuffer unchanged otherwise. Call
+// it once per displayed frame. The returned buffer is reused by the next call.
+func (vp *VideoProcessor) BlendFrame(frameBuffer []uint32) []uint32 {
+	if vp.blender == nil {
+		return frameBuffer
+	}
+	return vp.blender.blend(frameBuffer)
+}
+
+// BlendedFrame returns the last frame produced by BlendFrame, or nil if frame
+// blending is disabled or no frame was blended yet
+func (vp *VideoProcessor) BlendedFrame() []uint32 {
+	if vp.blender == nil {
+		return nil
+	}
+	return vp.blender.blended
+}
@@ -0,0 +1,42 @@
+package graphics
+
+import "testing"
+
+func TestBlendFrame_MixesPreviousFrame(t *testing.T) {
+	vp := NewVideoProcessor(1, 1, 1)
+	vp.SetFrameBlend(0.5)
+
+	first := vp.BlendFrame([]uint32{0xFF0000, 0x000000})
+	if first[0] != 0xFF0000 || first[1] != 0x000000 {
+		t.Fatalf("Expected the first frame unchanged, got %06X %06X", first[0], first[1])
+	}
+
+	// A sprite shown every other frame ends up half transparent
+	out := vp.BlendFrame([]uint32{0x000000, 0x00FF80})
+	if out[0] != 0x7F0000 {
+		t.Errorf("Expected 0x7F0000, got %06X", out[0])
+	}
+	if out[1] != 0x007F40 {
+		t.Errorf("Expected 0x007F40, got %06X", out[1])
+	}
+	if blended := vp.BlendedFrame(); len(blended) != 2 || blended[0] != out[0] {
+		t.Errorf("BlendedFrame should return the last result")
+	}
+}
+
+func TestBlendFrame_DisabledReturnsInput(t *testing.T) {
+	vp := NewVideoProcessor(1, 1, 1)
+	frame := []uint32{0x123456}
+	if out := vp.BlendFrame(frame); &out[0] != &frame[0] {
+		t.Errorf("Expected the frame to pass through without blending")
+	}
+	if vp.BlendedFrame() != nil {
+		t.Errorf("Expected no blended frame while disabled")
+	}
+
+	vp.SetFrameBlend(0.5)
+	vp.SetFrameBlend(0)
+	if out := vp.BlendFrame(frame); &out[0] != &frame[0] {
+		t.Errorf("Expected SetFrameBlend(0) to disable blending")
+	}
+}
@@ -0,0 +1,203 @@
+// Package graphics provides backend-independent gamepad helpers.
+package graphics
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// MaxGamepadPlayers is the number of players a gamepad can be assigned to
+// (players 3 and 4 are only read when a Four Score is connected)
+const MaxGamepadPlayers = 4
+
+// Gamepad assignment values for Config.GamepadAssignments
+const (
+	// GamepadAssignAuto gives the player the next connected gamepad not claimed by another player
+	GamepadAssignAuto = ""
+	// GamepadAssignNone disables gamepad input for the player
+	GamepadAssignNone = "none"
+)
+
+// stickDiagonalRatio is sin(22.5°): an axis counts as pressed when it contributes
+// at least this much of the stick deflection, giving eight equal 45° sectors
+const stickDiagonalRatio = 0.3827
+
+// GamepadInfo describes a connected gamepad
+type GamepadInfo struct {
+	ID   int
+	Name string
+	GUID string
+}
+
+// ControllerButtons lists the NES buttons in the order used for button state arrays and bindings
+var ControllerButtons = [8]Button{
+	ButtonA, ButtonB, ButtonSelect, ButtonStart,
+	ButtonUp, ButtonDown, ButtonLeft, ButtonRight,
+}
+
+// StickToDPad converts analog stick axes (-1..1, +y is down) into D-pad directions.
+// Deflections inside the radial deadzone are ignored.
+func StickToDPad(x, y, deadzone float64) (up, down, left, right bool) {
+	magnitude := math.Hypot(x, y)
+	if magnitude == 0 || magnitude <= deadzone {
+		return false, false, false, false
+	}
+
+	threshold := magnitude * stickDiagonalRatio
+	return y <= -threshold, y >= threshold, x <= -threshold, x >= threshold
+}
+
+// playerButtonBase is the first button (A) of each player; the other buttons follow
+// in ControllerButtons order
+var playerButtonBase = [MaxGamepadPlayers]Button{ButtonA, Button2A, Button3A, Button4A}
+
+// PlayerButton returns the button for the given player (0 = player 1 ... 3 = player 4)
+func PlayerButton(player int, button Button) Button {
+	if player <= 0 || player >= MaxGamepadPlayers {
+		return button
+	}
+	index := ControllerButtonIndex(button)
+	if index < 0 {
+		return button
+	}
+	return playerButtonBase[player] + Button(index)
+}
+
+// ButtonPlayer returns the player (0-3) a controller button belongs to, or -1
+func ButtonPlayer(button Button) int {
+	for player := MaxGamepadPlayers - 1; player >= 0; player-- {
+		if button >= playerButtonBase[player] && button < playerButtonBase[player]+Button(len(ControllerButtons)) {
+			return player
+		}
+	}
+	return -1
+}
+
+// appendGamepadChanges appends button events for the player's buttons that changed
+func appendGamepadChanges(events []InputEvent, player int, previous, current [8]bool) []InputEvent {
+	for i, pressed := range current {
+		if pressed == previous[i] {
+			continue
+		}
+		events = append(events, InputEvent{
+			Type:    InputEventTypeButton,
+			Button:  PlayerButton(player, ControllerButtons[i]),
+			Pressed: pressed,
+		})
+	}
+	return events
+}
+
+// GamepadAssigner tracks which connected gamepad drives which player.
+// Each player has a preference: GamepadAssignAuto, GamepadAssignNone, or a
+// gamepad GUID / case-insensitive name fragment to match.
+type GamepadAssigner struct {
+	preferences [MaxGamepadPlayers]string
+	assigned    [MaxGamepadPlayers]int
+	connected   map[int]GamepadInfo
+}
+
+// NewGamepadAssigner creates an assigner with per-player preferences (missing entries are auto)
+func NewGamepadAssigner(preferences []string) *GamepadAssigner {
+	a := &GamepadAssigner{
+		connected: make(map[int]GamepadInfo),
+	}
+	for i := range a.assigned {
+		a.assigned[i] = -1
+		if i < len(preferences) {
+			a.preferences[i] = strings.TrimSpace(preferences[i])
+		}
+	}
+	return a
+}
+
+// Connect registers a newly connected gamepad and returns the player it was assigned to, or -1
+func (a *GamepadAssigner) Connect(pad GamepadInfo) int {
+	a.connected[pad.ID] = pad
+	if player := a.PlayerFor(pad.ID); player >= 0 {
+		return player
+	}
+
+	// Players that asked for this specific device come first
+	for player, preference := range a.preferences {
+		if a.assigned[player] < 0 && matchesGamepad(preference, pad) {
+			a.assigned[player] = pad.ID
+			return player
+		}
+	}
+
+	// Otherwise the first player without a gamepad on auto gets it
+	for player, preference := range a.preferences {
+		if a.assigned[player] < 0 && preference == GamepadAssignAuto {
+			a.assigned[player] = pad.ID
+			return player
+		}
+	}
+
+	return -1
+}
+
+// Disconnect removes a gamepad and returns the player it was assigned to, or -1.
+// A spare connected gamepad is handed to the freed player if one is available.
+func (a *GamepadAssigner) Disconnect(id int) int {
+	delete(a.connected, id)
+	player := a.PlayerFor(id)
+	if player < 0 {
+		return -1
+	}
+	a.assigned[player] = -1
+
+	for _, pad := range a.sortedConnected() {
+		if a.PlayerFor(pad.ID) >= 0 {
+			continue
+		}
+		preference := a.preferences[player]
+		if preference == GamepadAssignAuto || matchesGamepad(preference, pad) {
+			a.assigned[player] = pad.ID
+			break
+		}
+	}
+
+	return player
+}
+
+// PlayerFor returns the player a gamepad is assigned to, or -1
+func (a *GamepadAssigner) PlayerFor(id int) int {
+	for player, assigned := range a.assigned {
+		if assigned == id {
+			return player
+		}
+	}
+	return -1
+}
+
+// GamepadFor returns the gamepad assigned to a player
+func (a *GamepadAssigner) GamepadFor(player int) (GamepadInfo, bool) {
+	if player < 0 || player >= MaxGamepadPlayers || a.assigned[player] < 0 {
+		return GamepadInfo{}, false
+	}
+	pad, ok := a.connected[a.assigned[player]]
+	return pad, ok
+}
+
+// sortedConnected returns connected gamepads in ID order (IDs grow with connection order)
+func (a *GamepadAssigner) sortedConnected() []GamepadInfo {
+	pads := make([]GamepadInfo, 0, len(a.connected))
+	for _, pad := range a.connected {
+		pads = append(pads, pad)
+	}
+	sort.Slice(pads, func(i, j int) bool { return pads[i].ID < pads[j].ID })
+	return pads
+}
+
+// matchesGamepad reports whether a non-auto preference selects the gamepad
+func matchesGamepad(preference string, pad GamepadInfo) bool {
+	if preference == GamepadAssignAuto || strings.EqualFold(preference, GamepadAssignNone) {
+		return false
+	}
+	if pad.GUID != "" && strings.EqualFold(preference, pad.GUID) {
+		return true
+	}
+	return strings.Contains(strings.ToLower(pad.Name), strings.ToLower(preference))
+}
@@ -0,0 +1,108 @@
+package graphics
+
+import "testing"
+
+func TestStickToDPad_Deadzone(t *testing.T) {
+	up, down, left, right := StickToDPad(0.05, -0.05, 0.1)
+	if up || down || left || right {
+		t.Error("Expected small deflection inside the deadzone to be ignored")
+	}
+
+	up, down, left, right = StickToDPad(0, -0.5, 0.1)
+	if !up || down || left || right {
+		t.Errorf("Expected only up, got up=%v down=%v left=%v right=%v", up, down, left, right)
+	}
+}
+
+func TestStickToDPad_Diagonals(t *testing.T) {
+	up, down, left, right := StickToDPad(0.7, 0.7, 0.2)
+	if up || !down || left || !right {
+		t.Errorf("Expected down+right, got up=%v down=%v left=%v right=%v", up, down, left, right)
+	}
+
+	// Mostly horizontal push stays a single direction
+	up, down, left, right = StickToDPad(-0.9, 0.2, 0.2)
+	if up || down || !left || right {
+		t.Errorf("Expected only left, got up=%v down=%v left=%v right=%v", up, down, left, right)
+	}
+}
+
+func TestPlayerButton(t *testing.T) {
+	if PlayerButton(0, ButtonA) != ButtonA {
+		t.Error("Expected player 1 buttons to be unchanged")
+	}
+	if PlayerButton(1, ButtonStart) != Button2Start {
+		t.Errorf("Expected Button2Start, got %v", PlayerButton(1, ButtonStart))
+	}
+	if PlayerButton(1, ButtonLeft) != Button2Left {
+		t.Errorf("Expected Button2Left, got %v", PlayerButton(1, ButtonLeft))
+	}
+	if PlayerButton(3, ButtonB) != Button4B {
+		t.Errorf("Expected Button4B, got %v", PlayerButton(3, ButtonB))
+	}
+	if ButtonPlayer(Button3Select) != 2 || ButtonPlayer(ButtonRight) != 0 || ButtonPlayer(ButtonUnknown) != -1 {
+		t.Error("Expected ButtonPlayer to identify the owning player")
+	}
+}
+
+func TestGamepadAssigner_AutoAssignsInOrder(t *testing.T) {
+	a := NewGamepadAssigner(nil)
+
+	if player := a.Connect(GamepadInfo{ID: 0, Name: "Pad A"}); player != 0 {
+		t.Errorf("Expected first gamepad on player 1, got player %d", player+1)
+	}
+	if player := a.Connect(GamepadInfo{ID: 1, Name: "Pad B"}); player != 1 {
+		t.Errorf("Expected second gamepad on player 2, got player %d", player+1)
+	}
+	a.Connect(GamepadInfo{ID: 2, Name: "Pad C"})
+	a.Connect(GamepadInfo{ID: 3, Name: "Pad D"})
+	if player := a.Connect(GamepadInfo{ID: 4, Name: "Pad E"}); player != -1 {
+		t.Errorf("Expected fifth gamepad to be unassigned, got player %d", player+1)
+	}
+}
+
+func TestGamepadAssigner_Preferences(t *testing.T) {
+	a := NewGamepadAssigner([]string{"", "xbox"})
+
+	// The Xbox pad goes to player 2 even though it connects first
+	if player := a.Connect(GamepadInfo{ID: 0, Name: "Xbox Wireless Controller"}); player != 1 {
+		t.Errorf("Expected Xbox pad on player 2, got player %d", player+1)
+	}
+	if player := a.Connect(GamepadInfo{ID: 1, Name: "8BitDo NES30"}); player != 0 {
+		t.Errorf("Expected other pad on player 1, got player %d", player+1)
+	}
+
+	none := NewGamepadAssigner([]string{"none", ""})
+	if player := none.Connect(GamepadInfo{ID: 0, Name: "Pad"}); player != 1 {
+		t.Errorf("Expected player 1 to be skipped, got player %d", player+1)
+	}
+}
+
+func TestGamepadAssigner_HotPlug(t *testing.T) {
+	a := NewGamepadAssigner(nil)
+	a.Connect(GamepadInfo{ID: 0, Name: "Pad A"})
+	a.Connect(GamepadInfo{ID: 1, Name: "Pad B"})
+	a.Connect(GamepadInfo{ID: 2, Name: "Pad C"})
+	a.Connect(GamepadInfo{ID: 3, Name: "Pad D"})
+	a.Connect(GamepadInfo{ID: 4, Name: "Pad E"})
+
+	// Unplugging player 1's pad hands the spare pad to player 1
+	if player := a.Disconnect(0); player != 0 {
+		t.Errorf("Expected player 1 freed, got player %d", player+1)
+	}
+	if pad, ok := a.GamepadFor(0); !ok || pad.ID != 4 {
+		t.Errorf("Expected spare pad 4 on player 1, got %v (ok=%v)", pad.ID, ok)
+	}
+
+	if player := a.Disconnect(4); player != 0 {
+		t.Errorf("Expected player 1 freed, got player %d", player+1)
+	}
+	if _, ok := a.GamepadFor(0); ok {
+		t.Error("Expected player 1 to have no gamepad")
+	}
+
+	// Reconnecting fills the empty slot
+	if player := a.Connect(GamepadInfo{ID: 5, Name: "Pad A"}); player != 0 {
+		t.Errorf("Expected reconnected pad on player 1, got player %d", player+1)
+	}
+}
@@ -54,6 +54,11 @@ func (b *HeadlessBackend) CreateWindow(title string, width, height int) (Window,
 	}, nil
 }
 
+// MaxWindows returns 0: headless windows have no limit
+func (b *HeadlessBackend) MaxWindows() int {
+	return 0
+}
+
 // Cleanup releases all headless resources
 func (b *HeadlessBackend) Cleanup() error {
 	b.initialized = false
@@ -92,13 +97,13 @@ func (w *HeadlessWindow) SwapBuffers() {
 	// No-op for headless
 }
 
-// PollEvents returns empty events list (no input in headless mode)
-func (w *HeadlessWindow) PollEvents() []InputEvent {
-	return nil
+// PollEvents adds no events (no input in headless mode)
+func (w *HeadlessWindow) PollEvents(events []InputEvent) []InputEvent {
+	return events
 }
 
 // RenderFrame optionally saves the frame to disk
-func (w *HeadlessWindow) RenderFrame(frameBuffer [256 * 240]uint32) error {
+func (w *HeadlessWindow) RenderFrame(frameBuffer *[256 * 240]uint32) error {
 	w.frameCount++
 
 	// Save specific frames for debugging
@@ -111,7 +116,7 @@ func (w *HeadlessWindow) RenderFrame(frameBuffer [256 * 240]uint32) error {
 }
 
 // saveFrameAsPPM saves the frame buffer as a PPM image file
-func (w *HeadlessWindow) saveFrameAsPPM(frameBuffer [256 * 240]uint32, filename string) error {
+func (w *HeadlessWindow) saveFrameAsPPM(frameBuffer *[256 * 240]uint32, filename string) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("failed to create file %s: %v", filename, err)
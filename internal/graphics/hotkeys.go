@@ -0,0 +1,104 @@
+// Package graphics provides the key combinations hotkeys are bound to.
+package graphics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hotkey is a key pressed with a set of modifiers, such as Ctrl+F6
+type Hotkey struct {
+	Key       Key
+	Modifiers ModifierKey
+}
+
+// hotkeyModifiers are the modifier names of hotkeys, in the order String
+// writes them
+var hotkeyModifiers = []struct {
+	name     string
+	modifier ModifierKey
+}{
+	{"Ctrl", ModifierCtrl},
+	{"Alt", ModifierAlt},
+	{"Shift", ModifierShift},
+	{"Super", ModifierSuper},
+}
+
+// hotkeyModifierAliases maps other modifier names to those of hotkeyModifiers
+var hotkeyModifierAliases = map[string]string{
+	"control": "Ctrl",
+	"meta":    "Super",
+	"cmd":     "Super",
+}
+
+// ParseHotkey parses a hotkey such as "F1", "Shift+F1" or "ctrl+alt+P".
+// The key is one of the names key bindings use.
+func ParseHotkey(s string) (Hotkey, error) {
+	parts := strings.Split(strings.TrimSpace(s), "+")
+	var hotkey Hotkey
+	for _, part := range parts[:len(parts)-1] {
+		name := strings.TrimSpace(part)
+		if alias, ok := hotkeyModifierAliases[strings.ToLower(name)]; ok {
+			name = alias
+		}
+		found := false
+		for _, m := range hotkeyModifiers {
+			if strings.EqualFold(m.name, name) {
+				hotkey.Modifiers |= m.modifier
+				found = true
+			}
+		}
+		if !found {
+			return Hotkey{}, fmt.Errorf("unknown modifier %q in %q", part, s)
+		}
+	}
+
+	key, ok := KeyByName(strings.TrimSpace(parts[len(parts)-1]))
+	if !ok {
+		return Hotkey{}, fmt.Errorf("unknown key in %q", s)
+	}
+	hotkey.Key = key
+	return hotkey, nil
+}
+
+// String returns the hotkey as ParseHotkey reads it
+func (h Hotkey) String() string {
+	var b strings.Builder
+	for _, m := range hotkeyModifiers {
+		if h.Modifiers&m.modifier != 0 {
+			b.WriteString(m.name + "+")
+		}
+	}
+	b.WriteString(KeyName(h.Key))
+	return b.String()
+}
+
+// Matches reports whether a key press is the hotkey. The modifiers have to
+// be the same, so Shift+F1 does not also trigger F1.
+func (h Hotkey) Matches(event InputEvent) bool {
+	return event.Type == InputEventTypeKey && event.Key == h.Key && event.Modifiers == h.Modifiers
+}
+
+// KeyByName returns the key of a key binding name, case-insensitively
+func KeyByName(name string) (Key, bool) {
+	name = NormalizeKeyName(name)
+	if key, ok := keyCodesByName[name]; ok {
+		return key, true
+	}
+	for keyName, key := range keyCodesByName {
+		if bindingKeyID(keyName) == bindingKeyID(name) {
+			return key, true
+		}
+	}
+	return KeyUnknown, false
+}
+
+// KeyName returns the binding name of a key, or "" for KeyUnknown
+func KeyName(key Key) string {
+	for name, k := range keyCodesByName {
+		if k == key {
+			return name
+		}
+	}
+	return ""
+}
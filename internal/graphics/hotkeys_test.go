@@ -0,0 +1,46 @@
+package graphics
+
+import "testing"
+
+func TestParseHotkey(t *testing.T) {
+	for s, want := range map[string]Hotkey{
+		"F1":             {Key: KeyF1},
+		"Shift+F1":       {Key: KeyF1, Modifiers: ModifierShift},
+		" ctrl + alt+p ": {Key: KeyP, Modifiers: ModifierCtrl | ModifierAlt},
+		"Control+Esc":    {Key: KeyEscape, Modifiers: ModifierCtrl},
+		"Digit0":         {Key: Key0},
+	} {
+		got, err := ParseHotkey(s)
+		if err != nil || got != want {
+			t.Errorf("ParseHotkey(%q) = %+v, %v, want %+v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "Ctrl+", "Hyper+F1", "F13", "Shift"} {
+		if _, err := ParseHotkey(s); err == nil {
+			t.Errorf("ParseHotkey(%q) succeeded", s)
+		}
+	}
+}
+
+func TestHotkeyString(t *testing.T) {
+	hotkey := Hotkey{Key: KeyF12, Modifiers: ModifierShift | ModifierCtrl}
+	if got := hotkey.String(); got != "Ctrl+Shift+F12" {
+		t.Errorf("String = %q", got)
+	}
+	if parsed, err := ParseHotkey(hotkey.String()); err != nil || parsed != hotkey {
+		t.Errorf("round trip = %+v, %v", parsed, err)
+	}
+}
+
+func TestHotkeyMatches(t *testing.T) {
+	hotkey := Hotkey{Key: KeyF1}
+	if !hotkey.Matches(InputEvent{Type: InputEventTypeKey, Key: KeyF1, Pressed: true}) {
+		t.Error("F1 did not match")
+	}
+	if hotkey.Matches(InputEvent{Type: InputEventTypeKey, Key: KeyF1, Pressed: true, Modifiers: ModifierShift}) {
+		t.Error("Shift+F1 matched F1")
+	}
+	if hotkey.Matches(InputEvent{Type: InputEventTypeButton, Pressed: true}) {
+		t.Error("a button matched")
+	}
+}
@@ -0,0 +1,158 @@
+// Package graphics implements the hq2x pixel-art upscaler.
+package graphics
+
+// hq2x color thresholds: neighbours closer than these in Y, U and V are the
+// same color as the center pixel, as in the reference implementation
+const (
+	hq2xThresholdY = 0x30
+	hq2xThresholdU = 0x07
+	hq2xThresholdV = 0x06
+)
+
+// hq2xScaler implements hq2x. Each pixel's neighbours are compared with it in
+// YUV; each of its four output pixels then blends it with the neighbours on
+// its corner that match it, or, where an edge cuts the corner, with the
+// colors across that edge.
+type hq2xScaler struct {
+	// Per source pixel YUV, packed as 0xYYUUVV
+	yuv []uint32
+}
+
+func (s *hq2xScaler) factor() int { return 2 }
+
+func (s *hq2xScaler) upscale(src []uint32, width, height int, dst []uint32) {
+	if cap(s.yuv) < width*height {
+		s.yuv = make([]uint32, width*height)
+	}
+	yuv := s.yuv[:width*height]
+	for i, pixel := range src {
+		yuv[i] = hq2xYUV(pixel)
+	}
+
+	at := func(x, y int) int {
+		if x < 0 {
+			x = 0
+		} else if x >= width {
+			x = width - 1
+		}
+		if y < 0 {
+			y = 0
+		} else if y >= height {
+			y = height - 1
+		}
+		return y*width + x
+	}
+
+	outWidth := width * 2
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// The neighbours of the center pixel 4:
+			//  0 | 1 | 2
+			//  3 | 4 | 5
+			//  6 | 7 | 8
+			var n [9]int
+			for row := 0; row < 3; row++ {
+				for col := 0; col < 3; col++ {
+					n[row*3+col] = at(x+col-1, y+row-1)
+				}
+			}
+			corner := func(a, b, c, d, g int) uint32 {
+				return hq2xCorner(src, yuv, n[4], n[a], n[b], n[c], n[d], n[g])
+			}
+
+			out := y*2*outWidth + x*2
+			dst[out] = corner(0, 1, 2, 3, 6)
+			dst[out+1] = corner(2, 1, 0, 5, 8)
+			dst[out+outWidth] = corner(6, 7, 8, 3, 0)
+			dst[out+outWidth+1] = corner(8, 7, 6, 5, 2)
+		}
+	}
+}
+
+// hq2xCorner returns the output pixel on one corner of the center pixel e:
+// a is the diagonal neighbour on that corner, b and d the neighbours beside it
+// above or below and left or right, c the pixel past b and g the one past d,
+// all as indexes into the frame
+//
+//	a | b | c
+//	d | e
+//	g
+func hq2xCorner(src, yuv []uint32, e, a, b, c, d, g int) uint32 {
+	center := src[e]
+	diff := func(i, j int) bool { return hq2xDiff(yuv[i], yuv[j]) }
+
+	switch diffB, diffD := diff(e, b), diff(e, d); {
+	case !diffB && !diffD:
+		return hq2xInterp(center, 2, src[b], 1, src[d], 1)
+	case !diffD:
+		if !diff(e, a) {
+			return hq2xInterp(center, 2, src[a], 1, src[d], 1)
+		}
+		return hq2xInterp(center, 3, src[d], 1, 0, 0)
+	case !diffB:
+		if !diff(e, a) {
+			return hq2xInterp(center, 2, src[a], 1, src[b], 1)
+		}
+		return hq2xInterp(center, 3, src[b], 1, 0, 0)
+	}
+
+	// Both sides differ from the center
+	if diff(b, d) {
+		// No edge across the corner
+		if !diff(e, a) {
+			return hq2xInterp(center, 3, src[a], 1, 0, 0)
+		}
+		return center
+	}
+	// An edge cuts the corner, b and d on its far side
+	if !diff(e, a) {
+		// A thin line runs on through the corner: keep most of it
+		return hq2xInterp(center, 6, src[b], 1, src[d], 1)
+	}
+	shallow, steep := !diff(b, c), !diff(d, g)
+	switch {
+	case shallow && steep:
+		// A lone pixel or the tip of a shape: round it off slightly
+		return hq2xInterp(center, 14, src[b], 1, src[d], 1)
+	case shallow:
+		return hq2xInterp(center, 5, src[b], 2, src[d], 1)
+	case steep:
+		return hq2xInterp(center, 5, src[d], 2, src[b], 1)
+	default:
+		return hq2xInterp(center, 2, src[b], 1, src[d], 1)
+	}
+}
+
+// hq2xInterp mixes up to three 0xRRGGBB colors by their weights
+func hq2xInterp(c1, w1, c2, w2, c3, w3 uint32) uint32 {
+	total := w1 + w2 + w3
+	mix := func(shift uint) uint32 {
+		sum := ((c1>>shift)&0xFF)*w1 + ((c2>>shift)&0xFF)*w2 + ((c3>>shift)&0xFF)*w3
+		return sum / total
+	}
+	return mix(16)<<16 | mix(8)<<8 | mix(0)
+}
+
+// hq2xYUV converts a 0xRRGGBB color to YUV packed as 0xYYUUVV
+func hq2xYUV(pixel uint32) uint32 {
+	r := int((pixel >> 16) & 0xFF)
+	g := int((pixel >> 8) & 0xFF)
+	b := int(pixel & 0xFF)
+	y := (299*r + 587*g + 114*b) / 1000
+	u := (-169*r-331*g+500*b)/1000 + 128
+	v := (500*r-419*g-81*b)/1000 + 128
+	return uint32(y)<<16 | uint32(u)<<8 | uint32(v)
+}
+
+// hq2xDiff reports whether two YUV colors are apart by more than a threshold
+// in any component
+func hq2xDiff(yuv1, yuv2 uint32) bool {
+	component := func(shift uint) int {
+		d := int((yuv1>>shift)&0xFF) - int((yuv2>>shift)&0xFF)
+		if d < 0 {
+			return -d
+		}
+		return d
+	}
+	return component(16) > hq2xThresholdY || component(8) > hq2xThresholdU || component(0) > hq2xThresholdV
+}
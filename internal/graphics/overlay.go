@@ -0,0 +1,223 @@
+// Package graphics provides software overlay drawing helpers for on-screen UI.
+package graphics
+
+import "strings"
+
+// Overlay drawing works directly on the NES-sized frame buffer (256x240, 0xRRGGBB)
+// so that every backend (Ebitengine, terminal, headless) shows the same UI
+// without needing backend-specific text rendering.
+
+const (
+	// OverlayWidth is the width of the frame buffer the overlay draws into
+	OverlayWidth = 256
+	// OverlayHeight is the height of the frame buffer the overlay draws into
+	OverlayHeight = 240
+
+	// FontWidth is the width of a glyph in pixels
+	FontWidth = 5
+	// FontHeight is the height of a glyph in pixels
+	FontHeight = 7
+	// FontAdvance is the horizontal distance between glyph origins
+	FontAdvance = FontWidth + 1
+	// LineHeight is the recommended vertical distance between text lines
+	LineHeight = FontHeight + 3
+)
+
+// Common overlay colors
+const (
+	OverlayColorWhite  uint32 = 0xFFFFFF
+	OverlayColorGray   uint32 = 0xA0A0A0
+	OverlayColorYellow uint32 = 0xFFE060
+	OverlayColorRed    uint32 = 0xFF5050
+	OverlayColorGreen  uint32 = 0x60E060
+	OverlayColorPanel  uint32 = 0x202040
+	OverlayColorBlack  uint32 = 0x000000
+)
+
+// overlayFont is a tiny 5x7 bitmap font. Each glyph is 7 rows, bit 4 is the leftmost pixel.
+var overlayFont = map[rune][FontHeight]uint8{
+	' ':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	'0':  {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1':  {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2':  {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3':  {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4':  {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5':  {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6':  {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7':  {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8':  {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9':  {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'A':  {0x0E, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'B':  {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C':  {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'D':  {0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C},
+	'E':  {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'F':  {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G':  {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F},
+	'H':  {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I':  {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'J':  {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K':  {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L':  {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M':  {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N':  {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O':  {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'P':  {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q':  {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
+	'R':  {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S':  {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E},
+	'T':  {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'V':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W':  {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A},
+	'X':  {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y':  {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04},
+	'Z':  {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	':':  {0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x0C, 0x00},
+	'-':  {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
+	'.':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C},
+	',':  {0x00, 0x00, 0x00, 0x00, 0x0C, 0x04, 0x08},
+	'/':  {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'%':  {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03},
+	'(':  {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')':  {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'[':  {0x0E, 0x08, 0x08, 0x08, 0x08, 0x08, 0x0E},
+	']':  {0x0E, 0x02, 0x02, 0x02, 0x02, 0x02, 0x0E},
+	'<':  {0x02, 0x04, 0x08, 0x10, 0x08, 0x04, 0x02},
+	'>':  {0x08, 0x04, 0x02, 0x01, 0x02, 0x04, 0x08},
+	'_':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1F},
+	'+':  {0x00, 0x04, 0x04, 0x1F, 0x04, 0x04, 0x00},
+	'=':  {0x00, 0x00, 0x1F, 0x00, 0x1F, 0x00, 0x00},
+	'!':  {0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04},
+	'?':  {0x0E, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+	'#':  {0x0A, 0x0A, 0x1F, 0x0A, 0x1F, 0x0A, 0x0A},
+	'*':  {0x00, 0x04, 0x15, 0x0E, 0x15, 0x04, 0x00},
+	'$':  {0x04, 0x0F, 0x14, 0x0E, 0x05, 0x1E, 0x04},
+	'\'': {0x0C, 0x04, 0x08, 0x00, 0x00, 0x00, 0x00},
+	'"':  {0x0A, 0x0A, 0x00, 0x00, 0x00, 0x00, 0x00},
+}
+
+// TextWidth returns the width in pixels of text drawn with DrawText
+func TextWidth(text string) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return n*FontAdvance - 1
+}
+
+// DrawText draws text into the frame buffer and returns the x position after the last glyph.
+// Lowercase letters are rendered as uppercase and unknown characters as '?'.
+func DrawText(frameBuffer *[256 * 240]uint32, x, y int, text string, color uint32) int {
+	for _, r := range strings.ToUpper(text) {
+		glyph, ok := overlayFont[r]
+		if !ok {
+			glyph = overlayFont['?']
+		}
+		for row := 0; row < FontHeight; row++ {
+			bits := glyph[row]
+			for col := 0; col < FontWidth; col++ {
+				if bits&(0x10>>uint(col)) != 0 {
+					setOverlayPixel(frameBuffer, x+col, y+row, color)
+				}
+			}
+		}
+		x += FontAdvance
+	}
+	return x
+}
+
+// DrawTextShadowed draws text with a one pixel black drop shadow for readability over game graphics
+func DrawTextShadowed(frameBuffer *[256 * 240]uint32, x, y int, text string, color uint32) int {
+	DrawText(frameBuffer, x+1, y+1, text, OverlayColorBlack)
+	return DrawText(frameBuffer, x, y, text, color)
+}
+
+// FillRect fills a rectangle with a solid color (clipped to the frame buffer)
+func FillRect(frameBuffer *[256 * 240]uint32, x, y, width, height int, color uint32) {
+	for py := y; py < y+height; py++ {
+		for px := x; px < x+width; px++ {
+			setOverlayPixel(frameBuffer, px, py, color)
+		}
+	}
+}
+
+// DrawRect draws a one pixel rectangle outline
+func DrawRect(frameBuffer *[256 * 240]uint32, x, y, width, height int, color uint32) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	FillRect(frameBuffer, x, y, width, 1, color)
+	FillRect(frameBuffer, x, y+height-1, width, 1, color)
+	FillRect(frameBuffer, x, y, 1, height, color)
+	FillRect(frameBuffer, x+width-1, y, 1, height, color)
+}
+
+// DarkenRect dims a rectangle of the frame buffer so overlay text stays readable.
+// shift is the number of bits each channel is shifted right (1 = half brightness).
+func DarkenRect(frameBuffer *[256 * 240]uint32, x, y, width, height int, shift uint) {
+	mask := uint32(0xFF>>shift) * 0x010101
+	for py := y; py < y+height; py++ {
+		if py < 0 || py >= OverlayHeight {
+			continue
+		}
+		for px := x; px < x+width; px++ {
+			if px < 0 || px >= OverlayWidth {
+				continue
+			}
+			idx := py*OverlayWidth + px
+			frameBuffer[idx] = (frameBuffer[idx] >> shift) & mask
+		}
+	}
+}
+
+// DrawPixels copies a width x height block of 0xRRGGBB pixels into the frame buffer
+func DrawPixels(frameBuffer *[256 * 240]uint32, x, y, width, height int, pixels []uint32) {
+	if len(pixels) < width*height {
+		return
+	}
+	for py := 0; py < height; py++ {
+		for px := 0; px < width; px++ {
+			setOverlayPixel(frameBuffer, x+px, y+py, pixels[py*width+px])
+		}
+	}
+}
+
+// DownscaleFrame box-filters a 256x240 frame by an integer factor (used for thumbnails)
+func DownscaleFrame(frame []uint32, factor int) (pixels []uint32, width, height int) {
+	if factor < 1 {
+		factor = 1
+	}
+	width = OverlayWidth / factor
+	height = OverlayHeight / factor
+	pixels = make([]uint32, width*height)
+	if len(frame) < OverlayWidth*OverlayHeight {
+		return pixels, width, height
+	}
+
+	samples := uint32(factor * factor)
+	for ty := 0; ty < height; ty++ {
+		for tx := 0; tx < width; tx++ {
+			var r, g, b uint32
+			for sy := 0; sy < factor; sy++ {
+				row := (ty*factor + sy) * OverlayWidth
+				for sx := 0; sx < factor; sx++ {
+					p := frame[row+tx*factor+sx]
+					r += (p >> 16) & 0xFF
+					g += (p >> 8) & 0xFF
+					b += p & 0xFF
+				}
+			}
+			pixels[ty*width+tx] = (r/samples)<<16 | (g/samples)<<8 | b/samples
+		}
+	}
+	return pixels, width, height
+}
+
+// setOverlayPixel writes a single pixel if it lies inside the frame buffer
+func setOverlayPixel(frameBuffer *[256 * 240]uint32, x, y int, color uint32) {
+	if x < 0 || x >= OverlayWidth || y < 0 || y >= OverlayHeight {
+		return
+	}
+	frameBuffer[y*OverlayWidth+x] = color & 0xFFFFFF
+}
@@ -0,0 +1,82 @@
+package graphics
+
+import "testing"
+
+func TestDrawText_DrawsGlyphPixels(t *testing.T) {
+	var fb [256 * 240]uint32
+
+	end := DrawText(&fb, 10, 20, "1", OverlayColorWhite)
+	if end != 10+FontAdvance {
+		t.Errorf("Expected end x %d, got %d", 10+FontAdvance, end)
+	}
+
+	// Bottom row of '1' is 0x0E (three middle pixels set)
+	row := 20 + FontHeight - 1
+	for col, expected := range []bool{false, true, true, true, false} {
+		got := fb[row*256+10+col] == OverlayColorWhite
+		if got != expected {
+			t.Errorf("Pixel (%d,%d): expected set=%v, got %v", 10+col, row, expected, got)
+		}
+	}
+}
+
+func TestDrawText_LowercaseMatchesUppercase(t *testing.T) {
+	var lower, upper [256 * 240]uint32
+	DrawText(&lower, 0, 0, "save", OverlayColorWhite)
+	DrawText(&upper, 0, 0, "SAVE", OverlayColorWhite)
+
+	if lower != upper {
+		t.Error("Expected lowercase text to render identically to uppercase")
+	}
+}
+
+func TestDrawText_ClipsAtEdges(t *testing.T) {
+	var fb [256 * 240]uint32
+
+	// Should not panic when drawing partially or fully off screen
+	DrawText(&fb, 250, 236, "WXYZ", OverlayColorWhite)
+	DrawText(&fb, -20, -5, "ABC", OverlayColorWhite)
+	FillRect(&fb, 250, 230, 50, 50, OverlayColorRed)
+
+	if fb[239*256+255] != OverlayColorRed {
+		t.Error("Expected clipped rectangle to fill the bottom right pixel")
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	if TextWidth("") != 0 {
+		t.Errorf("Expected empty text width 0, got %d", TextWidth(""))
+	}
+	if TextWidth("AB") != 2*FontAdvance-1 {
+		t.Errorf("Expected width %d, got %d", 2*FontAdvance-1, TextWidth("AB"))
+	}
+}
+
+func TestDarkenRect_HalvesBrightness(t *testing.T) {
+	var fb [256 * 240]uint32
+	fb[0] = 0xFF8040
+
+	DarkenRect(&fb, 0, 0, 1, 1, 1)
+
+	if fb[0] != 0x7F4020 {
+		t.Errorf("Expected 0x7F4020, got 0x%06X", fb[0])
+	}
+}
+
+func TestDownscaleFrame_AveragesBlocks(t *testing.T) {
+	frame := make([]uint32, 256*240)
+	// Top-left 2x2 block: two white and two black pixels
+	frame[0] = 0xFFFFFF
+	frame[1] = 0xFFFFFF
+
+	pixels, width, height := DownscaleFrame(frame, 2)
+	if width != 128 || height != 120 {
+		t.Fatalf("Expected 128x120, got %dx%d", width, height)
+	}
+	if pixels[0] != 0x7F7F7F {
+		t.Errorf("Expected averaged pixel 0x7F7F7F, got 0x%06X", pixels[0])
+	}
+	if pixels[1] != 0 {
+		t.Errorf("Expected black pixel, got 0x%06X", pixels[1])
+	}
+}
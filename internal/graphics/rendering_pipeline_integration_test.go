@@ -31,7 +31,7 @@ func (app *MockApplication) render() error {
 	}
 	
 	if app.window != nil {
-		return app.window.RenderFrame(app.frameBuffer)
+		return app.window.RenderFrame(&app.frameBuffer)
 	}
 	
 	return nil
@@ -220,7 +220,7 @@ func TestRenderingPipeline_EmulatorGameLoopIntegration(t *testing.T) {
 			newFrameBuffer[i] = 0x0000FFFF // Blue
 		}
 		
-		err := window.RenderFrame(newFrameBuffer)
+		err := window.RenderFrame(&newFrameBuffer)
 		if err != nil {
 			return err
 		}
@@ -285,7 +285,7 @@ func TestRenderingPipeline_FrameSynchronization(t *testing.T) {
 			frameBuffer[j] = uint32(i) << 16 // Different red intensity per frame
 		}
 		
-		err = window.RenderFrame(frameBuffer)
+		err = window.RenderFrame(&frameBuffer)
 		if err != nil {
 			t.Fatalf("Frame %d render failed: %v", i, err)
 		}
@@ -336,7 +336,7 @@ func TestRenderingPipeline_FrameBufferDataIntegrity(t *testing.T) {
 	}
 	
 	// Render the frame
-	err = window.RenderFrame(originalFrameBuffer)
+	err = window.RenderFrame(&originalFrameBuffer)
 	if err != nil {
 		t.Fatalf("Frame render failed: %v", err)
 	}
@@ -370,7 +370,7 @@ func TestRenderingPipeline_ErrorHandling(t *testing.T) {
 	window := &EbitengineWindow{game: nil}
 	var frameBuffer [256 * 240]uint32
 	
-	err = window.RenderFrame(frameBuffer)
+	err = window.RenderFrame(&frameBuffer)
 	if err == nil {
 		t.Fatal("Expected error when rendering with nil game")
 	}
@@ -420,7 +420,7 @@ func TestRenderingPipeline_ConcurrentAccess(t *testing.T) {
 					frameBuffer[i] = color
 				}
 				
-				err := window.RenderFrame(frameBuffer)
+				err := window.RenderFrame(&frameBuffer)
 				if err != nil {
 					errorChan <- err
 					return
@@ -469,7 +469,7 @@ func TestRenderingPipeline_MemoryLeakPrevention(t *testing.T) {
 			frameBuffer[j] = uint32(i%256) << 16 // Rotating red intensity
 		}
 		
-		err = window.RenderFrame(frameBuffer)
+		err = window.RenderFrame(&frameBuffer)
 		if err != nil {
 			t.Fatalf("Frame %d render failed: %v", i, err)
 		}
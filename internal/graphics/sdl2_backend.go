@@ -0,0 +1,885 @@
+//go:build sdl2 && cgo
+
+package graphics
+
+/*
+#cgo pkg-config: sdl2
+#include <stdlib.h>
+#include <SDL.h>
+
+// gones_event holds the fields of an SDL event used by the windows
+typedef struct {
+	Uint32 type;
+	Uint32 window;
+	int scancode;
+	int mod;
+	int repeat;
+	int x;
+	int y;
+	int button;
+	int which;
+} gones_event;
+
+static int gones_poll_event(gones_event *e) {
+	SDL_Event ev;
+	if (!SDL_PollEvent(&ev)) {
+		return 0;
+	}
+	e->type = ev.type;
+	e->window = 0;
+	switch (ev.type) {
+	case SDL_KEYDOWN:
+	case SDL_KEYUP:
+		e->window = ev.key.windowID;
+		e->scancode = ev.key.keysym.scancode;
+		e->mod = ev.key.keysym.mod;
+		e->repeat = ev.key.repeat;
+		break;
+	case SDL_MOUSEMOTION:
+		e->window = ev.motion.windowID;
+		e->x = ev.motion.x;
+		e->y = ev.motion.y;
+		break;
+	case SDL_MOUSEBUTTONDOWN:
+	case SDL_MOUSEBUTTONUP:
+		e->window = ev.button.windowID;
+		e->x = ev.button.x;
+		e->y = ev.button.y;
+		e->button = ev.button.button;
+		break;
+	case SDL_WINDOWEVENT:
+		e->window = ev.window.windowID;
+		e->button = ev.window.event;
+		break;
+	case SDL_CONTROLLERDEVICEADDED:
+	case SDL_CONTROLLERDEVICEREMOVED:
+		e->which = ev.cdevice.which;
+		break;
+	case SDL_CONTROLLERBUTTONDOWN:
+		e->which = ev.cbutton.which;
+		e->button = ev.cbutton.button;
+		break;
+	}
+	return 1;
+}
+
+static SDL_Window *gones_create_window(const char *title, int width, int height, Uint32 flags) {
+	return SDL_CreateWindow(title, SDL_WINDOWPOS_CENTERED, SDL_WINDOWPOS_CENTERED, width, height, flags);
+}
+
+static void gones_set_scale_quality(const char *quality) {
+	SDL_SetHint(SDL_HINT_RENDER_SCALE_QUALITY, quality);
+}
+
+static SDL_AudioDeviceID gones_open_audio(int freq, int samples) {
+	SDL_AudioSpec want, have;
+	SDL_zero(want);
+	want.freq = freq;
+	want.format = AUDIO_F32SYS;
+	want.channels = 1;
+	want.samples = samples;
+	return SDL_OpenAudioDevice(NULL, 0, &want, &have, 0);
+}
+
+static SDL_AudioDeviceID gones_open_capture(int freq) {
+	SDL_AudioSpec want, have;
+	SDL_zero(want);
+	want.freq = freq;
+	want.format = AUDIO_F32SYS;
+	want.channels = 1;
+	want.samples = 512;
+	return SDL_OpenAudioDevice(NULL, 1, &want, &have, 0);
+}
+
+static SDL_JoystickID gones_controller_id(SDL_GameController *controller) {
+	return SDL_JoystickInstanceID(SDL_GameControllerGetJoystick(controller));
+}
+
+static void gones_controller_guid(SDL_GameController *controller, char *buf, int size) {
+	SDL_JoystickGetGUIDString(SDL_JoystickGetGUID(SDL_GameControllerGetJoystick(controller)), buf, size);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+func init() {
+	// SDL must be driven from the main thread on some platforms
+	runtime.LockOSThread()
+}
+
+// SDL2Backend implements the Backend interface using SDL2. It is built with
+// the sdl2 build tag and needs the SDL2 development libraries. It can open
+// several windows; the first one gets the audio, microphone and gamepads.
+type SDL2Backend struct {
+	initialized bool
+	config      Config
+
+	windows map[C.Uint32]*SDL2Window // Open windows by SDL window ID
+	main    *SDL2Window              // First window opened
+}
+
+// SDL2Window implements the Window interface with an SDL2 window, renderer
+// and audio device
+type SDL2Window struct {
+	backend *SDL2Backend
+	id      C.Uint32
+	pending []C.gones_event // SDL events sent to this window, not yet polled
+	title   string
+	running bool
+	closed  bool
+
+	window        *C.SDL_Window
+	renderer      *C.SDL_Renderer
+	texture       *C.SDL_Texture
+	textureWidth  int
+	textureHeight int
+	aspectMode    string
+	vsync         bool // Configured VSync, off while unthrottled
+
+	audio           C.SDL_AudioDeviceID // 0 without audio
+	audioQueueLimit int                 // Queued bytes above which samples are dropped
+	audioTarget     int                 // Queued bytes WaitAudio waits for
+	audioPlaying    bool                // Samples have been queued since the device opened
+	audioUnderruns  uint64              // Times the queue ran dry while playing
+	audioAccepted   uint64              // Samples queued (not dropped) since the device opened
+
+	microphone       C.SDL_AudioDeviceID // Capture device, 0 without one
+	microphoneBuffer []float32
+
+	keyBindings map[string][]Button // Binding key ID to bound buttons
+	heldKeys    map[string]bool     // Bound keys currently held
+	capture     func(input CapturedInput)
+	events      []InputEvent // Events queued outside PollEvents
+
+	// Mouse state in window coordinates, and the last reported NES position
+	mouseX, mouseY         int
+	mouseButtons           MouseButton
+	lastMouseX, lastMouseY int
+	lastMouseButtons       MouseButton
+
+	controllers map[int]*C.SDL_GameController // By joystick instance ID
+	padStates   map[int][8]bool
+	padBindings [MaxGamepadPlayers][8][]int // SDL buttons (see sdlStandardButtons)
+	assigner    *GamepadAssigner
+	deadzone    float64
+}
+
+// NewSDL2Backend creates a new SDL2 graphics backend
+func NewSDL2Backend() Backend {
+	return &SDL2Backend{}
+}
+
+// Initialize initializes SDL
+func (b *SDL2Backend) Initialize(config Config) error {
+	if b.initialized {
+		return fmt.Errorf("SDL2 backend already initialized")
+	}
+	if config.Headless {
+		return fmt.Errorf("SDL2 backend cannot run headless")
+	}
+
+	if C.SDL_Init(C.SDL_INIT_VIDEO|C.SDL_INIT_GAMECONTROLLER) != 0 {
+		return fmt.Errorf("failed to initialize SDL: %s", sdlError())
+	}
+	if config.AudioEnabled || config.Microphone {
+		if C.SDL_InitSubSystem(C.SDL_INIT_AUDIO) != 0 {
+			log.Printf("[SDL2] Audio unavailable: %s", sdlError())
+			config.AudioEnabled = false
+			config.Microphone = false
+		}
+	}
+
+	b.config = config
+	b.windows = make(map[C.Uint32]*SDL2Window)
+	b.initialized = true
+	return nil
+}
+
+// MaxWindows returns 0: SDL opens as many windows as asked
+func (b *SDL2Backend) MaxWindows() int {
+	return 0
+}
+
+// pumpEvents takes the events SDL has queued and hands each to its window.
+// Events of no window, such as gamepads and quitting, go to the main one.
+func (b *SDL2Backend) pumpEvents() {
+	var ev C.gones_event
+	for C.gones_poll_event(&ev) != 0 {
+		target := b.windows[ev.window]
+		if target == nil {
+			target = b.main
+		}
+		if target != nil {
+			target.pending = append(target.pending, ev)
+		}
+	}
+}
+
+// CreateWindow creates an SDL window with an accelerated renderer
+func (b *SDL2Backend) CreateWindow(title string, width, height int) (Window, error) {
+	if !b.initialized {
+		return nil, fmt.Errorf("backend not initialized")
+	}
+
+	// High DPI windows get a renderer in device pixels (see outputSize)
+	flags := C.Uint32(C.SDL_WINDOW_RESIZABLE | C.SDL_WINDOW_ALLOW_HIGHDPI)
+	if b.config.Fullscreen {
+		flags |= C.SDL_WINDOW_FULLSCREEN_DESKTOP
+	}
+	cTitle := C.CString(title)
+	defer C.free(unsafe.Pointer(cTitle))
+	window := C.gones_create_window(cTitle, C.int(width), C.int(height), flags)
+	if window == nil {
+		return nil, fmt.Errorf("failed to create SDL window: %s", sdlError())
+	}
+
+	rendererFlags := C.Uint32(C.SDL_RENDERER_ACCELERATED)
+	if b.config.VSync {
+		rendererFlags |= C.SDL_RENDERER_PRESENTVSYNC
+	}
+	renderer := C.SDL_CreateRenderer(window, -1, rendererFlags)
+	if renderer == nil {
+		C.SDL_DestroyWindow(window)
+		return nil, fmt.Errorf("failed to create SDL renderer: %s", sdlError())
+	}
+
+	// Textures pick up the scale quality when they are created
+	quality := "nearest"
+	switch b.config.Filter {
+	case "linear":
+		quality = "linear"
+	case FilterCRT:
+		log.Printf("[SDL2] The CRT filter is not supported, using nearest")
+	}
+	cQuality := C.CString(quality)
+	C.gones_set_scale_quality(cQuality)
+	C.free(unsafe.Pointer(cQuality))
+
+	deadzone := b.config.GamepadDeadzone
+	if deadzone < 0 || deadzone >= 1 {
+		deadzone = 0.1
+	}
+	w := &SDL2Window{
+		backend:     b,
+		id:          C.SDL_GetWindowID(window),
+		title:       title,
+		running:     true,
+		window:      window,
+		renderer:    renderer,
+		aspectMode:  b.config.AspectRatio,
+		vsync:       b.config.VSync,
+		heldKeys:    make(map[string]bool),
+		lastMouseX:  -1,
+		lastMouseY:  -1,
+		controllers: make(map[int]*C.SDL_GameController),
+		padStates:   make(map[int][8]bool),
+		assigner:    NewGamepadAssigner(b.config.GamepadAssignments),
+		deadzone:    deadzone,
+	}
+	if err := w.resizeTexture(256, 240); err != nil {
+		w.Cleanup()
+		return nil, err
+	}
+	w.SetInputBindings(DefaultInputBindings())
+	b.windows[w.id] = w
+	if b.main != nil {
+		// Audio and the microphone belong to the main window
+		return w, nil
+	}
+	b.main = w
+
+	if b.config.AudioEnabled && b.config.AudioSampleRate > 0 {
+		bufferSize := b.config.AudioBufferSize
+		if bufferSize <= 0 {
+			bufferSize = 1024
+		}
+		w.audio = C.gones_open_audio(C.int(b.config.AudioSampleRate), C.int(bufferSize))
+		if w.audio == 0 {
+			log.Printf("[SDL2] Failed to open audio device: %s", sdlError())
+		} else {
+			latency := b.config.AudioSampleRate * max(0, b.config.AudioLatency) / 1000
+			w.audioQueueLimit = (latency + bufferSize) * 4
+			w.audioTarget = latency * 4
+			C.SDL_PauseAudioDevice(w.audio, 0)
+		}
+	}
+	if b.config.Microphone {
+		w.microphone = C.gones_open_capture(22050)
+		if w.microphone == 0 {
+			log.Printf("[SDL2] Failed to open microphone: %s", sdlError())
+		} else {
+			w.microphoneBuffer = make([]float32, 1024)
+			C.SDL_PauseAudioDevice(w.microphone, 0)
+		}
+	}
+
+	return w, nil
+}
+
+// Cleanup shuts SDL down
+func (b *SDL2Backend) Cleanup() error {
+	if b.initialized {
+		C.SDL_Quit()
+	}
+	b.initialized = false
+	return nil
+}
+
+// IsHeadless returns false
+func (b *SDL2Backend) IsHeadless() bool {
+	return false
+}
+
+// GetName returns the backend name
+func (b *SDL2Backend) GetName() string {
+	return "SDL2"
+}
+
+// sdlError returns the last SDL error message
+func sdlError() string {
+	return C.GoString(C.SDL_GetError())
+}
+
+// SDL2Window implementation
+
+// SetTitle sets the window title
+func (w *SDL2Window) SetTitle(title string) {
+	w.title = title
+	cTitle := C.CString(title)
+	defer C.free(unsafe.Pointer(cTitle))
+	C.SDL_SetWindowTitle(w.window, cTitle)
+}
+
+// GetSize returns window dimensions
+func (w *SDL2Window) GetSize() (width, height int) {
+	var cw, ch C.int
+	C.SDL_GetWindowSize(w.window, &cw, &ch)
+	return int(cw), int(ch)
+}
+
+// IsFocused reports whether the window has the keyboard focus
+func (w *SDL2Window) IsFocused() bool {
+	return C.SDL_GetWindowFlags(w.window)&C.SDL_WINDOW_INPUT_FOCUS != 0
+}
+
+// ShouldClose returns true if window should close
+func (w *SDL2Window) ShouldClose() bool {
+	return !w.running
+}
+
+// SwapBuffers presents the frame drawn by RenderFrame
+func (w *SDL2Window) SwapBuffers() {
+	C.SDL_RenderPresent(w.renderer)
+}
+
+// PollEvents processes the SDL events sent to the window and appends the
+// resulting input events to events
+func (w *SDL2Window) PollEvents(events []InputEvent) []InputEvent {
+	events = append(events, w.events...)
+	w.events = w.events[:0]
+
+	w.backend.pumpEvents()
+	for _, ev := range w.pending {
+		switch ev._type {
+		case C.SDL_QUIT:
+			w.running = false
+			events = append(events, InputEvent{Type: InputEventTypeQuit, Pressed: true})
+		case C.SDL_WINDOWEVENT:
+			if ev.button != C.SDL_WINDOWEVENT_CLOSE {
+				continue
+			}
+			// SDL only sends SDL_QUIT once the last window closes
+			w.running = false
+			if w == w.backend.main && len(w.backend.windows) > 1 {
+				events = append(events, InputEvent{Type: InputEventTypeQuit, Pressed: true})
+			}
+		case C.SDL_KEYDOWN, C.SDL_KEYUP:
+			if ev.repeat != 0 {
+				continue
+			}
+			events = w.appendKeyEvents(events, sdlScancodeName(int(ev.scancode)), sdlModifiers(int(ev.mod)), ev._type == C.SDL_KEYDOWN)
+		case C.SDL_MOUSEMOTION:
+			w.mouseX, w.mouseY = int(ev.x), int(ev.y)
+		case C.SDL_MOUSEBUTTONDOWN, C.SDL_MOUSEBUTTONUP:
+			w.mouseX, w.mouseY = int(ev.x), int(ev.y)
+			var button MouseButton
+			switch ev.button {
+			case C.SDL_BUTTON_LEFT:
+				button = MouseButtonLeft
+			case C.SDL_BUTTON_RIGHT:
+				button = MouseButtonRight
+			case C.SDL_BUTTON_MIDDLE:
+				button = MouseButtonMiddle
+			}
+			if ev._type == C.SDL_MOUSEBUTTONDOWN {
+				w.mouseButtons |= button
+			} else {
+				w.mouseButtons &^= button
+			}
+		case C.SDL_CONTROLLERDEVICEADDED:
+			w.openController(int(ev.which))
+		case C.SDL_CONTROLLERDEVICEREMOVED:
+			events = w.closeController(events, int(ev.which))
+		case C.SDL_CONTROLLERBUTTONDOWN:
+			if w.capture == nil {
+				continue
+			}
+			player := w.assigner.PlayerFor(int(ev.which))
+			if name := sdlStandardButtonName(int(ev.button)); name != "" && player >= 0 {
+				callback := w.capture
+				w.capture = nil
+				callback(CapturedInput{Gamepad: true, Player: player, Name: name})
+			}
+		}
+	}
+	w.pending = w.pending[:0]
+
+	events = w.appendMouseEvent(events)
+	return w.pollControllers(events)
+}
+
+// appendKeyEvents reports a key press or release as button events if the key
+// is bound to controller buttons, or as a key event otherwise
+func (w *SDL2Window) appendKeyEvents(events []InputEvent, name string, modifiers ModifierKey, pressed bool) []InputEvent {
+	if name == "" {
+		return events
+	}
+	if pressed && w.capture != nil {
+		callback := w.capture
+		w.capture = nil
+		callback(CapturedInput{Name: name})
+		return events
+	}
+
+	id := bindingKeyID(name)
+	if buttons, bound := w.keyBindings[id]; bound {
+		if pressed == w.heldKeys[id] {
+			return events
+		}
+		if pressed {
+			w.heldKeys[id] = true
+		} else {
+			delete(w.heldKeys, id)
+		}
+		for _, button := range buttons {
+			events = append(events, InputEvent{Type: InputEventTypeButton, Button: button, Pressed: pressed})
+		}
+		return events
+	}
+	if key, ok := keyCodesByName[name]; ok {
+		events = append(events, InputEvent{Type: InputEventTypeKey, Key: key, Pressed: pressed, Modifiers: modifiers})
+	}
+	return events
+}
+
+// sdlModifiers converts SDL key modifiers
+func sdlModifiers(mod int) ModifierKey {
+	modifiers := ModifierNone
+	if mod&C.KMOD_SHIFT != 0 {
+		modifiers |= ModifierShift
+	}
+	if mod&C.KMOD_CTRL != 0 {
+		modifiers |= ModifierCtrl
+	}
+	if mod&C.KMOD_ALT != 0 {
+		modifiers |= ModifierAlt
+	}
+	if mod&C.KMOD_GUI != 0 {
+		modifiers |= ModifierSuper
+	}
+	return modifiers
+}
+
+// appendMouseEvent appends a mouse event if the pointer moved to another NES
+// pixel or a button changed
+func (w *SDL2Window) appendMouseEvent(events []InputEvent) []InputEvent {
+	// Mouse coordinates are in window units, which differ from renderer
+	// pixels on high density displays
+	windowWidth, windowHeight := w.GetSize()
+	outputWidth, outputHeight := w.outputSize()
+	x, y := -1, -1
+	if windowWidth > 0 && windowHeight > 0 {
+		scaleX, scaleY, offsetX, offsetY := DisplayRect(w.aspectMode, outputWidth, outputHeight, 256, 240)
+		if scaleX > 0 && scaleY > 0 {
+			pixelX := float64(w.mouseX) * float64(outputWidth) / float64(windowWidth)
+			pixelY := float64(w.mouseY) * float64(outputHeight) / float64(windowHeight)
+			x = int(math.Floor((pixelX - offsetX) / scaleX))
+			y = int(math.Floor((pixelY - offsetY) / scaleY))
+		}
+	}
+
+	if x == w.lastMouseX && y == w.lastMouseY && w.mouseButtons == w.lastMouseButtons {
+		return events
+	}
+	w.lastMouseX, w.lastMouseY, w.lastMouseButtons = x, y, w.mouseButtons
+
+	return append(events, InputEvent{
+		Type:         InputEventTypeMouse,
+		Pressed:      w.mouseButtons&MouseButtonLeft != 0,
+		MouseX:       x,
+		MouseY:       y,
+		MouseButtons: w.mouseButtons,
+	})
+}
+
+// openController opens a newly connected game controller
+func (w *SDL2Window) openController(deviceIndex int) {
+	controller := C.SDL_GameControllerOpen(C.int(deviceIndex))
+	if controller == nil {
+		log.Printf("[SDL2] Failed to open game controller %d: %s", deviceIndex, sdlError())
+		return
+	}
+	id := int(C.gones_controller_id(controller))
+	if _, open := w.controllers[id]; open {
+		C.SDL_GameControllerClose(controller)
+		return
+	}
+
+	var guid [33]C.char
+	C.gones_controller_guid(controller, &guid[0], C.int(len(guid)))
+	info := GamepadInfo{
+		ID:   id,
+		Name: C.GoString(C.SDL_GameControllerName(controller)),
+		GUID: C.GoString(&guid[0]),
+	}
+	w.controllers[id] = controller
+	w.padStates[id] = [8]bool{}
+	if player := w.assigner.Connect(info); player >= 0 {
+		log.Printf("[SDL2] Gamepad connected: %s (id %d) -> player %d", info.Name, id, player+1)
+	} else {
+		log.Printf("[SDL2] Gamepad connected: %s (id %d) - not assigned", info.Name, id)
+	}
+}
+
+// closeController closes a disconnected game controller, releasing the
+// buttons it was holding
+func (w *SDL2Window) closeController(events []InputEvent, id int) []InputEvent {
+	controller, open := w.controllers[id]
+	if !open {
+		return events
+	}
+	if player := w.assigner.PlayerFor(id); player >= 0 {
+		events = appendGamepadChanges(events, player, w.padStates[id], [8]bool{})
+		events = append(events, InputEvent{Type: InputEventTypeGamepadDisconnected, Player: player})
+	}
+	C.SDL_GameControllerClose(controller)
+	delete(w.controllers, id)
+	delete(w.padStates, id)
+
+	player := w.assigner.Disconnect(id)
+	log.Printf("[SDL2] Gamepad disconnected (id %d)", id)
+	if pad, ok := w.assigner.GamepadFor(player); ok {
+		log.Printf("[SDL2] Gamepad %s (id %d) -> player %d", pad.Name, pad.ID, player+1)
+	}
+	return events
+}
+
+// pollControllers appends button events for every change in the state of the
+// assigned controllers
+func (w *SDL2Window) pollControllers(events []InputEvent) []InputEvent {
+	for player := 0; player < MaxGamepadPlayers; player++ {
+		pad, ok := w.assigner.GamepadFor(player)
+		if !ok {
+			continue
+		}
+		controller := w.controllers[pad.ID]
+		if controller == nil {
+			continue
+		}
+
+		var state [8]bool
+		for i, buttons := range w.padBindings[player] {
+			for _, button := range buttons {
+				if button >= 0 {
+					state[i] = state[i] || C.SDL_GameControllerGetButton(controller, C.SDL_GameControllerButton(button)) != 0
+				} else {
+					axis := C.SDL_GameControllerAxis(-1 - button)
+					state[i] = state[i] || C.SDL_GameControllerGetAxis(controller, axis) > 16384
+				}
+			}
+		}
+
+		// The left stick always drives the D-pad
+		x := float64(C.SDL_GameControllerGetAxis(controller, C.SDL_CONTROLLER_AXIS_LEFTX)) / 32767
+		y := float64(C.SDL_GameControllerGetAxis(controller, C.SDL_CONTROLLER_AXIS_LEFTY)) / 32767
+		up, down, left, right := StickToDPad(x, y, w.deadzone)
+		state[4] = state[4] || up
+		state[5] = state[5] || down
+		state[6] = state[6] || left
+		state[7] = state[7] || right
+
+		events = appendGamepadChanges(events, player, w.padStates[pad.ID], state)
+		w.padStates[pad.ID] = state
+	}
+	return events
+}
+
+// SetInputBindings replaces the active key and gamepad bindings. SDL game
+// controllers always use the standard layout, so raw "ButtonN" bindings do
+// not apply.
+func (w *SDL2Window) SetInputBindings(bindings InputBindings) {
+	keyBindings := make(map[string][]Button)
+	for player := 0; player < MaxGamepadPlayers; player++ {
+		for i, names := range bindings.Keyboard[player] {
+			button := PlayerButton(player, ControllerButtons[i])
+			for _, name := range names {
+				id := bindingKeyID(name)
+				keyBindings[id] = append(keyBindings[id], button)
+			}
+		}
+
+		for i, names := range bindings.Gamepad[player] {
+			var buttons []int
+			for _, name := range names {
+				_, standard, index, ok := NormalizeGamepadButtonName(name)
+				if ok && standard && index < len(sdlStandardButtons) {
+					buttons = append(buttons, sdlStandardButtons[index])
+				}
+			}
+			w.padBindings[player][i] = buttons
+		}
+	}
+
+	// Release buttons held through keys whose binding is going away
+	for id := range w.heldKeys {
+		for _, button := range w.keyBindings[id] {
+			w.events = append(w.events, InputEvent{Type: InputEventTypeButton, Button: button, Pressed: false})
+		}
+	}
+	clear(w.heldKeys)
+	w.keyBindings = keyBindings
+}
+
+// CaptureNextInput reports the next key or gamepad button press to callback
+// instead of processing it. The callback runs from PollEvents.
+func (w *SDL2Window) CaptureNextInput(callback func(input CapturedInput)) {
+	w.capture = callback
+}
+
+// CancelCapture stops a pending capture
+func (w *SDL2Window) CancelCapture() {
+	w.capture = nil
+}
+
+// RenderFrame draws a NES frame buffer, presented by SwapBuffers
+func (w *SDL2Window) RenderFrame(frameBuffer *[256 * 240]uint32) error {
+	return w.draw(frameBuffer[:], 256, 240)
+}
+
+// RenderScaledFrame draws an upscaled frame in place of the NES picture
+func (w *SDL2Window) RenderScaledFrame(pixels []uint32, width, height int) error {
+	if len(pixels) < width*height {
+		return fmt.Errorf("scaled frame too small: %d pixels for %dx%d", len(pixels), width, height)
+	}
+	return w.draw(pixels, width, height)
+}
+
+// SetAspectRatio changes how the picture is fitted into the window (see DisplayRect)
+func (w *SDL2Window) SetAspectRatio(mode string) {
+	w.aspectMode = mode
+}
+
+// SetUnthrottled stops waiting for VSync when presenting, or goes back to the
+// configured VSync. It needs SDL 2.0.18 or later.
+func (w *SDL2Window) SetUnthrottled(unthrottled bool) {
+	vsync := C.int(0)
+	if w.vsync && !unthrottled {
+		vsync = 1
+	}
+	if C.SDL_RenderSetVSync(w.renderer, vsync) != 0 {
+		log.Printf("[SDL2] Failed to change VSync: %s", sdlError())
+	}
+}
+
+// SetWindowScale leaves fullscreen and resizes the window to a multiple of 256x240
+func (w *SDL2Window) SetWindowScale(scale int) {
+	scale = max(MinWindowScale, min(MaxWindowScale, scale))
+	C.SDL_SetWindowFullscreen(w.window, 0)
+	C.SDL_SetWindowSize(w.window, C.int(256*scale), C.int(240*scale))
+}
+
+// SetFullscreen switches between a fullscreen window at the desktop
+// resolution and the window
+func (w *SDL2Window) SetFullscreen(fullscreen bool) {
+	flags := C.Uint32(0)
+	if fullscreen {
+		flags = C.SDL_WINDOW_FULLSCREEN_DESKTOP
+	}
+	C.SDL_SetWindowFullscreen(w.window, flags)
+}
+
+// IsFullscreen reports whether the window is fullscreen
+func (w *SDL2Window) IsFullscreen() bool {
+	return C.SDL_GetWindowFlags(w.window)&C.SDL_WINDOW_FULLSCREEN != 0
+}
+
+// draw uploads a width x height 0xRRGGBB frame and copies it into the window
+func (w *SDL2Window) draw(pixels []uint32, width, height int) error {
+	if width != w.textureWidth || height != w.textureHeight {
+		if err := w.resizeTexture(width, height); err != nil {
+			return err
+		}
+	}
+	if C.SDL_UpdateTexture(w.texture, nil, unsafe.Pointer(&pixels[0]), C.int(width*4)) != 0 {
+		return fmt.Errorf("failed to update SDL texture: %s", sdlError())
+	}
+
+	// The frame is stretched to the NES picture size, so upscaled frames
+	// keep the aspect ratio of the original
+	outputWidth, outputHeight := w.outputSize()
+	scaleX, scaleY, offsetX, offsetY := DisplayRect(w.aspectMode, outputWidth, outputHeight, 256, 240)
+	dst := C.SDL_Rect{
+		x: C.int(math.Round(offsetX)),
+		y: C.int(math.Round(offsetY)),
+		w: C.int(math.Round(256 * scaleX)),
+		h: C.int(math.Round(240 * scaleY)),
+	}
+
+	C.SDL_SetRenderDrawColor(w.renderer, 0, 0, 0, 255)
+	C.SDL_RenderClear(w.renderer)
+	if C.SDL_RenderCopy(w.renderer, w.texture, nil, &dst) != 0 {
+		return fmt.Errorf("failed to draw SDL texture: %s", sdlError())
+	}
+	return nil
+}
+
+// resizeTexture replaces the streaming texture frames are uploaded to
+func (w *SDL2Window) resizeTexture(width, height int) error {
+	if w.texture != nil {
+		C.SDL_DestroyTexture(w.texture)
+	}
+	w.texture = C.SDL_CreateTexture(w.renderer, C.SDL_PIXELFORMAT_RGB888, C.SDL_TEXTUREACCESS_STREAMING, C.int(width), C.int(height))
+	if w.texture == nil {
+		w.textureWidth, w.textureHeight = 0, 0
+		return fmt.Errorf("failed to create SDL texture: %s", sdlError())
+	}
+	w.textureWidth, w.textureHeight = width, height
+	return nil
+}
+
+// outputSize returns the renderer size in pixels
+func (w *SDL2Window) outputSize() (width, height int) {
+	var cw, ch C.int
+	C.SDL_GetRendererOutputSize(w.renderer, &cw, &ch)
+	return int(cw), int(ch)
+}
+
+// QueueAudio queues samples for playback. Samples are dropped while more than
+// the configured latency is queued, so audio never lags far behind the video.
+func (w *SDL2Window) QueueAudio(samples []float32) error {
+	if w.audio == 0 || len(samples) == 0 {
+		return nil
+	}
+	queued := int(C.SDL_GetQueuedAudioSize(w.audio))
+	if queued > w.audioQueueLimit {
+		return nil
+	}
+	if queued == 0 && w.audioPlaying {
+		w.audioUnderruns++
+	}
+	w.audioPlaying = true
+	if C.SDL_QueueAudio(w.audio, unsafe.Pointer(&samples[0]), C.Uint32(len(samples)*4)) != 0 {
+		return fmt.Errorf("failed to queue audio: %s", sdlError())
+	}
+	w.audioAccepted += uint64(len(samples))
+	return nil
+}
+
+// MicrophoneLevel returns the peak level of the sound captured since the
+// last call, taking it out of the capture queue
+func (w *SDL2Window) MicrophoneLevel() float64 {
+	if w.microphone == 0 {
+		return 0
+	}
+	var peak float64
+	for {
+		size := C.SDL_DequeueAudio(w.microphone, unsafe.Pointer(&w.microphoneBuffer[0]), C.Uint32(len(w.microphoneBuffer)*4))
+		if size == 0 {
+			return peak
+		}
+		for _, sample := range w.microphoneBuffer[:size/4] {
+			peak = max(peak, math.Abs(float64(sample)))
+		}
+	}
+}
+
+// AudioUnderruns returns how many times the audio queue ran dry while playing
+func (w *SDL2Window) AudioUnderruns() uint64 {
+	return w.audioUnderruns
+}
+
+// AudioPlayed returns how many samples have left the queue for the device
+func (w *SDL2Window) AudioPlayed() uint64 {
+	if w.audio == 0 {
+		return 0
+	}
+	queued := uint64(w.AudioQueued())
+	return w.audioAccepted - min(queued, w.audioAccepted)
+}
+
+// AudioQueued returns how many samples wait in the queue
+func (w *SDL2Window) AudioQueued() int {
+	if w.audio == 0 {
+		return 0
+	}
+	return int(C.SDL_GetQueuedAudioSize(w.audio)) / 4
+}
+
+// sdlAudioWaitTimeout bounds WaitAudio when the device stops consuming samples
+const sdlAudioWaitTimeout = 100 * time.Millisecond
+
+// WaitAudio blocks until the queued audio drops to the configured latency
+func (w *SDL2Window) WaitAudio() bool {
+	if w.audio == 0 {
+		return false
+	}
+	deadline := time.Now().Add(sdlAudioWaitTimeout)
+	for int(C.SDL_GetQueuedAudioSize(w.audio)) > w.audioTarget {
+		if time.Now().After(deadline) {
+			return false
+		}
+		C.SDL_Delay(1)
+	}
+	return true
+}
+
+// Cleanup destroys the window, renderer, controllers and audio device
+func (w *SDL2Window) Cleanup() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.running = false
+	delete(w.backend.windows, w.id)
+	if w.backend.main == w {
+		w.backend.main = nil
+	}
+
+	if w.audio != 0 {
+		C.SDL_CloseAudioDevice(w.audio)
+		w.audio = 0
+	}
+	if w.microphone != 0 {
+		C.SDL_CloseAudioDevice(w.microphone)
+		w.microphone = 0
+	}
+	for id, controller := range w.controllers {
+		C.SDL_GameControllerClose(controller)
+		delete(w.controllers, id)
+	}
+	if w.texture != nil {
+		C.SDL_DestroyTexture(w.texture)
+		w.texture = nil
+	}
+	C.SDL_DestroyRenderer(w.renderer)
+	C.SDL_DestroyWindow(w.window)
+	return nil
+}
@@ -0,0 +1,38 @@
+//go:build !sdl2 || !cgo
+
+package graphics
+
+import "fmt"
+
+// SDL2Backend stub for builds without the sdl2 build tag or without cgo
+type SDL2Backend struct{}
+
+// NewSDL2Backend creates a stub backend that reports SDL2 as unavailable
+func NewSDL2Backend() Backend {
+	return &SDL2Backend{}
+}
+
+// Initialize fails: SDL2 support is not compiled in
+func (b *SDL2Backend) Initialize(config Config) error {
+	return fmt.Errorf("SDL2 backend not available in this build (rebuild with -tags sdl2 and cgo enabled)")
+}
+
+// CreateWindow fails: SDL2 support is not compiled in
+func (b *SDL2Backend) CreateWindow(title string, width, height int) (Window, error) {
+	return nil, fmt.Errorf("SDL2 backend not available in this build (rebuild with -tags sdl2 and cgo enabled)")
+}
+
+// Cleanup does nothing
+func (b *SDL2Backend) Cleanup() error {
+	return nil
+}
+
+// IsHeadless returns false
+func (b *SDL2Backend) IsHeadless() bool {
+	return false
+}
+
+// GetName returns the backend name
+func (b *SDL2Backend) GetName() string {
+	return "SDL2"
+}
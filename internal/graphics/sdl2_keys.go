@@ -0,0 +1,65 @@
+// Package graphics provides the SDL2 key and controller tables of the SDL2 backend.
+package graphics
+
+import "strconv"
+
+// sdlScancodeNames maps SDL scancodes to key binding names. Scancodes name
+// physical keys, like Ebitengine keys, so bindings work on every layout.
+var sdlScancodeNames = map[int]string{
+	40: "Enter", 41: "Escape", 42: "Backspace", 43: "Tab", 44: "Space",
+	45: "Minus", 46: "Equal", 47: "BracketLeft", 48: "BracketRight", 49: "Backslash",
+	51: "Semicolon", 52: "Quote", 53: "Backquote", 54: "Comma", 55: "Period", 56: "Slash",
+	57: "CapsLock", 70: "PrintScreen", 71: "ScrollLock", 72: "Pause",
+	73: "Insert", 74: "Home", 75: "PageUp", 76: "Delete", 77: "End", 78: "PageDown",
+	79: "ArrowRight", 80: "ArrowLeft", 81: "ArrowDown", 82: "ArrowUp",
+	83: "NumLock", 84: "NumpadDivide", 85: "NumpadMultiply", 86: "NumpadSubtract",
+	87: "NumpadAdd", 88: "NumpadEnter", 98: "Numpad0", 99: "NumpadDecimal",
+	224: "ControlLeft", 225: "ShiftLeft", 226: "AltLeft", 227: "MetaLeft",
+	228: "ControlRight", 229: "ShiftRight", 230: "AltRight", 231: "MetaRight",
+}
+
+// sdlScancodeName returns the binding name of an SDL scancode, or "" for keys
+// without one
+func sdlScancodeName(scancode int) string {
+	switch {
+	case scancode >= 4 && scancode <= 29: // A-Z
+		return string(rune('A' + scancode - 4))
+	case scancode >= 30 && scancode <= 38: // 1-9
+		return strconv.Itoa(scancode - 29)
+	case scancode == 39:
+		return "0"
+	case scancode >= 58 && scancode <= 69: // F1-F12
+		return "F" + strconv.Itoa(scancode-57)
+	case scancode >= 89 && scancode <= 97: // Keypad 1-9
+		return "Numpad" + strconv.Itoa(scancode-88)
+	}
+	return sdlScancodeNames[scancode]
+}
+
+// SDL game controller axes used as buttons
+const (
+	sdlAxisTriggerLeft  = 4
+	sdlAxisTriggerRight = 5
+)
+
+// sdlStandardButtons maps each standard layout button (StandardGamepadButtonNames
+// order) to an SDL game controller button. Negative values are trigger axes,
+// encoded as -1-axis.
+var sdlStandardButtons = [17]int{
+	0, 1, 2, 3, // RightBottom (A), RightRight (B), RightLeft (X), RightTop (Y)
+	9, 10, -1 - sdlAxisTriggerLeft, -1 - sdlAxisTriggerRight, // Shoulders and triggers
+	4, 6, 7, 8, // Back, Start, left and right stick
+	11, 12, 13, 14, // D-pad up, down, left, right
+	5, // Guide
+}
+
+// sdlStandardButtonName returns the standard layout name of an SDL game
+// controller button, or "" if it has none
+func sdlStandardButtonName(button int) string {
+	for i, sdlButton := range sdlStandardButtons {
+		if sdlButton == button {
+			return StandardGamepadButtonNames[i]
+		}
+	}
+	return ""
+}
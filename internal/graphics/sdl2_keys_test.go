@@ -0,0 +1,55 @@
+package graphics
+
+import "testing"
+
+func TestSDLScancodeName(t *testing.T) {
+	tests := map[int]string{
+		4:   "A",
+		29:  "Z",
+		30:  "1",
+		39:  "0",
+		58:  "F1",
+		69:  "F12",
+		82:  "ArrowUp",
+		89:  "Numpad1",
+		88:  "NumpadEnter",
+		229: "ShiftRight",
+		0:   "",
+	}
+	for scancode, want := range tests {
+		if got := sdlScancodeName(scancode); got != want {
+			t.Errorf("sdlScancodeName(%d) = %q, expected %q", scancode, got, want)
+		}
+	}
+
+	// Every name must resolve through the same path as the bindings
+	for _, names := range DefaultInputBindings().Keyboard[0] {
+		for _, name := range names {
+			found := false
+			for scancode := 0; scancode < 256; scancode++ {
+				if bindingKeyID(sdlScancodeName(scancode)) == bindingKeyID(name) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("Default binding %q has no SDL scancode", name)
+			}
+		}
+	}
+}
+
+func TestSDLStandardButtons(t *testing.T) {
+	if len(sdlStandardButtons) != len(StandardGamepadButtonNames) {
+		t.Fatalf("Expected %d standard buttons, got %d", len(StandardGamepadButtonNames), len(sdlStandardButtons))
+	}
+	if name := sdlStandardButtonName(0); name != "RightBottom" {
+		t.Errorf("Expected SDL A to be RightBottom, got %s", name)
+	}
+	if name := sdlStandardButtonName(6); name != "CenterRight" {
+		t.Errorf("Expected SDL Start to be CenterRight, got %s", name)
+	}
+	if name := sdlStandardButtonName(11); name != "LeftTop" {
+		t.Errorf("Expected SDL D-pad up to be LeftTop, got %s", name)
+	}
+}
@@ -1,19 +1,56 @@
 package graphics
 
-import "fmt"
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Escape sequences used by the terminal window
+const (
+	terminalEnterScreen = "\x1b[?1049h" + // Alternate screen
+		"\x1b[?25l" + // Hide cursor
+		"\x1b[?7l" + // No auto-wrap, so the last column never scrolls
+		"\x1b[>11u" + // Kitty keyboard protocol: disambiguate, report releases, all keys as codes
+		"\x1b[2J"
+	terminalLeaveScreen = "\x1b[<u" + // Pop kitty keyboard flags
+		"\x1b[0m\x1b[?7h\x1b[?25h" +
+		"\x1b[?1049l"
+)
 
 // TerminalBackend implements the Backend interface for terminal-based rendering
 type TerminalBackend struct {
 	initialized bool
 	config      Config
+	window      *TerminalWindow // The one window, which owns the terminal
 }
 
-// TerminalWindow implements the Window interface for terminal rendering
+// TerminalWindow implements the Window interface for terminal rendering. The
+// picture is drawn with 24-bit colored half blocks (or braille patterns on
+// small terminals) and only changed cells are sent, which keeps it playable
+// over SSH. Keys are read from stdin in raw mode.
 type TerminalWindow struct {
-	title       string
-	width       int
-	height      int
-	running     bool
+	title   string
+	width   int
+	height  int
+	running bool
+
+	in       *os.File
+	out      *os.File
+	state    *terminalState // Original terminal mode, nil if raw mode is unavailable
+	renderer *terminalRenderer
+	input    chan []byte
+	pending  []byte // Incomplete escape sequence from the last read
+
+	keys        terminalKeyTracker
+	keyBindings map[string][]Button // Terminal key ID to bound buttons
+	capture     func(input CapturedInput)
+	events      []InputEvent // Events queued outside PollEvents
+	closed      bool
 }
 
 // NewTerminalBackend creates a new terminal graphics backend
@@ -38,13 +75,34 @@ func (b *TerminalBackend) CreateWindow(title string, width, height int) (Window,
 	if !b.initialized {
 		return nil, fmt.Errorf("backend not initialized")
 	}
+	if b.window != nil {
+		return nil, fmt.Errorf("the terminal backend has a single window")
+	}
+
+	w := &TerminalWindow{
+		title:    title,
+		width:    width,
+		height:   height,
+		running:  true,
+		in:       os.Stdin,
+		out:      os.Stdout,
+		renderer: newTerminalRenderer(b.config.TerminalMode, terminalTrueColor()),
+		input:    make(chan []byte, 64),
+	}
+	w.SetInputBindings(DefaultInputBindings())
+
+	state, err := enableRawMode(w.in, w.out)
+	if err != nil {
+		log.Printf("[Terminal] Keyboard input unavailable: %v", err)
+	} else {
+		w.state = state
+		go w.readInput()
+	}
+	w.out.WriteString(terminalEnterScreen)
+	w.SetTitle(title)
 
-	return &TerminalWindow{
-		title:   title,
-		width:   width,
-		height:  height,
-		running: true,
-	}, nil
+	b.window = w
+	return w, nil
 }
 
 // Cleanup releases all terminal resources
@@ -63,12 +121,24 @@ func (b *TerminalBackend) GetName() string {
 	return "Terminal"
 }
 
+// terminalTrueColor reports whether the terminal advertises 24-bit colors.
+// Windows consoles with escape sequence support always have them.
+func terminalTrueColor() bool {
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	colorTerm := strings.ToLower(os.Getenv("COLORTERM"))
+	term := strings.ToLower(os.Getenv("TERM"))
+	return colorTerm == "truecolor" || colorTerm == "24bit" ||
+		strings.Contains(term, "truecolor") || strings.Contains(term, "direct")
+}
+
 // TerminalWindow implementation
 
 // SetTitle sets the window title (for terminal title)
 func (w *TerminalWindow) SetTitle(title string) {
 	w.title = title
-	fmt.Printf("\033]0;%s\007", title) // Set terminal title
+	fmt.Fprintf(w.out, "\033]0;%s\007", title) // Set terminal title
 }
 
 // GetSize returns window dimensions
@@ -86,37 +156,156 @@ func (w *TerminalWindow) SwapBuffers() {
 	// No-op for terminal
 }
 
-// PollEvents returns empty events list (no input handling for now)
-func (w *TerminalWindow) PollEvents() []InputEvent {
-	return nil
+// readInput forwards raw stdin reads to PollEvents
+func (w *TerminalWindow) readInput() {
+	buf := make([]byte, 256)
+	for {
+		n, err := w.in.Read(buf)
+		if err != nil {
+			return
+		}
+		w.input <- append([]byte(nil), buf[:n]...)
+	}
 }
 
-// RenderFrame renders the frame as ASCII art to terminal
-func (w *TerminalWindow) RenderFrame(frameBuffer [256 * 240]uint32) error {
-	// Simple ASCII art rendering (very basic)
-	// This is just a placeholder - real implementation would be more sophisticated
-	
-	// Clear screen
-	fmt.Print("\033[2J\033[H")
-	
-	// Render every 8th pixel as a character
-	for y := 0; y < 240; y += 8 {
-		for x := 0; x < 256; x += 4 {
-			pixel := frameBuffer[y*256+x]
-			if pixel == 0x000000 {
-				fmt.Print(" ")
-			} else {
-				fmt.Print("█")
+// PollEvents turns the keys typed since the last call into key and button
+// events, appended to events. Ctrl+C closes the window.
+func (w *TerminalWindow) PollEvents(events []InputEvent) []InputEvent {
+	events = append(events, w.events...)
+	w.events = w.events[:0]
+
+	data := w.pending
+	for more := true; more; {
+		select {
+		case chunk := <-w.input:
+			data = append(data, chunk...)
+		default:
+			more = false
+		}
+	}
+	keys, rest := parseTerminalInput(data)
+	w.pending = append(w.pending[:0], rest...)
+
+	now := time.Now()
+	for _, key := range keys {
+		if key.name == "C" && key.modifiers&ModifierCtrl != 0 {
+			w.running = false
+			events = append(events, InputEvent{Type: InputEventTypeQuit, Pressed: true})
+			continue
+		}
+		if key.release {
+			w.keys.precise = true
+			if modifiers, held := w.keys.release(key.name); held {
+				events = w.appendKeyEvents(events, key.name, modifiers, false)
 			}
+			continue
+		}
+		if w.capture != nil {
+			callback := w.capture
+			w.capture = nil
+			callback(CapturedInput{Name: key.name})
+			continue
+		}
+		if w.keys.press(key.name, key.modifiers, now) {
+			events = w.appendKeyEvents(events, key.name, key.modifiers, true)
+		}
+	}
+
+	for _, name := range w.keys.expired(now) {
+		if modifiers, held := w.keys.release(name); held {
+			events = w.appendKeyEvents(events, name, modifiers, false)
+		}
+	}
+	return events
+}
+
+// appendKeyEvents reports a key as button events if it is bound to controller
+// buttons, or as a key event otherwise
+func (w *TerminalWindow) appendKeyEvents(events []InputEvent, name string, modifiers ModifierKey, pressed bool) []InputEvent {
+	if buttons, bound := w.keyBindings[bindingKeyID(name)]; bound {
+		for _, button := range buttons {
+			events = append(events, InputEvent{Type: InputEventTypeButton, Button: button, Pressed: pressed})
+		}
+		return events
+	}
+	if key, ok := keyCodesByName[name]; ok {
+		events = append(events, InputEvent{Type: InputEventTypeKey, Key: key, Pressed: pressed, Modifiers: modifiers})
+	}
+	return events
+}
+
+// SetInputBindings replaces the active key bindings. Gamepad bindings do not
+// apply to the terminal.
+func (w *TerminalWindow) SetInputBindings(bindings InputBindings) {
+	keyBindings := make(map[string][]Button)
+	for player := 0; player < MaxGamepadPlayers; player++ {
+		for i, names := range bindings.Keyboard[player] {
+			button := PlayerButton(player, ControllerButtons[i])
+			for _, name := range names {
+				id := bindingKeyID(name)
+				keyBindings[id] = append(keyBindings[id], button)
+			}
+		}
+	}
+
+	// Release buttons held through keys whose binding is going away
+	for name := range w.keys.held {
+		w.events = w.appendKeyEvents(w.events, name, 0, false)
+		w.keys.release(name)
+	}
+	w.keyBindings = keyBindings
+}
+
+// CaptureNextInput reports the next key pressed to callback instead of
+// processing it. The callback runs from PollEvents.
+func (w *TerminalWindow) CaptureNextInput(callback func(input CapturedInput)) {
+	w.capture = callback
+}
+
+// CancelCapture stops a pending capture
+func (w *TerminalWindow) CancelCapture() {
+	w.capture = nil
+}
+
+// RenderFrame draws the frame, sending only the cells that changed
+func (w *TerminalWindow) RenderFrame(frameBuffer *[256 * 240]uint32) error {
+	cols, rows, err := terminalSize(w.out)
+	if err != nil {
+		cols, rows = terminalEnvSize()
+	}
+	if data := w.renderer.render(frameBuffer, cols, rows); len(data) > 0 {
+		if _, err := w.out.Write(data); err != nil {
+			return fmt.Errorf("failed to write to terminal: %v", err)
 		}
-		fmt.Println()
 	}
-	
 	return nil
 }
 
-// Cleanup releases window resources
+// terminalEnvSize returns the terminal size from $COLUMNS and $LINES, or 80x24
+func terminalEnvSize() (cols, rows int) {
+	cols, rows = 80, 24
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		cols = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		rows = n
+	}
+	return cols, rows
+}
+
+// Cleanup restores the terminal
 func (w *TerminalWindow) Cleanup() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
 	w.running = false
+	w.out.WriteString(terminalLeaveScreen)
+	if w.state != nil {
+		if err := w.state.restore(); err != nil {
+			return fmt.Errorf("failed to restore terminal mode: %v", err)
+		}
+		w.state = nil
+	}
 	return nil
-}
\ No newline at end of file
+}
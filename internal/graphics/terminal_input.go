@@ -0,0 +1,270 @@
+// Package graphics implements keyboard input parsing for the terminal backend.
+package graphics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Terminals only report key presses (and auto-repeats), so without the kitty
+// keyboard protocol a key counts as held until it stops repeating. The first
+// timeout covers the usual auto-repeat delay, the second the repeat interval.
+const (
+	terminalKeyFirstRepeat = 600 * time.Millisecond
+	terminalKeyRepeatGap   = 150 * time.Millisecond
+)
+
+// terminalKey is one key reported by the terminal
+type terminalKey struct {
+	name      string // Binding name: "A", "1", "ArrowUp", "Enter", "F5", "Numpad8", ...
+	modifiers ModifierKey
+	release   bool // Key release (kitty keyboard protocol only)
+}
+
+// terminalSymbolNames maps punctuation to key names
+var terminalSymbolNames = map[rune]string{
+	'-': "Minus", '=': "Equal", ',': "Comma", '.': "Period", '/': "Slash",
+	';': "Semicolon", '\'': "Quote", '[': "BracketLeft", ']': "BracketRight",
+	'\\': "Backslash", '`': "Backquote",
+}
+
+// terminalTildeKeys maps "CSI n ~" sequences to key names
+var terminalTildeKeys = map[int]string{
+	2: "Insert", 3: "Delete", 5: "PageUp", 6: "PageDown",
+	15: "F5", 17: "F6", 18: "F7", 19: "F8", 20: "F9", 21: "F10", 23: "F11", 24: "F12",
+}
+
+// terminalFinalKeys maps CSI and SS3 final bytes to key names
+var terminalFinalKeys = map[byte]string{
+	'A': "ArrowUp", 'B': "ArrowDown", 'C': "ArrowRight", 'D': "ArrowLeft",
+	'H': "Home", 'F': "End", 'P': "F1", 'Q': "F2", 'R': "F3", 'S': "F4",
+}
+
+// parseTerminalInput decodes keys from terminal input. An escape sequence cut
+// off at the end of data is returned as rest, to be completed by the next read.
+// Both legacy xterm sequences and kitty keyboard protocol "CSI u" sequences
+// (which report releases) are understood.
+func parseTerminalInput(data []byte) (keys []terminalKey, rest []byte) {
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b != 0x1B {
+			r, size := utf8.DecodeRune(data[i:])
+			if r == utf8.RuneError && !utf8.FullRune(data[i:]) {
+				return keys, data[i:]
+			}
+			if key, ok := terminalRuneKey(r); ok {
+				keys = append(keys, key)
+			}
+			i += size
+			continue
+		}
+
+		// A lone escape at the end of a read is the Escape key
+		if i+1 == len(data) {
+			keys = append(keys, terminalKey{name: "Escape"})
+			i++
+			continue
+		}
+
+		switch data[i+1] {
+		case '[':
+			end := i + 2
+			for end < len(data) && (data[end] < 0x40 || data[end] > 0x7E) {
+				end++
+			}
+			if end == len(data) {
+				return keys, data[i:]
+			}
+			if key, ok := parseTerminalCSI(string(data[i+2:end]), data[end]); ok {
+				keys = append(keys, key)
+			}
+			i = end + 1
+		case 'O':
+			if i+2 == len(data) {
+				return keys, data[i:]
+			}
+			if name, ok := terminalFinalKeys[data[i+2]]; ok {
+				keys = append(keys, terminalKey{name: name})
+			} else if data[i+2] == 'M' {
+				keys = append(keys, terminalKey{name: "NumpadEnter"})
+			}
+			i += 3
+		case 0x1B:
+			keys = append(keys, terminalKey{name: "Escape"})
+			i++
+		default:
+			// Escape followed by a key is Alt+key
+			r, size := utf8.DecodeRune(data[i+1:])
+			if key, ok := terminalRuneKey(r); ok {
+				key.modifiers |= ModifierAlt
+				keys = append(keys, key)
+			}
+			i += 1 + size
+		}
+	}
+	return keys, nil
+}
+
+// terminalRuneKey decodes a plain character, including control characters
+func terminalRuneKey(r rune) (terminalKey, bool) {
+	switch {
+	case r == '\r' || r == '\n':
+		return terminalKey{name: "Enter"}, true
+	case r == '\t':
+		return terminalKey{name: "Tab"}, true
+	case r == 0x7F || r == 0x08:
+		return terminalKey{name: "Backspace"}, true
+	case r == ' ':
+		return terminalKey{name: "Space"}, true
+	case r >= 1 && r <= 26:
+		return terminalKey{name: string(rune('A' + r - 1)), modifiers: ModifierCtrl}, true
+	case r >= 'a' && r <= 'z':
+		return terminalKey{name: string(r - 'a' + 'A')}, true
+	case r >= 'A' && r <= 'Z':
+		return terminalKey{name: string(r), modifiers: ModifierShift}, true
+	case r >= '0' && r <= '9':
+		return terminalKey{name: string(r)}, true
+	}
+	if name, ok := terminalSymbolNames[r]; ok {
+		return terminalKey{name: name}, true
+	}
+	return terminalKey{}, false
+}
+
+// parseTerminalCSI decodes "CSI params final". The second parameter holds the
+// modifiers (plus one) and, with the kitty protocol, ":event" (3 = release).
+func parseTerminalCSI(params string, final byte) (terminalKey, bool) {
+	fields := strings.Split(params, ";")
+	number := func(field int, sub int) int {
+		if field >= len(fields) {
+			return 0
+		}
+		parts := strings.Split(fields[field], ":")
+		if sub >= len(parts) {
+			return 0
+		}
+		n, _ := strconv.Atoi(parts[sub])
+		return n
+	}
+
+	var key terminalKey
+	if mods := number(1, 0) - 1; mods > 0 {
+		if mods&1 != 0 {
+			key.modifiers |= ModifierShift
+		}
+		if mods&2 != 0 {
+			key.modifiers |= ModifierAlt
+		}
+		if mods&4 != 0 {
+			key.modifiers |= ModifierCtrl
+		}
+		if mods&8 != 0 {
+			key.modifiers |= ModifierSuper
+		}
+	}
+	key.release = number(1, 1) == 3
+
+	switch final {
+	case '~':
+		key.name = terminalTildeKeys[number(0, 0)]
+	case 'u':
+		key.name = terminalCodepointName(number(0, 0))
+	case 'Z':
+		key.name = "Tab"
+		key.modifiers |= ModifierShift
+	default:
+		key.name = terminalFinalKeys[final]
+	}
+	return key, key.name != ""
+}
+
+// terminalCodepointName names a kitty keyboard protocol key code
+func terminalCodepointName(code int) string {
+	switch {
+	case code == 13:
+		return "Enter"
+	case code == 27:
+		return "Escape"
+	case code >= 57399 && code <= 57408:
+		return "Numpad" + strconv.Itoa(code-57399)
+	}
+	switch code {
+	case 57409:
+		return "NumpadDecimal"
+	case 57410:
+		return "NumpadDivide"
+	case 57411:
+		return "NumpadMultiply"
+	case 57412:
+		return "NumpadSubtract"
+	case 57413:
+		return "NumpadAdd"
+	case 57414:
+		return "NumpadEnter"
+	}
+	if code > 0 && code < 0x80 {
+		if key, ok := terminalRuneKey(rune(code)); ok && key.modifiers == 0 {
+			return key.name
+		}
+	}
+	return ""
+}
+
+// terminalHeldKey is a key currently considered held
+type terminalHeldKey struct {
+	last      time.Time
+	repeated  bool
+	modifiers ModifierKey
+}
+
+// terminalKeyTracker turns the press-only key reports of a terminal into
+// press and release transitions
+type terminalKeyTracker struct {
+	held map[string]*terminalHeldKey
+	// Precise is set once the terminal reports releases; timeouts are then unused
+	precise bool
+}
+
+// press records a key report and returns true if the key was not held before
+func (t *terminalKeyTracker) press(name string, modifiers ModifierKey, now time.Time) bool {
+	if t.held == nil {
+		t.held = make(map[string]*terminalHeldKey)
+	}
+	if held, ok := t.held[name]; ok {
+		held.last = now
+		held.repeated = true
+		return false
+	}
+	t.held[name] = &terminalHeldKey{last: now, modifiers: modifiers}
+	return true
+}
+
+// release forgets a held key and returns its modifiers, or false if it was not held
+func (t *terminalKeyTracker) release(name string) (ModifierKey, bool) {
+	held, ok := t.held[name]
+	if !ok {
+		return 0, false
+	}
+	delete(t.held, name)
+	return held.modifiers, true
+}
+
+// expired releases the keys that stopped repeating and returns their names
+func (t *terminalKeyTracker) expired(now time.Time) []string {
+	if t.precise {
+		return nil
+	}
+	var names []string
+	for name, held := range t.held {
+		timeout := terminalKeyFirstRepeat
+		if held.repeated {
+			timeout = terminalKeyRepeatGap
+		}
+		if now.Sub(held.last) > timeout {
+			names = append(names, name)
+		}
+	}
+	return names
+}
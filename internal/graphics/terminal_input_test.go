@@ -0,0 +1,103 @@
+package graphics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTerminalInputLegacy(t *testing.T) {
+	keys, rest := parseTerminalInput([]byte("a\x1b[A\x1bOP\x1b[15;5~\r\x03"))
+	want := []terminalKey{
+		{name: "A"},
+		{name: "ArrowUp"},
+		{name: "F1"},
+		{name: "F5", modifiers: ModifierCtrl},
+		{name: "Enter"},
+		{name: "C", modifiers: ModifierCtrl},
+	}
+	if !reflect.DeepEqual(keys, want) || len(rest) != 0 {
+		t.Errorf("Expected %v, got %v (rest %q)", want, keys, rest)
+	}
+}
+
+func TestParseTerminalInputKitty(t *testing.T) {
+	keys, _ := parseTerminalInput([]byte("\x1b[97u\x1b[97;1:3u\x1b[57407;3u"))
+	want := []terminalKey{
+		{name: "A"},
+		{name: "A", release: true},
+		{name: "Numpad8", modifiers: ModifierAlt},
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("Expected %v, got %v", want, keys)
+	}
+}
+
+func TestParseTerminalInputPartial(t *testing.T) {
+	keys, rest := parseTerminalInput([]byte("z\x1b[1;"))
+	if len(keys) != 1 || keys[0].name != "Z" {
+		t.Errorf("Expected Z before the partial sequence, got %v", keys)
+	}
+	if string(rest) != "\x1b[1;" {
+		t.Fatalf("Expected partial sequence to be kept, got %q", rest)
+	}
+
+	keys, rest = parseTerminalInput(append(rest, "2B"...))
+	if len(keys) != 1 || keys[0].name != "ArrowDown" || keys[0].modifiers != ModifierShift || len(rest) != 0 {
+		t.Errorf("Expected Shift+ArrowDown once completed, got %v (rest %q)", keys, rest)
+	}
+
+	keys, _ = parseTerminalInput([]byte("\x1b"))
+	if len(keys) != 1 || keys[0].name != "Escape" {
+		t.Errorf("Expected lone escape to be the Escape key, got %v", keys)
+	}
+}
+
+func TestTerminalKeyTracker(t *testing.T) {
+	var tracker terminalKeyTracker
+	start := time.Now()
+
+	if !tracker.press("A", 0, start) {
+		t.Fatal("Expected first report to be a press")
+	}
+	if tracker.press("A", 0, start.Add(500*time.Millisecond)) {
+		t.Error("Expected auto-repeat not to be a new press")
+	}
+	if names := tracker.expired(start.Add(600 * time.Millisecond)); len(names) != 0 {
+		t.Errorf("Expected repeating key to stay held, got %v", names)
+	}
+	names := tracker.expired(start.Add(800 * time.Millisecond))
+	if !reflect.DeepEqual(names, []string{"A"}) {
+		t.Errorf("Expected A to expire once it stops repeating, got %v", names)
+	}
+
+	tracker.precise = true
+	if names := tracker.expired(start.Add(time.Hour)); names != nil {
+		t.Errorf("Expected no timeouts once releases are reported, got %v", names)
+	}
+	if _, held := tracker.release("A"); !held {
+		t.Error("Expected A to be held until released")
+	}
+	if _, held := tracker.release("A"); held {
+		t.Error("Expected released key not to be held")
+	}
+}
+
+func TestTerminalPollEventsAppendsToCallerSlice(t *testing.T) {
+	w := &TerminalWindow{input: make(chan []byte, 1)}
+	w.events = append(w.events, InputEvent{Type: InputEventTypeKey, Key: KeyEscape, Pressed: true})
+	w.input <- []byte("\x1b[15~") // F5
+
+	buffer := make([]InputEvent, 0, 8)
+	events := w.PollEvents(buffer)
+	if len(events) != 2 || events[0].Key != KeyEscape || events[1].Key != KeyF5 {
+		t.Fatalf("Expected the queued Escape then F5, got %+v", events)
+	}
+	if &events[0] != &buffer[:1][0] {
+		t.Error("Expected the events in the caller's slice")
+	}
+
+	if events = w.PollEvents(events[:0]); len(events) != 0 {
+		t.Errorf("Expected no events on the next poll, got %+v", events)
+	}
+}
@@ -0,0 +1,307 @@
+// Package graphics implements the character cell renderer of the terminal backend.
+package graphics
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Terminal render modes (Config.TerminalMode)
+const (
+	// TerminalModeAuto uses half blocks, or braille when the terminal is too
+	// small for a recognizable half block picture
+	TerminalModeAuto = "auto"
+	// TerminalModeHalfBlock draws two pixels per cell with "▀" and 24-bit colors
+	TerminalModeHalfBlock = "halfblock"
+	// TerminalModeBraille draws 2x4 dots per cell with braille patterns
+	TerminalModeBraille = "braille"
+)
+
+// TerminalModes lists the accepted terminal render modes
+var TerminalModes = []string{TerminalModeAuto, TerminalModeHalfBlock, TerminalModeBraille}
+
+// terminalFullRedrawFrames forces a complete redraw now and then, repairing
+// anything other output wrote over the picture
+const terminalFullRedrawFrames = 300
+
+// terminalCell is one character cell
+type terminalCell struct {
+	ch rune
+	fg uint32
+	bg uint32
+}
+
+// terminalRenderer converts frames to character cells and encodes the cells
+// that changed since the previous frame as ANSI escape sequences
+type terminalRenderer struct {
+	mode      string
+	trueColor bool // 24-bit colors; otherwise the xterm 256 color palette
+
+	cols, rows int
+	cells      []terminalCell
+	prev       []terminalCell
+	full       bool // Redraw every cell on the next frame
+	frames     int
+	samples    []uint32 // Frame resampled to the cell grid resolution
+	out        bytes.Buffer
+}
+
+func newTerminalRenderer(mode string, trueColor bool) *terminalRenderer {
+	return &terminalRenderer{mode: mode, trueColor: trueColor, full: true}
+}
+
+// cellMode returns the mode used for a terminal size
+func (r *terminalRenderer) cellMode(cols, rows int) string {
+	if r.mode == TerminalModeHalfBlock || r.mode == TerminalModeBraille {
+		return r.mode
+	}
+	// Half blocks need a quarter of the NES resolution to stay readable
+	if cols < 64 || rows*2 < 60 {
+		return TerminalModeBraille
+	}
+	return TerminalModeHalfBlock
+}
+
+// render returns the escape sequences that update a cols x rows terminal to
+// show the frame. The result is only valid until the next call.
+func (r *terminalRenderer) render(frame *[256 * 240]uint32, cols, rows int) []byte {
+	r.out.Reset()
+	if cols <= 0 || rows <= 0 {
+		return nil
+	}
+	if cols != r.cols || rows != r.rows {
+		r.cols, r.rows = cols, rows
+		r.cells = make([]terminalCell, cols*rows)
+		r.prev = make([]terminalCell, cols*rows)
+		r.full = true
+	}
+	r.frames++
+	if r.frames%terminalFullRedrawFrames == 0 {
+		r.full = true
+	}
+
+	if r.cellMode(cols, rows) == TerminalModeBraille {
+		r.resample(frame, cols*2, rows*4, 2, 4)
+		r.brailleCells()
+	} else {
+		r.resample(frame, cols, rows*2, 1, 2)
+		r.halfBlockCells()
+	}
+	r.encode()
+
+	r.prev, r.cells = r.cells, r.prev
+	r.full = false
+	return r.out.Bytes()
+}
+
+// resample scales the frame into a gridW x gridH pixel grid, centered and
+// keeping square pixels. Each grid pixel averages the frame pixels it covers.
+// The picture is aligned to cellW x cellH so cells do not straddle its edge.
+func (r *terminalRenderer) resample(frame *[256 * 240]uint32, gridW, gridH, cellW, cellH int) {
+	if cap(r.samples) < gridW*gridH {
+		r.samples = make([]uint32, gridW*gridH)
+	}
+	r.samples = r.samples[:gridW*gridH]
+	clear(r.samples)
+
+	scale := min(float64(gridW)/256, float64(gridH)/240)
+	width := max(cellW, int(256*scale)/cellW*cellW)
+	height := max(cellH, int(240*scale)/cellH*cellH)
+	left := (gridW - width) / 2 / cellW * cellW
+	top := (gridH - height) / 2 / cellH * cellH
+
+	for y := 0; y < height && top+y < gridH; y++ {
+		sy0 := y * 240 / height
+		sy1 := max(sy0+1, (y+1)*240/height)
+		for x := 0; x < width && left+x < gridW; x++ {
+			sx0 := x * 256 / width
+			sx1 := max(sx0+1, (x+1)*256/width)
+
+			var red, green, blue, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				for _, c := range frame[sy*256+sx0 : sy*256+sx1] {
+					red += c >> 16 & 0xFF
+					green += c >> 8 & 0xFF
+					blue += c & 0xFF
+					n++
+				}
+			}
+			r.samples[(top+y)*gridW+left+x] = red/n<<16 | green/n<<8 | blue/n
+		}
+	}
+}
+
+// halfBlockCells fills the cells with "▀": the foreground is the upper pixel
+// and the background the lower one
+func (r *terminalRenderer) halfBlockCells() {
+	for row := 0; row < r.rows; row++ {
+		for col := 0; col < r.cols; col++ {
+			top := r.samples[row*2*r.cols+col]
+			bottom := r.samples[(row*2+1)*r.cols+col]
+			if top == bottom {
+				r.cells[row*r.cols+col] = terminalCell{ch: ' ', fg: bottom, bg: bottom}
+			} else {
+				r.cells[row*r.cols+col] = terminalCell{ch: '▀', fg: top, bg: bottom}
+			}
+		}
+	}
+}
+
+// brailleDots holds the braille pattern bit of each dot, indexed [y][x]
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// brailleCells fills the cells with braille patterns. Dots brighter than the
+// cell average are set and drawn in the average color of the bright pixels,
+// over the average color of the dark ones.
+func (r *terminalRenderer) brailleCells() {
+	gridW := r.cols * 2
+	for row := 0; row < r.rows; row++ {
+		for col := 0; col < r.cols; col++ {
+			var pixels [8]uint32
+			var luma [8]uint32
+			var total uint32
+			for i := range pixels {
+				pixels[i] = r.samples[(row*4+i/2)*gridW+col*2+i%2]
+				luma[i] = terminalLuma(pixels[i])
+				total += luma[i]
+			}
+
+			var on, off colorSum
+			var ch rune = 0x2800
+			for i, c := range pixels {
+				if luma[i]*8 > total {
+					ch |= brailleDots[i/2][i%2]
+					on.add(c)
+				} else {
+					off.add(c)
+				}
+			}
+			if on.n == 0 || off.n == 0 {
+				avg := off.average()
+				if off.n == 0 {
+					avg = on.average()
+				}
+				r.cells[row*r.cols+col] = terminalCell{ch: ' ', fg: avg, bg: avg}
+				continue
+			}
+			r.cells[row*r.cols+col] = terminalCell{ch: ch, fg: on.average(), bg: off.average()}
+		}
+	}
+}
+
+// colorSum accumulates colors for averaging
+type colorSum struct {
+	r, g, b, n uint32
+}
+
+func (s *colorSum) add(c uint32) {
+	s.r += c >> 16 & 0xFF
+	s.g += c >> 8 & 0xFF
+	s.b += c & 0xFF
+	s.n++
+}
+
+func (s *colorSum) average() uint32 {
+	if s.n == 0 {
+		return 0
+	}
+	return s.r/s.n<<16 | s.g/s.n<<8 | s.b/s.n
+}
+
+// terminalLuma returns the approximate brightness of a color (0-255)
+func terminalLuma(c uint32) uint32 {
+	return (299*(c>>16&0xFF) + 587*(c>>8&0xFF) + 114*(c&0xFF)) / 1000
+}
+
+// encode writes cursor moves, colors and characters for the changed cells
+func (r *terminalRenderer) encode() {
+	curRow, curCol := -1, -1
+	var fg, bg uint32
+	colorsSet := false
+	for row := 0; row < r.rows; row++ {
+		for col := 0; col < r.cols; col++ {
+			i := row*r.cols + col
+			cell := r.cells[i]
+			if !r.full && cell == r.prev[i] {
+				continue
+			}
+			if row != curRow || col != curCol {
+				r.out.WriteString("\x1b[")
+				r.out.WriteString(strconv.Itoa(row + 1))
+				r.out.WriteByte(';')
+				r.out.WriteString(strconv.Itoa(col + 1))
+				r.out.WriteByte('H')
+			}
+			if !colorsSet || cell.bg != bg {
+				r.writeColor(48, cell.bg)
+				bg = cell.bg
+			}
+			if cell.ch != ' ' && (!colorsSet || cell.fg != fg) {
+				r.writeColor(38, cell.fg)
+				fg = cell.fg
+			}
+			colorsSet = true
+			r.out.WriteRune(cell.ch)
+			curRow, curCol = row, col+1
+		}
+	}
+	if colorsSet {
+		r.out.WriteString("\x1b[0m")
+	}
+}
+
+// writeColor writes an SGR color: 38 selects the foreground, 48 the background
+func (r *terminalRenderer) writeColor(layer int, c uint32) {
+	r.out.WriteString("\x1b[")
+	r.out.WriteString(strconv.Itoa(layer))
+	if r.trueColor {
+		r.out.WriteString(";2;")
+		r.out.WriteString(strconv.Itoa(int(c >> 16 & 0xFF)))
+		r.out.WriteByte(';')
+		r.out.WriteString(strconv.Itoa(int(c >> 8 & 0xFF)))
+		r.out.WriteByte(';')
+		r.out.WriteString(strconv.Itoa(int(c & 0xFF)))
+	} else {
+		r.out.WriteString(";5;")
+		r.out.WriteString(strconv.Itoa(xterm256Color(c)))
+	}
+	r.out.WriteByte('m')
+}
+
+// xterm256Color returns the nearest color of the xterm 256 color palette,
+// choosing between the 6x6x6 color cube and the gray ramp
+func xterm256Color(c uint32) int {
+	red, green, blue := int(c>>16&0xFF), int(c>>8&0xFF), int(c&0xFF)
+	cubeIndex := func(v int) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (v - 35) / 40
+	}
+	cubeLevel := func(i int) int {
+		if i == 0 {
+			return 0
+		}
+		return 55 + i*40
+	}
+	ri, gi, bi := cubeIndex(red), cubeIndex(green), cubeIndex(blue)
+	cr, cg, cb := cubeLevel(ri), cubeLevel(gi), cubeLevel(bi)
+
+	average := (red + green + blue) / 3
+	grayIndex := 23
+	if average < 238 {
+		grayIndex = max(0, (average-3)/10)
+	}
+	gray := 8 + grayIndex*10
+
+	distance := func(r2, g2, b2 int) int {
+		return (red-r2)*(red-r2) + (green-g2)*(green-g2) + (blue-b2)*(blue-b2)
+	}
+	if distance(gray, gray, gray) < distance(cr, cg, cb) {
+		return 232 + grayIndex
+	}
+	return 16 + 36*ri + 6*gi + bi
+}
@@ -0,0 +1,92 @@
+package graphics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTerminalRendererHalfBlock(t *testing.T) {
+	var frame [256 * 240]uint32
+	for y := 0; y < 240; y++ {
+		for x := 0; x < 256; x++ {
+			if y < 120 {
+				frame[y*256+x] = 0xFF0000
+			} else {
+				frame[y*256+x] = 0x0000FF
+			}
+		}
+	}
+
+	r := newTerminalRenderer(TerminalModeHalfBlock, true)
+	out := r.render(&frame, 128, 60)
+	if !bytes.Contains(out, []byte("\x1b[48;2;255;0;0m")) || !bytes.Contains(out, []byte("\x1b[48;2;0;0;255m")) {
+		t.Error("Expected truecolor backgrounds for both halves")
+	}
+	if r.prev[0] != (terminalCell{ch: ' ', fg: 0xFF0000, bg: 0xFF0000}) {
+		t.Errorf("Expected solid red top-left cell, got %+v", r.prev[0])
+	}
+
+	if out := r.render(&frame, 128, 60); len(out) != 0 {
+		t.Errorf("Expected unchanged frame to send nothing, got %d bytes", len(out))
+	}
+
+	frame[0] = 0xFFFFFF
+	out = r.render(&frame, 128, 60)
+	if len(out) == 0 || len(out) > 64 {
+		t.Errorf("Expected a small update for one changed pixel, got %q", out)
+	}
+
+	if out := r.render(&frame, 100, 40); !bytes.Contains(out, []byte("\x1b[40;1H")) {
+		t.Error("Expected a full redraw after a resize")
+	}
+}
+
+func TestTerminalRendererAutoMode(t *testing.T) {
+	r := newTerminalRenderer(TerminalModeAuto, true)
+	if r.cellMode(40, 20) != TerminalModeBraille {
+		t.Error("Expected braille on a small terminal")
+	}
+	if r.cellMode(160, 50) != TerminalModeHalfBlock {
+		t.Error("Expected half blocks on a large terminal")
+	}
+
+	var frame [256 * 240]uint32
+	for i := range frame {
+		if i%2 == 0 {
+			frame[i] = 0xFFFFFF
+		}
+	}
+	r.render(&frame, 40, 20)
+	braille := 0
+	for _, cell := range r.prev {
+		if cell.ch >= 0x2800 && cell.ch <= 0x28FF {
+			braille++
+		}
+	}
+	if braille == 0 {
+		t.Error("Expected braille patterns for a striped frame")
+	}
+}
+
+func TestXterm256Color(t *testing.T) {
+	tests := []struct {
+		color uint32
+		want  int
+	}{
+		{0x000000, 16},
+		{0xFF0000, 196},
+		{0xFFFFFF, 231},
+		{0x808080, 244},
+	}
+	for _, test := range tests {
+		if got := xterm256Color(test.color); got != test.want {
+			t.Errorf("xterm256Color(%06X) = %d, expected %d", test.color, got, test.want)
+		}
+	}
+
+	r := newTerminalRenderer(TerminalModeHalfBlock, false)
+	var frame [256 * 240]uint32
+	if out := r.render(&frame, 80, 30); !bytes.Contains(out, []byte("\x1b[48;5;16m")) {
+		t.Error("Expected 256 color sequences without truecolor")
+	}
+}
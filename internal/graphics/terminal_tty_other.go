@@ -0,0 +1,25 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd || windows)
+
+package graphics
+
+import (
+	"errors"
+	"os"
+)
+
+// terminalState is unused on platforms without raw terminal support
+type terminalState struct{}
+
+// enableRawMode is not supported on this platform; input stays line buffered
+func enableRawMode(in, out *os.File) (*terminalState, error) {
+	return nil, errors.New("raw terminal input is not supported on this platform")
+}
+
+func (s *terminalState) restore() error {
+	return nil
+}
+
+// terminalSize is not supported on this platform
+func terminalSize(out *os.File) (cols, rows int, err error) {
+	return 0, 0, errors.New("terminal size is not available on this platform")
+}
@@ -0,0 +1,53 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package graphics
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminalState is the terminal mode to restore on exit
+type terminalState struct {
+	fd      int
+	termios unix.Termios
+}
+
+// enableRawMode switches the input terminal to unbuffered input without echo
+// or signal keys. Output processing stays on so stray newlines still work.
+func enableRawMode(in, out *os.File) (*terminalState, error) {
+	fd := int(in.Fd())
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, fmt.Errorf("stdin is not a terminal: %v", err)
+	}
+	state := &terminalState{fd: fd, termios: *termios}
+
+	raw := *termios
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, fmt.Errorf("failed to set raw mode: %v", err)
+	}
+	return state, nil
+}
+
+// restore puts the terminal back into its original mode
+func (s *terminalState) restore() error {
+	return unix.IoctlSetTermios(s.fd, ioctlSetTermios, &s.termios)
+}
+
+// terminalSize returns the size of the output terminal in cells
+func terminalSize(out *os.File) (cols, rows int, err error) {
+	ws, err := unix.IoctlGetWinsize(int(out.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(ws.Col), int(ws.Row), nil
+}
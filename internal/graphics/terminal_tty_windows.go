@@ -0,0 +1,56 @@
+package graphics
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// terminalState is the console mode to restore on exit
+type terminalState struct {
+	in, out         windows.Handle
+	inMode, outMode uint32
+}
+
+// enableRawMode switches the console to unbuffered virtual terminal input
+// without echo, and enables escape sequence processing on the output
+func enableRawMode(in, out *os.File) (*terminalState, error) {
+	state := &terminalState{in: windows.Handle(in.Fd()), out: windows.Handle(out.Fd())}
+	if err := windows.GetConsoleMode(state.in, &state.inMode); err != nil {
+		return nil, fmt.Errorf("stdin is not a console: %v", err)
+	}
+	if err := windows.GetConsoleMode(state.out, &state.outMode); err != nil {
+		return nil, fmt.Errorf("stdout is not a console: %v", err)
+	}
+
+	inMode := state.inMode&^(windows.ENABLE_ECHO_INPUT|windows.ENABLE_LINE_INPUT|windows.ENABLE_PROCESSED_INPUT) |
+		windows.ENABLE_VIRTUAL_TERMINAL_INPUT
+	if err := windows.SetConsoleMode(state.in, inMode); err != nil {
+		return nil, fmt.Errorf("failed to set raw mode: %v", err)
+	}
+	outMode := state.outMode | windows.ENABLE_PROCESSED_OUTPUT | windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING
+	if err := windows.SetConsoleMode(state.out, outMode); err != nil {
+		windows.SetConsoleMode(state.in, state.inMode)
+		return nil, fmt.Errorf("console does not support escape sequences: %v", err)
+	}
+	return state, nil
+}
+
+// restore puts the console back into its original modes
+func (s *terminalState) restore() error {
+	errIn := windows.SetConsoleMode(s.in, s.inMode)
+	if err := windows.SetConsoleMode(s.out, s.outMode); err != nil {
+		return err
+	}
+	return errIn
+}
+
+// terminalSize returns the size of the console window in cells
+func terminalSize(out *os.File) (cols, rows int, err error) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(out.Fd()), &info); err != nil {
+		return 0, 0, err
+	}
+	return int(info.Window.Right-info.Window.Left) + 1, int(info.Window.Bottom-info.Window.Top) + 1, nil
+}
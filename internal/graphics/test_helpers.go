@@ -7,10 +7,10 @@ package graphics
 
 // GetFrameBufferForTesting returns the internal frame buffer for testing purposes
 func (w *EbitengineWindow) GetFrameBufferForTesting() [256 * 240]uint32 {
-	if w.game == nil {
+	if w.game == nil || w.game.frameBuffer == nil {
 		return [256 * 240]uint32{}
 	}
-	return w.game.frameBuffer
+	return *w.game.frameBuffer
 }
 
 // GetGameForTesting returns the internal game instance for testing purposes
@@ -0,0 +1,163 @@
+// Package graphics provides pixel-art upscalers used by the video processor.
+package graphics
+
+import "fmt"
+
+// Upscaler names accepted by VideoProcessor.SetUpscaler
+const (
+	UpscalerNone    = "none"
+	UpscalerScale2x = "scale2x"
+	UpscalerScale3x = "scale3x"
+	UpscalerHQ2x    = "hq2x"
+	UpscalerXBRZ2x  = "xbrz2x"
+	UpscalerXBRZ3x  = "xbrz3x"
+)
+
+// Upscalers lists the available upscalers in the order they are cycled through
+var Upscalers = []string{UpscalerNone, UpscalerScale2x, UpscalerScale3x, UpscalerHQ2x, UpscalerXBRZ2x, UpscalerXBRZ3x}
+
+// ScaledFrameRenderer is implemented by windows that can display frames larger
+// than 256x240, such as the output of an upscaler
+type ScaledFrameRenderer interface {
+	// RenderScaledFrame displays a width x height 0xRRGGBB frame in place of the NES picture
+	RenderScaledFrame(pixels []uint32, width, height int) error
+}
+
+// upscaler scales a frame by a fixed integer factor
+type upscaler interface {
+	// factor returns the scale factor
+	factor() int
+	// upscale writes the scaled frame to dst, which holds width*height*factor² pixels
+	upscale(src []uint32, width, height int, dst []uint32)
+}
+
+// newUpscaler returns the upscaler with the given name, or nil for "none"
+func newUpscaler(name string) (upscaler, error) {
+	switch name {
+	case "", UpscalerNone:
+		return nil, nil
+	case UpscalerScale2x:
+		return scale2x{}, nil
+	case UpscalerScale3x:
+		return scale3x{}, nil
+	case UpscalerHQ2x:
+		return &hq2xScaler{}, nil
+	case UpscalerXBRZ2x:
+		return &xbrzScaler{scale: 2}, nil
+	case UpscalerXBRZ3x:
+		return &xbrzScaler{scale: 3}, nil
+	default:
+		return nil, fmt.Errorf("unknown upscaler: %s", name)
+	}
+}
+
+// pixelAt returns the source pixel at (x, y), clamping coordinates to the frame edges
+func pixelAt(src []uint32, width, height, x, y int) uint32 {
+	if x < 0 {
+		x = 0
+	} else if x >= width {
+		x = width - 1
+	}
+	if y < 0 {
+		y = 0
+	} else if y >= height {
+		y = height - 1
+	}
+	return src[y*width+x]
+}
+
+// scale2x implements the Scale2x (AdvMAME2x) edge-directed upscaler
+type scale2x struct{}
+
+func (scale2x) factor() int { return 2 }
+
+func (scale2x) upscale(src []uint32, width, height int, dst []uint32) {
+	outWidth := width * 2
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			b := pixelAt(src, width, height, x, y-1)
+			d := pixelAt(src, width, height, x-1, y)
+			e := src[y*width+x]
+			f := pixelAt(src, width, height, x+1, y)
+			h := pixelAt(src, width, height, x, y+1)
+
+			e0, e1, e2, e3 := e, e, e, e
+			if b != h && d != f {
+				if d == b {
+					e0 = d
+				}
+				if b == f {
+					e1 = f
+				}
+				if d == h {
+					e2 = d
+				}
+				if h == f {
+					e3 = f
+				}
+			}
+
+			out := y*2*outWidth + x*2
+			dst[out], dst[out+1] = e0, e1
+			dst[out+outWidth], dst[out+outWidth+1] = e2, e3
+		}
+	}
+}
+
+// scale3x implements the Scale3x (AdvMAME3x) edge-directed upscaler
+type scale3x struct{}
+
+func (scale3x) factor() int { return 3 }
+
+func (scale3x) upscale(src []uint32, width, height int, dst []uint32) {
+	outWidth := width * 3
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			a := pixelAt(src, width, height, x-1, y-1)
+			b := pixelAt(src, width, height, x, y-1)
+			c := pixelAt(src, width, height, x+1, y-1)
+			d := pixelAt(src, width, height, x-1, y)
+			e := src[y*width+x]
+			f := pixelAt(src, width, height, x+1, y)
+			g := pixelAt(src, width, height, x-1, y+1)
+			h := pixelAt(src, width, height, x, y+1)
+			i := pixelAt(src, width, height, x+1, y+1)
+
+			var block [9]uint32
+			for n := range block {
+				block[n] = e
+			}
+			if b != h && d != f {
+				if d == b {
+					block[0] = d
+				}
+				if (d == b && e != c) || (b == f && e != a) {
+					block[1] = b
+				}
+				if b == f {
+					block[2] = f
+				}
+				if (d == b && e != g) || (d == h && e != a) {
+					block[3] = d
+				}
+				if (b == f && e != i) || (h == f && e != c) {
+					block[5] = f
+				}
+				if d == h {
+					block[6] = d
+				}
+				if (d == h && e != i) || (h == f && e != g) {
+					block[7] = h
+				}
+				if h == f {
+					block[8] = f
+				}
+			}
+
+			out := y*3*outWidth + x*3
+			for row := 0; row < 3; row++ {
+				copy(dst[out+row*outWidth:out+row*outWidth+3], block[row*3:row*3+3])
+			}
+		}
+	}
+}
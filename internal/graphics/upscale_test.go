@@ -0,0 +1,143 @@
+package graphics
+
+import "testing"
+
+func TestScale2x_SmoothsDiagonal(t *testing.T) {
+	const w, k = 0xFFFFFF, 0x000000
+	// A black diagonal edge: the top-left pixel of the white center pixel's
+	// block takes the color of its matching up and left neighbours
+	src := []uint32{
+		k, k, w,
+		k, w, w,
+		w, w, w,
+	}
+	vp := NewVideoProcessor(1, 1, 1)
+	if err := vp.SetUpscaler(UpscalerScale2x); err != nil {
+		t.Fatalf("SetUpscaler failed: %v", err)
+	}
+	out, width, height := vp.UpscaleFrame(src, 3, 3)
+	if width != 6 || height != 6 {
+		t.Fatalf("Expected 6x6 output, got %dx%d", width, height)
+	}
+	// Center pixel block starts at (2, 2)
+	if out[2*6+2] != k {
+		t.Errorf("Expected top-left of the center block to be black, got %06X", out[2*6+2])
+	}
+	if out[3*6+3] != w {
+		t.Errorf("Expected bottom-right of the center block to stay white, got %06X", out[3*6+3])
+	}
+}
+
+func TestUpscalers_UniformFrameUnchanged(t *testing.T) {
+	src := make([]uint32, 16*16)
+	for i := range src {
+		src[i] = 0x3C7CFC
+	}
+	vp := NewVideoProcessor(1, 1, 1)
+	for _, name := range Upscalers {
+		if err := vp.SetUpscaler(name); err != nil {
+			t.Fatalf("SetUpscaler(%s) failed: %v", name, err)
+		}
+		out, width, height := vp.UpscaleFrame(src, 16, 16)
+		if len(out) != width*height {
+			t.Fatalf("%s: buffer size %d doesn't match %dx%d", name, len(out), width, height)
+		}
+		for i, pixel := range out {
+			if pixel != 0x3C7CFC {
+				t.Fatalf("%s: pixel %d changed to %06X", name, i, pixel)
+			}
+		}
+	}
+}
+
+func TestXBRZ_BlendsDiagonalEdge(t *testing.T) {
+	// White triangle below a diagonal on black
+	const size = 8
+	src := make([]uint32, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if x <= y {
+				src[y*size+x] = 0xFFFFFF
+			}
+		}
+	}
+
+	for _, name := range []string{UpscalerXBRZ2x, UpscalerXBRZ3x} {
+		vp := NewVideoProcessor(1, 1, 1)
+		vp.SetUpscaler(name)
+		out, width, _ := vp.UpscaleFrame(src, size, size)
+
+		blended := 0
+		for _, pixel := range out {
+			if pixel != 0xFFFFFF && pixel != 0x000000 {
+				blended++
+			}
+		}
+		if blended == 0 {
+			t.Errorf("%s: expected anti-aliased pixels along the diagonal", name)
+		}
+		// Far from the edge the colors are untouched
+		if out[(width-1)*width] != 0xFFFFFF || out[width-1] != 0x000000 {
+			t.Errorf("%s: expected corners away from the edge to keep their color", name)
+		}
+	}
+}
+
+func TestHQ2x_BlendsDiagonalEdge(t *testing.T) {
+	const w, k = 0xFFFFFF, 0x000000
+	// The same diagonal edge as above: the white center pixel's top-left
+	// output pixel blends toward the black across the edge, its bottom-right
+	// one stays white
+	src := []uint32{
+		k, k, w,
+		k, w, w,
+		w, w, w,
+	}
+	vp := NewVideoProcessor(1, 1, 1)
+	if err := vp.SetUpscaler(UpscalerHQ2x); err != nil {
+		t.Fatalf("SetUpscaler failed: %v", err)
+	}
+	out, width, height := vp.UpscaleFrame(src, 3, 3)
+	if width != 6 || height != 6 {
+		t.Fatalf("Expected 6x6 output, got %dx%d", width, height)
+	}
+	if topLeft := out[2*6+2]; topLeft == w || topLeft == k {
+		t.Errorf("Expected top-left of the center block to be blended, got %06X", topLeft)
+	}
+	if out[3*6+3] != w {
+		t.Errorf("Expected bottom-right of the center block to stay white, got %06X", out[3*6+3])
+	}
+}
+
+func TestHQ2x_KeepsLonePixel(t *testing.T) {
+	const w, k = 0xFFFFFF, 0x000000
+	src := []uint32{
+		k, k, k,
+		k, w, k,
+		k, k, k,
+	}
+	vp := NewVideoProcessor(1, 1, 1)
+	vp.SetUpscaler(UpscalerHQ2x)
+	out, _, _ := vp.UpscaleFrame(src, 3, 3)
+
+	// Each output pixel of the white dot is rounded off, but stays mostly white
+	for _, i := range []int{2*6 + 2, 2*6 + 3, 3*6 + 2, 3*6 + 3} {
+		if out[i] == w || out[i]&0xFF < 0xC0 {
+			t.Errorf("Expected pixel %d of the dot to stay near white, got %06X", i, out[i])
+		}
+	}
+	// The black around it is untouched
+	if out[0] != k || out[5] != k || out[30] != k || out[35] != k {
+		t.Error("Expected the black around the dot to stay black")
+	}
+}
+
+func TestVideoProcessor_UnknownUpscaler(t *testing.T) {
+	vp := NewVideoProcessor(1, 1, 1)
+	if err := vp.SetUpscaler("hq9x"); err == nil {
+		t.Error("Expected an error for an unknown upscaler")
+	}
+	if vp.GetUpscaler() != UpscalerNone {
+		t.Errorf("Expected upscaler to stay %q, got %q", UpscalerNone, vp.GetUpscaler())
+	}
+}
@@ -9,6 +9,18 @@ type VideoProcessor struct {
 	brightness float32
 	contrast   float32
 	saturation float32
+	adjusted   []uint32 // Reused output of the color adjustments
+
+	// Upscaling stage (nil when disabled)
+	upscaler     upscaler
+	upscalerName string
+	scaled       []uint32
+
+	// Interframe blending stage (nil when disabled)
+	blender *frameBlender
+
+	// Color vision deficiency filter (nil when disabled)
+	colorFilter *colorFilter
 }
 
 // NewVideoProcessor creates a new video processor
@@ -20,14 +32,28 @@ func NewVideoProcessor(brightness, contrast, saturation float32) *VideoProcessor
 	}
 }
 
-// ProcessFrame applies video effects to a frame buffer
+// ProcessFrame applies video effects to a frame buffer: the color adjustments,
+// then the color filter. The frame returned may be the one passed in or a
+// buffer the processor reuses for the next frame.
 func (vp *VideoProcessor) ProcessFrame(frameBuffer []uint32) []uint32 {
+	processed := vp.adjustColors(frameBuffer)
+	if vp.colorFilter != nil {
+		processed = vp.colorFilter.apply(processed)
+	}
+	return processed
+}
+
+// adjustColors applies brightness, contrast and saturation
+func (vp *VideoProcessor) adjustColors(frameBuffer []uint32) []uint32 {
 	// If all values are at default (1.0), no processing needed
 	if vp.brightness == 1.0 && vp.contrast == 1.0 && vp.saturation == 1.0 {
 		return frameBuffer
 	}
 
-	processed := make([]uint32, len(frameBuffer))
+	if len(vp.adjusted) != len(frameBuffer) {
+		vp.adjusted = make([]uint32, len(frameBuffer))
+	}
+	processed := vp.adjusted
 	
 	for i, pixel := range frameBuffer {
 		// Extract RGB components
@@ -171,4 +197,44 @@ func (vp *VideoProcessor) SetContrast(contrast float32) {
 // SetSaturation updates the saturation value  
 func (vp *VideoProcessor) SetSaturation(saturation float32) {
 	vp.saturation = saturation
-}
\ No newline at end of file
+}
+
+// SetUpscaler selects the upscaling stage by name (see Upscalers); "none" disables it
+func (vp *VideoProcessor) SetUpscaler(name string) error {
+	scaler, err := newUpscaler(name)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = UpscalerNone
+	}
+	vp.upscaler = scaler
+	vp.upscalerName = name
+	return nil
+}
+
+// GetUpscaler returns the name of the active upscaler
+func (vp *VideoProcessor) GetUpscaler() string {
+	if vp.upscalerName == "" {
+		return UpscalerNone
+	}
+	return vp.upscalerName
+}
+
+// UpscaleFrame runs the upscaling stage on a width x height frame and returns the
+// scaled frame and its size. Without an upscaler the frame is returned unchanged.
+// The returned buffer is reused by the next call.
+func (vp *VideoProcessor) UpscaleFrame(frameBuffer []uint32, width, height int) ([]uint32, int, int) {
+	if vp.upscaler == nil {
+		return frameBuffer, width, height
+	}
+
+	factor := vp.upscaler.factor()
+	size := width * factor * height * factor
+	if cap(vp.scaled) < size {
+		vp.scaled = make([]uint32, size)
+	}
+	vp.scaled = vp.scaled[:size]
+	vp.upscaler.upscale(frameBuffer, width, height, vp.scaled)
+	return vp.scaled, width * factor, height * factor
+}
@@ -0,0 +1,25 @@
+package graphics
+
+import "testing"
+
+func TestProcessFrame_ReusesAdjustedFrame(t *testing.T) {
+	vp := NewVideoProcessor(1.2, 1, 1)
+	frame := make([]uint32, 256*240)
+	for i := range frame {
+		frame[i] = 0x404040
+	}
+
+	first := vp.ProcessFrame(frame)
+	if &first[0] == &frame[0] {
+		t.Fatal("Expected the brightness to be applied to a separate frame")
+	}
+	if first[0] == 0x404040 {
+		t.Errorf("Expected a brighter pixel, got %06X", first[0])
+	}
+	if allocs := testing.AllocsPerRun(5, func() { vp.ProcessFrame(frame) }); allocs != 0 {
+		t.Errorf("Expected ProcessFrame not to allocate, got %v allocations", allocs)
+	}
+	if second := vp.ProcessFrame(frame); &second[0] != &first[0] {
+		t.Error("Expected the adjusted frame to be reused")
+	}
+}
@@ -0,0 +1,279 @@
+// Package graphics implements the xBRZ pixel-art upscaler.
+package graphics
+
+import "math"
+
+// xBRZ tuning, matching the defaults of the reference implementation
+const (
+	xbrzLuminanceWeight            = 1.0
+	xbrzEqualColorTolerance        = 30.0
+	xbrzCenterDirectionBias        = 4.0
+	xbrzDominantDirectionThreshold = 3.6
+	xbrzSteepDirectionThreshold    = 2.2
+)
+
+// Corner blend types
+const (
+	xbrzBlendNone uint8 = iota
+	xbrzBlendNormal
+	xbrzBlendDominant
+)
+
+// xbrzScaler implements xBRZ at 2x and 3x. The first pass classifies the corner
+// between every 2x2 block of source pixels; the second pass scales each pixel
+// and blends its corners, rotating the kernel so each corner is handled as the
+// bottom-right one.
+type xbrzScaler struct {
+	scale int
+	// Per source pixel corner blend types: topL | topR<<2 | bottomR<<4 | bottomL<<6
+	blend []uint8
+}
+
+func (s *xbrzScaler) factor() int { return s.scale }
+
+func (s *xbrzScaler) upscale(src []uint32, width, height int, dst []uint32) {
+	if cap(s.blend) < width*height {
+		s.blend = make([]uint8, width*height)
+	}
+	blend := s.blend[:width*height]
+	clear(blend)
+
+	at := func(x, y int) uint32 { return pixelAt(src, width, height, x, y) }
+
+	// Pass 1: corner classification. The kernel around the corner between f, g, j and k:
+	//  - | b | c | -
+	//  e | f | g | h
+	//  i | j | k | l
+	//  - | n | o | -
+	for y := 0; y < height-1; y++ {
+		for x := 0; x < width-1; x++ {
+			blendF, blendG, blendJ, blendK := xbrzPreProcessCorners(
+				at(x, y-1), at(x+1, y-1),
+				at(x-1, y), at(x, y), at(x+1, y), at(x+2, y),
+				at(x-1, y+1), at(x, y+1), at(x+1, y+1), at(x+2, y+1),
+				at(x, y+2), at(x+1, y+2))
+			blend[y*width+x] |= blendF << 4     // Bottom right of f
+			blend[y*width+x+1] |= blendG << 6   // Bottom left of g
+			blend[(y+1)*width+x] |= blendJ << 2 // Top right of j
+			blend[(y+1)*width+x+1] |= blendK    // Top left of k
+		}
+	}
+
+	// Pass 2: scale and blend
+	scale := s.scale
+	outWidth := width * scale
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			center := src[y*width+x]
+			out := y*scale*outWidth + x*scale
+			for row := 0; row < scale; row++ {
+				line := dst[out+row*outWidth : out+row*outWidth+scale]
+				for col := range line {
+					line[col] = center
+				}
+			}
+
+			info := blend[y*width+x]
+			if info == 0 {
+				continue
+			}
+
+			kernel := [9]uint32{
+				at(x-1, y-1), at(x, y-1), at(x+1, y-1),
+				at(x-1, y), center, at(x+1, y),
+				at(x-1, y+1), at(x, y+1), at(x+1, y+1),
+			}
+			for rotation := 0; rotation < 4; rotation++ {
+				ref := func(i, j int) *uint32 {
+					for r := 0; r < rotation; r++ {
+						i, j = scale-1-j, i
+					}
+					return &dst[out+i*outWidth+j]
+				}
+				s.blendPixel(kernel, info, ref)
+				kernel = xbrzRotateKernel(kernel)
+				info = info<<2 | info>>6
+			}
+		}
+	}
+}
+
+// xbrzRotateKernel rotates a 3x3 kernel by 90 degrees so that the corner handled
+// next becomes the bottom-right one
+func xbrzRotateKernel(k [9]uint32) [9]uint32 {
+	var rotated [9]uint32
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			rotated[i*3+j] = k[(2-j)*3+i]
+		}
+	}
+	return rotated
+}
+
+// xbrzPreProcessCorners decides how the corner between f, g, j and k is blended
+// by comparing the color gradients along both diagonals
+func xbrzPreProcessCorners(b, c, e, f, g, h, i, j, k, l, n, o uint32) (blendF, blendG, blendJ, blendK uint8) {
+	if (f == g && j == k) || (f == j && g == k) {
+		return
+	}
+
+	jg := xbrzDist(i, f) + xbrzDist(f, c) + xbrzDist(n, k) + xbrzDist(k, h) + xbrzCenterDirectionBias*xbrzDist(j, g)
+	fk := xbrzDist(e, j) + xbrzDist(j, o) + xbrzDist(b, g) + xbrzDist(g, l) + xbrzCenterDirectionBias*xbrzDist(f, k)
+
+	if jg < fk {
+		blendType := xbrzBlendNormal
+		if xbrzDominantDirectionThreshold*jg < fk {
+			blendType = xbrzBlendDominant
+		}
+		if f != g && f != j {
+			blendF = blendType
+		}
+		if k != j && k != g {
+			blendK = blendType
+		}
+	} else if fk < jg {
+		blendType := xbrzBlendNormal
+		if xbrzDominantDirectionThreshold*fk < jg {
+			blendType = xbrzBlendDominant
+		}
+		if j != f && j != k {
+			blendJ = blendType
+		}
+		if g != f && g != k {
+			blendG = blendType
+		}
+	}
+	return
+}
+
+// blendPixel blends the bottom-right corner of the (rotated) kernel
+//
+//	a | b | c
+//	d | e | f
+//	g | h | i
+func (s *xbrzScaler) blendPixel(k [9]uint32, info uint8, ref func(i, j int) *uint32) {
+	topR := (info >> 2) & 3
+	bottomR := (info >> 4) & 3
+	bottomL := info >> 6
+	if bottomR < xbrzBlendNormal {
+		return
+	}
+
+	b, c, d, e, f, g, h, i := k[1], k[2], k[3], k[4], k[5], k[6], k[7], k[8]
+
+	doLineBlend := true
+	if bottomR < xbrzBlendDominant {
+		// No second blend in an adjacent rotation for this pixel (insular pixels)
+		if topR != xbrzBlendNone && !xbrzEqual(e, g) {
+			doLineBlend = false
+		}
+		if bottomL != xbrzBlendNone && !xbrzEqual(e, c) {
+			doLineBlend = false
+		}
+		// No full blend for L-shapes; blend the corner only
+		if !xbrzEqual(e, i) && xbrzEqual(g, h) && xbrzEqual(h, i) && xbrzEqual(i, f) && xbrzEqual(f, c) {
+			doLineBlend = false
+		}
+	}
+
+	// Blend towards the most similar neighbour
+	px := h
+	if xbrzDist(e, f) <= xbrzDist(e, h) {
+		px = f
+	}
+
+	last := s.scale - 1
+	if !doLineBlend {
+		// Round corner
+		if s.scale == 2 {
+			xbrzAlphaGrad(ref(1, 1), px, 21, 100)
+		} else {
+			xbrzAlphaGrad(ref(2, 2), px, 45, 100)
+		}
+		return
+	}
+
+	fg := xbrzDist(f, g)
+	hc := xbrzDist(h, c)
+	shallow := xbrzSteepDirectionThreshold*fg <= hc && e != g && d != g
+	steep := xbrzSteepDirectionThreshold*hc <= fg && e != c && b != c
+
+	switch {
+	case shallow && steep:
+		if s.scale == 2 {
+			xbrzAlphaGrad(ref(1, 0), px, 1, 4)
+			xbrzAlphaGrad(ref(0, 1), px, 1, 4)
+			xbrzAlphaGrad(ref(1, 1), px, 5, 6)
+		} else {
+			xbrzAlphaGrad(ref(2, 0), px, 1, 4)
+			xbrzAlphaGrad(ref(0, 2), px, 1, 4)
+			xbrzAlphaGrad(ref(2, 1), px, 3, 4)
+			xbrzAlphaGrad(ref(1, 2), px, 3, 4)
+			*ref(2, 2) = px
+		}
+	case shallow:
+		xbrzAlphaGrad(ref(last, 0), px, 1, 4)
+		if s.scale == 2 {
+			xbrzAlphaGrad(ref(last, 1), px, 3, 4)
+		} else {
+			xbrzAlphaGrad(ref(last-1, 2), px, 1, 4)
+			xbrzAlphaGrad(ref(last, 1), px, 3, 4)
+			*ref(last, 2) = px
+		}
+	case steep:
+		xbrzAlphaGrad(ref(0, last), px, 1, 4)
+		if s.scale == 2 {
+			xbrzAlphaGrad(ref(1, last), px, 3, 4)
+		} else {
+			xbrzAlphaGrad(ref(2, last-1), px, 1, 4)
+			xbrzAlphaGrad(ref(1, last), px, 3, 4)
+			*ref(2, last) = px
+		}
+	default:
+		// Diagonal
+		if s.scale == 2 {
+			xbrzAlphaGrad(ref(1, 1), px, 1, 2)
+		} else {
+			xbrzAlphaGrad(ref(1, 2), px, 1, 8)
+			xbrzAlphaGrad(ref(2, 1), px, 1, 8)
+			xbrzAlphaGrad(ref(2, 2), px, 7, 8)
+		}
+	}
+}
+
+// xbrzAlphaGrad blends front over *back with weight m/n
+func xbrzAlphaGrad(back *uint32, front uint32, m, n uint32) {
+	mix := func(shift uint) uint32 {
+		bc := (*back >> shift) & 0xFF
+		fc := (front >> shift) & 0xFF
+		return (fc*m + bc*(n-m)) / n
+	}
+	*back = mix(16)<<16 | mix(8)<<8 | mix(0)
+}
+
+// xbrzDist returns the YCbCr distance between two 0xRRGGBB colors
+func xbrzDist(p1, p2 uint32) float64 {
+	if p1 == p2 {
+		return 0
+	}
+	rDiff := float64(int((p1>>16)&0xFF) - int((p2>>16)&0xFF))
+	gDiff := float64(int((p1>>8)&0xFF) - int((p2>>8)&0xFF))
+	bDiff := float64(int(p1&0xFF) - int(p2&0xFF))
+
+	// ITU-R BT.2020 conversion
+	const kb = 0.0593
+	const kr = 0.2627
+	const kg = 1 - kb - kr
+	const scaleB = 0.5 / (1 - kb)
+	const scaleR = 0.5 / (1 - kr)
+
+	y := kr*rDiff + kg*gDiff + kb*bDiff
+	cb := scaleB * (bDiff - y)
+	cr := scaleR * (rDiff - y)
+	return math.Sqrt(xbrzLuminanceWeight*y*xbrzLuminanceWeight*y + cb*cb + cr*cr)
+}
+
+// xbrzEqual reports whether two colors are within the equal color tolerance
+func xbrzEqual(p1, p2 uint32) bool {
+	return xbrzDist(p1, p2) < xbrzEqualColorTolerance
+}
@@ -0,0 +1,96 @@
+// Package input implements the Arkanoid "Vaus" paddle controller.
+package input
+
+const (
+	// Arkanoid paddle bits in $4017 reads
+	arkanoidDataBit   = 0x08 // Serial potentiometer data, inverted, MSB first
+	arkanoidButtonBit = 0x10 // 1 = button pressed
+
+	// Potentiometer range of the NES paddle (knob fully left to fully right)
+	ArkanoidMinPosition = 0x62
+	ArkanoidMaxPosition = 0xF2
+)
+
+// Arkanoid emulates the NES Arkanoid paddle: a potentiometer read through an
+// 8-bit shift register latched by the $4016 strobe, plus a single button.
+type Arkanoid struct {
+	position uint8
+	button   bool
+
+	shiftRegister uint8
+	strobe        bool
+}
+
+// NewArkanoid creates a paddle with the knob centered
+func NewArkanoid() *Arkanoid {
+	a := &Arkanoid{}
+	a.Reset()
+	return a
+}
+
+// SetPosition sets the raw potentiometer value, clamped to the paddle's range
+func (a *Arkanoid) SetPosition(position int) {
+	if position < ArkanoidMinPosition {
+		position = ArkanoidMinPosition
+	}
+	if position > ArkanoidMaxPosition {
+		position = ArkanoidMaxPosition
+	}
+	a.position = uint8(position)
+}
+
+// SetScreenX turns the knob to follow a NES pixel column (0-255)
+func (a *Arkanoid) SetScreenX(x int) {
+	if x < 0 {
+		x = 0
+	}
+	if x > 255 {
+		x = 255
+	}
+	a.SetPosition(ArkanoidMinPosition + x*(ArkanoidMaxPosition-ArkanoidMinPosition)/255)
+}
+
+// GetPosition returns the potentiometer value
+func (a *Arkanoid) GetPosition() uint8 {
+	return a.position
+}
+
+// SetButton sets whether the paddle button is pressed
+func (a *Arkanoid) SetButton(pressed bool) {
+	a.button = pressed
+}
+
+// Read returns the paddle port bits: potentiometer data in bit 3 and the button in bit 4
+func (a *Arkanoid) Read() uint8 {
+	if a.strobe {
+		a.shiftRegister = a.position
+	}
+
+	var value uint8
+	if a.shiftRegister&0x80 == 0 {
+		value |= arkanoidDataBit
+	}
+	if a.button {
+		value |= arkanoidButtonBit
+	}
+	if !a.strobe {
+		a.shiftRegister <<= 1
+	}
+	return value
+}
+
+// Write latches the potentiometer value while the strobe is high
+func (a *Arkanoid) Write(value uint8) {
+	a.strobe = (value & 1) != 0
+	if a.strobe {
+		a.shiftRegister = a.position
+	}
+}
+
+// Reset centers the knob and releases the button
+func (a *Arkanoid) Reset() {
+	a.position = (ArkanoidMinPosition + ArkanoidMaxPosition) / 2
+	a.button = false
+	a.shiftRegister = 0
+	a.strobe = false
+}
@@ -0,0 +1,63 @@
+package input
+
+import (
+	"testing"
+)
+
+// readPaddle strobes the port and reassembles the 8-bit potentiometer value
+func readPaddle(is *InputState) uint8 {
+	is.Write(0x4016, 1)
+	is.Write(0x4016, 0)
+	var value uint8
+	for i := 0; i < 8; i++ {
+		value <<= 1
+		if is.Read(0x4017)&arkanoidDataBit == 0 {
+			value |= 1
+		}
+	}
+	return value
+}
+
+func TestArkanoid_SerialPosition(t *testing.T) {
+	is := NewInputState()
+	paddle := NewArkanoid()
+	is.SetPort2Device(paddle)
+
+	paddle.SetPosition(0xA5)
+	if value := readPaddle(is); value != 0xA5 {
+		t.Errorf("Expected position 0xA5, got 0x%02X", value)
+	}
+
+	// The value is latched on strobe; moving the knob mid-read has no effect
+	is.Write(0x4016, 1)
+	is.Write(0x4016, 0)
+	is.Read(0x4017)
+	paddle.SetPosition(0x70)
+	if value := readPaddle(is); value != 0x70 {
+		t.Errorf("Expected new position after re-strobe, got 0x%02X", value)
+	}
+}
+
+func TestArkanoid_ScreenXRange(t *testing.T) {
+	paddle := NewArkanoid()
+
+	paddle.SetScreenX(-20)
+	if paddle.GetPosition() != ArkanoidMinPosition {
+		t.Errorf("Expected left edge to clamp to 0x%02X, got 0x%02X", ArkanoidMinPosition, paddle.GetPosition())
+	}
+	paddle.SetScreenX(255)
+	if paddle.GetPosition() != ArkanoidMaxPosition {
+		t.Errorf("Expected right edge to map to 0x%02X, got 0x%02X", ArkanoidMaxPosition, paddle.GetPosition())
+	}
+}
+
+func TestArkanoid_Button(t *testing.T) {
+	paddle := NewArkanoid()
+	if paddle.Read()&arkanoidButtonBit != 0 {
+		t.Error("Expected button released initially")
+	}
+	paddle.SetButton(true)
+	if paddle.Read()&arkanoidButtonBit == 0 {
+		t.Error("Expected button bit set while pressed")
+	}
+}
@@ -40,11 +40,11 @@ type Controller struct {
 	shiftRegister uint8
 	strobe        bool
 
-	// Snapshot of button states when strobe was activated
+	// Button states at the last latch
 	buttonSnapshot uint8
 	
-	// Bit position tracking for proper NES controller protocol
-	bitPosition uint8  // Tracks which bit we're reading (0-7 for buttons, 8+ for extended reads)
+	// Reads since the last latch (0-7 for buttons, 8+ for extended reads)
+	bitPosition uint8
 	
 	// Debug tracking
 	readCount    uint64
@@ -103,74 +103,51 @@ func (c *Controller) IsPressed(button Button) bool {
 	return (c.buttons & uint8(button)) != 0
 }
 
-// Write handles writes to the controller register ($4016)
+// Write handles writes to the controller register ($4016). Bit 0 is the
+// strobe: while it is high the shift register reloads from the buttons
+// continuously, so the buttons at the falling edge are the ones shifted out.
 func (c *Controller) Write(value uint8) {
 	c.writeCount++
 	wasStrobe := c.strobe
 	c.strobe = (value & 1) != 0
 
-	if c.strobe {
-		// Strobe is active - capture current button state immediately
-		c.buttonSnapshot = c.buttons
-		c.shiftRegister = c.buttons // Set shift register immediately for compatibility
-		c.bitPosition = 0           // Reset bit position for new read sequence
-		if c.debugEnabled {
-			log.Printf("[CONTROLLER_DEBUG] Strobe activated: buttons=0x%02X, snapshot=0x%02X, bitPos=0", 
-				c.buttons, c.buttonSnapshot)
-		}
-	} else if wasStrobe {
-		// Strobe was just deactivated - capture current button state and load into shift register
-		c.buttonSnapshot = c.buttons  // Update snapshot with current button state
-		c.shiftRegister = c.buttonSnapshot
-		c.bitPosition = 0 // Reset bit position for new read sequence
+	if c.strobe || wasStrobe {
+		c.latch()
 		if c.debugEnabled {
-			log.Printf("[CONTROLLER_DEBUG] Strobe deactivated: captured buttons=0x%02X, snapshot=0x%02X, shiftRegister=0x%02X, bitPos=0", 
-				c.buttons, c.buttonSnapshot, c.shiftRegister)
+			log.Printf("[CONTROLLER_DEBUG] Strobe %t: latched buttons=0x%02X", c.strobe, c.buttons)
 		}
 	}
 }
 
-// Read handles reads from the controller register ($4016/$4017)
+// latch reloads the shift register from the buttons
+func (c *Controller) latch() {
+	c.buttonSnapshot = c.buttons
+	c.shiftRegister = c.buttons
+	c.bitPosition = 0
+}
+
+// Read handles reads from the controller register ($4016/$4017). The next
+// button is returned in bit 0; the controller drives no other bits, which the
+// CPU sees as open bus. While strobe is high every read returns A as it is
+// now. The shift register fills with 1s from its serial input, so reads past
+// the eighth return 1, as on an official controller.
 func (c *Controller) Read() uint8 {
 	c.readCount++
-	
+
 	if c.strobe {
-		// When strobe is active, always return button A state and reset to position 0
-		// This matches rgnes/fogleman behavior: reset index during read if strobe is high
-		c.bitPosition = 0
-		buttonBit := uint8(c.buttonSnapshot & 1)
-		result := buttonBit  // Only bit 0 contains button data
-		if c.debugEnabled && c.readCount%10 == 0 {
-			log.Printf("[CONTROLLER_DEBUG] Read during strobe: result=0x%02X (bits 0,1=%d), buttonSnapshot=0x%02X, bitPos reset to 0", 
-				result, buttonBit, c.buttonSnapshot)
-		}
-		return result
+		c.latch()
+		return c.shiftRegister & 1
 	}
 
-	var result uint8
-	
-	if c.bitPosition < 8 {
-		// Reading bits 0-7: Normal button sequence
-		buttonBit := uint8(c.shiftRegister & 1)
-		result = buttonBit  // Only bit 0 contains button data
-		c.shiftRegister >>= 1
+	result := c.shiftRegister & 1
+	c.shiftRegister = c.shiftRegister>>1 | 0x80
+	if c.bitPosition < 0xFF {
 		c.bitPosition++
-		
-		if c.debugEnabled && c.readCount%10 == 0 {
-			log.Printf("[CONTROLLER_DEBUG] Read bit %d: result=0x%02X (bits 0,1=%d), shiftRegister=0x%02X", 
-				c.bitPosition-1, result, buttonBit, c.shiftRegister)
-		}
-	} else {
-		// Reading bit 8+: Return 0 (matches rgnes/fogleman NES behavior)
-		result = 0
-		
-		if c.debugEnabled && c.readCount%10 == 0 {
-			log.Printf("[CONTROLLER_DEBUG] Extended read (bit %d): result=0x%02X", 
-				c.bitPosition, result)
-		}
-		c.bitPosition++ // Continue incrementing for debug purposes
 	}
-	
+	if c.debugEnabled && c.readCount%10 == 0 {
+		log.Printf("[CONTROLLER_DEBUG] Read bit %d: result=0x%02X, shiftRegister=0x%02X",
+			c.bitPosition-1, result, c.shiftRegister)
+	}
 	return result
 }
 
@@ -200,26 +177,81 @@ func (c *Controller) GetBitPosition() uint8 {
 type InputState struct {
 	Controller1 *Controller
 	Controller2 *Controller
+
+	// Controllers 3 and 4 are only read through the Four Score
+	Controller3 *Controller
+	Controller4 *Controller
+
+	// Optional device plugged into port 2 instead of Controller2 (e.g. Zapper)
+	port2 PortDevice
+
+	// Four Score adapter (controllers 1/3 on $4016, 2/4 on $4017)
+	fourScore      bool
+	fourScorePorts [2]*fourScorePort
+
+	// Famicom microphone on controller 2, read in bit 2 of $4016
+	microphone bool
 }
 
-// NewInputState creates a new input state with two controllers
+// NewInputState creates a new input state with four controllers (3 and 4 unplugged)
 func NewInputState() *InputState {
-	return &InputState{
+	is := &InputState{
 		Controller1: New(),
 		Controller2: New(),
+		Controller3: New(),
+		Controller4: New(),
+	}
+	is.fourScorePorts = [2]*fourScorePort{
+		{first: is.Controller1, second: is.Controller3, signature: fourScoreSignature1},
+		{first: is.Controller2, second: is.Controller4, signature: fourScoreSignature2},
 	}
+	return is
 }
 
 // Reset resets all input devices
 func (is *InputState) Reset() {
 	is.Controller1.Reset()
 	is.Controller2.Reset()
+	is.Controller3.Reset()
+	is.Controller4.Reset()
+	for _, port := range is.fourScorePorts {
+		port.Reset()
+	}
+	if is.port2 != nil {
+		is.port2.Reset()
+	}
+}
+
+// SetPort2Device plugs a device into port 2 in place of controller 2 (nil restores the controller)
+func (is *InputState) SetPort2Device(device PortDevice) {
+	is.port2 = device
+}
+
+// GetPort2Device returns the device plugged into port 2, or nil for the standard controller
+func (is *InputState) GetPort2Device() PortDevice {
+	return is.port2
+}
+
+// SetFourScore connects or disconnects the Four Score. While connected it occupies
+// both ports, so a device set with SetPort2Device is not read.
+func (is *InputState) SetFourScore(enabled bool) {
+	is.fourScore = enabled
+	for _, port := range is.fourScorePorts {
+		port.Reset()
+	}
+}
+
+// IsFourScoreEnabled returns whether the Four Score is connected
+func (is *InputState) IsFourScoreEnabled() bool {
+	return is.fourScore
 }
 
 // EnableDebug enables debug logging for all controllers
 func (is *InputState) EnableDebug(enable bool) {
 	is.Controller1.EnableDebug(enable)
 	is.Controller2.EnableDebug(enable)
+	is.Controller3.EnableDebug(enable)
+	is.Controller4.EnableDebug(enable)
 }
 
 // SetButtons1 sets all button states for controller 1 (array approach)
@@ -232,17 +264,42 @@ func (is *InputState) SetButtons2(buttons [8]bool) {
 	is.Controller2.SetButtons(buttons)
 }
 
+// SetButtons3 sets all button states for controller 3 (Four Score)
+func (is *InputState) SetButtons3(buttons [8]bool) {
+	is.Controller3.SetButtons(buttons)
+}
+
+// SetButtons4 sets all button states for controller 4 (Four Score)
+func (is *InputState) SetButtons4(buttons [8]bool) {
+	is.Controller4.SetButtons(buttons)
+}
+
 
 // Read reads from controller ports
 func (is *InputState) Read(address uint16) uint8 {
+	if is.fourScore {
+		switch address {
+		case 0x4016:
+			return is.fourScorePorts[0].Read() | is.microphoneBits()
+		case 0x4017:
+			return is.fourScorePorts[1].Read() | 0x40
+		}
+		return 0
+	}
+
 	switch address {
 	case 0x4016:
-		result := is.Controller1.Read()
+		result := is.Controller1.Read() | is.microphoneBits()
 		if is.Controller1.debugEnabled {
 			log.Printf("[INPUT_TRACE] $4016 read: result=0x%02X, readCount=%d", result, is.Controller1.readCount)
 		}
 		return result
 	case 0x4017:
+		if is.port2 != nil {
+			// Upper bits are open bus like the controller read below
+			return is.port2.Read() | 0x40
+		}
+
 		// Controller 2 - Independent controller with its own bitPosition tracking
 		// Critical for SMB title screen - Controller 2 must be completely independent
 		result := is.Controller2.Read()
@@ -271,5 +328,11 @@ func (is *InputState) Write(address uint16, value uint8) {
 		// Both controllers receive strobe signals
 		is.Controller1.Write(value)
 		is.Controller2.Write(value)
+		if is.port2 != nil {
+			is.port2.Write(value)
+		}
+		for _, port := range is.fourScorePorts {
+			port.Write(value)
+		}
 	}
 }
@@ -199,8 +199,8 @@ func TestRead_StrobeActive_ShouldReturnButtonAState(t *testing.T) {
 	controller.Write(0x01) // Enable strobe
 	value := controller.Read()
 
-	// Should return 0x40 (bit 6 set, bit 0 clear)
-	expected := uint8(0x40)
+	// Only bit 0 is driven; the upper bits are open bus
+	expected := uint8(0x00)
 	if value != expected {
 		t.Errorf("Expected read value 0x%02X with ButtonA not pressed, got 0x%02X", expected, value)
 	}
@@ -210,8 +210,7 @@ func TestRead_StrobeActive_ShouldReturnButtonAState(t *testing.T) {
 	controller.Write(0x01) // Refresh strobe
 	value = controller.Read()
 
-	// Should return 0x41 (bit 6 set, bit 0 set)
-	expected = uint8(0x41)
+	expected = uint8(0x01)
 	if value != expected {
 		t.Errorf("Expected read value 0x%02X with ButtonA pressed, got 0x%02X", expected, value)
 	}
@@ -231,11 +230,11 @@ func TestRead_StrobeInactive_ShouldShiftRegister(t *testing.T) {
 	// Read sequence should return buttons in order:
 	// A, B, Select, Start, Up, Down, Left, Right
 	expectedReadSequence := []uint8{
-		0x41,                   // A pressed (bit 0) + bit 6
-		0x40,                   // B not pressed + bit 6
-		0x40,                   // Select not pressed + bit 6
-		0x41,                   // Start pressed (bit 3 shifted to bit 0) + bit 6
-		0x40, 0x40, 0x40, 0x40, // Up, Down, Left, Right not pressed + bit 6
+		0x01,                   // A pressed (bit 0)
+		0x00,                   // B not pressed
+		0x00,                   // Select not pressed
+		0x01,                   // Start pressed (bit 3 shifted to bit 0)
+		0x00, 0x00, 0x00, 0x00, // Up, Down, Left, Right not pressed
 	}
 
 	for i, expected := range expectedReadSequence {
@@ -246,7 +245,7 @@ func TestRead_StrobeInactive_ShouldShiftRegister(t *testing.T) {
 	}
 }
 
-func TestRead_ExtendedReading_ShouldReturnZeros(t *testing.T) {
+func TestRead_ExtendedReading_ShouldReturnOnes(t *testing.T) {
 	controller := New()
 
 	// Set one button
@@ -261,34 +260,40 @@ func TestRead_ExtendedReading_ShouldReturnZeros(t *testing.T) {
 		controller.Read()
 	}
 
-	// Additional reads should return 0x40 (just bit 6, no button data)
+	// The shift register fills with 1s, so additional reads return 1
 	for i := 0; i < 5; i++ {
 		value := controller.Read()
-		if value != 0x40 {
-			t.Errorf("Extended read %d: expected 0x40, got 0x%02X", i, value)
+		if value != 0x01 {
+			t.Errorf("Extended read %d: expected 0x01, got 0x%02X", i, value)
 		}
 	}
 }
 
-func TestRead_ButtonStateChange_DuringStrobe_ShouldUseOriginalState(t *testing.T) {
+func TestRead_ButtonStateChange_DuringStrobe_ShouldReloadContinuously(t *testing.T) {
 	controller := New()
 
 	// Set initial state
 	controller.SetButton(ButtonA, true)
 
-	// Enable strobe (captures current state)
+	// Enable strobe (reloads the shift register continuously)
 	controller.Write(0x01)
 
 	// Change button state while strobe is active
 	controller.SetButton(ButtonA, false)
 	controller.SetButton(ButtonB, true)
 
-	// Read should still return original ButtonA state
+	// Read should return the current ButtonA state
 	value := controller.Read()
-	expected := uint8(0x41) // ButtonA was pressed when strobe was set
+	expected := uint8(0x00) // ButtonA was released while strobe was high
 
 	if value != expected {
-		t.Errorf("Expected 0x%02X (original state), got 0x%02X", expected, value)
+		t.Errorf("Expected 0x%02X (current state), got 0x%02X", expected, value)
+	}
+
+	// Reads while strobe is high keep returning A without shifting
+	controller.SetButton(ButtonA, true)
+	if value := controller.Read(); value != 0x01 {
+		t.Errorf("Expected 0x01 after pressing A during strobe, got 0x%02X", value)
 	}
 }
 
@@ -312,14 +317,14 @@ func TestRead_ButtonStateChange_AfterStrobeCleared_ShouldUseSnapshotState(t *tes
 	value2 := controller.Read() // Should be ButtonB (was pressed at snapshot)
 	value3 := controller.Read() // Should be Select (was NOT pressed at snapshot)
 
-	if value1 != 0x41 {
-		t.Errorf("First read: expected 0x41 (A pressed in snapshot), got 0x%02X", value1)
+	if value1 != 0x01 {
+		t.Errorf("First read: expected 0x01 (A pressed in snapshot), got 0x%02X", value1)
 	}
-	if value2 != 0x41 {
-		t.Errorf("Second read: expected 0x41 (B pressed in snapshot), got 0x%02X", value2)
+	if value2 != 0x01 {
+		t.Errorf("Second read: expected 0x01 (B pressed in snapshot), got 0x%02X", value2)
 	}
-	if value3 != 0x40 {
-		t.Errorf("Third read: expected 0x40 (Select not pressed in snapshot), got 0x%02X", value3)
+	if value3 != 0x00 {
+		t.Errorf("Third read: expected 0x00 (Select not pressed in snapshot), got 0x%02X", value3)
 	}
 }
 
@@ -419,7 +424,7 @@ func TestInputState_Read_ShouldRouteToCorrectController(t *testing.T) {
 	value2 := inputState.Read(0x4017) // Controller 2
 
 	// Controller 1 should return ButtonA state
-	expected1 := uint8(0x41) // ButtonA pressed + bit 6
+	expected1 := uint8(0x01) // ButtonA pressed
 	if value1 != expected1 {
 		t.Errorf("Controller 1 read: expected 0x%02X, got 0x%02X", expected1, value1)
 	}
@@ -513,14 +518,14 @@ func TestControllerReadingSequence_StandardPattern_ShouldMatchExpected(t *testin
 		button   string
 		expected uint8
 	}{
-		{"A", 0x41},      // Pressed
-		{"B", 0x40},      // Not pressed
-		{"Select", 0x40}, // Not pressed
-		{"Start", 0x41},  // Pressed
-		{"Up", 0x40},     // Not pressed
-		{"Down", 0x40},   // Not pressed
-		{"Left", 0x40},   // Not pressed
-		{"Right", 0x41},  // Pressed
+		{"A", 0x01},      // Pressed
+		{"B", 0x00},      // Not pressed
+		{"Select", 0x00}, // Not pressed
+		{"Start", 0x01},  // Pressed
+		{"Up", 0x00},     // Not pressed
+		{"Down", 0x00},   // Not pressed
+		{"Left", 0x00},   // Not pressed
+		{"Right", 0x01},  // Pressed
 	}
 
 	for i, expected := range expectedSequence {
@@ -544,8 +549,8 @@ func TestController_RapidStrobeCycle_ShouldWorkCorrectly(t *testing.T) {
 
 		// First read should always be ButtonA
 		value := controller.Read()
-		if value != 0x41 {
-			t.Errorf("Rapid cycle %d: expected 0x41, got 0x%02X", i, value)
+		if value != 0x01 {
+			t.Errorf("Rapid cycle %d: expected 0x01, got 0x%02X", i, value)
 		}
 	}
 }
@@ -563,14 +568,14 @@ func TestController_IncompleteReadSequence_ShouldResumeCorrectly(t *testing.T) {
 	controller.Write(0x00)
 
 	// Read first two buttons
-	value1 := controller.Read() // A - should be 0x41
-	value2 := controller.Read() // B - should be 0x40
+	value1 := controller.Read() // A - should be 0x01
+	value2 := controller.Read() // B - should be 0x00
 
-	if value1 != 0x41 {
-		t.Errorf("First read: expected 0x41, got 0x%02X", value1)
+	if value1 != 0x01 {
+		t.Errorf("First read: expected 0x01, got 0x%02X", value1)
 	}
-	if value2 != 0x40 {
-		t.Errorf("Second read: expected 0x40, got 0x%02X", value2)
+	if value2 != 0x00 {
+		t.Errorf("Second read: expected 0x00, got 0x%02X", value2)
 	}
 
 	// Re-strobe (should reset sequence)
@@ -579,8 +584,8 @@ func TestController_IncompleteReadSequence_ShouldResumeCorrectly(t *testing.T) {
 
 	// Should start over with ButtonA
 	value3 := controller.Read()
-	if value3 != 0x41 {
-		t.Errorf("After re-strobe: expected 0x41, got 0x%02X", value3)
+	if value3 != 0x01 {
+		t.Errorf("After re-strobe: expected 0x01, got 0x%02X", value3)
 	}
 }
 
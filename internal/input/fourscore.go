@@ -0,0 +1,78 @@
+// Package input implements the Four Score four-player adapter.
+package input
+
+import "gones/internal/savestate"
+
+const (
+	// Four Score signatures returned in reads 17-24 of each port (LSB first)
+	fourScoreSignature1 = 0x10 // $4016
+	fourScoreSignature2 = 0x20 // $4017
+
+	// fourScoreReportBits is the number of bits in a Four Score report
+	fourScoreReportBits = 24
+)
+
+// fourScorePort is one side of the Four Score. Each port reports 24 bits:
+// the first controller's buttons, the second controller's buttons, then a
+// signature byte that identifies the adapter. Reads past the report return 1.
+type fourScorePort struct {
+	first, second *Controller
+	signature     uint8
+
+	shiftRegister uint32
+	strobe        bool
+	bitPosition   uint8
+}
+
+// latch loads the report from the current button states
+func (p *fourScorePort) latch() {
+	p.shiftRegister = uint32(p.first.buttons) | uint32(p.second.buttons)<<8 | uint32(p.signature)<<16
+	p.bitPosition = 0
+}
+
+// Write handles the shared strobe on $4016
+func (p *fourScorePort) Write(value uint8) {
+	wasStrobe := p.strobe
+	p.strobe = (value & 1) != 0
+	if p.strobe || wasStrobe {
+		p.latch()
+	}
+}
+
+// Read returns the next bit of the report in bit 0
+func (p *fourScorePort) Read() uint8 {
+	if p.strobe {
+		p.latch()
+		return uint8(p.shiftRegister & 1)
+	}
+
+	if p.bitPosition >= fourScoreReportBits {
+		return 1
+	}
+	result := uint8(p.shiftRegister & 1)
+	p.shiftRegister >>= 1
+	p.bitPosition++
+	return result
+}
+
+// Reset clears the port's shift register
+func (p *fourScorePort) Reset() {
+	p.shiftRegister = 0
+	p.strobe = false
+	p.bitPosition = 0
+}
+
+// SaveState writes the port's shift register state
+func (p *fourScorePort) SaveState(w *savestate.Writer) {
+	w.WriteU32(p.shiftRegister)
+	w.WriteBool(p.strobe)
+	w.WriteU8(p.bitPosition)
+}
+
+// LoadState restores state written by SaveState
+func (p *fourScorePort) LoadState(r *savestate.Reader) error {
+	p.shiftRegister = r.ReadU32()
+	p.strobe = r.ReadBool()
+	p.bitPosition = r.ReadU8()
+	return r.Err()
+}
@@ -0,0 +1,62 @@
+package input
+
+import (
+	"testing"
+)
+
+// readReport strobes the ports and reads n bits from the given port
+func readReport(is *InputState, address uint16, n int) []uint8 {
+	is.Write(0x4016, 1)
+	is.Write(0x4016, 0)
+	bits := make([]uint8, n)
+	for i := range bits {
+		bits[i] = is.Read(address) & 1
+	}
+	return bits
+}
+
+func TestFourScore_ReportLayout(t *testing.T) {
+	is := NewInputState()
+	is.SetFourScore(true)
+	is.SetButtons1([8]bool{true})                                                  // A
+	is.SetButtons3([8]bool{false, false, false, true})                             // Start
+	is.SetButtons2([8]bool{false, true})                                           // B
+	is.SetButtons4([8]bool{false, false, false, false, false, false, false, true}) // Right
+
+	port1 := readReport(is, 0x4016, 26)
+	port2 := readReport(is, 0x4017, 26)
+
+	expectBits := func(name string, bits []uint8, set ...int) {
+		t.Helper()
+		want := make([]uint8, len(bits))
+		for _, i := range set {
+			want[i] = 1
+		}
+		// Reads past the 24 bit report return 1
+		want[24], want[25] = 1, 1
+		for i := range bits {
+			if bits[i] != want[i] {
+				t.Errorf("%s bit %d: expected %d, got %d", name, i, want[i], bits[i])
+			}
+		}
+	}
+
+	// Controller 1 A (bit 0), controller 3 Start (bit 8+3), signature $10 (bit 16+4)
+	expectBits("$4016", port1, 0, 11, 20)
+	// Controller 2 B (bit 1), controller 4 Right (bit 8+7), signature $20 (bit 16+5)
+	expectBits("$4017", port2, 1, 15, 21)
+}
+
+func TestFourScore_DisabledUsesStandardControllers(t *testing.T) {
+	is := NewInputState()
+	is.SetButtons3([8]bool{true}) // A
+
+	// Controller 1's empty report, then the 1s of a standard controller read
+	// past its 8 buttons, not controller 3's
+	bits := readReport(is, 0x4016, 16)
+	for i, bit := range bits {
+		if want := uint8(i / 8); bit != want {
+			t.Errorf("Expected controller 3 to be ignored without Four Score, bit %d = %d", i, bit)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// Package input implements the Famicom's microphone, built into controller 2
+// and read in bit 2 of $4016.
+package input
+
+// microphoneBit is the microphone's bit in $4016 reads: 1 while sound
+// reaches it
+const microphoneBit = 0x04
+
+// SetMicrophone sets whether the microphone hears sound, such as the player
+// blowing into it
+func (is *InputState) SetMicrophone(active bool) {
+	is.microphone = active
+}
+
+// IsMicrophoneActive returns whether the microphone hears sound
+func (is *InputState) IsMicrophoneActive() bool {
+	return is.microphone
+}
+
+// microphoneBits returns the microphone's bit of a $4016 read
+func (is *InputState) microphoneBits() uint8 {
+	if is.microphone {
+		return microphoneBit
+	}
+	return 0
+}
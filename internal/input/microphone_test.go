@@ -0,0 +1,28 @@
+package input
+
+import "testing"
+
+func TestMicrophone(t *testing.T) {
+	is := NewInputState()
+	is.Write(0x4016, 1)
+	is.Write(0x4016, 0)
+	if got := is.Read(0x4016); got&microphoneBit != 0 {
+		t.Errorf("$4016 = %02X with the microphone silent", got)
+	}
+
+	is.SetMicrophone(true)
+	is.Controller1.SetButton(ButtonA, true)
+	is.Write(0x4016, 1)
+	is.Write(0x4016, 0)
+	if got := is.Read(0x4016); got != microphoneBit|1 {
+		t.Errorf("$4016 = %02X, want the microphone and button A", got)
+	}
+	if got := is.Read(0x4017); got&microphoneBit != 0 {
+		t.Errorf("$4017 = %02X, the microphone is only on $4016", got)
+	}
+
+	is.SetFourScore(true)
+	if got := is.Read(0x4016); got&microphoneBit == 0 {
+		t.Errorf("$4016 = %02X through the Four Score, want the microphone", got)
+	}
+}
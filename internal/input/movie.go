@@ -0,0 +1,152 @@
+// Package input implements movie recording: scripts recorded frame by frame,
+// anchored to the savestate they start from, branched and saved.
+package input
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// scriptButtonOrder names the [8]bool buttons, as written to scripts
+var scriptButtonOrder = [8]string{"A", "B", "Select", "Start", "Up", "Down", "Left", "Right"}
+
+// NewScript creates an empty script for recording, starting from power on
+func NewScript() *Script {
+	return &Script{}
+}
+
+// SetSavestate anchors the script to a savestate, taken on the ROM whose
+// SHA-256 is rom: playback restores it before the first frame
+func (s *Script) SetSavestate(rom string, state []byte) {
+	s.rom, s.state = rom, state
+}
+
+// Savestate returns the savestate the script starts from and the SHA-256 of
+// its ROM, or nil when it starts from power on
+func (s *Script) Savestate() (rom string, state []byte) {
+	return s.rom, s.state
+}
+
+// Record sets the controller states of the frame after the last one. Only
+// changes are kept, as a script written by hand would list them.
+func (s *Script) Record(buttons [ScriptPlayers][8]bool) {
+	frame := s.Length()
+	if frame == 0 || s.Buttons(frame-1) != buttons {
+		s.events = append(s.events, scriptEvent{frame: frame, buttons: buttons})
+		for player, state := range buttons {
+			if state != [8]bool{} {
+				s.used[player] = true
+			}
+		}
+	}
+	s.length = frame + 1
+}
+
+// Branch returns a copy of the script's first frames, with its savestate,
+// for recording a new ending onto
+func (s *Script) Branch(frames int) *Script {
+	branch := &Script{length: max(frames, 0), rom: s.rom, state: s.state}
+	for _, event := range s.events {
+		if event.frame >= frames {
+			break
+		}
+		branch.events = append(branch.events, event)
+		for player, state := range event.buttons {
+			if state != [8]bool{} {
+				branch.used[player] = true
+			}
+		}
+	}
+	return branch
+}
+
+// FormatScriptButtons formats a controller state as button names joined by
+// '+', as ParseScriptButtons reads it
+func FormatScriptButtons(buttons [8]bool) string {
+	var names []string
+	for i, pressed := range buttons {
+		if pressed {
+			names = append(names, scriptButtonOrder[i])
+		}
+	}
+	return strings.Join(names, "+")
+}
+
+// entries returns the script's entries as player state strings, ending with
+// one for its last frame so the length survives a round trip
+func (s *Script) entries() []scriptEvent {
+	events := append([]scriptEvent(nil), s.events...)
+	if last := s.Length() - 1; last >= 0 && (len(events) == 0 || events[len(events)-1].frame < last) {
+		events = append(events, scriptEvent{frame: last, buttons: s.Buttons(last)})
+	}
+	return events
+}
+
+// SaveScript writes a script as CSV to files ending in .csv and as JSON to
+// everything else. Only JSON can hold a savestate.
+func SaveScript(s *Script, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create input script: %v", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = s.WriteCSV(file)
+	} else {
+		err = s.WriteJSON(file)
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write input script: %v", closeErr)
+	}
+	return err
+}
+
+// WriteJSON writes the script in the object form of JSON scripts, with its
+// savestate
+func (s *Script) WriteJSON(w io.Writer) error {
+	file := scriptFile{ROM: s.rom, State: s.state, Frames: []scriptEntry{}}
+	for _, event := range s.entries() {
+		frame := event.frame
+		entry := scriptEntry{Frame: &frame}
+		for player, state := range []*string{&entry.P1, &entry.P2, &entry.P3, &entry.P4} {
+			*state = FormatScriptButtons(event.buttons[player])
+		}
+		file.Frames = append(file.Frames, entry)
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode input script: %v", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write input script: %v", err)
+	}
+	return nil
+}
+
+// WriteCSV writes the script as CSV, with a header row. CSV scripts start
+// from power on, so one with a savestate cannot be written.
+func (s *Script) WriteCSV(w io.Writer) error {
+	if s.state != nil {
+		return errors.New("a script starting from a savestate has to be saved as JSON")
+	}
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"frame", "p1", "p2", "p3", "p4"})
+	for _, event := range s.entries() {
+		record := []string{strconv.Itoa(event.frame)}
+		for _, buttons := range event.buttons {
+			record = append(record, FormatScriptButtons(buttons))
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write input script: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package input
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScript_RecordKeepsChanges(t *testing.T) {
+	script := NewScript()
+	var start, released [ScriptPlayers][8]bool
+	start[0][3] = true
+	for _, buttons := range [][ScriptPlayers][8]bool{start, start, released, released} {
+		script.Record(buttons)
+	}
+
+	if script.Length() != 4 {
+		t.Errorf("Expected length 4, got %d", script.Length())
+	}
+	if len(script.events) != 2 {
+		t.Errorf("Expected 2 entries for 2 changes, got %d", len(script.events))
+	}
+	if !script.Buttons(1)[0][3] || script.Buttons(3)[0][3] {
+		t.Error("Expected Start held for frames 0-1 only")
+	}
+	if !script.UsesPlayer(0) || script.UsesPlayer(1) {
+		t.Error("Expected only player 1 to be used")
+	}
+}
+
+func TestScript_JSONRoundTripWithSavestate(t *testing.T) {
+	script := NewScript()
+	script.SetSavestate("abc123", []byte{0x00, 0xFF, 0x10})
+	var buttons [ScriptPlayers][8]bool
+	buttons[0][0], buttons[1][7] = true, true
+	script.Record(buttons)
+	script.Record([ScriptPlayers][8]bool{})
+	script.Record([ScriptPlayers][8]bool{})
+
+	var out bytes.Buffer
+	if err := script.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	loaded, err := ParseJSONScript(out.Bytes())
+	if err != nil {
+		t.Fatalf("ParseJSONScript failed: %v\n%s", err, out.String())
+	}
+
+	rom, state := loaded.Savestate()
+	if rom != "abc123" || !bytes.Equal(state, []byte{0x00, 0xFF, 0x10}) {
+		t.Errorf("Expected the savestate back, got %q %v", rom, state)
+	}
+	if loaded.Length() != 3 {
+		t.Errorf("Expected length 3, got %d", loaded.Length())
+	}
+	if loaded.Buttons(0) != buttons || loaded.Buttons(2) != ([ScriptPlayers][8]bool{}) {
+		t.Errorf("Expected the recorded buttons back, got %v and %v", loaded.Buttons(0), loaded.Buttons(2))
+	}
+}
+
+func TestScript_Branch(t *testing.T) {
+	script, err := ParseJSONScript([]byte(`{"rom": "abc", "state": "AQI=", "frames": [
+		{"frame": 0, "p1": "A"}, {"frame": 10, "p2": "B"}]}`))
+	if err != nil {
+		t.Fatalf("ParseJSONScript failed: %v", err)
+	}
+
+	branch := script.Branch(5)
+	if branch.Length() != 5 {
+		t.Errorf("Expected the branch to keep 5 frames, got %d", branch.Length())
+	}
+	if rom, state := branch.Savestate(); rom != "abc" || !bytes.Equal(state, []byte{1, 2}) {
+		t.Errorf("Expected the branch to keep the savestate, got %q %v", rom, state)
+	}
+	if branch.UsesPlayer(1) {
+		t.Error("Expected player 2's later input to be dropped")
+	}
+
+	var right [ScriptPlayers][8]bool
+	right[0][7] = true
+	branch.Record(right)
+	if branch.Length() != 6 || branch.Buttons(5) != right || !branch.Buttons(4)[0][0] {
+		t.Errorf("Expected the branch to continue at frame 5, got length %d", branch.Length())
+	}
+	if script.Length() != 11 {
+		t.Errorf("Expected the original script untouched, got length %d", script.Length())
+	}
+}
+
+func TestScript_CSVRoundTrip(t *testing.T) {
+	script, err := ParseCSVScript(bytes.NewBufferString("frame,p1,p2\n0,Up+A,\n30,,Start\n"))
+	if err != nil {
+		t.Fatalf("ParseCSVScript failed: %v", err)
+	}
+	var out bytes.Buffer
+	if err := script.WriteCSV(&out); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	loaded, err := ParseCSVScript(&out)
+	if err != nil {
+		t.Fatalf("ParseCSVScript of the written script failed: %v", err)
+	}
+	for _, frame := range []int{0, 29, 30} {
+		if loaded.Buttons(frame) != script.Buttons(frame) {
+			t.Errorf("Frame %d: expected %v, got %v", frame, script.Buttons(frame), loaded.Buttons(frame))
+		}
+	}
+
+	script.SetSavestate("abc", []byte{1})
+	if err := script.WriteCSV(&out); err == nil {
+		t.Error("Expected a script with a savestate not to be written as CSV")
+	}
+}
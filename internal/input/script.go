@@ -0,0 +1,263 @@
+// Package input implements scripted controller input for headless automation.
+package input
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ScriptPlayers is the number of controllers an input script can drive (with a Four Score)
+const ScriptPlayers = 4
+
+// scriptMaskOrder is the FM2-style button mask layout ("RLDUTSBA"), mapped to
+// [8]bool indices (A, B, Select, Start, Up, Down, Left, Right)
+var scriptMaskOrder = [8]int{7, 6, 5, 4, 3, 2, 1, 0}
+
+// scriptButtonNames maps button names to [8]bool indices
+var scriptButtonNames = map[string]int{
+	"a": 0, "b": 1, "select": 2, "start": 3,
+	"up": 4, "down": 5, "left": 6, "right": 7,
+}
+
+// scriptEvent sets the controller states from a frame until the next event
+type scriptEvent struct {
+	frame   int
+	buttons [ScriptPlayers][8]bool
+}
+
+// Script is a sequence of per-frame controller states. Each entry holds from
+// its frame until the next entry, so scripts can list every frame or only the
+// frames where the input changes. A script can start from a savestate
+// instead of power on (see SetSavestate).
+type Script struct {
+	events []scriptEvent
+	used   [ScriptPlayers]bool
+	length int // Frames recorded, which may run past the last entry
+
+	rom   string // SHA-256 of the ROM the savestate is for
+	state []byte // Savestate the script starts from, nil for power on
+}
+
+// scriptEntry is one JSON script entry. Frame is optional; entries without a
+// frame apply to the frame after the previous entry.
+type scriptEntry struct {
+	Frame *int   `json:"frame"`
+	P1    string `json:"p1,omitempty"`
+	P2    string `json:"p2,omitempty"`
+	P3    string `json:"p3,omitempty"`
+	P4    string `json:"p4,omitempty"`
+}
+
+// scriptFile is the object form of a JSON script, which can also hold the
+// savestate the script starts from
+type scriptFile struct {
+	ROM    string        `json:"rom,omitempty"`
+	State  []byte        `json:"state,omitempty"` // Base64
+	Frames []scriptEntry `json:"frames"`
+}
+
+// LoadScript reads an input script. Files ending in .csv are parsed as CSV;
+// everything else is parsed as JSON.
+//
+// JSON scripts are an array of entries (or {"frames": [...]}):
+//
+//	[{"frame": 0, "p1": "Start"}, {"frame": 5, "p1": ""}, {"frame": 60, "p1": "Right+A"}]
+//
+// The object form can start from a savestate: "state" holds it in base64 and
+// "rom" the SHA-256 of the ROM it is for.
+//
+// CSV scripts have the columns frame,p1,p2,p3,p4 with an optional header row.
+// Controller states are button names joined by '+' ("Up+A") or an 8 character
+// FM2 mask in RLDUTSBA order ("...U...A"); an empty state releases all buttons.
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input script: %v", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return ParseCSVScript(bytes.NewReader(data))
+	}
+	return ParseJSONScript(data)
+}
+
+// ParseJSONScript parses a JSON input script
+func ParseJSONScript(data []byte) (*Script, error) {
+	var entries []scriptEntry
+	s := &Script{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapper scriptFile
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			return nil, fmt.Errorf("invalid input script: %v", err)
+		}
+		entries = wrapper.Frames
+		s.rom, s.state = wrapper.ROM, wrapper.State
+	} else if err := json.Unmarshal(trimmed, &entries); err != nil {
+		return nil, fmt.Errorf("invalid input script: %v", err)
+	}
+
+	next := 0
+	for i, entry := range entries {
+		frame := next
+		if entry.Frame != nil {
+			frame = *entry.Frame
+		}
+		if err := s.add(frame, [ScriptPlayers]string{entry.P1, entry.P2, entry.P3, entry.P4}); err != nil {
+			return nil, fmt.Errorf("input script entry %d: %v", i, err)
+		}
+		next = frame + 1
+	}
+	s.sort()
+	return s, nil
+}
+
+// ParseCSVScript parses a CSV input script
+func ParseCSVScript(r io.Reader) (*Script, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	s := &Script{}
+	next := 0
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid input script: %v", err)
+		}
+		if len(record) == 0 {
+			continue
+		}
+
+		frame := next
+		if field := strings.TrimSpace(record[0]); field != "" {
+			frame, err = strconv.Atoi(field)
+			if err != nil {
+				if line == 1 {
+					continue // Header row
+				}
+				return nil, fmt.Errorf("input script line %d: invalid frame %q", line, field)
+			}
+		}
+
+		var states [ScriptPlayers]string
+		for player := 0; player < ScriptPlayers && player+1 < len(record); player++ {
+			states[player] = record[player+1]
+		}
+		if err := s.add(frame, states); err != nil {
+			return nil, fmt.Errorf("input script line %d: %v", line, err)
+		}
+		next = frame + 1
+	}
+	s.sort()
+	return s, nil
+}
+
+// add appends an event for frame from per-player state strings
+func (s *Script) add(frame int, states [ScriptPlayers]string) error {
+	if frame < 0 {
+		return fmt.Errorf("negative frame %d", frame)
+	}
+	event := scriptEvent{frame: frame}
+	for player, state := range states {
+		buttons, err := ParseScriptButtons(state)
+		if err != nil {
+			return fmt.Errorf("player %d: %v", player+1, err)
+		}
+		event.buttons[player] = buttons
+		if strings.TrimSpace(state) != "" {
+			s.used[player] = true
+		}
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+// sort orders events by frame; a later entry for the same frame wins
+func (s *Script) sort() {
+	sort.SliceStable(s.events, func(i, j int) bool {
+		return s.events[i].frame < s.events[j].frame
+	})
+}
+
+// ParseScriptButtons parses a controller state: button names joined by '+'
+// (or '|' / spaces), or an 8 character FM2 mask in RLDUTSBA order
+func ParseScriptButtons(state string) ([8]bool, error) {
+	var buttons [8]bool
+	state = strings.TrimSpace(state)
+	if state == "" {
+		return buttons, nil
+	}
+
+	if isScriptMask(state) {
+		for i, c := range state {
+			if c != '.' && c != ' ' && c != '-' {
+				buttons[scriptMaskOrder[i]] = true
+			}
+		}
+		return buttons, nil
+	}
+
+	names := strings.FieldsFunc(state, func(r rune) bool {
+		return r == '+' || r == '|' || r == ' '
+	})
+	for _, name := range names {
+		index, ok := scriptButtonNames[strings.ToLower(name)]
+		if !ok {
+			return buttons, fmt.Errorf("unknown button %q", name)
+		}
+		buttons[index] = true
+	}
+	return buttons, nil
+}
+
+// isScriptMask reports whether state is an FM2-style RLDUTSBA mask
+func isScriptMask(state string) bool {
+	if len(state) != 8 {
+		return false
+	}
+	for i, c := range state {
+		if c == '.' || c == ' ' || c == '-' {
+			continue
+		}
+		if byte(c) != "RLDUTSBA"[i] && byte(c) != "rldutsba"[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Buttons returns the controller states for a frame
+func (s *Script) Buttons(frame int) [ScriptPlayers][8]bool {
+	i := sort.Search(len(s.events), func(i int) bool {
+		return s.events[i].frame > frame
+	})
+	if i == 0 {
+		return [ScriptPlayers][8]bool{}
+	}
+	return s.events[i-1].buttons
+}
+
+// Length returns the number of frames the script covers: the last entry's
+// frame + 1, or the frames recorded if more
+func (s *Script) Length() int {
+	if len(s.events) == 0 {
+		return s.length
+	}
+	return max(s.length, s.events[len(s.events)-1].frame+1)
+}
+
+// UsesPlayer reports whether the script presses any button for a player (0-3)
+func (s *Script) UsesPlayer(player int) bool {
+	return player >= 0 && player < ScriptPlayers && s.used[player]
+}
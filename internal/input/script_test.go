@@ -0,0 +1,76 @@
+package input
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScript_JSONHoldsUntilNextEntry(t *testing.T) {
+	script, err := ParseJSONScript([]byte(`[
+		{"frame": 10, "p1": "Start"},
+		{"frame": 15, "p1": ""},
+		{"frame": 60, "p1": "Right+A", "p2": "b"}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseJSONScript failed: %v", err)
+	}
+
+	if script.Buttons(5)[0] != ([8]bool{}) {
+		t.Error("Expected no buttons before the first entry")
+	}
+	if !script.Buttons(12)[0][3] {
+		t.Error("Expected Start held between frames 10 and 14")
+	}
+	if script.Buttons(20)[0] != ([8]bool{}) {
+		t.Error("Expected Start released at frame 15")
+	}
+	buttons := script.Buttons(100)
+	if !buttons[0][0] || !buttons[0][7] || !buttons[1][1] {
+		t.Errorf("Expected P1 Right+A and P2 B from frame 60, got %v", buttons)
+	}
+	if script.Length() != 61 {
+		t.Errorf("Expected length 61, got %d", script.Length())
+	}
+	if !script.UsesPlayer(1) || script.UsesPlayer(2) {
+		t.Error("Expected only players 1 and 2 to be used")
+	}
+}
+
+func TestScript_JSONSequentialFrames(t *testing.T) {
+	script, err := ParseJSONScript([]byte(`{"frames": [{"p1": "A"}, {"p1": "B"}, {"p1": ""}]}`))
+	if err != nil {
+		t.Fatalf("ParseJSONScript failed: %v", err)
+	}
+	if !script.Buttons(0)[0][0] || !script.Buttons(1)[0][1] || script.Buttons(2)[0] != ([8]bool{}) {
+		t.Error("Expected entries without a frame to follow one another")
+	}
+}
+
+func TestScript_CSV(t *testing.T) {
+	script, err := ParseCSVScript(strings.NewReader(`frame,p1,p2
+# press start
+0,....T...,
+30,R......A,Up+Left
+`))
+	if err != nil {
+		t.Fatalf("ParseCSVScript failed: %v", err)
+	}
+	if !script.Buttons(0)[0][3] {
+		t.Error("Expected Start from the FM2 mask on frame 0")
+	}
+	buttons := script.Buttons(30)
+	expectedP1 := [8]bool{true, false, false, false, false, false, false, true}
+	expectedP2 := [8]bool{false, false, false, false, true, false, true, false}
+	if buttons[0] != expectedP1 || buttons[1] != expectedP2 {
+		t.Errorf("Unexpected frame 30 states: %v", buttons)
+	}
+}
+
+func TestScript_InvalidButton(t *testing.T) {
+	if _, err := ParseJSONScript([]byte(`[{"frame": 0, "p1": "Turbo"}]`)); err == nil {
+		t.Error("Expected an error for an unknown button")
+	}
+	if _, err := ParseCSVScript(strings.NewReader("0,A\nx,B\n")); err == nil {
+		t.Error("Expected an error for an invalid frame after the first line")
+	}
+}
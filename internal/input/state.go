@@ -0,0 +1,48 @@
+// Package input implements controller state snapshots.
+package input
+
+import "gones/internal/savestate"
+
+// SaveState writes the controller's button and shift register state
+func (c *Controller) SaveState(w *savestate.Writer) {
+	w.WriteU8(c.buttons)
+	w.WriteU8(c.shiftRegister)
+	w.WriteBool(c.strobe)
+	w.WriteU8(c.buttonSnapshot)
+	w.WriteU8(c.bitPosition)
+}
+
+// LoadState restores state written by SaveState
+func (c *Controller) LoadState(r *savestate.Reader) error {
+	c.buttons = r.ReadU8()
+	c.shiftRegister = r.ReadU8()
+	c.strobe = r.ReadBool()
+	c.buttonSnapshot = r.ReadU8()
+	c.bitPosition = r.ReadU8()
+	return r.Err()
+}
+
+// SaveState writes the state of both controller ports
+func (is *InputState) SaveState(w *savestate.Writer) {
+	w.WriteTag("INPT")
+	is.Controller1.SaveState(w)
+	is.Controller2.SaveState(w)
+	is.Controller3.SaveState(w)
+	is.Controller4.SaveState(w)
+	for _, port := range is.fourScorePorts {
+		port.SaveState(w)
+	}
+}
+
+// LoadState restores state written by SaveState
+func (is *InputState) LoadState(r *savestate.Reader) error {
+	r.ExpectTag("INPT")
+	is.Controller1.LoadState(r)
+	is.Controller2.LoadState(r)
+	is.Controller3.LoadState(r)
+	is.Controller4.LoadState(r)
+	for _, port := range is.fourScorePorts {
+		port.LoadState(r)
+	}
+	return r.Err()
+}
@@ -0,0 +1,143 @@
+// Package input implements the NES Zapper light gun.
+package input
+
+// PortDevice is a peripheral that can replace the standard controller on a port
+type PortDevice interface {
+	// Read returns the value read from the port register ($4016/$4017)
+	Read() uint8
+	// Write receives writes to $4016 (strobe)
+	Write(value uint8)
+	// Reset returns the device to its power-on state
+	Reset()
+}
+
+// ZapperLightSource gives the Zapper access to the picture as it is being drawn
+type ZapperLightSource interface {
+	GetScanline() int
+	GetCycle() int
+	GetPixel(x, y int) uint32
+}
+
+const (
+	// Zapper bits in $4016/$4017 reads
+	zapperLightBit   = 0x08 // 0 = light detected, 1 = no light
+	zapperTriggerBit = 0x10 // 1 = trigger pulled
+
+	// zapperLightThreshold is the minimum luma (0-255) the photodiode reacts to.
+	// The sensor only sees bright colors; dark blues or greens don't register.
+	zapperLightThreshold = 0xA0
+
+	// zapperSenseRadius is the radius in pixels of the area around the aim point the
+	// sensor sees (the lens isn't a perfect point)
+	zapperSenseRadius = 2
+
+	// zapperLightScanlines is how long the photodiode keeps reporting light after
+	// the beam has passed the aim point (about 1.4ms, ~20 scanlines)
+	zapperLightScanlines = 20
+)
+
+// Zapper emulates the NES Zapper light gun. The photodiode is modelled against the
+// frame being rendered: light is seen only after the beam has drawn a bright pixel
+// near the aim point, and only for a short time afterwards.
+type Zapper struct {
+	x, y     int
+	onScreen bool
+	trigger  bool
+	source   ZapperLightSource
+}
+
+// NewZapper creates a Zapper aimed off screen
+func NewZapper() *Zapper {
+	return &Zapper{}
+}
+
+// SetLightSource sets the picture the Zapper looks at (normally the PPU)
+func (z *Zapper) SetLightSource(source ZapperLightSource) {
+	z.source = source
+}
+
+// SetPosition aims the Zapper at a NES pixel; coordinates outside 256x240 point off screen
+func (z *Zapper) SetPosition(x, y int) {
+	z.x, z.y = x, y
+	z.onScreen = x >= 0 && x < 256 && y >= 0 && y < 240
+}
+
+// GetPosition returns the aim point and whether it is on screen
+func (z *Zapper) GetPosition() (x, y int, onScreen bool) {
+	return z.x, z.y, z.onScreen
+}
+
+// SetTrigger sets whether the trigger is pulled
+func (z *Zapper) SetTrigger(pulled bool) {
+	z.trigger = pulled
+}
+
+// Read returns the Zapper port bits: light sense in bit 3 and trigger in bit 4
+func (z *Zapper) Read() uint8 {
+	var value uint8
+	if !z.senseLight() {
+		value |= zapperLightBit
+	}
+	if z.trigger {
+		value |= zapperTriggerBit
+	}
+	return value
+}
+
+// Write is ignored; the Zapper has no shift register
+func (z *Zapper) Write(value uint8) {}
+
+// Reset releases the trigger
+func (z *Zapper) Reset() {
+	z.trigger = false
+}
+
+// senseLight reports whether the photodiode currently sees light
+func (z *Zapper) senseLight() bool {
+	if !z.onScreen || z.source == nil {
+		return false
+	}
+
+	scanline := z.source.GetScanline()
+	cycle := z.source.GetCycle()
+	if scanline < 0 || scanline >= 261 {
+		// Pre-render line: the new frame hasn't reached the aim point yet
+		return false
+	}
+
+	// The beam must have passed the aim point recently
+	linesSince := scanline - z.y
+	if linesSince < 0 || linesSince > zapperLightScanlines {
+		return false
+	}
+	if linesSince == 0 && cycle-1 <= z.x {
+		return false
+	}
+
+	for py := z.y - zapperSenseRadius; py <= z.y+zapperSenseRadius; py++ {
+		if py < 0 || py >= 240 || py > scanline {
+			continue
+		}
+		for px := z.x - zapperSenseRadius; px <= z.x+zapperSenseRadius; px++ {
+			if px < 0 || px >= 256 {
+				continue
+			}
+			// Only pixels of the current frame that have already been drawn count
+			if py == scanline && px >= cycle-1 {
+				continue
+			}
+			if zapperLuma(z.source.GetPixel(px, py)) >= zapperLightThreshold {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// zapperLuma returns the perceived brightness (0-255) of a 0xRRGGBB color
+func zapperLuma(color uint32) uint32 {
+	r := (color >> 16) & 0xFF
+	g := (color >> 8) & 0xFF
+	b := color & 0xFF
+	return (r*299 + g*587 + b*114) / 1000
+}
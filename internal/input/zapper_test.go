@@ -0,0 +1,114 @@
+package input
+
+import (
+	"testing"
+)
+
+// fakeLightSource is a frame buffer with a settable beam position
+type fakeLightSource struct {
+	pixels   [256 * 240]uint32
+	scanline int
+	cycle    int
+}
+
+func (f *fakeLightSource) GetScanline() int { return f.scanline }
+func (f *fakeLightSource) GetCycle() int    { return f.cycle }
+func (f *fakeLightSource) GetPixel(x, y int) uint32 {
+	return f.pixels[y*256+x]
+}
+
+func (f *fakeLightSource) fill(x, y, w, h int, color uint32) {
+	for py := y; py < y+h; py++ {
+		for px := x; px < x+w; px++ {
+			f.pixels[py*256+px] = color
+		}
+	}
+}
+
+func newAimedZapper(source *fakeLightSource, x, y int) *Zapper {
+	z := NewZapper()
+	z.SetLightSource(source)
+	z.SetPosition(x, y)
+	return z
+}
+
+func TestZapper_DetectsLightAfterBeamPasses(t *testing.T) {
+	source := &fakeLightSource{}
+	source.fill(100, 100, 16, 16, 0xFFFFFF)
+	z := newAimedZapper(source, 108, 108)
+
+	// Beam hasn't reached the target yet
+	source.scanline, source.cycle = 50, 10
+	if z.Read()&zapperLightBit == 0 {
+		t.Error("Expected no light before the beam reaches the aim point")
+	}
+
+	// Just after the beam drew the aim point
+	source.scanline, source.cycle = 110, 5
+	if z.Read()&zapperLightBit != 0 {
+		t.Error("Expected light right after the beam passed the aim point")
+	}
+
+	// Long after the beam passed, the photodiode has settled again
+	source.scanline = 108 + zapperLightScanlines + 1
+	if z.Read()&zapperLightBit == 0 {
+		t.Error("Expected light to fade after the beam has moved on")
+	}
+}
+
+func TestZapper_IgnoresDarkColors(t *testing.T) {
+	source := &fakeLightSource{}
+	source.fill(100, 100, 16, 16, 0x0000A8) // NES dark blue
+	z := newAimedZapper(source, 108, 108)
+
+	source.scanline, source.cycle = 112, 0
+	if z.Read()&zapperLightBit == 0 {
+		t.Error("Expected dark blue to be below the sensor threshold")
+	}
+}
+
+func TestZapper_OffScreenSeesNoLight(t *testing.T) {
+	source := &fakeLightSource{}
+	source.fill(0, 0, 256, 240, 0xFFFFFF)
+	z := newAimedZapper(source, -1, -1)
+
+	source.scanline, source.cycle = 120, 0
+	if z.Read()&zapperLightBit == 0 {
+		t.Error("Expected no light when aimed off screen")
+	}
+}
+
+func TestZapper_Trigger(t *testing.T) {
+	z := NewZapper()
+	if z.Read()&zapperTriggerBit != 0 {
+		t.Error("Expected trigger released initially")
+	}
+
+	z.SetTrigger(true)
+	if z.Read()&zapperTriggerBit == 0 {
+		t.Error("Expected trigger bit set while pulled")
+	}
+
+	z.Reset()
+	if z.Read()&zapperTriggerBit != 0 {
+		t.Error("Expected reset to release the trigger")
+	}
+}
+
+func TestInputState_Port2Device(t *testing.T) {
+	is := NewInputState()
+	z := NewZapper()
+	z.SetTrigger(true)
+	is.SetPort2Device(z)
+
+	// Trigger pulled, no light, open bus bit 6
+	expected := uint8(0x40 | zapperTriggerBit | zapperLightBit)
+	if value := is.Read(0x4017); value != expected {
+		t.Errorf("Expected $4017 = 0x%02X, got 0x%02X", expected, value)
+	}
+
+	is.SetPort2Device(nil)
+	if value := is.Read(0x4017); value != 0x40 {
+		t.Errorf("Expected controller 2 read 0x40 after unplugging, got 0x%02X", value)
+	}
+}
@@ -0,0 +1,170 @@
+// Package library identifies ROMs by hash against a No-Intro style
+// database and keeps the ROMs found in the library directories, with their
+// hashes and titles, in a cache file.
+package library
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Game is a database entry
+type Game struct {
+	Title  string // Such as "Super Mario Bros. (World)"
+	Region string // Such as "USA" or "USA, Europe", "" when the title has none
+	CRC32  string // Of the ROM without its iNES header, uppercase hex
+	SHA1   string // Of the ROM without its iNES header, uppercase hex
+}
+
+// Database identifies ROMs by the hashes of their data
+type Database struct {
+	bySHA1  map[string]Game
+	byCRC32 map[string]Game
+}
+
+// NewDatabase returns an empty database
+func NewDatabase() *Database {
+	return &Database{bySHA1: make(map[string]Game), byCRC32: make(map[string]Game)}
+}
+
+// LoadDatabase reads a Logiqx XML DAT file, such as No-Intro's headerless
+// NES DAT, or every .dat and .xml file in a directory
+func LoadDatabase(path string) (*Database, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (ext == ".dat" || ext == ".xml") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	db := NewDatabase()
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		games, err := ParseDAT(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		db.Add(games...)
+	}
+	return db, nil
+}
+
+// datFile is the part of a Logiqx XML DAT the database reads
+type datFile struct {
+	Games []struct {
+		Name string `xml:"name,attr"`
+		ROMs []struct {
+			CRC  string `xml:"crc,attr"`
+			SHA1 string `xml:"sha1,attr"`
+		} `xml:"rom"`
+	} `xml:"game"`
+}
+
+// ParseDAT reads the games of a Logiqx XML DAT. A game with several ROMs
+// gives an entry for each.
+func ParseDAT(r io.Reader) ([]Game, error) {
+	var dat datFile
+	if err := xml.NewDecoder(r).Decode(&dat); err != nil {
+		return nil, fmt.Errorf("invalid DAT: %v", err)
+	}
+	var games []Game
+	for _, game := range dat.Games {
+		for _, rom := range game.ROMs {
+			if rom.CRC == "" && rom.SHA1 == "" {
+				continue
+			}
+			games = append(games, Game{
+				Title:  game.Name,
+				Region: Region(game.Name),
+				CRC32:  strings.ToUpper(rom.CRC),
+				SHA1:   strings.ToUpper(rom.SHA1),
+			})
+		}
+	}
+	return games, nil
+}
+
+// Add adds games to the database
+func (db *Database) Add(games ...Game) {
+	for _, game := range games {
+		if game.SHA1 != "" {
+			db.bySHA1[game.SHA1] = game
+		}
+		if game.CRC32 != "" {
+			db.byCRC32[game.CRC32] = game
+		}
+	}
+}
+
+// Len returns the number of games with a SHA-1
+func (db *Database) Len() int {
+	return len(db.bySHA1)
+}
+
+// Lookup finds the game with a ROM's hashes. The SHA-1 decides when both
+// the ROM and the entry have one; the CRC32 is for DATs without.
+func (db *Database) Lookup(crc32, sha1 string) (Game, bool) {
+	if game, ok := db.bySHA1[strings.ToUpper(sha1)]; ok {
+		return game, true
+	}
+	game, ok := db.byCRC32[strings.ToUpper(crc32)]
+	if ok && game.SHA1 != "" && sha1 != "" {
+		return Game{}, false // A CRC32 collision
+	}
+	return game, ok
+}
+
+// regions are the region names of No-Intro titles
+var regions = map[string]bool{
+	"World": true, "USA": true, "Japan": true, "Europe": true, "Asia": true,
+	"Australia": true, "Brazil": true, "Canada": true, "China": true, "France": true,
+	"Germany": true, "Hong Kong": true, "Italy": true, "Korea": true, "Netherlands": true,
+	"Russia": true, "Scandinavia": true, "Spain": true, "Sweden": true, "Taiwan": true,
+	"UK": true,
+}
+
+// Region returns the region tag of a No-Intro title, such as "USA" for
+// "Contra (USA)" or "USA, Europe" for "Tetris (USA, Europe)", or "" when it
+// has none
+func Region(title string) string {
+	for rest := title; ; {
+		open := strings.Index(rest, "(")
+		if open < 0 {
+			return ""
+		}
+		end := strings.Index(rest[open:], ")")
+		if end < 0 {
+			return ""
+		}
+		tag := rest[open+1 : open+end]
+		rest = rest[open+end+1:]
+
+		known := true
+		for _, name := range strings.Split(tag, ",") {
+			known = known && regions[strings.TrimSpace(name)]
+		}
+		if known {
+			return tag
+		}
+	}
+}
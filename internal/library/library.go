@@ -0,0 +1,219 @@
+package library
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gones/internal/cartridge"
+)
+
+// Entry is a ROM found in the library
+type Entry struct {
+	Path    string    `json:"path"` // Absolute
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	CRC32   string    `json:"crc32"`
+	SHA1    string    `json:"sha1"`
+	Title   string    `json:"title,omitempty"` // From the database, "" when not identified
+	Region  string    `json:"region,omitempty"`
+}
+
+// Name returns the database title, or the file name without its extension
+// for ROMs the database does not have
+func (e Entry) Name() string {
+	if e.Title != "" {
+		return e.Title
+	}
+	name := filepath.Base(e.Path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// Hash returns the CRC32 and SHA-1 of a ROM file's data without the iNES
+// header and trainer, as No-Intro lists NES ROMs
+func Hash(data []byte) (crc string, sha string) {
+	if len(data) >= 16 && string(data[:4]) == "NES\x1A" {
+		skip := 16
+		if data[6]&0x04 != 0 {
+			skip += 512 // Trainer
+		}
+		data = data[min(skip, len(data)):]
+	}
+	return fmt.Sprintf("%08X", crc32.ChecksumIEEE(data)), fmt.Sprintf("%X", sha1.Sum(data))
+}
+
+// Library is the ROMs found by Scan. It is safe for concurrent use, so the
+// ROM browser can read it while a scan runs.
+type Library struct {
+	mu      sync.RWMutex
+	entries map[string]Entry // By path
+}
+
+// New returns an empty library
+func New() *Library {
+	return &Library{entries: make(map[string]Entry)}
+}
+
+// Load reads a library saved by Save. A missing file gives an empty library.
+func Load(path string) (*Library, error) {
+	l := New()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid library cache %s: %v", path, err)
+	}
+	for _, entry := range entries {
+		l.entries[entry.Path] = entry
+	}
+	return l, nil
+}
+
+// Save writes the library to a cache file
+func (l *Library) Save(path string) error {
+	data, err := json.MarshalIndent(l.Entries(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ScanResult counts what a scan found
+type ScanResult struct {
+	ROMs       int // Found in the directories
+	Hashed     int // New or changed since the last scan, so hashed again
+	Identified int // Found in the database
+}
+
+// Scan finds the ROMs under dirs, hashing those that are new or changed
+// since the last scan, and names them from db, which may be nil. Entries of
+// files that no longer exist are dropped. Directories that do not exist
+// are skipped.
+func (l *Library) Scan(dirs []string, db *Database) (ScanResult, error) {
+	var result ScanResult
+	found := make(map[string]Entry)
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == dir && os.IsNotExist(err) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() || !cartridge.IsROMFile(d.Name()) {
+				return nil
+			}
+			if abs, err := filepath.Abs(path); err == nil {
+				path = abs
+			}
+			if _, ok := found[path]; ok {
+				return nil // In two of the directories
+			}
+			entry, hashed, err := l.scanFile(path)
+			if err != nil {
+				return nil // Unreadable, so not in the library
+			}
+			if hashed {
+				result.Hashed++
+			}
+			found[path] = entry
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	for path, entry := range found {
+		entry.Title, entry.Region = "", ""
+		if db != nil {
+			if game, ok := db.Lookup(entry.CRC32, entry.SHA1); ok {
+				entry.Title, entry.Region = game.Title, game.Region
+				result.Identified++
+			}
+		}
+		found[path] = entry
+	}
+	result.ROMs = len(found)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for path := range l.entries {
+		if _, err := os.Stat(path); err != nil {
+			delete(l.entries, path)
+		}
+	}
+	for path, entry := range found {
+		l.entries[path] = entry
+	}
+	return result, nil
+}
+
+// scanFile returns the entry of a ROM, from the library when the file did
+// not change since it was hashed
+func (l *Library) scanFile(path string) (Entry, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	l.mu.RLock()
+	entry, ok := l.entries[path]
+	l.mu.RUnlock()
+	if ok && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+		return entry, false, nil
+	}
+
+	data, err := cartridge.ReadROM(path)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	entry = Entry{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+	entry.CRC32, entry.SHA1 = Hash(data)
+	return entry, true, nil
+}
+
+// Lookup returns the entry of a ROM file
+func (l *Library) Lookup(path string) (Entry, bool) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entry, ok := l.entries[path]
+	return entry, ok
+}
+
+// Entries returns the ROMs sorted by name
+func (l *Library) Entries() []Entry {
+	l.mu.RLock()
+	entries := make([]Entry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		entries = append(entries, entry)
+	}
+	l.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := strings.ToLower(entries[i].Name()), strings.ToLower(entries[j].Name())
+		if a != b {
+			return a < b
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
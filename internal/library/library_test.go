@@ -0,0 +1,150 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rom returns an iNES file whose data after the header is data
+func rom(data string) []byte {
+	return append([]byte("NES\x1A\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), data...)
+}
+
+func TestHash(t *testing.T) {
+	// The header is left out, so a headerless dump hashes the same
+	crc, sha := Hash(rom("hello"))
+	if crc != "3610A686" || sha != "AAF4C61DDCC5E8A2DABEDE0F3B482CD9AEA9434D" {
+		t.Errorf("Hash = %s %s", crc, sha)
+	}
+	if c, s := Hash([]byte("hello")); c != crc || s != sha {
+		t.Errorf("headerless Hash = %s %s, want %s %s", c, s, crc, sha)
+	}
+
+	trainer := rom(strings.Repeat("T", 512) + "hello")
+	trainer[6] |= 0x04
+	if c, _ := Hash(trainer); c != crc {
+		t.Errorf("Hash with a trainer = %s, want %s", c, crc)
+	}
+}
+
+const testDAT = `<?xml version="1.0"?>
+<datafile>
+	<header><name>Nintendo - Nintendo Entertainment System (Headerless)</name></header>
+	<game name="Hello (USA, Europe)">
+		<description>Hello (USA, Europe)</description>
+		<rom name="Hello (USA, Europe).nes" size="5" crc="3610a686" sha1="aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"/>
+	</game>
+	<game name="Goodbye (Japan) (Rev 1)">
+		<rom name="Goodbye (Japan) (Rev 1).nes" size="7" crc="0A0B0C0D"/>
+	</game>
+</datafile>`
+
+func TestParseDAT(t *testing.T) {
+	games, err := ParseDAT(strings.NewReader(testDAT))
+	if err != nil {
+		t.Fatalf("ParseDAT: %v", err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("%d games, want 2", len(games))
+	}
+	want := Game{Title: "Hello (USA, Europe)", Region: "USA, Europe", CRC32: "3610A686", SHA1: "AAF4C61DDCC5E8A2DABEDE0F3B482CD9AEA9434D"}
+	if games[0] != want {
+		t.Errorf("games[0] = %+v, want %+v", games[0], want)
+	}
+
+	db := NewDatabase()
+	db.Add(games...)
+	if game, ok := db.Lookup("3610A686", "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"); !ok || game.Title != want.Title {
+		t.Errorf("Lookup by SHA-1 = %+v, %v", game, ok)
+	}
+	if game, ok := db.Lookup("0a0b0c0d", "FFFF"); !ok || game.Region != "Japan" {
+		t.Errorf("Lookup by CRC32 = %+v, %v", game, ok)
+	}
+	if _, ok := db.Lookup("3610A686", "FFFF"); ok {
+		t.Error("a CRC32 match with another SHA-1 was accepted")
+	}
+
+	if _, err := ParseDAT(strings.NewReader("<datafile><game>")); err == nil {
+		t.Error("truncated DAT parsed")
+	}
+}
+
+func TestRegion(t *testing.T) {
+	for title, want := range map[string]string{
+		"Contra (USA)":                            "USA",
+		"Tetris (USA, Europe) (Rev 1)":            "USA, Europe",
+		"Zelda no Densetsu (Japan) (Disk Writer)": "Japan",
+		"Homebrew Game (PD)":                      "",
+		"No Tags":                                 "",
+	} {
+		if got := Region(title); got != want {
+			t.Errorf("Region(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	roms := filepath.Join(dir, "roms")
+	if err := os.MkdirAll(filepath.Join(roms, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	hello := filepath.Join(roms, "hello.nes")
+	other := filepath.Join(roms, "sub", "other.NES")
+	os.WriteFile(hello, rom("hello"), 0644)
+	os.WriteFile(other, rom("other"), 0644)
+	os.WriteFile(filepath.Join(roms, "notes.txt"), []byte("hello"), 0644)
+
+	db := NewDatabase()
+	games, _ := ParseDAT(strings.NewReader(testDAT))
+	db.Add(games...)
+
+	l := New()
+	result, err := l.Scan([]string{roms, filepath.Join(dir, "missing")}, db)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if result != (ScanResult{ROMs: 2, Hashed: 2, Identified: 1}) {
+		t.Errorf("Scan = %+v", result)
+	}
+	if entry, ok := l.Lookup(hello); !ok || entry.Name() != "Hello (USA, Europe)" || entry.Region != "USA, Europe" {
+		t.Errorf("Lookup(hello) = %+v, %v", entry, ok)
+	}
+	if entry, ok := l.Lookup(other); !ok || entry.Name() != "other" {
+		t.Errorf("Lookup(other) = %+v, %v", entry, ok)
+	}
+
+	// The cache keeps the hashes, so only changed files are read again
+	cache := filepath.Join(dir, "library.json")
+	if err := l.Save(cache); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	l, err = Load(cache)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	os.WriteFile(other, rom("changed"), 0644)
+	later := time.Now().Add(time.Second)
+	os.Chtimes(other, later, later)
+	os.Remove(hello)
+	result, err = l.Scan([]string{roms}, nil)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if result != (ScanResult{ROMs: 1, Hashed: 1}) {
+		t.Errorf("Scan after changes = %+v", result)
+	}
+	if _, ok := l.Lookup(hello); ok {
+		t.Error("deleted ROM still in the library")
+	}
+	if entries := l.Entries(); len(entries) != 1 || entries[0].CRC32 == "" {
+		t.Errorf("Entries = %+v", entries)
+	}
+
+	if _, err := Load(filepath.Join(dir, "none.json")); err != nil {
+		t.Errorf("Load of a missing cache: %v", err)
+	}
+}
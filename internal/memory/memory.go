@@ -22,6 +22,25 @@ type Memory struct {
 
 	// DMA callback
 	dmaCallback func(uint8)
+
+	// PRG read hook for cheat codes (nil when none are active)
+	prgReadHook func(address uint16, value uint8) uint8
+
+	// PRG access hook for the code/data logger (nil when not logging)
+	prgAccessHook func(address uint16)
+
+	// Register write hook for the event viewer (nil when not recording)
+	registerWriteHook func(address uint16, value uint8)
+
+	// Access hook for the trace logger (nil when not tracing)
+	accessHook func(address uint16, value uint8, write bool)
+
+	// Called before each register access, so the bus can bring the PPU and
+	// APU up to the CPU (nil when they always are)
+	syncHook func()
+
+	// Called before each controller port read (nil when none is set)
+	controllerReadHook func(address uint16)
 	
 	// Open bus - last value read from bus (for unmapped areas)
 	openBusValue uint8
@@ -29,10 +48,15 @@ type Memory struct {
 
 // PPUMemory represents the PPU's memory space for testing
 type PPUMemory struct {
-	vram       [0x1000]uint8 // 4KB VRAM (nametables)
+	vram       [0x1000]uint8 // Nametables: the console's 2KB, then the 2KB of four-screen cartridges
 	paletteRAM [32]uint8     // 32 bytes palette RAM
 	cartridge  CartridgeInterface
 	mirroring  MirrorMode
+	pages      [4]uint8 // VRAM page of each nametable with MirrorMapped, or NametablePageCartridge
+	nametables NametableCartridge // The cartridge, when it can provide nametables
+
+	// Pattern table access hook for the code/data logger (nil when not logging)
+	chrAccessHook func(address uint16, rendering bool)
 	
 	// Debug counters for palette analysis
 	debugFrameCount uint64
@@ -49,6 +73,7 @@ const (
 	MirrorSingleScreen0
 	MirrorSingleScreen1
 	MirrorFourScreen
+	MirrorMapped // Each nametable on the page the mapper picks (SetNametablePages)
 )
 
 // PPUInterface defines the interface for PPU register access
@@ -77,6 +102,28 @@ type CartridgeInterface interface {
 	WriteCHR(address uint16, value uint8)
 }
 
+// ExpansionCartridge is implemented by cartridges with registers or memory in
+// the expansion area ($4020-$5FFF), such as the NSF bank registers. Other
+// cartridges leave the area unmapped.
+type ExpansionCartridge interface {
+	// ReadExpansion returns the byte at address, or false to leave the
+	// address unmapped (open bus)
+	ReadExpansion(address uint16) (uint8, bool)
+	WriteExpansion(address uint16, value uint8)
+}
+
+// NametableCartridge is implemented by cartridges that can put memory of
+// their own, such as CHR ROM, in place of a nametable. It is used for the
+// nametables SetNametablePages puts on NametablePageCartridge.
+type NametableCartridge interface {
+	ReadNametable(nametable int, offset uint16) uint8
+	WriteNametable(nametable int, offset uint16, value uint8)
+}
+
+// NametablePageCartridge is the page of SetNametablePages for a nametable
+// the cartridge provides (see NametableCartridge)
+const NametablePageCartridge = 0x80
+
 // New creates a new Memory instance
 func New(ppu PPUInterface, apu APUInterface, cart CartridgeInterface) *Memory {
 	mem := &Memory{
@@ -102,6 +149,45 @@ func (m *Memory) SetDMACallback(callback func(uint8)) {
 	m.dmaCallback = callback
 }
 
+// SetPRGReadHook sets a function that may replace each byte read from PRG
+// ROM ($8000-$FFFF), as Game Genie codes do. nil removes it.
+func (m *Memory) SetPRGReadHook(hook func(address uint16, value uint8) uint8) {
+	m.prgReadHook = hook
+}
+
+// SetPRGAccessHook sets a function called with the address of every CPU read
+// of PRG ROM ($8000-$FFFF), opcode fetches included. nil removes it.
+func (m *Memory) SetPRGAccessHook(hook func(address uint16)) {
+	m.prgAccessHook = hook
+}
+
+// SetRegisterWriteHook sets a function called with every CPU write to a
+// register: PPU ($2000-$3FFF), APU and I/O ($4000-$401F) and cartridge
+// ($4020-$5FFF, $8000-$FFFF) writes, but not RAM or PRG RAM. nil removes it.
+func (m *Memory) SetRegisterWriteHook(hook func(address uint16, value uint8)) {
+	m.registerWriteHook = hook
+}
+
+// SetAccessHook sets a function called with every CPU read and write, after
+// reads and before writes take effect. nil removes it.
+func (m *Memory) SetAccessHook(hook func(address uint16, value uint8, write bool)) {
+	m.accessHook = hook
+}
+
+// SetSyncHook sets a function called before every CPU access that the PPU,
+// APU, controllers or mapper may see or answer: reads and writes of $2000-$5FFF
+// and writes to $8000-$FFFF. nil removes it.
+func (m *Memory) SetSyncHook(hook func()) {
+	m.syncHook = hook
+}
+
+// SetControllerReadHook sets a function called before each CPU read of
+// $4016 and $4017, after the sync hook, so the bus can have DMA disturb the
+// controllers. nil removes it.
+func (m *Memory) SetControllerReadHook(hook func(address uint16)) {
+	m.controllerReadHook = hook
+}
+
 // initializePowerUpRAM initializes RAM with realistic power-up patterns
 // Real NES RAM contains semi-random patterns on power-up, not all zeros
 func (m *Memory) initializePowerUpRAM() {
@@ -164,6 +250,9 @@ func (m *Memory) initializePowerUpRAM() {
 // Read reads a byte from the given address
 func (m *Memory) Read(address uint16) uint8 {
 	var value uint8
+	if m.syncHook != nil && address >= 0x2000 && address < 0x6000 {
+		m.syncHook()
+	}
 	
 	switch {
 	case address < 0x2000:
@@ -178,17 +267,17 @@ func (m *Memory) Read(address uint16) uint8 {
 	case address < 0x4020:
 		// APU and I/O registers
 		if address == 0x4015 {
-			// APU status register
-			value = m.apuRegisters.ReadStatus()
+			// APU status register; bit 5 is not driven and reads open bus
+			value = m.apuRegisters.ReadStatus()&^0x20 | m.openBusValue&0x20
 		} else if address == 0x4016 || address == 0x4017 {
-			// Controller registers
+			// Controller registers: the devices drive bits 0-4 and the rest
+			// is open bus, usually $40 from the high byte of the address
+			value = m.openBusValue & 0xE0
+			if m.controllerReadHook != nil {
+				m.controllerReadHook(address)
+			}
 			if m.inputSystem != nil {
-				value = m.inputSystem.Read(address)
-				// Debug log for controller reads (disabled for performance - uncomment if needed for debugging)
-				// fmt.Printf("[MEMORY_DEBUG] Controller read $%04X = $%02X\n", address, value)
-			} else {
-				// fmt.Printf("[MEMORY_DEBUG] Controller read $%04X = $00 (no input system)\n", address)
-				value = 0
+				value |= m.inputSystem.Read(address) & 0x1F
 			}
 		} else {
 			// Other APU/I/O registers are write-only, return open bus
@@ -205,13 +294,24 @@ func (m *Memory) Read(address uint16) uint8 {
 		}
 
 	case address < 0x8000:
-		// Cartridge expansion area ($4020-$5FFF) - unmapped, return open bus
+		// Cartridge expansion area ($4020-$5FFF) - open bus unless mapped
 		value = m.openBusValue
+		if expansion, ok := m.cartridge.(ExpansionCartridge); ok {
+			if mapped, ok := expansion.ReadExpansion(address); ok {
+				value = mapped
+			}
+		}
 
 	default:
 		// PRG ROM ($8000-$FFFF)
 		if m.cartridge != nil {
 			value = m.cartridge.ReadPRG(address)
+			if m.prgReadHook != nil {
+				value = m.prgReadHook(address, value)
+			}
+			if m.prgAccessHook != nil {
+				m.prgAccessHook(address)
+			}
 		} else {
 			// No cartridge, return open bus
 			value = m.openBusValue
@@ -219,13 +319,30 @@ func (m *Memory) Read(address uint16) uint8 {
 	}
 	
 	// Update open bus value with the value that was read
-	// This simulates the NES behavior where the last value on the bus "lingers"
-	m.openBusValue = value
+	// This simulates the NES behavior where the last value on the bus "lingers".
+	// $4015 is inside the CPU, so reading it leaves the external bus alone.
+	if address != 0x4015 {
+		m.openBusValue = value
+	}
+	if m.accessHook != nil {
+		m.accessHook(address, value, false)
+	}
 	return value
 }
 
 // Write writes a byte to the given address
 func (m *Memory) Write(address uint16, value uint8) {
+	register := address >= 0x2000 && (address < 0x6000 || address >= 0x8000)
+	if register && m.syncHook != nil {
+		m.syncHook()
+	}
+	if m.accessHook != nil {
+		m.accessHook(address, value, true)
+	}
+	if register && m.registerWriteHook != nil {
+		m.registerWriteHook(address, value)
+	}
+
 	switch {
 	case address < 0x2000:
 		// Internal RAM (mirrored)
@@ -276,7 +393,10 @@ func (m *Memory) Write(address uint16, value uint8) {
 		}
 
 	case address < 0x8000:
-		// Cartridge expansion area ($4020-$5FFF) - unmapped, ignore writes
+		// Cartridge expansion area ($4020-$5FFF) - ignored unless mapped
+		if expansion, ok := m.cartridge.(ExpansionCartridge); ok {
+			expansion.WriteExpansion(address, value)
+		}
 
 	default:
 		// PRG ROM ($8000-$FFFF) (some mappers allow writes)
@@ -286,6 +406,41 @@ func (m *Memory) Write(address uint16, value uint8) {
 	}
 }
 
+// Peek reads a byte for debugging without the side effects of Read: the open
+// bus value is left alone and I/O registers, whose reads change state (the
+// PPU status flags, the controller shift registers), are not read. It returns
+// false for addresses that cannot be peeked.
+func (m *Memory) Peek(address uint16) (uint8, bool) {
+	switch {
+	case address < 0x2000:
+		return m.ram[address&0x07FF], true
+	case address >= 0x6000 && m.cartridge != nil:
+		value := m.cartridge.ReadPRG(address)
+		if address >= 0x8000 && m.prgReadHook != nil {
+			value = m.prgReadHook(address, value)
+		}
+		return value, true
+	default:
+		return 0, false
+	}
+}
+
+// Poke writes a byte for debugging. Only internal RAM and PRG RAM
+// ($6000-$7FFF) can be poked; writes elsewhere would reach registers or
+// switch mapper banks, so Poke returns false for them.
+func (m *Memory) Poke(address uint16, value uint8) bool {
+	switch {
+	case address < 0x2000:
+		m.ram[address&0x07FF] = value
+		return true
+	case address >= 0x6000 && address < 0x8000 && m.cartridge != nil:
+		m.cartridge.WritePRG(address, value)
+		return true
+	default:
+		return false
+	}
+}
+
 // performOAMDMA performs OAM DMA transfer
 func (m *Memory) performOAMDMA(page uint8) {
 	// Copy 256 bytes from CPU page to OAM
@@ -302,6 +457,7 @@ func NewPPUMemory(cart CartridgeInterface, mirroring MirrorMode) *PPUMemory {
 		cartridge: cart,
 		mirroring: mirroring,
 	}
+	mem.nametables, _ = cart.(NametableCartridge)
 	
 	// Initialize palette RAM with proper default values
 	// Background color positions (0x00, 0x04, 0x08, 0x0C) should be black (0x0F)
@@ -312,13 +468,70 @@ func NewPPUMemory(cart CartridgeInterface, mirroring MirrorMode) *PPUMemory {
 	return mem
 }
 
+// SetMirroring switches the nametable mirroring, which mappers such as the
+// MMC1 and AxROM do while a game runs. VRAM is kept, so the nametables show
+// what they held in the new arrangement.
+func (pm *PPUMemory) SetMirroring(mode MirrorMode) {
+	pm.mirroring = mode
+}
+
+// SetNametablePages puts each nametable on a 1KB page of VRAM, for mappers
+// that drive the VRAM's A10 line themselves, such as TxSROM, or on the
+// cartridge's own memory with NametablePageCartridge. The mirroring becomes
+// MirrorMapped until SetMirroring sets a mode.
+func (pm *PPUMemory) SetNametablePages(pages [4]uint8) {
+	pm.mirroring = MirrorMapped
+	for i, page := range pages {
+		if page&NametablePageCartridge != 0 && pm.nametables != nil {
+			pm.pages[i] = NametablePageCartridge
+		} else {
+			pm.pages[i] = page & 3
+		}
+	}
+}
+
+// Mirroring returns the nametable mirroring
+func (pm *PPUMemory) Mirroring() MirrorMode {
+	return pm.mirroring
+}
+
+// SetCHRAccessHook sets a function called with the address of every pattern
+// table read, with rendering set for the PPU's own fetches and clear for
+// PPUDATA ($2007) reads by the CPU. nil removes it.
+func (pm *PPUMemory) SetCHRAccessHook(hook func(address uint16, rendering bool)) {
+	pm.chrAccessHook = hook
+}
+
 // Read reads from PPU memory space ($0000-$3FFF)
 func (pm *PPUMemory) Read(address uint16) uint8 {
+	return pm.read(address, true)
+}
+
+// ReadData reads from PPU memory space for PPUDATA ($2007). It only differs
+// from Read in what it reports to the CHR access hook.
+func (pm *PPUMemory) ReadData(address uint16) uint8 {
+	return pm.read(address, false)
+}
+
+// Peek reads from PPU memory space for debugging, without reporting pattern
+// table reads to the CHR access hook
+func (pm *PPUMemory) Peek(address uint16) uint8 {
+	if address&0x3FFF < 0x2000 {
+		return pm.cartridge.ReadCHR(address & 0x3FFF)
+	}
+	return pm.read(address, true)
+}
+
+// read reads from PPU memory space, for rendering or for PPUDATA
+func (pm *PPUMemory) read(address uint16, rendering bool) uint8 {
 	address &= 0x3FFF // Mask to 14-bit address space
 
 	switch {
 	case address < 0x2000:
 		// Pattern Tables ($0000-$1FFF) - CHR ROM/RAM
+		if pm.chrAccessHook != nil {
+			pm.chrAccessHook(address, rendering)
+		}
 		return pm.cartridge.ReadCHR(address)
 
 	case address < 0x3000:
@@ -368,16 +581,33 @@ func (pm *PPUMemory) Write(address uint16, value uint8) {
 
 // readNametable reads from nametable with mirroring
 func (pm *PPUMemory) readNametable(address uint16) uint8 {
+	if nametable, ok := pm.cartridgeNametable(address); ok {
+		return pm.nametables.ReadNametable(nametable, address&0x3FF)
+	}
 	index := pm.getNametableIndex(address)
 	return pm.vram[index]
 }
 
 // writeNametable writes to nametable with mirroring
 func (pm *PPUMemory) writeNametable(address uint16, value uint8) {
+	if nametable, ok := pm.cartridgeNametable(address); ok {
+		pm.nametables.WriteNametable(nametable, address&0x3FF, value)
+		return
+	}
 	index := pm.getNametableIndex(address)
 	pm.vram[index] = value
 }
 
+// cartridgeNametable returns the nametable of an address and whether the
+// cartridge provides it
+func (pm *PPUMemory) cartridgeNametable(address uint16) (int, bool) {
+	if pm.mirroring != MirrorMapped {
+		return 0, false
+	}
+	nametable := int(address>>10) & 3
+	return nametable, pm.pages[nametable] == NametablePageCartridge
+}
+
 // getNametableIndex calculates the actual VRAM index based on mirroring mode
 func (pm *PPUMemory) getNametableIndex(address uint16) uint16 {
 	address &= 0x0FFF                // Keep only nametable bits
@@ -413,6 +643,9 @@ func (pm *PPUMemory) getNametableIndex(address uint16) uint16 {
 		// Each nametable has its own 1KB (requires 4KB VRAM)
 		return uint16(nametable)*0x400 + offset
 
+	case MirrorMapped:
+		return uint16(pm.pages[nametable]&3)*0x400 + offset
+
 	default:
 		return offset
 	}
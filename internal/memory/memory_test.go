@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -30,6 +31,7 @@ func (m *MockPPU) WriteRegister(address uint16, value uint8) {
 type MockAPU struct {
 	registers  [0x18]uint8
 	writeCalls []RegisterWrite
+	status     uint8
 }
 
 func (m *MockAPU) WriteRegister(address uint16, value uint8) {
@@ -40,7 +42,7 @@ func (m *MockAPU) WriteRegister(address uint16, value uint8) {
 }
 
 func (m *MockAPU) ReadStatus() uint8 {
-	return 0x00 // Mock implementation
+	return m.status
 }
 
 // MockCartridge implements CartridgeInterface for testing
@@ -406,3 +408,246 @@ func TestMemory_UnmappedRegions(t *testing.T) {
 		})
 	}
 }
+
+func TestMemory_PeekHasNoSideEffects(t *testing.T) {
+	ppu := &MockPPU{}
+	apu := &MockAPU{}
+	cart := &MockCartridge{}
+	mem := New(ppu, apu, cart)
+
+	mem.Write(0x0042, 0x99)
+	cart.prgData[0x0010] = 0x77
+	mem.Read(0x8010) // Leaves 0x77 on the open bus
+
+	if value, ok := mem.Peek(0x0842); !ok || value != 0x99 {
+		t.Errorf("Peek($0842) = $%02X, %v, want $99, true", value, ok)
+	}
+	if value, ok := mem.Peek(0x8010); !ok || value != 0x77 {
+		t.Errorf("Peek($8010) = $%02X, %v, want $77, true", value, ok)
+	}
+	if _, ok := mem.Peek(0x2002); ok {
+		t.Error("Peek($2002) should not read the PPU status register")
+	}
+	if len(ppu.readCalls) != 0 {
+		t.Errorf("Peek read PPU registers: %v", ppu.readCalls)
+	}
+
+	mem.Write(0x4018, 0x00)
+	if mem.Read(0x4018) != 0x77 {
+		t.Error("Peek changed the open bus value")
+	}
+}
+
+func TestMemory_PokeOnlyWritesRAM(t *testing.T) {
+	ppu := &MockPPU{}
+	apu := &MockAPU{}
+	cart := &MockCartridge{}
+	mem := New(ppu, apu, cart)
+
+	if !mem.Poke(0x1801, 0x5A) || mem.Read(0x0001) != 0x5A {
+		t.Error("Poke should write mirrored internal RAM")
+	}
+	if !mem.Poke(0x6000, 0x12) {
+		t.Error("Poke should write PRG RAM")
+	}
+	if mem.Poke(0x2000, 0x80) || len(ppu.writeCalls) != 0 {
+		t.Error("Poke should not write PPU registers")
+	}
+	if mem.Poke(0x8000, 0x01) || cart.prgData[0] != 0 {
+		t.Error("Poke should not write to the PRG ROM area")
+	}
+}
+
+func TestMemory_PRGReadHook(t *testing.T) {
+	ppu := &MockPPU{}
+	apu := &MockAPU{}
+	cart := &MockCartridge{}
+	mem := New(ppu, apu, cart)
+	cart.prgData[0x1234] = 0xCE
+
+	mem.SetPRGReadHook(func(address uint16, value uint8) uint8 {
+		if address == 0x9234 {
+			return 0xAD
+		}
+		return value
+	})
+	if got := mem.Read(0x9234); got != 0xAD {
+		t.Errorf("Read($9234) = $%02X, want the hooked $AD", got)
+	}
+	if got, _ := mem.Peek(0x9234); got != 0xAD {
+		t.Errorf("Peek($9234) = $%02X, want the hooked $AD", got)
+	}
+	if got := mem.Read(0x9235); got != 0x00 {
+		t.Errorf("Read($9235) = $%02X, want $00", got)
+	}
+
+	mem.SetPRGReadHook(nil)
+	if got := mem.Read(0x9234); got != 0xCE {
+		t.Errorf("Read($9234) = $%02X after removing the hook, want $CE", got)
+	}
+}
+
+func TestMemory_RegisterWriteHook(t *testing.T) {
+	mem := New(&MockPPU{}, &MockAPU{}, &MockCartridge{})
+
+	var written []uint16
+	mem.SetRegisterWriteHook(func(address uint16, value uint8) {
+		written = append(written, address)
+	})
+	for _, address := range []uint16{0x0000, 0x2005, 0x3FFF, 0x4015, 0x5000, 0x6000, 0x7FFF, 0x8000} {
+		mem.Write(address, 0x01)
+	}
+
+	want := []uint16{0x2005, 0x3FFF, 0x4015, 0x5000, 0x8000}
+	if len(written) != len(want) {
+		t.Fatalf("hook saw %04X, want %04X", written, want)
+	}
+	for i := range want {
+		if written[i] != want[i] {
+			t.Errorf("hook saw %04X, want %04X", written, want)
+			break
+		}
+	}
+}
+
+func TestMemory_AccessHook(t *testing.T) {
+	mem := New(&MockPPU{}, &MockAPU{}, &MockCartridge{})
+
+	type access struct {
+		address uint16
+		value   uint8
+		write   bool
+	}
+	var seen []access
+	mem.SetAccessHook(func(address uint16, value uint8, write bool) {
+		seen = append(seen, access{address, value, write})
+	})
+	mem.Write(0x0010, 0x42)
+	mem.Read(0x0010)
+	if _, ok := mem.Peek(0x0010); !ok {
+		t.Fatal("Peek($0010) failed")
+	}
+
+	want := []access{{0x0010, 0x42, true}, {0x0010, 0x42, false}}
+	if len(seen) != len(want) || seen[0] != want[0] || seen[1] != want[1] {
+		t.Errorf("hook saw %+v, want %+v (Peek is not an access)", seen, want)
+	}
+}
+
+// expansionCartridge maps a register at $5000
+type expansionCartridge struct {
+	MockCartridge
+	register uint8
+}
+
+func (c *expansionCartridge) ReadExpansion(address uint16) (uint8, bool) {
+	if address != 0x5000 {
+		return 0, false
+	}
+	return c.register, true
+}
+
+func (c *expansionCartridge) WriteExpansion(address uint16, value uint8) {
+	if address == 0x5000 {
+		c.register = value
+	}
+}
+
+func TestMemory_ExpansionArea(t *testing.T) {
+	cart := &expansionCartridge{}
+	mem := New(&MockPPU{}, &MockAPU{}, cart)
+
+	mem.Write(0x5000, 0x42)
+	if cart.register != 0x42 {
+		t.Errorf("expansion register = $%02X, want $42", cart.register)
+	}
+	if value := mem.Read(0x5000); value != 0x42 {
+		t.Errorf("Read($5000) = $%02X, want $42", value)
+	}
+	// Unmapped addresses stay open bus
+	if value := mem.Read(0x4100); value != 0x42 {
+		t.Errorf("Read($4100) = $%02X, want the open bus value $42", value)
+	}
+
+	// Cartridges without expansion hardware ignore the area
+	plain := New(&MockPPU{}, &MockAPU{}, &MockCartridge{})
+	plain.Write(0x5000, 0x42)
+	if value := plain.Read(0x5000); value != 0x00 {
+		t.Errorf("Read($5000) without expansion = $%02X, want open bus $00", value)
+	}
+}
+
+// portInput is an input system whose ports return a fixed value
+type portInput struct {
+	value uint8
+}
+
+func (p *portInput) Read(address uint16) uint8 { return p.value }
+
+func (p *portInput) Write(address uint16, value uint8) {}
+
+func TestMemory_OpenBusRegisterBits(t *testing.T) {
+	apu := &MockAPU{}
+	cart := &MockCartridge{}
+	mem := New(&MockPPU{}, apu, cart)
+	input := &portInput{}
+	mem.SetInputSystem(input)
+
+	// LDA $4016 leaves the high byte of the address on the bus
+	for _, tc := range []struct {
+		bus, port, want uint8
+	}{
+		{0x40, 0x01, 0x41},
+		{0x40, 0xFF, 0x5F}, // Only bits 0-4 come from the device
+		{0xFF, 0x00, 0xE0},
+	} {
+		cart.prgData[0x0010] = tc.bus
+		input.value = tc.port
+		for _, address := range []uint16{0x4016, 0x4017} {
+			mem.Read(0x8010)
+			if value := mem.Read(address); value != tc.want {
+				t.Errorf("Read($%04X) with $%02X on the bus and $%02X from the port = $%02X, want $%02X",
+					address, tc.bus, tc.port, value, tc.want)
+			}
+		}
+	}
+
+	// Bit 5 of $4015 is open bus, and the read does not reach the bus
+	cart.prgData[0x0010] = 0x20
+	apu.status = 0xC1
+	mem.Read(0x8010)
+	if value := mem.Read(0x4015); value != 0xE1 {
+		t.Errorf("Read($4015) = $%02X, want $E1", value)
+	}
+	if value := mem.Read(0x4018); value != 0x20 {
+		t.Errorf("open bus after reading $4015 = $%02X, want $20", value)
+	}
+	apu.status = 0x20
+	cart.prgData[0x0010] = 0x00
+	mem.Read(0x8010)
+	if value := mem.Read(0x4015); value != 0x00 {
+		t.Errorf("Read($4015) with bit 5 set by the APU = $%02X, want $00", value)
+	}
+}
+
+func TestMemory_ControllerReadHook(t *testing.T) {
+	mem := New(&MockPPU{}, &MockAPU{}, &MockCartridge{})
+	input := &portInput{}
+	mem.SetInputSystem(input)
+
+	var reads []uint16
+	mem.SetControllerReadHook(func(address uint16) {
+		reads = append(reads, address)
+		input.value = 0x01 // Seen by the read that follows
+	})
+	for _, address := range []uint16{0x4015, 0x4016, 0x4017, 0x4018, 0x0016} {
+		value := mem.Read(address)
+		if (address == 0x4016 || address == 0x4017) && value&0x01 == 0 {
+			t.Errorf("Read($%04X) = $%02X, want the port's value set by the hook", address, value)
+		}
+		input.value = 0
+	}
+	if !reflect.DeepEqual(reads, []uint16{0x4016, 0x4017}) {
+		t.Errorf("hook called for %04X, want $4016 and $4017", reads)
+	}
+}
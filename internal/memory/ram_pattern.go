@@ -0,0 +1,81 @@
+// Package memory implements the patterns internal RAM is filled with at power
+// on, since some games behave differently depending on what uninitialized RAM
+// holds.
+package memory
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// RAMPattern is what internal RAM holds at power on
+type RAMPattern uint8
+
+const (
+	RAMPatternMixed       RAMPattern = iota // The mix of patterns seen on hardware (see initializePowerUpRAM)
+	RAMPatternZero                          // All $00
+	RAMPatternFF                            // All $FF
+	RAMPatternAlternating                   // $00 and $FF pages alternating, starting with $00
+	RAMPatternRandom                        // Random bytes from a seed
+)
+
+// ramPatternNames are the names of each pattern
+var ramPatternNames = [...]string{
+	RAMPatternMixed:       "mixed",
+	RAMPatternZero:        "zero",
+	RAMPatternFF:          "ff",
+	RAMPatternAlternating: "alternating",
+	RAMPatternRandom:      "random",
+}
+
+// RAMPatternNames returns the names ParseRAMPattern accepts
+func RAMPatternNames() []string {
+	return ramPatternNames[:]
+}
+
+// String returns the name of the pattern
+func (p RAMPattern) String() string {
+	if int(p) >= len(ramPatternNames) {
+		return fmt.Sprintf("RAMPattern(%d)", uint8(p))
+	}
+	return ramPatternNames[p]
+}
+
+// ParseRAMPattern parses a pattern name such as "zero" or "Random"
+func ParseRAMPattern(name string) (RAMPattern, error) {
+	for p, patternName := range ramPatternNames {
+		if strings.EqualFold(strings.TrimSpace(name), patternName) {
+			return RAMPattern(p), nil
+		}
+	}
+	return RAMPatternMixed, fmt.Errorf("unknown RAM pattern %q (use %s)", name, strings.Join(ramPatternNames[:], ", "))
+}
+
+// FillRAM fills internal RAM with a power-on pattern. seed picks the bytes of
+// RAMPatternRandom, the same ones for the same seed; the other patterns
+// ignore it.
+func (m *Memory) FillRAM(pattern RAMPattern, seed int64) {
+	switch pattern {
+	case RAMPatternZero:
+		clear(m.ram[:])
+	case RAMPatternFF:
+		for i := range m.ram {
+			m.ram[i] = 0xFF
+		}
+	case RAMPatternAlternating:
+		for i := range m.ram {
+			m.ram[i] = 0x00
+			if (i>>8)%2 == 1 {
+				m.ram[i] = 0xFF
+			}
+		}
+	case RAMPatternRandom:
+		random := rand.New(rand.NewPCG(uint64(seed), 0))
+		for i := range m.ram {
+			m.ram[i] = uint8(random.Uint32())
+		}
+	default:
+		m.initializePowerUpRAM()
+	}
+}
@@ -0,0 +1,55 @@
+package memory
+
+import "testing"
+
+func TestParseRAMPattern(t *testing.T) {
+	for name, want := range map[string]RAMPattern{
+		"mixed": RAMPatternMixed, "Zero": RAMPatternZero, "FF": RAMPatternFF,
+		" alternating ": RAMPatternAlternating, "random": RAMPatternRandom,
+	} {
+		if got, err := ParseRAMPattern(name); err != nil || got != want {
+			t.Errorf("ParseRAMPattern(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseRAMPattern("checkerboard"); err == nil {
+		t.Error("ParseRAMPattern(checkerboard) succeeded")
+	}
+}
+
+func TestFillRAM(t *testing.T) {
+	mem := New(&MockPPU{}, &MockAPU{}, &MockCartridge{})
+
+	for pattern, want := range map[RAMPattern]func(address uint16) uint8{
+		RAMPatternZero: func(uint16) uint8 { return 0x00 },
+		RAMPatternFF:   func(uint16) uint8 { return 0xFF },
+		RAMPatternAlternating: func(address uint16) uint8 {
+			if address&0x100 != 0 {
+				return 0xFF
+			}
+			return 0x00
+		},
+	} {
+		mem.FillRAM(pattern, 0)
+		for address := uint16(0); address < 0x800; address++ {
+			if value := mem.Read(address); value != want(address) {
+				t.Errorf("%v: RAM[$%03X] = $%02X, want $%02X", pattern, address, value, want(address))
+				break
+			}
+		}
+	}
+}
+
+func TestFillRAM_RandomSeed(t *testing.T) {
+	fill := func(seed int64) [0x800]uint8 {
+		mem := New(&MockPPU{}, &MockAPU{}, &MockCartridge{})
+		mem.FillRAM(RAMPatternRandom, seed)
+		return mem.ram
+	}
+
+	if fill(42) != fill(42) {
+		t.Error("the same seed filled RAM differently")
+	}
+	if fill(42) == fill(43) {
+		t.Error("different seeds filled RAM alike")
+	}
+}
@@ -0,0 +1,39 @@
+// Package memory implements memory state snapshots.
+package memory
+
+import "gones/internal/savestate"
+
+// SaveState writes CPU internal RAM and open bus state
+func (m *Memory) SaveState(w *savestate.Writer) {
+	w.WriteTag("RAM ")
+	w.WriteBytes(m.ram[:])
+	w.WriteU8(m.openBusValue)
+}
+
+// LoadState restores state written by SaveState
+func (m *Memory) LoadState(r *savestate.Reader) error {
+	r.ExpectTag("RAM ")
+	r.ReadBytesInto(m.ram[:])
+	m.openBusValue = r.ReadU8()
+	return r.Err()
+}
+
+// SaveState writes nametable VRAM, palette RAM, the mirroring mode and the
+// pages of MirrorMapped
+func (pm *PPUMemory) SaveState(w *savestate.Writer) {
+	w.WriteTag("VRAM")
+	w.WriteBytes(pm.vram[:])
+	w.WriteBytes(pm.paletteRAM[:])
+	w.WriteU8(uint8(pm.mirroring))
+	w.WriteBytes(pm.pages[:])
+}
+
+// LoadState restores state written by SaveState
+func (pm *PPUMemory) LoadState(r *savestate.Reader) error {
+	r.ExpectTag("VRAM")
+	r.ReadBytesInto(pm.vram[:])
+	r.ReadBytesInto(pm.paletteRAM[:])
+	pm.mirroring = MirrorMode(r.ReadU8())
+	r.ReadBytesInto(pm.pages[:])
+	return r.Err()
+}
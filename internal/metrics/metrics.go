@@ -0,0 +1,158 @@
+// Package metrics provides the Prometheus text exposition format, for
+// monitoring long-running emulators, and a sliding window of samples to
+// report quantiles of, such as frame times.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric types
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+	Summary = "summary"
+)
+
+// Encoder writes metrics in the text exposition format. Write errors are
+// kept and reported by Err.
+type Encoder struct {
+	w   io.Writer
+	err error
+}
+
+// NewEncoder returns an encoder writing to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Header starts a metric family with its HELP and TYPE lines
+func (e *Encoder) Header(name, kind, help string) {
+	e.printf("# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+}
+
+// Value writes a sample. Labels are given as name, value pairs.
+func (e *Encoder) Value(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(labels[i])
+			b.WriteString(`="`)
+			b.WriteString(escapeLabel(labels[i+1]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	e.printf("%s %s\n", b.String(), formatValue(value))
+}
+
+// Metric writes a family with a single unlabeled sample
+func (e *Encoder) Metric(name, kind, help string, value float64) {
+	e.Header(name, kind, help)
+	e.Value(name, value)
+}
+
+// Summary writes a summary family: the quantiles of window and the sum and
+// count of every observation
+func (e *Encoder) Summary(name, help string, window *Window, quantiles []float64, sum float64, count uint64) {
+	e.Header(name, Summary, help)
+	for i, value := range window.Quantiles(quantiles...) {
+		e.Value(name, value, "quantile", strconv.FormatFloat(quantiles[i], 'g', -1, 64))
+	}
+	e.Value(name+"_sum", sum)
+	e.Value(name+"_count", float64(count))
+}
+
+// Err returns the first write error
+func (e *Encoder) Err() error {
+	return e.err
+}
+
+func (e *Encoder) printf(format string, args ...any) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
+
+// formatValue formats a sample value, spelling out infinities and NaN
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+// Window keeps the last samples added, to report quantiles over recent
+// activity rather than the whole run
+type Window struct {
+	values []float64
+	next   int
+	full   bool
+}
+
+// NewWindow returns a window of size samples
+func NewWindow(size int) *Window {
+	return &Window{values: make([]float64, size)}
+}
+
+// Add adds a sample, dropping the oldest once the window is full
+func (w *Window) Add(value float64) {
+	w.values[w.next] = value
+	w.next++
+	if w.next == len(w.values) {
+		w.next = 0
+		w.full = true
+	}
+}
+
+// Len returns the number of samples in the window
+func (w *Window) Len() int {
+	if w.full {
+		return len(w.values)
+	}
+	return w.next
+}
+
+// Quantiles returns the samples at quantiles qs (0 to 1) by nearest rank,
+// or NaN for an empty window
+func (w *Window) Quantiles(qs ...float64) []float64 {
+	sorted := append([]float64(nil), w.values[:w.Len()]...)
+	sort.Float64s(sorted)
+
+	results := make([]float64, len(qs))
+	for i, q := range qs {
+		if len(sorted) == 0 {
+			results[i] = math.NaN()
+			continue
+		}
+		rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+		results[i] = sorted[max(0, min(rank, len(sorted)-1))]
+	}
+	return results
+}
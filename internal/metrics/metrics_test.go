@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	var b strings.Builder
+	e := NewEncoder(&b)
+	e.Metric("gones_fps", Gauge, "Frames per second.", 59.5)
+	e.Header("gones_component_seconds_total", Counter, "Time spent per component.")
+	e.Value("gones_component_seconds_total", 1.25, "component", "render")
+	e.Value("gones_info", 1, "rom", "a \"b\"\\c", "version", "1.0")
+
+	window := NewWindow(4)
+	for _, v := range []float64{0.02, 0.01, 0.03} {
+		window.Add(v)
+	}
+	e.Summary("gones_frame_time_seconds", "Frame time.", window, []float64{0.5, 0.99}, 0.06, 3)
+
+	want := `# HELP gones_fps Frames per second.
+# TYPE gones_fps gauge
+gones_fps 59.5
+# HELP gones_component_seconds_total Time spent per component.
+# TYPE gones_component_seconds_total counter
+gones_component_seconds_total{component="render"} 1.25
+gones_info{rom="a \"b\"\\c",version="1.0"} 1
+# HELP gones_frame_time_seconds Frame time.
+# TYPE gones_frame_time_seconds summary
+gones_frame_time_seconds{quantile="0.5"} 0.02
+gones_frame_time_seconds{quantile="0.99"} 0.03
+gones_frame_time_seconds_sum 0.06
+gones_frame_time_seconds_count 3
+`
+	if b.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	window := NewWindow(3)
+	if q := window.Quantiles(0.5); !math.IsNaN(q[0]) {
+		t.Errorf("empty window quantile = %v, want NaN", q[0])
+	}
+	for _, v := range []float64{5, 1, 2, 3} { // 5 drops out
+		window.Add(v)
+	}
+	if window.Len() != 3 {
+		t.Errorf("Len = %d, want 3", window.Len())
+	}
+	q := window.Quantiles(0, 0.5, 1)
+	if q[0] != 1 || q[1] != 2 || q[2] != 3 {
+		t.Errorf("quantiles = %v, want [1 2 3]", q)
+	}
+	if formatValue(math.Inf(1)) != "+Inf" || formatValue(math.NaN()) != "NaN" {
+		t.Error("special values not spelled out")
+	}
+}
@@ -0,0 +1,273 @@
+package netplay
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeMachine is a machine whose state depends on every input it ran
+type fakeMachine struct {
+	state   uint64
+	frames  int
+	powerOn int // Times powered on
+}
+
+func (m *fakeMachine) PowerOn() error {
+	m.state, m.frames = 1, 0
+	m.powerOn++
+	return nil
+}
+
+func (m *fakeMachine) SaveState() ([]byte, error) {
+	return binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, m.state), uint64(m.frames)), nil
+}
+
+func (m *fakeMachine) LoadState(data []byte) error {
+	if len(data) != 16 {
+		return errors.New("bad state")
+	}
+	m.state, m.frames = binary.LittleEndian.Uint64(data), int(binary.LittleEndian.Uint64(data[8:]))
+	return nil
+}
+
+func (m *fakeMachine) RunFrame(buttons [Players][8]bool) error {
+	m.state = m.state*1000003 + uint64(packButtons(buttons[0]))<<8 + uint64(packButtons(buttons[1]))
+	m.frames++
+	return nil
+}
+
+// inputAt returns a player's made-up input for a frame, changing now and then
+func inputAt(player, frame int) uint8 {
+	return uint8((frame/7 + player*3) % 5)
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	messages := []message{
+		{kind: msgHello, romHash: [32]byte{1, 2, 3}},
+		{kind: msgWelcome, settings: Options{InputDelay: 3, MaxRollback: 7, HashInterval: 600}},
+		{kind: msgReject, reason: "different ROM"},
+		{kind: msgInput, ack: 70, start: 65, inputs: []uint8{1, 0, 0x81}},
+		{kind: msgHash, frame: 120, hash: 0xDEADBEEF01},
+		{kind: msgBye},
+	}
+	for _, m := range messages {
+		got, err := decodeMessage(m.encode(nil))
+		if err != nil {
+			t.Errorf("type %d: %v", m.kind, err)
+			continue
+		}
+		if got.kind != m.kind || got.romHash != m.romHash || got.settings != m.settings || got.reason != m.reason ||
+			got.ack != m.ack || got.start != m.start || string(got.inputs) != string(m.inputs) ||
+			got.frame != m.frame || got.hash != m.hash {
+			t.Errorf("round trip of %+v gave %+v", m, got)
+		}
+	}
+
+	if _, err := decodeMessage([]byte("GNP\x00\x01")); err == nil {
+		t.Error("other protocol version accepted")
+	}
+	if _, err := decodeMessage((&message{kind: msgInput, inputs: []uint8{1, 2}}).encode(nil)[:14]); err == nil {
+		t.Error("truncated inputs accepted")
+	}
+	if buttons := unpackButtons(packButtons([8]bool{true, false, false, true, false, false, false, true})); buttons != [8]bool{true, false, false, true, false, false, false, true} {
+		t.Errorf("buttons round trip = %v", buttons)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	const frames, latency = 300, 5
+	options := Options{InputDelay: 2, MaxRollback: 8, HashInterval: 10}
+
+	// Reference: every frame run with both inputs known, and the hash of the
+	// state at the start of each frame
+	reference := &fakeMachine{}
+	reference.PowerOn()
+	want := make(map[int]uint64)
+	for frame := 0; frame < frames; frame++ {
+		state, _ := reference.SaveState()
+		want[frame] = stateHashOf(state)
+		var buttons [Players][8]bool
+		for player := range buttons {
+			if frame >= options.InputDelay {
+				buttons[player] = unpackButtons(inputAt(player, frame-options.InputDelay))
+			}
+		}
+		reference.RunFrame(buttons)
+	}
+
+	// Each side gets the other's inputs latency frames late. The hashes
+	// they send are of states whose inputs were all known, so they have to
+	// match the reference even though the frames ran on predictions.
+	var sides [Players]*rollback
+	for player := range sides {
+		m := &fakeMachine{}
+		m.PowerOn()
+		sides[player] = newRollback(m, player, options)
+	}
+	hashed := 0
+	for tick := 0; tick < frames+2*latency; tick++ {
+		for player, side := range sides {
+			peer := sides[1-player]
+			if delivered := tick - latency; delivered >= 0 && delivered < len(peer.inputs[peer.local]) {
+				side.addRemoteInputs(delivered, peer.inputs[peer.local][delivered:delivered+1])
+			}
+			if side.frame < frames && side.canAdvance() {
+				side.addLocalInput(inputAt(player, side.frame))
+				if err := side.advance(); err != nil {
+					t.Fatalf("player %d: %v", player+1, err)
+				}
+			}
+			for _, h := range side.outgoing {
+				if h.hash != want[h.frame] {
+					t.Errorf("player %d: frame %d hash differs from the reference", player+1, h.frame)
+				}
+				peer.addRemoteHash(h.frame, h.hash)
+				hashed++
+			}
+			side.outgoing = side.outgoing[:0]
+		}
+	}
+
+	for player, side := range sides {
+		if side.frame != frames {
+			t.Errorf("player %d ran %d frames, want %d", player+1, side.frame, frames)
+		}
+		if side.rollbacks == 0 {
+			t.Errorf("player %d never rolled back", player+1)
+		}
+		if side.desync >= 0 {
+			t.Errorf("player %d reported a desync at frame %d", player+1, side.desync)
+		}
+	}
+	if hashed < 2*(frames/options.HashInterval-2) {
+		t.Errorf("only %d hashes sent", hashed)
+	}
+}
+
+// stateHashOf hashes a state like rollback.hashState
+func stateHashOf(state []byte) uint64 {
+	sum := sha256.Sum256(state)
+	return binary.LittleEndian.Uint64(sum[:])
+}
+
+func TestRollbackDesync(t *testing.T) {
+	options := Options{MaxRollback: 2, HashInterval: 10}
+	a, b := &fakeMachine{}, &fakeMachine{}
+	a.PowerOn()
+	b.PowerOn()
+	b.state = 2 // Different power-on state
+	sides := [Players]*rollback{newRollback(a, 0, options), newRollback(b, 1, options)}
+	for frame := 0; frame < 30; frame++ {
+		for player, side := range sides {
+			side.addLocalInput(0)
+			sides[1-player].addRemoteInputs(frame, []uint8{0})
+		}
+		for player, side := range sides {
+			if err := side.advance(); err != nil {
+				t.Fatal(err)
+			}
+			for _, h := range side.outgoing {
+				sides[1-player].addRemoteHash(h.frame, h.hash)
+			}
+			side.outgoing = nil
+		}
+	}
+	if sides[0].desync != 10 || sides[1].desync != 10 {
+		t.Errorf("desync at frames %d and %d, want 10", sides[0].desync, sides[1].desync)
+	}
+}
+
+func TestLockstep(t *testing.T) {
+	m := &fakeMachine{}
+	m.PowerOn()
+	side := newRollback(m, 0, Options{HashInterval: 60})
+	if side.canAdvance() {
+		t.Fatal("lockstep advanced without the peer's input")
+	}
+	side.addRemoteInputs(0, []uint8{1, 2})
+	for i := 0; i < 2; i++ {
+		if !side.canAdvance() {
+			t.Fatalf("frame %d blocked with the peer's input known", i)
+		}
+		side.addLocalInput(0)
+		if err := side.advance(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if side.canAdvance() || side.rollbacks != 0 || m.frames != 2 {
+		t.Errorf("after 2 frames: can advance %v, %d rollbacks, %d frames run", side.canAdvance(), side.rollbacks, m.frames)
+	}
+}
+
+func TestSession(t *testing.T) {
+	rom := [32]byte{0x42}
+	host, err := Host("127.0.0.1:0", rom, Options{InputDelay: 1, MaxRollback: 4, HashInterval: 20})
+	if err != nil {
+		t.Fatalf("Host: %v", err)
+	}
+	defer host.Close()
+	guest, err := Join(host.Status().Address, rom)
+	if err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+
+	wrongROM, err := Join(host.Status().Address, [32]byte{0x43})
+	if err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+	defer wrongROM.Close()
+
+	const frames = 120
+	machines := [Players]*fakeMachine{{}, {}}
+	sessions := [Players]*Session{host, guest}
+	var rejected error
+	deadline := time.Now().Add(10 * time.Second)
+	for machines[0].frames < frames || machines[1].frames < frames {
+		if time.Now().After(deadline) {
+			t.Fatalf("ran %d and %d frames before the deadline; status %+v, %+v",
+				machines[0].frames, machines[1].frames, host.Status(), guest.Status())
+		}
+		for player, session := range sessions {
+			if machines[player].frames >= frames && session.Status().Connected {
+				continue
+			}
+			buttons := unpackButtons(inputAt(player, machines[player].frames))
+			if _, err := session.Update(machines[player], buttons); err != nil {
+				t.Fatalf("player %d: %v", player+1, err)
+			}
+		}
+		if rejected == nil {
+			_, rejected = wrongROM.Update(&fakeMachine{}, [8]bool{})
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	status := guest.Status()
+	if !status.Connected || status.Player != 2 || status.Options.MaxRollback != 4 || status.Desync >= 0 {
+		t.Errorf("guest status = %+v", status)
+	}
+	if machines[0].powerOn != 1 || machines[1].powerOn != 1 {
+		t.Errorf("powered on %d and %d times", machines[0].powerOn, machines[1].powerOn)
+	}
+	if rejected == nil {
+		t.Error("a guest with another ROM was not refused")
+	}
+
+	guest.Close()
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		if _, err := host.Update(machines[0], [8]bool{}); err != nil {
+			if err != ErrPeerLeft {
+				t.Errorf("host after the guest left: %v", err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("host did not notice the guest leaving")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,131 @@
+// Package netplay implements online two-player sessions: inputs exchanged
+// over UDP, rollback (or lockstep) synchronization built on the bus's
+// in-memory save states, and desync detection by comparing state hashes.
+package netplay
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// magic starts every packet, with the protocol version in its last byte
+var magic = [4]byte{'G', 'N', 'P', 1}
+
+// Message types
+const (
+	msgHello   uint8 = iota + 1 // Guest to host: asks to join
+	msgWelcome                  // Host to guest: the session settings
+	msgReject                   // Host to guest: why it cannot join
+	msgInput                    // Inputs from a frame on, and how many of the peer's arrived
+	msgHash                     // State hash of a frame
+	msgBye                      // The peer left
+)
+
+// maxInputsPerPacket bounds the inputs resent in one packet
+const maxInputsPerPacket = 255
+
+// errShortPacket is returned for truncated packets
+var errShortPacket = errors.New("short packet")
+
+// message is a decoded packet. Fields are used by the types that need them.
+type message struct {
+	kind     uint8
+	romHash  [32]byte // Hello
+	settings Options  // Welcome
+	reason   string   // Reject
+	ack      uint32   // Input: the peer's inputs received so far
+	start    uint32   // Input: frame of inputs[0]
+	inputs   []uint8  // Input: buttons as bit masks
+	frame    uint32   // Hash
+	hash     uint64   // Hash
+}
+
+// encode appends the packet of a message to buf
+func (m *message) encode(buf []byte) []byte {
+	buf = append(buf, magic[:]...)
+	buf = append(buf, m.kind)
+	switch m.kind {
+	case msgHello:
+		buf = append(buf, m.romHash[:]...)
+	case msgWelcome:
+		buf = append(buf, uint8(m.settings.InputDelay), uint8(m.settings.MaxRollback))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(m.settings.HashInterval))
+	case msgReject:
+		buf = append(buf, m.reason...)
+	case msgInput:
+		buf = binary.LittleEndian.AppendUint32(buf, m.ack)
+		buf = binary.LittleEndian.AppendUint32(buf, m.start)
+		buf = append(buf, uint8(len(m.inputs)))
+		buf = append(buf, m.inputs...)
+	case msgHash:
+		buf = binary.LittleEndian.AppendUint32(buf, m.frame)
+		buf = binary.LittleEndian.AppendUint64(buf, m.hash)
+	}
+	return buf
+}
+
+// decodeMessage parses a packet
+func decodeMessage(packet []byte) (message, error) {
+	if len(packet) < len(magic)+1 || [4]byte(packet[:4]) != magic {
+		return message{}, errors.New("not a gones netplay packet (or another version)")
+	}
+	m := message{kind: packet[4]}
+	body := packet[5:]
+	switch m.kind {
+	case msgHello:
+		if len(body) < len(m.romHash) {
+			return message{}, errShortPacket
+		}
+		copy(m.romHash[:], body)
+	case msgWelcome:
+		if len(body) < 4 {
+			return message{}, errShortPacket
+		}
+		m.settings = Options{
+			InputDelay:   int(body[0]),
+			MaxRollback:  int(body[1]),
+			HashInterval: int(binary.LittleEndian.Uint16(body[2:])),
+		}
+	case msgReject:
+		m.reason = string(body)
+	case msgInput:
+		if len(body) < 9 || len(body) < 9+int(body[8]) {
+			return message{}, errShortPacket
+		}
+		m.ack = binary.LittleEndian.Uint32(body)
+		m.start = binary.LittleEndian.Uint32(body[4:])
+		m.inputs = body[9 : 9+int(body[8])]
+	case msgHash:
+		if len(body) < 12 {
+			return message{}, errShortPacket
+		}
+		m.frame = binary.LittleEndian.Uint32(body)
+		m.hash = binary.LittleEndian.Uint64(body[4:])
+	case msgBye:
+	default:
+		return message{}, fmt.Errorf("unknown message type %d", m.kind)
+	}
+	return m, nil
+}
+
+// packButtons packs buttons (A, B, Select, Start, Up, Down, Left, Right) into
+// a bit mask, A in bit 0
+func packButtons(buttons [8]bool) uint8 {
+	var mask uint8
+	for i, pressed := range buttons {
+		if pressed {
+			mask |= 1 << i
+		}
+	}
+	return mask
+}
+
+// unpackButtons is the inverse of packButtons
+func unpackButtons(mask uint8) [8]bool {
+	var buttons [8]bool
+	for i := range buttons {
+		buttons[i] = mask&(1<<i) != 0
+	}
+	return buttons
+}
@@ -0,0 +1,226 @@
+// Package netplay implements rollback: running ahead of the peer's inputs on
+// predictions, and re-running frames from a save state when a prediction
+// turns out wrong.
+package netplay
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// Players is the number of players in a session: the host is player 1 and
+// the guest player 2
+const Players = 2
+
+// Machine is the emulator a session drives
+type Machine interface {
+	// PowerOn puts the machine in the same state on both sides, before the
+	// first frame
+	PowerOn() error
+
+	// SaveState and LoadState snapshot and restore the whole machine
+	SaveState() ([]byte, error)
+	LoadState(data []byte) error
+
+	// RunFrame runs one frame with the players' buttons (A, B, Select,
+	// Start, Up, Down, Left, Right)
+	RunFrame(buttons [Players][8]bool) error
+}
+
+// stateHash is a frame's state hash sent to the peer
+type stateHash struct {
+	frame int
+	hash  uint64
+}
+
+// rollback runs the frames of a session. Each player's inputs are known up
+// to some frame; the peer's are predicted past it (the last one known is
+// held) for up to maxRollback frames. When the peer's inputs arrive and
+// differ from the prediction, the machine goes back to the save state of
+// that frame and runs the frames again. With maxRollback 0 it runs in
+// lockstep, only running frames whose inputs are all known.
+type rollback struct {
+	machine      Machine
+	local        int // Player played here
+	maxRollback  int
+	hashInterval int
+
+	inputs [Players][]uint8 // Known inputs of each player, by frame
+	frame  int              // Next frame to run
+
+	// State at the start of the last frames run, and the peer's input each
+	// was run with, by frame % len
+	snapshots [][]byte
+	predicted []uint8
+
+	rollbackFrom int // First frame run with a wrong prediction, -1 when none
+
+	nextHash     int // Next frame whose state is hashed
+	localHashes  map[int]uint64
+	remoteHashes map[int]uint64
+	outgoing     []stateHash // Hashes to send to the peer
+	desync       int         // First frame whose hashes differed, -1 when none
+
+	rollbacks  int // Times frames were run again
+	rolledBack int // Frames run again
+}
+
+// newRollback starts a session at frame 0. The first inputDelay frames of
+// both players are empty: inputs apply inputDelay frames after they are
+// added.
+func newRollback(machine Machine, local int, options Options) *rollback {
+	r := &rollback{
+		machine:      machine,
+		local:        local,
+		maxRollback:  options.MaxRollback,
+		hashInterval: options.HashInterval,
+		snapshots:    make([][]byte, options.MaxRollback+1),
+		predicted:    make([]uint8, options.MaxRollback+1),
+		rollbackFrom: -1,
+		nextHash:     options.HashInterval,
+		localHashes:  make(map[int]uint64),
+		remoteHashes: make(map[int]uint64),
+		desync:       -1,
+	}
+	for player := range r.inputs {
+		r.inputs[player] = make([]uint8, options.InputDelay)
+	}
+	return r
+}
+
+// remote returns the peer's player
+func (r *rollback) remote() int {
+	return 1 - r.local
+}
+
+// confirmed returns how many frames have both players' inputs
+func (r *rollback) confirmed() int {
+	return min(len(r.inputs[0]), len(r.inputs[1]))
+}
+
+// canAdvance reports whether the next frame may run: in lockstep when the
+// peer's input for it is known, otherwise while fewer than maxRollback
+// frames run on predictions
+func (r *rollback) canAdvance() bool {
+	return r.frame < len(r.inputs[r.remote()])+r.maxRollback
+}
+
+// addLocalInput adds the input of the frame inputDelay frames from now
+func (r *rollback) addLocalInput(buttons uint8) {
+	r.inputs[r.local] = append(r.inputs[r.local], buttons)
+}
+
+// addRemoteInputs adds the peer's inputs from frame start on. Inputs already
+// known are skipped, and inputs after a gap are dropped: the peer sends them
+// again. A frame already run with a different prediction is run again on the
+// next advance.
+func (r *rollback) addRemoteInputs(start int, inputs []uint8) {
+	remote := &r.inputs[r.remote()]
+	for i, buttons := range inputs {
+		frame := start + i
+		if frame < len(*remote) {
+			continue
+		}
+		if frame > len(*remote) {
+			break
+		}
+		*remote = append(*remote, buttons)
+		if frame < r.frame && r.predicted[frame%len(r.predicted)] != buttons &&
+			(r.rollbackFrom < 0 || frame < r.rollbackFrom) {
+			r.rollbackFrom = frame
+		}
+	}
+}
+
+// advance runs the next frame, after running again the frames since a wrong
+// prediction. Call it only when canAdvance.
+func (r *rollback) advance() error {
+	if from := r.rollbackFrom; from >= 0 {
+		r.rollbackFrom = -1
+		if err := r.machine.LoadState(r.snapshots[from%len(r.snapshots)]); err != nil {
+			return fmt.Errorf("failed to roll back to frame %d: %v", from, err)
+		}
+		r.rollbacks++
+		r.rolledBack += r.frame - from
+		for frame := from; frame < r.frame; frame++ {
+			if err := r.runFrame(frame); err != nil {
+				return err
+			}
+		}
+	}
+
+	// States saved before the peer's inputs were known can be hashed now
+	for r.nextHash < r.frame && r.nextHash <= len(r.inputs[r.remote()]) {
+		r.hashState(r.nextHash, r.snapshots[r.nextHash%len(r.snapshots)])
+	}
+
+	if err := r.runFrame(r.frame); err != nil {
+		return err
+	}
+	r.frame++
+	return nil
+}
+
+// runFrame saves the state at the start of a frame and runs it
+func (r *rollback) runFrame(frame int) error {
+	state, err := r.machine.SaveState()
+	if err != nil {
+		return fmt.Errorf("failed to save the state of frame %d: %v", frame, err)
+	}
+	slot := frame % len(r.snapshots)
+	r.snapshots[slot] = state
+	if frame == r.nextHash && frame <= len(r.inputs[r.remote()]) {
+		r.hashState(frame, state)
+	}
+
+	// Hold the peer's last known input past the ones received
+	remote := r.inputs[r.remote()]
+	var predicted uint8
+	switch {
+	case frame < len(remote):
+		predicted = remote[frame]
+	case len(remote) > 0:
+		predicted = remote[len(remote)-1]
+	}
+	r.predicted[slot] = predicted
+
+	var buttons [Players][8]bool
+	buttons[r.local] = unpackButtons(r.inputs[r.local][frame])
+	buttons[r.remote()] = unpackButtons(predicted)
+	if err := r.machine.RunFrame(buttons); err != nil {
+		return fmt.Errorf("frame %d: %v", frame, err)
+	}
+	return nil
+}
+
+// hashState records the hash of the state at the start of a frame whose
+// inputs before it are all known, to send to the peer
+func (r *rollback) hashState(frame int, state []byte) {
+	sum := sha256.Sum256(state)
+	hash := binary.LittleEndian.Uint64(sum[:])
+	r.localHashes[frame] = hash
+	r.outgoing = append(r.outgoing, stateHash{frame, hash})
+	r.nextHash = frame + r.hashInterval
+	r.compareHashes(frame)
+}
+
+// addRemoteHash records the hash of a frame's state on the peer
+func (r *rollback) addRemoteHash(frame int, hash uint64) {
+	r.remoteHashes[frame] = hash
+	r.compareHashes(frame)
+}
+
+// compareHashes compares the hashes of a frame once both sides have one
+func (r *rollback) compareHashes(frame int) {
+	local, ok := r.localHashes[frame]
+	remote, remoteOK := r.remoteHashes[frame]
+	if !ok || !remoteOK {
+		return
+	}
+	if local != remote && (r.desync < 0 || frame < r.desync) {
+		r.desync = frame
+	}
+	delete(r.localHashes, frame)
+	delete(r.remoteHashes, frame)
+}
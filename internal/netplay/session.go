@@ -0,0 +1,375 @@
+// Package netplay implements sessions: hosting and joining over UDP, the
+// exchange of inputs and state hashes, and keeping the two sides in time.
+package netplay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Defaults
+const (
+	DefaultPort         = 7845
+	DefaultInputDelay   = 2
+	DefaultMaxRollback  = 8
+	DefaultHashInterval = 60 // One check a second
+)
+
+// Limits of the options
+const (
+	MaxInputDelay  = 15
+	MaxMaxRollback = 30
+)
+
+// Timing of the connection
+const (
+	helloInterval = 250 * time.Millisecond // Between join attempts
+	joinTimeout   = 10 * time.Second       // Without an answer from the host
+	peerTimeout   = 10 * time.Second       // Without a packet from the peer
+)
+
+// timeSyncInterval is how often, in updates, a side ahead of its peer skips
+// an update to let it catch up
+const timeSyncInterval = 10
+
+// ErrPeerLeft is returned by Update when the other player ended the session
+var ErrPeerLeft = errors.New("the other player left the session")
+
+// Options configures a session. The host's options are used by both sides.
+type Options struct {
+	InputDelay   int // Frames between pressing a button and its effect, hiding the network latency
+	MaxRollback  int // Frames run ahead of the peer's inputs on predictions, 0 for lockstep
+	HashInterval int // Frames between comparisons of the state hashes
+}
+
+// DefaultOptions returns the default options
+func DefaultOptions() Options {
+	return Options{InputDelay: DefaultInputDelay, MaxRollback: DefaultMaxRollback, HashInterval: DefaultHashInterval}
+}
+
+// validate checks the options' ranges
+func (o Options) validate() error {
+	if o.InputDelay < 0 || o.InputDelay > MaxInputDelay {
+		return fmt.Errorf("input delay must be 0-%d frames", MaxInputDelay)
+	}
+	if o.MaxRollback < 0 || o.MaxRollback > MaxMaxRollback {
+		return fmt.Errorf("rollback must be 0-%d frames", MaxMaxRollback)
+	}
+	if o.HashInterval < 1 || o.HashInterval > 0xFFFF {
+		return errors.New("hash interval must be 1-65535 frames")
+	}
+	return nil
+}
+
+// Status describes a session
+type Status struct {
+	Connected  bool   // The other player joined (or the host answered)
+	Hosting    bool   //
+	Player     int    // Player played here, 1 for the host and 2 for the guest
+	Address    string // Address hosted on, or of the host joined
+	Options    Options
+	Frame      int // Frames run
+	Rollbacks  int // Times frames were run again after a wrong prediction
+	RolledBack int // Frames run again
+	Desync     int // First frame whose state differed from the peer's, -1 when none
+}
+
+// received is a packet from the reader goroutine
+type received struct {
+	data []byte
+	from *net.UDPAddr
+}
+
+// Session is a two-player netplay session. Update runs it from the
+// emulation loop; nothing runs in between but the reads from the socket.
+type Session struct {
+	conn    *net.UDPConn
+	peer    *net.UDPAddr // nil until a guest joins the host
+	hosting bool
+	romHash [32]byte
+	options Options
+	packets chan received
+
+	sync      *rollback // nil until the session starts
+	opened    time.Time
+	lastHello time.Time
+	lastHeard time.Time
+	peerAck   int // Local inputs the peer has
+	peerAhead int // Frames the peer was ahead of its view of us, at its last packet
+	updates   int
+	buf       []byte
+}
+
+// Host opens a session on a UDP address such as ":7845" for a guest with the
+// same ROM (its SHA-256 hash) to join
+func Host(address string, romHash [32]byte, options Options) (*Session, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %v", address, err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", address, err)
+	}
+	return newSession(conn, nil, true, romHash, options), nil
+}
+
+// Join joins the session hosted on a UDP address such as
+// "192.168.1.20:7845" (the default port when none is given). The host sets
+// the options.
+func Join(address string, romHash [32]byte) (*Session, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, strconv.Itoa(DefaultPort))
+	}
+	peer, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %v", address, err)
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a UDP socket: %v", err)
+	}
+	return newSession(conn, peer, false, romHash, Options{}), nil
+}
+
+func newSession(conn *net.UDPConn, peer *net.UDPAddr, hosting bool, romHash [32]byte, options Options) *Session {
+	s := &Session{
+		conn:    conn,
+		peer:    peer,
+		hosting: hosting,
+		romHash: romHash,
+		options: options,
+		packets: make(chan received, 256),
+		opened:  time.Now(),
+	}
+	go s.read()
+	return s
+}
+
+// read passes packets to Update until the socket is closed
+func (s *Session) read() {
+	defer close(s.packets)
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue // Such as ICMP port unreachable before the peer listens
+		}
+		select {
+		case s.packets <- received{bytes.Clone(buf[:n]), from}:
+		default: // Dropped while no one updates the session, like any lost packet
+		}
+	}
+}
+
+// Update handles the packets received, then runs the next frame of machine
+// with the local player's buttons when it can. Until the session starts,
+// and while the peer is too far behind, it runs nothing and returns false.
+// The machine is powered on when the session starts.
+func (s *Session) Update(machine Machine, local [8]bool) (bool, error) {
+	s.updates++
+	if err := s.receive(machine); err != nil {
+		return false, err
+	}
+	if s.sync == nil {
+		if !s.hosting {
+			if time.Since(s.opened) > joinTimeout {
+				return false, fmt.Errorf("no answer from %s", s.peer)
+			}
+			if time.Since(s.lastHello) >= helloInterval {
+				s.lastHello = time.Now()
+				s.send(&message{kind: msgHello, romHash: s.romHash})
+			}
+		}
+		return false, nil
+	}
+	if time.Since(s.lastHeard) > peerTimeout {
+		return false, fmt.Errorf("lost the connection to %s", s.peer)
+	}
+
+	ran := false
+	if s.sync.canAdvance() && !s.waitForPeer() {
+		s.sync.addLocalInput(packButtons(local))
+		if err := s.sync.advance(); err != nil {
+			return false, err
+		}
+		ran = true
+	}
+	s.sendInputs()
+	for _, h := range s.sync.outgoing {
+		s.send(&message{kind: msgHash, frame: uint32(h.frame), hash: h.hash})
+	}
+	s.sync.outgoing = s.sync.outgoing[:0]
+	return ran, nil
+}
+
+// waitForPeer reports whether to skip an update because this side runs
+// ahead of the peer, which would otherwise keep hitting the rollback limit.
+// Both sides see each other behind by the network latency; half the
+// difference between their views is how far ahead this side is.
+func (s *Session) waitForPeer() bool {
+	if s.updates%timeSyncInterval != 0 {
+		return false
+	}
+	remote := s.sync.inputs[s.sync.remote()]
+	ahead := s.sync.frame - (len(remote) - s.options.InputDelay)
+	return (ahead-s.peerAhead)/2 >= 1
+}
+
+// receive handles the packets waiting
+func (s *Session) receive(machine Machine) error {
+	for {
+		select {
+		case p, ok := <-s.packets:
+			if !ok {
+				return errors.New("session closed")
+			}
+			if err := s.handle(machine, p); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// handle handles a packet
+func (s *Session) handle(machine Machine, p received) error {
+	m, err := decodeMessage(p.data)
+	if err != nil {
+		return nil // Stray packets are ignored
+	}
+
+	if s.hosting && m.kind == msgHello {
+		switch {
+		case s.peer != nil && !sameAddr(p.from, s.peer):
+			s.sendTo(&message{kind: msgReject, reason: "the session already has two players"}, p.from)
+		case m.romHash != s.romHash:
+			s.sendTo(&message{kind: msgReject, reason: "the host is playing a different ROM"}, p.from)
+		default:
+			s.peer = p.from
+			s.send(&message{kind: msgWelcome, settings: s.options}) // Again if the first was lost
+			if s.sync == nil {
+				return s.start(machine, 0)
+			}
+		}
+		return nil
+	}
+	if s.peer == nil || !sameAddr(p.from, s.peer) {
+		return nil
+	}
+	s.lastHeard = time.Now()
+
+	switch m.kind {
+	case msgWelcome:
+		if s.sync == nil && !s.hosting {
+			if err := m.settings.validate(); err != nil {
+				return fmt.Errorf("host sent invalid settings: %v", err)
+			}
+			s.options = m.settings
+			return s.start(machine, 1)
+		}
+	case msgReject:
+		if !s.hosting {
+			return fmt.Errorf("%s refused to let you join: %s", s.peer, m.reason)
+		}
+	case msgInput:
+		if s.sync != nil {
+			s.peerAck = max(s.peerAck, int(m.ack))
+			s.sync.addRemoteInputs(int(m.start), m.inputs)
+			peerFrame := int(m.start) + len(m.inputs) - s.options.InputDelay
+			s.peerAhead = peerFrame - (int(m.ack) - s.options.InputDelay)
+		}
+	case msgHash:
+		if s.sync != nil {
+			s.sync.addRemoteHash(int(m.frame), m.hash)
+		}
+	case msgBye:
+		return ErrPeerLeft
+	}
+	return nil
+}
+
+// start powers on the machine and starts the session at frame 0
+func (s *Session) start(machine Machine, local int) error {
+	if err := machine.PowerOn(); err != nil {
+		return fmt.Errorf("failed to power on: %v", err)
+	}
+	s.sync = newRollback(machine, local, s.options)
+	s.peerAck = s.options.InputDelay // The delay frames are empty on both sides
+	s.lastHeard = time.Now()
+	return nil
+}
+
+// sendInputs sends the local inputs the peer does not have yet, so a lost
+// packet is made up for by the next
+func (s *Session) sendInputs() {
+	inputs := s.sync.inputs[s.sync.local]
+	start := min(s.peerAck, len(inputs))
+	end := min(len(inputs), start+maxInputsPerPacket)
+	s.send(&message{
+		kind:   msgInput,
+		ack:    uint32(len(s.sync.inputs[s.sync.remote()])),
+		start:  uint32(start),
+		inputs: inputs[start:end],
+	})
+}
+
+// send sends a message to the peer
+func (s *Session) send(m *message) {
+	if s.peer != nil {
+		s.sendTo(m, s.peer)
+	}
+}
+
+func (s *Session) sendTo(m *message, addr *net.UDPAddr) {
+	s.buf = m.encode(s.buf[:0])
+	s.conn.WriteToUDP(s.buf, addr) // Lost packets are sent again or not needed
+}
+
+// sameAddr reports whether two UDP addresses are the same endpoint
+func sameAddr(a, b *net.UDPAddr) bool {
+	return a.Port == b.Port && a.IP.Equal(b.IP)
+}
+
+// Status describes the session
+func (s *Session) Status() Status {
+	status := Status{
+		Connected: s.sync != nil,
+		Hosting:   s.hosting,
+		Player:    2,
+		Options:   s.options,
+		Desync:    -1,
+	}
+	if s.hosting {
+		status.Player = 1
+		status.Address = s.conn.LocalAddr().String()
+	} else {
+		status.Address = s.peer.String()
+	}
+	if s.sync != nil {
+		status.Frame = s.sync.frame
+		status.Rollbacks = s.sync.rollbacks
+		status.RolledBack = s.sync.rolledBack
+		status.Desync = s.sync.desync
+	}
+	return status
+}
+
+// Close tells the peer the session ended and closes the socket
+func (s *Session) Close() error {
+	if s.sync != nil {
+		s.send(&message{kind: msgBye})
+	}
+	return s.conn.Close()
+}
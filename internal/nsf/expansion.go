@@ -0,0 +1,92 @@
+// Package nsf provides the expansion sound chips of NSF music: the header's
+// expansion byte picks which the player creates and maps.
+package nsf
+
+import "gones/internal/apu"
+
+// expansionChips are the expansion sound chips an NSF uses, nil for those it
+// does not
+type expansionChips struct {
+	vrc6 *apu.VRC6Audio
+	vrc7 *apu.VRC7Audio
+	fds  *apu.FDSAudio
+	mmc5 *apu.MMC5Audio
+	n163 *apu.N163Audio
+	s5b  *apu.Sunsoft5BAudio
+
+	// All of them, for the APU to mix
+	audio apu.ExpansionChips
+}
+
+// newExpansionChips creates the chips set in an NSF's expansion byte
+func newExpansionChips(expansion uint8) expansionChips {
+	var e expansionChips
+	if expansion&ExpansionVRC6 != 0 {
+		e.vrc6 = apu.NewVRC6Audio()
+		e.audio = append(e.audio, e.vrc6)
+	}
+	if expansion&ExpansionVRC7 != 0 {
+		e.vrc7 = apu.NewVRC7Audio()
+		e.audio = append(e.audio, e.vrc7)
+	}
+	if expansion&ExpansionFDS != 0 {
+		e.fds = apu.NewFDSAudio()
+		e.audio = append(e.audio, e.fds)
+	}
+	if expansion&ExpansionMMC5 != 0 {
+		e.mmc5 = apu.NewMMC5Audio()
+		e.audio = append(e.audio, e.mmc5)
+	}
+	if expansion&ExpansionN163 != 0 {
+		e.n163 = apu.NewN163Audio()
+		e.audio = append(e.audio, e.n163)
+	}
+	if expansion&ExpansionS5B != 0 {
+		e.s5b = apu.NewSunsoft5BAudio()
+		e.audio = append(e.audio, e.s5b)
+	}
+	return e
+}
+
+// write passes a CPU write to the chips whose registers are at address
+func (e *expansionChips) write(address uint16, value uint8) {
+	if e.vrc6 != nil {
+		e.vrc6.Write(address, value)
+	}
+	if e.vrc7 != nil {
+		e.vrc7.Write(address, value)
+	}
+	if e.fds != nil {
+		e.fds.Write(address, value)
+	}
+	if e.mmc5 != nil {
+		e.mmc5.Write(address, value)
+	}
+	if e.n163 != nil {
+		e.n163.Write(address, value)
+	}
+	// The 5B decodes whole 8 KB ranges, but NSF music only uses these two
+	if e.s5b != nil && (address == 0xC000 || address == 0xE000) {
+		e.s5b.Write(address, value)
+	}
+}
+
+// read reads a chip register, or returns false if none is at address
+func (e *expansionChips) read(address uint16) (uint8, bool) {
+	if e.fds != nil {
+		if value, ok := e.fds.Read(address); ok {
+			return value, true
+		}
+	}
+	if e.mmc5 != nil {
+		if value, ok := e.mmc5.Read(address); ok {
+			return value, true
+		}
+	}
+	if e.n163 != nil {
+		if value, ok := e.n163.Read(address); ok {
+			return value, true
+		}
+	}
+	return 0, false
+}
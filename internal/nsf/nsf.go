@@ -0,0 +1,130 @@
+// Package nsf reads NSF music files, the sound code and data ripped from NES
+// games, and plays their tracks on the emulated CPU and APU.
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// headerSize is the size of the NSF header; the data follows it
+const headerSize = 0x80
+
+// magic starts every NSF file
+const magic = "NESM\x1A"
+
+// Expansion sound chips, the bits of the header's expansion byte
+const (
+	ExpansionVRC6 = 1 << iota
+	ExpansionVRC7
+	ExpansionFDS
+	ExpansionMMC5
+	ExpansionN163
+	ExpansionS5B
+)
+
+// expansionNames names the expansion bits in order
+var expansionNames = []string{"VRC6", "VRC7", "FDS", "MMC5", "Namco 163", "Sunsoft 5B"}
+
+// File is a parsed NSF file
+type File struct {
+	Version     int
+	Songs       int // Number of songs
+	StartSong   int // First song to play, counting from 1
+	LoadAddress uint16
+	InitAddress uint16
+	PlayAddress uint16
+	Title       string
+	Artist      string
+	Copyright   string
+	NTSCSpeed   uint16   // Microseconds between PLAY calls on NTSC
+	PALSpeed    uint16   // Microseconds between PLAY calls on PAL
+	Banks       [8]uint8 // Initial 4 KB banks at $8000-$FFFF; all 0 when not bankswitched
+	Region      uint8    // Bit 0: PAL, bit 1: NTSC and PAL
+	Expansion   uint8    // Expansion sound chips
+	Data        []byte
+}
+
+// Load reads an NSF file
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read NSF file: %v", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses NSF file data
+func Parse(data []byte) (*File, error) {
+	if len(data) < headerSize || string(data[:5]) != magic {
+		return nil, fmt.Errorf("not an NSF file")
+	}
+	f := &File{
+		Version:     int(data[0x05]),
+		Songs:       int(data[0x06]),
+		StartSong:   int(data[0x07]),
+		LoadAddress: binary.LittleEndian.Uint16(data[0x08:]),
+		InitAddress: binary.LittleEndian.Uint16(data[0x0A:]),
+		PlayAddress: binary.LittleEndian.Uint16(data[0x0C:]),
+		Title:       headerString(data[0x0E:0x2E]),
+		Artist:      headerString(data[0x2E:0x4E]),
+		Copyright:   headerString(data[0x4E:0x6E]),
+		NTSCSpeed:   binary.LittleEndian.Uint16(data[0x6E:]),
+		PALSpeed:    binary.LittleEndian.Uint16(data[0x78:]),
+		Region:      data[0x7A],
+		Expansion:   data[0x7B],
+		Data:        data[headerSize:],
+	}
+	copy(f.Banks[:], data[0x70:0x78])
+
+	// FDS music runs from RAM at $6000-$FFFF
+	var lowest uint16 = 0x8000
+	if f.Expansion&ExpansionFDS != 0 {
+		lowest = 0x6000
+	}
+	switch {
+	case f.Songs == 0:
+		return nil, fmt.Errorf("NSF file has no songs")
+	case f.LoadAddress < lowest && !f.Bankswitched():
+		return nil, fmt.Errorf("unsupported NSF load address $%04X", f.LoadAddress)
+	case f.InitAddress < lowest || f.PlayAddress < lowest:
+		return nil, fmt.Errorf("NSF init $%04X or play $%04X outside $%04X-$FFFF", f.InitAddress, f.PlayAddress, lowest)
+	}
+	if f.StartSong < 1 || f.StartSong > f.Songs {
+		f.StartSong = 1
+	}
+	return f, nil
+}
+
+// headerString returns a null-terminated header string
+func headerString(field []byte) string {
+	if i := bytes.IndexByte(field, 0); i >= 0 {
+		field = field[:i]
+	}
+	return string(field)
+}
+
+// Bankswitched reports whether the file uses the bank registers at
+// $5FF8-$5FFF
+func (f *File) Bankswitched() bool {
+	return f.Banks != [8]uint8{}
+}
+
+// PAL reports whether the music is meant for a PAL NES only
+func (f *File) PAL() bool {
+	return f.Region&0x03 == 0x01
+}
+
+// ExpansionChips returns the names of the expansion sound chips the music
+// uses
+func (f *File) ExpansionChips() []string {
+	var chips []string
+	for i, name := range expansionNames {
+		if f.Expansion&(1<<i) != 0 {
+			chips = append(chips, name)
+		}
+	}
+	return chips
+}
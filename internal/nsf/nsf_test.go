@@ -0,0 +1,195 @@
+package nsf
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// testNSF builds an NSF whose INIT stores the song in $00 and whose PLAY
+// counts its calls in $01
+func testNSF(load uint16, banks [8]uint8, data []byte) []byte {
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	header[0x05] = 1
+	header[0x06] = 3 // Songs
+	header[0x07] = 2 // Start song
+	binary.LittleEndian.PutUint16(header[0x08:], load)
+	binary.LittleEndian.PutUint16(header[0x0A:], 0x8000)
+	binary.LittleEndian.PutUint16(header[0x0C:], 0x8003)
+	copy(header[0x0E:], "Test Song")
+	copy(header[0x2E:], "Tester")
+	copy(header[0x4E:], "2024")
+	binary.LittleEndian.PutUint16(header[0x6E:], 16639)
+	copy(header[0x70:], banks[:])
+	header[0x7B] = ExpansionVRC6 | ExpansionFDS
+	return append(header, data...)
+}
+
+// driverData is the INIT and PLAY routines at $8000
+var driverData = []byte{
+	0x85, 0x00, // $8000: STA $00
+	0x60,       // RTS
+	0xE6, 0x01, // $8003: INC $01
+	0x60, // RTS
+}
+
+func TestParse(t *testing.T) {
+	f, err := Parse(testNSF(0x8000, [8]uint8{}, driverData))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if f.Songs != 3 || f.StartSong != 2 || f.Title != "Test Song" || f.Artist != "Tester" || f.Copyright != "2024" {
+		t.Errorf("header = %+v", f)
+	}
+	if f.InitAddress != 0x8000 || f.PlayAddress != 0x8003 || f.Bankswitched() || f.PAL() {
+		t.Errorf("addresses = %+v", f)
+	}
+	if chips := strings.Join(f.ExpansionChips(), ","); chips != "VRC6,FDS" {
+		t.Errorf("ExpansionChips = %s, want VRC6,FDS", chips)
+	}
+
+	bad := map[string][]byte{
+		"short":    []byte("NESM\x1A"),
+		"magic":    make([]byte, headerSize),
+		"no songs": func() []byte { d := testNSF(0x8000, [8]uint8{}, nil); d[0x06] = 0; return d }(),
+		"low load": func() []byte { d := testNSF(0x6000, [8]uint8{}, nil); d[0x7B] = 0; return d }(),
+		"low init": func() []byte { d := testNSF(0x8000, [8]uint8{}, nil); d[0x0B] = 0x60; d[0x7B] = 0; return d }(),
+		"FDS init": func() []byte { d := testNSF(0x8000, [8]uint8{}, nil); d[0x0B] = 0x50; return d }(),
+	}
+	for name, data := range bad {
+		if _, err := Parse(data); err == nil {
+			t.Errorf("%s: Parse succeeded", name)
+		}
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	f, err := Parse(testNSF(0x8000, [8]uint8{}, driverData))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, err := NewPlayer(f, 4); err == nil {
+		t.Error("NewPlayer accepted song 4 of 3")
+	}
+	p, err := NewPlayer(f, 3)
+	if err != nil {
+		t.Fatalf("NewPlayer: %v", err)
+	}
+	samples := 0
+	for i := 0; i < 10; i++ {
+		samples += len(p.Frame())
+	}
+	if got := p.Peek(0x00); got != 2 {
+		t.Errorf("INIT got song %d, want 2", got)
+	}
+	// The NMI is enabled during the first frame, so PLAY misses at most one
+	if got := p.Peek(0x01); got < 9 || got > 10 {
+		t.Errorf("PLAY called %d times in 10 frames", got)
+	}
+	if want := p.SampleRate() * 10 / 60; samples < want*9/10 {
+		t.Errorf("got %d samples in 10 frames, want about %d", samples, want)
+	}
+}
+
+func TestPlayerBankswitching(t *testing.T) {
+	// Load at $8100 so the data is padded within its bank; bank 1 is mapped
+	// at $8000 and holds the routines, bank 0 holds a marker
+	data := make([]byte, 2*bankSize-0x100)
+	data[0] = 0xAA // $0100 in bank 0
+	copy(data[bankSize-0x100:], driverData)
+	banks := [8]uint8{1, 0, 0, 0, 0, 0, 0, 0}
+	f, err := Parse(testNSF(0x8100, banks, data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	c := newCartridge(f)
+	if got := c.ReadPRG(0x8000); got != driverData[0] {
+		t.Errorf("$8000 = %02X, want %02X", got, driverData[0])
+	}
+	if got := c.ReadPRG(0x9100); got != 0xAA {
+		t.Errorf("$9100 = %02X, want AA", got)
+	}
+	c.WriteExpansion(0x5FF9, 3) // Wraps to bank 1
+	if got := c.ReadPRG(0x9000); got != driverData[0] {
+		t.Errorf("$9000 after switching = %02X, want %02X", got, driverData[0])
+	}
+	if got := c.ReadPRG(0xFFFC) | c.ReadPRG(0xFFFD); got == 0 {
+		t.Error("reset vector not mapped to the driver")
+	}
+
+	p, err := NewPlayer(f, 1)
+	if err != nil {
+		t.Fatalf("NewPlayer: %v", err)
+	}
+	p.Frame()
+	p.Frame()
+	if got := p.Peek(0x01); got == 0 {
+		t.Error("PLAY not called")
+	}
+}
+
+func TestPlayerFDSRAM(t *testing.T) {
+	// FDS music may load below $8000 and write over its own code
+	data := append(make([]byte, 0x2000), driverData...)
+	f, err := Parse(testNSF(0x6000, [8]uint8{}, data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	c := newCartridge(f)
+	if got := c.ReadPRG(0x8000); got != driverData[0] {
+		t.Errorf("$8000 = %02X, want %02X", got, driverData[0])
+	}
+	c.WritePRG(0x9000, 0x12)
+	if got := c.ReadPRG(0x9000); got != 0x12 {
+		t.Errorf("$9000 = %02X after writing 12", got)
+	}
+	if got := c.ReadPRG(0xFFFC) | c.ReadPRG(0xFFFD); got == 0 {
+		t.Error("reset vector not mapped to the driver")
+	}
+
+	p, err := NewPlayer(f, 1)
+	if err != nil {
+		t.Fatalf("NewPlayer: %v", err)
+	}
+	p.Frame()
+	p.Frame()
+	if got := p.Peek(0x01); got == 0 {
+		t.Error("PLAY not called")
+	}
+}
+
+// vrc6Data is an INIT that starts the VRC6's first pulse and an empty PLAY
+var vrc6Data = []byte{
+	0x4C, 0x06, 0x80, // $8000: JMP $8006
+	0x60,       // $8003: RTS
+	0x00, 0x00, // Padding
+	0xA9, 0x7F, 0x8D, 0x00, 0x90, // $8006: LDA #$7F, STA $9000 (half duty, volume 15)
+	0xA9, 0x40, 0x8D, 0x01, 0x90, // LDA #$40, STA $9001
+	0xA9, 0x80, 0x8D, 0x02, 0x90, // LDA #$80, STA $9002 (enable)
+	0x60, // RTS
+}
+
+func TestPlayerExpansionAudio(t *testing.T) {
+	for _, expansion := range []uint8{0, ExpansionVRC6} {
+		data := testNSF(0x8000, [8]uint8{}, vrc6Data)
+		data[0x7B] = expansion
+		f, err := Parse(data)
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		p, err := NewPlayer(f, 1)
+		if err != nil {
+			t.Fatalf("NewPlayer: %v", err)
+		}
+		low, high := float32(1), float32(-1)
+		for i := 0; i < 5; i++ {
+			for _, sample := range p.Frame() {
+				low, high = min(low, sample), max(high, sample)
+			}
+		}
+		if playing := high-low > 0.001; playing != (expansion != 0) {
+			t.Errorf("expansion %02X: samples from %f to %f", expansion, low, high)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package nsf provides the NSF player: a cartridge holding the music's code
+// and data, and a small driver that calls the music's INIT routine once and
+// its PLAY routine every frame.
+package nsf
+
+import (
+	"fmt"
+
+	"gones/internal/bus"
+)
+
+// The player's driver, mapped at $4100 in the expansion area. Reset runs
+// INIT and then turns on the vblank NMI, whose handler runs PLAY, so PLAY is
+// called once a frame (NTSC timing whatever the file's play speed).
+const (
+	driverReset = 0x4100
+	driverNMI   = 0x410B
+)
+
+func driverCode(init, play uint16) []uint8 {
+	return []uint8{
+		0x20, uint8(init), uint8(init >> 8), // $4100: JSR init
+		0xA9, 0x80, // LDA #$80
+		0x8D, 0x00, 0x20, // STA $2000 (NMI on vblank)
+		0x4C, 0x08, 0x41, // $4108: JMP $4108
+		0x20, uint8(play), uint8(play >> 8), // $410B: JSR play
+		0x40, // RTI
+	}
+}
+
+// bankSize is the size of the banks at $8000-$FFFF
+const bankSize = 0x1000
+
+// cartridge maps an NSF's data at $8000-$FFFF in eight 4 KB banks, with
+// 8 KB of RAM at $6000 and the driver in the expansion area. Files that are
+// not bankswitched get a fixed 32 KB image. FDS music runs from the Disk
+// System's RAM instead: $6000-$FFFF is writable and banks are copied into it.
+type cartridge struct {
+	data         []byte
+	banks        [8]int
+	bankswitched bool
+	ram          [0x2000]uint8
+	driver       []uint8
+	fdsRAM       []uint8 // $6000-$FFFF for FDS music
+
+	chips      expansionChips
+	exram      [0x400]uint8 // MMC5 $5C00-$5FFF, the last bytes under the bank registers
+	multiplier [2]uint8     // MMC5 $5205/$5206
+}
+
+func newCartridge(f *File) *cartridge {
+	c := &cartridge{
+		bankswitched: f.Bankswitched(),
+		driver:       driverCode(f.InitAddress, f.PlayAddress),
+		chips:        newExpansionChips(f.Expansion),
+	}
+	if c.chips.fds != nil {
+		c.fdsRAM = make([]uint8, 0xA000)
+	}
+	switch {
+	case c.bankswitched:
+		// The data starts at the load address's offset within its bank
+		padding := int(f.LoadAddress & (bankSize - 1))
+		size := (padding + len(f.Data) + bankSize - 1) / bankSize * bankSize
+		c.data = make([]byte, size)
+		copy(c.data[padding:], f.Data)
+		for i, bank := range f.Banks {
+			c.setBank(i+2, bank)
+		}
+		// FDS music starts with the banks at $6000-$7FFF of $E000-$FFFF
+		if c.fdsRAM != nil {
+			c.setBank(0, f.Banks[6])
+			c.setBank(1, f.Banks[7])
+		}
+	case c.fdsRAM != nil:
+		copy(c.fdsRAM[f.LoadAddress-0x6000:], f.Data)
+	default:
+		c.data = make([]byte, 0x8000)
+		copy(c.data[f.LoadAddress-0x8000:], f.Data)
+		for i := range c.banks {
+			c.banks[i] = i * bankSize
+		}
+	}
+	return c
+}
+
+// setBank maps bank into window i ($6000 + i*4 KB). FDS music gets a copy
+// of the bank in its RAM; for other music windows 0 and 1 are RAM.
+func (c *cartridge) setBank(i int, bank uint8) {
+	offset := int(bank) % (len(c.data) / bankSize) * bankSize
+	switch {
+	case c.fdsRAM != nil:
+		copy(c.fdsRAM[i*bankSize:(i+1)*bankSize], c.data[offset:])
+	case i >= 2:
+		c.banks[i-2] = offset
+	}
+}
+
+func (c *cartridge) ReadPRG(address uint16) uint8 {
+	switch {
+	// The driver's vectors
+	case address == 0xFFFA:
+		return uint8(driverNMI & 0xFF)
+	case address == 0xFFFB:
+		return uint8(driverNMI >> 8)
+	case address == 0xFFFC:
+		return uint8(driverReset & 0xFF)
+	case address == 0xFFFD:
+		return uint8(driverReset >> 8)
+	case c.fdsRAM != nil && address >= 0x6000:
+		return c.fdsRAM[address-0x6000]
+	case address >= 0x6000 && address < 0x8000:
+		return c.ram[address-0x6000]
+	case address < 0x8000:
+		return 0
+	}
+	offset := c.banks[(address-0x8000)/bankSize] + int(address&(bankSize-1))
+	return c.data[offset]
+}
+
+// WritePRG writes the RAM and the sound registers of the VRC6, VRC7, Namco
+// 163 and Sunsoft 5B
+func (c *cartridge) WritePRG(address uint16, value uint8) {
+	switch {
+	case c.fdsRAM != nil && address >= 0x6000:
+		c.fdsRAM[address-0x6000] = value
+	case address >= 0x6000 && address < 0x8000:
+		c.ram[address-0x6000] = value
+	}
+	if address >= 0x8000 {
+		c.chips.write(address, value)
+	}
+}
+
+func (c *cartridge) ReadCHR(address uint16) uint8         { return 0 }
+func (c *cartridge) WriteCHR(address uint16, value uint8) {}
+
+// ReadExpansion maps the driver, the sound registers of the FDS, MMC5 and
+// Namco 163, and the MMC5's multiplier and RAM
+func (c *cartridge) ReadExpansion(address uint16) (uint8, bool) {
+	if address >= driverReset && int(address-driverReset) < len(c.driver) {
+		return c.driver[address-driverReset], true
+	}
+	if value, ok := c.chips.read(address); ok {
+		return value, true
+	}
+	if c.chips.mmc5 != nil {
+		switch {
+		case address == 0x5205:
+			return uint8(uint16(c.multiplier[0]) * uint16(c.multiplier[1])), true
+		case address == 0x5206:
+			return uint8(uint16(c.multiplier[0]) * uint16(c.multiplier[1]) >> 8), true
+		case address >= 0x5C00 && address < 0x5FF6:
+			return c.exram[address-0x5C00], true
+		}
+	}
+	return 0, false
+}
+
+// WriteExpansion handles the bank registers at $5FF8-$5FFF, and $5FF6-$5FF7
+// for FDS music, and the chips' registers in the expansion area
+func (c *cartridge) WriteExpansion(address uint16, value uint8) {
+	switch {
+	case c.bankswitched && address >= 0x5FF8:
+		c.setBank(int(address-0x5FF6), value)
+	case c.bankswitched && c.fdsRAM != nil && address >= 0x5FF6:
+		c.setBank(int(address-0x5FF6), value)
+	case c.chips.mmc5 != nil && (address == 0x5205 || address == 0x5206):
+		c.multiplier[address-0x5205] = value
+	case c.chips.mmc5 != nil && address >= 0x5C00 && address < 0x5FF6:
+		c.exram[address-0x5C00] = value
+	default:
+		c.chips.write(address, value)
+	}
+}
+
+// Player plays a song of an NSF file
+type Player struct {
+	file *File
+	bus  *bus.Bus
+	song int
+}
+
+// NewPlayer starts playing song (counting from 1) of an NSF file
+func NewPlayer(f *File, song int) (*Player, error) {
+	if song < 1 || song > f.Songs {
+		return nil, fmt.Errorf("song %d out of range (1-%d)", song, f.Songs)
+	}
+	b := bus.New()
+	c := newCartridge(f)
+	b.LoadCartridge(c)
+	if len(c.chips.audio) > 0 {
+		b.APU.SetExpansionAudio(c.chips.audio)
+	}
+	// The driver turns on the NMI at once, as players that boot before
+	// loading a song can
+	b.SetPPUWarmUp(false)
+	b.Reset()
+
+	// Silence the APU and set up the registers INIT expects: the song in A,
+	// 0 for NTSC or 1 for PAL in X
+	for address := uint16(0x4000); address <= 0x4013; address++ {
+		b.Memory.Write(address, 0)
+	}
+	b.Memory.Write(0x4015, 0x00)
+	b.Memory.Write(0x4015, 0x0F)
+	b.Memory.Write(0x4017, 0x40)
+	b.CPU.A = uint8(song - 1)
+	b.CPU.X = 0
+	if f.PAL() {
+		b.CPU.X = 1
+	}
+	return &Player{file: f, bus: b, song: song}, nil
+}
+
+// Song returns the song being played, counting from 1
+func (p *Player) Song() int {
+	return p.song
+}
+
+// SampleRate returns the sample rate of the audio Frame returns
+func (p *Player) SampleRate() int {
+	return p.bus.APU.GetSampleRate()
+}
+
+// Frame plays a frame and returns its audio samples
+func (p *Player) Frame() []float32 {
+	p.bus.Run(1)
+	return p.bus.GetAudioSamples()
+}
+
+// Peek reads the player's memory without side effects, for tests and tools
+func (p *Player) Peek(address uint16) uint8 {
+	value, _ := p.bus.Memory.Peek(address)
+	return value
+}
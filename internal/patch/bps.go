@@ -0,0 +1,152 @@
+// Package patch implements BPS, which encodes the patched ROM as copies from
+// the original and from itself, and checks the CRC32 of both, so a patch made
+// for another revision of a game is refused instead of corrupting it.
+package patch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// bpsMagic starts a BPS patch, and bpsFooter is the size of its three CRC32s
+const (
+	bpsMagic  = "BPS1"
+	bpsFooter = 12
+)
+
+// BPS actions, in the low 2 bits of each command
+const (
+	bpsSourceRead = iota // Copy from the ROM at the same offset
+	bpsTargetRead        // Copy from the patch
+	bpsSourceCopy        // Copy from elsewhere in the ROM
+	bpsTargetCopy        // Copy from what was written so far
+)
+
+// ApplyBPS applies a BPS patch, checking the CRC32s of the patch, the ROM and
+// the patched ROM
+func ApplyBPS(rom, patch []byte) ([]byte, error) {
+	if len(patch) < len(bpsMagic)+bpsFooter || string(patch[:len(bpsMagic)]) != bpsMagic {
+		return nil, errors.New("missing BPS1 header")
+	}
+	footer := patch[len(patch)-bpsFooter:]
+	sourceCRC := binary.LittleEndian.Uint32(footer[0:])
+	targetCRC := binary.LittleEndian.Uint32(footer[4:])
+	if crc32.ChecksumIEEE(patch[:len(patch)-4]) != binary.LittleEndian.Uint32(footer[8:]) {
+		return nil, errors.New("patch checksum mismatch (the patch file is damaged)")
+	}
+	if crc32.ChecksumIEEE(rom) != sourceCRC {
+		return nil, fmt.Errorf("ROM checksum %08X, the patch is for %08X (a different ROM or revision)", crc32.ChecksumIEEE(rom), sourceCRC)
+	}
+
+	body := patch[:len(patch)-bpsFooter]
+	pos := len(bpsMagic)
+	number := func() (int, error) {
+		value, shift := 0, 1
+		for {
+			if pos >= len(body) {
+				return 0, errors.New("unexpected end of patch")
+			}
+			b := body[pos]
+			pos++
+			value += int(b&0x7F) * shift
+			if b&0x80 != 0 {
+				break
+			}
+			shift <<= 7
+			value += shift
+			if value > maxSize*4 {
+				return 0, errors.New("number out of range")
+			}
+		}
+		return value, nil
+	}
+
+	sourceSize, err := number()
+	if err != nil {
+		return nil, err
+	}
+	targetSize, err := number()
+	if err != nil {
+		return nil, err
+	}
+	metadataSize, err := number()
+	if err != nil {
+		return nil, err
+	}
+	if sourceSize != len(rom) {
+		return nil, fmt.Errorf("ROM is %d bytes, the patch is for %d", len(rom), sourceSize)
+	}
+	if targetSize > maxSize {
+		return nil, fmt.Errorf("patched ROM would be %d bytes, more than %d", targetSize, maxSize)
+	}
+	if metadataSize > len(body)-pos {
+		return nil, errors.New("unexpected end of patch")
+	}
+	pos += metadataSize
+
+	out := make([]byte, targetSize)
+	written, sourceOffset, targetOffset := 0, 0, 0
+	for pos < len(body) {
+		command, err := number()
+		if err != nil {
+			return nil, err
+		}
+		length := command>>2 + 1
+		if written+length > targetSize {
+			return nil, errors.New("patch writes past the end of the ROM")
+		}
+
+		switch command & 3 {
+		case bpsSourceRead:
+			if written+length > len(rom) {
+				return nil, errors.New("source read past the end of the ROM")
+			}
+			copy(out[written:], rom[written:written+length])
+		case bpsTargetRead:
+			if pos+length > len(body) {
+				return nil, errors.New("unexpected end of patch")
+			}
+			copy(out[written:], body[pos:pos+length])
+			pos += length
+		case bpsSourceCopy, bpsTargetCopy:
+			delta, err := number()
+			if err != nil {
+				return nil, err
+			}
+			if delta&1 != 0 {
+				delta = -(delta >> 1)
+			} else {
+				delta >>= 1
+			}
+			if command&3 == bpsSourceCopy {
+				sourceOffset += delta
+				if sourceOffset < 0 || sourceOffset+length > len(rom) {
+					return nil, errors.New("source copy outside the ROM")
+				}
+				copy(out[written:], rom[sourceOffset:sourceOffset+length])
+				sourceOffset += length
+			} else {
+				targetOffset += delta
+				if targetOffset < 0 || targetOffset >= written {
+					return nil, errors.New("target copy outside what was written")
+				}
+				// Byte by byte, since the copy may overlap what it writes
+				for i := 0; i < length; i++ {
+					out[written+i] = out[targetOffset+i]
+				}
+				targetOffset += length
+			}
+		}
+		written += length
+	}
+
+	if written != targetSize {
+		return nil, fmt.Errorf("patch wrote %d of %d bytes", written, targetSize)
+	}
+	if crc32.ChecksumIEEE(out) != targetCRC {
+		return nil, errors.New("patched ROM checksum mismatch")
+	}
+	return out, nil
+}
@@ -0,0 +1,94 @@
+// Package patch implements IPS, the original patch format: records of bytes
+// to write at offsets of up to 16 MB, with no check of the ROM patched.
+package patch
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ipsMagic starts an IPS patch, and ipsEOF is the offset that ends its records
+const (
+	ipsMagic = "PATCH"
+	ipsEOF   = 0x454F46 // "EOF"
+)
+
+// ApplyIPS applies an IPS patch. Records past the end of the ROM grow it, and
+// the optional truncation size after the end marker shrinks it.
+func ApplyIPS(rom, patch []byte) ([]byte, error) {
+	if len(patch) < len(ipsMagic) || string(patch[:len(ipsMagic)]) != ipsMagic {
+		return nil, errors.New("missing PATCH header")
+	}
+	out := append([]byte(nil), rom...)
+	pos := len(ipsMagic)
+	read := func(n int) (int, error) {
+		if pos+n > len(patch) {
+			return 0, errors.New("unexpected end of patch")
+		}
+		value := 0
+		for _, b := range patch[pos : pos+n] {
+			value = value<<8 | int(b)
+		}
+		pos += n
+		return value, nil
+	}
+
+	for {
+		offset, err := read(3)
+		if err != nil {
+			return nil, err
+		}
+		if offset == ipsEOF {
+			break
+		}
+		size, err := read(2)
+		if err != nil {
+			return nil, err
+		}
+
+		// A size of 0 is a run of one byte
+		var data []byte
+		var fill byte
+		run := size == 0
+		if run {
+			if size, err = read(2); err != nil {
+				return nil, err
+			}
+			value, err := read(1)
+			if err != nil {
+				return nil, err
+			}
+			fill = byte(value)
+		} else {
+			if pos+size > len(patch) {
+				return nil, errors.New("unexpected end of patch")
+			}
+			data = patch[pos : pos+size]
+			pos += size
+		}
+
+		end := offset + size
+		if end > maxSize {
+			return nil, fmt.Errorf("record at $%06X grows the ROM past %d bytes", offset, maxSize)
+		}
+		if end > len(out) {
+			out = append(out, make([]byte, end-len(out))...)
+		}
+		if run {
+			for i := offset; i < end; i++ {
+				out[i] = fill
+			}
+		} else {
+			copy(out[offset:], data)
+		}
+	}
+
+	// Lunar IPS's extension: the size to truncate the ROM to
+	if pos+3 <= len(patch) {
+		size, _ := read(3)
+		if size < len(out) {
+			out = out[:size]
+		}
+	}
+	return out, nil
+}
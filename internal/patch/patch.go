@@ -0,0 +1,51 @@
+// Package patch applies IPS and BPS patches to ROM images, the formats
+// translations and ROM hacks are distributed in.
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// maxSize is the largest ROM a patch may produce, which keeps a corrupt patch
+// from allocating gigabytes. The largest NES ROMs are a few megabytes.
+const maxSize = 16 << 20
+
+// Format is a patch file format
+type Format string
+
+const (
+	IPS Format = "IPS"
+	BPS Format = "BPS"
+)
+
+// Detect returns the format of a patch from its magic bytes
+func Detect(patch []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(patch, []byte(ipsMagic)):
+		return IPS, nil
+	case bytes.HasPrefix(patch, []byte(bpsMagic)):
+		return BPS, nil
+	}
+	return "", errors.New("not an IPS or BPS patch")
+}
+
+// Apply applies an IPS or BPS patch to a ROM image and returns the patched
+// image. The ROM is not modified.
+func Apply(rom, patch []byte) ([]byte, error) {
+	format, err := Detect(patch)
+	if err != nil {
+		return nil, err
+	}
+	var patched []byte
+	if format == IPS {
+		patched, err = ApplyIPS(rom, patch)
+	} else {
+		patched, err = ApplyBPS(rom, patch)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s patch: %v", format, err)
+	}
+	return patched, nil
+}
@@ -0,0 +1,109 @@
+package patch
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func TestApplyIPS(t *testing.T) {
+	rom := []byte("ABCDEFGH")
+	patch := []byte(ipsMagic +
+		"\x00\x00\x01\x00\x02xy" + // "xy" at 1
+		"\x00\x00\x05\x00\x00\x00\x02Z" + // Run of 2 "Z" at 5
+		"\x00\x00\x09\x00\x02!!" + // Past the end: grows the ROM
+		"EOF")
+	got, err := ApplyIPS(rom, patch)
+	if err != nil {
+		t.Fatalf("ApplyIPS failed: %v", err)
+	}
+	if want := "AxyDEZZH\x00!!"; string(got) != want {
+		t.Errorf("Patched ROM %q, want %q", got, want)
+	}
+	if string(rom) != "ABCDEFGH" {
+		t.Error("ApplyIPS modified the ROM")
+	}
+
+	// Lunar IPS truncation
+	got, err = ApplyIPS(rom, []byte(ipsMagic+"EOF\x00\x00\x04"))
+	if err != nil || string(got) != "ABCD" {
+		t.Errorf("Truncated ROM %q, %v", got, err)
+	}
+
+	if _, err := ApplyIPS(rom, []byte(ipsMagic+"\x00\x00\x01\x00\x05ab")); err == nil {
+		t.Error("Expected an error for a cut off record")
+	}
+}
+
+// bpsNumber encodes a BPS number
+func bpsNumber(n int) []byte {
+	var out []byte
+	for {
+		x := byte(n & 0x7F)
+		n >>= 7
+		if n == 0 {
+			return append(out, 0x80|x)
+		}
+		out = append(out, x)
+		n--
+	}
+}
+
+// bpsPatch builds a BPS patch from its commands
+func bpsPatch(source, target []byte, commands ...[]byte) []byte {
+	patch := []byte(bpsMagic)
+	patch = append(patch, bpsNumber(len(source))...)
+	patch = append(patch, bpsNumber(len(target))...)
+	patch = append(patch, bpsNumber(0)...)
+	for _, command := range commands {
+		patch = append(patch, command...)
+	}
+	patch = binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(source))
+	patch = binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(target))
+	return binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(patch))
+}
+
+// bpsCommand encodes an action of length bytes
+func bpsCommand(action, length int, rest ...byte) []byte {
+	return append(bpsNumber((length-1)<<2|action), rest...)
+}
+
+func TestApplyBPS(t *testing.T) {
+	source := []byte("ABCDEFGH")
+	target := []byte("ABCDxyxyxyGH")
+	patch := bpsPatch(source, target,
+		bpsCommand(bpsSourceRead, 4),
+		bpsCommand(bpsTargetRead, 2, 'x', 'y'),
+		bpsCommand(bpsTargetCopy, 4, bpsNumber(4<<1)...), // Overlapping copy from 4
+		bpsCommand(bpsSourceCopy, 2, bpsNumber(6<<1)...), // From 6
+	)
+
+	got, err := Apply(source, patch)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if string(got) != string(target) {
+		t.Errorf("Patched ROM %q, want %q", got, target)
+	}
+
+	if _, err := Apply([]byte("ABCDEFGX"), patch); err == nil || !strings.Contains(err.Error(), "different ROM") {
+		t.Errorf("Expected a ROM checksum error, got %v", err)
+	}
+	damaged := append([]byte(nil), patch...)
+	damaged[len(bpsMagic)+4] ^= 1
+	if _, err := Apply(source, damaged); err == nil || !strings.Contains(err.Error(), "damaged") {
+		t.Errorf("Expected a patch checksum error, got %v", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for patch, want := range map[string]Format{"PATCH...EOF": IPS, "BPS1...": BPS} {
+		if got, err := Detect([]byte(patch)); err != nil || got != want {
+			t.Errorf("Detect(%q) = %v, %v, want %v", patch, got, err, want)
+		}
+	}
+	if _, err := Apply([]byte("ROM"), []byte("UPS1")); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,88 @@
+// Package ppu provides the layer filter, which leaves the background, the
+// sprites or some sprite palettes out of the picture to debug priority and
+// transparency.
+package ppu
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LayerFilter picks the layers the PPU draws. It changes only the picture:
+// sprite 0 hits, the sprite overflow flag and everything else a game can
+// see work as if every layer were drawn. The zero value draws them all.
+type LayerFilter struct {
+	HideBackground bool
+	HideSprites    bool
+
+	// Bit n hides the sprites of palette n, showing the sprites behind them
+	HiddenSpritePalettes uint8
+}
+
+// OnlySpritePalette returns the filter drawing only the sprites of one
+// palette, 0-3
+func OnlySpritePalette(palette int) LayerFilter {
+	return LayerFilter{HideBackground: true, HiddenSpritePalettes: 0x0F &^ (1 << palette)}
+}
+
+// String describes the layers drawn, such as "all layers" or "sprite
+// palette 2 only"
+func (f LayerFilter) String() string {
+	if f.HiddenSpritePalettes&0x0F == 0x0F {
+		f.HideSprites, f.HiddenSpritePalettes = true, 0
+	}
+	switch {
+	case f == LayerFilter{}:
+		return "all layers"
+	case f.HideBackground && f.HideSprites:
+		return "backdrop only"
+	case f == LayerFilter{HideSprites: true}:
+		return "background only"
+	case f == LayerFilter{HideBackground: true}:
+		return "sprites only"
+	}
+
+	var shown []string
+	if !f.HideBackground {
+		shown = append(shown, "background")
+	}
+	if !f.HideSprites {
+		var palettes []string
+		for palette := 0; palette < 4; palette++ {
+			if f.HiddenSpritePalettes&(1<<palette) == 0 {
+				palettes = append(palettes, fmt.Sprint(palette))
+			}
+		}
+		shown = append(shown, "sprite palette "+strings.Join(palettes, ", "))
+	}
+	if len(shown) == 1 {
+		return shown[0] + " only"
+	}
+	return strings.Join(shown, " and ")
+}
+
+// SetLayerFilter sets the layers drawn from the next pixel on
+func (p *PPU) SetLayerFilter(filter LayerFilter) {
+	p.layers = filter
+}
+
+// LayerFilter returns the layers drawn
+func (p *PPU) LayerFilter() LayerFilter {
+	return p.layers
+}
+
+// filterLayers removes the hidden layers from a pixel's background and
+// sprite, after sprite 0 hits were checked against them. A sprite of a hidden
+// palette gives way to the next sprite on the pixel.
+func (p *PPU) filterLayers(pixelX, pixelY int, background, sprite SpritePixel) (SpritePixel, SpritePixel) {
+	if p.layers.HideBackground {
+		background = SpritePixel{transparent: true}
+	}
+	switch {
+	case p.layers.HideSprites:
+		sprite = SpritePixel{transparent: true}
+	case !sprite.transparent && p.layers.HiddenSpritePalettes&(1<<sprite.paletteIndex) != 0:
+		sprite = p.spritePixel(pixelX, pixelY, p.layers.HiddenSpritePalettes)
+	}
+	return background, sprite
+}
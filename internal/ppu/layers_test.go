@@ -0,0 +1,65 @@
+package ppu
+
+import "testing"
+
+func TestLayerFilter(t *testing.T) {
+	const (
+		backdrop   = 0x0F
+		background = 0x16
+		palette0   = 0x2A
+		palette2   = 0x12
+	)
+	for _, tc := range []struct {
+		filter       LayerFilter
+		name         string
+		sprite, bare uint8 // Colors where the sprites are and elsewhere
+	}{
+		{LayerFilter{}, "all layers", palette0, background},
+		{LayerFilter{HideSprites: true}, "background only", background, background},
+		{LayerFilter{HideBackground: true}, "sprites only", palette0, backdrop},
+		{LayerFilter{HideBackground: true, HideSprites: true}, "backdrop only", backdrop, backdrop},
+		{OnlySpritePalette(2), "sprite palette 2 only", palette2, backdrop},
+		{LayerFilter{HiddenSpritePalettes: 0x01}, "background and sprite palette 1, 2, 3", palette2, background},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.String(); got != tc.name {
+				t.Errorf("String() = %q", got)
+			}
+
+			ppuMem, mockCart := NewTestPPUMemorySetup()
+			ppu := New()
+			ppu.SetMemory(ppuMem)
+			ppu.Reset()
+			ppu.SetLayerFilter(tc.filter)
+
+			// Tile 0 is solid color 1, for the background and both sprites
+			for row := uint16(0); row < 8; row++ {
+				mockCart.SetCHRByte(row, 0xFF)
+			}
+			ppuMem.Write(0x3F00, backdrop)
+			ppuMem.Write(0x3F01, background)
+			ppuMem.Write(0x3F11, palette0)
+			ppuMem.Write(0x3F19, palette2)
+
+			// Sprite 0 in palette 0 over sprite 1 in palette 2, at (20, 11)
+			for i, b := range []uint8{10, 0, 0, 20, 10, 0, 2, 20} {
+				ppu.WriteOAM(uint8(i), b)
+			}
+			ppu.WriteRegister(0x2001, 0x1E) // Background and sprites, unclipped
+
+			for ppu.GetScanline() != 20 {
+				ppu.Step()
+			}
+			if got, want := ppu.GetPixel(20, 12), ppu.NESColorToRGB(tc.sprite); got != want {
+				t.Errorf("Sprite pixel %06X, want %06X", got, want)
+			}
+			if got, want := ppu.GetPixel(100, 12), ppu.NESColorToRGB(tc.bare); got != want {
+				t.Errorf("Background pixel %06X, want %06X", got, want)
+			}
+			// The game sees every layer whatever is drawn
+			if ppu.ReadRegister(0x2002)&0x40 == 0 {
+				t.Error("Sprite 0 hit missing with layers hidden")
+			}
+		})
+	}
+}
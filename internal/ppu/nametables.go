@@ -0,0 +1,46 @@
+// Package ppu implements drawing the four nametables at once, for the
+// nametable viewer.
+package ppu
+
+// NametablesWidth and NametablesHeight are the size of the picture
+// RenderNametables draws: the four nametables in a 2x2 grid, $2000 top left
+// and $2C00 bottom right
+const (
+	NametablesWidth  = 512
+	NametablesHeight = 480
+)
+
+// RenderNametables draws the four nametables into dst, which holds
+// NametablesWidth x NametablesHeight pixels, with the background pattern
+// table PPUCTRL selects and the current palettes. It only peeks at memory,
+// so it does not disturb emulation.
+func (p *PPU) RenderNametables(dst []uint32) {
+	if p.memory == nil {
+		return
+	}
+	patterns := uint16(p.ppuCtrl&0x10) << 8
+	for table := 0; table < 4; table++ {
+		base := 0x2000 + uint16(table)*0x400
+		originX, originY := table&1*256, table>>1*240
+		for row := 0; row < 30; row++ {
+			for column := 0; column < 32; column++ {
+				tile := uint16(p.memory.Peek(base + uint16(row*32+column)))
+				attribute := p.memory.Peek(base + 0x3C0 + uint16(row/4*8+column/4))
+				palette := uint16(attribute>>(row&2<<1|column&2)&0x03) << 2
+				for y := 0; y < 8; y++ {
+					low := p.memory.Peek(patterns + tile*16 + uint16(y))
+					high := p.memory.Peek(patterns + tile*16 + uint16(y) + 8)
+					line := dst[(originY+row*8+y)*NametablesWidth+originX+column*8:]
+					for x := 0; x < 8; x++ {
+						pixel := uint16(low>>(7-x)&1 | high>>(7-x)&1<<1)
+						address := uint16(0x3F00)
+						if pixel != 0 {
+							address |= palette | pixel
+						}
+						line[x] = p.colorToRGB(p.memory.Peek(address))
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package ppu
+
+import "testing"
+
+func TestRenderNametables(t *testing.T) {
+	ppuMem, mockCart := NewTestPPUMemorySetup()
+	ppu := New()
+	ppu.SetMemory(ppuMem)
+	ppu.Reset()
+
+	// Tile 1 is solid color 3; tile 0 is blank
+	for row := uint16(0); row < 16; row++ {
+		mockCart.SetCHRByte(16+row, 0xFF)
+	}
+	ppuMem.Write(0x3F00, 0x0F)
+	ppuMem.Write(0x3F0B, 0x16) // Palette 2, color 3
+	ppuMem.Write(0x3F03, 0x2A) // Palette 0, color 3
+
+	// Tile 1 at row 2, column 3 of $2000, with palette 2 in the bottom right
+	// of its attribute byte's area
+	ppuMem.Write(0x2000+2*32+3, 1)
+	ppuMem.Write(0x23C0, 0x80)
+	// Tile 1 at the top left of $2800, palette 0. With horizontal mirroring
+	// $2400 shows $2000 and $2C00 shows $2800.
+	ppuMem.Write(0x2800, 1)
+
+	dst := make([]uint32, NametablesWidth*NametablesHeight)
+	ppu.RenderNametables(dst)
+	for _, tc := range []struct {
+		name  string
+		x, y  int
+		color uint8
+	}{
+		{"tile in $2000", 3*8 + 4, 2*8 + 4, 0x16},
+		{"blank tile", 0, 0, 0x0F},
+		{"$2400", 256 + 3*8 + 4, 2*8 + 4, 0x16},
+		{"tile in $2800", 7, 240 + 7, 0x2A},
+		{"$2C00", 256, 240, 0x2A},
+		{"blank tile in $2800", 8, 240, 0x0F},
+	} {
+		if got, want := dst[tc.y*NametablesWidth+tc.x], NESColorToRGB(tc.color); got != want {
+			t.Errorf("%s: (%d, %d) = %06X, want %06X", tc.name, tc.x, tc.y, got, want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Package ppu provides the quirks of the OAM ports: what $2004 reads while
+// rendering, the attribute bits OAM does not have, and what rendering does
+// to OAMADDR and to OAM when it starts with OAMADDR past the first two
+// sprites.
+package ppu
+
+// readOAMData reads $2004. While rendering, the read returns the byte the
+// sprite logic has on the OAM bus rather than the byte at OAMADDR: $FF while
+// secondary OAM is cleared, primary OAM during sprite evaluation, and
+// secondary OAM during the sprite fetches and after.
+func (p *PPU) readOAMData() uint8 {
+	if p.renderingEnabled && p.scanline < 240 {
+		switch {
+		case p.cycle >= 1 && p.cycle <= 64:
+			return 0xFF
+		case p.cycle >= 65 && p.cycle <= 256:
+			// Evaluation reads a sprite's Y every two cycles; this PPU
+			// evaluates a line at once, so the read is of the sprite it
+			// would be on with none before it in range
+			if sprite := (p.cycle - 65) / 2; sprite < 64 {
+				return p.oam[sprite*4]
+			}
+			return p.oam[0]
+		case p.cycle >= 257 && p.cycle <= 320:
+			// Each slot reads Y, tile, attributes and X, then X again
+			// during the pattern fetches
+			step := min((p.cycle-257)&7, 3)
+			return p.secondaryOAM[(p.cycle-257)/8*4+step]
+		default:
+			return p.secondaryOAM[0]
+		}
+	}
+
+	value := p.oam[p.oamAddr]
+	if p.oamAddr&3 == 2 {
+		value &= 0xE3 // Attribute bits 2-4 do not exist
+	}
+	return value
+}
+
+// stepOAM does what rendering does to OAMADDR each cycle. The sprite
+// fetches leave it at 0, and when rendering starts with it at 8 or more, the
+// eight bytes of its row are copied over the first two sprites.
+func (p *PPU) stepOAM() {
+	switch {
+	case p.scanline == -1 && p.cycle == 1 && p.oamAddr >= 8:
+		row := int(p.oamAddr & 0xF8)
+		copy(p.oam[:8], p.oam[row:row+8])
+	case p.cycle >= 257 && p.cycle <= 320:
+		p.oamAddr = 0
+	}
+}
@@ -0,0 +1,55 @@
+package ppu
+
+import "testing"
+
+func TestOAMQuirks(t *testing.T) {
+	ppuMem, _ := NewTestPPUMemorySetup()
+	ppu := New()
+	ppu.SetMemory(ppuMem)
+	ppu.Reset()
+
+	runTo := func(scanline, cycle int) {
+		for ppu.GetScanline() != scanline || ppu.GetCycle() != cycle {
+			ppu.Step()
+		}
+	}
+
+	// Attribute bits 2-4 read back as 0
+	ppu.WriteRegister(0x2003, 0x02)
+	ppu.WriteRegister(0x2004, 0xFF)
+	ppu.WriteRegister(0x2003, 0x02)
+	if got := ppu.ReadRegister(0x2004); got != 0xE3 {
+		t.Errorf("Attribute byte read $%02X, want $E3", got)
+	}
+
+	for i := 0; i < 256; i++ {
+		ppu.WriteOAM(uint8(i), uint8(i))
+	}
+	ppu.WriteRegister(0x2001, 0x18)
+
+	// Rendering starting with OAMADDR at $2B copies $28-$2F over $00-$07
+	runTo(240, 0)
+	ppu.WriteRegister(0x2003, 0x2B)
+	runTo(-1, 2)
+	for i := 0; i < 8; i++ {
+		if got := ppu.ReadOAM(uint8(i)); got != uint8(0x28+i) {
+			t.Errorf("OAM $%02X = $%02X after rendering started, want $%02X", i, got, 0x28+i)
+		}
+	}
+	if got := ppu.ReadOAM(0x28); got != 0x28 {
+		t.Errorf("OAM $28 = $%02X, want it left alone", got)
+	}
+
+	// Reads while rendering see the sprite logic's OAM bus
+	runTo(10, 30)
+	if got := ppu.ReadRegister(0x2004); got != 0xFF {
+		t.Errorf("Read $%02X while secondary OAM is cleared, want $FF", got)
+	}
+	runTo(10, 258)
+	if got := ppu.ReadRegister(0x2004); got != ppu.secondaryOAM[1] {
+		t.Errorf("Read $%02X during the sprite fetches, want $%02X", got, ppu.secondaryOAM[1])
+	}
+	if ppu.oamAddr != 0 {
+		t.Errorf("OAMADDR $%02X during the sprite fetches, want 0", ppu.oamAddr)
+	}
+}
@@ -0,0 +1,69 @@
+// Package ppu implements the lookup of the RGB color of each pixel, with the
+// grayscale and color emphasis bits of PPUMASK applied.
+package ppu
+
+// emphasisDim is how much color emphasis darkens the channels it does not
+// emphasize
+const emphasisDim = 0.816
+
+// rgbTable holds the RGB color of each NES color under each of the 8
+// combinations of PPUMASK's emphasis bits, at emphasis<<6 | color. It is
+// built once from the palette, so the per-pixel lookup needs no branches.
+var rgbTable = buildRGBTable(&nesColorPalette)
+
+// buildRGBTable applies each combination of emphasis bits to a palette
+func buildRGBTable(palette *[64]uint32) (table [512]uint32) {
+	for emphasis := 0; emphasis < 8; emphasis++ {
+		for color, argb := range palette {
+			rgb := argb & 0x00FFFFFF
+			if emphasis != 0 {
+				rgb = emphasize(rgb, emphasis)
+			}
+			table[emphasis<<6|color] = rgb
+		}
+	}
+	return table
+}
+
+// emphasize darkens the channels emphasis (bit 0 red, 1 green, 2 blue) does
+// not name; with all three set, all are darkened
+func emphasize(rgb uint32, emphasis int) uint32 {
+	var out uint32
+	for channel := 0; channel < 3; channel++ {
+		shift := 16 - 8*channel // Red, green, blue
+		value := rgb >> shift & 0xFF
+		if emphasis&(1<<channel) == 0 || emphasis == 7 {
+			value = uint32(float64(value) * emphasisDim)
+		}
+		out |= value << shift
+	}
+	return out
+}
+
+// updateColorLookup takes the grayscale and emphasis bits from PPUMASK:
+// grayscale keeps only the brightness bits of each color index, and the
+// emphasis bits select one of rgbTable's palettes
+func (p *PPU) updateColorLookup() {
+	p.colorMask = 0x3F
+	if p.ppuMask&0x01 != 0 {
+		p.colorMask = 0x30
+	}
+	p.emphasis = uint16(p.ppuMask>>5) << 6
+}
+
+// colorToRGB returns the RGB color of a NES color index as PPUMASK shows it
+func (p *PPU) colorToRGB(colorIndex uint8) uint32 {
+	return rgbTable[p.emphasis|uint16(colorIndex&p.colorMask)]
+}
+
+// backdropColor returns the color shown where neither the background nor a
+// sprite is drawn: the one at $3F00, or, with rendering off and v pointing
+// into the palette, the one v points at. Some games draw with that while
+// rendering is off.
+func (p *PPU) backdropColor() uint32 {
+	address := uint16(0x3F00)
+	if !p.renderingEnabled && p.v&0x3F00 == 0x3F00 {
+		address = p.v
+	}
+	return p.colorToRGB(p.memory.Read(address))
+}
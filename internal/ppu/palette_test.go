@@ -0,0 +1,84 @@
+package ppu
+
+import "testing"
+
+func TestColorToRGB_MaskBits(t *testing.T) {
+	ppu := New()
+	ppu.Reset()
+
+	if got, want := ppu.colorToRGB(0x16), NESColorToRGB(0x16); got != want {
+		t.Errorf("Plain color $16 = %06X, want %06X", got, want)
+	}
+
+	ppu.WriteRegister(0x2001, 0x01) // Grayscale
+	if got, want := ppu.colorToRGB(0x16), NESColorToRGB(0x10); got != want {
+		t.Errorf("Grayscale color $16 = %06X, want the gray $10 %06X", got, want)
+	}
+
+	ppu.WriteRegister(0x2001, 0x20) // Emphasize red
+	white := NESColorToRGB(0x30)
+	got := ppu.colorToRGB(0x30)
+	if got>>16 != white>>16 {
+		t.Errorf("Red emphasis changed the red of white: %06X, was %06X", got, white)
+	}
+	if got&0xFF >= white&0xFF || got>>8&0xFF >= white>>8&0xFF {
+		t.Errorf("Red emphasis did not darken the green and blue of white: %06X", got)
+	}
+
+	ppu.WriteRegister(0x2001, 0xE0) // All three
+	if got := ppu.colorToRGB(0x30); got&0xFF >= white&0xFF || got>>16 >= white>>16 {
+		t.Errorf("Full emphasis did not darken every channel of white: %06X", got)
+	}
+}
+
+func TestColorToRGB_OutOfRangeIndex(t *testing.T) {
+	ppu := New()
+	ppu.Reset()
+	if got, want := ppu.colorToRGB(0xD6), NESColorToRGB(0x16); got != want {
+		t.Errorf("Color $D6 = %06X, want that of $16 %06X", got, want)
+	}
+}
+
+func TestBackdropColor(t *testing.T) {
+	ppuMem, _ := NewTestPPUMemorySetup()
+	ppu := New()
+	ppu.SetMemory(ppuMem)
+	ppu.Reset()
+
+	setAddr := func(address uint16) {
+		ppu.WriteRegister(0x2006, uint8(address>>8))
+		ppu.WriteRegister(0x2006, uint8(address))
+	}
+	drawLine := func(scanline int) uint32 {
+		for ppu.GetScanline() != scanline+1 {
+			ppu.Step()
+		}
+		return ppu.GetPixel(100, scanline)
+	}
+
+	setAddr(0x3F00)
+	ppu.WriteRegister(0x2007, 0x0F)
+	setAddr(0x3F04)
+	ppu.WriteRegister(0x2007, 0x2A)
+
+	// $3F04 keeps its own value, but color 0 of every palette shows $3F00
+	setAddr(0x3F04)
+	if got := ppu.ReadRegister(0x2007); got != 0x2A {
+		t.Errorf("$3F04 read $%02X, want $2A", got)
+	}
+	ppu.WriteRegister(0x2001, 0x0A) // Background, unclipped
+	if got, want := drawLine(10), NESColorToRGB(0x0F); got != want {
+		t.Errorf("Transparent background drawn %06X, want $3F00's %06X", got, want)
+	}
+
+	// With rendering off, v in the palette picks the backdrop
+	ppu.WriteRegister(0x2001, 0x00)
+	setAddr(0x3F04)
+	if got, want := drawLine(20), NESColorToRGB(0x2A); got != want {
+		t.Errorf("Backdrop with v at $3F04 drawn %06X, want %06X", got, want)
+	}
+	setAddr(0x2000)
+	if got, want := drawLine(30), NESColorToRGB(0x0F); got != want {
+		t.Errorf("Backdrop with v at $2000 drawn %06X, want %06X", got, want)
+	}
+}
@@ -4,6 +4,7 @@ package ppu
 import (
 	"fmt"
 	"gones/internal/memory"
+	"gones/internal/region"
 )
 
 // PPU represents the NES Picture Processing Unit (2C02)
@@ -24,11 +25,16 @@ type PPU struct {
 	x uint8  // Fine X scroll (3 bits)
 	w bool   // Write latch (toggles between first/second write)
 
+	// How far $2007 accesses while rendering moved the background: in X
+	// until the end of the line, in Y until the end of the frame
+	glitchX int
+	glitchY int
+
 	// PPU Memory
 	memory *memory.PPUMemory
 
 	// Rendering State
-	scanline    int // Current scanline (-1 to 260)
+	scanline    int // Current scanline (-1 to 260 on NTSC, 310 on PAL and Dendy)
 	cycle       int // Current cycle (0 to 340)
 	frameCount  uint64
 	oddFrame    bool
@@ -47,24 +53,70 @@ type PPU struct {
 	spriteIndexes    [8]uint8   // Original sprite indices for secondary OAM entries
 	sprite0OnScanline bool      // True if sprite 0 is present on current scanline
 
-	// Frame Buffer
+	// Frame Buffers: the PPU draws into frameBuffer and copies each finished
+	// frame to frontBuffer, which is what the rest of the emulator shows
 	frameBuffer [256 * 240]uint32 // RGB frame buffer
+	frontBuffer [256 * 240]uint32 // Last finished frame
 
 	// Callbacks
 	nmiCallback           func()
 	frameCompleteCallback func()
 
+	// Mapper signals (nil when the mapper takes none): PPU address line
+	// A12 rising, and the start of each scanline
+	a12Callback      func()
+	scanlineCallback func(scanline int, rendering bool)
+	a12              bool   // A12 of the last fetch or $2006/$2007 access
+	a12Fell          uint64 // cycleCount A12 last went low on
+
 	// Rendering Control
 	backgroundEnabled bool
 	spritesEnabled    bool
 	renderingEnabled  bool
 
+	// Color lookup from PPUMASK: the color index bits grayscale keeps, and
+	// the emphasis bits as an offset into rgbTable
+	colorMask uint8
+	emphasis  uint16
+
 	// Timing
-	cycleCount uint64
+	cycleCount     uint64
+	scanlines      int // Per frame, the pre-render line included
+	vblankScanline int // Scanline vblank starts on
+
+	// cycleCount from which writes to $2000, $2001, $2005 and $2006 are
+	// taken again after power on or reset (see StartWarmUp)
+	warmUpEnd uint64
+
+	// Background tile row being drawn, fetched once for its 8 pixels
+	backgroundTile backgroundTile
+
+	// Layers drawn, for debugging; not part of the console's state
+	layers LayerFilter
+
+	// Scroll of each scanline while tracked, for debugging (nil when not)
+	scrollLines *[240]ScanlineScroll
+
+	// Sprites of the current scanline past the eighth, drawn when the
+	// unlimited sprites enhancement is on; not part of the console's state
+	unlimitedSprites bool
+	extraSprites     [maxExtraSprites * 4]uint8
+	extraSpriteCount uint8
+}
 
-	// Background pixel cache for sprite 0 hit optimization
-	currentBackgroundPixel SpritePixel
-	backgroundPixelCached  bool
+// backgroundTile holds the nametable, attribute and pattern bytes of one row
+// of a background tile, so its pixels after the first need no memory reads
+type backgroundTile struct {
+	valid       bool
+	nametable   int
+	tileX       int
+	tileY       int
+	row         int    // Pixel row within the tile
+	patternBase uint16 // Pattern table the bytes were read from
+
+	paletteIndex uint8
+	patternLow   uint8
+	patternHigh  uint8
 }
 
 // New creates a new PPU instance
@@ -75,6 +127,11 @@ func New() *PPU {
 		frameCount: 0,
 		oddFrame:   false,
 
+		colorMask: 0x3F,
+
+		scanlines:      region.NTSC.Timing().Scanlines,
+		vblankScanline: region.NTSC.Timing().VBlankScanline,
+
 		// Initialize frame buffer to black
 		frameBuffer: [256 * 240]uint32{},
 	}
@@ -94,6 +151,7 @@ func (p *PPU) Reset() {
 	p.v = 0
 	p.t = 0
 	p.x = 0
+	p.glitchX, p.glitchY = 0, 0
 	p.w = false
 
 	p.scanline = -1
@@ -104,15 +162,21 @@ func (p *PPU) Reset() {
 	p.readBuffer = 0
 
 	p.spriteCount = 0
+	p.extraSpriteCount = 0
 	p.sprite0Hit = false
 	p.spriteOverflow = false
 
 	p.backgroundEnabled = false
 	p.spritesEnabled = false
 	p.renderingEnabled = false
+	p.updateColorLookup()
 
 	p.cycleCount = 0
+	p.warmUpEnd = 0
 	p.lastEvalScanline = -999
+	p.backgroundTile.valid = false
+	p.a12 = false
+	p.a12Fell = 0
 
 	// Clear OAM
 	for i := range p.oam {
@@ -123,11 +187,21 @@ func (p *PPU) Reset() {
 	for i := range p.frameBuffer {
 		p.frameBuffer[i] = 0x000000 // Black in RGB format
 	}
+	p.frontBuffer = p.frameBuffer
+}
+
+// SetRegion sets the frame timing to that of a region's console: PAL and
+// Dendy frames are 312 scanlines, and the Dendy starts vblank 50 scanlines
+// later than the others
+func (p *PPU) SetRegion(r region.Region) {
+	p.scanlines = r.Timing().Scanlines
+	p.vblankScanline = r.Timing().VBlankScanline
 }
 
 // SetMemory sets the PPU memory interface
 func (p *PPU) SetMemory(memory *memory.PPUMemory) {
 	p.memory = memory
+	p.backgroundTile.valid = false
 }
 
 // SetNMICallback sets the NMI callback function
@@ -140,6 +214,20 @@ func (p *PPU) SetFrameCompleteCallback(callback func()) {
 	p.frameCompleteCallback = callback
 }
 
+// SetA12Callback sets the function called when PPU address line A12 rises
+// after being low long enough for the MMC3 to count it, for mappers clocked
+// by it (nil for none)
+func (p *PPU) SetA12Callback(callback func()) {
+	p.a12Callback = callback
+}
+
+// SetScanlineCallback sets the function called at the start of every
+// scanline with whether rendering is on, for mappers counting scanlines (nil
+// for none)
+func (p *PPU) SetScanlineCallback(callback func(scanline int, rendering bool)) {
+	p.scanlineCallback = callback
+}
+
 // ReadRegister reads from a PPU register (CPU $2000-$2007)
 func (p *PPU) ReadRegister(address uint16) uint8 {
 	switch address {
@@ -161,7 +249,7 @@ func (p *PPU) ReadRegister(address uint16) uint8 {
 	case 0x2003: // OAMADDR - write only
 		return p.ppuStatus & 0x1F // Return open bus with lower 5 bits
 	case 0x2004: // OAMDATA
-		return p.oam[p.oamAddr]
+		return p.readOAMData()
 	case 0x2005: // PPUSCROLL - write only
 		return p.ppuStatus & 0x1F // Return open bus with lower 5 bits
 	case 0x2006: // PPUADDR - write only
@@ -175,6 +263,13 @@ func (p *PPU) ReadRegister(address uint16) uint8 {
 
 // WriteRegister writes to a PPU register (CPU $2000-$2007)
 func (p *PPU) WriteRegister(address uint16, value uint8) {
+	if p.cycleCount < p.warmUpEnd {
+		switch address {
+		case 0x2000, 0x2001, 0x2005, 0x2006:
+			return // Still warming up
+		}
+	}
+
 	switch address {
 	case 0x2000: // PPUCTRL
 		p.ppuCtrl = value
@@ -205,6 +300,11 @@ func (p *PPU) WriteOAM(address uint8, value uint8) {
 	p.oam[address] = value
 }
 
+// ReadOAM reads OAM at the specified address without the side effects of $2004
+func (p *PPU) ReadOAM(address uint8) uint8 {
+	return p.oam[address]
+}
+
 // Step advances the PPU by one cycle
 func (p *PPU) Step() {
 	p.cycleCount++
@@ -215,7 +315,7 @@ func (p *PPU) Step() {
 		p.cycle = 0
 		p.scanline++
 
-		if p.scanline > 260 {
+		if p.scanline > p.scanlines-2 {
 			p.scanline = -1
 			p.frameCount++
 			p.oddFrame = !p.oddFrame
@@ -224,10 +324,17 @@ func (p *PPU) Step() {
 				p.frameCompleteCallback()
 			}
 		}
+
+		if p.scanlineCallback != nil {
+			p.scanlineCallback(p.scanline, p.renderingEnabled)
+		}
 	}
 
-	// Handle VBlank start at scanline 241, cycle 1
-	if p.scanline == 241 && p.cycle == 1 {
+	// Handle VBlank start at scanline 241 (291 on the Dendy), cycle 1
+	if p.scanline == p.vblankScanline && p.cycle == 1 {
+		// The picture is finished, and shown until the next one is
+		p.frontBuffer = p.frameBuffer
+
 		// Set VBL flag
 		p.ppuStatus |= 0x80
 		// Clear sprite 0 hit and sprite overflow flags at VBlank START (critical timing fix)
@@ -238,7 +345,7 @@ func (p *PPU) Step() {
 		
 		// Log sprite 0 hit flag clearing for debugging
 		if wasSprite0Hit {
-			fmt.Printf("[SPRITE0_CLEAR] Frame %d: Sprite 0 hit flag cleared at VBlank start (scanline 241)\n", p.frameCount)
+			fmt.Printf("[SPRITE0_CLEAR] Frame %d: Sprite 0 hit flag cleared at VBlank start (scanline %d)\n", p.frameCount, p.scanline)
 		}
 		
 		// Trigger NMI if enabled
@@ -262,7 +369,76 @@ func (p *PPU) Step() {
 	// Handle rendering cycles
 	if p.scanline >= -1 && p.scanline < 240 {
 		p.renderCycle()
+		if p.renderingEnabled {
+			p.stepOAM()
+			p.endAddrGlitches()
+		}
+		if p.a12Callback != nil && p.renderingEnabled {
+			p.followPatternFetches()
+		}
+	}
+}
+
+// a12Filter is how many PPU cycles A12 has to stay low before a rise counts.
+// The MMC3 only counts a rise after three falling edges of M2 (the CPU
+// clock) with A12 low, which ignores the rises between the fetches of one
+// scanline, 4 PPU cycles apart, and keeps one per line.
+const a12Filter = 10
+
+// followPatternFetches tracks A12 through the fetches of a rendered scanline.
+// Each 8-cycle slot fetches a nametable byte and an attribute byte (or two
+// nametable bytes, for sprites), with A12 low, then the two pattern bytes:
+// tiles from the background's table at cycles 1-256 and 321-336, sprites
+// from theirs at cycles 257-320. Nametable fetches end the line.
+func (p *PPU) followPatternFetches() {
+	switch {
+	case p.cycle == 0 || p.cycle > 337:
+		return
+	case p.cycle == 337:
+		// The two nametable fetches closing the line
+		p.setA12(false)
+	case p.cycle >= 257 && p.cycle <= 320:
+		switch (p.cycle - 257) & 7 {
+		case 0:
+			p.setA12(false)
+		case 4:
+			p.setA12(p.spritePatternA12((p.cycle - 257) / 8))
+		}
+	default:
+		switch (p.cycle - 1) & 7 {
+		case 0:
+			p.setA12(false)
+		case 4:
+			p.setA12(p.ppuCtrl&0x10 != 0)
+		}
+	}
+}
+
+// spritePatternA12 returns whether a sprite slot's pattern fetches reach the
+// table at $1000. 8x16 sprites pick their table by tile, and the slots of a
+// line with fewer than eight sprites fetch tile $FF, from $1000.
+func (p *PPU) spritePatternA12(slot int) bool {
+	if p.ppuCtrl&0x20 == 0 {
+		return p.ppuCtrl&0x08 != 0
+	}
+	if slot >= int(p.spriteCount) {
+		return true
+	}
+	return p.secondaryOAM[slot*4+1]&0x01 != 0
+}
+
+// setA12 sets the level of PPU address line A12, calling the A12 callback
+// when it rises after being low for a12Filter cycles
+func (p *PPU) setA12(high bool) {
+	switch {
+	case high && !p.a12:
+		if p.a12Callback != nil && p.cycleCount-p.a12Fell >= a12Filter {
+			p.a12Callback()
+		}
+	case !high && p.a12:
+		p.a12Fell = p.cycleCount
 	}
+	p.a12 = high
 }
 
 // renderCycle handles rendering for a single PPU cycle
@@ -283,6 +459,11 @@ func (p *PPU) renderCycle() {
 		}
 	}
 
+	// Scroll tracking, as of the scanline's first pixel
+	if p.scrollLines != nil && p.scanline >= 0 && p.cycle == 2 {
+		p.recordScroll()
+	}
+
 	// Only render pixels during visible scanlines and cycles
 	// TIMING FIX: Sprite 0 hit detection should start at cycle 2 according to NES spec
 	if p.scanline < 0 || p.scanline >= 240 || p.cycle < 2 || p.cycle > 257 {
@@ -294,16 +475,23 @@ func (p *PPU) renderCycle() {
 		return
 	}
 
-	// Skip rendering entirely if both background and sprites are disabled
-	if !p.backgroundEnabled && !p.spritesEnabled {
-		return
-	}
-
 	// Calculate pixel position
 	// TIMING FIX: Adjust for cycle 2 start (cycle 2 = pixel 0)
 	pixelX := p.cycle - 2 // Convert to 0-based with correct timing
 	pixelY := p.scanline
 
+	// With both background and sprites disabled only the backdrop is drawn
+	if !p.backgroundEnabled && !p.spritesEnabled {
+		p.frameBuffer[pixelY*256+pixelX] = p.backdropColor()
+		return
+	}
+
+	// Fetch the first tile of each scanline afresh, in case VRAM changed
+	// since the last one
+	if pixelX == 0 {
+		p.backgroundTile.valid = false
+	}
+
 	// Initialize as transparent pixels
 	var backgroundPixel SpritePixel = SpritePixel{transparent: true}
 	var spritePixel SpritePixel = SpritePixel{transparent: true}
@@ -311,12 +499,6 @@ func (p *PPU) renderCycle() {
 	// Render background pixel only if enabled via PPUMASK
 	if p.backgroundEnabled {
 		backgroundPixel = p.renderBackgroundPixel(pixelX, pixelY)
-		// Cache background pixel for sprite 0 hit detection optimization
-		p.currentBackgroundPixel = backgroundPixel
-		p.backgroundPixelCached = true
-	} else {
-		// Clear background pixel cache when background rendering is disabled
-		p.backgroundPixelCached = false
 	}
 
 	// Render sprite pixel if enabled
@@ -329,6 +511,17 @@ func (p *PPU) renderCycle() {
 		}
 	}
 
+	// Sprite 0 hit, checked against the background pixel just rendered.
+	// Sprite 0 is always first on its scanlines, so where it is opaque it is
+	// the sprite pixel.
+	if !spritePixel.transparent && p.isOriginalSprite0(int(spritePixel.spriteIndex)) {
+		p.checkSprite0Hit(pixelX, pixelY, backgroundPixel, spritePixel.colorIndex)
+	}
+
+	if p.layers != (LayerFilter{}) {
+		backgroundPixel, spritePixel = p.filterLayers(pixelX, pixelY, backgroundPixel, spritePixel)
+	}
+
 	// Combine background and sprite pixels
 	finalColor := p.compositeFinalPixel(backgroundPixel, spritePixel)
 
@@ -353,6 +546,7 @@ func (p *PPU) evaluateSprites() {
 	p.lastEvalScanline = p.scanline
 
 	p.spriteCount = 0
+	p.extraSpriteCount = 0
 	p.spriteOverflow = false
 	p.sprite0OnScanline = false
 
@@ -419,6 +613,10 @@ func (p *PPU) evaluateSprites() {
 					fmt.Printf("[PPU_SPRITE] Sprite overflow detected on scanline %d (frame %d)\n", 
 						p.scanline, p.frameCount)
 				}
+
+				if p.unlimitedSprites {
+					p.evaluateExtraSprites(spriteIndex, spriteHeight)
+				}
 				break
 			}
 		}
@@ -530,6 +728,8 @@ func (p *PPU) renderBackgroundPixel(pixelX, pixelY int) SpritePixel {
 		scrollY = 0
 		effectiveNametable = 0
 	}
+	scrollX += p.glitchX
+	scrollY += p.glitchY
 	
 	// Apply scroll to get world coordinates
 	worldX := pixelX + scrollX
@@ -593,22 +793,6 @@ func (p *PPU) renderBackgroundPixel(pixelX, pixelY int) SpritePixel {
 		return SpritePixel{transparent: true}
 	}
 
-	// Fetch nametable byte - determines which tile to use
-	nametableAddr := 0x2000 | (uint16(finalNametable&3) << 10) | uint16(tileY*32+tileX)
-	tileID := p.memory.Read(nametableAddr)
-
-	// Fetch attribute table byte - determines palette selection
-	attributeAddr := 0x23C0 | (uint16(finalNametable&3) << 10) | uint16((tileY>>2)*8+(tileX>>2))
-	attributeByte := p.memory.Read(attributeAddr)
-
-	// Extract 2-bit palette index from attribute byte 
-	// Each attribute byte controls a 4x4 tile area (32x32 pixels)
-	// Divided into 4 quadrants of 2x2 tiles each
-	// blockID: 0=top-left, 1=top-right, 2=bottom-left, 3=bottom-right
-	// Use bit operations for better performance
-	blockID := ((tileX & 3) >> 1) + ((tileY & 3) >> 1) * 2
-	paletteIndex := (attributeByte >> (blockID << 1)) & 0x03
-
 	// Determine pattern table base address from PPUCTRL bit 4
 	var patternTableBase uint16
 	if p.ppuCtrl&0x10 != 0 {
@@ -617,17 +801,13 @@ func (p *PPU) renderBackgroundPixel(pixelX, pixelY int) SpritePixel {
 		patternTableBase = 0x0000 // Pattern table 0
 	}
 
-	// Fetch pattern table data
-	patternAddr := patternTableBase + uint16(tileID)*16 + uint16(pixelInTileY)
-
-	// Read pattern data using standard NES format
-	patternLow := p.memory.Read(patternAddr)
-	patternHigh := p.memory.Read(patternAddr + 0x08)
+	tile := p.fetchBackgroundTile(finalNametable, tileX, tileY, pixelInTileY, patternTableBase)
+	paletteIndex := tile.paletteIndex
 
 	// Extract the specific pixel bits
 	bitShift := 7 - pixelInTileX
-	bit0 := (patternLow >> bitShift) & 1
-	bit1 := (patternHigh >> bitShift) & 1
+	bit0 := (tile.patternLow >> bitShift) & 1
+	bit1 := (tile.patternHigh >> bitShift) & 1
 	colorIndex := (bit1 << 1) | bit0
 
 	// Calculate palette address
@@ -640,7 +820,7 @@ func (p *PPU) renderBackgroundPixel(pixelX, pixelY int) SpritePixel {
 
 	// Read color and convert to RGB
 	nesColorIndex := p.memory.Read(paletteAddr)
-	rgbColor := p.NESColorToRGB(nesColorIndex)
+	rgbColor := p.colorToRGB(nesColorIndex)
 
 	// Color debugging can be enabled here if needed
 
@@ -654,18 +834,60 @@ func (p *PPU) renderBackgroundPixel(pixelX, pixelY int) SpritePixel {
 	}
 }
 
+// fetchBackgroundTile returns the bytes of a background tile row, reading
+// them from memory only when the row differs from the last one fetched
+func (p *PPU) fetchBackgroundTile(nametable, tileX, tileY, row int, patternTableBase uint16) *backgroundTile {
+	tile := &p.backgroundTile
+	if tile.valid && tile.nametable == nametable && tile.tileX == tileX && tile.tileY == tileY &&
+		tile.row == row && tile.patternBase == patternTableBase {
+		return tile
+	}
+
+	// Fetch nametable byte - determines which tile to use
+	nametableAddr := 0x2000 | (uint16(nametable&3) << 10) | uint16(tileY*32+tileX)
+	tileID := p.memory.Read(nametableAddr)
+
+	// Fetch attribute table byte - determines palette selection
+	attributeAddr := 0x23C0 | (uint16(nametable&3) << 10) | uint16((tileY>>2)*8+(tileX>>2))
+	attributeByte := p.memory.Read(attributeAddr)
+
+	// Extract 2-bit palette index from attribute byte
+	// Each attribute byte controls a 4x4 tile area (32x32 pixels)
+	// Divided into 4 quadrants of 2x2 tiles each
+	// blockID: 0=top-left, 1=top-right, 2=bottom-left, 3=bottom-right
+	blockID := ((tileX & 3) >> 1) + ((tileY&3)>>1)*2
+	tile.paletteIndex = (attributeByte >> (blockID << 1)) & 0x03
+
+	// Read pattern data using standard NES format
+	patternAddr := patternTableBase + uint16(tileID)*16 + uint16(row)
+	tile.patternLow = p.memory.Read(patternAddr)
+	tile.patternHigh = p.memory.Read(patternAddr + 0x08)
+
+	tile.valid = true
+	tile.nametable, tile.tileX, tile.tileY, tile.row = nametable, tileX, tileY, row
+	tile.patternBase = patternTableBase
+	return tile
+}
+
 // renderSpritePixel renders a single sprite pixel
 func (p *PPU) renderSpritePixel(pixelX, pixelY int) SpritePixel {
+	return p.spritePixel(pixelX, pixelY, 0)
+}
+
+// spritePixel returns the sprite pixel at a position, skipping the sprites
+// of the palettes set in hiddenPalettes (see LayerFilter)
+func (p *PPU) spritePixel(pixelX, pixelY int, hiddenPalettes uint8) SpritePixel {
 
-	// Check each sprite in secondary OAM (in forward order for correct priority)
+	// Check each sprite in secondary OAM (in forward order for correct priority),
+	// then the sprites past the eighth when the sprite limit is lifted
 	// Lower OAM index = higher priority, so first non-transparent sprite wins
-	for i := 0; i < int(p.spriteCount); i++ {
-		secondaryIndex := i * 4
+	for i := 0; i < int(p.spriteCount)+int(p.extraSpriteCount); i++ {
+		sprite := p.spriteSlot(i)
 
-		sY := int(p.secondaryOAM[secondaryIndex])
-		tileIndex := p.secondaryOAM[secondaryIndex+1]
-		attributes := p.secondaryOAM[secondaryIndex+2]
-		sX := int(p.secondaryOAM[secondaryIndex+3])
+		sY := int(sprite[0])
+		tileIndex := sprite[1]
+		attributes := sprite[2]
+		sX := int(sprite[3])
 
 		// Determine sprite height and handle 8x16 mode
 		spriteHeight := 8
@@ -708,26 +930,14 @@ func (p *PPU) renderSpritePixel(pixelX, pixelY int) SpritePixel {
 					p.frameCount, pixelX, pixelY, spritePixelX, spritePixelY, colorIndex)
 			}
 
-			if colorIndex != 0 { // Non-transparent pixel
-				
-				// Check for sprite 0 hit FIRST, before any other processing
-				// This ensures sprite 0 hit is never overridden by subsequent sprites
-				if p.isOriginalSprite0(i) && !p.sprite0Hit {
-					// Reduced debug: Only log when actually attempting sprite 0 hit check
-					if pixelX >= 90 && pixelX <= 95 && pixelY >= 28 && pixelY <= 32 {
-						fmt.Printf("[SPRITE0_CHECK] Frame %d: Checking hit at (%d,%d), colorIdx %d\n", 
-							p.frameCount, pixelX, pixelY, colorIndex)
-					}
-					p.checkSprite0Hit(pixelX, pixelY, colorIndex)
-				}
-
+			if colorIndex != 0 && hiddenPalettes&(1<<(attributes&0x03)) == 0 { // Non-transparent pixel
 				// Extract palette index from attributes (bits 1-0)
 				paletteIndex := attributes & 0x03
 
 				// Calculate sprite palette address
 				paletteAddr := 0x3F10 + uint16(paletteIndex)*4 + uint16(colorIndex)
 				nesColorIndex := p.memory.Read(paletteAddr)
-				rgbColor := p.NESColorToRGB(nesColorIndex)
+				rgbColor := p.colorToRGB(nesColorIndex)
 
 				spritePixel := SpritePixel{
 					colorIndex:   colorIndex,
@@ -818,8 +1028,9 @@ func (p *PPU) isOriginalSprite0(secondaryOAMIndex int) bool {
 	return p.spriteIndexes[secondaryOAMIndex] == 0
 }
 
-// checkSprite0Hit checks for sprite 0 hit detection
-func (p *PPU) checkSprite0Hit(pixelX, pixelY int, spriteColorIndex uint8) {
+// checkSprite0Hit checks for sprite 0 hit detection against the background
+// pixel rendered at the same position
+func (p *PPU) checkSprite0Hit(pixelX, pixelY int, backgroundPixel SpritePixel, spriteColorIndex uint8) {
 
 	if p.sprite0Hit {
 		return // Already set - never clear this flag once set
@@ -853,10 +1064,6 @@ func (p *PPU) checkSprite0Hit(pixelX, pixelY int, spriteColorIndex uint8) {
 		return
 	}
 
-	// CRITICAL FIX: Always render fresh background pixel instead of using cache
-	// The cached pixel might be stale or from wrong coordinates, causing missed detection
-	backgroundPixel := p.renderBackgroundPixel(pixelX, pixelY)
-
 	// Note: Removed artificial sprite 0 hit forcing - let natural background pixels determine hits
 
 	// Debug: Only log when background is non-transparent (potential hit condition)
@@ -994,12 +1201,7 @@ func (p *PPU) compositeFinalPixel(background, sprite SpritePixel) uint32 {
 	if sprite.transparent {
 		if background.transparent {
 			// Both transparent - use backdrop color
-			backdropColor := p.memory.Read(0x3F00)
-			rgbColor := p.NESColorToRGB(backdropColor)
-
-			// Backdrop color debugging can be enabled here if needed
-
-			return rgbColor
+			return p.backdropColor()
 		}
 		return background.rgbColor
 	}
@@ -1025,6 +1227,7 @@ func (p *PPU) updateRenderingFlags() {
 	p.backgroundEnabled = (p.ppuMask & 0x08) != 0
 	p.spritesEnabled = (p.ppuMask & 0x10) != 0
 	p.renderingEnabled = p.backgroundEnabled || p.spritesEnabled
+	p.updateColorLookup()
 }
 
 // checkNMI checks if an NMI should be triggered
@@ -1060,7 +1263,9 @@ func (p *PPU) writePPUAddr(value uint8) {
 		// Second write: low byte
 		p.t = (p.t & 0xFF00) | uint16(value)
 		p.v = p.t
+		p.glitchX, p.glitchY = 0, 0
 		p.w = false
+		p.setA12(p.v&0x1000 != 0)
 	}
 }
 
@@ -1079,17 +1284,12 @@ func (p *PPU) readPPUData() uint8 {
 		} else {
 			// Other data is buffered
 			data = p.readBuffer
-			p.readBuffer = p.memory.Read(p.v)
+			p.readBuffer = p.memory.ReadData(p.v)
 		}
 	}
 
 	// Auto-increment address (this must happen regardless of memory availability)
-	if p.ppuCtrl&0x04 != 0 {
-		p.v += 32 // Increment by 32 (down)
-	} else {
-		p.v += 1 // Increment by 1 (across)
-	}
-	p.v &= 0x3FFF // Wrap to 14-bit address space
+	p.incrementAddr()
 
 	return data
 }
@@ -1101,17 +1301,61 @@ func (p *PPU) writePPUData(value uint8) {
 	}
 
 	// Auto-increment address (this must happen regardless of memory availability)
-	if p.ppuCtrl&0x04 != 0 {
+	p.incrementAddr()
+}
+
+// incrementAddr moves v on after a $2007 access, by 1 or 32. While rendering,
+// the access instead sets off the coarse X and Y increments rendering makes
+// to v, at once: the rest of the line is drawn a tile to the right and the
+// rest of the frame a line down. The background is drawn from t here, so the
+// moves are kept in glitchX and glitchY for it.
+func (p *PPU) incrementAddr() {
+	switch {
+	case p.renderingEnabled && p.scanline < 240:
+		p.incrementX()
+		p.incrementY()
+		p.glitchX = (p.glitchX + 8) % 512 // Both nametables across
+		p.glitchY = (p.glitchY + 1) % 480 // and down
+	case p.ppuCtrl&0x04 != 0:
 		p.v += 32 // Increment by 32 (down)
-	} else {
+	default:
 		p.v += 1 // Increment by 1 (across)
 	}
 	p.v &= 0x3FFF // Wrap to 14-bit address space
+	p.setA12(p.v&0x1000 != 0)
+}
+
+// endAddrGlitches drops the moves of incrementAddr when rendering copies X
+// from t to v, at the end of each line, and Y, on the pre-render line
+func (p *PPU) endAddrGlitches() {
+	switch {
+	case p.cycle == 257:
+		p.glitchX = 0
+	case p.scanline == -1 && p.cycle == 304:
+		p.glitchY = 0
+	}
 }
 
-// GetFrameBuffer returns the current frame buffer
+// GetFrameBuffer returns a copy of the last finished frame
 func (p *PPU) GetFrameBuffer() [256 * 240]uint32 {
-	return p.frameBuffer
+	return p.frontBuffer
+}
+
+// FrameBuffer returns the last finished frame without copying it. The PPU
+// draws the next frame elsewhere and replaces this one whole at the start of
+// vblank, so it never holds part of a frame.
+func (p *PPU) FrameBuffer() *[256 * 240]uint32 {
+	return &p.frontBuffer
+}
+
+// GetPixel returns a single pixel (0xRRGGBB) of the frame being drawn, without
+// copying the frame. While rendering, rows above the current scanline already
+// hold the new frame.
+func (p *PPU) GetPixel(x, y int) uint32 {
+	if x < 0 || x >= 256 || y < 0 || y >= 240 {
+		return 0
+	}
+	return p.frameBuffer[y*256+x]
 }
 
 // GetFrameCount returns the current frame count
@@ -1144,11 +1388,42 @@ func (p *PPU) IsVBlank() bool {
 	return (p.ppuStatus & 0x80) != 0
 }
 
+// StartWarmUp has the PPU ignore writes to $2000, $2001, $2005 and $2006
+// for as long as a console's does after power on or reset: it comes up at
+// the top of the picture and takes them once it reaches the pre-render line,
+// about 29658 CPU cycles later on NTSC and 33132 on PAL. Other registers
+// work throughout, so games wait two vblanks on $2002 before setting up.
+func (p *PPU) StartWarmUp() {
+	p.warmUpEnd = p.cycleCount + uint64(p.scanlines-1)*341
+}
+
+// WarmingUp returns whether the PPU still ignores the writes StartWarmUp
+// describes
+func (p *PPU) WarmingUp() bool {
+	return p.cycleCount < p.warmUpEnd
+}
+
 // GetCycleCount returns the total PPU cycle count
 func (p *PPU) GetCycleCount() uint64 {
 	return p.cycleCount
 }
 
+// CyclesToNextEvent returns how many Steps it takes the PPU to reach the next
+// point the CPU sees without reading a register: the start of vblank, which
+// may raise an NMI, or the end of the frame. Between events the PPU can be
+// stepped in bulk.
+func (p *PPU) CyclesToNextEvent() int {
+	frame := p.scanlines * 341
+	position := (p.scanline+1)*341 + p.cycle
+	vblank := (p.vblankScanline+1)*341 + 1
+
+	cycles := frame - position // To the end of the frame
+	if toVBlank := vblank - position; toVBlank > 0 && toVBlank < cycles {
+		cycles = toVBlank
+	}
+	return max(cycles, 1)
+}
+
 // EnableBackgroundDebugLogging enables background debug logging
 func (p *PPU) EnableBackgroundDebugLogging(enabled bool) {
 	// Debug logging placeholder - can be extended for actual logging
@@ -1184,7 +1459,8 @@ func NESColorToRGB(colorIndex uint8) uint32 {
 	return nesColorPalette[colorIndex] & 0x00FFFFFF
 }
 
-// NESColorToRGB converts a NES color index to RGB value (PPU method)
+// NESColorToRGB converts a NES color index to RGB value (PPU method), without
+// PPUMASK's grayscale and emphasis
 func (p *PPU) NESColorToRGB(colorIndex uint8) uint32 {
 	return NESColorToRGB(colorIndex)
 }
@@ -1194,6 +1470,7 @@ func (p *PPU) ClearFrameBuffer(color uint32) {
 	for i := range p.frameBuffer {
 		p.frameBuffer[i] = color
 	}
+	p.frontBuffer = p.frameBuffer
 }
 
 // Scroll helper methods for VRAM address manipulation
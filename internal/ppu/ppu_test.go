@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 	"gones/internal/memory"
+	"gones/internal/region"
 )
 
 // MockCartridge implements a simple cartridge for testing
@@ -565,6 +566,56 @@ func TestPPUFrameBuffer(t *testing.T) {
 	}
 }
 
+// TestPPUFrameBufferNoCopy tests that FrameBuffer shares the PPU's finished frame
+func TestPPUFrameBufferNoCopy(t *testing.T) {
+	ppu := New()
+	ppu.Reset()
+
+	frameBuffer := ppu.FrameBuffer()
+	ppu.frontBuffer[100] = 0x123456
+	if frameBuffer[100] != 0x123456 {
+		t.Errorf("Expected FrameBuffer to share the PPU frame, got pixel %06X", frameBuffer[100])
+	}
+	if allocs := testing.AllocsPerRun(10, func() { ppu.FrameBuffer() }); allocs != 0 {
+		t.Errorf("Expected FrameBuffer not to allocate, got %v allocations", allocs)
+	}
+}
+
+// TestPPUDoubleBuffering tests that the finished frame is only replaced at vblank
+func TestPPUDoubleBuffering(t *testing.T) {
+	ppuMem, _ := NewTestPPUMemorySetup()
+	ppu := New()
+	ppu.SetMemory(ppuMem)
+	ppu.Reset()
+
+	runTo := func(scanline int) {
+		for ppu.GetScanline() != scanline {
+			ppu.Step()
+		}
+	}
+	setBackdrop := func(color uint8) {
+		ppuMem.Write(0x3F00, color)
+	}
+	first, second := NESColorToRGB(0x16), NESColorToRGB(0x2A)
+
+	setBackdrop(0x16)
+	runTo(242)
+	setBackdrop(0x2A)
+	runTo(100)
+	frameBuffer := ppu.FrameBuffer()
+	if frameBuffer[50*256] != first || frameBuffer[200*256] != first {
+		t.Errorf("Finished frame changed mid-frame: %06X, %06X", frameBuffer[50*256], frameBuffer[200*256])
+	}
+	if got := ppu.GetPixel(0, 50); got != second {
+		t.Errorf("Frame being drawn has %06X at line 50, want %06X", got, second)
+	}
+
+	runTo(242)
+	if frameBuffer[50*256] != second || frameBuffer[200*256] != second {
+		t.Errorf("Finished frame not replaced at vblank: %06X, %06X", frameBuffer[50*256], frameBuffer[200*256])
+	}
+}
+
 // TestPPURenderingFlags tests rendering enable/disable logic
 func TestPPURenderingFlags(t *testing.T) {
 	ppu := New()
@@ -747,6 +798,76 @@ func TestPatternTableAccess(t *testing.T) {
 	}
 }
 
+// TestBackgroundTileFetchedOncePerTile tests that a scanline reads each
+// tile's pattern bytes once, not once per pixel
+func TestBackgroundTileFetchedOncePerTile(t *testing.T) {
+	ppuMem, mockCart := NewTestPPUMemorySetup()
+	ppu := New()
+	ppu.SetMemory(ppuMem)
+	ppu.Reset()
+
+	// Tile 0, row 0: pixels alternate between colors 1 and 2
+	mockCart.SetCHRByte(0x0000, 0xAA)
+	mockCart.SetCHRByte(0x0008, 0x55)
+	ppuMem.Write(0x3F00, 0x0F)
+	ppuMem.Write(0x3F01, 0x16)
+	ppuMem.Write(0x3F02, 0x2A)
+	ppu.WriteRegister(0x2001, 0x08) // Background only
+
+	for ppu.GetScanline() != 1 {
+		ppu.Step()
+	}
+
+	// Every tile of the blank nametable is tile 0
+	if reads := mockCart.GetCHRReadCount(0x0000); reads != 32 {
+		t.Errorf("Expected 32 reads of the pattern low byte for 32 tiles, got %d", reads)
+	}
+	want := [2]uint32{ppu.NESColorToRGB(0x16), ppu.NESColorToRGB(0x2A)}
+	for x := 0; x < 256; x++ {
+		if got := ppu.GetPixel(x, 0); got != want[x&1] {
+			t.Fatalf("Pixel %d = %06X, want %06X", x, got, want[x&1])
+		}
+	}
+}
+
+// TestSprite0HitUsesRenderedBackground tests sprite 0 hits against the
+// background pixel the pipeline rendered
+func TestSprite0HitUsesRenderedBackground(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		ctrl    uint8
+		wantHit bool
+	}{
+		{"Opaque background", 0x00, true},
+		{"Transparent background", 0x10, false}, // Background from the blank $1000 table
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ppuMem, mockCart := NewTestPPUMemorySetup()
+			ppu := New()
+			ppu.SetMemory(ppuMem)
+			ppu.Reset()
+
+			// Tile 0 of the $0000 table is solid color 1
+			for row := uint16(0); row < 8; row++ {
+				mockCart.SetCHRByte(row, 0xFF)
+			}
+			ppu.WriteOAM(0, 10) // Sprite 0: Y, tile 0, attributes, X
+			ppu.WriteOAM(1, 0)
+			ppu.WriteOAM(2, 0)
+			ppu.WriteOAM(3, 20)
+			ppu.WriteRegister(0x2000, tc.ctrl)
+			ppu.WriteRegister(0x2001, 0x1E) // Background and sprites, unclipped
+
+			for ppu.GetScanline() != 20 {
+				ppu.Step()
+			}
+			if hit := ppu.ReadRegister(0x2002)&0x40 != 0; hit != tc.wantHit {
+				t.Errorf("Sprite 0 hit = %v, want %v", hit, tc.wantHit)
+			}
+		})
+	}
+}
+
 // TestNametableAccess tests Nametable access ($2000-$2FFF)
 func TestNametableAccess(t *testing.T) {
 	ppuMem, _ := NewTestPPUMemorySetup()
@@ -2994,4 +3115,142 @@ func TestScrollWriteLatchBehavior(t *testing.T) {
 	if ppu.w {
 		t.Error("Expected write latch to be false after second PPUADDR write")
 	}
-}
\ No newline at end of file
+}
+func TestA12Filter(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		ctrl  uint8
+		rises int
+	}{
+		{"background $0000, sprites $1000", 0x08, 241},
+		// The rise at the first background fetch out of vblank counts too
+		{"background $1000, sprites $0000", 0x10, 242},
+		// Empty 8x16 slots fetch tile $FF, from $1000
+		{"8x16 sprites", 0x20, 241},
+		{"both at $1000", 0x18, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ppuMem, _ := NewTestPPUMemorySetup()
+			ppu := New()
+			ppu.SetMemory(ppuMem)
+			ppu.Reset()
+			for i := 0; i < 256; i++ {
+				ppu.WriteOAM(uint8(i), 0xFF) // No sprites on screen
+			}
+			rises := 0
+			ppu.SetA12Callback(func() { rises++ })
+			ppu.WriteRegister(0x2000, tc.ctrl)
+			ppu.WriteRegister(0x2001, 0x18)
+
+			runTo := func(scanline int) {
+				for ppu.GetScanline() != scanline {
+					ppu.Step()
+				}
+			}
+
+			// Count over a whole frame, from the second line to the next
+			runTo(1)
+			rises = 0
+			runTo(0)
+			runTo(1)
+			if rises != tc.rises {
+				t.Errorf("%d counted A12 rises in a frame, want %d", rises, tc.rises)
+			}
+		})
+	}
+}
+
+func TestPPUDataRenderingGlitch(t *testing.T) {
+	ppuMem, _ := NewTestPPUMemorySetup()
+	ppu := New()
+	ppu.SetMemory(ppuMem)
+	ppu.Reset()
+	ppu.SetScrollTracking(true)
+
+	runTo := func(scanline, cycle int) {
+		for ppu.GetScanline() != scanline || ppu.GetCycle() != cycle {
+			ppu.Step()
+		}
+	}
+
+	// Out of rendering, v steps by 1
+	ppu.WriteRegister(0x2006, 0x20)
+	ppu.WriteRegister(0x2006, 0x1F)
+	ppu.WriteRegister(0x2007, 0x00)
+	if ppu.v != 0x2020 {
+		t.Errorf("v = $%04X after a write out of rendering, want $2020", ppu.v)
+	}
+
+	// While rendering, coarse X and Y step instead: X 31 wraps to the next
+	// nametable, fine Y goes 0 to 1
+	ppu.WriteRegister(0x2001, 0x08)
+	runTo(10, 100)
+	ppu.WriteRegister(0x2006, 0x20)
+	ppu.WriteRegister(0x2006, 0x1F)
+	ppu.ReadRegister(0x2007)
+	if ppu.v != 0x3400 {
+		t.Errorf("v = $%04X after a read while rendering, want $3400", ppu.v)
+	}
+	ppu.WriteRegister(0x2005, 0x00)
+	ppu.WriteRegister(0x2005, 0x00)
+
+	// The rest of the frame is drawn a line down, the rest of the line a
+	// tile right
+	runTo(10, 200)
+	ppu.WriteRegister(0x2007, 0x00)
+	if ppu.glitchX != 16 || ppu.glitchY != 2 {
+		t.Errorf("Background moved %d, %d, want 16, 2", ppu.glitchX, ppu.glitchY)
+	}
+	runTo(11, 10)
+	if got := ppu.ScrollLines()[11]; got.X != 0 || got.Y != 2 {
+		t.Errorf("Line 11 scroll %d, %d, want 0, 2", got.X, got.Y)
+	}
+	runTo(0, 10)
+	if got := ppu.ScrollLines()[0]; got.X != 0 || got.Y != 0 {
+		t.Errorf("Next frame scroll %d, %d, want 0, 0", got.X, got.Y)
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	for _, tc := range []struct {
+		region region.Region
+		cycles uint64 // CPU cycles writes are ignored for
+	}{
+		{region.NTSC, 29667},
+		{region.PAL, 33140},
+	} {
+		t.Run(tc.region.String(), func(t *testing.T) {
+			ppuMem, _ := NewTestPPUMemorySetup()
+			ppu := New()
+			ppu.SetMemory(ppuMem)
+			ppu.SetRegion(tc.region)
+			ppu.Reset()
+			ppu.StartWarmUp()
+
+			ppu.WriteRegister(0x2000, 0x80)
+			ppu.WriteRegister(0x2001, 0x1E)
+			ppu.WriteRegister(0x2005, 0x10)
+			ppu.WriteRegister(0x2006, 0x21)
+			ppu.WriteRegister(0x2003, 0x40)
+			if regs := ppu.Registers(); regs.Ctrl != 0 || regs.Mask != 0 || regs.T != 0 || regs.X != 0 || regs.W {
+				t.Errorf("writes taken while warming up: %+v", regs)
+			}
+			if regs := ppu.Registers(); regs.OAMAddr != 0x40 {
+				t.Errorf("OAMADDR = $%02X while warming up, want $40", regs.OAMAddr)
+			}
+
+			dots := ppu.GetCycleCount()
+			for ppu.WarmingUp() {
+				ppu.Step()
+			}
+			timing := tc.region.Timing()
+			if got := (ppu.GetCycleCount() - dots) * timing.CPUCycles / timing.PPUCycles; got != tc.cycles {
+				t.Errorf("warmed up after %d CPU cycles, want %d", got, tc.cycles)
+			}
+			ppu.WriteRegister(0x2000, 0x80)
+			if regs := ppu.Registers(); regs.Ctrl != 0x80 {
+				t.Errorf("PPUCTRL = $%02X after warming up, want $80", regs.Ctrl)
+			}
+		})
+	}
+}
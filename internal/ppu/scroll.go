@@ -0,0 +1,63 @@
+// Package ppu provides scroll tracking, which records the scroll each
+// scanline was drawn with to debug status bar splits and parallax.
+package ppu
+
+// ScanlineScroll is the scroll a scanline was drawn with, as of its first
+// pixel
+type ScanlineScroll struct {
+	X         int  // Fine scroll in the nametable, 0-255
+	Y         int  // 0-239, or up to 255 for games that scroll into the attributes
+	Nametable int  // 0-3, for $2000, $2400, $2800 and $2C00
+	Rendering bool // Background or sprites on; lines without show the backdrop
+}
+
+// WorldX returns the scroll across the two nametables side by side, 0-511
+func (s ScanlineScroll) WorldX() int {
+	return s.Nametable&1*256 + s.X
+}
+
+// WorldY returns the scroll down the two nametables stacked, 0-479 (more
+// when scrolling into the attributes)
+func (s ScanlineScroll) WorldY() int {
+	return s.Nametable>>1*240 + s.Y
+}
+
+// SetScrollTracking turns scroll tracking on or off. It costs nothing off.
+func (p *PPU) SetScrollTracking(on bool) {
+	switch {
+	case on && p.scrollLines == nil:
+		p.scrollLines = new([240]ScanlineScroll)
+	case !on:
+		p.scrollLines = nil
+	}
+}
+
+// ScrollLines returns the scroll of each scanline, or nil when tracking is
+// off. Lines are overwritten as they are drawn, so between frames they are
+// those of the last frame.
+func (p *PPU) ScrollLines() *[240]ScanlineScroll {
+	return p.scrollLines
+}
+
+// recordScroll records the scroll of the current scanline from the scroll
+// registers the background is drawn with, moved on by any $2007 accesses
+// while rendering as the background is
+func (p *PPU) recordScroll() {
+	x := int(p.t&0x001F)<<3 + int(p.x) + p.glitchX
+	y := int((p.t>>5)&0x001F)<<3 + int((p.t>>12)&0x0007) + p.glitchY
+	nametable := int((p.t >> 10) & 0x0003)
+	for x >= 256 {
+		x -= 256
+		nametable ^= 1
+	}
+	for p.glitchY != 0 && y >= 240 {
+		y -= 240
+		nametable ^= 2
+	}
+	p.scrollLines[p.scanline] = ScanlineScroll{
+		X:         x,
+		Y:         y,
+		Nametable: nametable,
+		Rendering: p.backgroundEnabled || p.spritesEnabled,
+	}
+}
@@ -0,0 +1,54 @@
+package ppu
+
+import "testing"
+
+func TestScrollTracking(t *testing.T) {
+	ppuMem, _ := NewTestPPUMemorySetup()
+	ppu := New()
+	ppu.SetMemory(ppuMem)
+	ppu.Reset()
+	if ppu.ScrollLines() != nil {
+		t.Fatal("Scroll tracked before it was turned on")
+	}
+	ppu.SetScrollTracking(true)
+
+	runTo := func(scanline int) {
+		for ppu.GetScanline() != scanline {
+			ppu.Step()
+		}
+	}
+
+	// A status bar at the top, then a split to the second nametable
+	ppu.WriteRegister(0x2001, 0x08)
+	ppu.WriteRegister(0x2005, 0)
+	ppu.WriteRegister(0x2005, 0)
+	runTo(31)
+	ppu.WriteRegister(0x2000, 0x01)
+	ppu.WriteRegister(0x2005, 0x35)
+	ppu.WriteRegister(0x2005, 0x12)
+	runTo(40)
+	ppu.WriteRegister(0x2001, 0x00)
+	runTo(50)
+
+	lines := ppu.ScrollLines()
+	want := map[int]ScanlineScroll{
+		0:  {Rendering: true},
+		30: {Rendering: true},
+		31: {X: 0x35, Y: 0x12, Nametable: 1, Rendering: true},
+		39: {X: 0x35, Y: 0x12, Nametable: 1, Rendering: true},
+		45: {X: 0x35, Y: 0x12, Nametable: 1},
+	}
+	for line, scroll := range want {
+		if lines[line] != scroll {
+			t.Errorf("Line %d scroll %+v, want %+v", line, lines[line], scroll)
+		}
+	}
+	if x, y := lines[31].WorldX(), lines[31].WorldY(); x != 256+0x35 || y != 0x12 {
+		t.Errorf("World scroll (%d, %d)", x, y)
+	}
+
+	ppu.SetScrollTracking(false)
+	if ppu.ScrollLines() != nil {
+		t.Error("Scroll still tracked after turning it off")
+	}
+}
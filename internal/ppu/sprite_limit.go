@@ -0,0 +1,47 @@
+// Package ppu provides the unlimited sprites enhancement, which draws every
+// sprite of a scanline instead of the first eight so games that flicker
+// sprites past the limit show them all.
+package ppu
+
+// maxExtraSprites is how many sprites past the eighth a scanline can have
+const maxExtraSprites = 64 - 8
+
+// SetUnlimitedSprites sets whether the sprites past the eighth of a scanline
+// are drawn, from the next scanline on. This is an enhancement: real
+// consoles drop them. Only the picture changes; the sprite overflow flag,
+// sprite 0 hits and the mapper's view of the pattern fetches stay as on a
+// console.
+func (p *PPU) SetUnlimitedSprites(on bool) {
+	p.unlimitedSprites = on
+	if !on {
+		p.extraSpriteCount = 0
+	}
+}
+
+// UnlimitedSprites returns whether the sprites past the eighth of a scanline
+// are drawn
+func (p *PPU) UnlimitedSprites() bool {
+	return p.unlimitedSprites
+}
+
+// evaluateExtraSprites finds the sprites on the current scanline from OAM
+// index first on, once the eight of secondary OAM are found
+func (p *PPU) evaluateExtraSprites(first, spriteHeight int) {
+	for spriteIndex := first; spriteIndex < 64; spriteIndex++ {
+		sprite := p.oam[spriteIndex*4 : spriteIndex*4+4]
+		if sY := int(sprite[0]); p.scanline >= sY+1 && p.scanline < sY+1+spriteHeight {
+			copy(p.extraSprites[int(p.extraSpriteCount)*4:], sprite)
+			p.extraSpriteCount++
+		}
+	}
+}
+
+// spriteSlot returns the Y, tile, attribute and X bytes of a sprite drawn on
+// the current scanline: the eight of secondary OAM, then the extra ones
+func (p *PPU) spriteSlot(slot int) []uint8 {
+	if slot < int(p.spriteCount) {
+		return p.secondaryOAM[slot*4 : slot*4+4]
+	}
+	slot -= int(p.spriteCount)
+	return p.extraSprites[slot*4 : slot*4+4]
+}
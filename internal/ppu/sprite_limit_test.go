@@ -0,0 +1,48 @@
+package ppu
+
+import "testing"
+
+func TestUnlimitedSprites(t *testing.T) {
+	const (
+		backdrop = 0x0F
+		sprite   = 0x2A
+	)
+	for _, unlimited := range []bool{false, true} {
+		ppuMem, mockCart := NewTestPPUMemorySetup()
+		ppu := New()
+		ppu.SetMemory(ppuMem)
+		ppu.Reset()
+		ppu.SetUnlimitedSprites(unlimited)
+
+		// Tile 0 is solid color 1; ten sprites side by side on lines 11-18
+		for row := uint16(0); row < 8; row++ {
+			mockCart.SetCHRByte(row, 0xFF)
+		}
+		ppuMem.Write(0x3F00, backdrop)
+		ppuMem.Write(0x3F11, sprite)
+		for i := 0; i < 10; i++ {
+			for j, b := range []uint8{10, 0, 0, uint8(20 + i*16)} {
+				ppu.WriteOAM(uint8(i*4+j), b)
+			}
+		}
+		ppu.WriteRegister(0x2001, 0x14) // Sprites only, unclipped
+
+		for ppu.GetScanline() != 20 {
+			ppu.Step()
+		}
+		if got, want := ppu.GetPixel(20+7*16, 12), ppu.NESColorToRGB(sprite); got != want {
+			t.Errorf("unlimited %v: eighth sprite pixel %06X, want %06X", unlimited, got, want)
+		}
+		want := ppu.NESColorToRGB(backdrop)
+		if unlimited {
+			want = ppu.NESColorToRGB(sprite)
+		}
+		if got := ppu.GetPixel(20+9*16, 12); got != want {
+			t.Errorf("unlimited %v: tenth sprite pixel %06X, want %06X", unlimited, got, want)
+		}
+		// The game sees the limit either way
+		if ppu.ReadRegister(0x2002)&0x20 == 0 {
+			t.Errorf("unlimited %v: sprite overflow flag not set", unlimited)
+		}
+	}
+}
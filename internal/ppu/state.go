@@ -0,0 +1,154 @@
+// Package ppu implements PPU state snapshots.
+package ppu
+
+import (
+	"gones/internal/memory"
+	"gones/internal/savestate"
+)
+
+// Registers is the PPU's register and scroll state, for debugging
+type Registers struct {
+	Ctrl    uint8  // PPUCTRL ($2000)
+	Mask    uint8  // PPUMASK ($2001)
+	Status  uint8  // PPUSTATUS ($2002)
+	OAMAddr uint8  // OAMADDR ($2003)
+	V       uint16 // Current VRAM address
+	T       uint16 // Temporary VRAM address
+	X       uint8  // Fine X scroll
+	W       bool   // Second write of $2005/$2006 pending
+}
+
+// Registers returns the register and scroll state without the side effects
+// of reading the registers
+func (p *PPU) Registers() Registers {
+	return Registers{
+		Ctrl: p.ppuCtrl, Mask: p.ppuMask, Status: p.ppuStatus, OAMAddr: p.oamAddr,
+		V: p.v, T: p.t, X: p.x, W: p.w,
+	}
+}
+
+// SaveState writes the PPU registers, timing, OAM, frame buffer and VRAM
+func (p *PPU) SaveState(w *savestate.Writer) {
+	w.WriteTag("PPU ")
+
+	// CPU-visible registers
+	w.WriteU8(p.ppuCtrl)
+	w.WriteU8(p.ppuMask)
+	w.WriteU8(p.ppuStatus)
+	w.WriteU8(p.oamAddr)
+	w.WriteU8(p.oamData)
+	w.WriteU8(p.ppuScroll)
+	w.WriteU8(p.ppuAddr)
+	w.WriteU8(p.ppuData)
+
+	// Internal scroll registers
+	w.WriteU16(p.v)
+	w.WriteU16(p.t)
+	w.WriteU8(p.x)
+	w.WriteBool(p.w)
+	w.WriteInt(p.glitchX)
+	w.WriteInt(p.glitchY)
+
+	// Rendering state
+	w.WriteInt(p.scanline)
+	w.WriteInt(p.cycle)
+	w.WriteU64(p.frameCount)
+	w.WriteBool(p.oddFrame)
+	w.WriteBool(p.suppressVBL)
+	w.WriteU8(p.readBuffer)
+
+	// Sprite state
+	w.WriteBytes(p.oam[:])
+	w.WriteBytes(p.secondaryOAM[:])
+	w.WriteU8(p.spriteCount)
+	w.WriteBool(p.sprite0Hit)
+	w.WriteBool(p.spriteOverflow)
+	w.WriteInt(p.lastEvalScanline)
+	w.WriteBytes(p.spriteIndexes[:])
+	w.WriteBool(p.sprite0OnScanline)
+
+	w.WriteBool(p.backgroundEnabled)
+	w.WriteBool(p.spritesEnabled)
+	w.WriteBool(p.renderingEnabled)
+	w.WriteU64(p.cycleCount)
+	w.WriteU64(p.warmUpEnd)
+	w.WriteBool(p.a12)
+	w.WriteU64(p.a12Fell)
+
+	// Frame buffer so a restored state shows the right picture immediately
+	w.WriteU32s(p.frameBuffer[:])
+
+	// Nametables and palette
+	w.WriteBool(p.memory != nil)
+	if p.memory != nil {
+		p.memory.SaveState(w)
+	}
+}
+
+// LoadState restores state written by SaveState
+func (p *PPU) LoadState(r *savestate.Reader) error {
+	r.ExpectTag("PPU ")
+
+	p.ppuCtrl = r.ReadU8()
+	p.ppuMask = r.ReadU8()
+	p.ppuStatus = r.ReadU8()
+	p.oamAddr = r.ReadU8()
+	p.oamData = r.ReadU8()
+	p.ppuScroll = r.ReadU8()
+	p.ppuAddr = r.ReadU8()
+	p.ppuData = r.ReadU8()
+
+	p.v = r.ReadU16()
+	p.t = r.ReadU16()
+	p.x = r.ReadU8()
+	p.w = r.ReadBool()
+	p.glitchX = r.ReadInt()
+	p.glitchY = r.ReadInt()
+
+	p.scanline = r.ReadInt()
+	p.cycle = r.ReadInt()
+	p.frameCount = r.ReadU64()
+	p.oddFrame = r.ReadBool()
+	p.suppressVBL = r.ReadBool()
+	p.readBuffer = r.ReadU8()
+
+	r.ReadBytesInto(p.oam[:])
+	r.ReadBytesInto(p.secondaryOAM[:])
+	p.spriteCount = r.ReadU8()
+	p.sprite0Hit = r.ReadBool()
+	p.spriteOverflow = r.ReadBool()
+	p.lastEvalScanline = r.ReadInt()
+	r.ReadBytesInto(p.spriteIndexes[:])
+	p.sprite0OnScanline = r.ReadBool()
+
+	p.backgroundEnabled = r.ReadBool()
+	p.spritesEnabled = r.ReadBool()
+	p.renderingEnabled = r.ReadBool()
+	p.updateColorLookup()
+	p.cycleCount = r.ReadU64()
+	p.warmUpEnd = r.ReadU64()
+	p.a12 = r.ReadBool()
+	p.a12Fell = r.ReadU64()
+
+	r.ReadU32sInto(p.frameBuffer[:])
+	p.frontBuffer = p.frameBuffer
+
+	// The per-tile cache is transient and rebuilt on the next pixel
+	p.backgroundTile.valid = false
+
+	if r.ReadBool() {
+		target := p.memory
+		if target == nil {
+			// No cartridge loaded: consume the VRAM section without applying it
+			target = memory.NewPPUMemory(nil, memory.MirrorHorizontal)
+		}
+		target.LoadState(r)
+	}
+
+	return r.Err()
+}
+
+// GetMemory returns the PPU memory (nametables and palette)
+func (p *PPU) GetMemory() *memory.PPUMemory {
+	return p.memory
+}
@@ -5,4 +5,5 @@ package ppu
 // SetFrameBufferForTesting sets a frame buffer for testing purposes
 func (p *PPU) SetFrameBufferForTesting(frameBuffer [256 * 240]uint32) {
 	p.frameBuffer = frameBuffer
+	p.frontBuffer = frameBuffer
 }
\ No newline at end of file
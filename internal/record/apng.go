@@ -0,0 +1,156 @@
+// Package record implements an animated PNG (APNG) encoder.
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+
+// apngEncoder writes a true color APNG. The frame count in the acTL chunk is
+// not known until the recording ends, so it is patched in by close. Viewers
+// without APNG support show the first frame.
+type apngEncoder struct {
+	file       *os.File
+	w          *bufio.Writer
+	width      int
+	height     int
+	delayNum   uint16
+	delayDen   uint16
+	actlOffset int64  // File offset of the acTL chunk
+	sequence   uint32 // Next fcTL/fdAT sequence number
+	frames     uint32
+	raw        []byte // Filtered scanlines of the current frame
+	compressed bytes.Buffer
+	zw         *zlib.Writer
+}
+
+func newAPNGEncoder(path string, options Options) (*apngEncoder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %v", err)
+	}
+	e := &apngEncoder{
+		file:   file,
+		w:      bufio.NewWriter(file),
+		width:  options.Width,
+		height: options.Height,
+		raw:    make([]byte, options.Height*(1+options.Width*3)),
+	}
+	// Frame delay as a fraction of a second: 1000/60099 for NTSC
+	e.delayNum, e.delayDen = 1000, uint16(options.FrameRate*1000)
+	if options.FrameRate*1000 > 0xFFFF {
+		e.delayNum, e.delayDen = 1, uint16(min(options.FrameRate, 0xFFFF))
+	}
+	e.zw, _ = zlib.NewWriterLevel(&e.compressed, zlib.BestSpeed)
+
+	e.w.Write(pngSignature)
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(e.width))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(e.height))
+	ihdr[8] = 8 // Bit depth
+	ihdr[9] = 2 // True color
+	e.writeChunk("IHDR", ihdr)
+
+	e.actlOffset = int64(len(pngSignature) + 12 + len(ihdr))
+	e.writeChunk("acTL", make([]byte, 8)) // Frame count 0 and loop forever, patched on close
+	return e, nil
+}
+
+// writeChunk writes a PNG chunk with its length and CRC
+func (e *apngEncoder) writeChunk(name string, data []byte) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], name)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+
+	e.w.Write(header[:])
+	e.w.Write(data)
+	_, err := e.w.Write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+	return err
+}
+
+func (e *apngEncoder) writeFrame(pixels []uint32) error {
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl[0:], e.sequence)
+	binary.BigEndian.PutUint32(fctl[4:], uint32(e.width))
+	binary.BigEndian.PutUint32(fctl[8:], uint32(e.height))
+	// x and y offsets stay 0
+	binary.BigEndian.PutUint16(fctl[20:], e.delayNum)
+	binary.BigEndian.PutUint16(fctl[22:], e.delayDen)
+	// Dispose op none, blend op source
+	e.sequence++
+	e.writeChunk("fcTL", fctl)
+
+	// Scanlines with the Sub filter, which suits the flat colors of NES frames
+	stride := 1 + e.width*3
+	for y := 0; y < e.height; y++ {
+		line := e.raw[y*stride : (y+1)*stride]
+		line[0] = 1
+		var pr, pg, pb byte
+		for x, pixel := range pixels[y*e.width : (y+1)*e.width] {
+			r, g, b := byte(pixel>>16), byte(pixel>>8), byte(pixel)
+			line[1+x*3], line[2+x*3], line[3+x*3] = r-pr, g-pg, b-pb
+			pr, pg, pb = r, g, b
+		}
+	}
+	e.compressed.Reset()
+	e.zw.Reset(&e.compressed)
+	e.zw.Write(e.raw)
+	e.zw.Close()
+
+	var err error
+	if e.frames == 0 {
+		err = e.writeChunk("IDAT", e.compressed.Bytes())
+	} else {
+		data := binary.BigEndian.AppendUint32(nil, e.sequence)
+		e.sequence++
+		err = e.writeChunk("fdAT", append(data, e.compressed.Bytes()...))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write recording: %v", err)
+	}
+	e.frames++
+	return nil
+}
+
+func (e *apngEncoder) writeAudio(samples []float32) error {
+	return nil
+}
+
+func (e *apngEncoder) close() error {
+	// A PNG needs image data, so an empty recording gets one black frame
+	if e.frames == 0 {
+		e.writeFrame(make([]uint32, e.width*e.height))
+	}
+	e.writeChunk("IEND", nil)
+	err := e.w.Flush()
+	if err == nil {
+		// Patch the frame count into acTL
+		actl := make([]byte, 8)
+		binary.BigEndian.PutUint32(actl, e.frames)
+		crc := crc32.NewIEEE()
+		crc.Write([]byte("acTL"))
+		crc.Write(actl)
+		if _, err = e.file.Seek(e.actlOffset+8, io.SeekStart); err == nil {
+			_, err = e.file.Write(append(actl, binary.BigEndian.AppendUint32(nil, crc.Sum32())...))
+		}
+	}
+	if err != nil {
+		e.file.Close()
+		return fmt.Errorf("failed to write recording: %v", err)
+	}
+	if err := e.file.Close(); err != nil {
+		return fmt.Errorf("failed to close recording: %v", err)
+	}
+	return nil
+}
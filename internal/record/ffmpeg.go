@@ -0,0 +1,131 @@
+// Package record implements video recording through an external ffmpeg binary.
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ffmpegEncoder pipes raw RGB frames to ffmpeg, which picks the codecs for the
+// container given by the file extension. Audio goes to a temporary WAV file
+// that is muxed with the video when the recording ends.
+type ffmpegEncoder struct {
+	ffmpeg    string
+	path      string
+	videoPath string // Output of the encoding pass; a temporary file when recording audio
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	w         *bufio.Writer
+	stderr    bytes.Buffer
+	rgb       []byte
+	audioPath string
+	audio     *wavWriter
+}
+
+func newFFmpegEncoder(path string, options Options) (*ffmpegEncoder, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("%s recording requires ffmpeg in PATH (use .gif or .png without it)", strings.TrimPrefix(filepath.Ext(path), "."))
+	}
+
+	e := &ffmpegEncoder{
+		ffmpeg:    ffmpeg,
+		path:      path,
+		videoPath: path,
+		rgb:       make([]byte, options.Width*options.Height*3),
+	}
+	if options.SampleRate > 0 {
+		dir, base := filepath.Split(path)
+		e.videoPath = filepath.Join(dir, "."+base+".video"+filepath.Ext(path))
+		e.audioPath = filepath.Join(dir, "."+base+".audio.wav")
+		if e.audio, err = newWAVWriter(e.audioPath, options.SampleRate); err != nil {
+			return nil, err
+		}
+	}
+
+	e.cmd = exec.Command(ffmpeg, "-y", "-loglevel", "error",
+		"-f", "rawvideo", "-pixel_format", "rgb24",
+		"-video_size", fmt.Sprintf("%dx%d", options.Width, options.Height),
+		"-framerate", fmt.Sprintf("%.4f", options.FrameRate),
+		"-i", "-",
+		"-pix_fmt", "yuv420p",
+		e.videoPath)
+	e.cmd.Stderr = &e.stderr
+	if e.stdin, err = e.cmd.StdinPipe(); err != nil {
+		e.discardAudio()
+		return nil, fmt.Errorf("failed to start ffmpeg: %v", err)
+	}
+	if err := e.cmd.Start(); err != nil {
+		e.discardAudio()
+		return nil, fmt.Errorf("failed to start ffmpeg: %v", err)
+	}
+	e.w = bufio.NewWriterSize(e.stdin, len(e.rgb))
+	return e, nil
+}
+
+func (e *ffmpegEncoder) writeFrame(pixels []uint32) error {
+	for i, pixel := range pixels {
+		e.rgb[i*3], e.rgb[i*3+1], e.rgb[i*3+2] = byte(pixel>>16), byte(pixel>>8), byte(pixel)
+	}
+	if _, err := e.w.Write(e.rgb); err != nil {
+		return fmt.Errorf("ffmpeg stopped: %v %s", err, strings.TrimSpace(e.stderr.String()))
+	}
+	return nil
+}
+
+func (e *ffmpegEncoder) writeAudio(samples []float32) error {
+	if e.audio == nil {
+		return nil
+	}
+	return e.audio.write(samples)
+}
+
+func (e *ffmpegEncoder) close() error {
+	e.w.Flush()
+	e.stdin.Close()
+	if err := e.cmd.Wait(); err != nil {
+		e.discardAudio()
+		return fmt.Errorf("ffmpeg failed: %v %s", err, strings.TrimSpace(e.stderr.String()))
+	}
+	if e.audio == nil {
+		return nil
+	}
+
+	err := e.audio.close()
+	samples := e.audio.samples
+	e.audio = nil
+	defer os.Remove(e.audioPath)
+	if err != nil || samples == 0 {
+		if renameErr := os.Rename(e.videoPath, e.path); renameErr != nil {
+			return fmt.Errorf("failed to move recording: %v", renameErr)
+		}
+		return err
+	}
+
+	defer os.Remove(e.videoPath)
+	e.stderr.Reset()
+	mux := exec.Command(e.ffmpeg, "-y", "-loglevel", "error",
+		"-i", e.videoPath, "-i", e.audioPath,
+		"-c:v", "copy", "-shortest",
+		e.path)
+	mux.Stderr = &e.stderr
+	if err := mux.Run(); err != nil {
+		return fmt.Errorf("failed to add audio to recording: %v %s", err, strings.TrimSpace(e.stderr.String()))
+	}
+	return nil
+}
+
+// discardAudio closes and removes the temporary audio file
+func (e *ffmpegEncoder) discardAudio() {
+	if e.audio != nil {
+		e.audio.close()
+		e.audio = nil
+		os.Remove(e.audioPath)
+	}
+}
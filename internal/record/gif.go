@@ -0,0 +1,237 @@
+// Package record implements a streaming animated GIF encoder.
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"compress/lzw"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"image/color/palette"
+	"math"
+	"os"
+	"slices"
+)
+
+// gifFrameStep records every other frame: GIF delays are in 1/100 s and many
+// viewers slow down delays under 2/100 s, so 60 fps cannot be represented
+const gifFrameStep = 2
+
+// gifEncoder writes frames as they arrive instead of holding the animation in
+// memory like image/gif. A frame is kept pending until a different one arrives
+// so identical frames merge into one longer delay, and each frame only stores
+// the rectangle that changed since the previous one.
+type gifEncoder struct {
+	file      *os.File
+	w         *bufio.Writer
+	width     int
+	height    int
+	frameRate float64
+
+	input        int      // Source frames received
+	prev         []uint32 // Last frame written to the file
+	pending      []uint32 // Frame waiting for its delay
+	pendingStart int      // Source frame index of the pending frame
+	hasPending   bool
+}
+
+func newGIFEncoder(path string, options Options) (*gifEncoder, error) {
+	if options.Width > 0xFFFF || options.Height > 0xFFFF {
+		return nil, fmt.Errorf("frame too large for GIF: %dx%d", options.Width, options.Height)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %v", err)
+	}
+	e := &gifEncoder{
+		file:      file,
+		w:         bufio.NewWriter(file),
+		width:     options.Width,
+		height:    options.Height,
+		frameRate: options.FrameRate,
+		pending:   make([]uint32, options.Width*options.Height),
+	}
+
+	// Header and logical screen descriptor without a global color table
+	e.w.WriteString("GIF89a")
+	binary.Write(e.w, binary.LittleEndian, [2]uint16{uint16(e.width), uint16(e.height)})
+	e.w.Write([]byte{0x00, 0x00, 0x00})
+	// NETSCAPE2.0 extension: loop forever
+	e.w.Write([]byte{0x21, 0xFF, 0x0B})
+	e.w.WriteString("NETSCAPE2.0")
+	e.w.Write([]byte{0x03, 0x01, 0x00, 0x00, 0x00})
+	return e, nil
+}
+
+func (e *gifEncoder) writeFrame(pixels []uint32) error {
+	index := e.input
+	e.input++
+	if index%gifFrameStep != 0 {
+		return nil
+	}
+	if e.hasPending && slices.Equal(e.pending, pixels) {
+		return nil
+	}
+	if e.hasPending {
+		if err := e.flush(index); err != nil {
+			return err
+		}
+	}
+	copy(e.pending, pixels)
+	e.pendingStart = index
+	e.hasPending = true
+	return nil
+}
+
+func (e *gifEncoder) writeAudio(samples []float32) error {
+	return nil
+}
+
+func (e *gifEncoder) close() error {
+	var err error
+	if e.hasPending {
+		err = e.flush(e.input)
+	}
+	e.w.WriteByte(0x3B) // Trailer
+	if flushErr := e.w.Flush(); err == nil && flushErr != nil {
+		err = fmt.Errorf("failed to write recording: %v", flushErr)
+	}
+	if closeErr := e.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close recording: %v", closeErr)
+	}
+	return err
+}
+
+// centiseconds returns the time of a source frame in 1/100 s
+func (e *gifEncoder) centiseconds(frame int) int {
+	return int(math.Round(float64(frame) * 100 / e.frameRate))
+}
+
+// flush writes the pending frame, shown until source frame end
+func (e *gifEncoder) flush(end int) error {
+	delay := e.centiseconds(end) - e.centiseconds(e.pendingStart)
+	if delay < 1 {
+		delay = 1
+	} else if delay > 0xFFFF {
+		delay = 0xFFFF
+	}
+
+	x0, y0, x1, y1 := 0, 0, e.width, e.height
+	if e.prev != nil {
+		x0, y0, x1, y1 = changedRect(e.prev, e.pending, e.width, e.height)
+	} else {
+		e.prev = make([]uint32, len(e.pending))
+	}
+
+	colors, indices := gifPalette(e.pending, e.width, x0, y0, x1, y1)
+	sizeBits := 1
+	for 1<<sizeBits < len(colors) {
+		sizeBits++
+	}
+
+	// Graphic control extension: delay, leave the frame in place
+	e.w.Write([]byte{0x21, 0xF9, 0x04, 0x04})
+	binary.Write(e.w, binary.LittleEndian, uint16(delay))
+	e.w.Write([]byte{0x00, 0x00})
+
+	// Image descriptor with a local color table
+	e.w.WriteByte(0x2C)
+	binary.Write(e.w, binary.LittleEndian, [4]uint16{uint16(x0), uint16(y0), uint16(x1 - x0), uint16(y1 - y0)})
+	e.w.WriteByte(0x80 | byte(sizeBits-1))
+	table := make([]byte, 3<<sizeBits)
+	for i, c := range colors {
+		table[i*3], table[i*3+1], table[i*3+2] = byte(c>>16), byte(c>>8), byte(c)
+	}
+	e.w.Write(table)
+
+	// LZW image data in sub-blocks of up to 255 bytes
+	litWidth := sizeBits
+	if litWidth < 2 {
+		litWidth = 2
+	}
+	var data bytes.Buffer
+	lw := lzw.NewWriter(&data, lzw.LSB, litWidth)
+	lw.Write(indices)
+	lw.Close()
+	e.w.WriteByte(byte(litWidth))
+	for block := data.Bytes(); len(block) > 0; {
+		n := min(len(block), 255)
+		e.w.WriteByte(byte(n))
+		e.w.Write(block[:n])
+		block = block[n:]
+	}
+	if _, err := e.w.Write([]byte{0x00}); err != nil {
+		return fmt.Errorf("failed to write recording: %v", err)
+	}
+
+	copy(e.prev, e.pending)
+	return nil
+}
+
+// gifPalette returns the colors of a rectangle and its pixels as palette
+// indices. Frames with more than 256 colors (possible with color emphasis)
+// are mapped to the Plan 9 palette.
+func gifPalette(pixels []uint32, width, x0, y0, x1, y1 int) ([]uint32, []byte) {
+	indices := make([]byte, 0, (x1-x0)*(y1-y0))
+	lookup := make(map[uint32]byte)
+	var colors []uint32
+	for y := y0; y < y1; y++ {
+		for _, c := range pixels[y*width+x0 : y*width+x1] {
+			c &= 0xFFFFFF
+			index, ok := lookup[c]
+			if !ok {
+				if len(colors) == 256 {
+					return quantizePlan9(pixels, width, x0, y0, x1, y1)
+				}
+				index = byte(len(colors))
+				lookup[c] = index
+				colors = append(colors, c)
+			}
+			indices = append(indices, index)
+		}
+	}
+	return colors, indices
+}
+
+// quantizePlan9 maps a rectangle to the nearest Plan 9 palette colors
+func quantizePlan9(pixels []uint32, width, x0, y0, x1, y1 int) ([]uint32, []byte) {
+	colors := make([]uint32, len(palette.Plan9))
+	for i, c := range palette.Plan9 {
+		r, g, b, _ := c.RGBA()
+		colors[i] = (r>>8)<<16 | (g>>8)<<8 | b>>8
+	}
+	indices := make([]byte, 0, (x1-x0)*(y1-y0))
+	lookup := make(map[uint32]byte)
+	for y := y0; y < y1; y++ {
+		for _, c := range pixels[y*width+x0 : y*width+x1] {
+			c &= 0xFFFFFF
+			index, ok := lookup[c]
+			if !ok {
+				index = byte(color.Palette(palette.Plan9).Index(color.RGBA{uint8(c >> 16), uint8(c >> 8), uint8(c), 0xFF}))
+				lookup[c] = index
+			}
+			indices = append(indices, index)
+		}
+	}
+	return colors, indices
+}
+
+// changedRect returns the bounding rectangle of the pixels that differ
+// between two frames, or a single pixel if they are identical
+func changedRect(a, b []uint32, width, height int) (x0, y0, x1, y1 int) {
+	x0, y0, x1, y1 = width, height, 0, 0
+	for y := 0; y < height; y++ {
+		row := y * width
+		for x := 0; x < width; x++ {
+			if a[row+x] != b[row+x] {
+				x0, x1 = min(x0, x), max(x1, x+1)
+				y0, y1 = min(y0, y), max(y1, y+1)
+			}
+		}
+	}
+	if x0 >= x1 {
+		return 0, 0, 1, 1
+	}
+	return x0, y0, x1, y1
+}
@@ -0,0 +1,159 @@
+// Package record implements video capture of the emulator output.
+//
+// A Recorder receives one frame (and the audio samples generated with it) per
+// emulated frame and encodes them to a file whose format is chosen by the
+// extension: animated GIF (.gif), APNG (.png/.apng) or, through an external
+// ffmpeg binary, MP4, MKV, MOV, AVI and WebM. GIF and APNG are video only;
+// the ffmpeg formats also carry the audio track. WAV (.wav) records only the
+// audio.
+package record
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// NTSCFrameRate is the frame rate of the NTSC NES (CPU clock / cycles per frame)
+const NTSCFrameRate = 60.0988
+
+// Output formats
+const (
+	FormatGIF    = "gif"
+	FormatAPNG   = "apng"
+	FormatFFmpeg = "ffmpeg"
+	FormatWAV    = "wav"
+)
+
+// Options configures a recording
+type Options struct {
+	Width      int     // Frame width in pixels (default 256)
+	Height     int     // Frame height in pixels (default 240)
+	FrameRate  float64 // Frames per second (default NTSCFrameRate)
+	SampleRate int     // Audio sample rate in Hz; 0 records no audio
+}
+
+// encoder writes frames in one output format
+type encoder interface {
+	writeFrame(pixels []uint32) error
+	writeAudio(samples []float32) error
+	close() error
+}
+
+// Recorder encodes frames to a video file
+type Recorder struct {
+	path    string
+	format  string
+	options Options
+	enc     encoder
+	frames  int
+	closed  bool
+}
+
+// FormatForPath returns the output format for a file name, based on its extension
+func FormatForPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gif":
+		return FormatGIF, nil
+	case ".png", ".apng":
+		return FormatAPNG, nil
+	case ".mp4", ".mkv", ".mov", ".avi", ".webm":
+		return FormatFFmpeg, nil
+	case ".wav":
+		return FormatWAV, nil
+	default:
+		return "", fmt.Errorf("unsupported recording format: %q (use .gif, .png, .apng, .mp4, .mkv, .mov, .avi, .webm or .wav)", filepath.Ext(path))
+	}
+}
+
+// Create starts a recording to path
+func Create(path string, options Options) (*Recorder, error) {
+	format, err := FormatForPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if options.Width <= 0 {
+		options.Width = 256
+	}
+	if options.Height <= 0 {
+		options.Height = 240
+	}
+	if options.FrameRate <= 0 {
+		options.FrameRate = NTSCFrameRate
+	}
+	if options.SampleRate < 0 {
+		options.SampleRate = 0
+	}
+
+	var enc encoder
+	switch format {
+	case FormatGIF:
+		enc, err = newGIFEncoder(path, options)
+	case FormatAPNG:
+		enc, err = newAPNGEncoder(path, options)
+	case FormatWAV:
+		if options.SampleRate == 0 {
+			return nil, errors.New("WAV recording needs an audio sample rate")
+		}
+		enc, err = newWAVEncoder(path, options.SampleRate)
+	default:
+		enc, err = newFFmpegEncoder(path, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{path: path, format: format, options: options, enc: enc}, nil
+}
+
+// AddFrame encodes one frame of Width*Height 0xRRGGBB pixels
+func (r *Recorder) AddFrame(pixels []uint32) error {
+	if r.closed {
+		return errors.New("recording is closed")
+	}
+	if len(pixels) < r.options.Width*r.options.Height {
+		return fmt.Errorf("frame too small: %d pixels", len(pixels))
+	}
+	if err := r.enc.writeFrame(pixels[:r.options.Width*r.options.Height]); err != nil {
+		return err
+	}
+	r.frames++
+	return nil
+}
+
+// AddAudio appends mono samples in the range -1.0 to 1.0. Formats without an
+// audio track ignore them.
+func (r *Recorder) AddAudio(samples []float32) error {
+	if r.closed {
+		return errors.New("recording is closed")
+	}
+	if r.options.SampleRate == 0 || len(samples) == 0 {
+		return nil
+	}
+	return r.enc.writeAudio(samples)
+}
+
+// Close finishes the file
+func (r *Recorder) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.enc.close()
+}
+
+// Path returns the output file
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Format returns the output format (FormatGIF, FormatAPNG, FormatFFmpeg or FormatWAV)
+func (r *Recorder) Format() string {
+	return r.format
+}
+
+// Frames returns the number of frames recorded so far
+func (r *Recorder) Frames() int {
+	return r.frames
+}
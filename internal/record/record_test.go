@@ -0,0 +1,228 @@
+package record
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testFrame returns a 256x240 frame filled with c, with a marker pixel at x
+func testFrame(c uint32, x int) []uint32 {
+	frame := make([]uint32, 256*240)
+	for i := range frame {
+		frame[i] = c
+	}
+	frame[100*256+x] = 0xFFFFFF
+	return frame
+}
+
+func TestFormatForPath(t *testing.T) {
+	cases := map[string]string{
+		"a.gif": FormatGIF, "b.PNG": FormatAPNG, "c.apng": FormatAPNG,
+		"d.mp4": FormatFFmpeg, "e.webm": FormatFFmpeg, "f.mkv": FormatFFmpeg,
+		"g.wav": FormatWAV,
+	}
+	for path, want := range cases {
+		if got, err := FormatForPath(path); err != nil || got != want {
+			t.Errorf("FormatForPath(%q) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	if _, err := FormatForPath("video.txt"); err == nil {
+		t.Error("FormatForPath accepted .txt")
+	}
+}
+
+func TestGIFRecording(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.gif")
+	r, err := Create(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 frames of one image, then 10 frames of another: with every other
+	// frame recorded and identical frames merged this gives two GIF frames
+	for i := 0; i < 20; i++ {
+		frame := testFrame(0x0000FF, 10)
+		if i >= 10 {
+			frame = testFrame(0x0000FF, 20)
+		}
+		if err := r.AddFrame(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.AddAudio([]float32{0, 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Frames() != 20 {
+		t.Errorf("Frames() = %d, want 20", r.Frames())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	g, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatalf("invalid GIF: %v", err)
+	}
+	if len(g.Image) != 2 {
+		t.Fatalf("GIF has %d frames, want 2", len(g.Image))
+	}
+	if g.Config.Width != 256 || g.Config.Height != 240 {
+		t.Errorf("GIF size = %dx%d", g.Config.Width, g.Config.Height)
+	}
+	// 10 frames at 60.0988 fps each
+	if g.Delay[0] != 17 || g.Delay[1] != 16 {
+		t.Errorf("delays = %v, want [17 16]", g.Delay)
+	}
+
+	// The second frame only covers the pixels that changed
+	bounds := g.Image[1].Bounds()
+	if bounds.Min.X != 10 || bounds.Max.X != 21 || bounds.Min.Y != 100 || bounds.Max.Y != 101 {
+		t.Errorf("second frame bounds = %v", bounds)
+	}
+	if r, _, b, _ := g.Image[0].At(5, 5).RGBA(); r != 0 || b != 0xFFFF {
+		t.Errorf("first frame background = %x/%x", r, b)
+	}
+	if r, _, _, _ := g.Image[1].At(20, 100).RGBA(); r != 0xFFFF {
+		t.Error("second frame marker pixel not white")
+	}
+}
+
+func TestGIFRecording_ManyColors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "colors.gif")
+	r, err := Create(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := make([]uint32, 256*240)
+	for i := range frame {
+		frame[i] = uint32(i * 97)
+	}
+	r.AddFrame(frame)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if _, err := gif.DecodeAll(bytes.NewReader(data)); err != nil {
+		t.Fatalf("invalid GIF: %v", err)
+	}
+}
+
+func TestAPNGRecording(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.png")
+	r, err := Create(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := r.AddFrame(testFrame(0x123456, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decoders without APNG support see the first frame
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	if r, g, b, _ := img.At(50, 50).RGBA(); r>>8 != 0x12 || g>>8 != 0x34 || b>>8 != 0x56 {
+		t.Errorf("pixel = %02x%02x%02x, want 123456", r>>8, g>>8, b>>8)
+	}
+	if r, _, _, _ := img.At(0, 100).RGBA(); r != 0xFFFF {
+		t.Error("marker pixel not white")
+	}
+
+	// Walk the chunks: acTL holds the frame count, then one fcTL per frame
+	var chunks []string
+	var frameCount uint32
+	for p := 8; p < len(data); {
+		length := int(binary.BigEndian.Uint32(data[p:]))
+		name := string(data[p+4 : p+8])
+		if name == "acTL" {
+			frameCount = binary.BigEndian.Uint32(data[p+8:])
+		}
+		chunks = append(chunks, name)
+		p += 12 + length
+	}
+	if frameCount != 3 {
+		t.Errorf("acTL frame count = %d, want 3", frameCount)
+	}
+	want := "IHDR acTL fcTL IDAT fcTL fdAT fcTL fdAT IEND"
+	if got := strings.Join(chunks, " "); got != want {
+		t.Errorf("chunks = %s, want %s", got, want)
+	}
+}
+
+func TestWAVWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	w, err := newWAVWriter(path, 44100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.write([]float32{0, 1, -1, 2})
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if len(data) != 44+8 {
+		t.Fatalf("file size = %d, want 52", len(data))
+	}
+	if string(data[:4]) != "RIFF" || binary.LittleEndian.Uint32(data[4:]) != 44 {
+		t.Errorf("bad RIFF header: %q %d", data[:4], binary.LittleEndian.Uint32(data[4:]))
+	}
+	if binary.LittleEndian.Uint32(data[24:]) != 44100 || binary.LittleEndian.Uint32(data[40:]) != 8 {
+		t.Error("bad sample rate or data size")
+	}
+	// Out of range samples are clipped
+	if s := int16(binary.LittleEndian.Uint16(data[50:])); s != 32767 {
+		t.Errorf("clipped sample = %d, want 32767", s)
+	}
+}
+
+func TestWAVRecording(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	if _, err := Create(path, Options{}); err == nil {
+		t.Error("WAV recording without audio accepted")
+	}
+	r, err := Create(path, Options{SampleRate: 44100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddFrame(testFrame(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	r.AddAudio([]float32{0, 0.5, -0.5})
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if len(data) != 44+6 || r.Frames() != 1 {
+		t.Errorf("file size = %d, frames = %d; want 50 bytes and 1 frame", len(data), r.Frames())
+	}
+}
+
+func TestFFmpegMissing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if _, err := Create(filepath.Join(t.TempDir(), "out.mp4"), Options{}); err == nil || !strings.Contains(err.Error(), "ffmpeg") {
+		t.Errorf("Create without ffmpeg: err = %v", err)
+	}
+}
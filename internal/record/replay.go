@@ -0,0 +1,151 @@
+// Package record implements a rolling buffer of recent frames for retroactive capture.
+package record
+
+import "math"
+
+// replayFrame is one buffered frame. NES frames rarely use more than a few
+// dozen colors, so they are stored as palette indices (a quarter of the size
+// of RGB pixels); frames with more than 256 colors keep their pixels.
+type replayFrame struct {
+	indices []byte
+	palette []uint32
+	pixels  []uint32
+}
+
+// ReplayBuffer keeps the most recent frames so they can be saved after the
+// fact, like a recording started a few seconds in the past
+type ReplayBuffer struct {
+	options Options
+	frames  []replayFrame // Ring buffer
+	next    int           // Slot the next frame is written to
+	count   int
+	lookup  map[uint32]byte
+}
+
+// NewReplayBuffer creates a buffer holding the last seconds of frames
+func NewReplayBuffer(seconds float64, options Options) *ReplayBuffer {
+	if options.Width <= 0 {
+		options.Width = 256
+	}
+	if options.Height <= 0 {
+		options.Height = 240
+	}
+	if options.FrameRate <= 0 {
+		options.FrameRate = NTSCFrameRate
+	}
+	options.SampleRate = 0
+	capacity := max(1, int(math.Ceil(seconds*options.FrameRate)))
+	return &ReplayBuffer{options: options, frames: make([]replayFrame, capacity), lookup: make(map[uint32]byte)}
+}
+
+// Add appends a frame of Width*Height 0xRRGGBB pixels, dropping the oldest
+// one when the buffer is full
+func (b *ReplayBuffer) Add(pixels []uint32) {
+	size := b.options.Width * b.options.Height
+	if len(pixels) < size {
+		return
+	}
+	pixels = pixels[:size]
+
+	frame := &b.frames[b.next]
+	b.next = (b.next + 1) % len(b.frames)
+	b.count = min(b.count+1, len(b.frames))
+
+	if cap(frame.indices) < size {
+		frame.indices = make([]byte, size)
+	}
+	frame.indices = frame.indices[:size]
+	frame.palette = frame.palette[:0]
+	frame.pixels = frame.pixels[:0]
+
+	lookup := b.lookup
+	clear(lookup)
+	last, lastIndex := ^uint32(0), byte(0)
+	for i, c := range pixels {
+		if c != last {
+			index, ok := lookup[c]
+			if !ok {
+				if len(frame.palette) == 256 {
+					frame.pixels = append(frame.pixels, pixels...)
+					return
+				}
+				index = byte(len(frame.palette))
+				lookup[c] = index
+				frame.palette = append(frame.palette, c)
+			}
+			last, lastIndex = c, index
+		}
+		frame.indices[i] = lastIndex
+	}
+}
+
+// decode writes a buffered frame to dst
+func (f *replayFrame) decode(dst []uint32) {
+	if len(f.pixels) > 0 {
+		copy(dst, f.pixels)
+		return
+	}
+	for i, index := range f.indices {
+		dst[i] = f.palette[index]
+	}
+}
+
+// Len returns the number of buffered frames
+func (b *ReplayBuffer) Len() int {
+	return b.count
+}
+
+// Duration returns the buffered time in seconds
+func (b *ReplayBuffer) Duration() float64 {
+	return float64(b.count) / b.options.FrameRate
+}
+
+// oldest returns the slot of the oldest buffered frame
+func (b *ReplayBuffer) oldest() int {
+	return (b.next - b.count + len(b.frames)) % len(b.frames)
+}
+
+// Reset drops all buffered frames
+func (b *ReplayBuffer) Reset() {
+	b.next = 0
+	b.count = 0
+}
+
+// Clone returns a copy of the buffered frames that can be saved while the
+// original keeps receiving frames
+func (b *ReplayBuffer) Clone() *ReplayBuffer {
+	clone := &ReplayBuffer{options: b.options, frames: make([]replayFrame, max(1, b.count)), count: b.count, lookup: make(map[uint32]byte)}
+	start := b.oldest()
+	for i := 0; i < b.count; i++ {
+		src := &b.frames[(start+i)%len(b.frames)]
+		clone.frames[i] = replayFrame{
+			indices: append([]byte(nil), src.indices...),
+			palette: append([]uint32(nil), src.palette...),
+			pixels:  append([]uint32(nil), src.pixels...),
+		}
+	}
+	return clone
+}
+
+// Save writes the buffered frames, oldest first, to a recording at path and
+// returns the number of frames written
+func (b *ReplayBuffer) Save(path string) (int, error) {
+	recorder, err := Create(path, b.options)
+	if err != nil {
+		return 0, err
+	}
+
+	pixels := make([]uint32, b.options.Width*b.options.Height)
+	start := b.oldest()
+	for i := 0; i < b.count; i++ {
+		b.frames[(start+i)%len(b.frames)].decode(pixels)
+		if err := recorder.AddFrame(pixels); err != nil {
+			recorder.Close()
+			return 0, err
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		return 0, err
+	}
+	return b.count, nil
+}
@@ -0,0 +1,75 @@
+package record
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplayBuffer_KeepsLatestFrames(t *testing.T) {
+	// One second at 3 fps holds 3 frames
+	b := NewReplayBuffer(1, Options{FrameRate: 3})
+	for i := 0; i < 5; i++ {
+		b.Add(testFrame(uint32(i), 0))
+	}
+	if b.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", b.Len())
+	}
+
+	clone := b.Clone()
+	b.Add(testFrame(9, 0)) // Does not affect the clone
+
+	pixels := make([]uint32, 256*240)
+	for i, want := range []uint32{2, 3, 4} {
+		clone.frames[(clone.oldest()+i)%len(clone.frames)].decode(pixels)
+		if pixels[0] != want || pixels[100*256] != 0xFFFFFF {
+			t.Errorf("frame %d = %x, want %x", i, pixels[0], want)
+		}
+	}
+
+	b.Reset()
+	if b.Len() != 0 {
+		t.Errorf("Len() after Reset = %d", b.Len())
+	}
+}
+
+func TestReplayBuffer_ManyColors(t *testing.T) {
+	b := NewReplayBuffer(1, Options{FrameRate: 2})
+	frame := make([]uint32, 256*240)
+	for i := range frame {
+		frame[i] = uint32(i)
+	}
+	b.Add(frame)
+	b.Add(testFrame(1, 5)) // Two colors, stored as indices
+
+	pixels := make([]uint32, 256*240)
+	b.frames[0].decode(pixels)
+	if pixels[1000] != 1000 || pixels[256*240-1] != 256*240-1 {
+		t.Error("frame with more than 256 colors not kept exactly")
+	}
+	if len(b.frames[1].pixels) != 0 || len(b.frames[1].palette) != 2 {
+		t.Errorf("two color frame stored with %d pixels, %d colors", len(b.frames[1].pixels), len(b.frames[1].palette))
+	}
+}
+
+func TestReplayBuffer_Save(t *testing.T) {
+	b := NewReplayBuffer(1, Options{FrameRate: 4})
+	for i := 0; i < 6; i++ {
+		b.Add(testFrame(0x102030, i))
+	}
+	path := filepath.Join(t.TempDir(), "replay.png")
+	frames, err := b.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frames != 4 {
+		t.Errorf("Save wrote %d frames, want 4", frames)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+
+	if frames, err := NewReplayBuffer(1, Options{}).Clone().Save(filepath.Join(t.TempDir(), "empty.gif")); err != nil || frames != 0 {
+		t.Errorf("empty Save = %d, %v", frames, err)
+	}
+}
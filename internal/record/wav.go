@@ -0,0 +1,87 @@
+// Package record implements a 16-bit PCM WAV writer for recorded audio.
+package record
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// wavWriter streams mono 16-bit samples to a WAV file. The chunk sizes in the
+// header are patched in by close.
+type wavWriter struct {
+	file    *os.File
+	w       *bufio.Writer
+	samples uint32
+}
+
+func newWAVWriter(path string, sampleRate int) (*wavWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audio file: %v", err)
+	}
+	w := &wavWriter{file: file, w: bufio.NewWriter(file)}
+
+	header := make([]byte, 0, 44)
+	header = append(header, "RIFF\x00\x00\x00\x00WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, 16) // fmt chunk size
+	header = binary.LittleEndian.AppendUint16(header, 1)  // PCM
+	header = binary.LittleEndian.AppendUint16(header, 1)  // Mono
+	header = binary.LittleEndian.AppendUint32(header, uint32(sampleRate))
+	header = binary.LittleEndian.AppendUint32(header, uint32(sampleRate*2)) // Byte rate
+	header = binary.LittleEndian.AppendUint16(header, 2)                    // Block align
+	header = binary.LittleEndian.AppendUint16(header, 16)                   // Bits per sample
+	header = append(header, "data\x00\x00\x00\x00"...)
+	w.w.Write(header)
+	return w, nil
+}
+
+// write appends samples in the range -1.0 to 1.0
+func (w *wavWriter) write(samples []float32) error {
+	buf := make([]byte, 0, len(samples)*2)
+	for _, s := range samples {
+		s = max(-1, min(1, s))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(int16(s*32767)))
+	}
+	if _, err := w.w.Write(buf); err != nil {
+		return fmt.Errorf("failed to write audio: %v", err)
+	}
+	w.samples += uint32(len(samples))
+	return nil
+}
+
+func (w *wavWriter) close() error {
+	err := w.w.Flush()
+	dataSize := w.samples * 2
+	if err == nil {
+		_, err = w.file.WriteAt(binary.LittleEndian.AppendUint32(nil, 36+dataSize), 4)
+	}
+	if err == nil {
+		_, err = w.file.WriteAt(binary.LittleEndian.AppendUint32(nil, dataSize), 40)
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write audio: %v", err)
+	}
+	return nil
+}
+
+// wavEncoder records only the audio of a recording
+type wavEncoder struct {
+	*wavWriter
+}
+
+func newWAVEncoder(path string, sampleRate int) (*wavEncoder, error) {
+	w, err := newWAVWriter(path, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	return &wavEncoder{w}, nil
+}
+
+func (e *wavEncoder) writeFrame(pixels []uint32) error   { return nil }
+func (e *wavEncoder) writeAudio(samples []float32) error { return e.write(samples) }
+func (e *wavEncoder) close() error                       { return e.wavWriter.close() }
@@ -0,0 +1,157 @@
+// Package region describes the consoles of each TV system and how fast they
+// run: the NTSC NES of North America and Japan, the PAL NES of Europe and
+// Australia, and the Dendy, the PAL famiclone most Russian games were made for.
+package region
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Region is a console's TV system
+type Region uint8
+
+const (
+	NTSC Region = iota
+	PAL
+	Dendy
+)
+
+// Timing is how a region's console is clocked
+type Timing struct {
+	CPUClock float64 // CPU cycles per second
+
+	// PPU cycles per CPU cycle, as PPUCycles/CPUCycles: 3 for NTSC and the
+	// Dendy, 16/5 for PAL
+	PPUCycles uint64
+	CPUCycles uint64
+
+	// Master clock cycles per CPU cycle: 12 for NTSC, 16 for PAL and 15 for
+	// the Dendy (a PPU cycle is 4, 5 and 5)
+	MasterCycles uint64
+
+	Scanlines      int // Per frame, counting the pre-render line
+	VBlankScanline int // The scanline vblank and its NMI start on
+
+	// Whether every other frame is a PPU cycle short while rendering
+	SkipsOddCycle bool
+}
+
+// timings are the timings of each region
+var timings = [...]Timing{
+	NTSC:  {CPUClock: 1789773, PPUCycles: 3, CPUCycles: 1, MasterCycles: 12, Scanlines: 262, VBlankScanline: 241, SkipsOddCycle: true},
+	PAL:   {CPUClock: 1662607, PPUCycles: 16, CPUCycles: 5, MasterCycles: 16, Scanlines: 312, VBlankScanline: 241},
+	Dendy: {CPUClock: 1773448, PPUCycles: 3, CPUCycles: 1, MasterCycles: 15, Scanlines: 312, VBlankScanline: 291},
+}
+
+// names are the names of each region
+var names = [...]string{NTSC: "NTSC", PAL: "PAL", Dendy: "Dendy"}
+
+// Timing returns the region's timing
+func (r Region) Timing() Timing {
+	if int(r) >= len(timings) {
+		return timings[NTSC]
+	}
+	return timings[r]
+}
+
+// String returns the name of the region
+func (r Region) String() string {
+	if int(r) >= len(names) {
+		return fmt.Sprintf("Region(%d)", uint8(r))
+	}
+	return names[r]
+}
+
+// CPUCyclesPerFrame returns the average CPU cycles of a frame: 29780.5 for
+// NTSC, 33247.5 for PAL and 35464 for the Dendy
+func (t Timing) CPUCyclesPerFrame() float64 {
+	ppuCycles := float64(t.Scanlines * 341)
+	if t.SkipsOddCycle {
+		ppuCycles -= 0.5
+	}
+	return ppuCycles * float64(t.CPUCycles) / float64(t.PPUCycles)
+}
+
+// FrameRate returns the frames per second: 60.0988 for NTSC and 50.007 for
+// PAL and the Dendy
+func (t Timing) FrameRate() float64 {
+	return t.CPUClock / t.CPUCyclesPerFrame()
+}
+
+// Parse parses a region name such as "NTSC", "pal" or "Dendy"
+func Parse(s string) (Region, error) {
+	for r, name := range names {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return Region(r), nil
+		}
+	}
+	return NTSC, fmt.Errorf("unknown region %q (use NTSC, PAL or Dendy)", s)
+}
+
+// tagRegions are the regions of the country and region tags of ROM file
+// names: No-Intro's "(Europe)", GoodNES's "(E)" and the like
+var tagRegions = map[string]Region{
+	"NTSC": NTSC, "USA": NTSC, "U": NTSC, "Japan": NTSC, "J": NTSC, "Canada": NTSC,
+	"Korea": NTSC, "K": NTSC, "Brazil": NTSC, "Taiwan": NTSC,
+
+	"PAL": PAL, "Europe": PAL, "E": PAL, "Australia": PAL, "A": PAL, "UK": PAL,
+	"Germany": PAL, "G": PAL, "France": PAL, "F": PAL, "Spain": PAL, "S": PAL,
+	"Italy": PAL, "I": PAL, "Sweden": PAL, "Sw": PAL, "Netherlands": PAL, "Nl": PAL,
+	"Scandinavia": PAL,
+
+	"Dendy": Dendy,
+}
+
+// FromName detects the region from the tags of a ROM file name or No-Intro
+// title, such as "Elite (Europe).nes" or "Gradius (E) [!].nes". It returns
+// false without a region tag, and for games sold in both NTSC and PAL
+// regions ("(USA, Europe)", "(World)"), which are left to the header.
+func FromName(name string) (Region, bool) {
+	found, detected := NTSC, false
+	for rest := name; ; {
+		open := strings.IndexAny(rest, "([")
+		if open < 0 {
+			break
+		}
+		end := strings.IndexAny(rest[open:], ")]")
+		if end < 0 {
+			break
+		}
+		tag := rest[open+1 : open+end]
+		rest = rest[open+end+1:]
+
+		for _, r := range tagRegionsOf(tag) {
+			if detected && r != found {
+				return NTSC, false
+			}
+			found, detected = r, true
+		}
+	}
+	return found, detected
+}
+
+// tagRegionsOf returns the regions a tag names: each of a comma separated
+// list, or each letter of GoodNES's combined codes such as "JU". A tag that
+// is not all region names names none, so "(Rev 1)" and "(Hack)" are skipped.
+func tagRegionsOf(tag string) []Region {
+	var found []Region
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if r, ok := tagRegions[part]; ok {
+			found = append(found, r)
+			continue
+		}
+		if len(part) < 2 || len(part) > 3 || strings.ToUpper(part) != part {
+			return nil
+		}
+		for _, letter := range part {
+			r, ok := tagRegions[string(letter)]
+			if !ok {
+				return nil
+			}
+			found = append(found, r)
+		}
+	}
+	return found
+}
@@ -0,0 +1,52 @@
+package region
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFrameRate(t *testing.T) {
+	for r, want := range map[Region]float64{NTSC: 60.0988, PAL: 50.0070, Dendy: 50.0070} {
+		if got := r.Timing().FrameRate(); math.Abs(got-want) > 0.0001 {
+			t.Errorf("%v frame rate = %.4f, want %.4f", r, got, want)
+		}
+	}
+	if got := PAL.Timing().CPUCyclesPerFrame(); got != 33247.5 {
+		t.Errorf("PAL CPU cycles per frame = %v", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for s, want := range map[string]Region{"NTSC": NTSC, "pal": PAL, " Dendy ": Dendy} {
+		if got, err := Parse(s); err != nil || got != want {
+			t.Errorf("Parse(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := Parse("SECAM"); err == nil {
+		t.Error("Parse(SECAM) succeeded")
+	}
+}
+
+func TestFromName(t *testing.T) {
+	for name, want := range map[string]Region{
+		"Elite (Europe).nes":               PAL,
+		"Gradius (E) [!].nes":              PAL,
+		"Contra (U) [!].nes":               NTSC,
+		"Tetris (Japan) (Rev 1).nes":       NTSC,
+		"Kirby's Adventure (Germany).nes":  PAL,
+		"Castlevania (JU) [b1].nes":        NTSC,
+		"Super Contra 7 (Dendy) (Unl).nes": Dendy,
+	} {
+		if got, ok := FromName(name); !ok || got != want {
+			t.Errorf("FromName(%q) = %v, %v, want %v", name, got, ok, want)
+		}
+	}
+	for _, name := range []string{
+		"game.nes", "Tetris (USA, Europe).nes", "Pac-Man (UE).nes",
+		"Super Mario Bros. (World).nes", "Homebrew (PD).nes", "Game (Hack).nes",
+	} {
+		if got, ok := FromName(name); ok {
+			t.Errorf("FromName(%q) = %v", name, got)
+		}
+	}
+}
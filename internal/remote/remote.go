@@ -0,0 +1,415 @@
+// Package remote provides a JSON-RPC 2.0 server that external debuggers and
+// editors use to control a running emulator. Clients connect over TCP, sending
+// one request per line, or over WebSocket on the same port or through an HTTP
+// server, one request per text message. Requests are queued and run by Process
+// on the emulation thread, so handlers can touch the emulator without locking.
+package remote
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Standard JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeServerError    = -32000
+)
+
+// maxMessageSize limits a request's size
+const maxMessageSize = 1 << 20
+
+// writeTimeout drops clients that stop reading instead of stalling emulation
+const writeTimeout = 2 * time.Second
+
+// Error is a JSON-RPC error. Handlers return one to choose the code; other
+// errors are reported as CodeServerError.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// InvalidParams returns a CodeInvalidParams error
+func InvalidParams(format string, args ...any) *Error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// Handler runs a method with its raw params (nil if absent) and returns the
+// result, which is encoded as JSON
+type Handler func(params json.RawMessage) (any, error)
+
+// Options configures a server
+type Options struct {
+	// Origins browsers may open WebSocket connections from. Requests with any
+	// other Origin header are refused, so web pages cannot drive the emulator.
+	AllowedOrigins []string
+}
+
+// request is an incoming JSON-RPC request or notification
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// notification is a message sent to clients without a request
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// call is a request waiting for Process, from a client or from Call
+type call struct {
+	conn *conn
+	req  request
+	done chan result // Set by Call
+}
+
+// result is the outcome of a call made with Call
+type result struct {
+	value any
+	err   error
+}
+
+// Server accepts clients and queues their requests for Process
+type Server struct {
+	listener net.Listener // nil unless Listen was called
+	options  Options
+	methods  map[string]Handler // Only used by the Process goroutine
+	calls    chan call
+
+	mu     sync.Mutex
+	conns  map[*conn]bool
+	wg     sync.WaitGroup
+	closed chan struct{}
+	once   sync.Once
+}
+
+// New creates a server. It takes clients from Listen, from an HTTP server
+// it is mounted on as a WebSocket endpoint, and calls from Call.
+func New(options Options) *Server {
+	return &Server{
+		options: options,
+		methods: make(map[string]Handler),
+		calls:   make(chan call, 64),
+		conns:   make(map[*conn]bool),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Listen accepts clients on a TCP address such as "127.0.0.1:6502"
+func (s *Server) Listen(address string) error {
+	if s.listener != nil {
+		return fmt.Errorf("already listening on %s", s.listener.Addr())
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", address, err)
+	}
+	s.listener = listener
+	s.wg.Add(1)
+	go s.accept()
+	return nil
+}
+
+// Addr returns the address the server listens on, or nil before Listen
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// ErrClosed is returned by Call after Close
+var ErrClosed = errors.New("server closed")
+
+// Call queues a method call like a client request and waits for Process to
+// run it, or for ctx to end. It is for other servers' goroutines, such as HTTP
+// handlers; calling it from the goroutine that runs Process would wait forever.
+func (s *Server) Call(ctx context.Context, method string, params any) (any, error) {
+	var raw json.RawMessage
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, InvalidParams("invalid params: %v", err)
+		}
+		raw = data
+	}
+	done := make(chan result, 1)
+	select {
+	case s.calls <- call{req: request{JSONRPC: "2.0", Method: method, Params: raw}, done: done}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.closed:
+		return nil, ErrClosed
+	}
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.closed:
+		return nil, ErrClosed
+	}
+}
+
+// Handle registers a method. Call it from the goroutine that calls Process.
+func (s *Server) Handle(method string, handler Handler) {
+	s.methods[method] = handler
+}
+
+// Process runs the queued requests and returns how many ran. It never blocks.
+func (s *Server) Process() int {
+	n := 0
+	for {
+		select {
+		case c := <-s.calls:
+			s.run(c)
+			n++
+		default:
+			return n
+		}
+	}
+}
+
+// Wait runs requests as they arrive until timeout passes, for loops that
+// have nothing else to do, and returns how many ran
+func (s *Server) Wait(timeout time.Duration) int {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	n := 0
+	for {
+		select {
+		case c := <-s.calls:
+			s.run(c)
+			n++
+		case <-timer.C:
+			return n
+		}
+	}
+}
+
+// Notify sends a notification to every client
+func (s *Server) Notify(method string, params any) {
+	data, err := json.Marshal(notification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.send(data)
+	}
+}
+
+// Clients returns the number of connected clients
+func (s *Server) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Close stops accepting clients and disconnects the connected ones
+func (s *Server) Close() error {
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	s.once.Do(func() { close(s.closed) })
+	s.mu.Lock()
+	for c := range s.conns {
+		c.close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// accept serves each client on its own goroutine
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.serve(&conn{server: s, netConn: netConn, reader: bufio.NewReader(netConn)})
+	}
+}
+
+// serve serves a client on its own goroutine until it disconnects
+func (s *Server) serve(c *conn) {
+	s.mu.Lock()
+	s.conns[c] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		c.serve()
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.close()
+	}()
+}
+
+// run calls a request's handler and answers it unless it is a notification
+func (s *Server) run(c call) {
+	value, err := s.dispatch(c.req)
+	if c.done != nil {
+		c.done <- result{value: value, err: err}
+		return
+	}
+	if c.req.ID == nil {
+		return
+	}
+	resp := response{JSONRPC: "2.0", ID: c.req.ID, Result: value}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeServerError, Message: err.Error()}
+		}
+		resp.Result, resp.Error = nil, rpcErr
+	} else if value == nil {
+		resp.Result = struct{}{} // A result member is required on success
+	}
+	c.conn.reply(resp)
+}
+
+// dispatch calls the handler of a request's method
+func (s *Server) dispatch(req request) (any, error) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, &Error{Code: CodeInvalidRequest, Message: "invalid request"}
+	}
+	handler, ok := s.methods[req.Method]
+	if !ok {
+		return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+	}
+	return handler(req.Params)
+}
+
+// conn is a connected client on either transport
+type conn struct {
+	server  *Server
+	netConn net.Conn
+	reader  *bufio.Reader
+	ws      bool // WebSocket framing after the handshake
+
+	writeMu sync.Mutex
+	once    sync.Once
+}
+
+// serve reads the client's requests until it disconnects
+func (c *conn) serve() {
+	if start, err := c.reader.Peek(4); !c.ws && err == nil && string(start) == "GET " {
+		if err := c.handshake(); err != nil {
+			return
+		}
+		c.ws = true
+	}
+
+	for {
+		var message []byte
+		var err error
+		if c.ws {
+			message, err = c.readFrame()
+		} else {
+			message, err = c.readLine()
+		}
+		if err != nil {
+			return
+		}
+		if len(message) == 0 {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal(message, &req); err != nil {
+			c.reply(response{JSONRPC: "2.0", ID: json.RawMessage("null"),
+				Error: &Error{Code: CodeParseError, Message: "parse error"}})
+			continue
+		}
+		select {
+		case c.server.calls <- call{conn: c, req: req}:
+		case <-c.server.closed:
+			return
+		}
+	}
+}
+
+// readLine reads a newline terminated message
+func (c *conn) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := c.reader.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxMessageSize {
+			return nil, fmt.Errorf("message too large")
+		}
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
+
+// reply sends a response
+func (c *conn) reply(resp response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(response{JSONRPC: "2.0", ID: resp.ID,
+			Error: &Error{Code: CodeServerError, Message: fmt.Sprintf("failed to encode result: %v", err)}})
+	}
+	c.send(data)
+}
+
+// send writes a message, disconnecting the client if it cannot keep up
+func (c *conn) send(data []byte) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.netConn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	var err error
+	if c.ws {
+		err = c.writeFrame(opText, data)
+	} else {
+		_, err = c.netConn.Write(append(data, '\n'))
+	}
+	if err != nil {
+		c.close()
+	}
+}
+
+// close disconnects the client
+func (c *conn) close() {
+	c.once.Do(func() { c.netConn.Close() })
+}
@@ -0,0 +1,278 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer starts a server on a free port with an "add" method
+func startServer(t *testing.T, options Options) *Server {
+	t.Helper()
+	s := New(options)
+	if err := s.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	s.Handle("add", func(params json.RawMessage) (any, error) {
+		var args []int
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, InvalidParams("want a list of numbers")
+		}
+		sum := 0
+		for _, n := range args {
+			sum += n
+		}
+		return sum, nil
+	})
+	s.Handle("fail", func(json.RawMessage) (any, error) {
+		return nil, fmt.Errorf("no ROM loaded")
+	})
+	return s
+}
+
+// roundTrip sends a line and processes requests until a line comes back
+func roundTrip(t *testing.T, s *Server, c net.Conn, r *bufio.Reader, line string) map[string]any {
+	t.Helper()
+	if _, err := c.Write([]byte(line + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	s.Wait(200 * time.Millisecond)
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	reply, err := r.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("no reply to %s: %v", line, err)
+	}
+	var message map[string]any
+	if err := json.Unmarshal(reply, &message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func errorCode(message map[string]any) int {
+	e, _ := message["error"].(map[string]any)
+	code, _ := e["code"].(float64)
+	return int(code)
+}
+
+func TestServerTCP(t *testing.T) {
+	s := startServer(t, Options{})
+	c, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	reply := roundTrip(t, s, c, r, `{"jsonrpc":"2.0","id":1,"method":"add","params":[1,2,3]}`)
+	if reply["result"] != 6.0 || reply["id"] != 1.0 {
+		t.Errorf("add reply = %v", reply)
+	}
+
+	tests := []struct {
+		line string
+		code int
+	}{
+		{`{"jsonrpc":"2.0","id":2,"method":"add","params":"x"}`, CodeInvalidParams},
+		{`{"jsonrpc":"2.0","id":3,"method":"nope"}`, CodeMethodNotFound},
+		{`{"jsonrpc":"2.0","id":4,"method":"fail"}`, CodeServerError},
+		{`{"id":5,"method":"add"}`, CodeInvalidRequest},
+		{`{not json`, CodeParseError},
+	}
+	for _, test := range tests {
+		if reply := roundTrip(t, s, c, r, test.line); errorCode(reply) != test.code {
+			t.Errorf("%s: reply %v, want error %d", test.line, reply, test.code)
+		}
+	}
+}
+
+func TestServerNotify(t *testing.T) {
+	s := startServer(t, Options{})
+	c, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	// A notification request gets no reply; the server's notification does
+	c.Write([]byte(`{"jsonrpc":"2.0","method":"add","params":[1]}` + "\n"))
+	for deadline := time.Now().Add(time.Second); s.Clients() == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	s.Wait(100 * time.Millisecond)
+	s.Notify("stopped", map[string]int{"pc": 0x8000})
+
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(line, `"method":"stopped"`) || strings.Contains(line, `"id"`) {
+		t.Errorf("notification = %s", line)
+	}
+}
+
+// dialWebSocket opens a WebSocket connection with an optional Origin
+func dialWebSocket(t *testing.T, s *Server, origin string) (net.Conn, *bufio.Reader, string) {
+	t.Helper()
+	c, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	request := "GET / HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+	if origin != "" {
+		request += "Origin: " + origin + "\r\n"
+	}
+	c.Write([]byte(request + "\r\n"))
+
+	r := bufio.NewReader(c)
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	status, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil || line == "\r\n" {
+			break
+		}
+	}
+	return c, r, strings.TrimSpace(status)
+}
+
+// writeMasked writes a masked client text frame
+func writeMasked(c net.Conn, payload []byte) {
+	frame := []byte{0x81, 0x80 | byte(len(payload)), 1, 2, 3, 4}
+	for i, b := range payload {
+		frame = append(frame, b^frame[2+i%4])
+	}
+	c.Write(frame)
+}
+
+func TestServerWebSocket(t *testing.T) {
+	if got := websocketAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("websocketAccept = %s", got) // The RFC 6455 example
+	}
+
+	s := startServer(t, Options{AllowedOrigins: []string{"http://localhost:8080"}})
+	c, r, status := dialWebSocket(t, s, "http://localhost:8080")
+	if status != "HTTP/1.1 101 Switching Protocols" {
+		t.Fatalf("handshake status %q", status)
+	}
+
+	writeMasked(c, []byte(`{"jsonrpc":"2.0","id":"a","method":"add","params":[40,2]}`))
+	s.Wait(200 * time.Millisecond)
+
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	if header[0] != 0x81 || header[1]&0x80 != 0 {
+		t.Fatalf("frame header %X", header)
+	}
+	payload := make([]byte, header[1])
+	io.ReadFull(r, payload)
+	if string(payload) != `{"jsonrpc":"2.0","id":"a","result":42}` {
+		t.Errorf("reply = %s", payload)
+	}
+
+	if _, _, status := dialWebSocket(t, s, "http://evil.example"); !strings.Contains(status, "403") {
+		t.Errorf("foreign origin got %q", status)
+	}
+}
+
+func TestWriteFrameLength(t *testing.T) {
+	for _, n := range []int{5, 200, 70000} {
+		server, client := net.Pipe()
+		c := &conn{netConn: server}
+		go func() {
+			c.writeFrame(opText, make([]byte, n))
+			server.Close()
+		}()
+		data, _ := io.ReadAll(client)
+		length := int(data[1])
+		switch length {
+		case 126:
+			length = int(binary.BigEndian.Uint16(data[2:]))
+		case 127:
+			length = int(binary.BigEndian.Uint64(data[2:]))
+		}
+		if length != n {
+			t.Errorf("frame of %d bytes has length %d", n, length)
+		}
+	}
+}
+
+func TestServerCall(t *testing.T) {
+	s := New(Options{})
+	s.Handle("add", func(params json.RawMessage) (any, error) {
+		var args []int
+		json.Unmarshal(params, &args)
+		return args[0] + args[1], nil
+	})
+
+	done := make(chan any)
+	go func() {
+		value, err := s.Call(context.Background(), "add", []int{2, 3})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- value
+	}()
+	for s.Wait(10*time.Millisecond) == 0 {
+	}
+	if value := <-done; value != 5 {
+		t.Errorf("Call = %v, want 5", value)
+	}
+
+	s.Close()
+	if _, err := s.Call(context.Background(), "add", []int{1, 1}); err != ErrClosed {
+		t.Errorf("Call after Close = %v", err)
+	}
+}
+
+func TestServerHTTP(t *testing.T) {
+	s := startServer(t, Options{})
+	httpServer := httptest.NewServer(s)
+	defer httpServer.Close()
+
+	c, err := net.Dial("tcp", strings.TrimPrefix(httpServer.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Write([]byte("GET /api/ws HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	r := bufio.NewReader(c)
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	if status, _ := r.ReadString('\n'); !strings.Contains(status, "101") {
+		t.Fatalf("handshake status %q", status)
+	}
+	for {
+		if line, err := r.ReadString('\n'); err != nil || line == "\r\n" {
+			break
+		}
+	}
+
+	writeMasked(c, []byte(`{"jsonrpc":"2.0","id":1,"method":"add","params":[1,1]}`))
+	s.Wait(200 * time.Millisecond)
+	var header [2]byte
+	io.ReadFull(r, header[:])
+	payload := make([]byte, header[1]&0x7F)
+	io.ReadFull(r, payload)
+	if string(payload) != `{"jsonrpc":"2.0","id":1,"result":2}` {
+		t.Errorf("reply = %s", payload)
+	}
+}
@@ -0,0 +1,192 @@
+// Package remote provides the WebSocket transport (RFC 6455) of the server.
+package remote
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// websocketGUID is appended to the client's key to prove the handshake
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket frame opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// websocketAccept returns the Sec-WebSocket-Accept value for a key
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// handshake answers the HTTP upgrade request of a client that connected to
+// the server's own port
+func (c *conn) handshake() error {
+	req, err := http.ReadRequest(c.reader)
+	if err != nil {
+		return fmt.Errorf("invalid handshake: %v", err)
+	}
+	status, err := c.server.checkUpgrade(req)
+	if err != nil {
+		c.netConn.Write([]byte(fmt.Sprintf("HTTP/1.1 %d %s\r\nContent-Length: 0\r\n\r\n", status, http.StatusText(status))))
+		return err
+	}
+	return writeUpgrade(c.netConn, req)
+}
+
+// ServeHTTP serves JSON-RPC over WebSocket on an HTTP server's route
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if status, err := s.checkUpgrade(r); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection cannot be upgraded", http.StatusInternalServerError)
+		return
+	}
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	netConn.SetDeadline(time.Time{})
+	if err := writeUpgrade(netConn, r); err != nil {
+		netConn.Close()
+		return
+	}
+	s.serve(&conn{server: s, netConn: netConn, reader: rw.Reader, ws: true})
+}
+
+// checkUpgrade checks a WebSocket upgrade request, returning the HTTP status
+// to refuse it with
+func (s *Server) checkUpgrade(req *http.Request) (int, error) {
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") || req.Header.Get("Sec-WebSocket-Key") == "" {
+		return http.StatusBadRequest, fmt.Errorf("not a WebSocket request")
+	}
+	if origin := req.Header.Get("Origin"); origin != "" && !s.originAllowed(origin) {
+		return http.StatusForbidden, fmt.Errorf("origin %s not allowed", origin)
+	}
+	return 0, nil
+}
+
+// writeUpgrade accepts a checked upgrade request
+func writeUpgrade(w io.Writer, req *http.Request) error {
+	_, err := w.Write([]byte("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + websocketAccept(req.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n"))
+	return err
+}
+
+// originAllowed returns whether browsers on origin may connect
+func (s *Server) originAllowed(origin string) bool {
+	for _, allowed := range s.options.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// readFrame reads the next text or binary message, answering pings and
+// joining fragments. It returns io.EOF when the client closes.
+func (c *conn) readFrame() ([]byte, error) {
+	var message []byte
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			return nil, err
+		}
+		final := header[0]&0x80 != 0
+		opcode := header[0] & 0x0F
+		masked := header[1]&0x80 != 0
+		length := uint64(header[1] & 0x7F)
+
+		switch length {
+		case 126:
+			var extended [2]byte
+			if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+				return nil, err
+			}
+			length = uint64(binary.BigEndian.Uint16(extended[:]))
+		case 127:
+			var extended [8]byte
+			if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+				return nil, err
+			}
+			length = binary.BigEndian.Uint64(extended[:])
+		}
+		if !masked {
+			return nil, fmt.Errorf("unmasked client frame")
+		}
+		if length > maxMessageSize || uint64(len(message))+length > maxMessageSize {
+			return nil, fmt.Errorf("message too large")
+		}
+
+		var mask [4]byte
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return nil, err
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return nil, err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+
+		switch opcode {
+		case opPing:
+			c.writeMu.Lock()
+			err := c.writeFrame(opPong, payload)
+			c.writeMu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opClose:
+			c.writeMu.Lock()
+			c.writeFrame(opClose, nil)
+			c.writeMu.Unlock()
+			return nil, io.EOF
+		case opText, opBinary, opContinuation:
+			message = append(message, payload...)
+			if final {
+				return message, nil
+			}
+		default:
+			return nil, fmt.Errorf("unknown frame opcode %d", opcode)
+		}
+	}
+}
+
+// writeFrame writes an unmasked frame. The caller holds writeMu.
+func (c *conn) writeFrame(opcode byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err := c.netConn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+// Package savestate provides a compact binary encoding for emulator component state.
+//
+// Components implement Stateful and write their fields in a fixed order with a
+// Writer; the matching LoadState reads them back in the same order with a Reader.
+// Both Writer and Reader use sticky errors so component code can write/read many
+// fields and check the error once at the end.
+package savestate
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrTruncated is returned when a state payload ends before all fields were read
+var ErrTruncated = errors.New("savestate: data truncated")
+
+// Stateful is implemented by components that can snapshot and restore their state
+type Stateful interface {
+	SaveState(w *Writer)
+	LoadState(r *Reader) error
+}
+
+// Writer appends little-endian encoded values to a byte buffer
+type Writer struct {
+	buf []byte
+}
+
+// NewWriter creates a writer with an initial capacity hint
+func NewWriter(capacity int) *Writer {
+	return &Writer{buf: make([]byte, 0, capacity)}
+}
+
+// Bytes returns the encoded data
+func (w *Writer) Bytes() []byte {
+	return w.buf
+}
+
+// Len returns the number of bytes written so far
+func (w *Writer) Len() int {
+	return len(w.buf)
+}
+
+// Reset empties the writer, keeping its buffer for reuse
+func (w *Writer) Reset() {
+	w.buf = w.buf[:0]
+}
+
+// WriteU8 writes a byte
+func (w *Writer) WriteU8(v uint8) {
+	w.buf = append(w.buf, v)
+}
+
+// WriteBool writes a boolean as one byte
+func (w *Writer) WriteBool(v bool) {
+	if v {
+		w.buf = append(w.buf, 1)
+	} else {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+// WriteU16 writes a 16-bit value
+func (w *Writer) WriteU16(v uint16) {
+	w.buf = binary.LittleEndian.AppendUint16(w.buf, v)
+}
+
+// WriteU32 writes a 32-bit value
+func (w *Writer) WriteU32(v uint32) {
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, v)
+}
+
+// WriteU64 writes a 64-bit value
+func (w *Writer) WriteU64(v uint64) {
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, v)
+}
+
+// WriteInt writes an int as a signed 64-bit value
+func (w *Writer) WriteInt(v int) {
+	w.WriteU64(uint64(int64(v)))
+}
+
+// WriteF64 writes a float64
+func (w *Writer) WriteF64(v float64) {
+	w.WriteU64(math.Float64bits(v))
+}
+
+// WriteBytes writes a length-prefixed byte slice
+func (w *Writer) WriteBytes(data []byte) {
+	w.WriteU32(uint32(len(data)))
+	w.buf = append(w.buf, data...)
+}
+
+// WriteU32s writes a length-prefixed slice of 32-bit values
+func (w *Writer) WriteU32s(data []uint32) {
+	w.WriteU32(uint32(len(data)))
+	for _, v := range data {
+		w.buf = binary.LittleEndian.AppendUint32(w.buf, v)
+	}
+}
+
+// WriteTag writes a 4-byte section tag used to detect misaligned payloads
+func (w *Writer) WriteTag(tag string) {
+	var t [4]byte
+	copy(t[:], tag)
+	w.buf = append(w.buf, t[:]...)
+}
+
+// Reader decodes values written by Writer
+type Reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// NewReader creates a reader over encoded state data
+func NewReader(data []byte) *Reader {
+	return &Reader{data: data}
+}
+
+// Err returns the first error encountered while reading
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Remaining returns the number of unread bytes
+func (r *Reader) Remaining() int {
+	return len(r.data) - r.pos
+}
+
+// take returns the next n bytes, or nil (and sets the error) if not enough data remains
+func (r *Reader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.err = ErrTruncated
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// ReadU8 reads a byte
+func (r *Reader) ReadU8() uint8 {
+	b := r.take(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+// ReadBool reads a boolean
+func (r *Reader) ReadBool() bool {
+	return r.ReadU8() != 0
+}
+
+// ReadU16 reads a 16-bit value
+func (r *Reader) ReadU16() uint16 {
+	b := r.take(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+// ReadU32 reads a 32-bit value
+func (r *Reader) ReadU32() uint32 {
+	b := r.take(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+// ReadU64 reads a 64-bit value
+func (r *Reader) ReadU64() uint64 {
+	b := r.take(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// ReadInt reads an int written by WriteInt
+func (r *Reader) ReadInt() int {
+	return int(int64(r.ReadU64()))
+}
+
+// ReadF64 reads a float64
+func (r *Reader) ReadF64() float64 {
+	return math.Float64frombits(r.ReadU64())
+}
+
+// ReadBytes reads a length-prefixed byte slice
+func (r *Reader) ReadBytes() []byte {
+	n := int(r.ReadU32())
+	b := r.take(n)
+	if b == nil {
+		return nil
+	}
+	out := make([]byte, n)
+	copy(out, b)
+	return out
+}
+
+// ReadBytesInto reads a length-prefixed byte slice into dst, which must have the same length
+func (r *Reader) ReadBytesInto(dst []byte) {
+	n := int(r.ReadU32())
+	if r.err == nil && n != len(dst) {
+		r.err = fmt.Errorf("savestate: expected %d bytes, found %d", len(dst), n)
+		return
+	}
+	if b := r.take(n); b != nil {
+		copy(dst, b)
+	}
+}
+
+// ReadU32sInto reads a length-prefixed slice of 32-bit values into dst, which must have the same length
+func (r *Reader) ReadU32sInto(dst []uint32) {
+	n := int(r.ReadU32())
+	if r.err == nil && n != len(dst) {
+		r.err = fmt.Errorf("savestate: expected %d values, found %d", len(dst), n)
+		return
+	}
+	b := r.take(n * 4)
+	if b == nil {
+		return
+	}
+	for i := range dst {
+		dst[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+}
+
+// ExpectTag reads a 4-byte section tag and fails if it doesn't match
+func (r *Reader) ExpectTag(tag string) {
+	b := r.take(4)
+	if b == nil {
+		return
+	}
+	var t [4]byte
+	copy(t[:], tag)
+	if string(b) != string(t[:]) {
+		r.err = fmt.Errorf("savestate: expected section %q, found %q", tag, string(b))
+	}
+}
+
+// Fail records an error (e.g. a failed validation) if none has been recorded yet
+func (r *Reader) Fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
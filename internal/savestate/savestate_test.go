@@ -0,0 +1,102 @@
+package savestate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWriterReader_RoundTrip(t *testing.T) {
+	w := NewWriter(0)
+	w.WriteTag("TEST")
+	w.WriteU8(0x12)
+	w.WriteBool(true)
+	w.WriteU16(0x3456)
+	w.WriteU32(0x789ABCDE)
+	w.WriteU64(0x0123456789ABCDEF)
+	w.WriteInt(-5)
+	w.WriteF64(1.5)
+	w.WriteBytes([]byte{1, 2, 3})
+	w.WriteU32s([]uint32{0xFFFFFF, 0x000001})
+
+	r := NewReader(w.Bytes())
+	r.ExpectTag("TEST")
+	if v := r.ReadU8(); v != 0x12 {
+		t.Errorf("ReadU8 = 0x%02X", v)
+	}
+	if !r.ReadBool() {
+		t.Error("ReadBool = false")
+	}
+	if v := r.ReadU16(); v != 0x3456 {
+		t.Errorf("ReadU16 = 0x%04X", v)
+	}
+	if v := r.ReadU32(); v != 0x789ABCDE {
+		t.Errorf("ReadU32 = 0x%08X", v)
+	}
+	if v := r.ReadU64(); v != 0x0123456789ABCDEF {
+		t.Errorf("ReadU64 = 0x%016X", v)
+	}
+	if v := r.ReadInt(); v != -5 {
+		t.Errorf("ReadInt = %d", v)
+	}
+	if v := r.ReadF64(); v != 1.5 {
+		t.Errorf("ReadF64 = %f", v)
+	}
+	if b := r.ReadBytes(); len(b) != 3 || b[2] != 3 {
+		t.Errorf("ReadBytes = %v", b)
+	}
+	var words [2]uint32
+	r.ReadU32sInto(words[:])
+	if words[0] != 0xFFFFFF || words[1] != 1 {
+		t.Errorf("ReadU32sInto = %v", words)
+	}
+
+	if r.Err() != nil {
+		t.Errorf("Unexpected error: %v", r.Err())
+	}
+	if r.Remaining() != 0 {
+		t.Errorf("Expected no remaining bytes, got %d", r.Remaining())
+	}
+}
+
+func TestReader_TruncatedDataIsSticky(t *testing.T) {
+	r := NewReader([]byte{0x01})
+	r.ReadU16()
+	r.ReadU8()
+
+	if !errors.Is(r.Err(), ErrTruncated) {
+		t.Errorf("Expected ErrTruncated, got %v", r.Err())
+	}
+}
+
+func TestReader_LengthMismatch(t *testing.T) {
+	w := NewWriter(0)
+	w.WriteBytes([]byte{1, 2, 3})
+
+	var dst [4]byte
+	r := NewReader(w.Bytes())
+	r.ReadBytesInto(dst[:])
+	if r.Err() == nil {
+		t.Error("Expected error for length mismatch")
+	}
+}
+
+func TestReader_WrongTag(t *testing.T) {
+	w := NewWriter(0)
+	w.WriteTag("AAAA")
+
+	r := NewReader(w.Bytes())
+	r.ExpectTag("BBBB")
+	if r.Err() == nil {
+		t.Error("Expected error for wrong tag")
+	}
+}
+
+func TestWriter_Reset(t *testing.T) {
+	w := NewWriter(4)
+	w.WriteU32(0xDEADBEEF)
+	w.Reset()
+	w.WriteU8(7)
+	if got := w.Bytes(); len(got) != 1 || got[0] != 7 {
+		t.Errorf("Bytes after Reset = %v, want [7]", got)
+	}
+}
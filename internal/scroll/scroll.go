@@ -0,0 +1,92 @@
+// Package scroll writes the scroll of every scanline to CSV logs, one row per
+// scanline, for finding where status bar splits and parallax go wrong.
+package scroll
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"gones/internal/ppu"
+)
+
+// Header is the first row of a log
+const Header = "frame,scanline,scroll_x,scroll_y,nametable,rendering"
+
+// Log writes the scanlines of each frame as CSV rows
+type Log struct {
+	path   string
+	file   *os.File
+	w      *bufio.Writer
+	frames int
+}
+
+// Create starts a log in a new file at path
+func Create(path string) (*Log, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scroll log: %v", err)
+	}
+	l := &Log{path: path, file: file, w: bufio.NewWriter(file)}
+	fmt.Fprintln(l.w, Header)
+	return l, nil
+}
+
+// AddFrame writes the scanlines of a frame
+func (l *Log) AddFrame(frame uint64, lines *[240]ppu.ScanlineScroll) error {
+	if err := WriteFrame(l.w, frame, lines); err != nil {
+		return fmt.Errorf("failed to write scroll log: %v", err)
+	}
+	l.frames++
+	return nil
+}
+
+// Path returns the file the log is written to
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Frames returns the number of frames written
+func (l *Log) Frames() int {
+	return l.frames
+}
+
+// Close flushes and closes the log
+func (l *Log) Close() error {
+	err := l.w.Flush()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write scroll log: %v", err)
+	}
+	return nil
+}
+
+// WriteFrame writes the rows of a frame's scanlines, without the header.
+// The nametable is given as its address, such as 2400.
+func WriteFrame(w io.Writer, frame uint64, lines *[240]ppu.ScanlineScroll) error {
+	for scanline, s := range lines {
+		rendering := 0
+		if s.Rendering {
+			rendering = 1
+		}
+		if _, err := fmt.Fprintf(w, "%d,%d,%d,%d,%X,%d\n", frame, scanline, s.X, s.Y, 0x2000+s.Nametable*0x400, rendering); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Splits returns the scanlines whose scroll differs from the line above,
+// where a game changed it mid-frame. The first line is never one.
+func Splits(lines *[240]ppu.ScanlineScroll) []int {
+	var splits []int
+	for scanline := 1; scanline < len(lines); scanline++ {
+		if lines[scanline] != lines[scanline-1] {
+			splits = append(splits, scanline)
+		}
+	}
+	return splits
+}
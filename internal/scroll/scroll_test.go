@@ -0,0 +1,59 @@
+package scroll
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gones/internal/ppu"
+)
+
+func TestLog(t *testing.T) {
+	var lines [240]ppu.ScanlineScroll
+	for scanline := range lines {
+		lines[scanline] = ppu.ScanlineScroll{Rendering: true}
+		if scanline >= 32 {
+			lines[scanline] = ppu.ScanlineScroll{X: 53, Y: 18, Nametable: 1, Rendering: true}
+		}
+	}
+	if splits := Splits(&lines); len(splits) != 1 || splits[0] != 32 {
+		t.Errorf("Splits %v, want [32]", splits)
+	}
+
+	path := filepath.Join(t.TempDir(), "scroll.csv")
+	log, err := Create(path)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for frame := uint64(1); frame <= 2; frame++ {
+		if err := log.AddFrame(frame, &lines); err != nil {
+			t.Fatalf("AddFrame failed: %v", err)
+		}
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if log.Frames() != 2 {
+		t.Errorf("Frames() = %d", log.Frames())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(rows) != 1+2*240 {
+		t.Fatalf("%d rows, want %d", len(rows), 1+2*240)
+	}
+	for i, want := range map[int]string{
+		0:   Header,
+		1:   "1,0,0,0,2000,1",
+		33:  "1,32,53,18,2400,1",
+		480: "2,239,53,18,2400,1",
+	} {
+		if rows[i] != want {
+			t.Errorf("Row %d = %q, want %q", i, rows[i], want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+// Package trace provides a CPU trace logger that streams executed
+// instructions and memory accesses to a file, or keeps the last of them in a
+// ring buffer to be dumped later, such as after a crash.
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gones/internal/cpu"
+)
+
+// Range is an inclusive range of CPU addresses
+type Range struct {
+	Start uint16
+	End   uint16
+}
+
+// Contains returns whether address is in the range
+func (r Range) Contains(address uint16) bool {
+	return address >= r.Start && address <= r.End
+}
+
+// ParseRange parses "8000-80FF" or a single address "C000", with optional
+// "$" prefixes
+func ParseRange(s string) (Range, error) {
+	start, end, found := strings.Cut(strings.ReplaceAll(strings.TrimSpace(s), "$", ""), "-")
+	if !found {
+		end = start
+	}
+	a, err := strconv.ParseUint(strings.TrimSpace(start), 16, 16)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid address range %q", s)
+	}
+	b, err := strconv.ParseUint(strings.TrimSpace(end), 16, 16)
+	if err != nil || b < a {
+		return Range{}, fmt.Errorf("invalid address range %q", s)
+	}
+	return Range{Start: uint16(a), End: uint16(b)}, nil
+}
+
+// ParseOpcodes parses an opcode in hex ("A9", "$A9") or a mnemonic ("LDA"),
+// which stands for all of its opcodes
+func ParseOpcodes(s string) ([]uint8, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if value, err := strconv.ParseUint(strings.TrimPrefix(s, "$"), 16, 8); err == nil && len(strings.TrimPrefix(s, "$")) == 2 {
+		return []uint8{uint8(value)}, nil
+	}
+	var opcodes []uint8
+	for opcode := 0; opcode < 256; opcode++ {
+		if cpu.Mnemonic(uint8(opcode)) == s {
+			opcodes = append(opcodes, uint8(opcode))
+		}
+	}
+	if len(opcodes) == 0 {
+		return nil, fmt.Errorf("unknown opcode %q", s)
+	}
+	return opcodes, nil
+}
+
+// CPUState is the CPU and PPU state before an instruction runs
+type CPUState struct {
+	PC       uint16
+	A        uint8
+	X        uint8
+	Y        uint8
+	P        uint8
+	SP       uint8
+	Cycles   uint64
+	Scanline int
+	Dot      int
+}
+
+// Options selects what is traced. Empty filters let everything through.
+type Options struct {
+	Instructions bool // Log executed instructions
+	Memory       bool // Log the memory reads and writes of logged instructions
+
+	Ranges       []Range // Only instructions at these addresses
+	AccessRanges []Range // Only accesses to these addresses
+	Opcodes      []uint8 // Only these opcodes
+	Banks        []int   // Only instructions in these 16KB PRG ROM banks
+
+	// Keep the last RingSize entries in memory for Dump instead of writing
+	// each one as it happens (0 streams to the writer)
+	RingSize int
+}
+
+// BankFunc returns the 16KB PRG ROM bank a CPU address maps to
+type BankFunc func(address uint16) (int, bool)
+
+// entry is a traced instruction or memory access
+type entry struct {
+	access  bool
+	write   bool
+	address uint16
+	value   uint8
+
+	state  CPUState
+	bytes  [3]uint8
+	length uint8
+	bank   int // -1 if unknown
+}
+
+// Tracer logs instructions and memory accesses. Its Instruction and Access
+// methods are called from the CPU fetch hook and the memory access hook.
+type Tracer struct {
+	options    Options
+	opcodes    [256]bool
+	anyOpcode  bool
+	banks      map[int]bool
+	bankOf     BankFunc
+	out        *bufio.Writer // nil in ring mode
+	ring       []entry
+	next       int  // Ring index written next
+	full       bool // The ring wrapped
+	line       []byte
+	active     bool   // The current instruction passed the filters
+	fetchPC    uint16 // Address of the current instruction
+	fetchBytes uint16 // Length of the current instruction
+	err        error
+}
+
+// New creates a tracer writing to w (unused in ring mode). bankOf maps
+// addresses to PRG banks for the bank filter and the log; it may be nil.
+func New(w io.Writer, options Options, bankOf BankFunc) *Tracer {
+	t := &Tracer{
+		options:   options,
+		anyOpcode: len(options.Opcodes) == 0,
+		bankOf:    bankOf,
+		line:      make([]byte, 0, 128),
+	}
+	for _, opcode := range options.Opcodes {
+		t.opcodes[opcode] = true
+	}
+	if len(options.Banks) > 0 {
+		t.banks = make(map[int]bool)
+		for _, bank := range options.Banks {
+			t.banks[bank] = true
+		}
+	}
+	if options.RingSize > 0 {
+		t.ring = make([]entry, options.RingSize)
+	} else {
+		t.out = bufio.NewWriterSize(w, 64*1024)
+	}
+	return t
+}
+
+// IsRing returns whether the tracer keeps a ring buffer instead of writing
+func (t *Tracer) IsRing() bool {
+	return t.ring != nil
+}
+
+// Instruction traces the instruction about to run at state.PC. read reads
+// its bytes without side effects.
+func (t *Tracer) Instruction(state CPUState, read func(address uint16) uint8) {
+	opcode := read(state.PC)
+	length := cpu.InstructionLength(opcode)
+	t.fetchPC, t.fetchBytes = state.PC, uint16(length)
+
+	bank := -1
+	if t.bankOf != nil {
+		if b, ok := t.bankOf(state.PC); ok {
+			bank = b
+		}
+	}
+	t.active = t.anyOpcode || t.opcodes[opcode]
+	if t.active && len(t.options.Ranges) > 0 {
+		t.active = inRanges(t.options.Ranges, state.PC)
+	}
+	if t.active && t.banks != nil {
+		t.active = t.banks[bank]
+	}
+	if !t.active || !t.options.Instructions {
+		return
+	}
+
+	e := entry{state: state, length: uint8(length), bank: bank}
+	e.bytes[0] = opcode
+	for i := 1; i < length; i++ {
+		e.bytes[i] = read(state.PC + uint16(i))
+	}
+	t.add(&e)
+}
+
+// Access traces a memory read or write of the current instruction. Reads of
+// the instruction's own bytes are left out.
+func (t *Tracer) Access(address uint16, value uint8, write bool) {
+	if !t.active || !t.options.Memory {
+		return
+	}
+	if !write && address-t.fetchPC < t.fetchBytes {
+		return
+	}
+	if len(t.options.AccessRanges) > 0 && !inRanges(t.options.AccessRanges, address) {
+		return
+	}
+	t.add(&entry{access: true, write: write, address: address, value: value})
+}
+
+// inRanges returns whether address is in any of ranges
+func inRanges(ranges []Range, address uint16) bool {
+	for _, r := range ranges {
+		if r.Contains(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// add writes an entry, or stores it in the ring
+func (t *Tracer) add(e *entry) {
+	if t.ring != nil {
+		t.ring[t.next] = *e
+		t.next++
+		if t.next == len(t.ring) {
+			t.next = 0
+			t.full = true
+		}
+		return
+	}
+	if t.err != nil {
+		return
+	}
+	t.line = e.format(t.line[:0])
+	if _, err := t.out.Write(t.line); err != nil {
+		t.err = fmt.Errorf("failed to write trace: %v", err)
+	}
+}
+
+// Len returns the number of entries in the ring
+func (t *Tracer) Len() int {
+	if t.full {
+		return len(t.ring)
+	}
+	return t.next
+}
+
+// Dump writes the ring's entries, oldest first
+func (t *Tracer) Dump(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	line := make([]byte, 0, 128)
+	start := 0
+	if t.full {
+		start = t.next
+	}
+	for i := 0; i < t.Len(); i++ {
+		line = t.ring[(start+i)%len(t.ring)].format(line[:0])
+		if _, err := out.Write(line); err != nil {
+			return fmt.Errorf("failed to write trace: %v", err)
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write trace: %v", err)
+	}
+	return nil
+}
+
+// Flush writes buffered lines out and returns the first write error
+func (t *Tracer) Flush() error {
+	if t.err != nil {
+		return t.err
+	}
+	if t.out != nil {
+		if err := t.out.Flush(); err != nil {
+			t.err = fmt.Errorf("failed to write trace: %v", err)
+		}
+	}
+	return t.err
+}
+
+// hexDigits formats the log without fmt, which is too slow per instruction
+const hexDigits = "0123456789ABCDEF"
+
+// appendHex appends value as digits hex digits
+func appendHex(b []byte, value uint64, digits int) []byte {
+	for shift := (digits - 1) * 4; shift >= 0; shift -= 4 {
+		b = append(b, hexDigits[(value>>uint(shift))&0xF])
+	}
+	return b
+}
+
+// format appends the log line of the entry:
+//
+//	01:C000  4C F5 C5  JMP $C5F5      A:00 X:00 Y:00 P:24 SP:FD PPU: 21,  0 CYC:7
+//	           READ  $0200 = $12
+func (e *entry) format(b []byte) []byte {
+	if e.access {
+		b = append(b, "           "...)
+		if e.write {
+			b = append(b, "WRITE $"...)
+		} else {
+			b = append(b, "READ  $"...)
+		}
+		b = appendHex(b, uint64(e.address), 4)
+		b = append(b, " = $"...)
+		b = appendHex(b, uint64(e.value), 2)
+		return append(b, '\n')
+	}
+
+	s := &e.state
+	if e.bank >= 0 {
+		b = appendHex(b, uint64(e.bank), 2)
+	} else {
+		b = append(b, "--"...)
+	}
+	b = append(b, ':')
+	b = appendHex(b, uint64(s.PC), 4)
+	b = append(b, "  "...)
+	for i := 0; i < 3; i++ {
+		if i < int(e.length) {
+			b = appendHex(b, uint64(e.bytes[i]), 2)
+			b = append(b, ' ')
+		} else {
+			b = append(b, "   "...)
+		}
+	}
+
+	text, _ := cpu.Disassemble(func(address uint16) uint8 {
+		if offset := address - s.PC; offset < 3 {
+			return e.bytes[offset]
+		}
+		return 0
+	}, s.PC)
+	b = append(b, ' ')
+	b = append(b, text...)
+	for pad := 15 - len(text); pad > 0; pad-- {
+		b = append(b, ' ')
+	}
+
+	b = append(b, " A:"...)
+	b = appendHex(b, uint64(s.A), 2)
+	b = append(b, " X:"...)
+	b = appendHex(b, uint64(s.X), 2)
+	b = append(b, " Y:"...)
+	b = appendHex(b, uint64(s.Y), 2)
+	b = append(b, " P:"...)
+	b = appendHex(b, uint64(s.P), 2)
+	b = append(b, " SP:"...)
+	b = appendHex(b, uint64(s.SP), 2)
+	b = append(b, " PPU:"...)
+	b = appendPadded(b, s.Scanline, 3)
+	b = append(b, ',')
+	b = appendPadded(b, s.Dot, 3)
+	b = append(b, " CYC:"...)
+	b = strconv.AppendUint(b, s.Cycles, 10)
+	return append(b, '\n')
+}
+
+// appendPadded appends n right aligned in width characters
+func appendPadded(b []byte, n, width int) []byte {
+	start := len(b)
+	b = strconv.AppendInt(b, int64(n), 10)
+	for pad := width - (len(b) - start); pad > 0; pad-- {
+		b = append(b[:start+1], b[start:]...)
+		b[start] = ' '
+	}
+	return b
+}
@@ -0,0 +1,135 @@
+package trace
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// program is LDA $0200 at $8000, STA $2005 at $8003 and JMP $8000 at $8006
+var program = map[uint16]uint8{
+	0x8000: 0xAD, 0x8001: 0x00, 0x8002: 0x02,
+	0x8003: 0x8D, 0x8004: 0x05, 0x8005: 0x20,
+	0x8006: 0x4C, 0x8007: 0x00, 0x8008: 0x80,
+}
+
+func read(address uint16) uint8 {
+	return program[address]
+}
+
+// run traces one pass of the program with its memory accesses
+func run(t *Tracer) {
+	t.Instruction(CPUState{PC: 0x8000, P: 0x24, SP: 0xFD, Cycles: 7, Scanline: 0, Dot: 21}, read)
+	t.Access(0x8000, 0xAD, false) // Own fetch, left out
+	t.Access(0x0200, 0x12, false)
+	t.Instruction(CPUState{PC: 0x8003, A: 0x12, P: 0x24, SP: 0xFD, Cycles: 11}, read)
+	t.Access(0x2005, 0x12, true)
+	t.Instruction(CPUState{PC: 0x8006, A: 0x12, P: 0x24, SP: 0xFD, Cycles: 15}, read)
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Range
+	}{
+		{"8000-80FF", Range{0x8000, 0x80FF}},
+		{"$C000", Range{0xC000, 0xC000}},
+		{" $6000 - $7FFF ", Range{0x6000, 0x7FFF}},
+	}
+	for _, test := range tests {
+		if got, err := ParseRange(test.s); err != nil || got != test.want {
+			t.Errorf("ParseRange(%q) = %v, %v, want %v", test.s, got, err, test.want)
+		}
+	}
+	for _, s := range []string{"", "XYZ", "9000-8000", "10000"} {
+		if _, err := ParseRange(s); err == nil {
+			t.Errorf("ParseRange(%q) should fail", s)
+		}
+	}
+}
+
+func TestParseOpcodes(t *testing.T) {
+	if got, err := ParseOpcodes("$a9"); err != nil || len(got) != 1 || got[0] != 0xA9 {
+		t.Errorf("ParseOpcodes($a9) = %X, %v", got, err)
+	}
+	if got, err := ParseOpcodes("jmp"); err != nil || len(got) != 2 {
+		t.Errorf("ParseOpcodes(jmp) = %X, %v, want the two JMP opcodes", got, err)
+	}
+	if _, err := ParseOpcodes("XYZ"); err == nil {
+		t.Error("ParseOpcodes(XYZ) should fail")
+	}
+}
+
+func TestTracerStreams(t *testing.T) {
+	var out bytes.Buffer
+	tracer := New(&out, Options{Instructions: true, Memory: true}, func(address uint16) (int, bool) {
+		return int(address-0x8000) / 0x4000, address >= 0x8000
+	})
+	run(tracer)
+	if err := tracer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	want := []string{
+		"00:8000  AD 00 02  LDA $0200       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7",
+		"           READ  $0200 = $12",
+		"00:8003  8D 05 20  STA $2005       A:12 X:00 Y:00 P:24 SP:FD PPU:  0,  0 CYC:11",
+		"           WRITE $2005 = $12",
+		"00:8006  4C 00 80  JMP $8000       A:12 X:00 Y:00 P:24 SP:FD PPU:  0,  0 CYC:15",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines:\n%s", len(lines), out.String())
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d:\n got %q\nwant %q", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestTracerFilters(t *testing.T) {
+	var out bytes.Buffer
+	tracer := New(&out, Options{Instructions: true, Memory: true, Opcodes: []uint8{0x8D}}, nil)
+	run(tracer)
+	tracer.Flush()
+	if got := out.String(); !strings.Contains(got, "--:8003") || strings.Contains(got, "8000 ") || strings.Count(got, "\n") != 2 {
+		t.Errorf("opcode filter let through:\n%s", got)
+	}
+
+	out.Reset()
+	tracer = New(&out, Options{Instructions: true, Memory: true, Ranges: []Range{{0x8000, 0x8002}}, AccessRanges: []Range{{0x2000, 0x3FFF}}}, nil)
+	run(tracer)
+	tracer.Flush()
+	if got := out.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, "--:8000") {
+		t.Errorf("range filters let through:\n%s", got)
+	}
+
+	out.Reset()
+	tracer = New(&out, Options{Instructions: true, Banks: []int{1}}, func(uint16) (int, bool) { return 0, true })
+	run(tracer)
+	tracer.Flush()
+	if out.Len() != 0 {
+		t.Errorf("bank filter let through:\n%s", out.String())
+	}
+}
+
+func TestTracerRing(t *testing.T) {
+	tracer := New(nil, Options{Instructions: true, RingSize: 2}, nil)
+	if !tracer.IsRing() {
+		t.Fatal("tracer should be in ring mode")
+	}
+	run(tracer)
+	if tracer.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", tracer.Len())
+	}
+
+	var out bytes.Buffer
+	if err := tracer.Dump(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "--:8003") || !strings.HasPrefix(lines[1], "--:8006") {
+		t.Errorf("ring kept:\n%s", out.String())
+	}
+}
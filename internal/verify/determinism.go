@@ -0,0 +1,153 @@
+// Package verify provides the determinism self-check: running a ROM twice
+// with the same input and comparing the machine state after every frame,
+// which movies and netplay depend on.
+package verify
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gones/internal/bus"
+	"gones/internal/cartridge"
+	"gones/internal/input"
+	"gones/internal/savestate"
+)
+
+// Determinism check modes
+const (
+	// DeterminismSequential runs the ROM once, then again
+	DeterminismSequential = "sequential"
+	// DeterminismParallel runs both at once on separate goroutines, which
+	// also catches state shared between emulator instances
+	DeterminismParallel = "parallel"
+)
+
+// stateComponents are the parts of the machine hashed separately, so a
+// divergence says where the runs differ
+var stateComponents = []struct {
+	name string
+	save func(m *machine, w *savestate.Writer)
+}{
+	{"timing", func(m *machine, w *savestate.Writer) {
+		w.WriteU64(m.bus.GetCycleCount())
+		w.WriteU64(m.bus.GetFrameCount())
+	}},
+	{"cpu", func(m *machine, w *savestate.Writer) { m.bus.CPU.SaveState(w) }},
+	{"ppu", func(m *machine, w *savestate.Writer) { m.bus.PPU.SaveState(w) }},
+	{"apu", func(m *machine, w *savestate.Writer) { m.bus.APU.SaveState(w) }},
+	{"memory", func(m *machine, w *savestate.Writer) { m.bus.Memory.SaveState(w) }},
+	{"input", func(m *machine, w *savestate.Writer) { m.bus.Input.SaveState(w) }},
+	{"cartridge", func(m *machine, w *savestate.Writer) { m.cart.SaveState(w) }},
+}
+
+// stateHash is the hash of each state component after a frame
+type stateHash [][sha256.Size]byte
+
+// Divergence is the first frame after which two runs differ
+type Divergence struct {
+	Frame      int      // Frames count from 1
+	Components []string // Parts of the machine whose state differs
+}
+
+func (d *Divergence) String() string {
+	return fmt.Sprintf("runs diverge after frame %d (%s differ)", d.Frame, strings.Join(d.Components, ", "))
+}
+
+// CheckDeterminism runs rom twice for frames frames, applying the script's
+// controller states (if not nil) before each frame, and compares the state
+// after every frame. It returns the first divergence, or nil if the runs
+// match throughout.
+func CheckDeterminism(rom string, script *input.Script, frames int, mode string) (*Divergence, error) {
+	var runs [2][]stateHash
+	var errs [2]error
+	switch mode {
+	case DeterminismSequential, "":
+		for i := range runs {
+			runs[i], errs[i] = hashRun(rom, script, frames)
+		}
+	case DeterminismParallel:
+		var wg sync.WaitGroup
+		for i := range runs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				runs[i], errs[i] = hashRun(rom, script, frames)
+			}(i)
+		}
+		wg.Wait()
+	default:
+		return nil, fmt.Errorf("unknown determinism mode %q (sequential or parallel)", mode)
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return compareRuns(runs[0], runs[1]), nil
+}
+
+// compareRuns returns the first frame whose state hashes differ, or nil
+func compareRuns(a, b []stateHash) *Divergence {
+	for frame := range a {
+		var differ []string
+		for i, component := range stateComponents {
+			if a[frame][i] != b[frame][i] {
+				differ = append(differ, component.name)
+			}
+		}
+		if len(differ) > 0 {
+			return &Divergence{Frame: frame + 1, Components: differ}
+		}
+	}
+	return nil
+}
+
+// hashRun runs rom and returns the state hash after each frame
+func hashRun(rom string, script *input.Script, frames int) (hashes []stateHash, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("emulator panic after %d frames: %v", len(hashes), r)
+		}
+	}()
+
+	cart, err := cartridge.LoadFromFile(rom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ROM: %v", err)
+	}
+	m := newMachine(cart)
+	if script != nil {
+		// A movie can start from a savestate instead of power on
+		if _, state := script.Savestate(); state != nil {
+			if err := m.bus.LoadStateFromBytes(state); err != nil {
+				return nil, fmt.Errorf("failed to load the script's savestate: %v", err)
+			}
+		}
+	}
+	hashes = make([]stateHash, 0, frames)
+	w := savestate.NewWriter(64 * 1024)
+	for frame := 0; frame < frames; frame++ {
+		if script != nil {
+			applyScript(m.bus, script, frame)
+		}
+		m.frame()
+
+		hash := make(stateHash, len(stateComponents))
+		for i, component := range stateComponents {
+			w.Reset()
+			component.save(m, w)
+			hash[i] = sha256.Sum256(w.Bytes())
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// applyScript sets the controllers to the script's states for frame
+func applyScript(b *bus.Bus, script *input.Script, frame int) {
+	for player, buttons := range script.Buttons(frame) {
+		b.SetControllerButtons(player+1, buttons)
+	}
+}
@@ -0,0 +1,88 @@
+// Package verify provides golden frame hashes: the screen hashes of chosen
+// frames of a headless run, stored to catch rendering regressions.
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Golden holds the expected screen hash of frames of a ROM's headless run.
+// Frames count from 1, the first frame run.
+//
+//	{"rom": "game.nes", "frames": {"31": "9f86d0...", "120": "60303a..."}}
+type Golden struct {
+	ROM    string         `json:"rom,omitempty"` // ROM file name, for reference
+	Frames map[int]string `json:"frames"`
+}
+
+// Mismatch is a frame whose hash differs from its golden hash. Got is ""
+// when the frame was not hashed.
+type Mismatch struct {
+	Frame int
+	Want  string
+	Got   string
+}
+
+func (m Mismatch) String() string {
+	if m.Got == "" {
+		return fmt.Sprintf("frame %d: not reached", m.Frame)
+	}
+	return fmt.Sprintf("frame %d: hash %s, golden %s", m.Frame, m.Got, m.Want)
+}
+
+// LoadGolden reads a golden file
+func LoadGolden(path string) (*Golden, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden file: %v", err)
+	}
+	var golden Golden
+	if err := json.Unmarshal(data, &golden); err != nil {
+		return nil, fmt.Errorf("failed to parse golden file %s: %v", path, err)
+	}
+	for frame := range golden.Frames {
+		if frame < 1 {
+			return nil, fmt.Errorf("golden file %s: frame %d (frames count from 1)", path, frame)
+		}
+	}
+	return &golden, nil
+}
+
+// Save writes the golden file
+func (g *Golden) Save(path string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode golden file: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write golden file: %v", err)
+	}
+	return nil
+}
+
+// FrameNumbers returns the frames with golden hashes, in order
+func (g *Golden) FrameNumbers() []int {
+	frames := make([]int, 0, len(g.Frames))
+	for frame := range g.Frames {
+		frames = append(frames, frame)
+	}
+	sort.Ints(frames)
+	return frames
+}
+
+// Compare checks frame hashes against the golden ones and returns the
+// frames that differ, in order
+func (g *Golden) Compare(hashes map[int]string) []Mismatch {
+	var mismatches []Mismatch
+	for _, frame := range g.FrameNumbers() {
+		want, got := g.Frames[frame], hashes[frame]
+		if !strings.EqualFold(want, got) {
+			mismatches = append(mismatches, Mismatch{Frame: frame, Want: want, Got: got})
+		}
+	}
+	return mismatches
+}
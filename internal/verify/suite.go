@@ -0,0 +1,189 @@
+// Package verify provides test suites, read from ROM files, directories of
+// ROMs and JSON manifests, and the summary table of their results.
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"gones/internal/cartridge"
+)
+
+// Manifest lists the tests of a suite. Relative ROM paths are relative to
+// the manifest.
+//
+//	{"tests": [
+//	  {"rom": "cpu_instrs/01-basics.nes"},
+//	  {"rom": "nestest.nes"},
+//	  {"name": "title screen", "rom": "game.nes", "method": "hash", "frames": 120, "hash": "9f86d0..."}
+//	]}
+type Manifest struct {
+	Tests []Test `json:"tests"`
+}
+
+// LoadManifest reads the tests of a JSON manifest
+func LoadManifest(path string) ([]Test, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %v", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %v", path, err)
+	}
+	dir := filepath.Dir(path)
+	for i, test := range manifest.Tests {
+		if test.ROM == "" {
+			return nil, fmt.Errorf("manifest %s: test %d has no rom", path, i+1)
+		}
+		if test.Name == "" {
+			manifest.Tests[i].Name = test.ROM
+		}
+		if !filepath.IsAbs(test.ROM) {
+			manifest.Tests[i].ROM = filepath.Join(dir, test.ROM)
+		}
+	}
+	return manifest.Tests, nil
+}
+
+// Discover returns the tests of paths: a .json manifest, a ROM file, or a
+// directory whose .nes and .zip files are all tests, named by their path within it
+func Discover(paths []string) ([]Test, error) {
+	var tests []Test
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case info.IsDir():
+			found, err := discoverDir(path)
+			if err != nil {
+				return nil, err
+			}
+			tests = append(tests, found...)
+		case strings.EqualFold(filepath.Ext(path), ".json"):
+			found, err := LoadManifest(path)
+			if err != nil {
+				return nil, err
+			}
+			tests = append(tests, found...)
+		default:
+			tests = append(tests, Test{Name: filepath.Base(path), ROM: path})
+		}
+	}
+	return tests, nil
+}
+
+// discoverDir returns a test for each .nes or .zip file under dir, in path order
+func discoverDir(dir string) ([]Test, error) {
+	var tests []Test
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !cartridge.IsROMFile(path) {
+			return nil
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			name = path
+		}
+		tests = append(tests, Test{Name: filepath.ToSlash(name), ROM: path})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %v", dir, err)
+	}
+	return tests, nil
+}
+
+// maxDetail is the longest message shown in the summary table
+const maxDetail = 72
+
+// WriteSummary writes a table of results followed by the totals, and
+// reports whether every test passed
+func WriteSummary(w io.Writer, results []Result) bool {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TEST\tRESULT\tMETHOD\tFRAMES\tTIME\tDETAIL")
+	counts := make(map[Status]int)
+	for _, r := range results {
+		counts[r.Status]++
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.2fs\t%s\n",
+			r.Test.Name, r.Status, r.Method, r.Frames, r.Duration.Seconds(), detail(r))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d passed, %d failed, %d timed out, %d errors (%d tests)\n",
+		counts[Pass], counts[Fail], counts[Timeout], counts[Error], len(results))
+	return counts[Pass] == len(results)
+}
+
+// jsonResult is a result in the JSON report
+type jsonResult struct {
+	Name    string  `json:"name"`
+	ROM     string  `json:"rom"`
+	Method  string  `json:"method"`
+	Status  Status  `json:"status"`
+	Code    int     `json:"code"` // -1 when none
+	Message string  `json:"message,omitempty"`
+	Frames  int     `json:"frames"`
+	Hash    string  `json:"hash,omitempty"`
+	Seconds float64 `json:"seconds"`
+}
+
+// jsonReport is the JSON form of a summary
+type jsonReport struct {
+	Passed  bool         `json:"passed"` // Every test passed
+	Total   int          `json:"total"`
+	Pass    int          `json:"pass"`
+	Fail    int          `json:"fail"`
+	Timeout int          `json:"timeout"`
+	Error   int          `json:"error"`
+	Results []jsonResult `json:"results"`
+}
+
+// WriteJSON writes the results and their totals as a JSON object, for CI
+// pipelines and dashboards, and reports whether every test passed
+func WriteJSON(w io.Writer, results []Result) (bool, error) {
+	report := jsonReport{Total: len(results), Results: make([]jsonResult, len(results))}
+	counts := make(map[Status]int)
+	for i, r := range results {
+		counts[r.Status]++
+		report.Results[i] = jsonResult{
+			Name:    r.Test.Name,
+			ROM:     r.Test.ROM,
+			Method:  r.Method,
+			Status:  r.Status,
+			Code:    r.Code,
+			Message: strings.TrimSpace(r.Message),
+			Frames:  r.Frames,
+			Hash:    r.Hash,
+			Seconds: r.Duration.Seconds(),
+		}
+	}
+	report.Pass, report.Fail, report.Timeout, report.Error = counts[Pass], counts[Fail], counts[Timeout], counts[Error]
+	report.Passed = counts[Pass] == len(results)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return report.Passed, encoder.Encode(report)
+}
+
+// detail returns a result's message on one line, shortened for the table
+func detail(r Result) string {
+	message := strings.Join(strings.Fields(r.Message), " ")
+	if r.Status == Fail && r.Method == MethodStatus {
+		message = fmt.Sprintf("code %d: %s", r.Code, message)
+	}
+	if len(message) > maxDetail {
+		message = message[:maxDetail-3] + "..."
+	}
+	return message
+}
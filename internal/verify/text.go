@@ -0,0 +1,33 @@
+// Package verify provides the text test ROMs print to the screen, read back
+// from the nametables.
+package verify
+
+import (
+	"strings"
+
+	"gones/internal/memory"
+)
+
+// NametableText returns the text in the four nametables ($2000, $2400,
+// $2800, $2C00), one line per row of 32 tiles. Tile numbers are read as
+// ASCII, as test ROMs print their results; other tiles read as spaces and
+// trailing spaces are trimmed.
+func NametableText(vram *memory.PPUMemory) string {
+	var text strings.Builder
+	line := make([]byte, 32)
+	for nametable := uint16(0); nametable < 4; nametable++ {
+		base := 0x2000 + nametable*0x400
+		for row := uint16(0); row < 30; row++ {
+			for column := uint16(0); column < 32; column++ {
+				tile := vram.Peek(base + row*32 + column)
+				if tile < 0x20 || tile > 0x7E {
+					tile = ' '
+				}
+				line[column] = tile
+			}
+			text.WriteString(strings.TrimRight(string(line), " "))
+			text.WriteByte('\n')
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,325 @@
+// Package verify runs accuracy test ROMs, such as blargg's test suites and
+// nestest, and decides whether they pass: from the result a ROM reports at
+// $6000, from nestest's error bytes or from a hash of the screen.
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gones/internal/bus"
+	"gones/internal/cartridge"
+)
+
+// Methods of telling whether a test passed
+const (
+	// MethodStatus reads the $6000 status protocol of blargg's test ROMs:
+	// $6001-$6003 hold DE B0 61 once the ROM reports, $6000 is $80 while
+	// running, $81 when the ROM wants the reset button pressed, and the
+	// result code otherwise (0 passed). $6004 holds a text message.
+	MethodStatus = "status"
+	// MethodHash runs a fixed number of frames and compares a SHA-256 hash
+	// of the screen against the expected hash
+	MethodHash = "hash"
+	// MethodNestest runs nestest in automation mode from $C000 and checks
+	// the error codes it leaves at $02 and $03
+	MethodNestest = "nestest"
+)
+
+// DefaultFrames is how many frames a test may run before it times out, and
+// the frame a hash test hashes when it gives none
+const DefaultFrames = 3600
+
+// resetDelay is how many frames to wait before pressing reset for a ROM that
+// asks for it; blargg's ROMs want at least 100 ms
+const resetDelay = 10
+
+// nestest automation mode
+const (
+	nestestStart           = 0xC000
+	nestestEnd             = 0xC66E // The final RTS
+	nestestMaxInstructions = 30000
+)
+
+// statusSignature marks a valid status at $6001-$6003
+var statusSignature = [3]uint8{0xDE, 0xB0, 0x61}
+
+// Test is one test ROM and how to check it
+type Test struct {
+	Name   string `json:"name,omitempty"`   // Shown in the summary, the ROM path by default
+	ROM    string `json:"rom"`              // Path of the ROM
+	Method string `json:"method,omitempty"` // status, hash or nestest; guessed when empty
+	Frames int    `json:"frames,omitempty"` // Frame limit, or the frame to hash (default DefaultFrames)
+	Hash   string `json:"hash,omitempty"`   // Expected screen hash (hex SHA-256)
+}
+
+// method returns the test's method, guessing it when not given: a test with
+// an expected hash is a hash test, nestest.nes is nestest, and anything else
+// is expected to report at $6000
+func (t Test) method() string {
+	switch {
+	case t.Method != "":
+		return t.Method
+	case t.Hash != "":
+		return MethodHash
+	case strings.Contains(strings.ToLower(filepath.Base(t.ROM)), "nestest"):
+		return MethodNestest
+	default:
+		return MethodStatus
+	}
+}
+
+// frames returns the test's frame limit
+func (t Test) frames() int {
+	if t.Frames > 0 {
+		return t.Frames
+	}
+	return DefaultFrames
+}
+
+// Status is the outcome of a test
+type Status string
+
+// Test outcomes
+const (
+	Pass    Status = "PASS"
+	Fail    Status = "FAIL"
+	Timeout Status = "TIMEOUT"
+	Error   Status = "ERROR" // The test could not run, or has nothing to check against
+)
+
+// Result is the outcome of running a test
+type Result struct {
+	Test     Test
+	Method   string
+	Status   Status
+	Code     int    // The $6000 result code or nestest error byte, -1 when none
+	Message  string // The ROM's message, or why the test did not pass
+	Frames   int    // Frames run
+	Hash     string // Hash of the final screen
+	Duration time.Duration
+}
+
+// Passed reports whether the test passed
+func (r Result) Passed() bool {
+	return r.Status == Pass
+}
+
+// Run runs a test ROM and checks its result
+func Run(test Test) (result Result) {
+	result = Result{Test: test, Method: test.method(), Code: -1}
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			result.Status = Error
+			result.Message = fmt.Sprintf("emulator panic: %v", r)
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	cart, err := cartridge.LoadFromFile(test.ROM)
+	if err != nil {
+		result.Status = Error
+		result.Message = fmt.Sprintf("failed to load ROM: %v", err)
+		return result
+	}
+	m := newMachine(cart)
+
+	switch result.Method {
+	case MethodStatus:
+		m.runStatus(test, &result)
+	case MethodHash:
+		m.runHash(test, &result)
+	case MethodNestest:
+		m.runNestest(&result)
+	default:
+		result.Status = Error
+		result.Message = fmt.Sprintf("unknown method %q (status, hash or nestest)", result.Method)
+	}
+	result.Frames = m.frames
+	result.Hash = ScreenHash(m.bus.GetFrameBuffer())
+	return result
+}
+
+// RunAll runs tests in order, calling done, if not nil, after each one
+func RunAll(tests []Test, done func(Result)) []Result {
+	results := make([]Result, 0, len(tests))
+	for _, test := range tests {
+		result := Run(test)
+		results = append(results, result)
+		if done != nil {
+			done(result)
+		}
+	}
+	return results
+}
+
+// ScreenHash returns the hex SHA-256 hash of a frame's RGB pixels
+func ScreenHash(frame []uint32) string {
+	pixels := make([]byte, 0, len(frame)*3)
+	for _, pixel := range frame {
+		pixels = append(pixels, byte(pixel>>16), byte(pixel>>8), byte(pixel))
+	}
+	sum := sha256.Sum256(pixels)
+	return hex.EncodeToString(sum[:])
+}
+
+// machine is a bare NES running a test ROM. It counts frames itself, as a
+// reset restarts the bus frame counter.
+type machine struct {
+	bus    *bus.Bus
+	cart   *cartridge.Cartridge
+	frames int
+}
+
+func newMachine(cart *cartridge.Cartridge) *machine {
+	b := bus.New()
+	b.LoadCartridge(cart)
+	b.Reset()
+	return &machine{bus: b, cart: cart}
+}
+
+// frame runs a frame, returning false if the CPU jammed
+func (m *machine) frame() bool {
+	m.bus.Run(1)
+	m.frames++
+	return !m.bus.CPU.Jammed()
+}
+
+func (m *machine) peek(address uint16) uint8 {
+	value, _ := m.bus.Memory.Peek(address)
+	return value
+}
+
+// jammed fills in the result for a CPU locked up by a JAM opcode
+func (m *machine) jammed(result *Result) {
+	pc := m.bus.CPU.PC
+	result.Status = Error
+	result.Message = fmt.Sprintf("CPU jammed by opcode $%02X at $%04X", m.peek(pc), pc)
+}
+
+// runStatus runs a ROM until it reports a result at $6000
+func (m *machine) runStatus(test Test, result *Result) {
+	limit := test.frames()
+	resetAt := -1
+	last := -1
+	for m.frames < limit {
+		if !m.frame() {
+			m.jammed(result)
+			return
+		}
+		if m.frames == resetAt {
+			m.bus.Reset()
+			resetAt = -1
+		}
+		if [3]uint8{m.peek(0x6001), m.peek(0x6002), m.peek(0x6003)} != statusSignature {
+			continue
+		}
+		status := int(m.peek(0x6000))
+		switch status {
+		case 0x80:
+			// Running
+		case 0x81:
+			if last != 0x81 {
+				resetAt = m.frames + resetDelay
+			}
+		default:
+			result.Code = status
+			result.Message = m.statusMessage()
+			if status == 0 {
+				result.Status = Pass
+			} else {
+				result.Status = Fail
+			}
+			return
+		}
+		last = status
+	}
+
+	result.Status = Timeout
+	if last == -1 {
+		result.Message = fmt.Sprintf("no result at $6000 after %d frames", limit)
+	} else {
+		result.Message = fmt.Sprintf("still running after %d frames", limit)
+		if message := m.statusMessage(); message != "" {
+			result.Message += ": " + message
+		}
+	}
+}
+
+// statusMessage returns the text at $6004
+func (m *machine) statusMessage() string {
+	var b strings.Builder
+	for address := uint16(0x6004); address < 0x8000; address++ {
+		c := m.peek(address)
+		if c == 0 {
+			break
+		}
+		b.WriteByte(c)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// runHash runs a ROM for the test's frames and compares the screen hash
+func (m *machine) runHash(test Test, result *Result) {
+	for m.frames < test.frames() {
+		if !m.frame() {
+			m.jammed(result)
+			return
+		}
+	}
+	hash := ScreenHash(m.bus.GetFrameBuffer())
+	switch {
+	case test.Hash == "":
+		result.Status = Error
+		result.Message = "no expected hash; the screen hashes to " + hash
+	case strings.EqualFold(test.Hash, hash):
+		result.Status = Pass
+	default:
+		result.Status = Fail
+		result.Message = "screen hashes to " + hash
+	}
+}
+
+// runNestest runs nestest's automated tests, which start at $C000 and end by
+// returning from $C66E with the first failure's code in $02 (official
+// opcodes) or $03 (unofficial opcodes)
+func (m *machine) runNestest(result *Result) {
+	cpu := m.bus.CPU
+	cpu.PC = nestestStart
+	frame := m.bus.GetFrameCount()
+	for i := 0; i < nestestMaxInstructions; i++ {
+		if cpu.PC == nestestEnd {
+			official, unofficial := m.peek(0x02), m.peek(0x03)
+			switch {
+			case official != 0:
+				result.Status = Fail
+				result.Code = int(official)
+				result.Message = fmt.Sprintf("official opcode test failed with error $%02X", official)
+			case unofficial != 0:
+				result.Status = Fail
+				result.Code = int(unofficial)
+				result.Message = fmt.Sprintf("unofficial opcode test failed with error $%02X", unofficial)
+			default:
+				result.Status = Pass
+				result.Code = 0
+			}
+			return
+		}
+		m.bus.Step()
+		if cpu.Jammed() {
+			m.jammed(result)
+			return
+		}
+		if count := m.bus.GetFrameCount(); count != frame {
+			m.frames += int(count - frame)
+			frame = count
+		}
+	}
+	result.Status = Timeout
+	result.Message = fmt.Sprintf("did not reach $%04X in %d instructions", nestestEnd, nestestMaxInstructions)
+}
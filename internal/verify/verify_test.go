@@ -0,0 +1,376 @@
+package verify
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gones/internal/cartridge"
+	"gones/internal/input"
+)
+
+// statusSignatureCode writes DE B0 61 to $6001-$6003
+var statusSignatureCode = []uint8{
+	0xA9, 0xDE, 0x8D, 0x01, 0x60, // LDA #$DE; STA $6001
+	0xA9, 0xB0, 0x8D, 0x02, 0x60, // LDA #$B0; STA $6002
+	0xA9, 0x61, 0x8D, 0x03, 0x60, // LDA #$61; STA $6003
+}
+
+// writeROM builds a ROM running code and writes it to dir/name
+func writeROM(t *testing.T, dir, name string, code []uint8, data map[uint16][]uint8) string {
+	t.Helper()
+	builder := cartridge.NewTestROMBuilder().WithInstructions(code).WithResetVector(0x8000)
+	for address, bytes := range data {
+		builder.WithData(address, bytes)
+	}
+	rom, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// statusROM reports result code with the message "OK"
+func statusROM(t *testing.T, dir, name string, code uint8) string {
+	program := append([]uint8{}, statusSignatureCode...)
+	program = append(program,
+		0xA9, 'O', 0x8D, 0x04, 0x60, // LDA #'O'; STA $6004
+		0xA9, 'K', 0x8D, 0x05, 0x60, // LDA #'K'; STA $6005
+		0xA9, 0x00, 0x8D, 0x06, 0x60, // LDA #0; STA $6006
+		0xA9, code, 0x8D, 0x00, 0x60, // LDA #code; STA $6000
+		0x4C, 0x23, 0x80, // JMP *
+	)
+	return writeROM(t, dir, name, program, nil)
+}
+
+func TestRunStatus(t *testing.T) {
+	dir := t.TempDir()
+
+	result := Run(Test{Name: "pass", ROM: statusROM(t, dir, "pass.nes", 0)})
+	if !result.Passed() || result.Code != 0 || result.Message != "OK" || result.Method != MethodStatus {
+		t.Errorf("passing ROM: %+v", result)
+	}
+
+	result = Run(Test{Name: "fail", ROM: statusROM(t, dir, "fail.nes", 3)})
+	if result.Status != Fail || result.Code != 3 {
+		t.Errorf("failing ROM: %+v", result)
+	}
+
+	result = Run(Test{ROM: writeROM(t, dir, "silent.nes", []uint8{0x4C, 0x00, 0x80}, nil), Frames: 30})
+	if result.Status != Timeout || result.Frames != 30 {
+		t.Errorf("silent ROM: %+v", result)
+	}
+
+	result = Run(Test{ROM: writeROM(t, dir, "jam.nes", []uint8{0x02}, nil)})
+	if result.Status != Error || !strings.Contains(result.Message, "jammed") {
+		t.Errorf("jamming ROM: %+v", result)
+	}
+
+	result = Run(Test{ROM: filepath.Join(dir, "missing.nes")})
+	if result.Status != Error {
+		t.Errorf("missing ROM: %+v", result)
+	}
+}
+
+func TestRunStatusReset(t *testing.T) {
+	// Asks for a reset on the first run, passes after it. PRG RAM survives
+	// the reset, so $6010 tells the runs apart.
+	program := append([]uint8{}, statusSignatureCode...)
+	program = append(program,
+		0xAD, 0x10, 0x60, // $800F: LDA $6010
+		0xD0, 0x0A, // BNE $801E
+		0xEE, 0x10, 0x60, // INC $6010
+		0xA9, 0x81, 0x8D, 0x00, 0x60, // LDA #$81; STA $6000
+		0xD0, 0xFE, // $801C: BNE *
+		0xA9, 0x00, 0x8D, 0x00, 0x60, // $801E: LDA #0; STA $6000
+		0xF0, 0xFE, // BEQ *
+	)
+	result := Run(Test{ROM: writeROM(t, t.TempDir(), "reset.nes", program, nil), Frames: 100})
+	if !result.Passed() || result.Frames <= resetDelay {
+		t.Errorf("reset ROM: %+v", result)
+	}
+}
+
+func TestRunHash(t *testing.T) {
+	rom := writeROM(t, t.TempDir(), "loop.nes", []uint8{0x4C, 0x00, 0x80}, nil)
+
+	result := Run(Test{ROM: rom, Method: MethodHash, Frames: 10})
+	if result.Status != Error || len(result.Hash) != 64 || !strings.Contains(result.Message, result.Hash) {
+		t.Fatalf("hash without expected hash: %+v", result)
+	}
+	hash := result.Hash
+
+	result = Run(Test{ROM: rom, Frames: 10, Hash: strings.ToUpper(hash)})
+	if !result.Passed() || result.Method != MethodHash {
+		t.Errorf("matching hash: %+v", result)
+	}
+	result = Run(Test{ROM: rom, Frames: 10, Hash: strings.Repeat("0", 64)})
+	if result.Status != Fail {
+		t.Errorf("wrong hash: %+v", result)
+	}
+}
+
+func TestNametableText(t *testing.T) {
+	// Print "OK" at row 2, column 3 of the first nametable and "#" in the
+	// last tile of the fourth, once the PPU has warmed up
+	rom := writeROM(t, t.TempDir(), "text.nes", []uint8{
+		0x2C, 0x02, 0x20, // BIT $2002 (clear the vblank flag)
+		0x2C, 0x02, 0x20, 0x10, 0xFB, // BIT $2002; BPL *-3
+		0x2C, 0x02, 0x20, 0x10, 0xFB, // BIT $2002; BPL *-3
+		0xA9, 0x20, 0x8D, 0x06, 0x20, // LDA #$20; STA $2006
+		0xA9, 0x43, 0x8D, 0x06, 0x20, // LDA #$43; STA $2006
+		0xA9, 'O', 0x8D, 0x07, 0x20, // LDA #'O'; STA $2007
+		0xA9, 'K', 0x8D, 0x07, 0x20, // LDA #'K'; STA $2007
+		0xA9, 0x2F, 0x8D, 0x06, 0x20, // LDA #$2F; STA $2006
+		0xA9, 0xBF, 0x8D, 0x06, 0x20, // LDA #$BF; STA $2006
+		0xA9, '#', 0x8D, 0x07, 0x20, // LDA #'#'; STA $2007
+		0x4C, 0x30, 0x80, // JMP *
+	}, nil)
+	cart, err := cartridge.LoadFromFile(rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newMachine(cart)
+	for i := 0; i < 3; i++ {
+		m.frame()
+	}
+
+	lines := strings.Split(NametableText(m.bus.PPU.GetMemory()), "\n")
+	if len(lines) != 4*30+1 {
+		t.Fatalf("got %d lines, want %d", len(lines), 4*30+1)
+	}
+	if lines[2] != "   OK" {
+		t.Errorf("row 2 = %q, want \"   OK\"", lines[2])
+	}
+	// $2FBF is the last tile of the fourth nametable, which horizontal
+	// mirroring shows at $2BBF too
+	if want := strings.Repeat(" ", 31) + "#"; lines[4*30-1] != want {
+		t.Errorf("last row = %q, want %q", lines[4*30-1], want)
+	}
+}
+
+func TestRunNestest(t *testing.T) {
+	dir := t.TempDir()
+	// $C000 stores the error codes and jumps to the end of the tests
+	nestest := func(name string, official, unofficial uint8) string {
+		return writeROM(t, dir, name, []uint8{
+			0xA9, official, 0x85, 0x02, // LDA #official; STA $02
+			0xA9, unofficial, 0x85, 0x03, // LDA #unofficial; STA $03
+			0x4C, 0x6E, 0xC6, // JMP $C66E
+		}, map[uint16][]uint8{0x066E: {0x60}})
+	}
+
+	result := Run(Test{ROM: nestest("nestest.nes", 0, 0)})
+	if !result.Passed() || result.Method != MethodNestest {
+		t.Errorf("passing nestest: %+v", result)
+	}
+	result = Run(Test{ROM: nestest("nestest-fail.nes", 0, 0x05)})
+	if result.Status != Fail || result.Code != 0x05 || !strings.Contains(result.Message, "unofficial") {
+		t.Errorf("failing nestest: %+v", result)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "cpu"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	statusROM(t, filepath.Join(dir, "cpu"), "01-basics.nes", 0)
+	statusROM(t, dir, "ppu.nes", 0)
+	manifest := filepath.Join(dir, "suite.json")
+	err := os.WriteFile(manifest, []byte(`{"tests": [
+		{"rom": "ppu.nes"},
+		{"name": "title", "rom": "cpu/01-basics.nes", "method": "hash", "frames": 5}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests, err := Discover([]string{dir, manifest})
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	var names []string
+	for _, test := range tests {
+		names = append(names, test.Name)
+	}
+	want := "cpu/01-basics.nes ppu.nes ppu.nes title"
+	if strings.Join(names, " ") != want {
+		t.Errorf("names = %v, want %s", names, want)
+	}
+	if tests[3].ROM != filepath.Join(dir, "cpu", "01-basics.nes") || tests[3].method() != MethodHash {
+		t.Errorf("manifest test = %+v", tests[3])
+	}
+
+	if _, err := Discover([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("missing path accepted")
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	results := []Result{
+		{Test: Test{Name: "01-basics.nes"}, Method: MethodStatus, Status: Pass, Message: "Passed"},
+		{Test: Test{Name: "02-implied.nes"}, Method: MethodStatus, Status: Fail, Code: 2, Message: "6A ROR A\n\nFailed"},
+	}
+	var b bytes.Buffer
+	if WriteSummary(&b, results) {
+		t.Error("WriteSummary reported success with a failure")
+	}
+	out := b.String()
+	for _, want := range []string{"TEST", "01-basics.nes", "code 2: 6A ROR A Failed", "1 passed, 1 failed, 0 timed out, 0 errors (2 tests)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+	if !WriteSummary(&b, results[:1]) {
+		t.Error("WriteSummary reported failure with every test passing")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	results := []Result{
+		{Test: Test{Name: "01-basics.nes", ROM: "cpu/01-basics.nes"}, Method: MethodStatus, Status: Pass, Code: 0, Frames: 90, Duration: 500 * time.Millisecond},
+		{Test: Test{Name: "02-implied.nes"}, Method: MethodStatus, Status: Fail, Code: 2, Message: "Failed\n"},
+	}
+	var b bytes.Buffer
+	passed, err := WriteJSON(&b, results)
+	if err != nil || passed {
+		t.Fatalf("WriteJSON = %v, %v; want false, nil", passed, err)
+	}
+	var report struct {
+		Passed  bool `json:"passed"`
+		Total   int  `json:"total"`
+		Fail    int  `json:"fail"`
+		Results []struct {
+			Name    string  `json:"name"`
+			ROM     string  `json:"rom"`
+			Status  string  `json:"status"`
+			Code    int     `json:"code"`
+			Message string  `json:"message"`
+			Frames  int     `json:"frames"`
+			Seconds float64 `json:"seconds"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, b.String())
+	}
+	if report.Passed || report.Total != 2 || report.Fail != 1 || len(report.Results) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	first, second := report.Results[0], report.Results[1]
+	if first.ROM != "cpu/01-basics.nes" || first.Status != "PASS" || first.Frames != 90 || first.Seconds != 0.5 {
+		t.Errorf("first result = %+v", first)
+	}
+	if second.Code != 2 || second.Message != "Failed" {
+		t.Errorf("second result = %+v", second)
+	}
+}
+
+// TestSuites runs the test ROM suites in GONES_TEST_ROMS, a list of ROMs,
+// directories and manifests separated like PATH, such as a checkout of
+// nes-test-roms. It is skipped when unset.
+func TestSuites(t *testing.T) {
+	paths := filepath.SplitList(os.Getenv("GONES_TEST_ROMS"))
+	if len(paths) == 0 {
+		t.Skip("set GONES_TEST_ROMS to run test ROM suites")
+	}
+	tests, err := Discover(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := Run(test)
+			if !result.Passed() {
+				t.Errorf("%s after %d frames: %s", result.Status, result.Frames, detail(result))
+			}
+		})
+	}
+}
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.golden.json")
+	golden := &Golden{ROM: "game.nes", Frames: map[int]string{120: "bb", 31: "AA"}}
+	if err := golden.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := LoadGolden(path)
+	if err != nil {
+		t.Fatalf("LoadGolden: %v", err)
+	}
+	if frames := loaded.FrameNumbers(); len(frames) != 2 || frames[0] != 31 || frames[1] != 120 {
+		t.Errorf("FrameNumbers = %v, want [31 120]", frames)
+	}
+
+	if mismatches := loaded.Compare(map[int]string{31: "aa", 120: "bb", 60: "cc"}); len(mismatches) != 0 {
+		t.Errorf("matching hashes: %v", mismatches)
+	}
+	mismatches := loaded.Compare(map[int]string{31: "ab"})
+	if len(mismatches) != 2 || mismatches[0].Got != "ab" || mismatches[1].String() != "frame 120: not reached" {
+		t.Errorf("mismatches = %v", mismatches)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"frames": {"0": "aa"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGolden(path); err == nil {
+		t.Error("frame 0 accepted")
+	}
+}
+
+func TestCheckDeterminism(t *testing.T) {
+	// Polls controller 1 into $10 and counts in $11
+	rom := writeROM(t, t.TempDir(), "poll.nes", []uint8{
+		0xA9, 0x01, 0x8D, 0x16, 0x40, // LDA #1; STA $4016
+		0xA9, 0x00, 0x8D, 0x16, 0x40, // LDA #0; STA $4016
+		0xAD, 0x16, 0x40, 0x85, 0x10, // LDA $4016; STA $10
+		0xE6, 0x11, // INC $11
+		0x4C, 0x00, 0x80, // JMP $8000
+	}, nil)
+	script, err := input.ParseJSONScript([]byte(`[{"frame": 0, "p1": "A"}, {"frame": 10, "p1": ""}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []string{DeterminismSequential, DeterminismParallel} {
+		divergence, err := CheckDeterminism(rom, script, 20, mode)
+		if err != nil || divergence != nil {
+			t.Errorf("%s: divergence %v, error %v", mode, divergence, err)
+		}
+	}
+	if _, err := CheckDeterminism(rom, nil, 1, "twice"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+	if _, err := CheckDeterminism(filepath.Join(t.TempDir(), "missing.nes"), nil, 1, ""); err == nil {
+		t.Error("missing ROM accepted")
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	run := func(frames int) []stateHash {
+		hashes := make([]stateHash, frames)
+		for i := range hashes {
+			hashes[i] = make(stateHash, len(stateComponents))
+		}
+		return hashes
+	}
+	a, b := run(5), run(5)
+	if divergence := compareRuns(a, b); divergence != nil {
+		t.Errorf("identical runs: %v", divergence)
+	}
+	b[3][1][0] = 1 // cpu
+	b[3][4][0] = 1 // memory
+	b[4][2][0] = 1
+	divergence := compareRuns(a, b)
+	if divergence == nil || divergence.String() != "runs diverge after frame 4 (cpu, memory differ)" {
+		t.Errorf("divergence = %v", divergence)
+	}
+}
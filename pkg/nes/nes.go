@@ -0,0 +1,298 @@
+// Package nes is the public API for embedding the gones emulator in other Go
+// programs, such as bots, research tools and test harnesses. A Console is one
+// NES: load a ROM, set the controllers, run frames, and read the picture,
+// the audio and the memory. There is no window, audio output or timing; the
+// caller runs frames as fast or as slowly as it likes.
+//
+// A Console is not safe for concurrent use. Consoles are independent, so
+// several can run on different goroutines.
+package nes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+
+	"gones/internal/bus"
+	"gones/internal/cartridge"
+	"gones/internal/record"
+)
+
+// Size of the picture in pixels
+const (
+	Width  = 256
+	Height = 240
+)
+
+// FrameRate is the NTSC NES's frames per second
+const FrameRate = record.NTSCFrameRate
+
+// DefaultSampleRate is the audio sample rate of a new Console
+const DefaultSampleRate = 44100
+
+// Players is the number of controllers: two, or four with the Four Score
+const Players = 4
+
+// Buttons is a set of controller buttons
+type Buttons uint8
+
+// Controller buttons
+const (
+	ButtonA Buttons = 1 << iota
+	ButtonB
+	ButtonSelect
+	ButtonStart
+	ButtonUp
+	ButtonDown
+	ButtonLeft
+	ButtonRight
+)
+
+// ErrNoROM is returned by methods that need a ROM loaded
+var ErrNoROM = errors.New("no ROM loaded")
+
+// JamError is returned by RunFrame once the CPU executed a jam (KIL)
+// instruction, which stops it until the console is reset
+type JamError struct {
+	PC uint16 // Address of the instruction
+}
+
+func (e *JamError) Error() string {
+	return fmt.Sprintf("CPU jammed at $%04X", e.PC)
+}
+
+// Console is an emulated NES
+type Console struct {
+	bus        *bus.Bus // nil until a ROM is loaded
+	rom        []byte   // The ROM image, parsed again at each power cycle
+	cart       *cartridge.Cartridge
+	sampleRate int
+	fourScore  bool
+	audio      []float32 // Samples of the frames run since AudioSamples
+}
+
+// New returns a console with no ROM loaded
+func New() *Console {
+	return &Console{sampleRate: DefaultSampleRate}
+}
+
+// LoadROM loads an iNES ROM file, or a .zip archive of one, and powers the
+// console on
+func (c *Console) LoadROM(path string) error {
+	data, err := cartridge.ReadROM(path)
+	if err != nil {
+		return err
+	}
+	return c.LoadROMData(data)
+}
+
+// LoadROMData loads an iNES ROM image and powers the console on
+func (c *Console) LoadROMData(data []byte) error {
+	cart, err := cartridge.LoadFromReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to load ROM: %v", err)
+	}
+	if !cartridge.MapperSupported(cart.MapperID()) {
+		return fmt.Errorf("mapper %d is not supported", cart.MapperID())
+	}
+	c.rom = bytes.Clone(data)
+	c.cart = cart
+	c.powerOn()
+	return nil
+}
+
+// PowerCycle turns the console off and on again, clearing its memory: the
+// cartridge is loaded again from the ROM, so its mapper registers and RAM
+// start over too. The cartridge's battery RAM is kept.
+func (c *Console) PowerCycle() error {
+	if c.cart == nil {
+		return ErrNoROM
+	}
+	cart, err := cartridge.LoadFromReader(bytes.NewReader(c.rom))
+	if err != nil {
+		// The image parsed when it was loaded
+		return fmt.Errorf("failed to reload ROM: %v", err)
+	}
+	if c.cart.HasBattery() {
+		cart.LoadSRAM(c.cart.GetSRAM())
+	}
+	c.cart = cart
+	c.powerOn()
+	return nil
+}
+
+// powerOn starts a new console with the cartridge plugged in
+func (c *Console) powerOn() {
+	c.bus = bus.New()
+	c.bus.SetAudioSampleRate(c.sampleRate)
+	c.bus.SetFourScore(c.fourScore)
+	c.bus.LoadCartridge(c.cart)
+	c.bus.Reset()
+	c.audio = c.audio[:0]
+}
+
+// Reset presses the console's reset button
+func (c *Console) Reset() {
+	if c.bus != nil {
+		c.bus.Reset()
+	}
+}
+
+// RunFrame runs the console until the picture of the next frame is complete
+func (c *Console) RunFrame() (err error) {
+	if c.bus == nil {
+		return ErrNoROM
+	}
+	if c.bus.CPU.Jammed() {
+		return &JamError{PC: c.bus.CPU.PC}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("emulator panic: %v", r)
+		}
+	}()
+	c.bus.Run(1)
+	c.audio = append(c.audio, c.bus.GetAudioSamples()...)
+	if c.bus.CPU.Jammed() {
+		return &JamError{PC: c.bus.CPU.PC}
+	}
+	return nil
+}
+
+// Frame returns the number of frames run since power on or reset
+func (c *Console) Frame() uint64 {
+	if c.bus == nil {
+		return 0
+	}
+	return c.bus.GetFrameCount()
+}
+
+// Framebuffer returns the last picture as Width×Height pixels, row by row,
+// each 0xRRGGBB. It is the console's own buffer, overwritten by RunFrame:
+// copy it to keep it.
+func (c *Console) Framebuffer() []uint32 {
+	if c.bus == nil {
+		return make([]uint32, Width*Height)
+	}
+	return c.bus.GetFrameBuffer()
+}
+
+// Image returns a copy of the last picture
+func (c *Console) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	for i, pixel := range c.Framebuffer() {
+		img.Pix[i*4] = uint8(pixel >> 16)
+		img.Pix[i*4+1] = uint8(pixel >> 8)
+		img.Pix[i*4+2] = uint8(pixel)
+		img.Pix[i*4+3] = 0xFF
+	}
+	return img
+}
+
+// AudioSamples returns the audio of the frames run since the last call, as
+// mono samples from -1 to 1 at the sample rate
+func (c *Console) AudioSamples() []float32 {
+	samples := c.audio
+	c.audio = nil
+	return samples
+}
+
+// SampleRate returns the audio sample rate
+func (c *Console) SampleRate() int {
+	return c.sampleRate
+}
+
+// SetSampleRate sets the audio sample rate, such as 44100 or 48000
+func (c *Console) SetSampleRate(rate int) error {
+	if rate < 8000 || rate > 192000 {
+		return fmt.Errorf("sample rate %d is not 8000-192000", rate)
+	}
+	c.sampleRate = rate
+	if c.bus != nil {
+		c.bus.SetAudioSampleRate(rate)
+	}
+	return nil
+}
+
+// SetFourScore connects or disconnects the Four Score adapter, which
+// players 3 and 4 need
+func (c *Console) SetFourScore(enabled bool) {
+	c.fourScore = enabled
+	if c.bus != nil {
+		c.bus.SetFourScore(enabled)
+	}
+}
+
+// SetInput sets the buttons a player (1-4) holds, until changed
+func (c *Console) SetInput(player int, buttons Buttons) error {
+	if player < 1 || player > Players {
+		return fmt.Errorf("player %d is not 1-%d", player, Players)
+	}
+	if c.bus == nil {
+		return ErrNoROM
+	}
+	var pressed [8]bool
+	for i := range pressed {
+		pressed[i] = buttons&(1<<i) != 0
+	}
+	c.bus.SetControllerButtons(player, pressed)
+	return nil
+}
+
+// ReadMemory reads a byte of the CPU's address space without side effects:
+// RAM ($0000-$1FFF) and the cartridge ($6000-$FFFF). Registers, whose reads
+// change the console's state, read as 0.
+func (c *Console) ReadMemory(address uint16) uint8 {
+	if c.bus == nil {
+		return 0
+	}
+	value, _ := c.bus.Memory.Peek(address)
+	return value
+}
+
+// SaveState snapshots the whole console, for LoadState on a console with
+// the same ROM
+func (c *Console) SaveState() ([]byte, error) {
+	if c.bus == nil {
+		return nil, ErrNoROM
+	}
+	return c.bus.SaveStateToBytes()
+}
+
+// LoadState restores a snapshot from SaveState. The console is left as it
+// was when the snapshot is invalid.
+func (c *Console) LoadState(data []byte) error {
+	if c.bus == nil {
+		return ErrNoROM
+	}
+	if err := c.bus.LoadStateFromBytes(data); err != nil {
+		return err
+	}
+	c.audio = c.audio[:0]
+	return nil
+}
+
+// HasBattery reports whether the cartridge keeps its RAM with a battery,
+// for games that save
+func (c *Console) HasBattery() bool {
+	return c.cart != nil && c.cart.HasBattery()
+}
+
+// BatteryRAM returns a copy of the cartridge's battery RAM, to save
+func (c *Console) BatteryRAM() []byte {
+	if c.cart == nil {
+		return nil
+	}
+	return c.cart.GetSRAM()
+}
+
+// LoadBatteryRAM restores battery RAM saved from BatteryRAM, usually right
+// after LoadROM
+func (c *Console) LoadBatteryRAM(data []byte) error {
+	if c.cart == nil {
+		return ErrNoROM
+	}
+	c.cart.LoadSRAM(data)
+	return nil
+}
@@ -0,0 +1,181 @@
+package nes
+
+import (
+	"errors"
+	"testing"
+
+	"gones/internal/cartridge"
+)
+
+// buildROM returns an NROM image running code from $8000
+func buildROM(t *testing.T, code []uint8) []byte {
+	t.Helper()
+	rom, err := cartridge.NewTestROMBuilder().WithInstructions(code).WithResetVector(0x8000).Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	return rom
+}
+
+// inputCode keeps storing controller 1's A button in $10 and counting in $11
+var inputCode = []uint8{
+	0xA9, 0x01, 0x8D, 0x16, 0x40, // LDA #1; STA $4016
+	0xA9, 0x00, 0x8D, 0x16, 0x40, // LDA #0; STA $4016
+	0xAD, 0x16, 0x40, // LDA $4016
+	0x29, 0x01, // AND #1
+	0x85, 0x10, // STA $10
+	0xE6, 0x11, // INC $11
+	0x4C, 0x00, 0x80, // JMP $8000
+}
+
+func TestConsole(t *testing.T) {
+	c := New()
+	if err := c.RunFrame(); err != ErrNoROM {
+		t.Fatalf("RunFrame without a ROM = %v, want ErrNoROM", err)
+	}
+	if err := c.LoadROMData(buildROM(t, inputCode)); err != nil {
+		t.Fatalf("LoadROMData: %v", err)
+	}
+
+	if err := c.RunFrame(); err != nil {
+		t.Fatalf("RunFrame: %v", err)
+	}
+	if c.ReadMemory(0x10) != 0 {
+		t.Errorf("A read as held before SetInput")
+	}
+	if err := c.SetInput(1, ButtonA|ButtonRight); err != nil {
+		t.Fatalf("SetInput: %v", err)
+	}
+	if err := c.RunFrame(); err != nil {
+		t.Fatalf("RunFrame: %v", err)
+	}
+	if c.ReadMemory(0x10) != 1 {
+		t.Errorf("A read as released after SetInput")
+	}
+	if c.Frame() != 2 {
+		t.Errorf("Frame() = %d, want 2", c.Frame())
+	}
+	if n := len(c.AudioSamples()); n < 1400 || n > 1540 {
+		t.Errorf("%d audio samples for 2 frames at 44100 Hz", n)
+	}
+	if n := len(c.AudioSamples()); n != 0 {
+		t.Errorf("%d audio samples again without running a frame", n)
+	}
+	if err := c.SetInput(5, 0); err == nil {
+		t.Error("player 5 accepted")
+	}
+
+	// Frames run after loading a state repeat those run after saving it
+	state, err := c.SaveState()
+	if err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+	run := func() (uint8, []uint32) {
+		for i := 0; i < 3; i++ {
+			if err := c.RunFrame(); err != nil {
+				t.Fatalf("RunFrame: %v", err)
+			}
+		}
+		return c.ReadMemory(0x11), append([]uint32(nil), c.Framebuffer()...)
+	}
+	counter, frame := run()
+	if err := c.LoadState(state); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	again, frameAgain := run()
+	if counter != again || len(frame) != Width*Height || string(uint32Bytes(frame)) != string(uint32Bytes(frameAgain)) {
+		t.Errorf("after LoadState: counter %d, want %d, or the picture differs", again, counter)
+	}
+	if err := c.LoadState([]byte("junk")); err == nil {
+		t.Error("invalid state loaded")
+	}
+	if img := c.Image(); img.Bounds().Dx() != Width || img.Pix[3] != 0xFF {
+		t.Errorf("Image() is %v", img.Bounds())
+	}
+}
+
+// uint32Bytes flattens pixels for comparison
+func uint32Bytes(pixels []uint32) []byte {
+	out := make([]byte, 0, len(pixels)*4)
+	for _, p := range pixels {
+		out = append(out, byte(p), byte(p>>8), byte(p>>16), byte(p>>24))
+	}
+	return out
+}
+
+func TestConsoleJam(t *testing.T) {
+	c := New()
+	if err := c.LoadROMData(buildROM(t, []uint8{0xEA, 0x02})); err != nil { // NOP; KIL
+		t.Fatalf("LoadROMData: %v", err)
+	}
+	var jam *JamError
+	if err := c.RunFrame(); !errors.As(err, &jam) || jam.PC != 0x8001 {
+		t.Fatalf("RunFrame = %v, want a jam at $8001", err)
+	}
+	if err := c.RunFrame(); !errors.As(err, &jam) {
+		t.Errorf("RunFrame after the jam = %v", err)
+	}
+	if err := c.LoadROMData([]byte("not a ROM")); err == nil {
+		t.Error("invalid ROM loaded")
+	}
+}
+
+func TestConsolePowerCycle(t *testing.T) {
+	// MMC3 with 8 KB banks starting $B0, $B1, ...; the fixed last bank at
+	// $E000 loops
+	mmc3 := func(battery bool) []byte {
+		b := cartridge.NewTestROMBuilder().WithMapper(4).WithPRGSize(2).
+			WithData(0x0000, []uint8{0xB0}).
+			WithData(0x2000, []uint8{0xB1}).
+			WithData(0x6000, []uint8{0x4C, 0x00, 0xE0}). // JMP $E000
+			WithResetVector(0xE000)
+		if battery {
+			b = b.WithBattery()
+		}
+		rom, err := b.Build()
+		if err != nil {
+			t.Fatalf("Build: %v", err)
+		}
+		return rom
+	}
+
+	for _, battery := range []bool{false, true} {
+		c := New()
+		if err := c.LoadROMData(mmc3(battery)); err != nil {
+			t.Fatalf("LoadROMData: %v", err)
+		}
+		c.bus.Memory.Write(0x6000, 0x5A)
+		c.bus.Memory.Write(0x8000, 0x06) // R6, the bank at $8000
+		c.bus.Memory.Write(0x8001, 0x01)
+		if c.ReadMemory(0x6000) != 0x5A || c.ReadMemory(0x8000) != 0xB1 {
+			t.Fatalf("battery %v: PRG RAM $%02X and $8000 $%02X before the power cycle",
+				battery, c.ReadMemory(0x6000), c.ReadMemory(0x8000))
+		}
+
+		if err := c.PowerCycle(); err != nil {
+			t.Fatalf("battery %v: PowerCycle: %v", battery, err)
+		}
+		if got := c.ReadMemory(0x8000); got != 0xB0 {
+			t.Errorf("battery %v: $8000 = $%02X after a power cycle, want bank 0's $B0", battery, got)
+		}
+		want := uint8(0x00)
+		if battery {
+			want = 0x5A
+		}
+		if got := c.ReadMemory(0x6000); got != want {
+			t.Errorf("battery %v: $6000 = $%02X after a power cycle, want $%02X", battery, got, want)
+		}
+	}
+
+	if err := New().PowerCycle(); err != ErrNoROM {
+		t.Errorf("PowerCycle without a ROM = %v, want ErrNoROM", err)
+	}
+	c := New()
+	if err := c.LoadROMData(mmc3(false)); err != nil {
+		t.Fatalf("LoadROMData: %v", err)
+	}
+	c.rom = c.rom[:8] // No longer parses
+	if err := c.PowerCycle(); err == nil {
+		t.Error("PowerCycle kept going with a ROM that does not parse")
+	}
+}
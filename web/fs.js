@@ -0,0 +1,363 @@
+// File system for the WebAssembly build of gones. Go's syscall package calls
+// the Node.js fs API on globalThis.fs; this implements it in memory, and
+// keeps every file outside /tmp in IndexedDB so battery saves, save states
+// and settings survive reloads. Load it before wasm_exec.js.
+"use strict";
+
+(() => {
+	const O_WRONLY = 1, O_RDWR = 2, O_CREAT = 64, O_EXCL = 128, O_TRUNC = 512, O_APPEND = 1024, O_DIRECTORY = 65536;
+	const S_IFDIR = 0o040000, S_IFREG = 0o100000;
+
+	const dbName = "gones", storeName = "files";
+	const flushDelay = 500; // Milliseconds from a change to writing it to IndexedDB
+
+	// Files and directories by absolute path
+	const nodes = new Map([["/", { dir: true, mtime: Date.now() }], ["/tmp", { dir: true, mtime: Date.now() }]]);
+	const fds = new Map(); // Open files: {path, pos, append, written}
+	let nextFd = 100;
+
+	const fsError = (code) => {
+		const err = new Error(code);
+		err.code = code;
+		return err;
+	};
+
+	// normalize makes a path absolute (relative to /) and removes . and ..
+	const normalize = (path) => {
+		const parts = [];
+		for (const part of String(path).split("/")) {
+			if (part === "" || part === ".") continue;
+			if (part === "..") parts.pop();
+			else parts.push(part);
+		}
+		return "/" + parts.join("/");
+	};
+
+	const parentOf = (path) => path.slice(0, path.lastIndexOf("/")) || "/";
+
+	// lookup returns the node at a path, or throws ENOENT
+	const lookup = (path) => {
+		const node = nodes.get(path);
+		if (!node) throw fsError("ENOENT");
+		return node;
+	};
+
+	// create adds a node to an existing directory
+	const create = (path, node) => {
+		const parent = nodes.get(parentOf(path));
+		if (!parent) throw fsError("ENOENT");
+		if (!parent.dir) throw fsError("ENOTDIR");
+		nodes.set(path, node);
+		changed(path);
+	};
+
+	const fileOf = (fd) => {
+		const file = fds.get(fd);
+		if (!file) throw fsError("EBADF");
+		return file;
+	};
+
+	const stat = (node) => ({
+		dev: 0, ino: 0, nlink: 1, uid: 0, gid: 0, rdev: 0, blksize: 4096,
+		mode: node.dir ? S_IFDIR | 0o755 : S_IFREG | 0o644,
+		size: node.dir ? 0 : node.data.length,
+		blocks: node.dir ? 0 : Math.ceil(node.data.length / 512),
+		atimeMs: node.mtime, mtimeMs: node.mtime, ctimeMs: node.mtime,
+		isDirectory: () => !!node.dir,
+	});
+
+	// resize grows or shrinks a file's data
+	const resize = (node, length) => {
+		const data = new Uint8Array(length);
+		data.set(node.data.subarray(0, Math.min(length, node.data.length)));
+		node.data = data;
+	};
+
+	// Standard output and error go to the console, a line at a time
+	const decoder = new TextDecoder("utf-8");
+	const output = { 1: "", 2: "" };
+	const writeConsole = (fd, buf) => {
+		output[fd] += decoder.decode(buf);
+		const nl = output[fd].lastIndexOf("\n");
+		if (nl !== -1) {
+			(fd === 2 ? console.error : console.log)(output[fd].substring(0, nl));
+			output[fd] = output[fd].substring(nl + 1);
+		}
+		return buf.length;
+	};
+
+	// call runs an operation in the callback style of Node.js
+	const call = (callback, run) => {
+		let result;
+		try {
+			result = run();
+		} catch (err) {
+			if (!err.code) throw err;
+			callback(err);
+			return;
+		}
+		callback(null, result);
+	};
+
+	globalThis.fs = {
+		constants: { O_WRONLY, O_RDWR, O_CREAT, O_EXCL, O_TRUNC, O_APPEND, O_DIRECTORY },
+
+		writeSync(fd, buf) {
+			if (fd !== 1 && fd !== 2) throw fsError("EBADF");
+			return writeConsole(fd, buf);
+		},
+
+		open(path, flags, mode, callback) {
+			call(callback, () => {
+				path = normalize(path);
+				let node = nodes.get(path);
+				if (node && (flags & O_CREAT) && (flags & O_EXCL)) throw fsError("EEXIST");
+				if (!node) {
+					if (!(flags & O_CREAT)) throw fsError("ENOENT");
+					node = { data: new Uint8Array(0), mtime: Date.now() };
+					create(path, node);
+				}
+				const writing = (flags & (O_WRONLY | O_RDWR)) !== 0;
+				if (node.dir && writing) throw fsError("EISDIR");
+				if (!node.dir && (flags & O_DIRECTORY)) throw fsError("ENOTDIR");
+				if (!node.dir && (flags & O_TRUNC) && writing && node.data.length > 0) {
+					node.data = new Uint8Array(0);
+					node.mtime = Date.now();
+					changed(path);
+				}
+				const fd = nextFd++;
+				fds.set(fd, { path, pos: 0, append: (flags & O_APPEND) !== 0 });
+				return fd;
+			});
+		},
+
+		close(fd, callback) {
+			call(callback, () => {
+				fileOf(fd);
+				fds.delete(fd);
+			});
+		},
+
+		read(fd, buffer, offset, length, position, callback) {
+			call(callback, () => {
+				const file = fileOf(fd);
+				const node = lookup(file.path);
+				if (node.dir) throw fsError("EISDIR");
+				const start = position === null ? file.pos : position;
+				const n = Math.max(0, Math.min(length, node.data.length - start));
+				buffer.set(node.data.subarray(start, start + n), offset);
+				if (position === null) file.pos += n;
+				return n;
+			});
+		},
+
+		write(fd, buffer, offset, length, position, callback) {
+			call(callback, () => {
+				if (fd === 1 || fd === 2) return writeConsole(fd, buffer.subarray(offset, offset + length));
+				const file = fileOf(fd);
+				const node = lookup(file.path);
+				const start = file.append ? node.data.length : position === null ? file.pos : position;
+				if (start + length > node.data.length) resize(node, start + length);
+				node.data.set(buffer.subarray(offset, offset + length), start);
+				node.mtime = Date.now();
+				if (position === null) file.pos = start + length;
+				changed(file.path);
+				return length;
+			});
+		},
+
+		fstat(fd, callback) {
+			call(callback, () => stat(lookup(fileOf(fd).path)));
+		},
+
+		stat(path, callback) {
+			call(callback, () => stat(lookup(normalize(path))));
+		},
+
+		lstat(path, callback) {
+			this.stat(path, callback);
+		},
+
+		mkdir(path, perm, callback) {
+			call(callback, () => {
+				path = normalize(path);
+				if (nodes.has(path)) throw fsError("EEXIST");
+				create(path, { dir: true, mtime: Date.now() });
+			});
+		},
+
+		readdir(path, callback) {
+			call(callback, () => {
+				path = normalize(path);
+				if (!lookup(path).dir) throw fsError("ENOTDIR");
+				const prefix = path === "/" ? "/" : path + "/";
+				const names = [];
+				for (const child of nodes.keys()) {
+					if (child !== "/" && child.startsWith(prefix) && !child.slice(prefix.length).includes("/")) {
+						names.push(child.slice(prefix.length));
+					}
+				}
+				return names;
+			});
+		},
+
+		rename(from, to, callback) {
+			call(callback, () => {
+				from = normalize(from);
+				to = normalize(to);
+				const node = lookup(from);
+				if (from === to) return;
+				const target = nodes.get(to);
+				if (target && target.dir && !node.dir) throw fsError("EISDIR");
+				if (target && !target.dir && node.dir) throw fsError("ENOTDIR");
+				if (target && target.dir && [...nodes.keys()].some((p) => p.startsWith(to + "/"))) throw fsError("ENOTEMPTY");
+				if (!nodes.has(parentOf(to))) throw fsError("ENOENT");
+				// Move the node and, for a directory, everything in it
+				for (const [path, moved] of [...nodes]) {
+					if (path === from || path.startsWith(from + "/")) {
+						const dest = to + path.slice(from.length);
+						nodes.delete(path);
+						nodes.set(dest, moved);
+						changed(path);
+						changed(dest);
+					}
+				}
+				for (const file of fds.values()) {
+					if (file.path === from) file.path = to;
+				}
+			});
+		},
+
+		unlink(path, callback) {
+			call(callback, () => {
+				path = normalize(path);
+				if (lookup(path).dir) throw fsError("EISDIR");
+				nodes.delete(path);
+				changed(path);
+			});
+		},
+
+		rmdir(path, callback) {
+			call(callback, () => {
+				path = normalize(path);
+				if (!lookup(path).dir) throw fsError("ENOTDIR");
+				if ([...nodes.keys()].some((p) => p.startsWith(path + "/"))) throw fsError("ENOTEMPTY");
+				nodes.delete(path);
+				changed(path);
+			});
+		},
+
+		ftruncate(fd, length, callback) {
+			call(callback, () => {
+				const file = fileOf(fd);
+				resize(lookup(file.path), length);
+				changed(file.path);
+			});
+		},
+
+		truncate(path, length, callback) {
+			call(callback, () => {
+				path = normalize(path);
+				resize(lookup(path), length);
+				changed(path);
+			});
+		},
+
+		utimes(path, atime, mtime, callback) {
+			call(callback, () => {
+				path = normalize(path);
+				lookup(path).mtime = mtime * 1000;
+				changed(path);
+			});
+		},
+
+		fsync(fd, callback) { callback(null); },
+		chmod(path, mode, callback) { call(callback, () => { lookup(normalize(path)); }); },
+		fchmod(fd, mode, callback) { call(callback, () => { fileOf(fd); }); },
+		chown(path, uid, gid, callback) { call(callback, () => { lookup(normalize(path)); }); },
+		fchown(fd, uid, gid, callback) { call(callback, () => { fileOf(fd); }); },
+		lchown(path, uid, gid, callback) { call(callback, () => { lookup(normalize(path)); }); },
+		link(path, link, callback) { callback(fsError("ENOSYS")); },
+		symlink(path, link, callback) { callback(fsError("ENOSYS")); },
+		readlink(path, callback) { callback(fsError("EINVAL")); },
+	};
+
+	// Persistence: paths changed since the last flush are written to
+	// IndexedDB (or deleted from it) a moment later, and when the page is
+	// hidden or closed
+
+	const dirty = new Set();
+	let flushTimer = null;
+	let dbPromise = null;
+
+	const persisted = (path) => path !== "/" && path !== "/tmp" && !path.startsWith("/tmp/");
+
+	function changed(path) {
+		if (!persisted(path)) return;
+		dirty.add(path);
+		if (flushTimer === null) flushTimer = setTimeout(flush, flushDelay);
+	}
+
+	const openDB = () => {
+		if (!dbPromise) {
+			dbPromise = new Promise((resolve, reject) => {
+				const request = indexedDB.open(dbName, 1);
+				request.onupgradeneeded = () => request.result.createObjectStore(storeName);
+				request.onsuccess = () => resolve(request.result);
+				request.onerror = () => reject(request.error);
+			});
+		}
+		return dbPromise;
+	};
+
+	// flush writes the changed files to IndexedDB
+	async function flush() {
+		flushTimer = null;
+		if (dirty.size === 0) return;
+		const paths = [...dirty];
+		dirty.clear();
+		const db = await openDB();
+		await new Promise((resolve, reject) => {
+			const tx = db.transaction(storeName, "readwrite");
+			const store = tx.objectStore(storeName);
+			for (const path of paths) {
+				const node = nodes.get(path);
+				if (node) store.put(node.dir ? { dir: true, mtime: node.mtime } : { data: node.data.slice(), mtime: node.mtime }, path);
+				else store.delete(path);
+			}
+			tx.oncomplete = resolve;
+			tx.onerror = () => reject(tx.error);
+		});
+	}
+
+	// restore loads the files kept in IndexedDB. Run it before Go starts.
+	async function restore() {
+		const db = await openDB();
+		const entries = await new Promise((resolve, reject) => {
+			const tx = db.transaction(storeName, "readonly");
+			const store = tx.objectStore(storeName);
+			const keys = store.getAllKeys(), values = store.getAll();
+			tx.oncomplete = () => resolve(keys.result.map((key, i) => [key, values.result[i]]));
+			tx.onerror = () => reject(tx.error);
+		});
+		// Parents sort before their children
+		entries.sort(([a], [b]) => (a < b ? -1 : a > b ? 1 : 0));
+		for (const [path, node] of entries) {
+			for (let dir = parentOf(path); !nodes.has(dir); dir = parentOf(dir)) {
+				nodes.set(dir, { dir: true, mtime: node.mtime });
+			}
+			nodes.set(path, node);
+		}
+	}
+
+	const flushNow = () => {
+		clearTimeout(flushTimer);
+		flush().catch((err) => console.error("gones: saving files failed:", err));
+	};
+	addEventListener("pagehide", flushNow);
+	addEventListener("visibilitychange", () => {
+		if (document.visibilityState === "hidden") flushNow();
+	});
+
+	globalThis.gonesFS = { restore, flush };
+})();
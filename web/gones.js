@@ -0,0 +1,61 @@
+// Starts gones in the page: restores the files kept in IndexedDB, runs the
+// WebAssembly build, and hands it the ROMs picked, dropped on the page, or
+// given in the URL (index.html?rom=game.nes) when the page is embedded.
+"use strict";
+
+(async () => {
+	const picker = document.getElementById("rom");
+	const status = document.getElementById("status");
+	const setStatus = (text) => { status.textContent = text; };
+
+	const loadROM = async (name, data) => {
+		setStatus(`Loading ${name}...`);
+		try {
+			await globalThis.gones.loadROM(name, data);
+			setStatus(name);
+		} catch (err) {
+			setStatus(`${name}: ${err.message}`);
+		}
+	};
+
+	const loadFile = async (file) => {
+		if (file) await loadROM(file.name, new Uint8Array(await file.arrayBuffer()));
+	};
+
+	const ready = new Promise((resolve) => addEventListener("gonesready", resolve, { once: true }));
+
+	try {
+		setStatus("Loading saved files...");
+		await gonesFS.restore();
+	} catch (err) {
+		setStatus(`Saves are unavailable (${err.message || err}); they will not be kept`);
+	}
+
+	const go = new Go();
+	const wasm = await WebAssembly.instantiateStreaming(fetch("gones.wasm"), go.importObject);
+	go.run(wasm.instance).then(() => setStatus("gones stopped"));
+	await ready;
+
+	picker.disabled = false;
+	setStatus("Open a ROM (.nes), or drop one on the page");
+	picker.addEventListener("change", () => {
+		loadFile(picker.files[0]);
+		picker.value = "";
+		picker.blur(); // Keys go to the game
+	});
+	addEventListener("dragover", (event) => event.preventDefault());
+	addEventListener("drop", (event) => {
+		event.preventDefault();
+		loadFile(event.dataTransfer.files[0]);
+	});
+
+	const url = new URLSearchParams(location.search).get("rom");
+	if (url) {
+		const response = await fetch(url);
+		if (!response.ok) {
+			setStatus(`${url}: ${response.status} ${response.statusText}`);
+			return;
+		}
+		await loadROM(url.split("/").pop(), new Uint8Array(await response.arrayBuffer()));
+	}
+})();